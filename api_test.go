@@ -0,0 +1,208 @@
+package main
+
+import (
+	"flag"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var updateAPI = flag.Bool("update-api", false, "update testdata/api.txt with the current library API")
+
+// apiPackages are the packages whose exported API is supported for use as a
+// library, see docs/library.md
+var apiPackages = []string{"checker", "fixer", "parser", "scanner"}
+
+// TestAPICompatibility fails when an exported identifier of a supported
+// package is removed, changes type or is added without being recorded in
+// testdata/api.txt. Run go test -run TestAPICompatibility -update-api to
+// record an intended change.
+func TestAPICompatibility(t *testing.T) {
+	var got []string
+	for _, pkg := range apiPackages {
+		lines, err := exportedAPI(filepath.Join("pkg", pkg))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, lines...)
+	}
+
+	const golden = "testdata/api.txt"
+	if *updateAPI {
+		if err := os.MkdirAll(filepath.Dir(golden), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(golden, []byte(strings.Join(got, "\n")+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+
+	for _, line := range want {
+		if !slices.Contains(got, line) {
+			t.Errorf("removed or changed: %s", line)
+		}
+	}
+	for _, line := range got {
+		if !slices.Contains(want, line) {
+			t.Errorf("not recorded: %s", line)
+		}
+	}
+	if t.Failed() {
+		t.Log("run go test -run TestAPICompatibility -update-api if the change is intended")
+	}
+}
+
+// exportedAPI returns one sorted line per exported identifier declared in
+// the non-test files of the package in dir, with its type
+func exportedAPI(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	pkg := filepath.Base(dir)
+	fset := token.NewFileSet()
+	var lines []string
+	add := func(format ...string) {
+		lines = append(lines, pkg+": "+strings.Join(format, " "))
+	}
+	for _, path := range files {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if !decl.Name.IsExported() {
+					continue
+				}
+				if decl.Recv == nil {
+					add("func", decl.Name.Name+signature(decl.Type))
+					continue
+				}
+				recv := types.ExprString(decl.Recv.List[0].Type)
+				if !ast.IsExported(strings.TrimPrefix(recv, "*")) {
+					continue
+				}
+				add("method", "("+recv+")", decl.Name.Name+signature(decl.Type))
+			case *ast.GenDecl:
+				var typ ast.Expr // of untyped constants in an iota group
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						if spec.Name.IsExported() {
+							typeAPI(spec, add)
+						}
+					case *ast.ValueSpec:
+						kind := "var"
+						if decl.Tok == token.CONST {
+							kind = "const"
+							if spec.Type != nil || len(spec.Values) > 0 {
+								typ = spec.Type
+							}
+						}
+						for i, name := range spec.Names {
+							if !name.IsExported() {
+								continue
+							}
+							switch {
+							case spec.Type != nil:
+								add(kind, name.Name, typeString(spec.Type))
+							case kind == "const" && typ != nil:
+								add(kind, name.Name, types.ExprString(typ))
+							case kind == "const" && i < len(spec.Values):
+								add(kind, name.Name, "=", types.ExprString(spec.Values[i]))
+							default:
+								add(kind, name.Name)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	slices.Sort(lines)
+	return lines, nil
+}
+
+// typeAPI adds the lines of an exported type: its kind and, for structs and
+// interfaces, the exported fields and methods
+func typeAPI(spec *ast.TypeSpec, add func(...string)) {
+	name := spec.Name.Name
+	switch t := spec.Type.(type) {
+	case *ast.StructType:
+		add("type", name, "struct")
+		for _, field := range t.Fields.List {
+			typ := typeString(field.Type)
+			if len(field.Names) == 0 {
+				if ast.IsExported(strings.TrimPrefix(typ[strings.LastIndex(typ, ".")+1:], "*")) {
+					add("field", name+"."+typ, "embedded")
+				}
+				continue
+			}
+			for _, n := range field.Names {
+				if n.IsExported() {
+					add("field", name+"."+n.Name, typ)
+				}
+			}
+		}
+	case *ast.InterfaceType:
+		add("type", name, "interface")
+		for _, m := range t.Methods.List {
+			if ft, ok := m.Type.(*ast.FuncType); ok && len(m.Names) > 0 {
+				add("method", name+"."+m.Names[0].Name+signature(ft))
+			} else {
+				add("embedded", name+"."+types.ExprString(m.Type))
+			}
+		}
+	default:
+		assign := ""
+		if spec.Assign.IsValid() {
+			assign = "= "
+		}
+		add("type", name, assign+typeString(spec.Type))
+	}
+}
+
+// typeString formats a type, without parameter names for a function type
+func typeString(x ast.Expr) string {
+	if ft, ok := x.(*ast.FuncType); ok {
+		return "func" + signature(ft)
+	}
+	return types.ExprString(x)
+}
+
+// signature formats the parameters and results of a function without their
+// names, which callers do not depend on
+func signature(ft *ast.FuncType) string {
+	s := types.ExprString(&ast.FuncType{Params: unnamed(ft.Params), Results: unnamed(ft.Results)})
+	return strings.TrimPrefix(s, "func")
+}
+
+// unnamed returns fields with one unnamed field per name
+func unnamed(fields *ast.FieldList) *ast.FieldList {
+	if fields == nil {
+		return nil
+	}
+	out := &ast.FieldList{}
+	for _, f := range fields.List {
+		for range max(len(f.Names), 1) {
+			out.List = append(out.List, &ast.Field{Type: f.Type})
+		}
+	}
+	return out
+}
@@ -0,0 +1,1004 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/output"
+	"github.com/sebrandon1/ocp-doc-checker/pkg/scanner"
+)
+
+// runMainEnv makes the test binary run main instead of the tests, so that
+// the CLI tests run it as a separate process with its own flags, given one
+// per line in runMainEnv_ARGS
+const runMainEnv = "OCP_DOC_CHECKER_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) == "1" {
+		os.Args = append([]string{"ocp-doc-checker"}, strings.Split(os.Getenv(runMainEnv+"_ARGS"), "\n")...)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// cliDocsPath is the fake docs path of the ingress page at a version
+const cliDocsPath = "/en/documentation/openshift_container_platform/%s/html/networking/ingress"
+
+// cliLandingPath is the landing page of 4.17, which the legacy ingress page
+// redirects to
+const cliLandingPath = "/en/documentation/openshift_container_platform/4.17"
+
+// cliROSAPath is the fake docs path of the ingress page in the ROSA
+// documentation, the only page of another product served
+const cliROSAPath = "/en/documentation/red_hat_openshift_service_on_aws/4/html/networking/ingress"
+
+// cliShardingPath is the fake docs path of the ingress sharding page at a
+// version; 4.17 renamed the id of its section
+const cliShardingPath = "/en/documentation/openshift_container_platform/%s/html/networking/ingress-sharding"
+
+// cliURL is an outdated URL served by the fake docs server: the anchor
+// exists up to 4.17, the cap of every CLI test run
+const cliURL = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html/networking/ingress#configuring-ingress"
+
+// fakeDocs serves the ingress and ingress sharding pages at 4.16 and 4.17,
+// and the ingress page in the ROSA documentation, as docs.redhat.com over
+// TLS, through an HTTPS proxy, and returns the proxy URL and the path of a
+// PEM file with the CA certificate to trust
+func fakeDocs(t *testing.T) (proxyURL, caFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "docs.redhat.com"},
+		DNSNames:     []string{"docs.redhat.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caFile = filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	page := `<html><head><title>Ingress | Networking | OpenShift Container Platform | 4.16 | Red Hat Documentation</title></head><body><h2 id="configuring-ingress">Ingress</h2></body></html>`
+	docs := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case strings.Replace(cliDocsPath, "%s", "4.16", 1), strings.Replace(cliDocsPath, "%s", "4.17", 1), cliROSAPath:
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = io.WriteString(w, page)
+		case strings.Replace(cliShardingPath, "%s", "4.16", 1):
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = io.WriteString(w, `<html><body><h2 id="nw-ingress-sharding">Ingress sharding</h2></body></html>`)
+		case strings.Replace(cliShardingPath, "%s", "4.17", 1):
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = io.WriteString(w, `<html><body><h2 id="nw-ingress-sharding_configuring-ingress">Ingress sharding</h2></body></html>`)
+		case strings.Replace(cliDocsPath, "%s", "4.17", 1) + "-legacy":
+			http.Redirect(w, r, cliLandingPath, http.StatusMovedPermanently)
+		case cliLandingPath:
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = io.WriteString(w, `<html><head><title>OpenShift Container Platform | 4.17 | Red Hat Documentation</title></head><body></body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	docs.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	docs.Config.ErrorLog = log.New(io.Discard, "", 0)
+	docs.StartTLS()
+	t.Cleanup(docs.Close)
+
+	// The proxy tunnels every CONNECT to the docs server, whatever the host
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "CONNECT only", http.StatusMethodNotAllowed)
+			return
+		}
+		upstream, err := net.Dial("tcp", docs.Listener.Addr().String())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		_, _ = io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		go func() {
+			_, _ = io.Copy(upstream, buf)
+			upstream.Close()
+		}()
+		_, _ = io.Copy(conn, upstream)
+		conn.Close()
+	}))
+	t.Cleanup(proxy.Close)
+
+	return proxy.URL, caFile
+}
+
+// runCLI runs the CLI with args against the fake docs, trusting caFile
+// unless empty, and returns its stdout, stderr and exit code
+func runCLI(t *testing.T, proxyURL, caFile string, args ...string) (stdout, stderr []byte, code int) {
+	t.Helper()
+
+	// The same report in and out of GitHub Actions, without the network
+	args = append([]string{"-ci-mode", "none", "-as-of", "4.17", "-no-cache", "-rate-limit", "0", "-retries", "0"}, args...)
+	if caFile != "" {
+		args = append([]string{"-ca-cert", caFile}, args...)
+	}
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(),
+		runMainEnv+"=1",
+		runMainEnv+"_ARGS="+strings.Join(args, "\n"),
+		"HTTPS_PROXY="+proxyURL, "https_proxy="+proxyURL, "NO_PROXY=", "no_proxy=")
+	var out, errOut bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &errOut
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		t.Fatalf("running the CLI: %v", err)
+	}
+	return out.Bytes(), errOut.Bytes(), cmd.ProcessState.ExitCode()
+}
+
+// withDocsDir returns args with {dir} replaced by a new directory holding a
+// README that links to cliURL
+func withDocsDir(t *testing.T, args []string) []string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("See [ingress]("+cliURL+").\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	replaced := make([]string, len(args))
+	for i, arg := range args {
+		replaced[i] = strings.ReplaceAll(arg, "{dir}", dir)
+	}
+	return replaced
+}
+
+// decodeExactly decodes stdout as a single JSON value into v, without
+// unknown fields, followed by nothing but the encoder's newline
+func decodeExactly(t *testing.T, stdout []byte, v any) {
+	t.Helper()
+	dec := json.NewDecoder(bytes.NewReader(stdout))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		t.Fatalf("stdout is not the JSON report: %v\n%s", err, stdout)
+	}
+	if rest := stdout[dec.InputOffset():]; string(rest) != "\n" {
+		t.Fatalf("stdout has %d extraneous bytes after the JSON report: %q", len(rest), rest)
+	}
+}
+
+// checkTSV checks that every line of stdout is a tsv row of every column,
+// after the header
+func checkTSV(t *testing.T, stdout []byte) {
+	t.Helper()
+	if !bytes.HasSuffix(stdout, []byte("\n")) {
+		t.Fatalf("stdout does not end with a newline: %q", stdout)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(stdout), "\n"), "\n")
+	var header []string
+	for _, col := range output.TSVColumns {
+		header = append(header, col.Name)
+	}
+	if lines[0] != strings.Join(header, "\t") {
+		t.Fatalf("first line = %q, want the tsv header", lines[0])
+	}
+	if len(lines) < 2 {
+		t.Fatalf("stdout has no rows: %q", stdout)
+	}
+	for i, line := range lines[1:] {
+		if got := strings.Count(line, "\t") + 1; got != len(output.TSVColumns) {
+			t.Errorf("line %d has %d fields, want %d: %q", i+2, got, len(output.TSVColumns), line)
+		}
+	}
+}
+
+// checkDiagnostics checks that every line of stderr is a diagnostic of
+// -error-format json, and returns them
+func checkDiagnostics(t *testing.T, stderr []byte) []diagnostic {
+	t.Helper()
+	var diags []diagnostic
+	lines := bufio.NewScanner(bytes.NewReader(stderr))
+	for lines.Scan() {
+		var d diagnostic
+		dec := json.NewDecoder(strings.NewReader(lines.Text()))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&d); err != nil || d.Level == "" || d.Message == "" {
+			t.Fatalf("stderr line is not a diagnostic: %q", lines.Text())
+		}
+		diags = append(diags, d)
+	}
+	return diags
+}
+
+func TestCLI_Streams(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the CLI in separate processes")
+	}
+	proxyURL, caFile := fakeDocs(t)
+
+	// Every run has an outdated or broken URL, so every run exits 1
+	tests := []struct {
+		name  string
+		args  []string
+		check func(t *testing.T, stdout, stderr []byte)
+	}{
+		{"url json", []string{"-verbose", "-output", "json", "-url", cliURL}, func(t *testing.T, stdout, stderr []byte) {
+			var result output.Result
+			decodeExactly(t, stdout, &result)
+			if result.LatestVersion != "4.17" {
+				t.Errorf("latest version = %q, want 4.17", result.LatestVersion)
+			}
+		}},
+		{"url tsv", []string{"-verbose", "-output", "tsv", "-url", cliURL}, func(t *testing.T, stdout, stderr []byte) {
+			checkTSV(t, stdout)
+		}},
+		{"url broken", []string{"-url", strings.Replace(cliURL, "#configuring-ingress", "#ingress-removed", 1)}, func(t *testing.T, stdout, stderr []byte) {
+			if !bytes.Contains(stdout, []byte("❌ This URL is BROKEN: anchor missing from the page at 4.16")) {
+				t.Errorf("stdout has no broken status: %s", stdout)
+			}
+		}},
+		{"url broken json", []string{"-output", "json", "-url", strings.Replace(cliURL, "ingress#", "ingres#", 1)}, func(t *testing.T, stdout, stderr []byte) {
+			var result output.Result
+			decodeExactly(t, stdout, &result)
+			if !result.Broken || result.OriginalExists == nil || *result.OriginalExists || result.IsOutdated {
+				t.Errorf("result = %+v, want broken with original_exists false", result)
+			}
+		}},
+		{"url redirected json", []string{"-output", "json", "-url", strings.Replace(cliURL, "4.16/html/networking/ingress#", "4.17/html/networking/ingress-legacy#", 1)}, func(t *testing.T, stdout, stderr []byte) {
+			var result output.Result
+			decodeExactly(t, stdout, &result)
+			if !result.Broken || result.OriginalRedirectedTo != "https://docs.redhat.com"+cliLandingPath {
+				t.Errorf("result = %+v, want broken, redirected to the landing page", result)
+			}
+		}},
+		{"dir json with progress", []string{"-verbose", "-output", "json", "-dir", "{dir}"}, func(t *testing.T, stdout, stderr []byte) {
+			var batch output.Batch
+			decodeExactly(t, stdout, &batch)
+			if len(batch.Results) != 1 {
+				t.Errorf("report has %d results, want 1", len(batch.Results))
+			}
+			if !bytes.Contains(stderr, []byte("[1/1] Checked: ")) {
+				t.Errorf("stderr has no progress: %q", stderr)
+			}
+		}},
+		{"dir tsv with progress", []string{"-verbose", "-output", "tsv", "-dir", "{dir}"}, func(t *testing.T, stdout, stderr []byte) {
+			checkTSV(t, stdout)
+		}},
+		{"dir text fix", []string{"-check-fix", "-dir", "{dir}"}, func(t *testing.T, stdout, stderr []byte) {
+			for _, narration := range []string{"Found 1 unique", "Checking Fixes", "-fix would change 1 file(s)"} {
+				if bytes.Contains(stdout, []byte(narration)) || !bytes.Contains(stderr, []byte(narration)) {
+					t.Errorf("%q is not on stderr alone\nstdout: %s\nstderr: %s", narration, stdout, stderr)
+				}
+			}
+			if !bytes.Contains(stdout, []byte("OUTDATED")) {
+				t.Errorf("stdout has no text report: %s", stdout)
+			}
+		}},
+		{"dir json with structured diagnostics", []string{"-error-format", "json", "-output", "json-legacy", "-dir", "{dir}"}, func(t *testing.T, stdout, stderr []byte) {
+			var legacy map[string]any
+			if err := json.Unmarshal(stdout, &legacy); err != nil {
+				t.Fatalf("stdout is not the JSON report: %v", err)
+			}
+			diags := checkDiagnostics(t, stderr)
+			if len(diags) != 1 || diags[0].Level != "warning" || !strings.Contains(diags[0].Message, "json-legacy is deprecated") {
+				t.Errorf("diagnostics = %+v, want the json-legacy deprecation warning", diags)
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, code := runCLI(t, proxyURL, caFile, withDocsDir(t, tt.args)...)
+			if code != 1 {
+				t.Fatalf("exit code = %d, want 1\nstdout: %s\nstderr: %s", code, stdout, stderr)
+			}
+			tt.check(t, stdout, stderr)
+		})
+	}
+}
+
+func TestCLI_ErrorFormatJSON(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the CLI in separate processes")
+	}
+	proxyURL, caFile := fakeDocs(t)
+
+	// A flag error, which also prints usage text with -error-format text
+	stdout, stderr, code := runCLI(t, proxyURL, caFile, "-error-format", "json", "-width", "-1", "-url", cliURL)
+	if code != 1 || len(stdout) != 0 {
+		t.Fatalf("exit code = %d with stdout %q, want 1 and nothing", code, stdout)
+	}
+	diags := checkDiagnostics(t, stderr)
+	if len(diags) != 1 || diags[0].Level != "error" || !strings.Contains(diags[0].Message, "invalid -width -1") {
+		t.Errorf("diagnostics = %+v, want the -width error alone", diags)
+	}
+
+	_, stderr, _ = runCLI(t, proxyURL, caFile, "-width", "-1", "-url", cliURL)
+	if !bytes.HasPrefix(stderr, []byte("Error: invalid -width -1")) || !bytes.Contains(stderr, []byte("Usage")) {
+		t.Errorf("text stderr = %q, want the error and usage", stderr)
+	}
+}
+
+func TestCLI_ExitCodes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the CLI in separate processes")
+	}
+	proxyURL, caFile := fakeDocs(t)
+	upToDate := strings.Replace(cliURL, "4.16", "4.17", 1)
+
+	tests := []struct {
+		name       string
+		untrusted  bool
+		args       []string
+		wantCode   int
+		wantStderr string
+	}{
+		{"up to date", false, []string{"-url", upToDate}, 0, ""},
+		{"outdated", false, []string{"-url", cliURL}, 1, ""},
+		{"not a documentation URL", false, []string{"-url", "https://docs.redhat.com/en/documentation/openshift_container_platform"}, exitNotDocURL, "not an OCP documentation URL"},
+		{"no version could be requested", true, []string{"-url", cliURL}, exitCheckFailed, "untrusted certificate (see -ca-cert)"},
+		{"directory with URLs not checked", true, []string{"-dir", "{dir}"}, exitCheckFailed, "could not check URL " + cliURL},
+		{"above -max-version", false, []string{"-url", cliURL, "-max-version", "4.15"}, 0, ""},
+		{"malformed -max-version", false, []string{"-dir", "{dir}", "-max-version", "4.x"}, 1, `invalid version range bound "4.x"`},
+		{"newer version excluded", false, []string{"-url", cliURL, "-exclude-versions", "4.17"}, 0, ""},
+		{"malformed -exclude-versions", false, []string{"-dir", "{dir}", "-exclude-versions", "4.17,next"}, 1, `invalid excluded version "next"`},
+		{"page parse budget spent", false, []string{"-url", cliURL, "-max-page-parse-time", "1ns"}, 0, "indeterminate: page too large"},
+		{"indeterminate anchors fail", false, []string{"-dir", "{dir}", "-max-page-parse-time", "1ns", "-indeterminate-anchors", "fail"}, 1, "indeterminate: page too large"},
+		{"negative -max-page-parse-time", false, []string{"-url", cliURL, "-max-page-parse-time", "-1s"}, 1, "invalid -max-page-parse-time -1s"},
+		{"malformed -indeterminate-anchors", false, []string{"-url", cliURL, "-indeterminate-anchors", "ignore"}, 1, `invalid -indeterminate-anchors "ignore"`},
+		{"malformed -network", false, []string{"-url", cliURL, "-network", "tcp6"}, 1, `invalid -network "tcp6"`},
+		{"malformed -anchor-policy", false, []string{"-url", cliURL, "-anchor-policy", "lenient"}, 1, `invalid -anchor-policy "lenient"`},
+		{"malformed -tracking-param", false, []string{"-dir", "{dir}", "-tracking-param", "utm_["}, 1, `invalid tracking parameter "utm_["`},
+		{"-product-context alone", false, []string{"-dir", "{dir}", "-product-context", "products.json"}, 1, "-product-context flag can only be used with -check-product-context"},
+		{"malformed -product-context", false, []string{"-dir", "{dir}", "-check-product-context", "-product-context", "{dir}/README.md"}, 1, "could not load product context"},
+		{"empty -deprecation-marker", false, []string{"-url", cliURL, "-deprecation-marker", "."}, 1, `-deprecation-marker "." is empty`},
+		{"broken", false, []string{"-url", strings.Replace(upToDate, "#configuring-ingress", "#ingress-removed", 1)}, 1, ""},
+		{"broken not checked", false, []string{"-url", strings.Replace(upToDate, "#configuring-ingress", "#ingress-removed", 1), "-no-original-check"}, 0, ""},
+		{"redirect to the landing page", false, []string{"-url", strings.Replace(upToDate, "ingress#configuring-ingress", "ingress-legacy", 1)}, 1, ""},
+		{"redirect accepted", false, []string{"-url", strings.Replace(upToDate, "ingress#configuring-ingress", "ingress-legacy", 1), "-accept-redirects"}, 0, ""},
+		{"soft 404", false, []string{"-url", upToDate, "-soft-404-marker", "Ingress"}, 1, ""},
+		{"-soft-404-marker with -no-soft-404-check", false, []string{"-url", cliURL, "-soft-404-marker", "Ingress", "-no-soft-404-check"}, 1, "-soft-404-marker and -no-soft-404-check flags are mutually exclusive"},
+		{"empty -soft-404-marker", false, []string{"-url", cliURL, "-soft-404-marker", " "}, 1, "-soft-404-marker is empty"},
+		{"-fix-generated with -generated-pattern", false, []string{"-dir", "{dir}", "-fix-generated", "-generated-pattern", "api/**"}, 1, "-fix-generated and -generated-pattern flags are mutually exclusive"},
+		{"malformed -generated-pattern", false, []string{"-dir", "{dir}", "-generated-pattern", "gen["}, 1, `invalid generated pattern "gen["`},
+		{"negative -minified-line-length", false, []string{"-dir", "{dir}", "-minified-line-length", "-1"}, 1, "-minified-line-length must not be negative"},
+		{"README taken for generated", false, []string{"-dir", "{dir}", "-generated-pattern", "README*"}, 0, ""},
+		{"fix verified", false, []string{"-dir", "{dir}", "-fix"}, 0, "Verified 1 file(s) and 1 new URL(s)"},
+		{"-git-new-only without -git-diff", false, []string{"-dir", "{dir}", "-git-new-only"}, 1, "-git-new-only flag can only be used with -git-diff flag"},
+		{"-git-diff outside a repository", false, []string{"-dir", "{dir}", "-git-diff", "HEAD"}, 1, "could not read the changes since HEAD"},
+		{"malformed -run-id", false, []string{"-url", cliURL, "-run-id", "nightly run"}, 1, `invalid -run-id: run ID "nightly run" holds a space`},
+		{"malformed -verify-after-fix", false, []string{"-dir", "{dir}", "-fix", "-verify-after-fix", "yes"}, 1, `invalid -verify-after-fix "yes"`},
+		{"-output pr-comment with -url", false, []string{"-url", cliURL, "-output", "pr-comment"}, 1, "-output pr-comment can only be used with -dir flag"},
+		{"-output pr-comment with -fix", false, []string{"-dir", "{dir}", "-output", "pr-comment", "-fix"}, 1, "cannot be used with -output pr-comment"},
+		{"-link-template without -output pr-comment", false, []string{"-dir", "{dir}", "-link-template", "https://example.com/{path}"}, 1, "-link-template flag can only be used with -output pr-comment"},
+		{"-list-anchors of a missing page", false, []string{"-list-anchors", strings.Replace(cliURL, "4.16", "4.12", 1)}, 1, "page not found"},
+		{"-list-anchors of a page redirected away", false, []string{"-list-anchors", strings.Replace(upToDate, "ingress#configuring-ingress", "ingress-legacy", 1)}, 1, "page not found: redirects to"},
+		{"-list-anchors of another URL", false, []string{"-list-anchors", "https://docs.redhat.com/en/products"}, exitNotDocURL, "not an OCP documentation URL"},
+		{"-list-anchors not requested", true, []string{"-list-anchors", cliURL}, exitCheckFailed, "untrusted certificate (see -ca-cert)"},
+		{"-list-anchors with -dir", false, []string{"-list-anchors", cliURL, "-dir", "{dir}"}, 1, "-list-anchors flag cannot be used with -url or -dir"},
+		{"-list-anchors with -output tsv", false, []string{"-list-anchors", cliURL, "-output", "tsv"}, 1, "-list-anchors flag can only be used with -output text or json"},
+		{"-link-template without {path}", false, []string{"-dir", "{dir}", "-output", "pr-comment", "-link-template", "https://example.com/#L{line}"}, 1, "has no {path} placeholder"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ca := caFile
+			if tt.untrusted {
+				ca = ""
+			}
+			_, stderr, code := runCLI(t, proxyURL, ca, withDocsDir(t, tt.args)...)
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d\nstderr: %s", code, tt.wantCode, stderr)
+			}
+			if !strings.Contains(string(stderr), tt.wantStderr) {
+				t.Errorf("stderr = %q, want it to mention %q", stderr, tt.wantStderr)
+			}
+		})
+	}
+}
+
+// Every report, log record, diagnostic and metrics file of a run carries
+// its ID, -run-id or a new one per run
+func TestCLI_RunID(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the CLI in separate processes")
+	}
+	proxyURL, caFile := fakeDocs(t)
+	const runID = "pipeline-42"
+
+	metrics := filepath.Join(t.TempDir(), "ocpdoc.prom")
+	stdout, stderr, _ := runCLI(t, proxyURL, caFile, withDocsDir(t, []string{
+		"-run-id", runID, "-output", "json", "-error-format", "json", "-verbose", "-metrics-file", metrics, "-dir", "{dir}",
+	})...)
+	var batch output.Batch
+	decodeExactly(t, stdout, &batch)
+	if batch.RunID != runID {
+		t.Errorf("report run_id = %q, want %q", batch.RunID, runID)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(stderr), "\n"), "\n")
+	if len(lines) == 0 || lines[0] == "" {
+		t.Fatal("no log records on stderr with -verbose")
+	}
+	for _, line := range lines {
+		var record struct {
+			RunID string `json:"run_id"`
+		}
+		if err := json.Unmarshal([]byte(line), &record); err != nil || record.RunID != runID {
+			t.Errorf("stderr line %q has run_id %q, want %q", line, record.RunID, runID)
+		}
+	}
+	data, err := os.ReadFile(metrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `ocpdoc_run_info{run_id="`+runID+`",root="`) {
+		t.Errorf("metrics file has no run info:\n%s", data)
+	}
+
+	stdout, _, _ = runCLI(t, proxyURL, caFile, "-run-id", runID, "-output", "tsv", "-url", cliURL)
+	checkTSV(t, stdout)
+	if !strings.HasSuffix(string(stdout), "\t"+runID+"\n") {
+		t.Errorf("tsv line %q does not end with the run ID", stdout)
+	}
+
+	stdout, _, _ = runCLI(t, proxyURL, caFile, withDocsDir(t, []string{"-run-id", runID, "-dir", "{dir}"})...)
+	if !bytes.Contains(stdout, []byte("Run: "+runID+"\n")) {
+		t.Errorf("text report has no run ID:\n%s", stdout)
+	}
+
+	// Without -run-id, every run gets its own
+	ids := make(map[string]bool)
+	for range 2 {
+		stdout, _, _ := runCLI(t, proxyURL, caFile, "-output", "json", "-url", cliURL)
+		var result output.Result
+		decodeExactly(t, stdout, &result)
+		if result.RunID == "" || ids[result.RunID] {
+			t.Errorf("run_id = %q, want a new ID per run", result.RunID)
+		}
+		ids[result.RunID] = true
+	}
+}
+
+// A campaign is started, then run in steps until complete, keeping its
+// state between runs
+func TestCLI_Campaign(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the CLI in separate processes")
+	}
+	proxyURL, caFile := fakeDocs(t)
+	args := withDocsDir(t, []string{"-dir", "{dir}"})
+	readme := filepath.Join(args[1], "README.md")
+	state := filepath.Join(t.TempDir(), "campaign.json")
+	fixed := strings.Replace(cliURL, "4.16", "4.17", 1)
+
+	runs := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStderr []string
+		wantURL    string
+	}{
+		{"not started", []string{"-campaign", state}, 1, []string{"start it with -campaign-target"}, cliURL},
+		{"started", []string{"-campaign", state, "-campaign-target", "4.17"}, 0, []string{"Campaign: 0/1 group(s) applied (0%) in 0 step(s)"}, cliURL},
+		{"other target", []string{"-campaign", state, "-campaign-target", "4.18"}, 1, []string{"does not match the target version 4.17"}, cliURL},
+		// The files a step writes are verified like those of -fix
+		{"step", []string{"-campaign", state, "-campaign-step", "1"}, 0, []string{"Campaign: 1/1 group(s) applied (100%) in 1 step(s)", "Verified 1 file(s) and 1 new URL(s)"}, fixed},
+		{"complete", []string{"-campaign", state, "-campaign-step", "1"}, 0, []string{"Campaign complete"}, fixed},
+	}
+	for _, run := range runs {
+		_, stderr, code := runCLI(t, proxyURL, caFile, append(args, run.args...)...)
+		if code != run.wantCode {
+			t.Errorf("%s: exit code = %d, want %d\nstderr: %s", run.name, code, run.wantCode, stderr)
+		}
+		for _, want := range run.wantStderr {
+			if !strings.Contains(string(stderr), want) {
+				t.Errorf("%s: stderr = %q, want it to mention %q", run.name, stderr, want)
+			}
+		}
+		content, err := os.ReadFile(readme)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(content), run.wantURL+")") {
+			t.Errorf("%s: README = %q, want it to link %s", run.name, content, run.wantURL)
+		}
+	}
+
+	data, err := os.ReadFile(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved struct {
+		TargetVersion string `json:"target_version"`
+		Steps         int
+		Groups        []struct{ Status string }
+	}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved.TargetVersion != "4.17" || saved.Steps != 1 || len(saved.Groups) != 1 || saved.Groups[0].Status != "applied" {
+		t.Errorf("campaign state = %s", data)
+	}
+}
+
+// A change that adds an up to date URL to a file with an outdated one
+// fails the run unless only the lines it added are checked
+func TestCLI_SuggestedAnchors(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the CLI in separate processes")
+	}
+	proxyURL, caFile := fakeDocs(t)
+	misspelled := strings.Replace(cliURL, "#configuring-ingress", "#configuring-ingres", 1)
+	suggested := strings.Replace(cliURL, "4.16", "4.17", 1)
+
+	stdout, stderr, code := runCLI(t, proxyURL, caFile, "-url", misspelled, "-width", "200")
+	if code != 1 {
+		t.Errorf("exit code = %d, want 1\nstderr: %s", code, stderr)
+	}
+	if want := "Closest anchor at 4.17: " + suggested; !strings.Contains(string(stdout), want) {
+		t.Errorf("stdout lacks %q:\n%s", want, stdout)
+	}
+
+	stdout, _, _ = runCLI(t, proxyURL, caFile, "-url", misspelled, "-output", "json")
+	var result output.Result
+	decodeExactly(t, stdout, &result)
+	if len(result.CheckedVersions) != 1 || !reflect.DeepEqual(result.CheckedVersions[0].SuggestedAnchors, []string{"configuring-ingress"}) {
+		t.Errorf("checked_versions = %+v, want 4.17 suggesting configuring-ingress", result.CheckedVersions)
+	}
+}
+
+func TestCLI_Generated(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the CLI in separate processes")
+	}
+	proxyURL, caFile := fakeDocs(t)
+	upToDate := strings.Replace(cliURL, "4.16", "4.17", 1)
+	dir := t.TempDir()
+	readme := filepath.Join(dir, "README.md")
+	if err := os.WriteFile(readme, []byte("See [ingress]("+upToDate+").\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// An API reference dump linking the outdated URL over and over
+	crds := filepath.Join(dir, "crds.md")
+	generated := "<!-- Code generated by crd-ref-docs. DO NOT EDIT. -->\n" + strings.Repeat("- "+cliURL+"\n", 3)
+	if err := os.WriteFile(crds, []byte(generated), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, code := runCLI(t, proxyURL, caFile, "-dir", dir, "-output", "json")
+	if code != 0 {
+		t.Errorf("exit code = %d, want 0\nstderr: %s", code, stderr)
+	}
+	var batch output.Batch
+	decodeExactly(t, stdout, &batch)
+	if len(batch.Results) != 1 || batch.Results[0].OriginalURL != upToDate {
+		t.Errorf("report checked %+v, want only %s", batch.Results, upToDate)
+	}
+	var want []output.NotChecked
+	for line := 2; line <= 4; line++ {
+		want = append(want, output.NotChecked{URL: cliURL, File: crds, Line: line, Reason: output.ReasonGenerated})
+	}
+	if !reflect.DeepEqual(batch.NotChecked, want) {
+		t.Errorf("not_checked = %+v, want %+v", batch.NotChecked, want)
+	}
+	if got := batch.ScanStats.FilesGenerated; !reflect.DeepEqual(got, map[string]int{scanner.GeneratedHeader: 1}) {
+		t.Errorf("files_generated = %v, want one header", got)
+	}
+
+	// -fix leaves the generated file to its generator
+	if _, stderr, code := runCLI(t, proxyURL, caFile, "-dir", dir, "-fix"); code != 0 {
+		t.Errorf("-fix: exit code = %d, want 0\nstderr: %s", code, stderr)
+	}
+	if content, _ := os.ReadFile(crds); string(content) != generated {
+		t.Errorf("-fix changed the generated file:\n%s", content)
+	}
+
+	if _, stderr, code := runCLI(t, proxyURL, caFile, "-dir", dir, "-fix", "-fix-generated"); code != 0 {
+		t.Errorf("-fix-generated: exit code = %d, want 0\nstderr: %s", code, stderr)
+	}
+	if content, _ := os.ReadFile(crds); strings.Contains(string(content), cliURL) || strings.Count(string(content), upToDate) != 3 {
+		t.Errorf("-fix-generated left the generated file:\n%s", content)
+	}
+}
+
+func TestCLI_ProductContext(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the CLI in separate processes")
+	}
+	proxyURL, caFile := fakeDocs(t)
+	upToDate := strings.Replace(cliURL, "4.16", "4.17", 1)
+	dir := t.TempDir()
+	files := map[string]string{
+		"README.md":        "See [ingress](" + upToDate + ").\n",
+		"docs/rosa/dns.md": "# DNS on ROSA\n\nSee [ingress](" + upToDate + ").\n",
+		"docs/osd/dns.md":  "---\nproduct: openshift_dedicated\n---\n\nSee [ingress](" + upToDate + ").\n",
+		"docs/rosa/ocp.md": "---\nproduct: openshift_container_platform\n---\n\nSee [ingress](" + upToDate + ").\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config := filepath.Join(t.TempDir(), "products.json")
+	contexts := `{"front_matter": ["product"], "paths": [{"glob": "docs/rosa/**", "product": "red_hat_openshift_service_on_aws"}],
+		"versions": {"red_hat_openshift_service_on_aws": "4", "openshift_dedicated": "4"}}`
+	if err := os.WriteFile(config, []byte(contexts), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Advisories never fail the run
+	stdout, stderr, code := runCLI(t, proxyURL, caFile, "-dir", dir, "-output", "json", "-check-product-context", "-product-context", config)
+	if code != 0 {
+		t.Errorf("exit code = %d, want 0\nstderr: %s", code, stderr)
+	}
+	var batch output.Batch
+	decodeExactly(t, stdout, &batch)
+	want := []output.WrongProductLink{
+		{URL: upToDate, File: filepath.Join(dir, "docs", "osd", "dns.md"), Line: 5, Column: 15, Product: "openshift_container_platform", DeclaredProduct: "openshift_dedicated", Severity: output.SeverityInfo},
+		{URL: upToDate, File: filepath.Join(dir, "docs", "rosa", "dns.md"), Line: 3, Column: 15, Product: "openshift_container_platform", DeclaredProduct: "red_hat_openshift_service_on_aws",
+			Equivalent: "https://docs.redhat.com" + cliROSAPath + "#configuring-ingress", Severity: output.SeverityInfo},
+	}
+	if !reflect.DeepEqual(batch.WrongProductLinks, want) {
+		t.Errorf("wrong_product_links = %+v, want %+v", batch.WrongProductLinks, want)
+	}
+
+	stdout, _, _ = runCLI(t, proxyURL, caFile, "-dir", dir, "-check-product-context", "-product-context", config, "-width", "300")
+	for _, line := range []string{
+		"Possible wrong-product links (advisory, never fixed):",
+		"2 possible wrong-product link(s)",
+		"→ https://docs.redhat.com" + cliROSAPath + "#configuring-ingress",
+		"no equivalent page found under openshift_dedicated",
+	} {
+		if !strings.Contains(string(stdout), line) {
+			t.Errorf("text report lacks %q:\n%s", line, stdout)
+		}
+	}
+
+	// The built-in context only reads the front matter
+	stdout, _, _ = runCLI(t, proxyURL, caFile, "-dir", dir, "-output", "json", "-check-product-context")
+	batch = output.Batch{}
+	decodeExactly(t, stdout, &batch)
+	if len(batch.WrongProductLinks) != 1 || batch.WrongProductLinks[0].DeclaredProduct != "openshift_dedicated" {
+		t.Errorf("wrong_product_links with the built-in context = %+v, want the front matter of docs/osd/dns.md", batch.WrongProductLinks)
+	}
+
+	stdout, _, _ = runCLI(t, proxyURL, caFile, "-dir", dir, "-output", "json")
+	batch = output.Batch{}
+	decodeExactly(t, stdout, &batch)
+	if batch.WrongProductLinks != nil {
+		t.Errorf("wrong_product_links without -check-product-context = %+v, want none", batch.WrongProductLinks)
+	}
+}
+
+func TestCLI_TrackingParams(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the CLI in separate processes")
+	}
+	proxyURL, caFile := fakeDocs(t)
+	upToDate := strings.Replace(cliURL, "4.16", "4.17", 1)
+	tracked := strings.Replace(upToDate, "#", "?sc_cid=701f2000001OH7JAAW#", 1)
+	dir := t.TempDir()
+	readme := filepath.Join(dir, "README.md")
+	content := "See [ingress](" + upToDate + ") and [the same](" + tracked + ").\n"
+	if err := os.WriteFile(readme, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both spellings are checked once
+	stdout, stderr, code := runCLI(t, proxyURL, caFile, "-dir", dir, "-output", "json")
+	if code != 0 {
+		t.Errorf("exit code = %d, want 0\nstderr: %s", code, stderr)
+	}
+	var batch output.Batch
+	decodeExactly(t, stdout, &batch)
+	if batch.TotalCount != 1 || batch.Results[0].OriginalURL != upToDate {
+		t.Errorf("report checked %+v, want only %s", batch.Results, upToDate)
+	}
+
+	if _, stderr, code := runCLI(t, proxyURL, caFile, "-dir", dir, "-fix"); code != 0 {
+		t.Errorf("-fix: exit code = %d, want 0\nstderr: %s", code, stderr)
+	}
+	if got, _ := os.ReadFile(readme); string(got) != content {
+		t.Errorf("-fix changed an up-to-date link:\n%s", got)
+	}
+	if _, stderr, code := runCLI(t, proxyURL, caFile, "-dir", dir, "-fix", "-normalize"); code != 0 {
+		t.Errorf("-normalize: exit code = %d, want 0\nstderr: %s", code, stderr)
+	}
+	if got, _ := os.ReadFile(readme); strings.Contains(string(got), "sc_cid") || strings.Count(string(got), upToDate) != 2 {
+		t.Errorf("-normalize left the tracking parameter:\n%s", got)
+	}
+}
+
+func TestCLI_AnchorPolicy(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the CLI in separate processes")
+	}
+	proxyURL, caFile := fakeDocs(t)
+	renamed := "https://docs.redhat.com" + strings.Replace(cliShardingPath, "%s", "4.16", 1) + "#nw-ingress-sharding"
+	page := "https://docs.redhat.com" + strings.Replace(cliShardingPath, "%s", "4.17", 1)
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		want     []string
+	}{
+		{"strict by default", nil, 0, []string{"UP TO DATE (version 4.16)", "Anchor policy: strict"}},
+		{"page-only", []string{"-anchor-policy", "page-only"}, 1, []string{
+			"OUTDATED", "Anchor policy: page-only", "Anchor #nw-ingress-sharding missing at 4.17 (closest: #nw-ingress-sharding_configuring-ingress)",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, code := runCLI(t, proxyURL, caFile, append([]string{"-url", renamed, "-width", "300"}, tt.args...)...)
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d\nstderr: %s", code, tt.wantCode, stderr)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(stdout), want) {
+					t.Errorf("stdout lacks %q:\n%s", want, stdout)
+				}
+			}
+		})
+	}
+
+	dir := t.TempDir()
+	readme := filepath.Join(dir, "README.md")
+	content := "See [sharding](" + renamed + ").\n"
+	if err := os.WriteFile(readme, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, code := runCLI(t, proxyURL, caFile, "-dir", dir, "-anchor-policy", "page-only", "-output", "json")
+	if code != 1 {
+		t.Errorf("exit code = %d, want 1\nstderr: %s", code, stderr)
+	}
+	var batch output.Batch
+	decodeExactly(t, stdout, &batch)
+	if r := batch.Results[0]; r.AnchorPolicy != "page-only" || r.BestSuggestion == nil || !r.BestSuggestion.AnchorMissing {
+		t.Errorf("result = %+v, want a page-only best suggestion missing the anchor", r)
+	}
+
+	// -fix leaves the link for review unless -fix-aggressive drops the anchor
+	_, stderr, _ = runCLI(t, proxyURL, caFile, "-dir", dir, "-anchor-policy", "page-only", "-fix")
+	if got, _ := os.ReadFile(readme); string(got) != content || !strings.Contains(string(stderr), "page at 4.17 lacks #nw-ingress-sharding") {
+		t.Errorf("-fix changed the link or did not flag it:\n%s\n%s", got, stderr)
+	}
+	if _, stderr, code := runCLI(t, proxyURL, caFile, "-dir", dir, "-anchor-policy", "page-only", "-fix", "-fix-aggressive"); code != 0 {
+		t.Errorf("-fix-aggressive: exit code = %d, want 0\nstderr: %s", code, stderr)
+	}
+	if got, _ := os.ReadFile(readme); string(got) != "See [sharding]("+page+").\n" {
+		t.Errorf("-fix-aggressive wrote:\n%s", got)
+	}
+}
+
+func TestCLI_HotspotCommand(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the CLI in separate processes")
+	}
+	proxyURL, caFile := fakeDocs(t)
+
+	// The command fixing just the file picks the same target as this run
+	args := withDocsDir(t, []string{"-dir", "{dir}", "-output", "json", "-hotspot-threshold", "1", "-min-version", "4.16", "-max-version", "4.17", "-exclude-versions", "4.15"})
+	stdout, stderr, code := runCLI(t, proxyURL, caFile, args...)
+	if code != 1 {
+		t.Errorf("exit code = %d, want 1\nstderr: %s", code, stderr)
+	}
+	var batch output.Batch
+	decodeExactly(t, stdout, &batch)
+	if len(batch.Hotspots) != 1 {
+		t.Fatalf("hotspots = %+v, want the README", batch.Hotspots)
+	}
+	for _, want := range []string{"-as-of 4.17", "-max-version 4.17", "-min-version 4.16", "-exclude-versions 4.15"} {
+		if !strings.Contains(batch.Hotspots[0].Command, want) {
+			t.Errorf("hotspot command %q lacks %q", batch.Hotspots[0].Command, want)
+		}
+	}
+}
+
+func TestCLI_SkippedVersions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the CLI in separate processes")
+	}
+	proxyURL, caFile := fakeDocs(t)
+	const skipped = "– Skipped: above the maximum version 4.16"
+
+	// 4.17 is above the range, not missing
+	stdout, stderr, code := runCLI(t, proxyURL, caFile, "-url", cliURL, "-max-version", "4.16", "-verbose")
+	if code != 0 {
+		t.Errorf("exit code = %d, want 0\nstderr: %s", code, stderr)
+	}
+	if !strings.Contains(string(stdout), skipped+" Version 4.17") || strings.Contains(string(stdout), "Not found") {
+		t.Errorf("stdout does not show 4.17 as skipped:\n%s", stdout)
+	}
+
+	for _, verbose := range []bool{false, true} {
+		args := []string{"-url", cliURL, "-max-version", "4.16", "-output", "json"}
+		if verbose {
+			args = append(args, "-verbose")
+		}
+		stdout, stderr, _ := runCLI(t, proxyURL, caFile, args...)
+		var result output.Result
+		decodeExactly(t, stdout, &result)
+		if !verbose {
+			if len(result.CheckedVersions) != 0 {
+				t.Errorf("checked_versions without -verbose = %+v, want none\nstderr: %s", result.CheckedVersions, stderr)
+			}
+			continue
+		}
+		if len(result.CheckedVersions) != 1 || result.CheckedVersions[0].SkippedReason != "above the maximum version 4.16" {
+			t.Errorf("checked_versions = %+v, want 4.17 skipped above the maximum\nstderr: %s", result.CheckedVersions, stderr)
+		}
+	}
+}
+
+func TestCLI_ListAnchors(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the CLI in separate processes")
+	}
+	proxyURL, caFile := fakeDocs(t)
+
+	stdout, stderr, code := runCLI(t, proxyURL, caFile, "-list-anchors", cliURL)
+	if code != 0 {
+		t.Errorf("exit code = %d, want 0\nstderr: %s", code, stderr)
+	}
+	if want := "configuring-ingress\th2\tIngress\n"; string(stdout) != want {
+		t.Errorf("stdout = %q, want %q", stdout, want)
+	}
+
+	stdout, stderr, code = runCLI(t, proxyURL, caFile, "-list-anchors", cliURL, "-json", "-run-id", "anchors-1")
+	if code != 0 {
+		t.Errorf("-json: exit code = %d, want 0\nstderr: %s", code, stderr)
+	}
+	var list output.AnchorList
+	decodeExactly(t, stdout, &list)
+	want := output.AnchorList{
+		RunID:   "anchors-1",
+		URL:     cliURL,
+		Anchors: []output.Anchor{{ID: "configuring-ingress", Tag: "h2", Heading: "Ingress"}},
+	}
+	if !reflect.DeepEqual(list, want) {
+		t.Errorf("-json report = %+v, want %+v", list, want)
+	}
+}
+
+func TestCLI_PRComment(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the CLI in separate processes")
+	}
+	proxyURL, caFile := fakeDocs(t)
+	dir := t.TempDir()
+	readme := filepath.Join(dir, "README.md")
+	if err := os.WriteFile(readme, []byte("# Ingress\n\nSee [ingress]("+cliURL+").\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	args := []string{"-dir", dir, "-output", "pr-comment", "-link-template", "https://github.com/org/repo/blob/0a1b2c3/{path}#L{line}"}
+	stdout, stderr, code := runCLI(t, proxyURL, caFile, append(args, "-run-id", "push-1")...)
+	if code != 1 {
+		t.Errorf("exit code = %d, want 1\nstderr: %s", code, stderr)
+	}
+	// The scope hash must not change between pushes, or the bot would add
+	// a comment per push instead of replacing its own
+	marker := output.PRCommentMarker(output.ScopeHash(dir, "", "false", "")) + "\n"
+	if !strings.HasPrefix(string(stdout), marker) {
+		t.Errorf("comment does not start with %q:\n%s", marker, stdout)
+	}
+	link := "https://github.com/org/repo/blob/0a1b2c3/" + strings.TrimPrefix(filepath.ToSlash(readme), "/")
+	for _, want := range []string{"1 finding(s) in 1 file(s)", "**outdated**: 4.16 → 4.17", "#L3)", link, "Run push-1."} {
+		if !strings.Contains(string(stdout), want) {
+			t.Errorf("comment does not mention %q:\n%s", want, stdout)
+		}
+	}
+
+	next, _, _ := runCLI(t, proxyURL, caFile, append(args, "-run-id", "push-2")...)
+	if !strings.HasPrefix(string(next), marker) {
+		t.Errorf("comment of the next push does not start with %q:\n%s", marker, next)
+	}
+	other, _, _ := runCLI(t, proxyURL, caFile, append(args, "-shard", "1/2")...)
+	if strings.HasPrefix(string(other), marker) {
+		t.Errorf("comment of another scope starts with %q", marker)
+	}
+
+	// Once fixed, the comment says so instead of disappearing
+	upToDate := strings.Replace(cliURL, "4.16", "4.17", 1)
+	if err := os.WriteFile(readme, []byte("See [ingress]("+upToDate+").\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr, code = runCLI(t, proxyURL, caFile, args...)
+	if code != 0 {
+		t.Errorf("fixed: exit code = %d, want 0\nstderr: %s", code, stderr)
+	}
+	if !strings.HasPrefix(string(stdout), marker) || !strings.Contains(string(stdout), "no findings") {
+		t.Errorf("comment once fixed:\n%s", stdout)
+	}
+}
+
+func TestCLI_GitNewOnly(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the CLI in separate processes")
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is needed to build the repository")
+	}
+	proxyURL, caFile := fakeDocs(t)
+	args := withDocsDir(t, []string{"-dir", "{dir}"})
+	dir := args[1]
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	git("add", "-A")
+	git("commit", "-q", "-m", "base")
+	upToDate := strings.Replace(cliURL, "4.16", "4.17", 1)
+	readme := filepath.Join(dir, "README.md")
+	content, err := os.ReadFile(readme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The new line goes first, so that the outdated URL moves down
+	if err := os.WriteFile(readme, append([]byte("See [ingress]("+upToDate+").\n\n"), content...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, stderr, code := runCLI(t, proxyURL, caFile, append(args, "-git-diff", "HEAD")...)
+	if code != 1 {
+		t.Errorf("-git-diff: exit code = %d, want 1\nstderr: %s", code, stderr)
+	}
+
+	stdout, stderr, code := runCLI(t, proxyURL, caFile, append(args, "-git-diff", "HEAD", "-git-new-only", "-output", "json")...)
+	if code != 0 {
+		t.Errorf("-git-new-only: exit code = %d, want 0\nstderr: %s", code, stderr)
+	}
+	var batch output.Batch
+	decodeExactly(t, stdout, &batch)
+	if len(batch.Results) != 1 || batch.Results[0].OriginalURL != upToDate {
+		t.Errorf("report checked %+v, want only %s", batch.Results, upToDate)
+	}
+	want := []output.NotChecked{{URL: cliURL, File: readme, Line: 3, Reason: output.ReasonPreexisting}}
+	if !reflect.DeepEqual(batch.NotChecked, want) {
+		t.Errorf("not_checked = %+v, want %+v", batch.NotChecked, want)
+	}
+}
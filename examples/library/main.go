@@ -0,0 +1,89 @@
+// Command library scans a directory for OpenShift documentation URLs, checks
+// them and rewrites the outdated ones, using only the supported library API
+// of ocp-doc-checker:
+//
+//	go run ./examples/library ./docs
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/checker"
+	"github.com/sebrandon1/ocp-doc-checker/pkg/fixer"
+	"github.com/sebrandon1/ocp-doc-checker/pkg/scanner"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: library <dir>")
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := run(ctx, checker.NewChecker(), os.Args[1], os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run scans dir, checks every URL found with c and fixes the occurrences of
+// the outdated ones, reporting each to w
+func run(ctx context.Context, c *checker.Checker, dir string, w io.Writer) error {
+	// Scan: every documentation URL, with the places it occurs at
+	locations, err := scanner.New().Scan(dir)
+	if err != nil {
+		return err
+	}
+	var urls []string
+	byURL := make(map[string]scanner.Location)
+	for _, loc := range locations {
+		// Placeholders and look-alike hosts are never checked
+		if loc.Placeholder || loc.Suspicious != "" {
+			continue
+		}
+		urls = append(urls, loc.URL)
+		byURL[loc.URL] = loc
+	}
+
+	// Check: one result per URL, nil for those that could not be checked
+	results, err := c.CheckAllContext(ctx, urls)
+	var checkErrs checker.CheckErrors
+	if err != nil && !errors.As(err, &checkErrs) {
+		return err
+	}
+	var checked []*checker.CheckResult
+	for i, result := range results {
+		if result == nil {
+			fmt.Fprintf(w, "not checked: %s: %v\n", urls[i], checkErrs[i])
+			continue
+		}
+		checked = append(checked, result)
+	}
+
+	// Fix: replace each outdated URL with its best suggestion
+	files, targets := fixer.Targets(checked, byURL, "", false)
+	for _, path := range files {
+		plan, err := fixer.FixFile(path, targets[path], fixer.Options{}, true)
+		if err != nil {
+			return err
+		}
+		for _, err := range plan.Errors {
+			fmt.Fprintf(w, "not fixed: %v\n", err)
+		}
+		for _, change := range plan.Changes {
+			occ, r := change.Occurrence, change.Replacement
+			if len(change.Edits) == 0 {
+				fmt.Fprintf(w, "%s:%d: left for review (%s): %s\n", occ.Path, occ.Line, change.Outcome, r.OldURL)
+				continue
+			}
+			fmt.Fprintf(w, "%s:%d: %s → %s: %s\n", occ.Path, occ.Line, r.OldVersion, r.NewVersion, r.NewURL)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/checker"
+)
+
+// docsTransport answers requests from canned pages keyed by URL without a
+// fragment, 404 for the others, without any network
+type docsTransport map[string]string
+
+func (pages docsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp := &http.Response{
+		StatusCode: http.StatusNotFound,
+		Header:     make(http.Header),
+		Body:       io.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}
+	if body, ok := pages[req.URL.String()]; ok {
+		resp.StatusCode = http.StatusOK
+		resp.Body = io.NopCloser(strings.NewReader(body))
+	}
+	return resp, nil
+}
+
+func TestRun(t *testing.T) {
+	const docURL = "https://docs.redhat.com/en/documentation/openshift_container_platform/%s/html/networking/index"
+	page := `<html><body><h2 id="ingress">Ingress</h2></body></html>`
+
+	c := checker.NewChecker()
+	c.SetVersions([]string{"4.16", "4.17"})
+	c.SetTransport(docsTransport{
+		fmt.Sprintf(docURL, "4.16"): page,
+		fmt.Sprintf(docURL, "4.17"): page,
+	})
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "README.md")
+	content := "See [Ingress](" + fmt.Sprintf(docURL, "4.16") + "#ingress) and [current](" + fmt.Sprintf(docURL, "4.17") + ").\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := run(context.Background(), c, dir, &out); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "See [Ingress](" + fmt.Sprintf(docURL, "4.17") + "#ingress) and [current](" + fmt.Sprintf(docURL, "4.17") + ").\n"
+	if string(got) != want {
+		t.Errorf("file after run() = %q, want %q", got, want)
+	}
+	wantOut := path + ":1: 4.16 → 4.17: " + fmt.Sprintf(docURL, "4.17") + "#ingress\n"
+	if out.String() != wantOut {
+		t.Errorf("run() output = %q, want %q", out.String(), wantOut)
+	}
+}
@@ -3,3 +3,9 @@ module github.com/sebrandon1/ocp-doc-checker
 go 1.25.4
 
 require golang.org/x/net v0.57.0
+
+require gopkg.in/yaml.v3 v3.0.1
+
+require golang.org/x/time v0.15.0
+
+require golang.org/x/text v0.40.0 // indirect
@@ -1,38 +1,167 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/fs"
+	"log/slog"
+	"math"
 	"os"
 	"path/filepath"
-	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/sebrandon1/ocp-doc-checker/pkg/checker"
+	"github.com/sebrandon1/ocp-doc-checker/pkg/fixer"
+	"github.com/sebrandon1/ocp-doc-checker/pkg/gitdiff"
+	"github.com/sebrandon1/ocp-doc-checker/pkg/output"
+	"github.com/sebrandon1/ocp-doc-checker/pkg/parser"
+	"github.com/sebrandon1/ocp-doc-checker/pkg/scanner"
 )
 
-// URLLocation tracks where a URL appears in the codebase
-type URLLocation struct {
-	URL   string
-	Files []string // Files where this URL appears
-}
-
 var (
 	version = "dev"
 	commit  = "none"
 	date    = "unknown"
 
 	// Flags
-	urlFlag          = flag.String("url", "", "OCP documentation URL to check")
-	dirFlag          = flag.String("dir", "", "Directory or file to scan for OCP documentation URLs")
-	fixFlag          = flag.Bool("fix", false, "Automatically fix outdated URLs in files (only works with -dir)")
-	verboseFlag      = flag.Bool("verbose", false, "Enable verbose output")
-	jsonFlag         = flag.Bool("json", false, "Output results in JSON format")
-	versionFlag      = flag.Bool("version", false, "Print version information")
-	allAvailableFlag = flag.Bool("all-available", false, "Show all available newer versions (default: latest only)")
+	urlFlag               = flag.String("url", "", "OCP documentation URL to check")
+	dirFlag               = flag.String("dir", "", "Directory or file to scan for OCP documentation URLs")
+	listAnchorsFlag       = flag.String("list-anchors", "", "Print the anchors of the page of this OCP documentation URL instead of checking it: one per line, sorted, with the element and the text of headings")
+	fixFlag               = flag.Bool("fix", false, "Automatically fix outdated URLs in files (only works with -dir)")
+	verboseFlag           = flag.Bool("verbose", false, "Enable verbose output")
+	jsonFlag              = flag.Bool("json", false, "Output results in JSON format (same as -output json)")
+	outputFlag            = flag.String("output", "text", "Output format: text, json, tsv, pr-comment or json-legacy (deprecated). tsv prints one tab-separated line per URL with the columns "+output.TSVColumnHelp()+"; pr-comment prints a Markdown pull request comment for a bot to upsert")
+	linkTemplateFlag      = flag.String("link-template", "", "With -output pr-comment, link each finding to this URL, replacing {path} and {line}, e.g. https://github.com/org/repo/blob/<head sha>/{path}#L{line}")
+	noHeaderFlag          = flag.Bool("no-header", false, "With -output tsv, leave out the line of column names")
+	versionFlag           = flag.Bool("version", false, "Print version information")
+	allAvailableFlag      = flag.Bool("all-available", false, "Show all available newer versions in text output (default: latest only); JSON always lists them all")
+	ciModeFlag            = flag.String("ci-mode", "auto", "CI log format for directory scans: auto, github or none")
+	placeholderFlag       stringList
+	historicalFlag        stringList
+	generatedFlag         stringList
+	trackingParamFlag     stringList
+	slugMapFlag           = flag.String("slug-map", "", "JSON file of page slug renames replacing the built-in map, which is empty for now")
+	allowHostFlag         stringList
+	pinFlag               stringList
+	aliasFlag             stringList
+	pinAllHostsFlag       = flag.Bool("pin-all-hosts", false, "Apply -pin-cert-sha256 to every allowed host, not only docs.redhat.com")
+	caCertFlag            = flag.String("ca-cert", "", "PEM file of CA certificates to trust on top of the system ones, e.g. of a TLS-intercepting proxy")
+	insecureFlag          = flag.Bool("insecure-skip-verify", false, "Do not verify TLS certificates (insecure; prefer -ca-cert)")
+	networkFlag           = flag.String("network", "auto", "Address family to connect over: auto, ipv4 or ipv6; auto retries a connection that timed out over the other family")
+	anchorPolicyFlag      = flag.String("anchor-policy", "strict", "Whether a newer version whose page lacks the URL's anchor counts as newer: strict, or page-only to count the page and have fixes drop the anchor")
+	upgradeEffortFlag     = flag.Bool("report-upgrade-effort", false, "Summarize the upgrade effort per (current version → latest working version) pair")
+	deepScanFlag          = flag.Bool("deep-scan", false, "Also search YAML and JSON files for URLs hidden in base64 or URL-encoded values")
+	allowedTargetsFlag    = flag.String("allowed-target-versions", "", "Comma-separated versions or version aliases allowed as upgrade targets, or eus for the even-minor releases (default: all)")
+	preferFormatFlag      = flag.String("fix-prefer-format", "", "With -fix, -check-fix, -fix-changesets or -campaign, rewrite other spellings of a section to this format: html or html-single")
+	checkFixFlag          = flag.Bool("check-fix", false, "Report the files -fix would change without writing them; exit 1 if any would change")
+	verifyAfterFixFlag    = flag.String("verify-after-fix", "auto", "After -fix or -campaign-step writes files, read them back and request every new URL to confirm the fixes: always, never, or auto for runs fixing fewer than 100 URLs")
+	changesetsFlag        = flag.String("fix-changesets", "", "Instead of fixing files in place, write the fixes to this directory as one patch per (document, target version) plus an index")
+	campaignFlag          = flag.String("campaign", "", "Fix the outdated URLs of -dir over several runs as an upgrade campaign kept in this state file; without -campaign-step, only report its progress")
+	campaignTargetFlag    = flag.String("campaign-target", "", "Version a new -campaign upgrades links to, e.g. 4.18; URLs are checked as if it were the latest release")
+	campaignStepFlag      = flag.Int("campaign-step", 0, "With -campaign, apply the fixes of the next N groups of one guide and target version each, failed groups first")
+	fixLinkTextFlag       = flag.Bool("fix-link-text", false, "With -fix, -check-fix, -fix-changesets or -campaign, also update the old version in Markdown link text instead of skipping those links")
+	crossDocumentFlag     = flag.Bool("allow-cross-document-fix", false, "With -fix, -check-fix, -fix-changesets or -campaign, also fix URLs whose newer version is served from another guide instead of leaving them for review")
+	fixAggressiveFlag     = flag.Bool("fix-aggressive", false, "With -fix, -check-fix, -fix-changesets or -campaign, also fix URLs whose section -search-sibling-pages found on another page of the guide, or whose newer page lacks the anchor under -anchor-policy page-only, instead of leaving them for review")
+	normalizeFlag         = flag.Bool("normalize", false, "With -fix, -check-fix, -fix-changesets or -campaign, also rewrite up-to-date URLs with a non-canonical version, a duplicated fragment, no locale or tracking parameters to their normalized spelling")
+	hotspotFlag           = flag.Int("hotspot-threshold", 5, "Call out files with at least this many outdated URLs and how to fix just that file (0 disables)")
+	strictEmptyFlag       = flag.Bool("strict-empty", false, "Exit 1 when the scan root contains no supported files at all")
+	widthFlag             = flag.Int("width", 0, "Text output width in columns (default: the terminal width, or 80 when not a terminal)")
+	includeHistoricalFlag = flag.Bool("include-historical", false, "Treat URLs in changelogs and release notes like any other URL instead of as informational historical references")
+	fixGeneratedFlag      = flag.Bool("fix-generated", false, "Check and fix URLs in generated and minified files like any other URL instead of reporting them as not checked")
+	checkProductFlag      = flag.Bool("check-product-context", false, "Report URLs to the OCP documentation in files whose front matter or path declares another product, e.g. ROSA, as possible wrong-product links, with the same page under that product when it exists (advisory)")
+	productContextFlag    = flag.String("product-context", "", "With -check-product-context, JSON file mapping front matter keys and path globs to the product slug of files, replacing the built-in \"product\" front matter key")
+	minifiedLengthFlag    = flag.Int("minified-line-length", scanner.DefaultMinifiedLineLength, "Average line length in bytes above which a file is taken for minified, and its URLs are not checked (0 disables)")
+	autoFlag              = flag.Bool("auto", false, "Treat a -url that names a file or directory as -dir, and a -dir that is a URL as -url")
+	metricsFileFlag       = flag.String("metrics-file", "", "Write run metrics in OpenMetrics text format to this file, e.g. for the node-exporter textfile collector")
+	exportMatrixFlag      = flag.String("export-matrix", "", "Write the page and anchor facts discovered during the run to this file, for -import-matrix in later runs")
+	importMatrixFlag      = flag.String("import-matrix", "", "Seed the page and anchor facts from a file written by -export-matrix instead of requesting those pages again")
+	matrixMaxAgeFlag      = flag.Duration("matrix-max-age", 24*time.Hour, "With -import-matrix, ignore page facts checked longer ago than this (0 accepts any age)")
+	matrixRefreshFlag     = flag.Bool("matrix-refresh", false, "With -import-matrix, request every page this run uses again; imported facts of other pages are still exported")
+	softDeadlineFlag      = flag.Duration("soft-deadline", 0, "Stop starting new checks once this much time has passed, less -soft-deadline-margin, and report the remaining URLs as not checked (0 disables)")
+	deadlineMarginFlag    = flag.Duration("soft-deadline-margin", time.Minute, "With -soft-deadline, time kept for the checks in progress and for writing the outputs")
+	asOfFlag              = flag.String("as-of", "", "Check as if this version, e.g. 4.16, were the latest release, for reproducible results; newer versions are never requested, and the cap is recorded in every report")
+	minVersionFlag        = flag.String("min-version", "", "Check and suggest only newer versions from this one, e.g. 4.14 or latest-2")
+	maxVersionFlag        = flag.String("max-version", "", "Check and suggest only newer versions up to this one, e.g. 4.16 or eus-latest; URLs above it are reported as up to date with a note")
+	excludeVersionsFlag   = flag.String("exclude-versions", "", "Comma-separated versions never to check or suggest, e.g. 4.17,4.19 for skipped releases; -fix moves to the newest version left")
+	priorityFlag          = flag.String("priority", string(checker.PriorityDiscovery), "Order to start checking the URLs of -dir in: discovery-order, oldest-first (oldest linked versions first) or random; with -soft-deadline it decides which URLs are left not checked")
+	retriesFlag           = flag.Int("retries", checker.DefaultRetryPolicy.MaxAttempts-1, "Request a page this many more times after a transient failure such as a dropped connection")
+	retryBackoffFlag      = flag.Duration("retry-backoff", checker.DefaultRetryPolicy.InitialBackoff, "Wait about this long before the first retry of a page, twice as long before each next one, up to 30s")
+	userAgentFlag         = flag.String("user-agent", "", "User-Agent header sent with every request (default: ocp-doc-checker/<version> with the project URL)")
+	rateLimitFlag         = flag.Float64("rate-limit", 10, "Send at most this many requests per second on average, in bursts of up to as many, across every concurrent check (0 disables)")
+	cacheDirFlag          = flag.String("cache-dir", defaultCacheDir(), "Keep the page and anchor facts of every requested page in this directory, and reuse them in later runs within -cache-ttl")
+	cacheTTLFlag          = flag.Duration("cache-ttl", 24*time.Hour, "With -cache-dir, request pages again once their cached facts are older than this")
+	noCacheFlag           = flag.Bool("no-cache", false, "Neither read nor write the -cache-dir cache")
+	cacheMaxMBFlag        = flag.Int("cache-max-mb", 512, "Keep at most this many megabytes of page anchors in memory, dropping the least recently used pages' anchors (to -cache-dir when set) first; 0 for no limit")
+	noOriginalCheckFlag   = flag.Bool("no-original-check", false, "Do not request each URL at its own version, saving a request per linked page, so dead links are not reported as broken")
+	noSoft404Flag         = flag.Bool("no-soft-404-check", false, "Do not look for not-found messages on pages answered with 200, so pages without an anchor are checked with HEAD alone")
+	acceptRedirectsFlag   = flag.Bool("accept-redirects", false, "Count a page that redirects to another page, another guide or the product landing page as existing instead of missing; the redirect target is reported either way")
+	noFormatFallbackFlag  = flag.Bool("no-format-fallback", false, "Do not look up anchors missing from a multi-page html page in the html-single variant of the guide")
+	searchSiblingsFlag    = flag.Bool("search-sibling-pages", false, "When an anchor is missing from its page at the newest version, look for it on other pages of the multi-page guide, picked from its table of contents, in case the section moved")
+	maxParseTimeFlag      = flag.Duration("max-page-parse-time", checker.DefaultMaxPageParseTime, "Stop reading a page for anchors after this long, e.g. for html-single guides of tens of megabytes, and report anchors not read by then as indeterminate (0 disables)")
+	indeterminateFlag     = flag.String("indeterminate-anchors", "warn", "What an anchor left indeterminate by -max-page-parse-time does: warn, or fail to exit 1")
+	siblingProbesFlag     = flag.Int("sibling-page-probes", 3, "With -search-sibling-pages, request at most this many pages per missing anchor, besides the table of contents")
+	discoverVersionsFlag  = flag.Bool("discover-versions", false, "Check the versions listed on the docs.redhat.com product page instead of the built-in list, falling back to the list when the page cannot be read")
+	errorFormatFlag       = flag.String("error-format", "text", "Format of the errors, warnings and notes written to stderr: text, or json for one JSON object per line")
+	runIDFlag             = flag.String("run-id", "", "ID of the run in every report, log record, metrics file and campaign state it writes, e.g. the correlation ID of a pipeline (default: the start time and a random suffix)")
+	logFormatFlag         = flag.String("log-format", "", "Format of the checker's log on stderr: text, or json for one JSON object per line (default: the -error-format). It logs retries and pages that cannot be parsed, and with -verbose every request")
+	gitDiffFlag           = flag.String("git-diff", "", "Scan only the files changed since this git revision, e.g. origin/main, or origin/main...HEAD for the changes of a branch alone")
+	gitNewOnlyFlag        = flag.Bool("git-new-only", false, "With -git-diff, check only the URLs on lines the change added, and report the others of the changed files as pre-existing without checking them")
+	shardFlag             = flag.String("shard", "", "Check only shard N of M of the unique URLs, written N/M, e.g. for parallel CI jobs; merge their JSON reports with -merge-reports")
+	mergeReportsFlag      stringList
+	soft404MarkerFlag     stringList
+	deprecationMarkerFlag stringList
+
+	// text renders text output at the terminal width, set once flags are
+	// parsed
+	text = &output.Text{W: os.Stdout, Width: output.DefaultWidth}
+	// narration renders progress and the fix narration on stderr, keeping
+	// stdout for the report alone
+	narration = &output.Text{W: os.Stderr, Width: output.DefaultWidth}
+
+	// run identifies the run in every report, log record and file it
+	// writes; -run-id replaces its ID once flags are parsed. Its start time
+	// is also that of the run duration metric and the soft deadline.
+	run = output.NewRunContext(time.Now())
+
+	// campaign is the upgrade campaign of -campaign, loaded or started once
+	// flags are parsed
+	campaign *fixer.Campaign
 )
 
+func init() {
+	flag.Var(&allowHostFlag, "allow-host", "Additional host the checker may contact besides docs.redhat.com (repeatable)")
+	flag.Var(&aliasFlag, "version-alias", "Version alias as name=version, usable in -allowed-target-versions; the version may be latest, latest-N or eus-latest (repeatable)")
+	flag.Var(&pinFlag, "pin-cert-sha256", "SHA-256 fingerprint, in hex or base64, of a certificate public key (SPKI) docs.redhat.com must present (repeatable)")
+	flag.Var(&historicalFlag, "historical-pattern", "Glob of files whose URLs are historical references, replacing the defaults such as CHANGELOG* and docs/release-notes/** (repeatable)")
+	flag.Var(&trackingParamFlag, "tracking-param", "Name or glob of a query parameter that only tracks campaigns, left out when grouping and checking URLs and removed by -normalize, replacing the defaults such as sc_cid and utm_* (repeatable)")
+	flag.Var(&generatedFlag, "generated-pattern", "Glob of generated files whose URLs are not checked, replacing the defaults such as *.min.* and **/generated/** (repeatable)")
+	flag.Var(&mergeReportsFlag, "merge-reports", "JSON report of a -shard run to merge into the report of the complete run; give one per shard (repeatable)")
+	flag.Var(&deprecationMarkerFlag, "deprecation-marker", "Text, .class or #id of the banner of a release that is no longer maintained, in addition to the built-in ones (repeatable)")
+	flag.Var(&soft404MarkerFlag, "soft-404-marker", "Message that makes a page answered with 200 count as missing, in addition to the built-in ones, matched without regard to case or whitespace in the title and start of the page (repeatable)")
+	flag.Var(&placeholderFlag, "placeholder-pattern", "Regular expression for an unresolved version placeholder, replacing the defaults (repeatable)")
+}
+
+// stringList is a flag.Value collecting every occurrence of a repeatable flag
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
 func main() {
 	flag.Parse()
 
@@ -42,431 +171,2164 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Validate flags - ensure mutual exclusivity
-	if *urlFlag == "" && *dirFlag == "" {
-		fmt.Fprintln(os.Stderr, "Error: either -url or -dir flag is required")
+	switch *errorFormatFlag {
+	case "text":
+	case "json":
+		// Only diagnostics go to stderr, one object per line: no usage
+		// text and no narration
+		flag.Usage = func() {}
+		narration.W = io.Discard
+	default:
+		errorf("invalid -error-format %q (expected text or json)", *errorFormatFlag)
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	if *urlFlag != "" && *dirFlag != "" {
-		fmt.Fprintln(os.Stderr, "Error: -url and -dir flags are mutually exclusive")
+	if *runIDFlag != "" {
+		if err := output.ValidateRunID(*runIDFlag); err != nil {
+			errorf("invalid -run-id: %v", err)
+			flag.Usage()
+			os.Exit(1)
+		}
+		run.ID = *runIDFlag
+	}
+
+	switch *logFormatFlag {
+	case "":
+		*logFormatFlag = *errorFormatFlag
+	case "text", "json":
+	default:
+		errorf("invalid -log-format %q (expected text or json)", *logFormatFlag)
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	if *fixFlag && *dirFlag == "" {
-		fmt.Fprintln(os.Stderr, "Error: -fix flag can only be used with -dir flag")
+	if len(mergeReportsFlag) > 0 {
+		if *urlFlag != "" || *dirFlag != "" {
+			errorf("-merge-reports flag cannot be used with -url or -dir")
+			flag.Usage()
+			os.Exit(1)
+		}
+		mergeReports(mergeReportsFlag)
+	}
+
+	// Validate flags - ensure mutual exclusivity
+	if *listAnchorsFlag != "" && (*urlFlag != "" || *dirFlag != "") {
+		errorf("-list-anchors flag cannot be used with -url or -dir")
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	if *fixFlag && *jsonFlag {
-		fmt.Fprintln(os.Stderr, "Error: -fix flag cannot be used with -json flag")
+	if *urlFlag == "" && *dirFlag == "" && *listAnchorsFlag == "" {
+		errorf("either -url, -dir or -list-anchors flag is required")
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	// Create checker
-	c := checker.NewChecker()
-
-	// Handle based on mode
-	if *urlFlag != "" {
-		// Single URL mode
-		handleSingleURL(c, *urlFlag)
-	} else {
-		// Directory/file scan mode
-		handleDirectory(c, *dirFlag)
+	if *urlFlag != "" && *dirFlag != "" {
+		errorf("-url and -dir flags are mutually exclusive")
+		flag.Usage()
+		os.Exit(1)
 	}
-}
 
-func handleSingleURL(c *checker.Checker, url string) {
-	// Perform check
-	result, err := c.Check(url)
+	resolvedURL, resolvedDir, note, err := resolveInput(*urlFlag, *dirFlag, *autoFlag, pathExists)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error checking URL: %v\n", err)
+		errorf("%v", err)
 		os.Exit(1)
 	}
-
-	// Output results
-	if *jsonFlag {
-		printJSONResults(result)
-	} else {
-		printTextResults(result, *verboseFlag)
+	if note != "" {
+		notef("%s", note)
 	}
+	*urlFlag, *dirFlag = resolvedURL, resolvedDir
 
-	// Exit with appropriate code
-	if result.IsOutdated {
-		os.Exit(1) // Exit with error code if documentation is outdated
+	if *includeHistoricalFlag && len(historicalFlag) > 0 {
+		errorf("-include-historical and -historical-pattern flags are mutually exclusive")
+		flag.Usage()
+		os.Exit(1)
 	}
-}
 
-func handleDirectory(c *checker.Checker, path string) {
-	// Check if path exists
-	info, err := os.Stat(path)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error accessing path: %v\n", err)
+	if *fixGeneratedFlag && len(generatedFlag) > 0 {
+		errorf("-fix-generated and -generated-pattern flags are mutually exclusive")
+		flag.Usage()
 		os.Exit(1)
 	}
-
-	// Collect URLs with their locations
-	var urlLocations []URLLocation
-	if info.IsDir() {
-		urlLocations, err = scanDirectoryWithLocations(path)
-	} else {
-		urlLocations, err = scanFileWithLocations(path)
+	if *minifiedLengthFlag < 0 {
+		errorf("-minified-line-length must not be negative")
+		flag.Usage()
+		os.Exit(1)
 	}
 
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error scanning for URLs: %v\n", err)
+	if *checkProductFlag && *dirFlag == "" {
+		errorf("-check-product-context flag can only be used with -dir flag")
+		flag.Usage()
 		os.Exit(1)
 	}
-
-	if len(urlLocations) == 0 {
-		if !*jsonFlag {
-			fmt.Println("✅ No OCP Documentation URLs found")
-		}
-		os.Exit(0)
+	if *productContextFlag != "" && !*checkProductFlag {
+		errorf("-product-context flag can only be used with -check-product-context")
+		flag.Usage()
+		os.Exit(1)
 	}
 
-	if !*jsonFlag {
-		fmt.Printf("Found %d unique OCP documentation URL(s)\n\n", len(urlLocations))
+	if *fixFlag && *dirFlag == "" {
+		errorf("-fix flag can only be used with -dir flag")
+		flag.Usage()
+		os.Exit(1)
 	}
 
-	// Check all URLs
-	var results []*checker.CheckResult
-	hasOutdated := false
-	urlToLocation := make(map[string]URLLocation)
+	switch *outputFlag {
+	case "text", "json", "tsv", "pr-comment", "json-legacy":
+	default:
+		errorf("invalid -output %q (expected text, json, tsv, pr-comment or json-legacy)", *outputFlag)
+		flag.Usage()
+		os.Exit(1)
+	}
 
-	for i, loc := range urlLocations {
-		if *verboseFlag {
-			fmt.Printf("[%d/%d] Checking: %s\n", i+1, len(urlLocations), loc.URL)
-		}
+	if *listAnchorsFlag != "" && *outputFlag != "text" && *outputFlag != "json" {
+		errorf("-list-anchors flag can only be used with -output text or json")
+		flag.Usage()
+		os.Exit(1)
+	}
 
-		urlToLocation[loc.URL] = loc
+	if *outputFlag == "pr-comment" && *dirFlag == "" {
+		errorf("-output pr-comment can only be used with -dir flag")
+		flag.Usage()
+		os.Exit(1)
+	}
 
-		result, err := c.Check(loc.URL)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error checking URL %s: %v\n", loc.URL, err)
-			continue
+	if *linkTemplateFlag != "" {
+		if *outputFlag != "pr-comment" {
+			errorf("-link-template flag can only be used with -output pr-comment")
+			flag.Usage()
+			os.Exit(1)
 		}
-
-		results = append(results, result)
-		if result.IsOutdated {
-			hasOutdated = true
+		if err := output.ValidateLinkTemplate(*linkTemplateFlag); err != nil {
+			errorf("invalid -link-template: %v", err)
+			flag.Usage()
+			os.Exit(1)
 		}
 	}
 
-	// Apply fixes if requested
-	if *fixFlag && hasOutdated {
-		applyFixes(results, urlToLocation)
+	if *noHeaderFlag && *outputFlag != "tsv" {
+		errorf("-no-header flag can only be used with -output tsv")
+		flag.Usage()
+		os.Exit(1)
 	}
 
-	// Output results
 	if *jsonFlag {
-		printBatchJSONResults(results)
-	} else {
-		printBatchTextResults(results, *verboseFlag)
+		if *outputFlag != "text" && *outputFlag != "json" {
+			errorf("-json flag conflicts with -output %s", *outputFlag)
+			flag.Usage()
+			os.Exit(1)
+		}
+		*outputFlag = "json"
 	}
 
-	// Exit with appropriate code
-	if hasOutdated && !*fixFlag {
+	if *softDeadlineFlag < 0 || *deadlineMarginFlag < 0 {
+		errorf("-soft-deadline and -soft-deadline-margin must not be negative")
+		flag.Usage()
 		os.Exit(1)
 	}
-}
-
-// scanDirectoryWithLocations recursively scans a directory and tracks URL locations
-func scanDirectoryWithLocations(dir string) ([]URLLocation, error) {
-	urlToFiles := make(map[string][]string)
-
-	// Supported file extensions
-	supportedExts := map[string]bool{
-		".md":       true,
-		".markdown": true,
-		".txt":      true,
-		".adoc":     true,
+	if *softDeadlineFlag > 0 && *deadlineMarginFlag >= *softDeadlineFlag {
+		errorf("-soft-deadline-margin %s leaves no time before -soft-deadline %s", *deadlineMarginFlag, *softDeadlineFlag)
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *softDeadlineFlag > 0 && *dirFlag == "" {
+		errorf("-soft-deadline flag can only be used with -dir flag")
+		flag.Usage()
+		os.Exit(1)
 	}
 
-	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
+	if *shardFlag != "" {
+		if *dirFlag == "" {
+			errorf("-shard flag can only be used with -dir flag")
+			flag.Usage()
+			os.Exit(1)
 		}
-
-		// Skip directories
-		if d.IsDir() {
-			return nil
+		if _, err := scanner.ParseShard(*shardFlag); err != nil {
+			errorf("%v", err)
+			flag.Usage()
+			os.Exit(1)
 		}
-
-		// Check if file has supported extension
-		ext := filepath.Ext(path)
-		if !supportedExts[ext] {
-			return nil
+		if *outputFlag == "json-legacy" {
+			errorf("-shard flag cannot be used with -output json-legacy")
+			flag.Usage()
+			os.Exit(1)
 		}
-
-		// Scan the file
-		fileURLs, err := scanFile(path)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: error scanning %s: %v\n", path, err)
-			return nil // Continue with other files
+		if fixMode() {
+			errorf("-shard flag cannot be used with -fix, -check-fix, -fix-changesets or -campaign")
+			flag.Usage()
+			os.Exit(1)
 		}
+	}
 
-		// Track which files contain which URLs
-		for _, url := range fileURLs {
-			urlToFiles[url] = append(urlToFiles[url], path)
-		}
+	if *upgradeEffortFlag && *dirFlag == "" {
+		errorf("-report-upgrade-effort flag can only be used with -dir flag")
+		flag.Usage()
+		os.Exit(1)
+	}
 
-		return nil
-	})
+	if *checkFixFlag && *dirFlag == "" {
+		errorf("-check-fix flag can only be used with -dir flag")
+		flag.Usage()
+		os.Exit(1)
+	}
 
-	if err != nil {
-		return nil, err
+	if *checkFixFlag && *fixFlag {
+		errorf("-check-fix and -fix flags are mutually exclusive")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	switch *verifyAfterFixFlag {
+	case "auto", "always", "never":
+	default:
+		errorf("invalid -verify-after-fix %q (expected auto, always or never)", *verifyAfterFixFlag)
+		flag.Usage()
+		os.Exit(1)
 	}
 
-	// Convert map to slice of URLLocation
-	var locations []URLLocation
-	for url, files := range urlToFiles {
-		// Deduplicate files
-		fileSet := make(map[string]bool)
-		for _, f := range files {
-			fileSet[f] = true
+	if *noSoft404Flag && len(soft404MarkerFlag) > 0 {
+		errorf("-soft-404-marker and -no-soft-404-check flags are mutually exclusive")
+		flag.Usage()
+		os.Exit(1)
+	}
+	for _, marker := range soft404MarkerFlag {
+		if strings.TrimSpace(marker) == "" {
+			errorf("-soft-404-marker is empty")
+			flag.Usage()
+			os.Exit(1)
 		}
-		uniqueFiles := make([]string, 0, len(fileSet))
-		for f := range fileSet {
-			uniqueFiles = append(uniqueFiles, f)
+	}
+	for _, marker := range deprecationMarkerFlag {
+		if strings.TrimSpace(strings.TrimLeft(marker, ".#")) == "" {
+			errorf("-deprecation-marker %q is empty", marker)
+			flag.Usage()
+			os.Exit(1)
 		}
-
-		locations = append(locations, URLLocation{
-			URL:   url,
-			Files: uniqueFiles,
-		})
 	}
 
-	return locations, nil
-}
-
-// scanFileWithLocations scans a single file and returns URL locations
-func scanFileWithLocations(path string) ([]URLLocation, error) {
-	urls, err := scanFile(path)
-	if err != nil {
-		return nil, err
+	if *gitDiffFlag != "" && *dirFlag == "" {
+		errorf("-git-diff flag can only be used with -dir flag")
+		flag.Usage()
+		os.Exit(1)
 	}
 
-	// Deduplicate URLs for this single file
-	urlSet := make(map[string]bool)
-	for _, url := range urls {
-		urlSet[url] = true
+	if *gitNewOnlyFlag && *gitDiffFlag == "" {
+		errorf("-git-new-only flag can only be used with -git-diff flag")
+		flag.Usage()
+		os.Exit(1)
 	}
 
-	var locations []URLLocation
-	for url := range urlSet {
-		locations = append(locations, URLLocation{
-			URL:   url,
-			Files: []string{path},
-		})
+	if *changesetsFlag != "" && *dirFlag == "" {
+		errorf("-fix-changesets flag can only be used with -dir flag")
+		flag.Usage()
+		os.Exit(1)
 	}
 
-	return locations, nil
-}
-
-// scanFile scans a single file for OCP documentation URLs
-func scanFile(path string) ([]string, error) {
-	content, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
+	if *changesetsFlag != "" && (*fixFlag || *checkFixFlag) {
+		errorf("-fix-changesets flag cannot be used with -fix or -check-fix")
+		flag.Usage()
+		os.Exit(1)
 	}
 
-	urlRegex := regexp.MustCompile(`https://docs\.redhat\.com/[^\s)\]"]*openshift_container_platform/\d+\.\d+/[^\s)\]"]*`)
-	matches := urlRegex.FindAllString(string(content), -1)
+	if *campaignFlag != "" && *dirFlag == "" {
+		errorf("-campaign flag can only be used with -dir flag")
+		flag.Usage()
+		os.Exit(1)
+	}
 
-	// Clean up URLs (remove trailing punctuation)
-	var cleanedURLs []string
-	for _, url := range matches {
-		cleaned := strings.TrimRight(url, ".,;:!?")
-		cleanedURLs = append(cleanedURLs, cleaned)
+	if *campaignFlag != "" && (*fixFlag || *checkFixFlag || *changesetsFlag != "") {
+		errorf("-campaign flag cannot be used with -fix, -check-fix or -fix-changesets")
+		flag.Usage()
+		os.Exit(1)
 	}
 
-	return cleanedURLs, nil
-}
+	if *campaignTargetFlag != "" && *campaignFlag == "" {
+		errorf("-campaign-target flag can only be used with -campaign flag")
+		flag.Usage()
+		os.Exit(1)
+	}
 
-// applyFixes updates files with the latest URLs
-func applyFixes(results []*checker.CheckResult, urlToLocation map[string]URLLocation) {
-	fmt.Println()
-	fmt.Println(strings.Repeat("=", 80))
-	fmt.Println("🔧 Applying Fixes...")
-	fmt.Println(strings.Repeat("=", 80))
-	fmt.Println()
+	if *campaignStepFlag != 0 && *campaignFlag == "" {
+		errorf("-campaign-step flag can only be used with -campaign flag")
+		flag.Usage()
+		os.Exit(1)
+	}
 
-	fixedFiles := make(map[string]bool)
-	fixCount := 0
+	if *campaignStepFlag < 0 {
+		errorf("invalid -campaign-step %d (expected 0 or more groups)", *campaignStepFlag)
+		flag.Usage()
+		os.Exit(1)
+	}
 
-	for _, result := range results {
-		if !result.IsOutdated || len(result.NewerVersions) == 0 {
-			continue
+	if *campaignFlag != "" {
+		var err error
+		campaign, err = loadCampaign(*campaignFlag, *campaignTargetFlag)
+		if err != nil {
+			errorf("%v", err)
+			flag.Usage()
+			os.Exit(1)
 		}
-
-		// Get the latest version URL
-		latest := result.NewerVersions[len(result.NewerVersions)-1]
-		oldURL := result.OriginalURL
-		newURL := latest.URL
-
-		// Get the files containing this URL
-		location, ok := urlToLocation[oldURL]
-		if !ok {
-			continue
+		// The campaign checks as of its target, so that a release during
+		// the campaign does not move it
+		if *asOfFlag == "" {
+			*asOfFlag = campaign.TargetVersion
+		} else if *asOfFlag != campaign.TargetVersion {
+			errorf("-as-of %s does not match the campaign target version %s", *asOfFlag, campaign.TargetVersion)
+			flag.Usage()
+			os.Exit(1)
 		}
+	}
 
-		// Update each file
-		for _, filePath := range location.Files {
-			content, err := os.ReadFile(filePath)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", filePath, err)
-				continue
-			}
+	if *fixLinkTextFlag && !fixMode() {
+		errorf("-fix-link-text flag can only be used with -fix, -check-fix, -fix-changesets or -campaign flag")
+		flag.Usage()
+		os.Exit(1)
+	}
 
-			// Replace all occurrences of the old URL with the new URL
-			newContent := strings.ReplaceAll(string(content), oldURL, newURL)
+	if *crossDocumentFlag && !fixMode() {
+		errorf("-allow-cross-document-fix flag can only be used with -fix, -check-fix, -fix-changesets or -campaign flag")
+		flag.Usage()
+		os.Exit(1)
+	}
 
-			if newContent != string(content) {
-				err = os.WriteFile(filePath, []byte(newContent), 0644)
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", filePath, err)
-					continue
-				}
+	if *fixAggressiveFlag && !fixMode() {
+		errorf("-fix-aggressive flag can only be used with -fix, -check-fix, -fix-changesets or -campaign flag")
+		flag.Usage()
+		os.Exit(1)
+	}
 
-				fixedFiles[filePath] = true
-				fixCount++
+	if *normalizeFlag && !fixMode() {
+		errorf("-normalize flag can only be used with -fix, -check-fix, -fix-changesets or -campaign flag")
+		flag.Usage()
+		os.Exit(1)
+	}
 
-				fmt.Printf("✅ Updated: %s\n", filePath)
-				fmt.Printf("   %s → %s\n", result.OriginalVersion, latest.Version)
-				fmt.Printf("   Old: %s\n", oldURL)
-				fmt.Printf("   New: %s\n\n", newURL)
-			}
-		}
+	switch *preferFormatFlag {
+	case "", "html", "html-single":
+	default:
+		errorf("invalid -fix-prefer-format %q (expected html or html-single)", *preferFormatFlag)
+		flag.Usage()
+		os.Exit(1)
 	}
 
-	fmt.Println(strings.Repeat("=", 80))
-	fmt.Printf("Summary: Fixed %d URL(s) in %d file(s)\n", fixCount, len(fixedFiles))
-	fmt.Println(strings.Repeat("=", 80))
-	fmt.Println()
-}
+	if *preferFormatFlag != "" && !fixMode() {
+		errorf("-fix-prefer-format flag can only be used with -fix, -check-fix, -fix-changesets or -campaign flag")
+		flag.Usage()
+		os.Exit(1)
+	}
 
-func printTextResults(result *checker.CheckResult, verbose bool) {
-	fmt.Printf("Checking: %s\n", result.OriginalURL)
-	fmt.Printf("Current Version: %s\n", result.OriginalVersion)
-	fmt.Println(strings.Repeat("-", 80))
+	if fixMode() && jsonOutput() {
+		errorf("-fix, -check-fix, -fix-changesets and -campaign flags cannot be used with JSON output")
+		flag.Usage()
+		os.Exit(1)
+	}
 
-	if result.IsOutdated {
-		fmt.Printf("⚠️  This documentation is OUTDATED!\n")
-		fmt.Printf("Latest Version: %s\n\n", result.LatestVersion)
+	if fixMode() && (*outputFlag == "tsv" || *outputFlag == "pr-comment") {
+		errorf("-fix, -check-fix, -fix-changesets and -campaign flags cannot be used with -output %s", *outputFlag)
+		flag.Usage()
+		os.Exit(1)
+	}
 
-		if *allAvailableFlag {
-			fmt.Println("Available newer versions:")
-			for _, v := range result.NewerVersions {
-				fmt.Printf("  ✓ Version %s: %s\n", v.Version, v.URL)
-			}
-		} else {
-			// Show only the latest version
-			if len(result.NewerVersions) > 0 {
-				latest := result.NewerVersions[len(result.NewerVersions)-1]
-				fmt.Printf("Latest available version:\n")
-				fmt.Printf("  ✓ Version %s: %s\n", latest.Version, latest.URL)
+	if *outputFlag == "json-legacy" {
+		warnf("-output json-legacy is deprecated and will be removed in a future release; use -output json")
+	}
 
-				if len(result.NewerVersions) > 1 {
-					fmt.Printf("\n(Use --all-available to see all %d newer versions)\n", len(result.NewerVersions))
-				}
-			}
-		}
+	if *widthFlag < 0 {
+		errorf("invalid -width %d (expected a number of columns, or 0 for the terminal width)", *widthFlag)
+		flag.Usage()
+		os.Exit(1)
+	}
+	text.Width = output.TerminalWidth(os.Stdout, *widthFlag)
+	text.FullURLs = *verboseFlag
+	narration.Width = output.TerminalWidth(os.Stderr, *widthFlag)
+	narration.FullURLs = *verboseFlag
+
+	switch *ciModeFlag {
+	case "auto", "github", "none":
+	default:
+		errorf("invalid -ci-mode %q (expected auto, github or none)", *ciModeFlag)
+		flag.Usage()
+		os.Exit(1)
+	}
 
-		if verbose {
-			fmt.Println("\nAll checked versions:")
-			for _, v := range result.AllResults {
-				status := "✗ Not found"
-				if v.Exists {
-					if v.HasAnchor {
-						if v.AnchorExists {
-							status = "✓ Found (page + anchor)"
-						} else {
-							status = "⚠ Page found, anchor missing"
-						}
-					} else {
-						status = "✓ Found"
-					}
-				}
-				fmt.Printf("  %s Version %s: %s\n", status, v.Version, v.URL)
-			}
-		}
+	// Create checker
+	c := checker.NewChecker()
+	c.SetLogger(newLogger(os.Stderr))
+	for _, host := range allowHostFlag {
+		c.AllowHost(host)
+	}
+	c.SetFormatFallback(!*noFormatFallbackFlag)
+	c.SetOriginalCheck(!*noOriginalCheckFlag)
+	c.SetAcceptRedirects(*acceptRedirectsFlag)
+	if *noSoft404Flag {
+		c.SetSoft404Markers(nil)
 	} else {
-		fmt.Printf("✓ This documentation is UP TO DATE (version %s)\n", result.LatestVersion)
+		c.SetSoft404Markers(append(slices.Clone(checker.DefaultSoft404Markers), soft404MarkerFlag...))
+	}
+	c.SetDeprecationMarkers(append(slices.Clone(checker.DefaultDeprecationMarkers), deprecationMarkerFlag...))
+	if *siblingProbesFlag < 1 {
+		errorf("invalid -sibling-page-probes %d (expected at least 1)", *siblingProbesFlag)
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *searchSiblingsFlag {
+		c.SetSiblingSearch(*siblingProbesFlag)
+	}
+	if *maxParseTimeFlag < 0 {
+		errorf("invalid -max-page-parse-time %s (expected 0 or more)", *maxParseTimeFlag)
+		flag.Usage()
+		os.Exit(1)
+	}
+	c.SetMaxPageParseTime(*maxParseTimeFlag)
+	switch *indeterminateFlag {
+	case "warn", "fail":
+	default:
+		errorf("invalid -indeterminate-anchors %q (expected warn or fail)", *indeterminateFlag)
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *userAgentFlag != "" {
+		c.SetUserAgent(*userAgentFlag)
+	} else {
+		c.SetUserAgent(checker.UserAgent(version))
+	}
 
-		// Check if there are newer versions with missing anchors
-		missingAnchors := []checker.VersionCheckResult{}
-		for _, v := range result.AllResults {
-			if v.Exists && v.HasAnchor && !v.AnchorExists {
-				missingAnchors = append(missingAnchors, v)
-			}
+	if *rateLimitFlag < 0 {
+		errorf("invalid -rate-limit %g (expected requests per second, or 0 for no limit)", *rateLimitFlag)
+		flag.Usage()
+		os.Exit(1)
+	}
+	c.SetRateLimit(*rateLimitFlag, int(math.Ceil(*rateLimitFlag)))
+
+	if *retriesFlag < 0 || *retryBackoffFlag < 0 {
+		errorf("invalid -retries %d or -retry-backoff %s (expected zero or more)", *retriesFlag, *retryBackoffFlag)
+		flag.Usage()
+		os.Exit(1)
+	}
+	retry := checker.DefaultRetryPolicy
+	retry.MaxAttempts = *retriesFlag + 1
+	retry.InitialBackoff = *retryBackoffFlag
+	if err := c.SetRetryPolicy(retry); err != nil {
+		errorf("%v", err)
+		os.Exit(1)
+	}
+
+	priority, err := checker.ParsePriority(*priorityFlag)
+	if err != nil {
+		errorf("%v", err)
+		flag.Usage()
+		os.Exit(1)
+	}
+	c.SetPriority(priority)
+
+	if *pinAllHostsFlag && len(pinFlag) == 0 {
+		errorf("-pin-all-hosts flag can only be used with -pin-cert-sha256 flag")
+		flag.Usage()
+		os.Exit(1)
+	}
+	if err := c.SetCertPins(pinFlag, *pinAllHostsFlag); err != nil {
+		errorf("%v", err)
+		os.Exit(1)
+	}
+	if *caCertFlag != "" || *insecureFlag {
+		tlsConfig := &tls.Config{InsecureSkipVerify: *insecureFlag}
+		if *caCertFlag != "" {
+			roots, err := checker.LoadCACerts(*caCertFlag)
+			if err != nil {
+				errorf("%v", err)
+				os.Exit(1)
+			}
+			tlsConfig.RootCAs = roots
+		}
+		if *insecureFlag {
+			warnf("-insecure-skip-verify disables TLS certificate verification")
+		}
+		c.SetTLSConfig(tlsConfig)
+	}
+	if err := c.SetNetwork(checker.Network(*networkFlag)); err != nil {
+		errorf("invalid -network %q (expected auto, ipv4 or ipv6)", *networkFlag)
+		flag.Usage()
+		os.Exit(1)
+	}
+	if err := c.SetAnchorPolicy(checker.AnchorPolicy(*anchorPolicyFlag)); err != nil {
+		errorf("invalid -anchor-policy %q (expected strict or page-only)", *anchorPolicyFlag)
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if err := c.SetAsOf(*asOfFlag); err != nil {
+		errorf("%v", err)
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *excludeVersionsFlag != "" {
+		if err := c.ExcludeVersions(strings.Split(*excludeVersionsFlag, ",")); err != nil {
+			errorf("%v", err)
+			flag.Usage()
+			os.Exit(1)
+		}
+	}
+
+	if *discoverVersionsFlag {
+		// Aliases and target policies resolve against the discovered versions
+		versions, err := c.DiscoverVersions()
+		if err != nil {
+			warnf("%v; using the built-in versions", err)
+		} else if *verboseFlag && !machineOutput() {
+			fmt.Fprintf(narration.W, "Discovered versions: %s\n\n", strings.Join(versions, ", "))
+		}
+	}
+
+	aliases := make(map[string]string)
+	for _, alias := range aliasFlag {
+		name, target, ok := strings.Cut(alias, "=")
+		if !ok {
+			errorf("invalid -version-alias %q (expected name=version)", alias)
+			os.Exit(1)
+		}
+		aliases[name] = target
+	}
+	if err := c.SetVersionAliases(aliases); err != nil {
+		errorf("%v", err)
+		os.Exit(1)
+	}
+
+	// Range bounds may be aliases, resolved like the others
+	if err := c.SetVersionRange(*minVersionFlag, *maxVersionFlag); err != nil {
+		errorf("%v", err)
+		flag.Usage()
+		os.Exit(1)
+	}
+	// The versions table tells versions left out of the range from 404s
+	c.SetRecordSkippedVersions(*verboseFlag)
+
+	if *allowedTargetsFlag != "" {
+		if err := c.SetAllowedTargets(strings.Split(*allowedTargetsFlag, ",")); err != nil {
+			errorf("%v", err)
+			os.Exit(1)
+		}
+	}
+	if resolved := c.ResolvedAliases(); len(resolved) > 0 && !machineOutput() {
+		fmt.Fprintf(narration.W, "Version aliases: %s\n\n", formatAliases(resolved))
+	}
+
+	if *matrixMaxAgeFlag < 0 {
+		errorf("invalid -matrix-max-age %s (expected a duration, or 0 for any age)", *matrixMaxAgeFlag)
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *cacheTTLFlag <= 0 {
+		errorf("invalid -cache-ttl %s (expected a positive duration)", *cacheTTLFlag)
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *cacheMaxMBFlag < 0 {
+		errorf("invalid -cache-max-mb %d (expected 0 or more)", *cacheMaxMBFlag)
+		flag.Usage()
+		os.Exit(1)
+	}
+	c.SetPageCacheLimit(int64(*cacheMaxMBFlag) << 20)
+	if !*noCacheFlag {
+		// The cache only saves requests, so a run goes on without it
+		if err := c.SetCache(*cacheDirFlag, *cacheTTLFlag); err != nil {
+			warnf("page cache disabled: %v", err)
+		}
+	}
+	if *matrixRefreshFlag && *importMatrixFlag == "" {
+		errorf("-matrix-refresh flag can only be used with -import-matrix flag")
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *importMatrixFlag != "" {
+		counts, err := c.ImportMatrixFile(*importMatrixFlag, *matrixMaxAgeFlag)
+		if err != nil {
+			errorf("could not import matrix: %v", err)
+			os.Exit(1)
+		}
+		c.RefreshImported(*matrixRefreshFlag)
+		if !machineOutput() {
+			fmt.Fprintf(narration.W, "Imported page facts: %d page(s)", counts.Imported)
+			if counts.Stale > 0 {
+				fmt.Fprintf(narration.W, ", %d older than %s ignored", counts.Stale, *matrixMaxAgeFlag)
+			}
+			fmt.Fprint(narration.W, "\n\n")
+		}
+	}
+
+	if *slugMapFlag != "" {
+		slugMap, err := checker.LoadSlugMapFile(*slugMapFlag)
+		if err != nil {
+			errorf("could not load slug map: %v", err)
+			os.Exit(1)
+		}
+		c.SetSlugMap(slugMap)
+	}
+
+	// Handle based on mode
+	if *listAnchorsFlag != "" {
+		handleListAnchors(c, *listAnchorsFlag)
+	} else if *urlFlag != "" {
+		// Single URL mode
+		handleSingleURL(c, *urlFlag)
+	} else {
+		// Directory/file scan mode
+		handleDirectory(c, *dirFlag)
+	}
+}
+
+func handleSingleURL(c *checker.Checker, url string) {
+	// Never contact a host that only imitates the documentation host
+	if host := scanner.URLHost(url); host != "" {
+		if reason, ok := scanner.SuspiciousHost(host); ok {
+			errorf("suspicious host %s (%s, %s), not checking", host, scanner.ASCIIHost(host), reason)
+			os.Exit(1)
+		}
+	}
+
+	// Perform check
+	result, err := c.Check(url)
+	if err != nil {
+		message, code := describeCheckError(err)
+		errorf("could not check URL: %s", message)
+		writeMetrics(c, "", nil, 1)
+		writeMatrix(c)
+		os.Exit(code)
+	}
+	writeMetrics(c, "", []*checker.CheckResult{result}, 0)
+	writeMatrix(c)
+	indeterminate := warnIndeterminate(result)
+
+	// Output results
+	if jsonOutput() {
+		printJSONResults(result)
+	} else if *outputFlag == "tsv" {
+		printTSVResults([]output.TSVRow{output.NewTSVRow(result, scanner.Location{})})
+	} else {
+		printTextResults(result, *verboseFlag)
+		if *verboseFlag {
+			fmt.Println()
+			printCheckerStats(c.Stats())
+			fmt.Printf("Run: %s\n", run.ID)
+		}
+	}
+
+	// Exit with appropriate code
+	if result.IsOutdated || result.Broken || result.FragmentIssue == parser.FragmentMalformed || (indeterminate && *indeterminateFlag == "fail") {
+		os.Exit(1) // Exit with error code if documentation is outdated or malformed
+	}
+}
+
+// handleListAnchors prints the anchors of the page of url, sorted by id
+func handleListAnchors(c *checker.Checker, url string) {
+	found, err := c.ListAnchors(url)
+	if err != nil {
+		message, code := describeCheckError(err)
+		errorf("could not list anchors: %s", message)
+		os.Exit(code)
+	}
+
+	list := output.NewAnchorList(url, found)
+	if jsonOutput() {
+		list.RunID = run.ID
+		if err := output.WriteJSON(os.Stdout, list); err != nil {
+			errorf("could not write results: %v", err)
+		}
+		return
+	}
+	for _, a := range list.Anchors {
+		line := a.ID + "\t" + a.Tag
+		if a.Heading != "" {
+			line += "\t" + a.Heading
+		}
+		fmt.Println(line)
+	}
+}
+
+// batchReport carries everything a directory scan reports
+type batchReport struct {
+	results       []*checker.CheckResult
+	urlToLocation map[string]scanner.Location
+	// placeholders are URLs with an unresolved version placeholder, which
+	// are reported but never checked
+	placeholders []scanner.Location
+	// suspicious are URLs whose host only resembles docs.redhat.com, which
+	// are reported as security findings and never checked
+	suspicious []scanner.Location
+	scanStats  scanner.Stats
+	// historical are the results of URLs found only in changelogs and
+	// release notes, which are reported but never fixed or failing
+	historical []*checker.CheckResult
+	// resolvedAliases are the version aliases of the run and the concrete
+	// versions they resolved to
+	resolvedAliases map[string]string
+	// asOf is the -as-of version capping the versions checked
+	asOf string
+	// shard is the part of the unique URLs checked by a -shard run
+	shard *scanner.Shard
+	// notChecked are the URLs left when the soft deadline was reached
+	notChecked []scanner.Location
+	// preexisting are the URLs of changed files on lines -git-new-only
+	// found the change did not add, which are reported but never checked
+	preexisting []scanner.Location
+	// generated are the URLs found only in generated or minified files,
+	// which are reported but never checked
+	generated []scanner.Location
+	// wrongProduct are the occurrences of checked URLs in files declaring
+	// another product, which are advisory and never fixed or failing
+	wrongProduct []output.WrongProductLink
+	// checkerStats are the page lookup counters once every URL is checked
+	checkerStats checker.Stats
+	// failed is set when the run exits 1
+	failed bool
+}
+
+func handleDirectory(c *checker.Checker, path string) {
+	// Check if path exists
+	if _, err := os.Stat(path); err != nil {
+		errorf("could not access path: %v", err)
+		os.Exit(1)
+	}
+
+	// Collect URLs with their locations
+	s := scanner.New()
+	s.DeepScan = *deepScanFlag
+	s.Warn = func(path string, err error) {
+		warnf("error scanning %s: %v", path, err)
+	}
+	if len(placeholderFlag) > 0 {
+		if err := s.SetPlaceholderPatterns(placeholderFlag); err != nil {
+			errorf("%v", err)
+			os.Exit(1)
+		}
+	}
+	if *includeHistoricalFlag {
+		_ = s.SetHistoricalPatterns(nil)
+	} else if len(historicalFlag) > 0 {
+		if err := s.SetHistoricalPatterns(historicalFlag); err != nil {
+			errorf("%v", err)
+			os.Exit(1)
+		}
+	}
+	if *fixGeneratedFlag {
+		s.SetGeneratedDetection(false)
+	} else if len(generatedFlag) > 0 {
+		if err := s.SetGeneratedPatterns(generatedFlag); err != nil {
+			errorf("%v", err)
+			os.Exit(1)
+		}
+	}
+	s.SetMinifiedLineLength(*minifiedLengthFlag)
+	if len(trackingParamFlag) > 0 {
+		if err := s.SetTrackingParams(trackingParamFlag); err != nil {
+			errorf("%v", err)
+			os.Exit(1)
+		}
+	}
+	var products *scanner.ProductContext
+	if *checkProductFlag {
+		products = scanner.DefaultProductContext()
+		if *productContextFlag != "" {
+			var err error
+			if products, err = scanner.LoadProductContextFile(*productContextFlag); err != nil {
+				errorf("could not load product context: %v", err)
+				os.Exit(1)
+			}
+		}
+		s.SetProductContext(products)
+	}
+
+	scanned, err := s.Scan(path)
+	if err != nil {
+		errorf("could not scan for URLs: %v", err)
+		os.Exit(1)
+	}
+
+	report := &batchReport{
+		urlToLocation:   make(map[string]scanner.Location),
+		scanStats:       s.Stats(),
+		resolvedAliases: c.ResolvedAliases(),
+		asOf:            c.AsOf(),
+	}
+	if *gitDiffFlag != "" {
+		diff, err := gitdiff.Run(context.Background(), path, *gitDiffFlag)
+		if err != nil {
+			errorf("could not read the changes since %s: %v", *gitDiffFlag, err)
+			os.Exit(1)
+		}
+		scanned = diff.FilterChanged(scanned)
+		if *gitNewOnlyFlag {
+			scanned, report.preexisting = diff.SplitAdded(scanned)
+		}
+	}
+	if *shardFlag != "" {
+		shard, _ := scanner.ParseShard(*shardFlag)
+		report.shard = &shard
+		scanned = scanner.FilterShard(scanned, shard)
+		report.preexisting = scanner.FilterShard(report.preexisting, shard)
+	}
+	var urlLocations []scanner.Location
+	for _, loc := range scanned {
+		if loc.Suspicious != "" {
+			report.suspicious = append(report.suspicious, loc)
+		} else if loc.Generated {
+			report.generated = append(report.generated, loc)
+		} else if loc.Placeholder {
+			report.placeholders = append(report.placeholders, loc)
+		} else {
+			urlLocations = append(urlLocations, loc)
+		}
+	}
+
+	// Pointing -dir at the wrong directory must not look like a clean scan
+	noFiles := report.scanStats.FilesMatched == 0
+	if noFiles {
+		message := fmt.Sprintf("no supported files found in %s (searched for %s)", path, strings.Join(s.Extensions(), ", "))
+		if contents := topLevelContents(path); contents != "" {
+			message += "\n  Top-level contents: " + contents
+		}
+		warnf("%s", message)
+	}
+
+	if len(scanned) == 0 {
+		report.failed = noFiles && *strictEmptyFlag
+		if jsonOutput() {
+			printBatchJSONResults(report)
+		} else if *outputFlag == "tsv" {
+			printBatchTSVResults(report)
+		} else if *outputFlag == "pr-comment" {
+			printBatchPRComment(report, path)
+		} else {
+			if !noFiles {
+				fmt.Print("✅ No OCP Documentation URLs found")
+				if *gitNewOnlyFlag {
+					fmt.Printf(" on lines added since %s", *gitDiffFlag)
+				} else if *gitDiffFlag != "" {
+					fmt.Printf(" in the files changed since %s", *gitDiffFlag)
+				}
+				if report.shard != nil {
+					fmt.Printf(" in shard %s", report.shard)
+				}
+				fmt.Println()
+				if len(report.preexisting) > 0 {
+					fmt.Printf("%d pre-existing URL(s) not checked\n", len(report.preexisting))
+				}
+				if len(report.generated) > 0 {
+					fmt.Printf("%d URL(s) in generated files not checked\n", len(report.generated))
+				}
+			}
+			if *verboseFlag {
+				fmt.Println()
+				printScanStats(report.scanStats)
+			}
+		}
+		writeMetrics(c, path, nil, 0)
+		writeMatrix(c)
+		if report.failed {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if !machineOutput() {
+		fmt.Fprintf(narration.W, "Found %d unique OCP documentation URL(s)", len(urlLocations))
+		if len(report.placeholders) > 0 {
+			fmt.Fprintf(narration.W, " and %d with an unresolved version placeholder", len(report.placeholders))
+		}
+		if len(report.suspicious) > 0 {
+			fmt.Fprintf(narration.W, " and %d with a suspicious host", len(report.suspicious))
+		}
+		if len(report.generated) > 0 {
+			fmt.Fprintf(narration.W, " and %d only in generated files", len(report.generated))
+		}
+		if *gitNewOnlyFlag {
+			fmt.Fprintf(narration.W, " on lines added since %s, besides %d pre-existing", *gitDiffFlag, len(report.preexisting))
+		} else if *gitDiffFlag != "" {
+			fmt.Fprintf(narration.W, " in the files changed since %s", *gitDiffFlag)
+		}
+		if report.shard != nil {
+			fmt.Fprintf(narration.W, " in shard %s", report.shard)
+		}
+		fmt.Fprint(narration.W, "\n\n")
+	}
+
+	// Check all URLs
+	hasOutdated := false
+	hasMalformed := false
+	hasBroken := false
+	// hasDeadEnd is set for a broken URL without a working newer version
+	// that -fix could move it to
+	hasDeadEnd := false
+	hasIndeterminate := false
+	checkErrors := 0
+
+	githubMode := ciMode() == "github" && !machineOutput()
+
+	// A terminal shows the check going on, on a single line redrawn in
+	// place and erased before anything else is printed
+	liveProgress := narration.W == io.Writer(os.Stderr) && output.IsTerminal(os.Stderr)
+	if liveProgress {
+		c.OnProgress(drawProgress)
+	}
+	deadline := newDeadline(run.Started, *softDeadlineFlag, *deadlineMarginFlag)
+	results, errs, notChecked := checkLocations(c, urlLocations, deadline)
+	if liveProgress {
+		c.OnProgress(nil)
+		fmt.Fprint(narration.W, "\r\033[K")
+	}
+	report.notChecked = notChecked
+	for i, loc := range urlLocations {
+		result := results[i]
+		err := errs[i]
+		if result == nil && err == nil {
+			continue // not checked before the soft deadline
+		}
+
+		// Progress goes to stderr, where it cannot break up the report;
+		// it would still interleave with the grouped CI output
+		if *verboseFlag && !githubMode {
+			fmt.Fprintf(narration.W, "[%d/%d] Checked: %s\n", i+1, len(urlLocations), loc.URL)
+		}
+
+		report.urlToLocation[loc.URL] = loc
+
+		if err != nil {
+			message, _ := describeCheckError(err)
+			errorf("could not check URL %s: %s", loc.URL, message)
+			checkErrors++
+			continue
+		}
+
+		if loc.Historical {
+			report.historical = append(report.historical, result)
+			continue
+		}
+
+		report.results = append(report.results, result)
+		if result.IsOutdated {
+			hasOutdated = true
+		}
+		if result.FragmentIssue == parser.FragmentMalformed {
+			hasMalformed = true
+		}
+		if result.Broken {
+			hasBroken = true
+			hasDeadEnd = hasDeadEnd || !result.IsOutdated
+		}
+		if warnIndeterminate(result) {
+			hasIndeterminate = true
+		}
+	}
+	if products != nil {
+		report.wrongProduct = wrongProductLinks(c, report, products, deadline)
+	}
+	report.checkerStats = c.Stats()
+	if len(report.notChecked) > 0 {
+		warnf("soft deadline of %s reached; %d URL(s) not checked", *softDeadlineFlag, len(report.notChecked))
+	}
+
+	// Apply fixes, or plan them in check mode, if requested
+	fixing := fixMode()
+	unfixed := 0
+	unverified := 0
+	wouldChange := false
+	if fixing && (hasOutdated || *preferFormatFlag != "" || *normalizeFlag || campaign != nil) {
+		if *checkFixFlag {
+			wouldChange, unfixed = checkFixes(report.results, report.urlToLocation)
+		} else if campaign != nil {
+			var written []*fixer.FilePlan
+			unfixed, written = runCampaign(report.results, report.urlToLocation)
+			unverified = verifyFixes(c, s, written)
+		} else if *changesetsFlag != "" {
+			unfixed = writeChangesets(report.results, report.urlToLocation)
+		} else {
+			var written []*fixer.FilePlan
+			unfixed, written = applyFixes(report.results, report.urlToLocation)
+			unverified = verifyFixes(c, s, written)
+		}
+	}
+
+	report.failed = (hasOutdated && !fixing) || (hasBroken && !fixing) || hasDeadEnd || wouldChange || unfixed > 0 || unverified > 0 || hasMalformed || len(report.placeholders) > 0 || len(report.suspicious) > 0 || len(report.notChecked) > 0 || (hasIndeterminate && *indeterminateFlag == "fail")
+
+	// Output results
+	if jsonOutput() {
+		printBatchJSONResults(report)
+	} else if *outputFlag == "tsv" {
+		printBatchTSVResults(report)
+	} else if *outputFlag == "pr-comment" {
+		printBatchPRComment(report, path)
+	} else if githubMode {
+		printBatchGitHubResults(report)
+	} else {
+		printBatchTextResults(report, *verboseFlag)
+	}
+
+	writeMetrics(c, path, report.results, checkErrors)
+	writeMatrix(c)
+
+	// Exit with appropriate code
+	if report.failed {
+		os.Exit(1)
+	}
+	if checkErrors > 0 {
+		os.Exit(exitCheckFailed)
+	}
+}
+
+// brokenReason says what is missing at the version of a broken URL
+func brokenReason(result *checker.CheckResult) string {
+	if !result.OriginalExists && result.OriginalRedirectedTo != "" {
+		return "redirects to " + result.OriginalRedirectedTo + " at " + result.OriginalVersion
+	}
+	if !result.OriginalExists {
+		return "page not found at " + result.OriginalVersion
+	}
+	return "anchor missing from the page at " + result.OriginalVersion
+}
+
+// rejectedRedirects returns the versions checked whose page redirects to
+// another page, counted missing without -accept-redirects
+func rejectedRedirects(result *checker.CheckResult) []checker.VersionCheckResult {
+	var versions []checker.VersionCheckResult
+	for _, v := range result.AllResults {
+		if !v.Exists && v.RedirectedTo != "" {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
+
+// closestAnchor returns the newest version after the latest working one
+// whose page lacks the anchor but has ids like it, for a hint at where the
+// section went. The hint is never applied by -fix.
+func closestAnchor(result *checker.CheckResult) (checker.VersionCheckResult, bool) {
+	for i := len(result.AllResults) - 1; i >= 0; i-- {
+		v := result.AllResults[i]
+		if v.Version == result.LatestVersion {
+			break
+		}
+		if len(v.SuggestedAnchors) > 0 {
+			return v, true
+		}
+	}
+	return checker.VersionCheckResult{}, false
+}
+
+// anchorPolicyNote names the -anchor-policy a verdict was reached under,
+// or returns "" when the policy made no difference: no newer page lacks
+// the anchor
+func anchorPolicyNote(result *checker.CheckResult) string {
+	if !slices.ContainsFunc(result.AllResults, checker.VersionCheckResult.AnchorMissing) {
+		return ""
+	}
+	if result.AnchorPolicy == checker.AnchorPolicyPageOnly {
+		return "Anchor policy: page-only (newer pages without the anchor count as newer)"
+	}
+	return "Anchor policy: strict (newer pages without the anchor do not count, see -anchor-policy)"
+}
+
+// missingAnchorNote describes the anchor a newer version lacks when it
+// counts under the page-only policy, or returns "" when it has the anchor
+func missingAnchorNote(v checker.VersionCheckResult) string {
+	if !v.AnchorMissing() {
+		return ""
+	}
+	_, fragment, _ := strings.Cut(v.URL, "#")
+	note := fmt.Sprintf("Anchor #%s missing at %s", fragment, v.Version)
+	if len(v.SuggestedAnchors) > 0 {
+		note += fmt.Sprintf(" (closest: #%s)", v.SuggestedAnchors[0])
+	}
+	return note
+}
+
+// suggestedAnchorURL returns the URL of v with its best suggested anchor
+func suggestedAnchorURL(v checker.VersionCheckResult) string {
+	page, _, _ := strings.Cut(v.URL, "#")
+	return page + "#" + v.SuggestedAnchors[0]
+}
+
+// warnIndeterminate warns about every version of a result whose anchor
+// was not read within -max-page-parse-time and reports whether there was one
+func warnIndeterminate(result *checker.CheckResult) bool {
+	indeterminate := result.Indeterminate()
+	for _, v := range indeterminate {
+		warnf("anchor of %s indeterminate: page too large, %d bytes scanned within -max-page-parse-time %s", v.URL, v.ScannedBytes, *maxParseTimeFlag)
+	}
+	return len(indeterminate) > 0
+}
+
+// Exit codes besides 0 and 1, so that scripts can tell a bad input from a
+// check that could not be done
+const (
+	// exitNotDocURL is the exit code for a -url that is not an OCP
+	// documentation URL
+	exitNotDocURL = 3
+	// exitCheckFailed is the exit code for URLs that could not be checked,
+	// e.g. with the network down, when nothing else failed the run
+	exitCheckFailed = 4
+)
+
+// describeCheckError says why a URL could not be checked, with a hint at
+// the flag that helps when there is one, and returns the exit code for it
+func describeCheckError(err error) (string, int) {
+	var verifyErr *tls.CertificateVerificationError
+	var reqErr *checker.RequestError
+	switch {
+	case errors.Is(err, checker.ErrNotOCPDocURL):
+		return err.Error(), exitNotDocURL
+	case errors.Is(err, checker.ErrPageNotFound) || errors.Is(err, checker.ErrPageTruncated):
+		return err.Error(), 1
+	case !errors.Is(err, checker.ErrAllVersionsFailed) && !errors.As(err, &reqErr):
+		return err.Error(), 1
+	case errors.Is(err, checker.ErrHostNotAllowed):
+		return "blocked by egress policy (see -allow-host): " + err.Error(), exitCheckFailed
+	case errors.Is(err, checker.ErrPinMismatch):
+		return "certificate pin mismatch (see -pin-cert-sha256): " + err.Error(), exitCheckFailed
+	case errors.As(err, &verifyErr):
+		return "untrusted certificate (see -ca-cert): " + err.Error(), exitCheckFailed
+	}
+	return err.Error(), exitCheckFailed
+}
+
+// deadline is the time after which a run starts no new checks
+type deadline struct {
+	at time.Time
+}
+
+// newDeadline returns the deadline of a run started at start with a soft
+// deadline of soft, keeping margin for the checks in progress and writing
+// the outputs. A soft deadline of 0 returns nil, which is never reached.
+func newDeadline(start time.Time, soft, margin time.Duration) *deadline {
+	if soft == 0 {
+		return nil
+	}
+	return &deadline{at: start.Add(soft - margin)}
+}
+
+// context returns a context done at the deadline, or never for a nil one
+func (d *deadline) context() (context.Context, context.CancelFunc) {
+	if d == nil {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithDeadline(context.Background(), d.at)
+}
+
+// drawProgress redraws the progress line of a directory scan with the
+// version check that just completed
+func drawProgress(ev checker.ProgressEvent) {
+	line := fmt.Sprintf("Checking URLs: %d/%d done, %s of %s", ev.Completed, ev.Total, ev.Version, ev.URL)
+	// One column short of the width, so the cursor never wraps the line
+	fmt.Fprintf(narration.W, "\r%s\033[K", output.TruncateMiddle(line, narration.Width-1))
+}
+
+// checkLocations checks every location with CheckAll, starting no check
+// after the deadline; a check in progress is never interrupted. It returns
+// the results by location index, nil for locations not checked, the errors
+// of the locations that could not be checked, and the locations not
+// started before the deadline, in order.
+func checkLocations(c *checker.Checker, locations []scanner.Location, d *deadline) ([]*checker.CheckResult, checker.CheckErrors, []scanner.Location) {
+	urls := make([]string, len(locations))
+	for i, loc := range locations {
+		urls[i] = loc.URL
+	}
+
+	ctx, cancel := d.context()
+	defer cancel()
+	results, err := c.CheckAllContext(ctx, urls)
+
+	errs := make(checker.CheckErrors)
+	var checkErrs checker.CheckErrors
+	errors.As(err, &checkErrs)
+	var notChecked []scanner.Location
+	for i, loc := range locations {
+		switch err := checkErrs[i]; {
+		case err != nil && err == ctx.Err():
+			notChecked = append(notChecked, loc)
+		case err != nil:
+			errs[i] = err
+		}
+	}
+	return results, errs, notChecked
+}
+
+// writeMetrics writes the run metrics to -metrics-file, if set. root is
+// the scanned path, empty when checking a single URL.
+func writeMetrics(c *checker.Checker, root string, results []*checker.CheckResult, checkErrors int) {
+	if *metricsFileFlag == "" {
+		return
+	}
+
+	m := output.NewMetrics(root, results)
+	m.CheckErrors = checkErrors
+	m.Duration = time.Since(run.Started)
+	m.RunID = run.ID
+	m.HTTPRequests = c.Requests()
+	if err := output.WriteMetricsFile(*metricsFileFlag, m); err != nil {
+		warnf("could not write metrics file: %v", err)
+	}
+}
+
+// writeMatrix writes the page facts of the run to -export-matrix, if set
+func writeMatrix(c *checker.Checker) {
+	if *exportMatrixFlag == "" {
+		return
+	}
+	if err := c.ExportMatrixFile(*exportMatrixFlag); err != nil {
+		warnf("could not write matrix file: %v", err)
+	}
+}
+
+// mergeReports merges the JSON reports of the shards of a run, writes the
+// report of the complete run and exits 1 when any shard failed
+func mergeReports(paths []string) {
+	var batches []output.Batch
+	for _, path := range paths {
+		b, err := output.ReadBatchFile(path)
+		if err != nil {
+			errorf("could not read report: %v", err)
+			os.Exit(1)
+		}
+		batches = append(batches, b)
+	}
+
+	merged, err := output.MergeShards(batches)
+	if err != nil {
+		errorf("could not merge reports: %v", err)
+		os.Exit(1)
+	}
+	merged.RunID = run.ID
+	if err := output.WriteJSON(os.Stdout, merged); err != nil {
+		errorf("could not write results: %v", err)
+	}
+	if merged.Failed {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// resolveInput catches a -url that names a file or directory and a -dir
+// that is a URL. With auto, the value is used as the other kind of input and
+// a note explains it; otherwise the error suggests the right flag. A -dir
+// that looks like a URL but exists as a path is used as given.
+func resolveInput(url, dir string, auto bool, exists func(string) bool) (string, string, string, error) {
+	switch {
+	case url != "" && !hasHTTPScheme(url) && exists(url):
+		if !auto {
+			return "", "", "", fmt.Errorf("-url %q is a file or directory, not a URL; use -dir %q to scan it, or add -auto", url, url)
+		}
+		return "", url, fmt.Sprintf("-url %q is a file or directory, scanning it as -dir", url), nil
+	case dir != "" && looksLikeURL(dir) && !exists(dir):
+		if !auto {
+			return "", "", "", fmt.Errorf("-dir %q is a URL, not a path; use -url %q to check it, or add -auto", dir, dir)
+		}
+		if !hasHTTPScheme(dir) {
+			dir = "https://" + dir
+		}
+		return dir, "", fmt.Sprintf("-dir value is a URL, checking it as -url %s", dir), nil
+	}
+	return url, dir, "", nil
+}
+
+// hasHTTPScheme reports whether s starts with http:// or https://
+func hasHTTPScheme(s string) bool {
+	lower := strings.ToLower(s)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// looksLikeURL reports whether s is an http(s) URL or a documentation URL
+// without its scheme
+func looksLikeURL(s string) bool {
+	return hasHTTPScheme(s) || strings.HasPrefix(strings.ToLower(s), checker.DefaultAllowedHost+"/")
+}
+
+// pathExists reports whether path names an existing file or directory
+func pathExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// topLevelContents lists the first entries of a directory, directories
+// marked with a trailing slash, or returns "" if it cannot be read
+func topLevelContents(dir string) string {
+	const maxEntries = 10
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) == 0 {
+		return ""
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if len(names) == maxEntries {
+			names = append(names, fmt.Sprintf("… and %d more", len(entries)-maxEntries))
+			break
+		}
+		name := entry.Name()
+		if entry.IsDir() {
+			name += "/"
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// formatAliases lists resolved version aliases as "name = version", sorted
+// by name
+func formatAliases(resolved map[string]string) string {
+	names := make([]string, 0, len(resolved))
+	for name := range resolved {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + " = " + resolved[name]
+	}
+	return strings.Join(parts, ", ")
+}
+
+// fixMode reports whether fixes are applied, checked, written as
+// changesets or applied by a campaign
+func fixMode() bool {
+	return *fixFlag || *checkFixFlag || *changesetsFlag != "" || *campaignFlag != ""
+}
+
+// fixOptions returns the fixer options set by the flags
+func fixOptions() fixer.Options {
+	return fixer.Options{
+		FixLinkText:        *fixLinkTextFlag,
+		AllowCrossDocument: *crossDocumentFlag,
+		AllowMovedAnchor:   *fixAggressiveFlag,
+		AllowDroppedAnchor: *fixAggressiveFlag,
+	}
+}
+
+// applyFixes updates files with the latest URLs and returns the number of
+// occurrences left outdated and the plans of the files written
+func applyFixes(results []*checker.CheckResult, urlToLocation map[string]scanner.Location) (int, []*fixer.FilePlan) {
+	fmt.Fprintln(narration.W)
+	narration.Heading("🔧 Applying Fixes...")
+	fmt.Fprintln(narration.W)
+
+	files, targets := fixer.Targets(results, urlToLocation, *preferFormatFlag, *normalizeFlag)
+	opts := fixOptions()
+	fixedFiles := 0
+	fixCount := 0
+	unfixed := 0
+	var crossDocument []fixer.Change
+	var written []*fixer.FilePlan
+
+	// Update each file
+	for _, filePath := range files {
+		plan, err := fixer.FixFile(filePath, targets[filePath], opts, true)
+		if err != nil {
+			errorf("could not fix %s: %v", filePath, err)
+			unfixed += len(targets[filePath])
+			continue
+		}
+
+		for _, err := range plan.Errors {
+			errorf("%v", err)
+		}
+		unfixed += plan.Unfixed()
+		crossDocument = append(crossDocument, plan.CrossDocument()...)
+		if plan.Modifies() {
+			fixedFiles++
+			written = append(written, plan)
+		}
+
+		for _, change := range plan.Changes {
+			occ, r := change.Occurrence, change.Replacement
+
+			switch change.Outcome {
+			case fixer.OutcomeEncoded:
+				fmt.Fprintf(narration.W, "⚠️  Skipped: %s:%d: encoded occurrence — manual fix required\n", occ.Path, occ.Line)
+				fmt.Fprintf(narration.W, "   Key: %s (%s encoded)\n", occ.KeyPath, occ.Encoding)
+				fmt.Fprintf(narration.W, "   %s → %s\n", r.OldVersion, r.NewVersion)
+				fmt.Fprintf(narration.W, "   Old: %s\n", r.OldURL)
+				fmt.Fprintf(narration.W, "   New: %s\n\n", r.NewURL)
+				continue
+			case fixer.OutcomeHistorical:
+				fmt.Fprintf(narration.W, "📜 Skipped: %s:%d: historical reference — left as written\n", occ.Path, occ.Line)
+				fmt.Fprintf(narration.W, "   URL: %s\n\n", r.OldURL)
+				continue
+			case fixer.OutcomeLinkTextReview:
+				fmt.Fprintf(narration.W, "⚠️  Skipped: %s:%d: link text mentions old version — manual review\n", occ.Path, occ.Line)
+				fmt.Fprintf(narration.W, "   Link text: %s\n", occ.LinkText)
+				fmt.Fprintf(narration.W, "   URL: %s\n", r.OldURL)
+				fmt.Fprintf(narration.W, "   (Use -fix-link-text to update the link text too)\n\n")
+				continue
+			case fixer.OutcomeCrossDocument:
+				fmt.Fprintf(narration.W, "⚠️  Skipped: %s:%d: %s moved to another guide — needs a decision\n", occ.Path, occ.Line, r.NewVersion)
+				fmt.Fprintf(narration.W, "   Guide: %s\n", guideChange(r))
+				fmt.Fprintf(narration.W, "   Old: %s\n", r.OldURL)
+				fmt.Fprintf(narration.W, "   New: %s\n\n", r.NewURL)
+				continue
+			case fixer.OutcomeMovedAnchor:
+				fmt.Fprintf(narration.W, "⚠️  Skipped: %s:%d: section found on another page at %s — needs a check\n", occ.Path, occ.Line, r.NewVersion)
+				fmt.Fprintf(narration.W, "   Old: %s\n", r.OldURL)
+				fmt.Fprintf(narration.W, "   New: %s\n", r.NewURL)
+				fmt.Fprintf(narration.W, "   (Use -fix-aggressive to fix URLs found by -search-sibling-pages)\n\n")
+				continue
+			case fixer.OutcomeDroppedAnchor:
+				fmt.Fprintf(narration.W, "⚠️  Skipped: %s:%d: page at %s lacks #%s — needs a check\n", occ.Path, occ.Line, r.NewVersion, r.DroppedAnchor)
+				fmt.Fprintf(narration.W, "   Old: %s\n", r.OldURL)
+				fmt.Fprintf(narration.W, "   New: %s\n", r.NewURL)
+				fmt.Fprintf(narration.W, "   (Use -fix-aggressive to fix URLs without their anchor)\n\n")
+				continue
+			}
+
+			fixCount++
+			fmt.Fprintf(narration.W, "✅ Updated: %s:%d\n", occ.Path, occ.Line)
+			if r.OldVersion == r.NewVersion {
+				fmt.Fprintln(narration.W, "   Spelling normalized")
+			} else {
+				fmt.Fprintf(narration.W, "   %s → %s\n", r.OldVersion, r.NewVersion)
+			}
+			if r.RenamedFrom != "" {
+				fmt.Fprintf(narration.W, "   Page renamed: %s\n", r.RenamedFrom)
+			}
+			if r.MovedFrom != "" {
+				fmt.Fprintf(narration.W, "   Section moved from page: %s\n", r.MovedFrom)
+			}
+			if r.DroppedAnchor != "" {
+				fmt.Fprintf(narration.W, "   Anchor dropped: #%s (missing at %s)\n", r.DroppedAnchor, r.NewVersion)
+			}
+			if r.CrossDocument() {
+				fmt.Fprintf(narration.W, "   Guide: %s\n", guideChange(r))
+			}
+			if change.Outcome == fixer.OutcomeLinkTextUpdated {
+				fmt.Fprintf(narration.W, "   Link text: %s → %s\n", occ.LinkText, change.NewLinkText)
+			}
+			fmt.Fprintf(narration.W, "   Old: %s\n", r.OldURL)
+			fmt.Fprintf(narration.W, "   New: %s\n\n", r.NewURL)
+		}
+	}
+
+	narration.Rule("=")
+	fmt.Fprintf(narration.W, "Summary: Fixed %d URL(s) in %d file(s)", fixCount, fixedFiles)
+	if unfixed > 0 {
+		fmt.Fprintf(narration.W, ", %d left for manual review", unfixed)
+	}
+	fmt.Fprintln(narration.W)
+	printCrossDocument(crossDocument)
+	narration.Rule("=")
+	fmt.Fprintln(narration.W)
+
+	return unfixed, written
+}
+
+// autoVerifyMaxFixes is the number of fixed URLs from which
+// -verify-after-fix auto skips the verification, to keep large runs from
+// requesting every new URL again
+const autoVerifyMaxFixes = 100
+
+// verifyFixes reads the files written by applyFixes or a campaign step
+// back, as set by -verify-after-fix, and requests every new URL they hold
+// at its own version. It reports each fix that did not land or whose new URL does not
+// resolve and returns how many there are.
+func verifyFixes(c *checker.Checker, s *scanner.Scanner, plans []*fixer.FilePlan) int {
+	newURLs := make(map[string][]string) // new URL to the files holding it
+	var urls []string
+	for _, plan := range plans {
+		for _, change := range plan.Changes {
+			if len(change.Edits) == 0 {
+				continue
+			}
+			url := change.Replacement.NewURL
+			if _, ok := newURLs[url]; !ok {
+				urls = append(urls, url)
+			}
+			if !slices.Contains(newURLs[url], plan.Path) {
+				newURLs[url] = append(newURLs[url], plan.Path)
+			}
+		}
+	}
+	switch {
+	case len(urls) == 0, *verifyAfterFixFlag == "never":
+		return 0
+	case *verifyAfterFixFlag == "auto" && len(urls) >= autoVerifyMaxFixes:
+		notef("%d URL(s) fixed; not verifying them (use -verify-after-fix always to verify)", len(urls))
+		return 0
+	}
+
+	narration.Heading("🔍 Verifying Fixes...")
+	failures := 0
+	for _, plan := range plans {
+		for _, err := range plan.VerifyWritten(s) {
+			errorf("%v", err)
+			failures++
+		}
+	}
+	for _, url := range urls {
+		v, err := c.VerifyURL(context.Background(), url)
+		var problem string
+		switch {
+		case err != nil:
+			problem = err.Error()
+		case v.Error != nil:
+			problem = v.Error.Error()
+		case !v.Exists:
+			problem = "page not found"
+		case v.AnchorIndeterminate:
+			// The parse budget already warned about the page being too large
+			continue
+		case v.HasAnchor && !v.AnchorExists:
+			problem = "anchor missing from the page"
+		default:
+			continue
+		}
+		for _, path := range newURLs[url] {
+			errorf("%v: %s: new URL does not resolve (%s): %s", fixer.ErrFixNotWritten, path, problem, url)
+			failures++
+		}
+	}
+
+	if failures > 0 {
+		fmt.Fprintf(narration.W, "❌ Post-fix verification failed: %d problem(s) in %d file(s) and %d new URL(s) checked\n\n", failures, len(plans), len(urls))
+	} else {
+		fmt.Fprintf(narration.W, "✅ Verified %d file(s) and %d new URL(s)\n\n", len(plans), len(urls))
+	}
+	return failures
+}
+
+// guideChange describes the move of a cross-document replacement from one
+// guide to another, by title when known and by slug otherwise
+func guideChange(r fixer.Replacement) string {
+	oldDoc, newDoc := r.ServedDocument, r.ServedDocument
+	if docURL, err := parser.ParseOCPDocURL(r.OldURL); err == nil {
+		oldDoc = docURL.Document
+	}
+	name := func(title, slug string) string {
+		if title == "" {
+			return slug
+		}
+		return fmt.Sprintf("%s (%s)", title, slug)
+	}
+	return name(r.OldTitle, oldDoc) + " → " + name(r.NewTitle, newDoc)
+}
+
+// printCrossDocument lists the changes that move readers to another guide
+// at the end of a fix summary, so a human can decide on them
+func printCrossDocument(changes []fixer.Change) {
+	if len(changes) == 0 {
+		return
+	}
+	fmt.Fprintf(narration.W, "\n⚠️  %d URL(s) moved to another guide, where guides were consolidated:\n", len(changes))
+	for _, change := range changes {
+		occ, r := change.Occurrence, change.Replacement
+		fmt.Fprintf(narration.W, "  %s:%d: %s at %s\n", occ.Path, occ.Line, guideChange(r), r.NewVersion)
+	}
+	if *crossDocumentFlag {
+		fmt.Fprintln(narration.W, "  Fixed as allowed by -allow-cross-document-fix")
+	} else {
+		fmt.Fprintln(narration.W, "  Left unchanged; review them, then use -allow-cross-document-fix to fix them")
+	}
+}
+
+// checkFixes builds the same plan as applyFixes without writing anything,
+// prints the files it would change and returns whether any would change and
+// the number of occurrences it would leave outdated
+func checkFixes(results []*checker.CheckResult, urlToLocation map[string]scanner.Location) (bool, int) {
+	fmt.Fprintln(narration.W)
+	narration.Heading("🔎 Checking Fixes (no files are written)...")
+	fmt.Fprintln(narration.W)
+
+	files, targets := fixer.Targets(results, urlToLocation, *preferFormatFlag, *normalizeFlag)
+	opts := fixOptions()
+	changedFiles := 0
+	editCount := 0
+	unfixed := 0
+	var crossDocument []fixer.Change
+
+	for _, filePath := range files {
+		plan, err := fixer.FixFile(filePath, targets[filePath], opts, false)
+		if err != nil {
+			errorf("could not plan fixes for %s: %v", filePath, err)
+			unfixed += len(targets[filePath])
+			continue
+		}
+
+		for _, err := range plan.Errors {
+			errorf("%v", err)
+		}
+		unfixed += plan.Unfixed()
+		crossDocument = append(crossDocument, plan.CrossDocument()...)
+
+		if edits := len(plan.Edits()); edits > 0 {
+			changedFiles++
+			editCount += edits
+			fmt.Fprintf(narration.W, "%s: %d edit(s)\n", filePath, edits)
+		}
+	}
+
+	if changedFiles > 0 {
+		fmt.Fprintln(narration.W)
+	}
+	narration.Rule("=")
+	fmt.Fprintf(narration.W, "Summary: -fix would change %d file(s) with %d edit(s)", changedFiles, editCount)
+	if unfixed > 0 {
+		fmt.Fprintf(narration.W, ", %d left for manual review", unfixed)
+	}
+	fmt.Fprintln(narration.W)
+	printCrossDocument(crossDocument)
+	narration.Rule("=")
+	fmt.Fprintln(narration.W)
+
+	return changedFiles > 0, unfixed
+}
+
+// writeChangesets writes the fixes -fix would make to -fix-changesets as one
+// patch per (document, target version) and returns the number of
+// occurrences left outdated
+func writeChangesets(results []*checker.CheckResult, urlToLocation map[string]scanner.Location) int {
+	fmt.Fprintln(narration.W)
+	narration.Heading("📦 Writing Changesets (no files are modified)...")
+	fmt.Fprintln(narration.W)
+
+	files, targets := fixer.Targets(results, urlToLocation, *preferFormatFlag, *normalizeFlag)
+	opts := fixOptions()
+	var plans []*fixer.FilePlan
+	var crossDocument []fixer.Change
+	unfixed := 0
+
+	for _, filePath := range files {
+		plan, err := fixer.FixFile(filePath, targets[filePath], opts, false)
+		if err == nil {
+			err = plan.Patchable()
+		}
+		if err != nil {
+			errorf("could not plan fixes for %s: %v", filePath, err)
+			unfixed += len(targets[filePath])
+			continue
+		}
+
+		for _, err := range plan.Errors {
+			errorf("%v", err)
+		}
+		unfixed += plan.Unfixed()
+		crossDocument = append(crossDocument, plan.CrossDocument()...)
+		plans = append(plans, plan)
+	}
+
+	var patches []string
+	changesets, err := fixer.Changesets(plans)
+	if err == nil {
+		var cwd string
+		if cwd, err = os.Getwd(); err == nil {
+			patches, err = fixer.WriteChangesets(*changesetsFlag, cwd, changesets)
+		}
+	}
+	if err != nil {
+		errorf("could not write changesets: %v", err)
+		os.Exit(1)
+	}
+
+	for i, cs := range changesets {
+		fmt.Fprintf(narration.W, "%s: %s (%d edit(s) in %d file(s))\n", patches[i], cs.Title(), cs.Edits(), len(cs.Files()))
+		if len(cs.Groups) > 1 {
+			fmt.Fprintln(narration.W, "   Merged: these documents are edited on the same or nearby lines")
+		}
+	}
+	if len(changesets) > 0 {
+		fmt.Fprintln(narration.W)
+	}
+
+	narration.Rule("=")
+	fmt.Fprintf(narration.W, "Summary: Wrote %d changeset(s) to %s", len(changesets), *changesetsFlag)
+	if unfixed > 0 {
+		fmt.Fprintf(narration.W, ", %d left for manual review", unfixed)
+	}
+	fmt.Fprintln(narration.W)
+	printCrossDocument(crossDocument)
+	fmt.Fprintln(narration.W, "Apply them from this directory with: git apply <patch>")
+	narration.Rule("=")
+	fmt.Fprintln(narration.W)
+
+	return unfixed
+}
+
+// loadCampaign reads the campaign state file at path, or starts a campaign
+// to target when there is none yet
+func loadCampaign(path, target string) (*fixer.Campaign, error) {
+	c, err := fixer.LoadCampaign(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		if target == "" {
+			return nil, fmt.Errorf("campaign %s does not exist; start it with -campaign-target", path)
+		}
+		return fixer.NewCampaign(target), nil
+	case err != nil:
+		return nil, err
+	case target != "" && target != c.TargetVersion:
+		return nil, fmt.Errorf("-campaign-target %s does not match the target version %s of campaign %s", target, c.TargetVersion, path)
+	}
+	return c, nil
+}
+
+// runCampaign reconciles the campaign with the fixes of the run, applies
+// the next -campaign-step groups, saves the campaign and reports its
+// progress. It returns the number of groups that failed and the plans of
+// the files written, to verify like those of -fix.
+func runCampaign(results []*checker.CheckResult, urlToLocation map[string]scanner.Location) (int, []*fixer.FilePlan) {
+	fmt.Fprintln(narration.W)
+	if *campaignStepFlag > 0 {
+		narration.Heading(fmt.Sprintf("🗺️  Upgrade Campaign to %s: Applying %d Group(s)...", campaign.TargetVersion, *campaignStepFlag))
+	} else {
+		narration.Heading(fmt.Sprintf("🗺️  Upgrade Campaign to %s (no files are written)...", campaign.TargetVersion))
+	}
+	fmt.Fprintln(narration.W)
+
+	files, targets := fixer.Targets(results, urlToLocation, *preferFormatFlag, *normalizeFlag)
+	campaign.RunID = run.ID
+	step := campaign.Step(files, targets, fixOptions(), *campaignStepFlag)
+	if err := campaign.Save(*campaignFlag); err != nil {
+		errorf("could not save campaign: %v", err)
+		os.Exit(1)
+	}
+
+	for _, plan := range step.Plans {
+		for _, change := range plan.Changes {
+			if len(change.Edits) == 0 {
+				continue
+			}
+			occ, r := change.Occurrence, change.Replacement
+			fmt.Fprintf(narration.W, "✅ Updated: %s:%d\n", occ.Path, occ.Line)
+			fmt.Fprintf(narration.W, "   %s → %s\n", r.OldVersion, r.NewVersion)
+			fmt.Fprintf(narration.W, "   Old: %s\n", r.OldURL)
+			fmt.Fprintf(narration.W, "   New: %s\n\n", r.NewURL)
+		}
+	}
+	for _, g := range step.Applied {
+		fmt.Fprintf(narration.W, "✅ Applied: %s\n", g.Name())
+	}
+	for _, g := range step.Failed {
+		fmt.Fprintf(narration.W, "❌ Failed: %s: %s\n", g.Name(), g.Error)
+	}
+	for _, g := range step.Regressed {
+		fmt.Fprintf(narration.W, "↩️  Regressed: %s has outdated URLs again and is pending again\n", g.Name())
+	}
+	if len(step.Applied)+len(step.Failed)+len(step.Regressed) > 0 {
+		fmt.Fprintln(narration.W)
+	}
+
+	applied, total := campaign.Progress()
+	review := 0
+	for _, g := range campaign.Groups {
+		review += g.Review
+	}
+	narration.Rule("=")
+	fmt.Fprintf(narration.W, "Campaign: %d/%d group(s) applied (%d%%) in %d step(s)", applied, total, campaign.Percent(), campaign.Steps)
+	if review > 0 {
+		fmt.Fprintf(narration.W, ", %d left for manual review", review)
+	}
+	fmt.Fprintln(narration.W)
+	if remaining := campaign.Remaining(); len(remaining) > 0 {
+		fmt.Fprintln(narration.W, "Remaining, in the order of the next steps:")
+		for _, g := range remaining {
+			fmt.Fprintf(narration.W, "  %s: %d edit(s) in %d file(s)", g.Name(), g.Edits, len(g.Files))
+			if g.Status == fixer.CampaignFailed {
+				fmt.Fprintf(narration.W, " (failed in step %d)", g.Step)
+			}
+			fmt.Fprintln(narration.W)
+		}
+		fmt.Fprintln(narration.W, "Continue with: -campaign-step <groups>")
+	} else {
+		fmt.Fprintln(narration.W, "Campaign complete")
+	}
+	narration.Rule("=")
+	fmt.Fprintln(narration.W)
+
+	return len(step.Failed), step.Plans
+}
+
+func printTextResults(result *checker.CheckResult, verbose bool) {
+	text.URLLine("Checking: ", result.OriginalURL, "")
+	fmt.Printf("Current Version: %s\n", result.OriginalVersion)
+	if result.OriginalDeprecated {
+		fmt.Println("⚠️  current version is EOL")
+	}
+	if label := documentLabel(result); label != "" {
+		fmt.Printf("Document: %s\n", label)
+	}
+	if result.AsOf != "" {
+		fmt.Println(asOfNote(result.AsOf))
+	}
+	printNotes("", result)
+	if verbose && len(result.AppliedHeuristics) > 0 {
+		fmt.Printf("Heuristics applied: %s\n", joinHeuristics(result.AppliedHeuristics))
+	}
+	text.Rule("-")
+
+	if result.FragmentIssue == parser.FragmentMalformed {
+		fmt.Println("❌ This URL has a MALFORMED FRAGMENT")
+		fmt.Println("The anchor contains characters that can never appear in an id (whitespace or an extra '#'), so it was not checked.")
+		return
+	}
+
+	if result.SuggestedURL != "" {
+		fmt.Printf("⚠️  %s; normalize the URL to:\n", normalizeReason(result))
+		text.URLLine("  ", result.SuggestedURL, "")
+		fmt.Println()
+	}
+
+	if excluded, ok := result.NewestExcluded(); ok {
+		fmt.Printf("ℹ️  %s available but excluded by target policy\n\n", excluded.Version)
+	}
+
+	if result.Broken {
+		fmt.Printf("❌ This URL is BROKEN: %s\n", brokenReason(result))
+		if !result.IsOutdated {
+			fmt.Println("No newer version has the page and anchor either; the link needs a manual fix.")
+			if note := anchorPolicyNote(result); note != "" {
+				fmt.Println(note)
+			}
+			if v, ok := closestAnchor(result); ok {
+				text.URLLine(fmt.Sprintf("Closest anchor at %s: ", v.Version), suggestedAnchorURL(v), "")
+			}
+			if verbose {
+				fmt.Println("\nChecked versions:")
+				printVersionRuns(result.AllResults, false)
+			}
+			return
+		}
+		fmt.Println()
+	}
+
+	if result.IsOutdated {
+		fmt.Printf("⚠️  This documentation is OUTDATED!\n")
+		if note := anchorPolicyNote(result); note != "" {
+			fmt.Println(note)
+		}
+		fmt.Printf("Latest Version: %s\n\n", result.LatestVersion)
+
+		if *allAvailableFlag {
+			fmt.Println("Available newer versions:")
+			for _, v := range result.NewerVersions {
+				mark := "✓"
+				if v.AnchorMissing() {
+					mark = "⚠"
+				}
+				text.URLLine(fmt.Sprintf("  %s Version %s: ", mark, v.Version), v.URL, "")
+			}
+		} else {
+			// Show only the best suggestion
+			if latest, ok := result.BestSuggestion(); ok {
+				fmt.Printf("Latest available version:\n")
+				text.URLLine(fmt.Sprintf("  ✓ Version %s: ", latest.Version), latest.URL, "")
+				if latest.RedirectedTo != "" {
+					text.URLLine("    Redirects to: ", latest.RedirectedTo, "")
+				}
+				if note := missingAnchorNote(latest); note != "" {
+					fmt.Printf("    ⚠️  %s\n", note)
+				}
+
+				if len(result.NewerVersions) > 1 {
+					fmt.Printf("\n(Use --all-available to see all %d newer versions)\n", len(result.NewerVersions))
+				}
+			}
+		}
+
+		if verbose {
+			if best, ok := result.BestSuggestion(); ok {
+				if runnerUp, reason, ok := result.RunnerUp(); ok {
+					fmt.Printf("\nChose %s over %s: %s\n", best.Version, runnerUp.Version, reason)
+				}
+			}
+			fmt.Println("\nAll checked versions:")
+			printVersionRuns(result.AllResults, true)
+		}
+	} else {
+		fmt.Printf("✓ This documentation is UP TO DATE (version %s)\n", result.LatestVersion)
+		if note := anchorPolicyNote(result); note != "" {
+			fmt.Println(note)
+		}
+
+		// Check if there are newer versions with missing anchors
+		missingAnchors := []checker.VersionCheckResult{}
+		for _, v := range result.AllResults {
+			if v.Exists && v.HasAnchor && !v.AnchorExists {
+				missingAnchors = append(missingAnchors, v)
+			}
 		}
 
 		if len(missingAnchors) > 0 {
 			fmt.Println("\n⚠️  Note: Newer versions exist but the anchor is missing:")
 			for _, v := range missingAnchors {
-				fmt.Printf("  - Version %s: page exists but anchor not found\n", v.Version)
+				fmt.Printf("  - Version %s: page exists but anchor not found", v.Version)
+				if len(v.SuggestedAnchors) > 0 {
+					fmt.Printf(" (closest: #%s)", v.SuggestedAnchors[0])
+				}
+				fmt.Println()
+			}
+		}
+		if redirects := rejectedRedirects(result); len(redirects) > 0 {
+			fmt.Println("\n⚠️  Note: Newer versions redirect to another page, counted as missing (see -accept-redirects):")
+			for _, v := range redirects {
+				text.URLLine(fmt.Sprintf("  - Version %s: ", v.Version), v.RedirectedTo, "")
 			}
 		}
 
 		if verbose {
 			fmt.Println("\nChecked versions:")
-			for _, v := range result.AllResults {
-				status := "✗ Not found"
-				if v.Exists {
-					if v.HasAnchor {
-						if v.AnchorExists {
-							status = "✓ Found (page + anchor)"
-						} else {
-							status = "⚠ Page found, anchor missing"
-						}
-					} else {
-						status = "✓ Found"
-					}
-				}
-				fmt.Printf("  %s Version %s\n", status, v.Version)
+			printVersionRuns(result.AllResults, false)
+		}
+	}
+}
+
+// printVersionRuns prints checked versions, collapsing consecutive versions
+// with the same outcome into one line and marking each version after which
+// the outcome changes. With urls, working versions are listed one by one
+// with their URL.
+func printVersionRuns(results []checker.VersionCheckResult, urls bool) {
+	runs := output.ClusterVersions(results, versionStatus)
+	for i, run := range runs {
+		if i > 0 {
+			prev := runs[i-1]
+			fmt.Printf("  ↳ After %s: %s → %s\n", prev.Last(), prev.Status, run.Status)
+		}
+
+		first := run.Results[0]
+		working := first.Error == nil && first.Exists && (!first.HasAnchor || first.AnchorExists)
+		if len(run.Results) > 1 && !(urls && working) {
+			var slowest time.Duration
+			for _, v := range run.Results {
+				slowest = max(slowest, v.Duration)
+			}
+			took := ""
+			if slowest > 0 {
+				took = ", slowest " + elapsed(slowest)
+			}
+			fmt.Printf("  %s Versions %s (%d%s)\n", run.Status, run.Versions(), len(run.Results), took)
+			continue
+		}
+		for _, v := range run.Results {
+			took := ""
+			if v.Duration > 0 {
+				took = " (" + elapsed(v.Duration) + ")"
+			}
+			if urls {
+				fmt.Printf("  %s Version %s%s: %s\n", run.Status, v.Version, took, v.URL)
+			} else {
+				fmt.Printf("  %s Version %s%s\n", run.Status, v.Version, took)
 			}
 		}
 	}
 }
 
-func printJSONResults(result *checker.CheckResult) {
-	fmt.Printf(`{
-  "original_url": "%s",
-  "original_version": "%s",
-  "latest_version": "%s",
-  "is_outdated": %t,
-  "newer_versions": [
-`, result.OriginalURL, result.OriginalVersion, result.LatestVersion, result.IsOutdated)
+// elapsed formats how long checking a version took
+func elapsed(d time.Duration) string {
+	switch {
+	case d < time.Millisecond:
+		return "<1ms"
+	case d < time.Second:
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
+
+// normalizeReason says why a result has a suggested normalized URL
+func normalizeReason(result *checker.CheckResult) string {
+	switch {
+	case result.VersionIssue == parser.VersionNonCanonical && result.FragmentIssue == parser.FragmentDuplicated:
+		return "Non-canonical version format and duplicated fragment"
+	case result.VersionIssue == parser.VersionNonCanonical:
+		return "Non-canonical version format"
+	default:
+		return "Duplicated fragment"
+	}
+}
+
+// missingLocale counts the occurrences of a location spelled without a
+// locale
+func missingLocale(loc scanner.Location) int {
+	n := 0
+	for _, occ := range loc.Occurrences {
+		if scanner.MissingLocale(occ.URL) {
+			n++
+		}
+	}
+	return n
+}
 
-	for i, v := range result.NewerVersions {
-		comma := ","
-		if i == len(result.NewerVersions)-1 {
-			comma = ""
+// trackingSpellings counts the occurrences of a location spelled with
+// tracking parameters, which the scan left out of its URL
+func trackingSpellings(loc scanner.Location) int {
+	n := 0
+	for _, occ := range loc.Occurrences {
+		if scanner.Query(occ.URL) != scanner.Query(loc.URL) {
+			n++
 		}
-		fmt.Printf(`    {"version": "%s", "url": "%s"}%s
-`, v.Version, v.URL, comma)
 	}
+	return n
+}
+
+// printNotes prints the notes of a result, such as those result hooks
+// attached
+func printNotes(indent string, result *checker.CheckResult) {
+	if result.LowConfidence {
+		fmt.Printf("%s⚠️  Low confidence: a result hook failed\n", indent)
+	}
+	for _, note := range result.Notes {
+		fmt.Printf("%sNote: %s\n", indent, note)
+	}
+}
+
+// versionStatus describes the outcome of checking a single version
+func versionStatus(v checker.VersionCheckResult) string {
+	if v.NotChecked {
+		return "– Skipped: " + v.SkippedReason
+	}
+	if errors.Is(v.Error, checker.ErrHostNotAllowed) {
+		return "⛔ Blocked by egress policy"
+	}
+	if errors.Is(v.Error, checker.ErrPinMismatch) {
+		return "⛔ Certificate pin mismatch"
+	}
+	var verifyErr *tls.CertificateVerificationError
+	if errors.As(v.Error, &verifyErr) {
+		return "⛔ Untrusted certificate (see -ca-cert)"
+	}
+	if v.Error != nil {
+		return "⚠ Request failed" + httpStatus(v) + retries(v)
+	}
+	if !v.Exists && v.RedirectedTo != "" {
+		return "↪ Redirects to " + v.RedirectedTo + " (see -accept-redirects)" + retries(v)
+	}
+	if !v.Exists && v.NotFoundMarker != "" {
+		return fmt.Sprintf("✗ Not found (soft 404: %q)", v.NotFoundMarker) + retries(v)
+	}
+	if !v.Exists {
+		return "✗ Not found" + httpStatus(v) + retries(v)
+	}
+
+	status := "⚠ Page found, anchor missing"
+	if v.AnchorIndeterminate {
+		status = fmt.Sprintf("❓ Anchor indeterminate (page too large, %d bytes scanned)", v.ScannedBytes)
+	} else if !v.HasAnchor {
+		status = "✓ Found"
+	} else if v.AnchorExists && v.AnchorText != "" {
+		status = fmt.Sprintf("✓ Found (anchor: %q)", v.AnchorText)
+	} else if v.AnchorExists {
+		status = "✓ Found (page + anchor)"
+	}
+	status += retries(v)
+	if v.RenamedFrom != "" {
+		status += fmt.Sprintf(" (page renamed from %s)", v.RenamedFrom)
+	}
+	if v.MovedFrom != "" {
+		status += fmt.Sprintf(" (section moved from page %s)", v.MovedFrom)
+	}
+	if v.RedirectedTo != "" {
+		status += fmt.Sprintf(" (redirects to %s)", v.RedirectedTo)
+	}
+	if v.AnchorVia != "" {
+		status += fmt.Sprintf(" (anchor verified via %s)", v.AnchorVia)
+	}
+	if v.Cached {
+		status += " (imported)"
+	}
+	return status
+}
+
+// joinHeuristics lists heuristics separated by commas
+func joinHeuristics(heuristics []checker.Heuristic) string {
+	names := make([]string, len(heuristics))
+	for i, h := range heuristics {
+		names[i] = string(h)
+	}
+	return strings.Join(names, ", ")
+}
+
+// asOfNote calls out that -as-of capped the versions checked
+func asOfNote(version string) string {
+	return fmt.Sprintf("📌 As of %s: newer releases were not checked (-as-of)", version)
+}
+
+// httpStatus notes the status that answered for a version, e.g. to tell a
+// 404 from a 403 of a firewall
+func httpStatus(v checker.VersionCheckResult) string {
+	if v.StatusCode == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (HTTP %d)", v.StatusCode)
+}
+
+// retries notes the retries a version check needed, if any
+func retries(v checker.VersionCheckResult) string {
+	if v.Error != nil && v.Attempts > 1 {
+		return fmt.Sprintf(" (gave up after %d attempts)", v.Attempts)
+	}
+	switch v.Attempts {
+	case 0, 1:
+		return ""
+	case 2:
+		return " (succeeded after 1 retry)"
+	}
+	return fmt.Sprintf(" (succeeded after %d retries)", v.Attempts-1)
+}
+
+// printJSONResults prints a single result in the selected JSON format
+func printJSONResults(result *checker.CheckResult) {
+	r := output.NewResult(result)
+	r.RunID = run.ID
+	var err error
+	if *outputFlag == "json-legacy" {
+		err = output.WriteLegacyJSON(os.Stdout, r)
+	} else {
+		err = output.WriteJSON(os.Stdout, r)
+	}
+	if err != nil {
+		errorf("could not write results: %v", err)
+	}
+}
 
-	fmt.Println(`  ]`)
-	fmt.Println(`}`)
+// documentLabel names the guide and page of a result the way doc writers
+// know them, e.g. "Networking — configuring-ingress-cluster-traffic", or
+// returns "" when the guide title is unknown
+func documentLabel(result *checker.CheckResult) string {
+	if result.DocumentTitle == "" {
+		return ""
+	}
+	docURL, err := parser.ParseOCPDocURL(result.OriginalURL)
+	if err != nil || docURL.Page == "index" {
+		return result.DocumentTitle
+	}
+	return result.DocumentTitle + " — " + docURL.Page
 }
 
-func printBatchTextResults(results []*checker.CheckResult, verbose bool) {
+func printBatchTextResults(report *batchReport, verbose bool) {
+	results := report.results
 	uptodateCount := 0
 	outdatedCount := 0
+	brokenCount := 0
+	malformedCount := 0
+	excludedCount := 0
+	deprecatedCount := 0
 
-	fmt.Println(strings.Repeat("=", 80))
-	fmt.Println("📋 OCP Documentation URL Check Results")
-	fmt.Println(strings.Repeat("=", 80))
+	text.Heading("📋 OCP Documentation URL Check Results")
 	fmt.Println()
 
 	for i, result := range results {
-		if result.IsOutdated {
+		if result.FragmentIssue == parser.FragmentMalformed {
+			malformedCount++
+			fmt.Printf("[%d] ❌ MALFORMED FRAGMENT\n", i+1)
+			fmt.Printf("    URL: %s\n", result.OriginalURL)
+			fmt.Println("    The anchor contains whitespace or an extra '#' and was not checked")
+			for _, f := range report.urlToLocation[result.OriginalURL].Files {
+				fmt.Printf("    Found in: %s\n", f)
+			}
+			fmt.Println()
+			continue
+		}
+
+		if result.Broken {
+			brokenCount++
+			fmt.Printf("[%d] ❌ BROKEN\n", i+1)
+		} else if result.IsOutdated {
 			outdatedCount++
 			fmt.Printf("[%d] ⚠️  OUTDATED\n", i+1)
 		} else {
@@ -474,19 +2336,66 @@ func printBatchTextResults(results []*checker.CheckResult, verbose bool) {
 			fmt.Printf("[%d] ✅ UP TO DATE\n", i+1)
 		}
 
-		fmt.Printf("    URL: %s\n", result.OriginalURL)
+		text.URLLine("    URL: ", result.OriginalURL, "")
+		if label := documentLabel(result); label != "" {
+			fmt.Printf("    Document: %s\n", label)
+		}
 		fmt.Printf("    Current Version: %s\n", result.OriginalVersion)
+		if result.OriginalDeprecated {
+			deprecatedCount++
+			fmt.Println("    ⚠️  current version is EOL")
+		}
 		fmt.Printf("    Latest Version: %s\n", result.LatestVersion)
+		if note := anchorPolicyNote(result); note != "" {
+			fmt.Printf("    %s\n", note)
+		}
+		if result.Broken {
+			fmt.Printf("    Broken: %s\n", brokenReason(result))
+		}
+		if redirects := rejectedRedirects(result); len(redirects) > 0 {
+			newest := redirects[len(redirects)-1]
+			text.URLLine(fmt.Sprintf("    Version %s redirects to: ", newest.Version), newest.RedirectedTo, " (see -accept-redirects)")
+		}
+		if v, ok := closestAnchor(result); ok {
+			fmt.Printf("    Version %s lacks the anchor, closest: #%s\n", v.Version, v.SuggestedAnchors[0])
+		}
+		printNotes("    ", result)
+
+		if result.SuggestedURL != "" {
+			text.URLLine("    "+normalizeReason(result)+", normalize to: ", result.SuggestedURL, "")
+		}
+		if loc := report.urlToLocation[result.OriginalURL]; missingLocale(loc) > 0 {
+			fmt.Printf("    Mixed locale spelling: %d of %d occurrence(s) without the /%s/ locale\n", missingLocale(loc), len(loc.Occurrences), scanner.DefaultLocale)
+		}
+		if loc := report.urlToLocation[result.OriginalURL]; trackingSpellings(loc) > 0 {
+			fmt.Printf("    Tracking parameters: %d of %d occurrence(s) with query parameters that only track campaigns\n", trackingSpellings(loc), len(loc.Occurrences))
+		}
+		if excluded, ok := result.NewestExcluded(); ok {
+			excludedCount++
+			fmt.Printf("    %s available but excluded by target policy\n", excluded.Version)
+		}
 
 		if result.IsOutdated && len(result.NewerVersions) > 0 {
 			if *allAvailableFlag {
 				fmt.Println("    Available newer versions:")
 				for _, v := range result.NewerVersions {
-					fmt.Printf("      - Version %s: %s\n", v.Version, v.URL)
+					text.URLLine(fmt.Sprintf("      - Version %s: ", v.Version), v.URL, "")
 				}
 			} else {
-				latest := result.NewerVersions[len(result.NewerVersions)-1]
-				fmt.Printf("    Latest available: %s (%s)\n", latest.Version, latest.URL)
+				latest, _ := result.BestSuggestion()
+				text.URLLine(fmt.Sprintf("    Latest available: %s (", latest.Version), latest.URL, ")")
+				if latest.RenamedFrom != "" {
+					fmt.Printf("    Page slug renamed from %s\n", latest.RenamedFrom)
+				}
+				if latest.MovedFrom != "" {
+					fmt.Printf("    Section moved from page %s\n", latest.MovedFrom)
+				}
+				if latest.RedirectedTo != "" {
+					text.URLLine("    Redirects to: ", latest.RedirectedTo, "")
+				}
+				if note := missingAnchorNote(latest); note != "" {
+					fmt.Printf("    %s\n", note)
+				}
 				if len(result.NewerVersions) > 1 {
 					fmt.Printf("    (%d newer versions available, use --all-available to see all)\n", len(result.NewerVersions))
 				}
@@ -496,74 +2405,750 @@ func printBatchTextResults(results []*checker.CheckResult, verbose bool) {
 		fmt.Println()
 	}
 
-	fmt.Println(strings.Repeat("=", 80))
-	fmt.Printf("Summary: %d total, %d up-to-date, %d outdated\n", len(results), uptodateCount, outdatedCount)
-	fmt.Println(strings.Repeat("=", 80))
+	text.Rule("=")
+	fmt.Printf("Summary: %d total, %d up-to-date, %d outdated", len(results), uptodateCount, outdatedCount)
+	if brokenCount > 0 {
+		fmt.Printf(", %d broken", brokenCount)
+	}
+	if malformedCount > 0 {
+		fmt.Printf(", %d malformed", malformedCount)
+	}
+	if len(report.placeholders) > 0 {
+		fmt.Printf(", %d unresolved placeholder(s)", len(report.placeholders))
+	}
+	if len(report.suspicious) > 0 {
+		fmt.Printf(", %d suspicious host(s)", len(report.suspicious))
+	}
+	if refs := historicalReferences(report); len(refs) > 0 {
+		fmt.Printf(", %d historical reference(s)", len(refs))
+	}
+	if len(report.wrongProduct) > 0 {
+		fmt.Printf(", %d possible wrong-product link(s)", len(report.wrongProduct))
+	}
+	if len(report.notChecked) > 0 {
+		fmt.Printf(", %d not checked (soft deadline)", len(report.notChecked))
+	}
+	if len(report.preexisting) > 0 {
+		fmt.Printf(", %d pre-existing (not checked)", len(report.preexisting))
+	}
+	if len(report.generated) > 0 {
+		fmt.Printf(", %d in generated files (not checked)", len(report.generated))
+	}
+	if excludedCount > 0 {
+		fmt.Printf(", %d with a newer version excluded by target policy", excludedCount)
+	}
+	if deprecatedCount > 0 {
+		fmt.Printf(", %d on an EOL version", deprecatedCount)
+	}
+	fmt.Println()
+	if report.asOf != "" {
+		fmt.Println(asOfNote(report.asOf))
+	}
+	fmt.Printf("Run: %s\n", run.ID)
+	text.Rule("=")
+
+	if verbose {
+		fmt.Println()
+		printScanStats(report.scanStats)
+		fmt.Println()
+		printCheckerStats(report.checkerStats)
+	}
+
+	if *upgradeEffortFlag {
+		fmt.Println()
+		printUpgradeEffort(results)
+	}
+
+	if len(report.placeholders) > 0 {
+		fmt.Println()
+		fmt.Println("❌ Unresolved version placeholders (not checked):")
+		fmt.Println()
+		for _, loc := range report.placeholders {
+			fmt.Printf("- %s\n", loc.URL)
+			for _, occ := range loc.Occurrences {
+				fmt.Printf("  %s:%d\n", occ.Path, occ.Line)
+			}
+		}
+	}
+
+	if len(report.suspicious) > 0 {
+		fmt.Println()
+		fmt.Println("🚨 Suspicious hosts imitating docs.redhat.com (not checked):")
+		fmt.Println()
+		for _, loc := range report.suspicious {
+			host := scanner.URLHost(loc.URL)
+			fmt.Printf("- %s\n", loc.URL)
+			fmt.Printf("  host %s (%s, %s)\n", host, scanner.ASCIIHost(host), loc.Suspicious)
+			for _, occ := range loc.Occurrences {
+				fmt.Printf("  %s:%d:%d\n", occ.Path, occ.Line, occ.Column)
+			}
+		}
+	}
+
+	if verbose && len(report.preexisting) > 0 {
+		fmt.Println()
+		fmt.Printf("📎 Pre-existing, on lines not added since %s (not checked):\n", *gitDiffFlag)
+		fmt.Println()
+		for _, loc := range report.preexisting {
+			fmt.Printf("- %s\n", loc.URL)
+			for _, occ := range loc.Occurrences {
+				fmt.Printf("  %s:%d\n", occ.Path, occ.Line)
+			}
+		}
+	}
+
+	if verbose && len(report.generated) > 0 {
+		fmt.Println()
+		fmt.Println("🏭 Only in generated or minified files (not checked; use -fix-generated to check them):")
+		fmt.Println()
+		for _, loc := range report.generated {
+			fmt.Printf("- %s\n", loc.URL)
+			for _, file := range loc.Files {
+				occurrences := 0
+				detector := ""
+				for _, occ := range loc.Occurrences {
+					if occ.Path == file {
+						occurrences++
+						detector = occ.Generated
+					}
+				}
+				fmt.Printf("  %s (%s, %d occurrence(s))\n", file, detector, occurrences)
+			}
+		}
+	}
+
+	if len(report.notChecked) > 0 {
+		fmt.Println()
+		fmt.Printf("⏱️  Not checked before the soft deadline of %s:\n", *softDeadlineFlag)
+		fmt.Println()
+		for _, loc := range report.notChecked {
+			fmt.Printf("- %s\n", loc.URL)
+			for _, occ := range loc.Occurrences {
+				fmt.Printf("  %s:%d\n", occ.Path, occ.Line)
+			}
+		}
+	}
+
+	printEncodedOccurrences(report)
+	printHistoricalReferences(report)
+	printWrongProductLinks(report)
+
+	if groups := checker.GroupSpellings(results); len(groups) > 0 {
+		fmt.Println()
+		fmt.Println("🔗 Sections linked with more than one spelling (use -fix-prefer-format to unify):")
+		fmt.Println()
+		for _, group := range groups {
+			fmt.Printf("- %d spellings of the same section:\n", len(group))
+			for _, s := range group {
+				fmt.Printf("  %s: %s\n", s.Format, s.Result.OriginalURL)
+			}
+		}
+	}
+
+	if spots := hotspots(report); len(spots) > 0 {
+		fmt.Println()
+		fmt.Printf("🔥 Hotspot files (%d or more outdated URLs):\n", *hotspotFlag)
+		fmt.Println()
+		for _, h := range spots {
+			fmt.Printf("- %s: %d outdated", h.File, h.Outdated)
+			if n := h.Findings[output.SeverityWarning]; n > 0 {
+				fmt.Printf(", %d warning(s)", n)
+			}
+			if n := h.Findings[output.SeverityError]; n > 0 {
+				fmt.Printf(", %d error(s)", n)
+			}
+			fmt.Println()
+			fmt.Printf("  Fix just this file: %s\n", h.Command)
+		}
+	}
 
-	// Print recommendations for outdated URLs
-	if outdatedCount > 0 {
+	// Print recommendations for outdated URLs, broken ones included
+	if outdatedCount > 0 || brokenCount > 0 {
 		fmt.Println()
 		fmt.Println("🔧 Recommended Updates:")
 		fmt.Println()
 		for _, result := range results {
-			if result.IsOutdated && len(result.NewerVersions) > 0 {
-				latest := result.NewerVersions[len(result.NewerVersions)-1]
+			if latest, ok := result.BestSuggestion(); result.IsOutdated && ok {
 				fmt.Printf("- Update from %s to %s:\n", result.OriginalVersion, latest.Version)
 				fmt.Printf("  Old: %s\n", result.OriginalURL)
 				fmt.Printf("  New: %s\n", latest.URL)
+				if note := missingAnchorNote(latest); note != "" {
+					fmt.Printf("  %s\n", note)
+				}
 				fmt.Println()
 			}
 		}
 	}
 }
 
-func printBatchJSONResults(results []*checker.CheckResult) {
-	uptodateCount := 0
-	outdatedCount := 0
+// hotspots returns the files with at least -hotspot-threshold outdated
+// URLs. Nothing is called out once a fix mode has run.
+func hotspots(report *batchReport) []output.Hotspot {
+	if *hotspotFlag <= 0 || fixMode() {
+		return nil
+	}
+	return output.NewHotspots(buildFindings(report), *hotspotFlag, fixFilterArgs())
+}
 
-	for _, result := range results {
-		if result.IsOutdated {
-			outdatedCount++
+// fixFilterArgs returns the flags of this run that change the target -fix
+// picks, to repeat them in suggested fix commands
+func fixFilterArgs() []string {
+	var args []string
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "allowed-target-versions", "as-of", "exclude-versions", "max-version", "min-version", "slug-map":
+			args = append(args, "-"+f.Name, f.Value.String())
+		case "allow-host":
+			for _, host := range allowHostFlag {
+				args = append(args, "-allow-host", host)
+			}
+		case "version-alias":
+			for _, alias := range aliasFlag {
+				args = append(args, "-version-alias", alias)
+			}
+		}
+	})
+	return args
+}
+
+// printEncodedOccurrences lists outdated URLs that a deep scan found inside
+// encoded values, which -fix cannot update
+func printEncodedOccurrences(report *batchReport) {
+	header := false
+	for _, result := range report.results {
+		if !result.IsOutdated {
+			continue
+		}
+		for _, occ := range report.urlToLocation[result.OriginalURL].Occurrences {
+			if occ.Encoding == "" {
+				continue
+			}
+			if !header {
+				fmt.Println()
+				fmt.Println("⚠️  Outdated URLs in encoded values (manual fix required):")
+				fmt.Println()
+				header = true
+			}
+			fmt.Printf("- %s\n", result.OriginalURL)
+			fmt.Printf("  %s:%d %s (%s encoded)\n", occ.Path, occ.Line, occ.KeyPath, occ.Encoding)
+		}
+	}
+}
+
+// wrongProductLinks lists the occurrences of the checked URLs in files
+// declaring another product than that of the URL, with the same page under
+// the declared product when a request finds it, anchor included. Each URL
+// is requested once per product, until the soft deadline.
+func wrongProductLinks(c *checker.Checker, report *batchReport, products *scanner.ProductContext, d *deadline) []output.WrongProductLink {
+	ctx, cancel := d.context()
+	defer cancel()
+
+	equivalents := make(map[[2]string]string) // (URL, product) to the page found, "" for none
+	var links []output.WrongProductLink
+	for _, result := range report.results {
+		docURL, err := parser.ParseOCPDocURL(result.OriginalURL)
+		if err != nil {
+			continue
+		}
+		for _, occ := range report.urlToLocation[result.OriginalURL].Occurrences {
+			if occ.Product == "" || occ.Product == docURL.Product || occ.Historical || occ.Generated != "" {
+				continue
+			}
+			key := [2]string{result.OriginalURL, occ.Product}
+			equivalent, probed := equivalents[key]
+			if !probed && ctx.Err() == nil {
+				v, err := c.CheckProductURL(ctx, docURL, occ.Product, products.Version(occ.Product, docURL.Version))
+				if err == nil && v.Error == nil && v.Exists && (!v.HasAnchor || v.AnchorExists) {
+					equivalent = v.URL
+				}
+				equivalents[key] = equivalent
+			}
+			links = append(links, output.WrongProductLink{
+				URL:             occ.URL,
+				File:            occ.Path,
+				Line:            occ.Line,
+				Column:          occ.Column,
+				Product:         docURL.Product,
+				DeclaredProduct: occ.Product,
+				Equivalent:      equivalent,
+				Severity:        output.SeverityInfo,
+			})
+		}
+	}
+	return links
+}
+
+// printWrongProductLinks lists the URLs to another product than their file
+// declares, which are advisory and never fixed
+func printWrongProductLinks(report *batchReport) {
+	if len(report.wrongProduct) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Println("🔀 Possible wrong-product links (advisory, never fixed):")
+	fmt.Println()
+	for _, link := range report.wrongProduct {
+		fmt.Printf("- %s:%d: %s link in a file declaring %s\n", link.File, link.Line, link.Product, link.DeclaredProduct)
+		text.URLLine("  ", link.URL, "")
+		if link.Equivalent != "" {
+			text.URLLine("  → ", link.Equivalent, "")
 		} else {
-			uptodateCount++
+			fmt.Printf("  no equivalent page found under %s\n", link.DeclaredProduct)
 		}
 	}
+}
 
-	fmt.Printf(`{
-  "total_count": %d,
-  "uptodate_count": %d,
-  "outdated_count": %d,
-  "results": [
-`, len(results), uptodateCount, outdatedCount)
+// historicalReferences lists every historical occurrence of the checked
+// URLs, whether or not the URL also appears in other files
+func historicalReferences(report *batchReport) []output.HistoricalReference {
+	all := append(append([]*checker.CheckResult{}, report.results...), report.historical...)
+	return output.NewHistoricalReferences(all, report.urlToLocation)
+}
 
-	for i, result := range results {
-		comma := ","
-		if i == len(results)-1 {
-			comma = ""
+// printHistoricalReferences lists the URLs of changelogs and release notes,
+// which are informational and never fixed
+func printHistoricalReferences(report *batchReport) {
+	refs := historicalReferences(report)
+	if len(refs) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Println("📜 Historical references in changelogs and release notes (informational, never fixed):")
+	fmt.Println()
+	for _, ref := range refs {
+		status := "current"
+		if ref.IsOutdated {
+			status = "latest " + ref.LatestVersion
+		}
+		fmt.Printf("- %s:%d: %s (%s)\n", ref.File, ref.Line, ref.Version, status)
+		text.URLLine("  ", ref.URL, "")
+	}
+	fmt.Println()
+	fmt.Println("Use -include-historical to check and fix them like any other URL.")
+}
+
+// printScanStats prints what the scanner visited, scanned and skipped
+func printScanStats(stats scanner.Stats) {
+	fmt.Println("Scan statistics:")
+	fmt.Printf("  Files visited: %d\n", stats.FilesVisited)
+	fmt.Printf("  Files scanned: %d (%d bytes)\n", stats.FilesScanned, stats.BytesScanned)
+
+	skipped := 0
+	var reasons []string
+	for _, reason := range sortedKeys(stats.FilesSkipped) {
+		skipped += stats.FilesSkipped[reason]
+		reasons = append(reasons, fmt.Sprintf("%s: %d", reason, stats.FilesSkipped[reason]))
+	}
+	if skipped > 0 {
+		fmt.Printf("  Files skipped: %d (%s)\n", skipped, strings.Join(reasons, ", "))
+	} else {
+		fmt.Println("  Files skipped: 0")
+	}
+
+	generated := 0
+	var detectors []string
+	for _, detector := range sortedKeys(stats.FilesGenerated) {
+		generated += stats.FilesGenerated[detector]
+		detectors = append(detectors, fmt.Sprintf("%s: %d", detector, stats.FilesGenerated[detector]))
+	}
+	if generated > 0 {
+		fmt.Printf("  Generated files, URLs not checked: %d (%s)\n", generated, strings.Join(detectors, ", "))
+	}
+
+	for _, ext := range sortedKeys(stats.FilesPerExt) {
+		fmt.Printf("  %s: %d file(s) in %s\n", ext, stats.FilesPerExt[ext], stats.TimePerExt[ext].Round(time.Microsecond))
+	}
+}
+
+// printCheckerStats prints how the page lookups of the checks were answered
+func printCheckerStats(stats checker.Stats) {
+	fmt.Println("Page cache:")
+	fmt.Printf("  Page lookups: %d\n", stats.PageLookups)
+	fmt.Printf("  Answered from cache: %d", stats.CacheHits)
+	if stats.ImportedHits > 0 {
+		fmt.Printf(" (%d from earlier runs, %d read from the disk cache)", stats.ImportedHits, stats.DiskReads)
+	}
+	fmt.Println()
+	fmt.Printf("  HTTP requests: %d\n", stats.Requests)
+	if stats.PageEvictions > 0 {
+		fmt.Printf("  Pages dropped from memory (-cache-max-mb): %d\n", stats.PageEvictions)
+	}
+	if stats.SiblingSearches > 0 {
+		fmt.Printf("  Sibling page searches: %d (%d request(s))\n", stats.SiblingSearches, stats.SiblingRequests)
+	}
+
+	if len(stats.Heuristics) == 0 {
+		return
+	}
+	// Most applied first
+	heuristics := make([]checker.Heuristic, 0, len(stats.Heuristics))
+	for h := range stats.Heuristics {
+		heuristics = append(heuristics, h)
+	}
+	sort.Slice(heuristics, func(i, j int) bool {
+		a, b := heuristics[i], heuristics[j]
+		if stats.Heuristics[a] != stats.Heuristics[b] {
+			return stats.Heuristics[a] > stats.Heuristics[b]
+		}
+		return a < b
+	})
+	fmt.Println("Heuristics applied:")
+	for _, h := range heuristics {
+		fmt.Printf("  %s: %d result(s)\n", h, stats.Heuristics[h])
+	}
+}
+
+// newLogger returns the logger of the checker, writing to w in the format
+// of -log-format: every request with -verbose, otherwise only retries and
+// pages that cannot be parsed. Every record carries the run ID.
+func newLogger(w io.Writer) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: slog.LevelWarn}
+	if *verboseFlag {
+		opts.Level = slog.LevelDebug
+	}
+	var handler slog.Handler = slog.NewTextHandler(w, opts)
+	if *logFormatFlag == "json" {
+		handler = slog.NewJSONHandler(w, opts)
+	}
+	return slog.New(handler).With("run_id", run.ID)
+}
+
+// diagnostic is a line of -error-format json
+type diagnostic struct {
+	Level   string `json:"level"`
+	Message string `json:"message"`
+	RunID   string `json:"run_id"`
+}
+
+// diagnose writes a diagnostic of level error, warning or note to stderr,
+// as "Error: message" and the like, or with -error-format json as one JSON
+// object per line
+func diagnose(level, format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	if *errorFormatFlag == "json" {
+		data, _ := json.Marshal(diagnostic{Level: level, Message: message, RunID: run.ID})
+		fmt.Fprintf(os.Stderr, "%s\n", data)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s: %s\n", strings.ToUpper(level[:1])+level[1:], message)
+}
+
+// errorf writes an error to stderr
+func errorf(format string, args ...any) {
+	diagnose("error", format, args...)
+}
+
+// warnf writes a warning to stderr
+func warnf(format string, args ...any) {
+	diagnose("warning", format, args...)
+}
+
+// notef writes a note to stderr
+func notef(format string, args ...any) {
+	diagnose("note", format, args...)
+}
+
+// defaultCacheDir returns the default -cache-dir: ocp-doc-checker in the
+// user's cache directory, e.g. ~/.cache/ocp-doc-checker, or no cache when
+// the user has none
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "ocp-doc-checker")
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ciMode resolves the -ci-mode flag, detecting GitHub Actions in auto mode
+func ciMode() string {
+	if *ciModeFlag == "auto" {
+		if os.Getenv("GITHUB_ACTIONS") == "true" {
+			return "github"
+		}
+		return "none"
+	}
+	return *ciModeFlag
+}
+
+// buildFindings turns a report into one finding per problem occurrence
+func buildFindings(report *batchReport) []output.Finding {
+	var findings []output.Finding
+
+	for _, loc := range report.suspicious {
+		host := scanner.URLHost(loc.URL)
+		for _, occ := range loc.Occurrences {
+			findings = append(findings, output.Finding{
+				Path:    occ.Path,
+				Line:    occ.Line,
+				Column:  occ.Column,
+				Status:  "suspicious-host",
+				Message: fmt.Sprintf("host %s (%s) imitates docs.redhat.com (%s): %s", host, scanner.ASCIIHost(host), loc.Suspicious, occ.URL),
+			})
+		}
+	}
+
+	for _, loc := range report.placeholders {
+		for _, occ := range loc.Occurrences {
+			findings = append(findings, output.Finding{
+				Path:    occ.Path,
+				Line:    occ.Line,
+				Column:  occ.Column,
+				Status:  "unresolved-placeholder",
+				Message: "unresolved version placeholder: " + occ.URL,
+			})
+		}
+	}
+
+	for _, link := range report.wrongProduct {
+		message := fmt.Sprintf("%s link in a file declaring %s: %s", link.Product, link.DeclaredProduct, link.URL)
+		if link.Equivalent != "" {
+			message += " (" + link.DeclaredProduct + ": " + link.Equivalent + ")"
 		}
+		findings = append(findings, output.Finding{
+			Path:    link.File,
+			Line:    link.Line,
+			Column:  link.Column,
+			Status:  "possible-wrong-product",
+			Message: message,
+		})
+	}
 
-		fmt.Printf(`    {
-      "original_url": "%s",
-      "original_version": "%s",
-      "latest_version": "%s",
-      "is_outdated": %t,
-      "newer_versions": [`, result.OriginalURL, result.OriginalVersion, result.LatestVersion, result.IsOutdated)
+	for _, result := range report.results {
+		var status, message string
+		switch {
+		case result.FragmentIssue == parser.FragmentMalformed:
+			status = "malformed-fragment"
+			message = "anchor contains whitespace or an extra '#': " + result.OriginalURL
+		case result.Broken:
+			status = "broken"
+			message = brokenReason(result) + ": " + result.OriginalURL
+			if latest, ok := result.BestSuggestion(); ok {
+				message += fmt.Sprintf(" (%s works: %s)", latest.Version, latest.URL)
+			}
+		case result.IsOutdated && len(result.NewerVersions) > 0:
+			latest, _ := result.BestSuggestion()
+			status = "outdated"
+			message = fmt.Sprintf("%s → %s: %s", result.OriginalVersion, latest.Version, latest.URL)
+			if latest.AnchorMissing() {
+				message += " (anchor missing, -anchor-policy page-only)"
+			}
+		case result.VersionIssue == parser.VersionNonCanonical:
+			status = "non-canonical-version"
+			message = "non-canonical version format, normalize to " + result.SuggestedURL
+		case result.SuggestedURL != "":
+			status = "duplicated-fragment"
+			message = "normalize to " + result.SuggestedURL
+		}
 
-		for j, v := range result.NewerVersions {
-			versionComma := ","
-			if j == len(result.NewerVersions)-1 {
-				versionComma = ""
+		for _, occ := range report.urlToLocation[result.OriginalURL].Occurrences {
+			if occ.Historical || occ.Generated != "" {
+				continue
+			}
+			finding := output.Finding{
+				Path:    occ.Path,
+				Line:    occ.Line,
+				Column:  occ.Column,
+				Status:  status,
+				Message: message,
+			}
+			if status == "" {
+				// Otherwise fine URLs are only reported where spelled without a locale
+				if !scanner.MissingLocale(occ.URL) {
+					continue
+				}
+				finding.Status = "mixed-locale-spelling"
+				finding.Message = "mixed locale spelling, normalize to " + result.OriginalURL
+			}
+			if occ.Encoding != "" {
+				finding.Status = status + "-encoded"
+				finding.Message = fmt.Sprintf("encoded occurrence — manual fix required at %s (%s encoded): %s", occ.KeyPath, occ.Encoding, message)
 			}
-			fmt.Printf(`
-        {"version": "%s", "url": "%s"}%s`, v.Version, v.URL, versionComma)
+			findings = append(findings, finding)
+		}
+	}
+
+	return findings
+}
+
+// printBatchGitHubResults prints findings grouped per file for GitHub Actions
+func printBatchGitHubResults(report *batchReport) {
+	results := report.results
+	if err := output.WriteGitHub(os.Stdout, buildFindings(report)); err != nil {
+		errorf("could not write results: %v", err)
+	}
+
+	outdatedCount := 0
+	brokenCount := 0
+	malformedCount := 0
+	for _, result := range results {
+		if result.FragmentIssue == parser.FragmentMalformed {
+			malformedCount++
+		} else if result.Broken {
+			brokenCount++
+		} else if result.IsOutdated {
+			outdatedCount++
 		}
+	}
+
+	fmt.Printf("Summary: %d total, %d up-to-date, %d outdated", len(results), len(results)-outdatedCount-brokenCount-malformedCount, outdatedCount)
+	if brokenCount > 0 {
+		fmt.Printf(", %d broken", brokenCount)
+	}
+	if malformedCount > 0 {
+		fmt.Printf(", %d malformed", malformedCount)
+	}
+	if len(report.placeholders) > 0 {
+		fmt.Printf(", %d unresolved placeholder(s)", len(report.placeholders))
+	}
+	if len(report.suspicious) > 0 {
+		fmt.Printf(", %d suspicious host(s)", len(report.suspicious))
+	}
+	if refs := historicalReferences(report); len(refs) > 0 {
+		fmt.Printf(", %d historical reference(s)", len(refs))
+	}
+	if len(report.wrongProduct) > 0 {
+		fmt.Printf(", %d possible wrong-product link(s)", len(report.wrongProduct))
+	}
+	if len(report.notChecked) > 0 {
+		fmt.Printf(", %d not checked (soft deadline)", len(report.notChecked))
+	}
+	if len(report.preexisting) > 0 {
+		fmt.Printf(", %d pre-existing (not checked)", len(report.preexisting))
+	}
+	if len(report.generated) > 0 {
+		fmt.Printf(", %d in generated files (not checked)", len(report.generated))
+	}
+	fmt.Println()
+	if report.asOf != "" {
+		fmt.Println(asOfNote(report.asOf))
+	}
+	fmt.Printf("Run: %s\n", run.ID)
+
+	if *upgradeEffortFlag {
+		fmt.Println()
+		printUpgradeEffort(results)
+	}
+}
+
+// printUpgradeEffort prints the upgrade effort table for a directory scan
+func printUpgradeEffort(results []*checker.CheckResult) {
+	fmt.Println("📈 Upgrade effort:")
+	fmt.Println()
+	if err := output.WriteUpgradeEffort(os.Stdout, output.NewUpgradeEffort(results)); err != nil {
+		errorf("could not write upgrade effort: %v", err)
+	}
+}
+
+// printBatchJSONResults prints a directory scan in the selected JSON format
+func printBatchJSONResults(report *batchReport) {
+	batch := output.NewBatch(report.results, report.placeholders, report.scanStats)
+	batch.RunID = run.ID
+	if *upgradeEffortFlag {
+		batch.UpgradeEffort = output.NewUpgradeEffort(report.results)
+	}
+	batch.EncodedOccurrences = output.NewEncodedOccurrences(report.results, report.urlToLocation)
+	batch.SuspiciousHosts = output.NewSuspiciousHosts(report.suspicious)
+	batch.HistoricalReferences = historicalReferences(report)
+	batch.WrongProductLinks = report.wrongProduct
+	batch.SameSection = output.NewSpellingGroups(checker.GroupSpellings(report.results))
+	batch.MixedLocaleSpellings = output.NewMixedLocaleSpellings(report.results, report.urlToLocation)
+	batch.Hotspots = hotspots(report)
+	batch.ResolvedAliases = report.resolvedAliases
+	batch.AsOf = report.asOf
+	batch.NotChecked = output.NewNotChecked(report.notChecked, output.ReasonSoftDeadline)
+	batch.NotChecked = append(batch.NotChecked, output.NewNotChecked(report.preexisting, output.ReasonPreexisting)...)
+	batch.NotChecked = append(batch.NotChecked, output.NewNotChecked(report.generated, output.ReasonGenerated)...)
+	if report.shard != nil {
+		batch.Shard = &output.Shard{Index: report.shard.Index, Count: report.shard.Count}
+	}
+	batch.Failed = report.failed
+
+	var err error
+	if *outputFlag == "json-legacy" {
+		err = output.WriteLegacyBatchJSON(os.Stdout, batch)
+	} else {
+		err = output.WriteJSON(os.Stdout, batch)
+	}
+	if err != nil {
+		errorf("could not write results: %v", err)
+	}
+}
 
-		fmt.Printf(`
-      ]
-    }%s
-`, comma)
+// jsonOutput reports whether results are printed as JSON
+func jsonOutput() bool {
+	return *outputFlag == "json" || *outputFlag == "json-legacy"
+}
+
+// machineOutput reports whether results are printed for programs, without
+// banners or progress on stdout
+func machineOutput() bool {
+	return jsonOutput() || *outputFlag == "tsv" || *outputFlag == "pr-comment"
+}
+
+// printBatchPRComment prints the findings of a directory scan as a pull
+// request comment, marked with the scope of the scan so that the comment of
+// the next push to the pull request replaces it
+func printBatchPRComment(report *batchReport, path string) {
+	comment := output.PRComment{
+		Scope:        output.ScopeHash(path, *gitDiffFlag, strconv.FormatBool(*gitNewOnlyFlag), *shardFlag),
+		Checked:      len(report.results) + len(report.historical),
+		Findings:     buildFindings(report),
+		Failed:       report.failed,
+		LinkTemplate: *linkTemplateFlag,
+		Preexisting:  len(report.preexisting),
+		Generated:    len(report.generated),
+		Historical:   len(historicalReferences(report)),
+		NotChecked:   len(report.notChecked),
+		RunID:        run.ID,
+	}
+	if err := output.WritePRComment(os.Stdout, comment); err != nil {
+		errorf("could not write results: %v", err)
+	}
+}
+
+// printTSVResults prints rows as -output tsv
+func printTSVResults(rows []output.TSVRow) {
+	for i := range rows {
+		rows[i].RunID = run.ID
 	}
+	if err := output.WriteTSV(os.Stdout, rows, !*noHeaderFlag); err != nil {
+		errorf("could not write results: %v", err)
+	}
+}
 
-	fmt.Println(`  ]`)
-	fmt.Println(`}`)
+// printBatchTSVResults prints one tsv line per URL of a directory scan:
+// the checked URLs in scan order, then the URLs never checked
+func printBatchTSVResults(report *batchReport) {
+	var rows []output.TSVRow
+	for _, result := range report.results {
+		rows = append(rows, output.NewTSVRow(result, report.urlToLocation[result.OriginalURL]))
+	}
+	for _, loc := range report.placeholders {
+		rows = append(rows, output.NewTSVLocationRow("unresolved-placeholder", loc))
+	}
+	for _, loc := range report.suspicious {
+		rows = append(rows, output.NewTSVLocationRow("suspicious-host", loc))
+	}
+	for _, loc := range report.notChecked {
+		rows = append(rows, output.NewTSVLocationRow("not-checked", loc))
+	}
+	for _, loc := range report.preexisting {
+		rows = append(rows, output.NewTSVLocationRow("pre-existing", loc))
+	}
+	for _, loc := range report.generated {
+		rows = append(rows, output.NewTSVLocationRow("generated", loc))
+	}
+	printTSVResults(rows)
 }
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/checker"
+	"github.com/sebrandon1/ocp-doc-checker/pkg/scanner"
+)
+
+func TestResolveInput(t *testing.T) {
+	const docURL = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.17/html/networking/index"
+	paths := map[string]bool{"README.md": true, "docs": true, "docs.redhat.com/en": true}
+	exists := func(path string) bool { return paths[path] }
+
+	tests := []struct {
+		name     string
+		url, dir string
+		auto     bool
+		wantURL  string
+		wantDir  string
+		wantNote bool
+		wantErr  bool
+	}{
+		{"URL", docURL, "", false, docURL, "", false, false},
+		{"directory", "", "docs", false, "", "docs", false, false},
+		{"file passed to -url", "README.md", "", false, "", "", false, true},
+		{"file passed to -url with -auto", "README.md", "", true, "", "README.md", true, false},
+		{"directory passed to -url with -auto", "docs", "", true, "", "docs", true, false},
+		{"missing path passed to -url", "notes.md", "", true, "notes.md", "", false, false},
+		{"URL passed to -dir", "", docURL, false, "", "", false, true},
+		{"URL passed to -dir with -auto", "", docURL, true, docURL, "", true, false},
+		{"URL without scheme passed to -dir with -auto", "", "docs.redhat.com/en/documentation", true, "https://docs.redhat.com/en/documentation", "", true, false},
+		{"existing path that looks like a URL", "", "docs.redhat.com/en", false, "", "docs.redhat.com/en", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url, dir, note, err := resolveInput(tt.url, tt.dir, tt.auto, exists)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveInput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if url != tt.wantURL || dir != tt.wantDir || (note != "") != tt.wantNote {
+				t.Errorf("resolveInput() = %q, %q, note %q; want %q, %q, note %v", url, dir, note, tt.wantURL, tt.wantDir, tt.wantNote)
+			}
+		})
+	}
+}
+
+func TestCheckLocations(t *testing.T) {
+	// Malformed fragments and unparsable URLs are answered without requests
+	const malformed = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.17/html/networking/index#a b"
+	locations := []scanner.Location{{URL: malformed}, {URL: "https://docs.redhat.com/1"}, {URL: malformed + "c"}}
+	start := time.Now()
+
+	tests := []struct {
+		name           string
+		d              *deadline
+		wantChecked    int
+		wantErrors     int
+		wantNotChecked int
+	}{
+		{"no soft deadline", newDeadline(start, 0, time.Minute), 2, 1, 0},
+		{"deadline far away", newDeadline(start, time.Hour, time.Minute), 2, 1, 0},
+		{"deadline reached", newDeadline(start.Add(-time.Hour), time.Minute, 15*time.Second), 0, 0, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, errs, notChecked := checkLocations(checker.NewChecker(), locations, tt.d)
+
+			checked := 0
+			for _, r := range results {
+				if r != nil {
+					checked++
+				}
+			}
+			if checked != tt.wantChecked || len(errs) != tt.wantErrors || len(notChecked) != tt.wantNotChecked {
+				t.Fatalf("checkLocations() checked %d, %d errors, left %d; want %d, %d and %d",
+					checked, len(errs), len(notChecked), tt.wantChecked, tt.wantErrors, tt.wantNotChecked)
+			}
+			if tt.wantErrors > 0 && errs[1] == nil {
+				t.Errorf("checkLocations() errors = %v, want the unparsable URL's", errs)
+			}
+			// Locations not checked are reported in order
+			for i, loc := range notChecked {
+				if loc.URL != locations[i].URL {
+					t.Errorf("not checked[%d] = %s, want %s", i, loc.URL, locations[i].URL)
+				}
+			}
+		})
+	}
+}
+
+func TestVersionStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		v    checker.VersionCheckResult
+		want string
+	}{
+		{"found", checker.VersionCheckResult{Exists: true, StatusCode: 200, Attempts: 1}, "✓ Found"},
+		{"found after a retry", checker.VersionCheckResult{Exists: true, StatusCode: 200, Attempts: 2}, "✓ Found (succeeded after 1 retry)"},
+		{"missing page", checker.VersionCheckResult{StatusCode: 404, Attempts: 1}, "✗ Not found (HTTP 404)"},
+		{"refused by a firewall", checker.VersionCheckResult{StatusCode: 403, Attempts: 1}, "✗ Not found (HTTP 403)"},
+		{"section moved", checker.VersionCheckResult{Exists: true, HasAnchor: true, AnchorExists: true, StatusCode: 200, Attempts: 1, MovedFrom: "ingress-operator"}, "✓ Found (page + anchor) (section moved from page ingress-operator)"},
+		{"anchor heading", checker.VersionCheckResult{Exists: true, HasAnchor: true, AnchorExists: true, AnchorText: "Mirroring an image set in full", StatusCode: 200, Attempts: 1}, `✓ Found (anchor: "Mirroring an image set in full")`},
+		{"imported missing page", checker.VersionCheckResult{Cached: true}, "✗ Not found"},
+		{"soft 404", checker.VersionCheckResult{StatusCode: 200, Attempts: 1, NotFoundMarker: "the page you requested could not be found"}, `✗ Not found (soft 404: "the page you requested could not be found")`},
+		{"timeout", checker.VersionCheckResult{Error: errors.New("timeout"), Attempts: 3}, "⚠ Request failed (gave up after 3 attempts)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := versionStatus(tt.v); got != tt.want {
+				t.Errorf("versionStatus() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClosestAnchor(t *testing.T) {
+	const page = "https://docs.redhat.com/en/documentation/openshift_container_platform/%s/html/networking/ingress"
+	missing := func(version string, suggested ...string) checker.VersionCheckResult {
+		return checker.VersionCheckResult{Version: version, URL: fmt.Sprintf(page, version) + "#configuring-ingress", Exists: true, HasAnchor: true, SuggestedAnchors: suggested}
+	}
+
+	tests := []struct {
+		name        string
+		result      checker.CheckResult
+		wantVersion string
+	}{
+		{
+			name:        "newest version with suggestions",
+			result:      checker.CheckResult{LatestVersion: "4.16", AllResults: []checker.VersionCheckResult{missing("4.17", "ingress-a"), missing("4.18", "ingress-b"), missing("4.19")}},
+			wantVersion: "4.18",
+		},
+		{
+			name:   "only before the latest working version",
+			result: checker.CheckResult{LatestVersion: "4.18", AllResults: []checker.VersionCheckResult{missing("4.17", "ingress-a"), {Version: "4.18", Exists: true}}},
+		},
+		{
+			name:   "no suggestions",
+			result: checker.CheckResult{LatestVersion: "4.16", AllResults: []checker.VersionCheckResult{missing("4.17")}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, ok := closestAnchor(&tt.result)
+			if ok != (tt.wantVersion != "") || v.Version != tt.wantVersion {
+				t.Fatalf("closestAnchor() = %s, %v; want %q", v.Version, ok, tt.wantVersion)
+			}
+			if ok && suggestedAnchorURL(v) != fmt.Sprintf(page, "4.18")+"#ingress-b" {
+				t.Errorf("suggestedAnchorURL() = %s", suggestedAnchorURL(v))
+			}
+		})
+	}
+}
+
+func TestElapsed(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{300 * time.Microsecond, "<1ms"},
+		{1234567 * time.Nanosecond, "1ms"},
+		{999 * time.Millisecond, "999ms"},
+		{12345 * time.Millisecond, "12.3s"},
+	}
+	for _, tt := range tests {
+		if got := elapsed(tt.d); got != tt.want {
+			t.Errorf("elapsed(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
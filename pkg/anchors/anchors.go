@@ -0,0 +1,284 @@
+package anchors
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// maxText is the most bytes of an anchor's text that are kept: enough for a
+// heading, without copying whole sections of a large guide
+const maxText = 256
+
+// Anchor is an element a URL fragment can point to: an element with an id,
+// or an <a> with a name (the older HTML anchor style)
+type Anchor struct {
+	// ID is the value of the id or name attribute
+	ID string
+	// Element is the tag name, e.g. "section", "h2" or "a"
+	Element string
+	// Text is the text inside the element with runs of whitespace
+	// collapsed, cut at maxText bytes; empty for void elements
+	Text string
+	// Heading is the text of the heading the anchor names, collapsed and
+	// cut like Text: its own text for h1 to h6, otherwise that of the first
+	// heading after its start tag with no other text in between, such as
+	// the title of a <section>. It is empty when text comes first, and
+	// for void elements such as <img>.
+	Heading string
+	// Offset is the byte offset of the element's start tag in the page,
+	// and Line its line, counting from 1
+	Offset int64
+	Line   int
+}
+
+// voidElements never have an end tag, so their text is always empty
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// ExtractIDs returns every anchor of an HTML page in document order, with
+// its text; Heading is left empty
+func ExtractIDs(r io.Reader) ([]Anchor, error) {
+	var anchors []Anchor
+	w := walker{z: html.NewTokenizer(r), line: 1, withText: true, visit: func(a Anchor) bool {
+		anchors = append(anchors, a)
+		return true
+	}}
+	if _, err := w.run(); err != nil {
+		return nil, err
+	}
+	return anchors, nil
+}
+
+// Walk reads an HTML page token by token, without building a node tree,
+// which for an html-single guide of several megabytes would take many times
+// its size in memory. It calls visit with every anchor in document order as
+// soon as its start tag is read, leaving Text empty, stops reading once
+// visit returns false, and returns the text of the first <title> element
+// read. It is the fast path for checking that an anchor exists: reading
+// the text of every anchor, as ExtractIDs does, costs time and memory.
+func Walk(r io.Reader, visit func(Anchor) bool) (string, error) {
+	w := walker{z: html.NewTokenizer(r), line: 1, visit: visit}
+	return w.run()
+}
+
+// WalkHeadings is Walk, calling visit with each anchor once its Heading is
+// known, which for a <section> is right after its title. Only the text of
+// headings that an anchor names is read, so it costs little more than Walk.
+func WalkHeadings(r io.Reader, visit func(Anchor) bool) (string, error) {
+	w := walker{z: html.NewTokenizer(r), line: 1, visit: visit, withHeadings: true}
+	return w.run()
+}
+
+// openAnchor is an anchor whose text is still being read
+type openAnchor struct {
+	Anchor
+	text  strings.Builder
+	space bool
+	// depth counts the elements of the same name opened inside it, so that
+	// the end tag that closes it is recognized
+	depth int
+	done  bool
+}
+
+// walker holds the state of Walk
+type walker struct {
+	z       *html.Tokenizer
+	offset  int64
+	line    int
+	visit   func(Anchor) bool
+	pending []*openAnchor
+	stopped bool
+	// withText delays visiting an anchor until its text is read, and
+	// withHeadings until its heading is known
+	withText     bool
+	withHeadings bool
+	// heading collects the text of the heading being read for the pending
+	// anchors, nil outside one
+	heading *openAnchor
+
+	title              strings.Builder
+	inTitle, titleDone bool
+}
+
+func (w *walker) run() (string, error) {
+	for !w.stopped {
+		tt := w.z.Next()
+		raw := w.z.Raw()
+		offset, line := w.offset, w.line
+		w.offset += int64(len(raw))
+		w.line += bytes.Count(raw, []byte("\n"))
+
+		switch tt {
+		case html.ErrorToken:
+			w.resolve("")
+			for _, a := range w.pending {
+				a.done = true
+			}
+			w.flush()
+			if err := w.z.Err(); err != io.EOF {
+				return w.title.String(), err
+			}
+			return w.title.String(), nil
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := w.z.TagName()
+			tag := string(name)
+			if tag == "title" && !w.titleDone {
+				w.inTitle = true
+			}
+			for _, a := range w.pending {
+				if !a.done && a.Element == tag && tt == html.StartTagToken {
+					a.depth++
+				}
+			}
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = w.z.TagAttr()
+				if string(key) != "id" && (tag != "a" || string(key) != "name") {
+					continue
+				}
+				anchor := Anchor{ID: string(val), Element: tag, Offset: offset, Line: line}
+				if w.withHeadings {
+					w.pending = append(w.pending, &openAnchor{Anchor: anchor, done: voidElements[tag]})
+					continue
+				}
+				if !w.withText {
+					if !w.visit(anchor) {
+						return w.title.String(), nil
+					}
+					continue
+				}
+				w.pending = append(w.pending, &openAnchor{
+					Anchor: anchor,
+					done:   tt == html.SelfClosingTagToken || voidElements[tag],
+				})
+			}
+			if w.withHeadings && w.heading == nil && IsHeading(tag) && tt == html.StartTagToken && w.reading() {
+				w.heading = &openAnchor{Anchor: Anchor{Element: tag}}
+			}
+
+		case html.TextToken:
+			if !w.inTitle && !w.reading() {
+				continue
+			}
+			text := w.z.Text()
+			if w.inTitle {
+				w.title.Write(text)
+			}
+			if w.withHeadings {
+				if w.heading != nil {
+					if !w.heading.done {
+						w.heading.write(text)
+					}
+				} else if len(bytes.TrimSpace(text)) > 0 {
+					// Text before any heading: the anchors name no heading
+					w.resolve("")
+				}
+				break
+			}
+			for _, a := range w.pending {
+				if !a.done {
+					a.write(text)
+				}
+			}
+
+		case html.EndTagToken:
+			if !w.inTitle && !w.reading() {
+				continue
+			}
+			name, _ := w.z.TagName()
+			tag := string(name)
+			if w.inTitle && tag == "title" {
+				w.inTitle, w.titleDone = false, true
+			}
+			if w.withHeadings {
+				if w.heading != nil && w.heading.Element == tag {
+					w.resolve(w.heading.text.String())
+				}
+				break
+			}
+			for _, a := range w.pending {
+				if !a.done && a.Element == tag {
+					if a.depth == 0 {
+						a.done = true
+					} else {
+						a.depth--
+					}
+				}
+			}
+		}
+		w.flush()
+	}
+	return w.title.String(), nil
+}
+
+// write appends text to the anchor's text, collapsing whitespace, and
+// marks the anchor done once its text is full
+func (a *openAnchor) write(text []byte) {
+	for len(text) > 0 {
+		r, size := utf8.DecodeRune(text)
+		text = text[size:]
+		if unicode.IsSpace(r) {
+			a.space = a.text.Len() > 0
+			continue
+		}
+		if a.text.Len()+utf8.RuneLen(r)+1 > maxText {
+			a.done = true
+			return
+		}
+		if a.space {
+			a.text.WriteByte(' ')
+			a.space = false
+		}
+		a.text.WriteRune(r)
+	}
+}
+
+// resolve sets the heading of the anchors waiting for one and ends the
+// heading being read
+func (w *walker) resolve(heading string) {
+	for _, a := range w.pending {
+		if !a.done {
+			a.Heading = heading
+			a.done = true
+		}
+	}
+	w.heading = nil
+}
+
+// IsHeading reports whether tag, a lowercase tag name such as
+// Anchor.Element, is that of a heading, h1 to h6
+func IsHeading(tag string) bool {
+	return len(tag) == 2 && tag[0] == 'h' && tag[1] >= '1' && tag[1] <= '6'
+}
+
+// reading reports whether the text or heading of an anchor is being read
+func (w *walker) reading() bool {
+	for _, a := range w.pending {
+		if !a.done {
+			return true
+		}
+	}
+	return false
+}
+
+// flush visits the anchors at the head of the queue whose text is complete,
+// keeping document order
+func (w *walker) flush() {
+	for len(w.pending) > 0 && w.pending[0].done {
+		a := w.pending[0]
+		w.pending = w.pending[1:]
+		a.Text = a.text.String()
+		if !w.visit(a.Anchor) {
+			w.stopped = true
+			return
+		}
+	}
+}
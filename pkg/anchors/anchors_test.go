@@ -0,0 +1,218 @@
+package anchors
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractIDs(t *testing.T) {
+	page, err := os.ReadFile(filepath.Join("testdata", "configuring-ingress.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Text is compared by prefix: a section's text runs to maxText bytes
+	want := []struct {
+		id, element string
+		line        int
+		text        string
+	}{
+		{"configuring-ingress", "section", 9, "Chapter 7. Configuring the Ingress Controller 7.1. OpenShift"},
+		{"nw-ne-openshift-ingress_configuring-ingress", "section", 11, "7.1. OpenShift Container Platform Ingress Operator When you create"},
+		{"nw-ingress-controller-configuration-parameters_configuring-ingress", "section", 15, "7.3. Ingress Controller configuration parameters Table 7.1."},
+		{"ingress-sharding", "a", 17, ""},
+		{"ingress-params-table", "table", 18, "Table 7.1. Ingress Controller configuration parameters"},
+		{"ingress-diagram", "img", 22, ""},
+		{"tls-security-profiles_configuring-ingress", "h4", 23, "Ingress & TLS security profiles"},
+		{"empty", "div", 24, ""},
+	}
+
+	got, err := ExtractIDs(strings.NewReader(string(page)))
+	if err != nil {
+		t.Fatalf("ExtractIDs() error = %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ExtractIDs() = %d anchors, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		a := got[i]
+		if a.ID != w.id || a.Element != w.element || a.Line != w.line {
+			t.Errorf("anchor %d = %s <%s> line %d, want %s <%s> line %d", i, a.ID, a.Element, a.Line, w.id, w.element, w.line)
+		}
+		if !strings.HasPrefix(a.Text, w.text) || w.text == "" && a.Text != "" || len(a.Text) > maxText {
+			t.Errorf("anchor %s text = %q, want %q", a.ID, a.Text, w.text)
+		}
+		if !strings.HasPrefix(string(page[a.Offset:]), "<"+a.Element+" ") {
+			t.Errorf("anchor %s offset %d is not its start tag: %.30q", a.ID, a.Offset, page[a.Offset:])
+		}
+	}
+}
+
+func TestExtractIDs_Text(t *testing.T) {
+	long := strings.Repeat("é", maxText)
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"whitespace collapsed", "<h2 id=\"a\">\n  Installing\t<code>oc</code>\n</h2>", "Installing oc"},
+		{"nested elements of the same name", `<div id="a">one <div>two</div> three</div><p>four</p>`, "one two three"},
+		{"unclosed element ends with the page", `<p id="a">one<p>two`, "onetwo"},
+		{"cut without splitting a character", `<p id="a">` + long + `</p>`, strings.Repeat("é", maxText/2-1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractIDs(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatalf("ExtractIDs() error = %v", err)
+			}
+			if len(got) != 1 || got[0].Text != tt.want {
+				t.Errorf("ExtractIDs() = %+v, want text %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWalk(t *testing.T) {
+	tests := []struct {
+		name      string
+		html      string
+		stopAt    string
+		wantIDs   []string
+		wantTitle string
+	}{
+		{"ids and a names in document order",
+			`<html><body><h2 id="one">One</h2><a name="legacy"></a><div name="not-an-anchor" id="two"></div><img id="three"/></body></html>`,
+			"", []string{"one", "legacy", "two", "three"}, ""},
+		{"attribute names are case-insensitive",
+			`<H2 ID="upper">Upper</H2><A NAME="legacy"></A>`,
+			"", []string{"upper", "legacy"}, ""},
+		{"first title with entities decoded",
+			`<html><head><title>Ingress &amp; routes | Networking</title></head><body><svg><title>Icon</title></svg></body></html>`,
+			"", nil, "Ingress & routes | Networking"},
+		{"stops at the anchor",
+			`<h2 id="one"></h2><h2 id="two"></h2><h2 id="three"></h2>`,
+			"two", []string{"one", "two"}, ""},
+		{"stops before the anchor closes",
+			`<section id="one"><h2 id="two">Two</h2><p id="three"></p>`,
+			"one", []string{"one"}, ""},
+		{"ids in scripts are not elements",
+			`<script>document.write('<h2 id="fake">')</script><h2 id="real"></h2>`,
+			"", []string{"real"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ids []string
+			title, err := Walk(strings.NewReader(tt.html), func(a Anchor) bool {
+				if a.Text != "" {
+					t.Errorf("Walk() text of %s = %q, want none", a.ID, a.Text)
+				}
+				ids = append(ids, a.ID)
+				return a.ID != tt.stopAt
+			})
+			if err != nil {
+				t.Fatalf("Walk() error = %v", err)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) || title != tt.wantTitle {
+				t.Errorf("Walk() = %v, %q; want %v, %q", ids, title, tt.wantIDs, tt.wantTitle)
+			}
+		})
+	}
+}
+
+func TestWalkHeadings(t *testing.T) {
+	page, err := os.ReadFile(filepath.Join("testdata", "configuring-ingress.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"configuring-ingress":                                                "Chapter 7. Configuring the Ingress Controller",
+		"nw-ne-openshift-ingress_configuring-ingress":                        "7.1. OpenShift Container Platform Ingress Operator",
+		"nw-ingress-controller-configuration-parameters_configuring-ingress": "7.3. Ingress Controller configuration parameters",
+		"ingress-sharding":                                                   "",
+		"ingress-params-table":                                               "",
+		"ingress-diagram":                                                    "",
+		"tls-security-profiles_configuring-ingress":                          "Ingress & TLS security profiles",
+		"empty": "",
+	}
+
+	var ids []string
+	title, err := WalkHeadings(strings.NewReader(string(page)), func(a Anchor) bool {
+		ids = append(ids, a.ID)
+		if a.Heading != want[a.ID] {
+			t.Errorf("WalkHeadings() heading of %s = %q, want %q", a.ID, a.Heading, want[a.ID])
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatalf("WalkHeadings() error = %v", err)
+	}
+	if len(ids) != len(want) || ids[0] != "configuring-ingress" || !strings.HasPrefix(title, "Chapter 7.") {
+		t.Errorf("WalkHeadings() = %v, %q; want the %d anchors of the page in order and its title", ids, title, len(want))
+	}
+}
+
+func TestWalkHeadings_Cases(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want map[string]string
+	}{
+		{"anchor before a heading",
+			"<a name=\"legacy\"></a>\n<h2>Legacy</h2>",
+			map[string]string{"legacy": "Legacy"}},
+		{"anchor inside a heading",
+			`<h2 id="outer"><a id="inner"></a>Title <code>oc</code></h2>`,
+			map[string]string{"outer": "Title oc", "inner": "Title oc"}},
+		{"text before the heading",
+			`<div id="intro"><p>Read this first.</p><h2>Later</h2></div>`,
+			map[string]string{"intro": ""}},
+		{"no heading before the end",
+			`<div id="last"></div>`,
+			map[string]string{"last": ""}},
+		{"unclosed heading ends with the page",
+			`<section id="s"><h3>Cut`,
+			map[string]string{"s": ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(map[string]string)
+			if _, err := WalkHeadings(strings.NewReader(tt.html), func(a Anchor) bool {
+				got[a.ID] = a.Heading
+				return true
+			}); err != nil {
+				t.Fatalf("WalkHeadings() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WalkHeadings() headings = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsHeading(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want bool
+	}{
+		{"h1", true},
+		{"h6", true},
+		{"h7", false},
+		{"h0", false},
+		{"hr", false},
+		{"header", false},
+		{"section", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsHeading(tt.tag); got != tt.want {
+			t.Errorf("IsHeading(%q) = %v, want %v", tt.tag, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package anchors
+
+import (
+	"slices"
+	"strings"
+)
+
+// minSimilarity is the score below which an id is too unlike the anchor
+// to be suggested for it
+const minSimilarity = 0.5
+
+// Closest returns up to n ids of a page that most resemble anchor, which
+// the page lacks, best first and in document order among equals. Ids with
+// the same base and another assembly context rank first, as they are what
+// a section renamed by a new assembly gets, e.g.
+// "mirroring-image-set-full_installing-mirroring-disconnected" for
+// "mirroring-image-set-full"; then ids whose base starts with the words of
+// the other's, then ids by edit distance or shared words. Ids that resemble
+// anchor too little are left out, so the result may be empty.
+func Closest(ids []string, anchor string, n int) []string {
+	type candidate struct {
+		id    string
+		score float64
+	}
+	var candidates []candidate
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		if id == anchor || seen[id] {
+			continue
+		}
+		seen[id] = true
+		if score := similarity(anchor, id); score >= minSimilarity {
+			candidates = append(candidates, candidate{id, score})
+		}
+	}
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		switch {
+		case a.score > b.score:
+			return -1
+		case a.score < b.score:
+			return 1
+		}
+		return 0
+	})
+
+	var closest []string
+	for _, c := range candidates[:min(n, len(candidates))] {
+		closest = append(closest, c.id)
+	}
+	return closest
+}
+
+// similarity scores how much id resembles anchor, from 0 to 1. The bases
+// are compared, without assembly contexts.
+func similarity(anchor, id string) float64 {
+	a, _ := SplitContext(anchor)
+	b, _ := SplitContext(id)
+	if a == b {
+		return 1
+	}
+
+	shorter, longer := min(len(a), len(b)), max(len(a), len(b))
+	if wordPrefix(a, b) || wordPrefix(b, a) {
+		return 0.8 + 0.15*float64(shorter)/float64(longer)
+	}
+
+	score := wordOverlap(a, b)
+	// Bases differing in length by half the longer one are less than half
+	// alike by edit distance, not worth computing
+	if longer-shorter < longer/2 {
+		score = max(score, 1-float64(editDistance(a, b))/float64(longer))
+	}
+	return score * 0.8
+}
+
+// wordPrefix reports whether s starts with the words of prefix
+func wordPrefix(s, prefix string) bool {
+	return len(s) > len(prefix) && strings.HasPrefix(s, prefix) && strings.ContainsRune("-_.", rune(s[len(prefix)]))
+}
+
+// wordOverlap returns the share of the words of a and b that both have
+func wordOverlap(a, b string) float64 {
+	wa, wb := idWords(a), idWords(b)
+	if len(wa) == 0 || len(wb) == 0 {
+		return 0
+	}
+	shared := 0
+	for _, w := range wa {
+		if slices.Contains(wb, w) {
+			shared++
+		}
+	}
+	return float64(2*shared) / float64(len(wa)+len(wb))
+}
+
+// idWords splits an id into its words
+func idWords(id string) []string {
+	return strings.FieldsFunc(strings.ToLower(id), func(r rune) bool {
+		return r == '-' || r == '_' || r == '.'
+	})
+}
+
+// editDistance returns the Levenshtein distance between a and b in bytes;
+// ids are nearly always ASCII
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
@@ -0,0 +1,85 @@
+package anchors
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClosest(t *testing.T) {
+	ids := []string{
+		"_abstract",
+		"installing-mirroring-disconnected",
+		"mirroring-image-set-full_installing-mirroring-disconnected",
+		"mirroring-image-set-partial_installing-mirroring-disconnected",
+		"oc-mirror-support_installing-mirroring-disconnected",
+		"mirroring-image-set_installing-mirroring-disconnected",
+		"configuring-ingress-controller",
+		"nw-ingress-configuring_ingress",
+		"configuring-ingress-controller",
+	}
+
+	tests := []struct {
+		name   string
+		anchor string
+		n      int
+		want   []string
+	}{
+		{
+			name:   "assembly context added",
+			anchor: "mirroring-image-set-full",
+			n:      3,
+			want: []string{
+				"mirroring-image-set-full_installing-mirroring-disconnected",
+				"mirroring-image-set_installing-mirroring-disconnected",
+				"mirroring-image-set-partial_installing-mirroring-disconnected",
+			},
+		},
+		{
+			name:   "assembly context changed",
+			anchor: "mirroring-image-set-full_about-installing-oc-mirror",
+			n:      1,
+			want:   []string{"mirroring-image-set-full_installing-mirroring-disconnected"},
+		},
+		{
+			name:   "typo",
+			anchor: "configuring-ingress-controler",
+			n:      3,
+			want:   []string{"configuring-ingress-controller", "nw-ingress-configuring_ingress"},
+		},
+		{
+			name:   "words reordered",
+			anchor: "ingress-configuring",
+			n:      1,
+			want:   []string{"nw-ingress-configuring_ingress"},
+		},
+		{name: "nothing alike", anchor: "persistent-storage-using-nfs", n: 3, want: nil},
+		{name: "a word is not a prefix of a longer one", anchor: "mirror", n: 3, want: nil},
+		{name: "none asked", anchor: "mirroring-image-set-full", n: 0, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Closest(ids, tt.anchor, tt.n); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Closest(%q) = %q, want %q", tt.anchor, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"ingress", "", 7},
+		{"ingress", "ingress", 0},
+		{"controler", "controller", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
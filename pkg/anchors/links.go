@@ -0,0 +1,70 @@
+package anchors
+
+import (
+	"io"
+
+	"golang.org/x/net/html"
+)
+
+// Link is an <a> element with an href, such as an entry of the table of
+// contents of a multi-page guide
+type Link struct {
+	// Href is the value of the href attribute, as written
+	Href string
+	// Text is the text inside the element with runs of whitespace
+	// collapsed, cut at maxText bytes
+	Text string
+}
+
+// ExtractLinks returns every link of an HTML page in document order, with
+// its text. A link opened inside another, which HTML does not allow, ends
+// the outer one.
+func ExtractLinks(r io.Reader) ([]Link, error) {
+	z := html.NewTokenizer(r)
+	var links []Link
+	// href and text are those of the link being read; text is nil outside
+	// a link
+	var href string
+	var text *openAnchor
+	end := func() {
+		if text != nil {
+			links = append(links, Link{Href: href, Text: text.text.String()})
+			text = nil
+		}
+	}
+
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			end()
+			if err := z.Err(); err != io.EOF {
+				return nil, err
+			}
+			return links, nil
+
+		case html.StartTagToken:
+			name, hasAttr := z.TagName()
+			if string(name) != "a" {
+				continue
+			}
+			end()
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = z.TagAttr()
+				if string(key) == "href" {
+					href, text = string(val), &openAnchor{}
+				}
+			}
+
+		case html.TextToken:
+			if text != nil && !text.done {
+				text.write(z.Text())
+			}
+
+		case html.EndTagToken:
+			if name, _ := z.TagName(); string(name) == "a" {
+				end()
+			}
+		}
+	}
+}
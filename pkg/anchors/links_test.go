@@ -0,0 +1,44 @@
+package anchors
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractLinks(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want []Link
+	}{
+		{
+			name: "table of contents",
+			html: `<nav><ol>
+				<li><a href="ingress-operator">Chapter 1.
+					Ingress Operator</a></li>
+				<li><a href="/en/documentation/openshift_container_platform/4.18/html/networking/configuring-ingress"><span>Chapter 2.</span> Configuring <code>Ingress</code></a></li>
+			</ol></nav>`,
+			want: []Link{
+				{Href: "ingress-operator", Text: "Chapter 1. Ingress Operator"},
+				{Href: "/en/documentation/openshift_container_platform/4.18/html/networking/configuring-ingress", Text: "Chapter 2. Configuring Ingress"},
+			},
+		},
+		{name: "named anchors are not links", html: `<a name="top"></a><a href="#top">Top</a>`, want: []Link{{Href: "#top", Text: "Top"}}},
+		{name: "unclosed link ends with the next one", html: `<a href="a">one<a href="b">two</a>`, want: []Link{{Href: "a", Text: "one"}, {Href: "b", Text: "two"}}},
+		{name: "unclosed link ends with the page", html: `<p><a href="a">one`, want: []Link{{Href: "a", Text: "one"}}},
+		{name: "no links", html: `<p id="a">text</p>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractLinks(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatalf("ExtractLinks() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExtractLinks() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
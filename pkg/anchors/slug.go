@@ -0,0 +1,66 @@
+package anchors
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// markupRegex matches the inline HTML tags and character references a
+// heading may contain, which never end up in its id
+var markupRegex = regexp.MustCompile(`<[^>]+>|&(?:[a-zA-Z][a-zA-Z]+\d{0,2}|#\d{2,5}|#x[\da-fA-F]{2,4});`)
+
+// SlugForHeading returns the id the OpenShift documentation toolchain gives
+// a heading without an explicit id. It follows the Asciidoctor rules with
+// an empty id prefix and "-" as separator, as set by the OpenShift docs:
+//
+//   - the text is lowercased
+//   - inline markup and character references are dropped
+//   - characters other than letters, digits, "_", "-", "." and spaces are
+//     dropped
+//   - runs of spaces, "-" and "." become a single "-", trimmed at both ends
+//
+// Most OpenShift headings carry an explicit id instead, with the assembly
+// context appended after an underscore (see WithContext); the slug is
+// the usual base of those ids, but writers may pick any id, so the result
+// is a best-effort guess rather than a guarantee.
+func SlugForHeading(text string) string {
+	text = markupRegex.ReplaceAllString(strings.ToLower(text), "")
+
+	var b strings.Builder
+	separate := false
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			if separate && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			separate = false
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || unicode.IsSpace(r):
+			separate = true
+		}
+	}
+	return b.String()
+}
+
+// WithContext returns the id of a module included with an assembly context,
+// e.g. "configuring-ingress_networking" for slug "configuring-ingress" and
+// context "networking". An empty context leaves the slug alone.
+func WithContext(slug, context string) string {
+	if context == "" {
+		return slug
+	}
+	return slug + "_" + context
+}
+
+// SplitContext splits an id into its base and the assembly context after
+// its last underscore. Underscores are also valid inside a base, so this is
+// a best-effort guess; an id without an underscore has no context.
+func SplitContext(id string) (base, context string) {
+	i := strings.LastIndexByte(id, '_')
+	if i <= 0 || i == len(id)-1 {
+		return id, ""
+	}
+	return id[:i], id[i+1:]
+}
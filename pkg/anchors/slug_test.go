@@ -0,0 +1,54 @@
+package anchors
+
+import "testing"
+
+func TestSlugForHeading(t *testing.T) {
+	tests := []struct {
+		heading string
+		want    string
+	}{
+		{"Configuring the Ingress Controller", "configuring-the-ingress-controller"},
+		{"Ingress & TLS security profiles", "ingress-tls-security-profiles"},
+		{"Ingress &amp; TLS security profiles", "ingress-tls-security-profiles"},
+		{"Installing the <code>oc</code> CLI", "installing-the-oc-cli"},
+		{"About OpenShift 4.17", "about-openshift-4-17"},
+		{"Using the oc-mirror plugin: mirror_to_disk", "using-the-oc-mirror-plugin-mirror_to_disk"},
+		{"  -- Leading and trailing --  ", "leading-and-trailing"},
+		{"Prerequisites (optional)", "prerequisites-optional"},
+		{"Übersicht der Netzwerke", "übersicht-der-netzwerke"},
+		{"!!!", ""},
+	}
+
+	for _, tt := range tests {
+		if got := SlugForHeading(tt.heading); got != tt.want {
+			t.Errorf("SlugForHeading(%q) = %q, want %q", tt.heading, got, tt.want)
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	tests := []struct {
+		id, base, context string
+	}{
+		{"configuring-ingress_networking", "configuring-ingress", "networking"},
+		{"nw-ne-openshift-ingress_configuring-ingress", "nw-ne-openshift-ingress", "configuring-ingress"},
+		{"configuring-ingress", "configuring-ingress", ""},
+		{"_configuring-ingress", "_configuring-ingress", ""},
+		{"configuring-ingress_", "configuring-ingress_", ""},
+		// A base with an underscore of its own is split at the last one
+		{"mirror_to_disk_oc-mirror", "mirror_to_disk", "oc-mirror"},
+	}
+
+	for _, tt := range tests {
+		base, context := SplitContext(tt.id)
+		if base != tt.base || context != tt.context {
+			t.Errorf("SplitContext(%q) = %q, %q; want %q, %q", tt.id, base, context, tt.base, tt.context)
+		}
+		if tt.context != "" && WithContext(base, context) != tt.id {
+			t.Errorf("WithContext(%q, %q) = %q, want %q", base, context, WithContext(base, context), tt.id)
+		}
+	}
+	if got := WithContext("configuring-ingress", ""); got != "configuring-ingress" {
+		t.Errorf("WithContext() without a context = %q", got)
+	}
+}
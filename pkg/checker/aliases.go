@@ -0,0 +1,141 @@
+package checker
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/parser"
+)
+
+// Built-in version aliases
+const (
+	// AliasLatest is the newest known version
+	AliasLatest = "latest"
+	// AliasEUSLatest is the newest known Extended Update Support version
+	AliasEUSLatest = "eus-latest"
+)
+
+var (
+	// latestMinusRe matches "latest-N", the Nth version before the newest
+	latestMinusRe = regexp.MustCompile(`^latest-(\d+)$`)
+	// concreteVersionRe matches a major.minor version
+	concreteVersionRe = regexp.MustCompile(`^\d+\.\d+$`)
+)
+
+// builtinAlias reports whether name is one of the built-in aliases
+func builtinAlias(name string) bool {
+	return name == AliasLatest || name == AliasEUSLatest || latestMinusRe.MatchString(name)
+}
+
+// SetVersionAliases adds user-defined version aliases, e.g. "stable" for
+// "4.18" or "next-eus" for "latest-2". Targets are a concrete version or a
+// built-in alias. Names are case-insensitive and must not shadow a built-in
+// alias or look like a version.
+func (c *Checker) SetVersionAliases(aliases map[string]string) error {
+	resolved := make(map[string]string)
+	for name, target := range aliases {
+		key := strings.ToLower(name)
+		switch {
+		case key == "" || key == "eus" || builtinAlias(key):
+			return fmt.Errorf("version alias %q is ambiguous: it is a built-in name", name)
+		case concreteVersionRe.MatchString(key):
+			return fmt.Errorf("version alias %q is ambiguous: it is a version", name)
+		}
+		if _, ok := resolved[key]; ok {
+			return fmt.Errorf("version alias %q is defined more than once", name)
+		}
+		if !concreteVersionRe.MatchString(target) && !builtinAlias(strings.ToLower(target)) {
+			return fmt.Errorf("version alias %q targets %q: expected major.minor, %s, latest-N or %s", name, target, AliasLatest, AliasEUSLatest)
+		}
+		resolved[key] = target
+	}
+
+	c.aliases = resolved
+	return nil
+}
+
+// ResolveVersion returns the concrete version for a version or alias:
+// "latest", "latest-N", "eus-latest" or a user-defined alias. Aliases
+// resolve against the known versions, so call it after SetVersions. Every
+// resolved alias is recorded for ResolvedAliases.
+func (c *Checker) ResolveVersion(name string) (string, error) {
+	if concreteVersionRe.MatchString(name) {
+		return name, nil
+	}
+
+	key := strings.ToLower(name)
+	target := key
+	if t, ok := c.aliases[key]; ok {
+		target = strings.ToLower(t)
+		if concreteVersionRe.MatchString(target) {
+			c.recordAlias(name, target)
+			return target, nil
+		}
+	}
+
+	versions := sortedVersions(c.knownVersions)
+	var resolved string
+	switch {
+	case target == AliasLatest && len(versions) > 0:
+		resolved = versions[len(versions)-1]
+	case target == AliasEUSLatest:
+		if eus := EUSVersions(versions); len(eus) > 0 {
+			resolved = eus[len(eus)-1]
+		}
+	case latestMinusRe.MatchString(target):
+		n, err := strconv.Atoi(latestMinusRe.FindStringSubmatch(target)[1])
+		if err == nil && n < len(versions) {
+			resolved = versions[len(versions)-1-n]
+		}
+	default:
+		return "", fmt.Errorf("unknown version or alias %q: expected major.minor, %s, latest-N, %s or a user-defined alias", name, AliasLatest, AliasEUSLatest)
+	}
+	if resolved == "" {
+		return "", fmt.Errorf("version alias %q does not resolve: only %d version(s) are known", name, len(versions))
+	}
+
+	c.recordAlias(name, resolved)
+	return resolved, nil
+}
+
+// recordAlias remembers the concrete version an alias resolved to
+func (c *Checker) recordAlias(name, version string) {
+	if c.resolvedAliases == nil {
+		c.resolvedAliases = make(map[string]string)
+	}
+	c.resolvedAliases[name] = version
+}
+
+// ResolvedAliases returns the aliases resolved so far and their concrete
+// versions, for reports to show which versions were meant
+func (c *Checker) ResolvedAliases() map[string]string {
+	return c.resolvedAliases
+}
+
+// sortedVersions returns the valid versions of versions, oldest first
+func sortedVersions(versions []string) []string {
+	type version struct {
+		name       string
+		majorMinor [2]int
+	}
+	var parsed []version
+	for _, v := range versions {
+		doc := &parser.OCPDocURL{Version: v}
+		if err := parseVersionInPlace(doc); err == nil {
+			parsed = append(parsed, version{v, doc.MajorMinor})
+		}
+	}
+	sort.Slice(parsed, func(i, j int) bool {
+		a, b := parsed[i].majorMinor, parsed[j].majorMinor
+		return a[0] < b[0] || a[0] == b[0] && a[1] < b[1]
+	})
+
+	sorted := make([]string, len(parsed))
+	for i, v := range parsed {
+		sorted[i] = v.name
+	}
+	return sorted
+}
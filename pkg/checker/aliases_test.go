@@ -0,0 +1,90 @@
+package checker
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveVersion(t *testing.T) {
+	aliases := map[string]string{"Stable": "4.18", "next-eus": "eus-latest", "previous": "latest-1"}
+
+	tests := []struct {
+		name     string
+		versions []string // nil keeps the built-in versions
+		alias    string
+		want     string
+		wantErr  bool
+	}{
+		{"version", nil, "4.12", "4.12", false},
+		{"latest", nil, "latest", "4.20", false},
+		{"latest is case-insensitive", nil, "LATEST", "4.20", false},
+		{"latest-1", nil, "latest-1", "4.19", false},
+		{"latest-0", nil, "latest-0", "4.20", false},
+		{"eus-latest", nil, "eus-latest", "4.20", false},
+		{"user alias to a version", nil, "stable", "4.18", false},
+		{"user alias to an alias", nil, "previous", "4.19", false},
+		{"discovered versions", []string{"4.21", "4.19", "4.22", "4.20"}, "latest", "4.22", false},
+		{"discovered latest-1", []string{"4.21", "4.19", "4.22", "4.20"}, "latest-1", "4.21", false},
+		{"discovered eus-latest", []string{"4.21", "4.19", "4.22", "4.20"}, "next-eus", "4.22", false},
+		{"discovered minor above 9", []string{"4.9", "4.10"}, "latest", "4.10", false},
+		{"beyond the oldest version", []string{"4.19", "4.20"}, "latest-2", "", true},
+		{"no EUS version", []string{"4.19", "4.21"}, "eus-latest", "", true},
+		{"unknown", nil, "nightly", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker()
+			if tt.versions != nil {
+				c.SetVersions(tt.versions)
+			}
+			if err := c.SetVersionAliases(aliases); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := c.ResolveVersion(tt.alias)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveVersion(%q) error = %v, wantErr %v", tt.alias, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveVersion(%q) = %q, want %q", tt.alias, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolvedAliases(t *testing.T) {
+	c := NewChecker()
+	if err := c.SetAllowedTargets([]string{"4.16", "latest-1", "eus"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Concrete versions and the eus keyword are not aliases
+	want := map[string]string{"latest-1": "4.19"}
+	if got := c.ResolvedAliases(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ResolvedAliases() = %v, want %v", got, want)
+	}
+}
+
+func TestSetVersionAliases_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		aliases map[string]string
+	}{
+		{"shadows latest", map[string]string{"Latest": "4.18"}},
+		{"shadows latest-N", map[string]string{"latest-3": "4.18"}},
+		{"shadows eus", map[string]string{"eus": "4.18"}},
+		{"looks like a version", map[string]string{"4.99": "4.18"}},
+		{"same name twice", map[string]string{"stable": "4.18", "STABLE": "4.17"}},
+		{"targets another user alias", map[string]string{"stable": "4.18", "prod": "stable"}},
+		{"empty target", map[string]string{"stable": ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := NewChecker().SetVersionAliases(tt.aliases); err == nil {
+				t.Error("SetVersionAliases() error = nil, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package checker
+
+import "fmt"
+
+// AnchorPolicy decides whether a newer version whose page exists without
+// the anchor of the URL counts as newer
+type AnchorPolicy string
+
+const (
+	// AnchorPolicyStrict counts a newer version only when its page has the
+	// anchor, so a URL whose anchor was renamed in every later release is
+	// up to date
+	AnchorPolicyStrict AnchorPolicy = "strict"
+	// AnchorPolicyPageOnly counts a newer version when its page exists,
+	// with AnchorExists false when it lacks the anchor
+	AnchorPolicyPageOnly AnchorPolicy = "page-only"
+)
+
+// SetAnchorPolicy sets whether newer versions whose page lacks the anchor
+// of the URL count as newer. The default is AnchorPolicyStrict. With
+// AnchorPolicyPageOnly, versions rank by the page alone, so the best
+// suggestion may lack the anchor; see VersionCheckResult.AnchorMissing.
+func (c *Checker) SetAnchorPolicy(p AnchorPolicy) error {
+	switch p {
+	case AnchorPolicyStrict, AnchorPolicyPageOnly:
+	default:
+		return fmt.Errorf("invalid anchor policy %q (expected strict or page-only)", p)
+	}
+	c.anchorPolicy = p
+	return nil
+}
+
+// AnchorMissing reports whether the page of the version exists but lacks
+// the anchor of the URL, or may lack it when it was indeterminate
+func (v VersionCheckResult) AnchorMissing() bool {
+	return v.Exists && v.HasAnchor && !v.AnchorExists
+}
+
+// countsAsNewer reports whether the version counts as a newer version of
+// the URL under the anchor policy
+func (c *Checker) countsAsNewer(v VersionCheckResult) bool {
+	if !v.Exists {
+		return false
+	}
+	return !v.AnchorMissing() || c.anchorPolicy == AnchorPolicyPageOnly
+}
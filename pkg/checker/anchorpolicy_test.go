@@ -0,0 +1,93 @@
+package checker
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestCheck_AnchorPolicy(t *testing.T) {
+	const docPath = "/en/documentation/openshift_container_platform/%s/html/networking/ingress"
+	withAnchor := `<html><body><h2 id="nw-ingress-sharding">Ingress sharding</h2></body></html>`
+	renamed := `<html><body><h2 id="nw-ingress-sharding_configuring-ingress">Ingress sharding</h2></body></html>`
+
+	tests := []struct {
+		name         string
+		policy       AnchorPolicy
+		pages        map[string]string
+		wantOutdated bool
+		wantLatest   string
+		wantMissing  bool // the best suggestion lacks the anchor
+	}{
+		{
+			name:       "strict keeps a renamed anchor up to date",
+			policy:     AnchorPolicyStrict,
+			pages:      map[string]string{"4.17": renamed, "4.18": renamed},
+			wantLatest: "4.16",
+		},
+		{
+			name:         "page-only counts the pages",
+			policy:       AnchorPolicyPageOnly,
+			pages:        map[string]string{"4.17": renamed, "4.18": renamed},
+			wantOutdated: true, wantLatest: "4.18", wantMissing: true,
+		},
+		{
+			name:         "strict stops at the last page with the anchor",
+			policy:       AnchorPolicyStrict,
+			pages:        map[string]string{"4.17": withAnchor, "4.18": renamed},
+			wantOutdated: true, wantLatest: "4.17",
+		},
+		{
+			name:         "page-only prefers the newer page",
+			policy:       AnchorPolicyPageOnly,
+			pages:        map[string]string{"4.17": withAnchor, "4.18": renamed},
+			wantOutdated: true, wantLatest: "4.18", wantMissing: true,
+		},
+		{
+			name:         "page-only still needs the page",
+			policy:       AnchorPolicyPageOnly,
+			pages:        map[string]string{"4.17": renamed},
+			wantOutdated: true, wantLatest: "4.17", wantMissing: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages := map[string]string{fmt.Sprintf(docPath, "4.16"): withAnchor}
+			for version, page := range tt.pages {
+				pages[fmt.Sprintf(docPath, version)] = page
+			}
+			c := newFakeDocsChecker(t, pages)
+			c.SetVersions([]string{"4.16", "4.17", "4.18"})
+			if err := c.SetAnchorPolicy(tt.policy); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := c.Check("https://docs.redhat.com" + fmt.Sprintf(docPath, "4.16") + "#nw-ingress-sharding")
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if result.IsOutdated != tt.wantOutdated || result.LatestVersion != tt.wantLatest || result.AnchorPolicy != tt.policy {
+				t.Errorf("Check() = outdated %v, latest %s, policy %s; want %v, %s, %s",
+					result.IsOutdated, result.LatestVersion, result.AnchorPolicy, tt.wantOutdated, tt.wantLatest, tt.policy)
+			}
+			best, ok := result.BestSuggestion()
+			if ok != tt.wantOutdated || best.AnchorMissing() != tt.wantMissing {
+				t.Errorf("BestSuggestion() = %+v, %v; want anchor missing %v", best, ok, tt.wantMissing)
+			}
+			if ok && best.AnchorMissing() && best.AnchorExists {
+				t.Errorf("BestSuggestion() AnchorExists = true for a page lacking the anchor")
+			}
+			if got := slices.Contains(result.AppliedHeuristics, HeuristicPageOnlyAnchor); got != tt.wantMissing {
+				t.Errorf("AppliedHeuristics = %v, want %s: %v", result.AppliedHeuristics, HeuristicPageOnlyAnchor, tt.wantMissing)
+			}
+		})
+	}
+
+	if err := NewChecker().SetAnchorPolicy("lenient"); err == nil {
+		t.Error("SetAnchorPolicy(lenient) succeeded, want error")
+	}
+	if got := NewChecker().anchorPolicy; got != AnchorPolicyStrict {
+		t.Errorf("default anchor policy = %s, want %s", got, AnchorPolicyStrict)
+	}
+}
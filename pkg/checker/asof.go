@@ -0,0 +1,63 @@
+package checker
+
+import (
+	"fmt"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/parser"
+)
+
+// SetAsOf caps the versions the checker knows at version, as if newer
+// releases were not published yet, so that a run repeated later reaches
+// the same verdicts. Newer versions are never requested, including those
+// set with SetVersions or found by DiscoverVersions afterwards, and allowing
+// one as an upgrade target is an error. An empty version removes the cap.
+// Call it before checking URLs.
+func (c *Checker) SetAsOf(version string) error {
+	if version == "" {
+		c.asOf = ""
+		return nil
+	}
+	if !concreteVersionRe.MatchString(version) {
+		return fmt.Errorf("invalid as-of version %q: expected major.minor", version)
+	}
+	for target := range c.allowedTargets {
+		if versionAfter(target, version) {
+			return fmt.Errorf("target version %s is newer than the as-of version %s", target, version)
+		}
+	}
+
+	c.asOf = version
+	c.knownVersions = c.capVersions(c.knownVersions)
+	return nil
+}
+
+// AsOf returns the version set with SetAsOf, empty when there is no cap
+func (c *Checker) AsOf() string {
+	return c.asOf
+}
+
+// capVersions returns the versions that are not newer than the as-of
+// version
+func (c *Checker) capVersions(versions []string) []string {
+	if c.asOf == "" {
+		return versions
+	}
+	var capped []string
+	for _, v := range versions {
+		if !versionAfter(v, c.asOf) {
+			capped = append(capped, v)
+		}
+	}
+	return capped
+}
+
+// versionAfter reports whether version a is newer than version b; versions
+// that do not parse are newer than none
+func versionAfter(a, b string) bool {
+	va, vb := &parser.OCPDocURL{Version: a}, &parser.OCPDocURL{Version: b}
+	if parseVersionInPlace(va) != nil || parseVersionInPlace(vb) != nil {
+		return false
+	}
+	x, y := va.MajorMinor, vb.MajorMinor
+	return x[0] > y[0] || x[0] == y[0] && x[1] > y[1]
+}
@@ -0,0 +1,97 @@
+package checker
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// verdict is the part of a result that must not change between runs
+type verdict struct {
+	Latest   string
+	Outdated bool
+	Checked  []string
+	Newer    []string
+	AsOf     string
+}
+
+func verdictOf(r *CheckResult) verdict {
+	v := verdict{Latest: r.LatestVersion, Outdated: r.IsOutdated, AsOf: r.AsOf}
+	for _, all := range r.AllResults {
+		v.Checked = append(v.Checked, all.Version)
+	}
+	for _, newer := range r.NewerVersions {
+		v.Newer = append(v.Newer, newer.URL)
+	}
+	return v
+}
+
+func TestSetAsOf_Reproducible(t *testing.T) {
+	const docPath = "/en/documentation/openshift_container_platform/%s/html/networking/index"
+	page := `<html><body><h2 id="ingress">Ingress</h2></body></html>`
+	pages := map[string]string{
+		fmt.Sprintf(docPath, "4.15"): page,
+		fmt.Sprintf(docPath, "4.16"): page,
+	}
+	url := "https://docs.redhat.com" + fmt.Sprintf(docPath, "4.15") + "#ingress"
+
+	run := func(asOf string) verdict {
+		c := newFakeDocsChecker(t, pages)
+		c.SetVersions([]string{"4.15", "4.16", "4.17"})
+		if err := c.SetAsOf(asOf); err != nil {
+			t.Fatal(err)
+		}
+		result, err := c.Check(url)
+		if err != nil {
+			t.Fatalf("Check() error = %v", err)
+		}
+		return verdictOf(result)
+	}
+
+	before := run("4.16")
+	// docs.redhat.com publishes 4.17 between the two runs
+	pages[fmt.Sprintf(docPath, "4.17")] = page
+	after := run("4.16")
+
+	if !reflect.DeepEqual(before, after) {
+		t.Errorf("verdict changed with 4.17 published: %+v, then %+v", before, after)
+	}
+	if want := (verdict{Latest: "4.16", Outdated: true, Checked: []string{"4.16"}, Newer: before.Newer, AsOf: "4.16"}); !reflect.DeepEqual(after, want) {
+		t.Errorf("verdict = %+v, want %+v", after, want)
+	}
+	if uncapped := run(""); uncapped.Latest != "4.17" {
+		t.Errorf("latest version without a cap = %s, want 4.17", uncapped.Latest)
+	}
+}
+
+func TestSetAsOf(t *testing.T) {
+	c := NewChecker()
+	if err := c.SetAsOf("4.16"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"4.10", "4.11", "4.12", "4.13", "4.14", "4.15", "4.16"}; !reflect.DeepEqual(c.knownVersions, want) {
+		t.Errorf("known versions = %v, want %v", c.knownVersions, want)
+	}
+	c.SetVersions([]string{"4.16", "4.17", "5.0"})
+	if want := []string{"4.16"}; !reflect.DeepEqual(c.knownVersions, want) {
+		t.Errorf("known versions after SetVersions = %v, want %v", c.knownVersions, want)
+	}
+	if latest, err := c.ResolveVersion(AliasLatest); err != nil || latest != "4.16" {
+		t.Errorf("ResolveVersion(latest) = %s, %v; want 4.16", latest, err)
+	}
+
+	if err := c.SetAllowedTargets([]string{"4.17"}); err == nil {
+		t.Error("SetAllowedTargets() above the as-of version succeeded, want an error")
+	}
+	if err := c.SetAsOf("latest"); err == nil {
+		t.Error("SetAsOf(latest) succeeded, want an error")
+	}
+
+	targets := NewChecker()
+	if err := targets.SetAllowedTargets([]string{"4.18"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := targets.SetAsOf("4.16"); err == nil {
+		t.Error("SetAsOf() below an allowed target succeeded, want an error")
+	}
+}
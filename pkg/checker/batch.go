@@ -0,0 +1,179 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/parser"
+)
+
+// CheckErrors reports the URLs of a CheckAll call that could not be
+// checked: their errors by index in the input. The results of those URLs
+// are nil.
+type CheckErrors map[int]error
+
+func (e CheckErrors) Error() string {
+	first := -1
+	for i := range e {
+		if first < 0 || i < first {
+			first = i
+		}
+	}
+	if len(e) == 1 {
+		return fmt.Sprintf("URL %d could not be checked: %v", first, e[first])
+	}
+	return fmt.Sprintf("%d URLs could not be checked, the first (URL %d): %v", len(e), first, e[first])
+}
+
+// CheckAll checks every URL like Check and returns the results in the order
+// of urls. URLs of the same page, e.g. with different anchors, are checked
+// one after the other, so every version of the page is requested once and
+// its anchors answer for all of them; different pages are checked
+// concurrently. A URL that cannot be checked leaves a nil result and its
+// error in the returned CheckErrors, without failing the others.
+func (c *Checker) CheckAll(urls []string) ([]*CheckResult, error) {
+	return c.CheckAllContext(context.Background(), urls)
+}
+
+// CheckAllContext is CheckAll, starting no new check once ctx is done: the
+// URLs not started by then fail with the error of ctx. A check in progress
+// runs to its end. URLs are started in the order set with SetPriority.
+func (c *Checker) CheckAllContext(ctx context.Context, urls []string) ([]*CheckResult, error) {
+	results := make([]*CheckResult, len(urls))
+	errs := make(CheckErrors)
+	var mu sync.Mutex
+	var completed atomic.Int64
+
+	groups := make(chan []int)
+	var wg sync.WaitGroup
+	for range c.maxConcurrent {
+		wg.Go(func() {
+			for group := range groups {
+				for _, i := range group {
+					if err := ctx.Err(); err != nil {
+						mu.Lock()
+						errs[i] = err
+						mu.Unlock()
+						continue
+					}
+					// A check in progress runs to its end
+					result, err := c.checkAt(context.WithoutCancel(ctx), urls[i], batchPosition{index: i, total: len(urls), completed: &completed})
+					completed.Add(1)
+					mu.Lock()
+					if err != nil {
+						errs[i] = err
+					}
+					results[i] = result
+					mu.Unlock()
+				}
+			}
+		})
+	}
+	for _, group := range orderGroups(pageGroups(urls), urls, c.priority) {
+		groups <- group
+	}
+	close(groups)
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return results, errs
+	}
+	return results, nil
+}
+
+// Priority is the order CheckAll starts checking URLs in. It decides which
+// URLs are left unchecked when a run is cut short, never the order of the
+// results.
+type Priority string
+
+const (
+	// PriorityDiscovery starts URLs in the order they are given
+	PriorityDiscovery Priority = "discovery-order"
+	// PriorityOldestFirst starts URLs linking the oldest versions first,
+	// as they are the most likely to be outdated
+	PriorityOldestFirst Priority = "oldest-first"
+	// PriorityRandom starts URLs in a random order
+	PriorityRandom Priority = "random"
+)
+
+// Priorities are the valid priorities, the default first
+var Priorities = []Priority{PriorityDiscovery, PriorityOldestFirst, PriorityRandom}
+
+// ParsePriority returns the priority named s
+func ParsePriority(s string) (Priority, error) {
+	for _, p := range Priorities {
+		if string(p) == s {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("invalid priority %q (expected discovery-order, oldest-first or random)", s)
+}
+
+// SetPriority sets the order CheckAll starts checking URLs in; the default
+// is PriorityDiscovery
+func (c *Checker) SetPriority(p Priority) {
+	c.priority = p
+}
+
+// orderGroups returns the page groups of urls in the order of priority.
+// Oldest-first keeps the order of groups of the same version, and puts URLs
+// that do not parse last.
+func orderGroups(groups [][]int, urls []string, priority Priority) [][]int {
+	ordered := make([][]int, len(groups))
+	copy(ordered, groups)
+
+	switch priority {
+	case PriorityOldestFirst:
+		version := func(group []int) [2]int {
+			docURL, err := parser.ParseOCPDocURL(urls[group[0]])
+			if err != nil {
+				return [2]int{math.MaxInt, math.MaxInt}
+			}
+			return docURL.MajorMinor
+		}
+		sort.SliceStable(ordered, func(a, b int) bool {
+			va, vb := version(ordered[a]), version(ordered[b])
+			return va[0] < vb[0] || va[0] == vb[0] && va[1] < vb[1]
+		})
+	case PriorityRandom:
+		rand.Shuffle(len(ordered), func(a, b int) {
+			ordered[a], ordered[b] = ordered[b], ordered[a]
+		})
+	}
+	return ordered
+}
+
+// pageGroups returns the indexes of urls grouped by the page they link to,
+// in order of first appearance. Within a group, URLs with an anchor come
+// first: the pages they download also answer the URLs without one, but not
+// the other way around.
+func pageGroups(urls []string) [][]int {
+	var groups [][]int
+	index := make(map[string]int)
+	for i, u := range urls {
+		key := u
+		if docURL, err := parser.ParseOCPDocURL(u); err == nil {
+			key = strings.Join([]string{docURL.Version, docURL.Format, docURL.Document, docURL.Page}, "/")
+		}
+		g, ok := index[key]
+		if !ok {
+			g = len(groups)
+			index[key] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], i)
+	}
+
+	for _, group := range groups {
+		sort.SliceStable(group, func(a, b int) bool {
+			return strings.Contains(urls[group[a]], "#") && !strings.Contains(urls[group[b]], "#")
+		})
+	}
+	return groups
+}
@@ -0,0 +1,267 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/scanner"
+)
+
+func TestCheckAll(t *testing.T) {
+	c := newMatrixChecker(t)
+	c.SetFormatFallback(false) // no html-single lookups for the missing anchor
+	ingress := "https://docs.redhat.com" + fmt.Sprintf(matrixDocPath, "4.16", "ingress")
+	urls := []string{
+		ingress, // checked after the anchors of its page, answered by their downloads
+		matrixURLs[2],
+		"https://docs.redhat.com/not-a-doc",
+		ingress + "#configuring-ingress",
+		ingress + "#missing",
+		ingress + "#ingress-sharding",
+	}
+
+	results, err := c.CheckAll(urls)
+	var errs CheckErrors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[2] == nil {
+		t.Fatalf("CheckAll() error = %v, want only the third URL failed", err)
+	}
+	if results[2] != nil {
+		t.Errorf("CheckAll() result of a failed URL = %+v, want nil", results[2])
+	}
+
+	for i, u := range urls {
+		if i == 2 {
+			continue
+		}
+		if results[i] == nil || results[i].OriginalURL != u {
+			t.Fatalf("CheckAll() result %d = %+v, want the result of %s", i, results[i], u)
+		}
+	}
+	if got := results[4].LatestVersion; got != "4.16" {
+		t.Errorf("latest version of a missing anchor = %s, want 4.16", got)
+	}
+	if got := results[5].LatestVersion; got != "4.19" {
+		t.Errorf("latest version of an existing anchor = %s, want 4.19", got)
+	}
+
+	// One GET per version of the ingress page, one HEAD per version of the
+	// dns page, the original versions included
+	if got := c.Requests(); got != 8 {
+		t.Errorf("Requests() = %d, want 8", got)
+	}
+}
+
+func TestCheckAllContext_Done(t *testing.T) {
+	c := newMatrixChecker(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, err := c.CheckAllContext(ctx, matrixURLs)
+	var errs CheckErrors
+	if !errors.As(err, &errs) || len(errs) != len(matrixURLs) {
+		t.Fatalf("CheckAllContext() error = %v, want every URL not started", err)
+	}
+	for i := range matrixURLs {
+		if results[i] != nil || !errors.Is(errs[i], context.Canceled) {
+			t.Errorf("URL %d = %+v, %v; want no result and context.Canceled", i, results[i], errs[i])
+		}
+	}
+	if got := c.Requests(); got != 0 {
+		t.Errorf("Requests() = %d, want 0", got)
+	}
+}
+
+func TestCheckAllContext_FinishesChecksInProgress(t *testing.T) {
+	started := make(chan struct{})
+	var once sync.Once
+	release := make(chan struct{})
+	c := newHandlerChecker(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() { close(started) })
+		<-release
+		w.Write([]byte(`<html><body><h2 id="a">A</h2></body></html>`))
+	}))
+	c.SetVersions([]string{"4.16", "4.17"})
+	c.SetMaxConcurrent(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+		close(release)
+	}()
+	urls := []string{
+		"https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html/networking/ingress#a",
+		"https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html/networking/dns#a",
+	}
+	results, err := c.CheckAllContext(ctx, urls)
+
+	// The first URL was being checked when ctx was canceled, the second
+	// was not started
+	var errs CheckErrors
+	if !errors.As(err, &errs) || len(errs) != 1 || !errors.Is(errs[1], context.Canceled) {
+		t.Fatalf("CheckAllContext() error = %v, want the second URL not started", err)
+	}
+	if results[0] == nil || !results[0].IsOutdated {
+		t.Errorf("first URL = %+v, want it checked to its end", results[0])
+	}
+}
+
+func TestParsePriority(t *testing.T) {
+	for _, p := range Priorities {
+		if got, err := ParsePriority(string(p)); err != nil || got != p {
+			t.Errorf("ParsePriority(%q) = %q, %v; want %q", p, got, err, p)
+		}
+	}
+	if _, err := ParsePriority("newest-first"); err == nil {
+		t.Error("ParsePriority(newest-first) succeeded, want an error")
+	}
+}
+
+func TestOrderGroups(t *testing.T) {
+	doc := func(version, page string) string {
+		return "https://docs.redhat.com" + fmt.Sprintf(matrixDocPath, version, page)
+	}
+	urls := []string{
+		doc("4.16", "ingress"),
+		doc("4.12", "dns"),
+		"https://docs.redhat.com/not-a-doc",
+		doc("4.16", "dns"),
+		doc("4.9", "ingress"),
+	}
+	groups := [][]int{{0}, {1}, {2}, {3}, {4}}
+
+	tests := []struct {
+		priority Priority
+		want     [][]int
+	}{
+		{PriorityDiscovery, [][]int{{0}, {1}, {2}, {3}, {4}}},
+		{"", [][]int{{0}, {1}, {2}, {3}, {4}}},
+		// 4.9 before 4.12, the two 4.16 pages in discovery order, the URL
+		// that does not parse last
+		{PriorityOldestFirst, [][]int{{4}, {1}, {0}, {3}, {2}}},
+	}
+	for _, tt := range tests {
+		if got := orderGroups(groups, urls, tt.priority); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("orderGroups(%q) = %v, want %v", tt.priority, got, tt.want)
+		}
+	}
+
+	random := orderGroups(groups, urls, PriorityRandom)
+	slices.SortFunc(random, func(a, b []int) int { return a[0] - b[0] })
+	if !reflect.DeepEqual(random, groups) {
+		t.Errorf("orderGroups(random) = %v, want a permutation of %v", random, groups)
+	}
+	if !reflect.DeepEqual(groups, [][]int{{0}, {1}, {2}, {3}, {4}}) {
+		t.Errorf("orderGroups() modified its input: %v", groups)
+	}
+}
+
+func TestCheckAll_PriorityKeepsResults(t *testing.T) {
+	want := checkAll(t, newMatrixChecker(t), matrixURLs)
+
+	for _, p := range Priorities {
+		c := newMatrixChecker(t)
+		c.SetPriority(p)
+		results, err := c.CheckAll(matrixURLs)
+		if err != nil {
+			t.Fatalf("%s: CheckAll() error = %v", p, err)
+		}
+		for i, u := range matrixURLs {
+			if results[i].OriginalURL != u || results[i].LatestVersion != want[i] {
+				t.Errorf("%s: result %d = %s at %s, want %s at %s",
+					p, i, results[i].OriginalURL, results[i].LatestVersion, u, want[i])
+			}
+		}
+	}
+}
+
+func TestCheckAll_PriorityKeepsShards(t *testing.T) {
+	// Shards are cut before checking, so every priority checks each URL in
+	// exactly one shard and reports it there
+	const count = 3
+	for _, p := range Priorities {
+		seen := make(map[string]int)
+		for index := 1; index <= count; index++ {
+			shard := scanner.Shard{Index: index, Count: count}
+			var urls []string
+			for _, u := range matrixURLs {
+				if shard.Owns(u) {
+					urls = append(urls, u)
+				}
+			}
+
+			c := newMatrixChecker(t)
+			c.SetPriority(p)
+			results, err := c.CheckAll(urls)
+			if err != nil {
+				t.Fatalf("%s: shard %s: CheckAll() error = %v", p, shard, err)
+			}
+			for i, result := range results {
+				if result.OriginalURL != urls[i] {
+					t.Errorf("%s: shard %s: result %d = %s, want %s", p, shard, i, result.OriginalURL, urls[i])
+				}
+				seen[result.OriginalURL]++
+			}
+		}
+		for _, u := range matrixURLs {
+			if seen[u] != 1 {
+				t.Errorf("%s: %s reported by %d shards, want 1", p, u, seen[u])
+			}
+		}
+	}
+}
+
+func TestCheckAll_FetchesSharedPagesOnce(t *testing.T) {
+	const docPath = "/en/documentation/openshift_container_platform/%s/html/networking/ingress"
+	versions := []string{"4.14", "4.15", "4.16", "4.17", "4.18"}
+	page := `<html><body><h2 id="configuring-ingress">Ingress</h2></body></html>`
+
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	c := newHandlerChecker(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		// Keep the requests in flight long enough for both checks to need them
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(page))
+	}))
+	c.SetVersions(versions)
+	c.SetMaxConcurrent(10)
+
+	// Both checks need 4.16 to 4.18, and the original of the second is a
+	// newer version of the first
+	urls := []string{
+		"https://docs.redhat.com" + fmt.Sprintf(docPath, "4.14") + "#configuring-ingress",
+		"https://docs.redhat.com" + fmt.Sprintf(docPath, "4.16") + "#configuring-ingress",
+	}
+	results, err := c.CheckAll(urls)
+	if err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	for i, result := range results {
+		if result.LatestVersion != "4.18" {
+			t.Errorf("result %d latest = %s, want 4.18", i, result.LatestVersion)
+		}
+	}
+
+	total := 0
+	for _, v := range versions {
+		path := fmt.Sprintf(docPath, v)
+		if requests[path] != 1 {
+			t.Errorf("%s requested %d times, want once", path, requests[path])
+		}
+		total += requests[path]
+	}
+	if total != len(versions) || c.Requests() != int64(len(versions)) {
+		t.Errorf("%d page requests, %d sent; want %d", total, c.Requests(), len(versions))
+	}
+}
@@ -0,0 +1,51 @@
+package checker
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// DefaultMaxPageParseTime is the default time a page may take to read and
+// parse before its anchors are left indeterminate
+const DefaultMaxPageParseTime = 5 * time.Second
+
+// errParseBudget stops reading a page once its parse budget is spent
+var errParseBudget = errors.New("page parse budget exceeded")
+
+// SetMaxPageParseTime sets how long the body of a page may take to read and
+// parse for anchors, e.g. to bound runs over html-single guides of tens of
+// megabytes. A page cut short keeps the anchors read so far and is marked
+// Truncated; an anchor not among them is neither found nor missing but
+// AnchorIndeterminate, and is never suggested. 0 disables the budget. The
+// default is DefaultMaxPageParseTime. Call it before checking URLs.
+func (c *Checker) SetMaxPageParseTime(d time.Duration) {
+	c.maxParseTime = max(d, 0)
+}
+
+// budgetReader fails every read once the deadline of a page's parse budget
+// has passed, as told by now
+type budgetReader struct {
+	r        io.Reader
+	now      func() time.Time
+	deadline time.Time
+}
+
+func (r *budgetReader) Read(p []byte) (int, error) {
+	if !r.now().Before(r.deadline) {
+		return 0, errParseBudget
+	}
+	return r.r.Read(p)
+}
+
+// Indeterminate returns the versions checked whose anchor could not be
+// verified within the parse budget of SetMaxPageParseTime
+func (r *CheckResult) Indeterminate() []VersionCheckResult {
+	var versions []VersionCheckResult
+	for _, v := range r.AllResults {
+		if v.AnchorIndeterminate {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
@@ -0,0 +1,133 @@
+package checker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tickingClock returns a clock that moves on by tick every time it is read,
+// so a parse budget runs out after a set number of reads of a page
+func tickingClock(tick time.Duration) func() time.Time {
+	var reads atomic.Int64
+	start := time.Date(2025, time.March, 4, 12, 0, 0, 0, time.UTC)
+	return func() time.Time {
+		return start.Add(time.Duration(reads.Add(1)) * tick)
+	}
+}
+
+// hugeGuide returns an html-single guide of about 4 MB with the given ids
+// at its start and its end
+func hugeGuide(first, last string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<html><head><title>Networking | OpenShift Container Platform</title></head><body><section id="%s"><h2>First</h2></section>`, first)
+	for i := range 20000 {
+		fmt.Fprintf(&b, `<section id="section-%d"><h3>Section %d</h3><p>%s</p></section>`, i, i, strings.Repeat("Lorem ipsum dolor sit amet. ", 5))
+	}
+	fmt.Fprintf(&b, `<section id="%s"><h2>Last</h2></section></body></html>`, last)
+	return b.String()
+}
+
+func TestCheck_ParseBudget(t *testing.T) {
+	const singlePath = "/en/documentation/openshift_container_platform/%s/html-single/networking/index"
+	const pagePath = "/en/documentation/openshift_container_platform/%s/html/networking/ingress"
+	guide := hugeGuide("ingress-overview", "ingress-sharding")
+	pages := map[string]string{
+		fmt.Sprintf(singlePath, "4.16"): `<html><body><section id="ingress-overview"></section><section id="ingress-sharding"></section></body></html>`,
+		fmt.Sprintf(singlePath, "4.17"): guide,
+		// The multi-page html page renders its sections with JavaScript
+		fmt.Sprintf(pagePath, "4.16"): `<html><body></body></html>`,
+		fmt.Sprintf(pagePath, "4.17"): `<html><body></body></html>`,
+	}
+	urlAt := func(path, version, anchor string) string {
+		return "https://docs.redhat.com" + fmt.Sprintf(path, version) + "#" + anchor
+	}
+
+	tests := []struct {
+		name   string
+		url    string
+		budget time.Duration
+		// want is the suggested version, empty when the URL is up to date
+		want string
+		// wantIndeterminate is whether 4.17 was left indeterminate
+		wantIndeterminate bool
+	}{
+		{name: "anchor read within the budget", url: urlAt(singlePath, "4.15", "ingress-overview"), budget: 50 * time.Millisecond, want: "4.17"},
+		{name: "anchor past the budget", url: urlAt(singlePath, "4.15", "ingress-sharding"), budget: 50 * time.Millisecond, want: "4.16", wantIndeterminate: true},
+		{name: "missing from a page read in full", url: urlAt(singlePath, "4.15", "ingress-removed"), budget: time.Hour},
+		{name: "no budget", url: urlAt(singlePath, "4.15", "ingress-sharding"), want: "4.17"},
+		{name: "html-single fallback past the budget", url: urlAt(pagePath, "4.15", "ingress-sharding"), budget: 50 * time.Millisecond, want: "4.16", wantIndeterminate: true},
+		{name: "html-single fallback without a budget", url: urlAt(pagePath, "4.15", "ingress-sharding"), want: "4.17"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeDocsChecker(t, pages)
+			c.SetVersions([]string{"4.15", "4.16", "4.17"})
+			c.now = tickingClock(time.Millisecond)
+			c.SetMaxPageParseTime(tt.budget)
+
+			result, err := c.Check(tt.url)
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			best, ok := result.BestSuggestion()
+			if ok != (tt.want != "") || best.Version != tt.want {
+				t.Errorf("BestSuggestion() = %s, %v; want %q", best.Version, ok, tt.want)
+			}
+
+			indeterminate := result.Indeterminate()
+			if (len(indeterminate) > 0) != tt.wantIndeterminate {
+				t.Fatalf("Indeterminate() = %+v, want 4.17 = %v", indeterminate, tt.wantIndeterminate)
+			}
+			if slices.Contains(result.AppliedHeuristics, HeuristicParseBudget) != tt.wantIndeterminate {
+				t.Errorf("AppliedHeuristics = %v, want %s applied = %v", result.AppliedHeuristics, HeuristicParseBudget, tt.wantIndeterminate)
+			}
+			if !tt.wantIndeterminate {
+				return
+			}
+			v := indeterminate[0]
+			if v.Version != "4.17" || v.AnchorExists || !v.Exists {
+				t.Errorf("indeterminate version = %+v, want 4.17 with its page", v)
+			}
+			if v.ScannedBytes <= 0 || v.ScannedBytes >= int64(len(guide)) {
+				t.Errorf("ScannedBytes = %d, want part of the %d bytes of the page", v.ScannedBytes, len(guide))
+			}
+		})
+	}
+}
+
+// A page cut short by its budget is exported without anchors, so a later
+// run importing it requests it again instead of finding anchors missing
+func TestExportMatrix_TruncatedPage(t *testing.T) {
+	const singlePath = "/en/documentation/openshift_container_platform/4.17/html-single/networking/index"
+	c := newFakeDocsChecker(t, map[string]string{singlePath: hugeGuide("ingress-overview", "ingress-sharding")})
+	c.now = tickingClock(time.Millisecond)
+	c.SetMaxPageParseTime(50 * time.Millisecond)
+
+	facts, err := c.CheckURLOnce(t.Context(), "https://docs.redhat.com"+singlePath+"#ingress-sharding")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !facts.Truncated || !facts.Fetched || !facts.HasAnchor("ingress-overview") || facts.HasAnchor("ingress-sharding") {
+		t.Fatalf("facts = Truncated %v, Fetched %v, %d anchors; want the first anchors of a truncated page", facts.Truncated, facts.Fetched, len(facts.AnchorIDs))
+	}
+	c.recordPage(facts)
+
+	var buf bytes.Buffer
+	if err := c.ExportMatrix(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var m Matrix
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Pages) != 1 || m.Pages[0].Fetched || m.Pages[0].AnchorIDs != nil || !m.Pages[0].Exists {
+		t.Errorf("exported pages = %+v, want one existing page without anchors", m.Pages)
+	}
+}
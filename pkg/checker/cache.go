@@ -0,0 +1,83 @@
+package checker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// diskCache keeps the facts of every requested page on disk, one file per
+// page URL, so repeated runs on one machine do not request pages checked
+// recently again. An entry is a MatrixPage; entries that cannot be read,
+// belong to another URL or are older than ttl are misses, and the page is
+// requested and stored again.
+type diskCache struct {
+	dir string
+	ttl time.Duration
+}
+
+// SetCache keeps page facts in dir across runs, and answers lookups from
+// entries checked less than ttl ago instead of requesting the page. The
+// directory is created when missing. An empty dir disables the cache.
+func (c *Checker) SetCache(dir string, ttl time.Duration) error {
+	if dir == "" {
+		c.cache = nil
+		return nil
+	}
+	if ttl <= 0 {
+		return errors.New("cache TTL must be positive")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	c.cache = &diskCache{dir: dir, ttl: ttl}
+	return nil
+}
+
+// path returns the file of the entry for url
+func (d *diskCache) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(d.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// load returns the facts of the page at url, if a fresh entry is stored
+func (d *diskCache) load(url string, now time.Time) (*PageFacts, bool) {
+	data, err := os.ReadFile(d.path(url))
+	if err != nil {
+		return nil, false
+	}
+	var page MatrixPage
+	if json.Unmarshal(data, &page) != nil || page.URL != url || now.Sub(page.CheckedAt) > d.ttl {
+		return nil, false
+	}
+	return page.facts(), true
+}
+
+// store writes the facts of a page, replacing its entry atomically so that
+// concurrent runs never read a partial one
+func (d *diskCache) store(facts *PageFacts) error {
+	data, err := json.Marshal(newMatrixPage(facts))
+	if err != nil {
+		return err
+	}
+
+	path := d.path(facts.URL)
+	tmp, err := os.CreateTemp(d.dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
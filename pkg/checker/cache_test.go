@@ -0,0 +1,118 @@
+package checker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newCachedChecker returns a matrix checker keeping page facts in dir
+func newCachedChecker(t *testing.T, dir string, ttl time.Duration) *Checker {
+	t.Helper()
+	c := newMatrixChecker(t)
+	if err := c.SetCache(dir, ttl); err != nil {
+		t.Fatalf("SetCache() error = %v", err)
+	}
+	return c
+}
+
+func TestCache_SecondRunSkipsRequests(t *testing.T) {
+	dir := t.TempDir()
+
+	first := newCachedChecker(t, dir, time.Hour)
+	want := checkAll(t, first, matrixURLs)
+	if got := first.Requests(); got != 8 {
+		t.Errorf("first run Requests() = %d, want 8", got)
+	}
+
+	second := newCachedChecker(t, dir, time.Hour)
+	if got := checkAll(t, second, matrixURLs); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("second run latest versions = %v, want %v", got, want)
+	}
+	if got := second.Requests(); got != 0 {
+		t.Errorf("second run Requests() = %d, want 0", got)
+	}
+	// Each page is read from disk once, then answered from memory
+	if stats := second.Stats(); stats.DiskReads != 8 || stats.CacheHits != stats.PageLookups {
+		t.Errorf("second run Stats() = %+v, want 8 disk reads and every lookup answered", stats)
+	}
+
+	uncached := newMatrixChecker(t)
+	checkAll(t, uncached, matrixURLs)
+	if got := uncached.Requests(); got != 8 {
+		t.Errorf("run without cache Requests() = %d, want 8", got)
+	}
+}
+
+func TestCache_RefetchesUnusableEntries(t *testing.T) {
+	tests := []struct {
+		name  string
+		ttl   time.Duration
+		spoil func(t *testing.T, dir string)
+	}{
+		{"expired", time.Nanosecond, func(t *testing.T, dir string) {
+			time.Sleep(time.Millisecond)
+		}},
+		{"corrupt", time.Hour, func(t *testing.T, dir string) {
+			entries, err := filepath.Glob(filepath.Join(dir, "*.json"))
+			if err != nil || len(entries) == 0 {
+				t.Fatalf("no cache entries written (err %v)", err)
+			}
+			for _, e := range entries {
+				if err := os.WriteFile(e, []byte(`{"url": `), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			checkAll(t, newCachedChecker(t, dir, tt.ttl), matrixURLs)
+			tt.spoil(t, dir)
+
+			second := newCachedChecker(t, dir, tt.ttl)
+			checkAll(t, second, matrixURLs)
+			if got := second.Requests(); got != 8 {
+				t.Errorf("Requests() = %d, want every page requested again", got)
+			}
+
+			// The refetched facts replace the unusable entries
+			third := newCachedChecker(t, dir, time.Hour)
+			checkAll(t, third, matrixURLs)
+			if got := third.Requests(); got != 0 {
+				t.Errorf("Requests() after refetching = %d, want 0", got)
+			}
+		})
+	}
+}
+
+func TestCache_HeadOnlyEntriesDoNotAnswerAnchors(t *testing.T) {
+	dir := t.TempDir()
+	checkAll(t, newCachedChecker(t, dir, time.Hour), matrixURLs[2:]) // HEAD requests only
+
+	second := newCachedChecker(t, dir, time.Hour)
+	second.SetFormatFallback(false)
+	checkAll(t, second, []string{matrixURLs[2] + "#dns-operator"})
+	// The existing dns pages are fetched for their anchors, the missing
+	// ones are known not to exist
+	if got := second.Requests(); got != 2 {
+		t.Errorf("Requests() = %d, want 2", got)
+	}
+}
+
+func TestSetCache_Invalid(t *testing.T) {
+	if err := NewChecker().SetCache(t.TempDir(), 0); err == nil {
+		t.Error("SetCache() with a zero TTL error = nil, want an error")
+	}
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewChecker().SetCache(file, time.Hour); err == nil {
+		t.Error("SetCache() on a file error = nil, want an error")
+	}
+}
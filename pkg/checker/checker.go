@@ -1,15 +1,23 @@
 package checker
 
 import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"errors"
 	"fmt"
-	"io"
+	"log/slog"
+	"net"
 	"net/http"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/sebrandon1/ocp-doc-checker/pkg/anchors"
 	"github.com/sebrandon1/ocp-doc-checker/pkg/parser"
-	"golang.org/x/net/html"
+	"golang.org/x/time/rate"
 )
 
 // VersionCheckResult represents the result of checking a version
@@ -21,6 +29,90 @@ type VersionCheckResult struct {
 	HasAnchor    bool // true if URL contains a fragment/anchor
 	Error        error
 	CheckedAt    time.Time
+
+	// RenamedFrom is the original page slug when the page was only found
+	// under a renamed slug from the slug map
+	RenamedFrom string
+	// MovedFrom is the original page slug when the anchor was missing from
+	// it and found on another page of the guide by the sibling search; URL
+	// is then that page, with the anchor as the page spells it
+	MovedFrom string
+	// Cached is set when the page facts came from an imported matrix
+	// instead of a request made by this run
+	Cached bool
+	// ServedDocument is the document slug the page was served from when a
+	// redirect moved it to another guide, e.g. after guides were merged.
+	// ServedTitle is the guide title of that page, when it was fetched.
+	ServedDocument string
+	ServedTitle    string
+	// RedirectedTo is the URL the page redirected to when it names another
+	// page, another guide or no documentation page at all, e.g. the
+	// product landing page. Exists is false for such a page unless
+	// SetAcceptRedirects accepts redirects.
+	RedirectedTo string
+	// NotFoundMarker is the not-found message of SetSoft404Markers that
+	// a page answered with 2xx shows; Exists is false for it
+	NotFoundMarker string
+	// DeprecationMarker is the marker of SetDeprecationMarkers the page
+	// shows: the version is past its end of life
+	DeprecationMarker string
+	// AnchorText is the text of the heading the anchor names, e.g.
+	// "Mirroring an image set in full" for the id of its <section>, when it
+	// exists and names one. Comparing it across versions tells a moved
+	// section from an id reused for another one.
+	AnchorText string
+	// AnchorVia is set when the anchor was not found on the page itself but
+	// verified elsewhere: AnchorViaSingle for the html-single variant
+	AnchorVia string
+	// SuggestedAnchors are the ids of the page most like the anchor, best
+	// first, when the page exists but lacks it, e.g. the anchor with the
+	// assembly context a new release appended. They are only hints: the
+	// version still counts as missing the anchor, and fixes never use them.
+	SuggestedAnchors []string
+	// AnchorIndeterminate is set when the anchor was not among those read
+	// before the parse budget of SetMaxPageParseTime ran out, so it may
+	// still exist; AnchorExists is false. ScannedBytes is how much of the
+	// page was read.
+	AnchorIndeterminate bool
+	ScannedBytes        int64
+	// Method is the HTTP method of the request that answered for the page,
+	// e.g. GET when the server refused HEAD
+	Method string
+	// Attempts is the number of requests the page took, retries included;
+	// 0 when its facts were imported
+	Attempts int
+	// StatusCode is the HTTP status that answered for the page, 0 when
+	// there was no answer
+	StatusCode int
+	// Duration is how long requesting the page took, retries and their
+	// backoff included; 0 when its facts were imported
+	Duration time.Duration
+	// Network is the address family of the connection that answered for
+	// the page, empty when unknown
+	Network Network
+	// NotChecked is set for a newer version that was never requested,
+	// with the reason in SkippedReason, e.g. "above the maximum version
+	// 4.16"; such versions are only listed with SetRecordSkippedVersions
+	NotChecked    bool
+	SkippedReason string
+
+	// heuristics are the heuristics applied to check the version
+	heuristics []Heuristic
+}
+
+// maxSuggestedAnchors is how many ids VersionCheckResult.SuggestedAnchors
+// holds at most
+const maxSuggestedAnchors = 3
+
+// AnchorViaSingle marks an anchor of a multi-page html URL that is missing
+// from the page's server-side HTML, because the page renders it with
+// JavaScript, but present in the html-single variant of the guide
+const AnchorViaSingle = "html-single"
+
+// CrossDocument reports whether the version is served from another guide
+// than the one the URL names
+func (v VersionCheckResult) CrossDocument() bool {
+	return v.ServedDocument != ""
 }
 
 // CheckResult represents the complete check result
@@ -31,208 +123,838 @@ type CheckResult struct {
 	IsOutdated      bool
 	NewerVersions   []VersionCheckResult
 	AllResults      []VersionCheckResult
+
+	// OriginalExists is set when the page of the original URL exists at
+	// its own version, and OriginalAnchorExists when the page also has the
+	// URL's anchor, never for a URL without one. Broken is set when the
+	// page or the anchor is missing: the link is dead, outdated or not.
+	// None is set when the original could not be verified, e.g. its
+	// request failed or its page was too large to read for the anchor, or
+	// when SetOriginalCheck disabled the check.
+	OriginalExists       bool
+	OriginalAnchorExists bool
+	Broken               bool
+	// OriginalRedirectedTo is the page the original URL redirects to at
+	// its own version when it is another page, see RedirectedTo. Without
+	// SetAcceptRedirects such a URL is Broken.
+	OriginalRedirectedTo string
+	// OriginalDeprecated is set when the page of the original URL shows
+	// the banner of a release that is no longer maintained, see
+	// SetDeprecationMarkers: the link is worth updating first, even when
+	// no newer version has its anchor
+	OriginalDeprecated bool
+
+	// FragmentIssue reports a fragment that was normalized or is malformed.
+	// Malformed fragments are never checked.
+	FragmentIssue parser.FragmentIssue
+	// VersionIssue reports a version segment that was not written as
+	// major.minor; OriginalVersion is the canonical spelling
+	VersionIssue parser.VersionIssue
+	// SuggestedURL is the normalized spelling of the original URL when its
+	// fragment was duplicated or its version is non-canonical
+	SuggestedURL string
+	// ExcludedVersions are newer versions where the page and anchor exist,
+	// or the page under AnchorPolicyPageOnly, but that are not allowed as
+	// upgrade targets
+	ExcludedVersions []VersionCheckResult
+	// DocumentTitle is the title of the guide, e.g. "Networking", when a
+	// page of it was fetched
+	DocumentTitle string
+	// Notes are remarks on the verdict, e.g. a version above the range of
+	// SetVersionRange, and those attached by result hooks, including their
+	// errors
+	Notes []string
+	// LowConfidence is set when a result hook failed, so the verdict may
+	// not reflect every rule the hooks apply
+	LowConfidence bool
+	// AsOf is the version set with SetAsOf that capped the versions
+	// checked, empty when there was no cap
+	AsOf string
+	// AnchorPolicy is the policy of SetAnchorPolicy the verdict was reached
+	// under. With AnchorPolicyPageOnly, NewerVersions may include versions
+	// whose page lacks the anchor.
+	AnchorPolicy AnchorPolicy
+	// AppliedHeuristics are the heuristics that altered or could have
+	// altered the verdict, in order of first use, to tell why a verdict
+	// was reached
+	AppliedHeuristics []Heuristic
+}
+
+// BestSuggestion returns the version an outdated URL should move to: the
+// newer version whose page and anchor exist, or whose page exists under
+// AnchorPolicyPageOnly, and that the target policy allows, ranked first by
+// CompareCandidates. Every output and -fix use it, so they never disagree.
+func (r *CheckResult) BestSuggestion() (VersionCheckResult, bool) {
+	best, _ := rankCandidates(r.NewerVersions)
+	if best < 0 {
+		return VersionCheckResult{}, false
+	}
+	return r.NewerVersions[best], true
+}
+
+// RunnerUp returns the candidate ranked second after BestSuggestion and the
+// reason of the selection rule that put the best suggestion ahead of it
+func (r *CheckResult) RunnerUp() (VersionCheckResult, string, bool) {
+	best, runnerUp := rankCandidates(r.NewerVersions)
+	if runnerUp < 0 {
+		return VersionCheckResult{}, "", false
+	}
+	_, reason := CompareCandidates(r.NewerVersions[best], r.NewerVersions[runnerUp])
+	return r.NewerVersions[runnerUp], reason, true
+}
+
+// NewestExcluded returns the newest excluded version if it is newer than
+// LatestVersion, i.e. when the target policy hides a newer working release
+func (r *CheckResult) NewestExcluded() (VersionCheckResult, bool) {
+	if len(r.ExcludedVersions) == 0 {
+		return VersionCheckResult{}, false
+	}
+
+	newest := r.ExcludedVersions[len(r.ExcludedVersions)-1]
+	newestDoc := &parser.OCPDocURL{Version: newest.Version}
+	latestDoc := &parser.OCPDocURL{Version: r.LatestVersion}
+	if parseVersionInPlace(newestDoc) != nil || parseVersionInPlace(latestDoc) != nil {
+		return VersionCheckResult{}, false
+	}
+	if newestDoc.GetVersionFloat() <= latestDoc.GetVersionFloat() {
+		return VersionCheckResult{}, false
+	}
+	return newest, true
+}
+
+// ErrHostNotAllowed is returned when a request or redirect targets a host
+// outside the checker's egress policy
+var ErrHostNotAllowed = errors.New("host not allowed by egress policy")
+
+// DefaultAllowedHost is the only host the checker talks to unless more are allowed
+const DefaultAllowedHost = "docs.redhat.com"
+
+// UserAgent returns the User-Agent the checker identifies itself with by
+// default, for the given release of the tool
+func UserAgent(version string) string {
+	return "ocp-doc-checker/" + version + " (+https://github.com/sebrandon1/ocp-doc-checker)"
 }
 
 // Checker handles checking OCP documentation URLs
 type Checker struct {
 	client        *http.Client
 	knownVersions []string
+	// discovered is set once DiscoverVersions replaced knownVersions with
+	// the versions published on the product landing page
+	discovered bool
+	discoverMu sync.Mutex
+	// priority is the order CheckAll starts URLs in
+	priority Priority
+	// retry is how transient failures are retried
+	retry RetryPolicy
+	// asOf caps the known versions, empty for no cap
+	asOf string
+	// userAgent is sent with every request
+	userAgent string
+	// slots bounds the version checks in flight across every Check call to
+	// maxConcurrent
 	maxConcurrent int
+	slots         chan struct{}
+	// limiter paces every request sent, across every Check call; nil sends
+	// requests as fast as the slots allow
+	limiter      *rate.Limiter
+	allowedHosts map[string]bool
+	slugMap      *SlugMap
+	// allowedTargets restricts the versions that count as upgrade targets;
+	// nil allows every known version
+	allowedTargets map[string]bool
+	// maxParseTime is the parse budget of a page, 0 for none; now is the
+	// clock it is measured with
+	maxParseTime time.Duration
+	now          func() time.Time
+	// minVersion and maxVersion bound the newer versions checked, set with
+	// SetVersionRange; empty for no bound
+	minVersion, maxVersion string
+	// excludedVersions are never checked, set with ExcludeVersions
+	excludedVersions map[string]bool
+	// recordSkipped lists the versions outside the range in AllResults,
+	// set with SetRecordSkippedVersions
+	recordSkipped bool
+	// requests counts the HTTP requests sent, including retries and redirects
+	requests atomic.Int64
+	// titles caches guide titles by (document, version)
+	titles   map[[2]string]string
+	titlesMu sync.Mutex
+	// pins are the accepted SPKI SHA-256 fingerprints; nil disables pinning
+	pins        map[[sha256.Size]byte]bool
+	pinAllHosts bool
+	// tlsConfig holds the TLS settings of SetTLSConfig; nil for the defaults
+	tlsConfig *tls.Config
+	// network is the address family of SetNetwork; dialer dials the
+	// connections of the default transport, whose TLS handshakes take at
+	// most connectTimeout. customTransport is set while a transport of
+	// SetTransport, which dials its own connections, is in use.
+	network         Network
+	dialer          *net.Dialer
+	connectTimeout  time.Duration
+	customTransport bool
+	// aliases are the user-defined version aliases by lowercase name, and
+	// resolvedAliases the concrete versions aliases resolved to
+	aliases         map[string]string
+	resolvedAliases map[string]string
+	// hooks adjust every result before Check returns it
+	hooks   []ResultHook
+	hooksMu sync.Mutex
+	// heuristicCounts counts the results each heuristic was applied to
+	heuristicCounts map[Heuristic]int64
+	heuristicsMu    sync.Mutex
+	// logger receives the checker's logs; it discards them by default
+	logger *slog.Logger
+	// onProgress is called as version checks complete, one call at a time
+	onProgress func(ProgressEvent)
+	progressMu sync.Mutex
+	// pages caches the facts of every page requested or imported, by URL
+	// without fragment; refreshImported ignores imported facts on lookup
+	pages   map[string]*PageFacts
+	pagesMu sync.Mutex
+	// inflight are the pages being requested, by URL without fragment,
+	// each with a channel closed once the request is over; guarded by
+	// pagesMu
+	inflight        map[string]chan struct{}
+	refreshImported bool
+	// lru bounds the anchors kept in pages; nil for no limit
+	lru *pageLRU
+	// noFormatFallback disables verifying anchors missing from an html
+	// page on the html-single variant
+	noFormatFallback bool
+	// noOriginalCheck disables requesting the original URL itself
+	noOriginalCheck bool
+	// acceptRedirects counts pages redirecting elsewhere as existing
+	acceptRedirects bool
+	// anchorPolicy decides whether pages lacking the anchor count as newer
+	anchorPolicy AnchorPolicy
+	// deprecationMarkers are the normalized markers of
+	// SetDeprecationMarkers; nil disables the detection
+	deprecationMarkers []string
+	// soft404Markers are the normalized messages of SetSoft404Markers; nil
+	// disables soft-404 detection
+	soft404Markers []string
+	// siblingProbes is how many other pages of a guide a sibling search
+	// probes, 0 to disable it; tocs caches the tables of contents read,
+	// by (document, version), and tocsInflight are those being requested
+	siblingProbes int
+	tocs          map[[2]string][]tocEntry
+	tocsInflight  map[[2]string]chan struct{}
+	tocsMu        sync.Mutex
+	// siblingSearches counts the sibling searches made and siblingRequests
+	// the requests they sent
+	siblingSearches atomic.Int64
+	siblingRequests atomic.Int64
+	// cache keeps page facts on disk across runs; nil disables it
+	cache *diskCache
+	// pageLookups counts the page facts needed, pageHits those already
+	// known, importedHits those known from an earlier run and diskReads
+	// those read from the disk cache
+	pageLookups  atomic.Int64
+	pageHits     atomic.Int64
+	importedHits atomic.Int64
+	diskReads    atomic.Int64
+	// pageEvictions counts the pages whose anchors were dropped by lru
+	pageEvictions atomic.Int64
 }
 
 // NewChecker creates a new Checker instance
 func NewChecker() *Checker {
-	return &Checker{
-		client: &http.Client{
-			Timeout: 30 * time.Second, // Increased timeout for CI environments
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				// Follow redirects
-				return nil
-			},
-		},
+	c := &Checker{
 		// Known OCP versions to check (can be expanded)
 		knownVersions: []string{
 			"4.10", "4.11", "4.12", "4.13", "4.14",
 			"4.15", "4.16", "4.17", "4.18", "4.19",
 			"4.20",
 		},
-		maxConcurrent: 5,
+		maxConcurrent:  5,
+		retry:          DefaultRetryPolicy,
+		userAgent:      UserAgent("dev"),
+		logger:         slog.New(slog.DiscardHandler),
+		maxParseTime:   DefaultMaxPageParseTime,
+		now:            time.Now,
+		network:        NetworkAuto,
+		anchorPolicy:   AnchorPolicyStrict,
+		dialer:         &net.Dialer{Timeout: defaultConnectTimeout, KeepAlive: 30 * time.Second},
+		connectTimeout: defaultConnectTimeout,
+		slots:          make(chan struct{}, 5),
+		allowedHosts:   map[string]bool{DefaultAllowedHost: true},
+		slugMap:        DefaultSlugMap(),
+		titles:         make(map[[2]string]string),
+		pages:          make(map[string]*PageFacts),
+		inflight:       make(map[string]chan struct{}),
+	}
+	c.SetSoft404Markers(DefaultSoft404Markers)
+	c.SetDeprecationMarkers(DefaultDeprecationMarkers)
+
+	c.client = &http.Client{
+		Timeout:   30 * time.Second, // Increased timeout for CI environments
+		Transport: &egressTransport{base: newTransport(c), checker: c},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			// Follow redirects, but only to allowed hosts
+			if !c.hostAllowed(req.URL.Hostname()) {
+				return fmt.Errorf("redirect to %s refused: %w", req.URL.Redacted(), ErrHostNotAllowed)
+			}
+			return nil
+		},
 	}
+
+	return c
 }
 
-// SetVersions allows setting custom versions to check
+// SetVersions allows setting custom versions to check; versions newer than
+// the as-of version are dropped
 func (c *Checker) SetVersions(versions []string) {
-	c.knownVersions = versions
+	c.knownVersions = c.capVersions(versions)
+}
+
+// SetMaxConcurrent sets how many versions are checked at once, across every
+// Check call of the checker; values below 1 check one version at a time.
+// Call it before checking URLs.
+func (c *Checker) SetMaxConcurrent(n int) {
+	if n < 1 {
+		n = 1
+	}
+	c.maxConcurrent = n
+	c.slots = make(chan struct{}, n)
+}
+
+// SetRateLimit paces the requests of the checker, including retries and
+// followed redirects, to rps per second on average with bursts of up to
+// burst requests, shared by every concurrent check. A request waiting for
+// its turn gives up when its context is done. An rps of zero or less
+// removes the limit. Call it before checking URLs.
+func (c *Checker) SetRateLimit(rps float64, burst int) {
+	if rps <= 0 {
+		c.limiter = nil
+		return
+	}
+	c.limiter = rate.NewLimiter(rate.Limit(rps), max(burst, 1))
+}
+
+// SetUserAgent sets the User-Agent sent with every request; the default is
+// UserAgent("dev"), and an empty ua restores it
+func (c *Checker) SetUserAgent(ua string) {
+	if ua == "" {
+		ua = UserAgent("dev")
+	}
+	c.userAgent = ua
+}
+
+// SetTransport sends the checker's requests through rt instead of the
+// default transport, e.g. to add the credentials of an egress proxy, or to
+// answer them from canned responses in tests. The egress policy, rate limit
+// and request count still apply, and redirects are still followed.
+// Certificate pins and the address family of SetNetwork are applied by the
+// default transport only, so they do not apply to rt. A nil rt restores the
+// default transport.
+func (c *Checker) SetTransport(rt http.RoundTripper) {
+	c.customTransport = rt != nil
+	if rt == nil {
+		rt = newTransport(c)
+	}
+	c.client.Transport = &egressTransport{base: rt, checker: c}
+}
+
+// SetFormatFallback sets whether an anchor missing from a multi-page html
+// page is looked up in the html-single variant of the guide at the same
+// version, and counted as existing when found there. It is on by default.
+func (c *Checker) SetFormatFallback(enabled bool) {
+	c.noFormatFallback = !enabled
+}
+
+// SetAllowedTargets restricts which versions count as upgrade targets.
+// Every newer version is still checked, but only allowed versions end up in
+// NewerVersions and LatestVersion; the rest are reported in
+// ExcludedVersions. The keyword "eus" expands to the even-minor (Extended
+// Update Support) releases among the known versions; other entries are
+// versions or aliases resolved with ResolveVersion. An empty list allows
+// every version again.
+func (c *Checker) SetAllowedTargets(versions []string) error {
+	if len(versions) == 0 {
+		c.allowedTargets = nil
+		return nil
+	}
+
+	allowed := make(map[string]bool)
+	for _, v := range versions {
+		if strings.EqualFold(v, "eus") {
+			for _, eus := range EUSVersions(c.knownVersions) {
+				allowed[eus] = true
+			}
+			continue
+		}
+
+		resolved, err := c.ResolveVersion(v)
+		if err != nil {
+			return fmt.Errorf("invalid target version: %w", err)
+		}
+		if c.asOf != "" && versionAfter(resolved, c.asOf) {
+			return fmt.Errorf("target version %s is newer than the as-of version %s", resolved, c.asOf)
+		}
+		allowed[resolved] = true
+	}
+
+	c.allowedTargets = allowed
+	return nil
+}
+
+// EUSVersions returns the Extended Update Support releases, the even-minor
+// versions, in versions
+func EUSVersions(versions []string) []string {
+	var eus []string
+	for _, v := range versions {
+		doc := &parser.OCPDocURL{Version: v}
+		if err := parseVersionInPlace(doc); err == nil && doc.MajorMinor[1]%2 == 0 {
+			eus = append(eus, v)
+		}
+	}
+	return eus
+}
+
+// SetSlugMap replaces the page slug renames consulted when a candidate page
+// is missing. A nil map disables the fallback.
+func (c *Checker) SetSlugMap(m *SlugMap) {
+	c.slugMap = m
+}
+
+// AllowHost adds a host to the egress allowlist. Requests and redirects to
+// any host that is not allowed fail with ErrHostNotAllowed.
+func (c *Checker) AllowHost(host string) {
+	c.allowedHosts[strings.ToLower(host)] = true
+}
+
+// hostAllowed reports whether the egress policy permits requests to host
+func (c *Checker) hostAllowed(host string) bool {
+	return c.allowedHosts[strings.ToLower(host)]
+}
+
+// Requests returns the number of HTTP requests sent so far, including
+// retries and followed redirects. Requests refused by the egress policy are
+// not counted.
+func (c *Checker) Requests() int64 {
+	return c.requests.Load()
 }
 
-// Check performs the URL check
+// Stats counts how the checker answered the page lookups of its checks
+type Stats struct {
+	// PageLookups is the number of times the facts of a page were needed:
+	// one per version checked, plus one per renamed slug tried
+	PageLookups int64
+	// CacheHits were answered from facts already known, without a request.
+	// ImportedHits of them came from an earlier run, through an imported
+	// matrix or the disk cache, and DiskReads were read from the disk cache.
+	CacheHits    int64
+	ImportedHits int64
+	DiskReads    int64
+	// Requests is the number of HTTP requests sent, as returned by Requests
+	Requests int64
+	// PageEvictions counts the pages whose anchors were dropped to stay
+	// within SetPageCacheLimit
+	PageEvictions int64
+	// Heuristics counts the results each heuristic was applied to
+	Heuristics map[Heuristic]int64
+	// SiblingSearches is the number of anchors looked for on other pages
+	// of their guide, see SetSiblingSearch, and SiblingRequests the
+	// requests those searches sent, included in Requests
+	SiblingSearches int64
+	SiblingRequests int64
+}
+
+// Stats returns the page lookup counters of the checker so far
+func (c *Checker) Stats() Stats {
+	return Stats{
+		PageLookups:  c.pageLookups.Load(),
+		CacheHits:    c.pageHits.Load(),
+		ImportedHits: c.importedHits.Load(),
+		DiskReads:    c.diskReads.Load(),
+		Requests:     c.requests.Load(),
+
+		PageEvictions:   c.pageEvictions.Load(),
+		Heuristics:      c.heuristicStats(),
+		SiblingSearches: c.siblingSearches.Load(),
+		SiblingRequests: c.siblingRequests.Load(),
+	}
+}
+
+// egressTransport enforces the checker's egress policy on every request,
+// including ones issued while following redirects
+type egressTransport struct {
+	base    http.RoundTripper
+	checker *Checker
+}
+
+// RoundTrip rejects requests to hosts outside the allowlist and waits for
+// the rate limit before sending the others
+func (t *egressTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.checker.hostAllowed(req.URL.Hostname()) {
+		return nil, fmt.Errorf("request to %s refused: %w", req.URL.Hostname(), ErrHostNotAllowed)
+	}
+	if l := t.checker.limiter; l != nil {
+		if err := l.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+	t.checker.requests.Add(1)
+	if req.Context().Value(siblingRequestKey{}) != nil {
+		t.checker.siblingRequests.Add(1)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// Check performs the URL check and runs the registered result hooks on
+// its verdict
 func (c *Checker) Check(rawURL string) (*CheckResult, error) {
+	return c.CheckContext(context.Background(), rawURL)
+}
+
+// CheckContext is Check, giving up once ctx is done: requests in flight are
+// canceled and the error of ctx is returned rather than a verdict from the
+// versions answered so far
+func (c *Checker) CheckContext(ctx context.Context, rawURL string) (*CheckResult, error) {
+	return c.checkAt(ctx, rawURL, batchPosition{total: 1})
+}
+
+// checkAt is CheckContext for a URL at pos of a CheckAll call
+func (c *Checker) checkAt(ctx context.Context, rawURL string, pos batchPosition) (*CheckResult, error) {
+	result, err := c.check(ctx, rawURL, pos)
+	if err != nil {
+		return nil, err
+	}
+	c.runHooks(result)
+	c.countHeuristics(result)
+	return result, nil
+}
+
+// check assembles the verdict for a URL
+func (c *Checker) check(ctx context.Context, rawURL string, pos batchPosition) (*CheckResult, error) {
 	// Parse the URL
 	docURL, err := parser.ParseOCPDocURL(rawURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse URL: %w", err)
+		return nil, err
 	}
 
 	result := &CheckResult{
 		OriginalURL:     rawURL,
 		OriginalVersion: docURL.Version,
 		AllResults:      []VersionCheckResult{},
+		FragmentIssue:   docURL.FragmentIssue,
+		VersionIssue:    docURL.VersionIssue,
+		AsOf:            c.asOf,
+		AnchorPolicy:    c.anchorPolicy,
+	}
+
+	if docURL.FragmentIssue == parser.FragmentMalformed {
+		// The anchor can never match an id, so probing newer versions is pointless
+		result.LatestVersion = docURL.Version
+		return result, nil
+	}
+	if docURL.FragmentIssue == parser.FragmentDuplicated {
+		applyHeuristic(&result.AppliedHeuristics, HeuristicFragmentDedup)
+	}
+	if docURL.VersionIssue == parser.VersionNonCanonical {
+		applyHeuristic(&result.AppliedHeuristics, HeuristicVersionCanonical)
+	}
+	if docURL.FragmentIssue == parser.FragmentDuplicated || docURL.VersionIssue == parser.VersionNonCanonical {
+		result.SuggestedURL = docURL.BuildURL(docURL.Version)
+	}
+	if c.asOf != "" {
+		applyHeuristic(&result.AppliedHeuristics, HeuristicAsOf)
+	}
+	if c.minVersion != "" || c.maxVersion != "" {
+		applyHeuristic(&result.AppliedHeuristics, HeuristicVersionRange)
+	}
+	if c.excludedVersions != nil {
+		applyHeuristic(&result.AppliedHeuristics, HeuristicExcludedVersions)
+	}
+	original := c.startOriginal(ctx, docURL)
+	if c.maxVersion != "" && versionAfter(docURL.Version, c.maxVersion) {
+		// Policy keeps the docs at or below the range, not above it
+		result.LatestVersion = docURL.Version
+		result.Notes = append(result.Notes, fmt.Sprintf("version %s is above the maximum version %s, so newer versions were not checked", docURL.Version, c.maxVersion))
+		awaitOriginal(result, original)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result.AllResults = c.withSkippedVersions(docURL, result.AllResults)
+		return result, nil
 	}
 
 	// Filter versions to check (only those newer than current)
 	versionsToCheck := c.getNewerVersions(docURL.Version)
 
-	// Check each version
-	for _, version := range versionsToCheck {
-		checkURL := docURL.BuildURL(version)
-		exists, anchorExists, hasAnchor, err := c.checkURL(checkURL)
-
-		versionResult := VersionCheckResult{
-			Version:      version,
-			URL:          checkURL,
-			Exists:       exists,
-			AnchorExists: anchorExists,
-			HasAnchor:    hasAnchor,
-			Error:        err,
-			CheckedAt:    time.Now(),
-		}
+	// Check each version; results keep the sorted version order
+	report := c.progressReporter(rawURL, pos)
+	for i, versionResult := range c.checkVersions(ctx, docURL, versionsToCheck, report) {
+		version := versionsToCheck[i]
 
 		result.AllResults = append(result.AllResults, versionResult)
+		for _, h := range versionResult.heuristics {
+			applyHeuristic(&result.AppliedHeuristics, h)
+		}
 
-		// Only consider it a valid newer version if both page and anchor (if present) exist
-		if exists && (!hasAnchor || anchorExists) {
+		// Only consider it a valid newer version if both page and anchor (if
+		// present) exist, or the page alone under the page-only policy
+		if c.countsAsNewer(versionResult) {
+			if versionResult.AnchorMissing() {
+				applyHeuristic(&result.AppliedHeuristics, HeuristicPageOnlyAnchor)
+			}
+			if c.allowedTargets != nil && !c.allowedTargets[version] {
+				applyHeuristic(&result.AppliedHeuristics, HeuristicTargetPolicy)
+				result.ExcludedVersions = append(result.ExcludedVersions, versionResult)
+				continue
+			}
 			result.NewerVersions = append(result.NewerVersions, versionResult)
 		}
 	}
 
+	awaitOriginal(result, original)
+
+	// Versions cut short would make the verdict a guess
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	// Without a single answer, "up to date" would be a guess
+	if err := allFailed(result.AllResults); err != nil {
+		return nil, err
+	}
+	result.AllResults = c.withSkippedVersions(docURL, result.AllResults)
+
 	// Determine if outdated and latest version
-	if len(result.NewerVersions) > 0 {
+	if best, ok := result.BestSuggestion(); ok {
 		result.IsOutdated = true
-		// Latest version is the last one in the list (they're sorted)
-		result.LatestVersion = result.NewerVersions[len(result.NewerVersions)-1].Version
+		result.LatestVersion = best.Version
 	} else {
 		result.LatestVersion = docURL.Version
 	}
+	result.DocumentTitle = c.documentTitle(docURL, result)
 
 	return result, nil
 }
 
-// checkURL checks if a URL exists and validates anchor if present
-// Returns: (pageExists, anchorExists, hasAnchor, error)
-func (c *Checker) checkURL(urlString string) (bool, bool, bool, error) {
-	maxRetries := 3
-	var lastErr error
+// CheckVersion checks the page and anchor of docURL at a single version,
+// e.g. whether a section still exists in 4.20, without the sweep of every
+// known version that Check makes. The version is validated exactly like
+// each version of Check: with retries, the renamed page slugs of the slug
+// map, the html-single fallback and, when SetSiblingSearch enables it, a
+// search of the other pages of the guide. It holds a slot like the checks
+// of Check. The version may be an alias resolved with ResolveVersion.
+func (c *Checker) CheckVersion(docURL *parser.OCPDocURL, version string) (VersionCheckResult, error) {
+	return c.CheckVersionContext(context.Background(), docURL, version)
+}
 
-	// Parse URL to extract fragment/anchor
-	fragment := ""
-	baseURL := urlString
-	if idx := strings.Index(urlString, "#"); idx != -1 {
-		fragment = urlString[idx+1:]
-		baseURL = urlString[:idx]
+// CheckVersionContext is CheckVersion, giving up once ctx is done. The
+// error is that of resolving version, of ctx, or the RequestError of a
+// page that got no answer, which the result also holds: a missing page is
+// a result with Exists false and no error.
+func (c *Checker) CheckVersionContext(ctx context.Context, docURL *parser.OCPDocURL, version string) (VersionCheckResult, error) {
+	resolved, err := c.ResolveVersion(version)
+	if err != nil {
+		return VersionCheckResult{}, err
 	}
+	v := c.checkVersionInSlot(ctx, docURL, resolved, true)
+	if err := ctx.Err(); err != nil {
+		return v, err
+	}
+	return v, v.Error
+}
 
-	hasAnchor := fragment != ""
-
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		if attempt > 0 {
-			// Wait a bit before retrying (exponential backoff)
-			time.Sleep(time.Duration(attempt) * 2 * time.Second)
-		}
-
-		// First check if the base URL exists
-		var resp *http.Response
-		var err error
-
-		if hasAnchor {
-			// If we need to check anchor, use GET to fetch the HTML
-			resp, err = c.client.Get(baseURL)
-		} else {
-			// No anchor, use HEAD for efficiency
-			resp, err = c.client.Head(baseURL)
-			if err != nil {
-				// If HEAD fails, try GET
-				resp, err = c.client.Get(baseURL)
+// checkVersions checks the document at every version concurrently, at most
+// maxConcurrent versions at a time, and returns the results in the order of
+// versions. Each result is passed to report, if not nil, as soon as it is
+// known. Only the newest version, the last, gets a sibling search.
+func (c *Checker) checkVersions(ctx context.Context, docURL *parser.OCPDocURL, versions []string, report func(VersionCheckResult)) []VersionCheckResult {
+	results := make([]VersionCheckResult, len(versions))
+	var wg sync.WaitGroup
+	for i, version := range versions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.checkVersionInSlot(ctx, docURL, version, i == len(versions)-1)
+			// The slot is free for other checks while reporting
+			if report != nil {
+				report(results[i])
 			}
-		}
+		}()
+	}
+	wg.Wait()
+	return results
+}
 
-		if err != nil {
-			lastErr = err
-			continue // Retry
-		}
-		defer resp.Body.Close()
+// checkVersionInSlot is checkVersion holding one of the maxConcurrent
+// slots. A version holds its slot through its retries and renamed slug, so
+// neither adds requests in flight.
+func (c *Checker) checkVersionInSlot(ctx context.Context, docURL *parser.OCPDocURL, version string, siblings bool) VersionCheckResult {
+	c.slots <- struct{}{}
+	defer func() { <-c.slots }()
+	return c.checkVersion(ctx, docURL, version, siblings)
+}
 
-		// Check if page exists
-		if resp.StatusCode >= 400 {
-			// 4xx or 5xx - page doesn't exist, no point retrying
-			return false, false, hasAnchor, nil
-		}
+// checkVersion checks the document at a single version, retrying with a
+// renamed page slug when the page is missing and the slug map knows a
+// rename. With siblings set, an anchor missing from the page is looked for
+// on the other pages of the guide first.
+func (c *Checker) checkVersion(ctx context.Context, docURL *parser.OCPDocURL, version string, siblings bool) VersionCheckResult {
+	versionResult := c.checkURL(ctx, docURL.BuildURL(version))
+	versionResult.Version = version
+	if reqErr, ok := versionResult.Error.(*RequestError); ok {
+		reqErr.Version = version
+	}
 
-		if resp.StatusCode < 200 || resp.StatusCode >= 400 {
-			// Unexpected status code
-			continue // Retry
-		}
+	if versionResult.Exists || versionResult.Error != nil {
+		return c.missingAnchor(ctx, docURL, versionResult, siblings)
+	}
 
-		// Page exists (2xx or 3xx)
-		pageExists := true
+	renamedPage, ok := c.slugMap.Lookup(docURL.Document, docURL.Page, version)
+	if !ok {
+		return versionResult
+	}
+	applyHeuristic(&versionResult.heuristics, HeuristicSlugMap)
 
-		// If no anchor, we're done
-		if !hasAnchor {
-			return pageExists, false, hasAnchor, nil
-		}
+	renamed := *docURL
+	renamed.Page = renamedPage
+	renamedResult := c.checkURL(ctx, renamed.BuildURL(version))
+	if !renamedResult.Exists {
+		return versionResult
+	}
 
-		// Validate anchor exists in HTML
-		anchorExists, err := c.checkAnchorInHTML(resp.Body, fragment)
-		if err != nil {
-			lastErr = err
-			continue // Retry
+	renamedResult.Version = version
+	renamedResult.RenamedFrom = docURL.Page
+	for _, h := range versionResult.heuristics {
+		applyHeuristic(&renamedResult.heuristics, h)
+	}
+	return c.missingAnchor(ctx, docURL, renamedResult, siblings)
+}
+
+// missingAnchor looks further for an anchor missing from the page of v:
+// with siblings set on the other pages of the guide, where the section may
+// have moved, and then on the html-single variant
+func (c *Checker) missingAnchor(ctx context.Context, docURL *parser.OCPDocURL, v VersionCheckResult, siblings bool) VersionCheckResult {
+	if siblings {
+		var moved bool
+		if v, moved = c.searchSiblings(ctx, docURL, v); moved {
+			return v
 		}
+	}
+	return c.formatFallback(ctx, docURL, v)
+}
 
-		return pageExists, anchorExists, hasAnchor, nil
+// formatFallback looks up the anchor of an html page that lacks it in the
+// html-single variant of the guide, which holds the full static content.
+// The html-single page is fetched once per version however many anchors
+// need it.
+func (c *Checker) formatFallback(ctx context.Context, docURL *parser.OCPDocURL, v VersionCheckResult) VersionCheckResult {
+	if c.noFormatFallback || docURL.Format != "html" || !v.Exists || !v.HasAnchor || v.AnchorExists {
+		return v
 	}
 
-	return false, false, hasAnchor, lastErr
+	single := *docURL
+	single.Format = "html-single"
+	single.Page = "index"
+	probe := c.checkURL(ctx, single.BuildURL(v.Version))
+	applyHeuristic(&v.heuristics, HeuristicFormatFallback)
+	for _, h := range probe.heuristics {
+		applyHeuristic(&v.heuristics, h)
+	}
+	switch {
+	case probe.Exists && probe.AnchorExists:
+		v.AnchorExists = true
+		v.AnchorText = probe.AnchorText
+		v.AnchorVia = AnchorViaSingle
+		v.SuggestedAnchors = nil
+		v.AnchorIndeterminate, v.ScannedBytes = false, 0
+	case probe.AnchorIndeterminate:
+		v.AnchorIndeterminate = true
+		v.ScannedBytes = probe.ScannedBytes
+	case probe.Exists:
+		// The html-single page holds every section of the guide, so an
+		// anchor missing from all of it is missing from the page too
+		v.AnchorIndeterminate, v.ScannedBytes = false, 0
+	}
+	return v
 }
 
-// checkAnchorInHTML parses HTML and checks if an anchor/fragment exists
-func (c *Checker) checkAnchorInHTML(body io.Reader, anchor string) (bool, error) {
-	doc, err := html.Parse(body)
-	if err != nil {
-		return false, fmt.Errorf("failed to parse HTML: %w", err)
-	}
+// checkURL checks if a URL exists and validates its anchor, if present. The
+// result carries everything but the version.
+func (c *Checker) checkURL(ctx context.Context, urlString string) VersionCheckResult {
+	_, fragment, _ := strings.Cut(urlString, "#")
+	result := VersionCheckResult{URL: urlString, HasAnchor: fragment != ""}
 
-	found := false
-	var checkNode func(*html.Node)
-	checkNode = func(n *html.Node) {
-		if found {
-			return
+	facts, err := c.pageFacts(ctx, urlString)
+	if err != nil {
+		reqErr, ok := err.(*RequestError)
+		if !ok {
+			reqErr = &RequestError{URL: urlString, Err: err}
 		}
-
-		if n.Type == html.ElementNode {
-			// Check for id attribute
-			for _, attr := range n.Attr {
-				if attr.Key == "id" && attr.Val == anchor {
-					found = true
-					return
-				}
-				// Also check for name attribute (older HTML anchor style)
-				if n.Data == "a" && attr.Key == "name" && attr.Val == anchor {
-					found = true
-					return
-				}
+		result.Error = reqErr
+		result.StatusCode = reqErr.StatusCode
+		result.Attempts = reqErr.Attempts
+		result.Duration = reqErr.Duration
+		result.CheckedAt = time.Now()
+		return result
+	}
+	result.Exists = facts.Exists
+	result.Method = facts.Method
+	result.Attempts = facts.Attempts
+	result.StatusCode = facts.StatusCode
+	result.Duration = facts.Duration
+	result.Cached = facts.Imported
+	result.CheckedAt = facts.CheckedAt
+	result.Network = facts.Network
+	if facts.NetworkFallback {
+		applyHeuristic(&result.heuristics, HeuristicNetworkFallback)
+	}
+	if facts.Imported {
+		applyHeuristic(&result.heuristics, HeuristicImportedFacts)
+	}
+	if !result.HasAnchor && facts.Method == http.MethodGet && !facts.Probed {
+		applyHeuristic(&result.heuristics, HeuristicHeadFallback)
+	}
+	if facts.NotFoundMarker != "" {
+		applyHeuristic(&result.heuristics, HeuristicSoft404)
+		result.NotFoundMarker = facts.NotFoundMarker
+	}
+	result.DeprecationMarker = facts.DeprecationMarker
+	if facts.Exists {
+		var served string
+		result.RedirectedTo, served = redirectTarget(urlString, facts.FinalURL)
+		if result.RedirectedTo != "" {
+			applyHeuristic(&result.heuristics, HeuristicRedirect)
+			if !c.acceptRedirects {
+				result.Exists = false
+				return result
 			}
 		}
-
-		// Recursively check child nodes
-		for child := n.FirstChild; child != nil; child = child.NextSibling {
-			checkNode(child)
+		if served != "" {
+			result.ServedDocument = served
+			applyHeuristic(&result.heuristics, HeuristicCrossDocument)
+			result.ServedTitle = GuideTitle(facts.Title)
 		}
 	}
+	if !facts.Exists || !result.HasAnchor {
+		return result
+	}
 
-	checkNode(doc)
-	return found, nil
+	// The title of a page moved to another guide is not this guide's
+	if !result.CrossDocument() {
+		c.recordTitle(facts.URL, facts.Title)
+	}
+	result.AnchorExists = facts.HasAnchor(fragment)
+	if result.AnchorExists {
+		result.AnchorText = facts.AnchorTexts[fragment]
+	}
+	if !result.AnchorExists && facts.Truncated {
+		applyHeuristic(&result.heuristics, HeuristicParseBudget)
+		result.AnchorIndeterminate = true
+		result.ScannedBytes = facts.Size
+	} else if !result.AnchorExists {
+		result.SuggestedAnchors = anchors.Closest(facts.AnchorIDs, fragment, maxSuggestedAnchors)
+	}
+	return result
 }
 
-// getNewerVersions returns versions newer than the given version
+// getNewerVersions returns versions newer than the given version, within
+// the range of SetVersionRange and not excluded with ExcludeVersions
 func (c *Checker) getNewerVersions(currentVersion string) []string {
 	var newer []string
 
@@ -249,7 +971,7 @@ func (c *Checker) getNewerVersions(currentVersion string) []string {
 			continue
 		}
 
-		if testDoc.GetVersionFloat() > currentFloat {
+		if testDoc.GetVersionFloat() > currentFloat && c.checksVersion(v) {
 			newer = append(newer, v)
 		}
 	}
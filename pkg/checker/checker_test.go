@@ -1,11 +1,26 @@
 package checker
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/parser"
 )
 
-func TestCheckAnchorInHTML(t *testing.T) {
+func TestCheckURLOnce_Anchor(t *testing.T) {
 	tests := []struct {
 		name       string
 		html       string
@@ -87,19 +102,20 @@ func TestCheckAnchorInHTML(t *testing.T) {
 		},
 	}
 
-	checker := NewChecker()
+	const pageURL = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html/networking/index"
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			reader := strings.NewReader(tt.html)
-			gotExists, err := checker.checkAnchorInHTML(reader, tt.anchor)
+			c := NewChecker()
+			c.SetTransport(&stubTransport{pages: map[string]string{pageURL: tt.html}})
+			facts, err := c.CheckURLOnce(context.Background(), pageURL+"#"+tt.anchor)
 
 			if (err != nil) != tt.wantErr {
-				t.Errorf("checkAnchorInHTML() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("CheckURLOnce() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 
-			if gotExists != tt.wantExists {
-				t.Errorf("checkAnchorInHTML() = %v, want %v", gotExists, tt.wantExists)
+			if got := facts.HasAnchor(tt.anchor); got != tt.wantExists {
+				t.Errorf("CheckURLOnce() HasAnchor(%q) = %v, want %v", tt.anchor, got, tt.wantExists)
 			}
 		})
 	}
@@ -153,3 +169,928 @@ func TestCheckURL_FragmentParsing(t *testing.T) {
 		})
 	}
 }
+
+func TestCheck_MalformedFragmentIsNotProbed(t *testing.T) {
+	c := NewChecker()
+	// An empty client would panic if Check attempted any request
+	c.client = nil
+
+	result, err := c.Check("https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html-single/operators/index#first#second")
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if result.FragmentIssue != parser.FragmentMalformed {
+		t.Errorf("FragmentIssue = %q, want %q", result.FragmentIssue, parser.FragmentMalformed)
+	}
+	if result.IsOutdated || len(result.AllResults) != 0 {
+		t.Errorf("malformed fragment should not be checked, got %d results", len(result.AllResults))
+	}
+}
+
+func TestCheck_NonCanonicalVersion(t *testing.T) {
+	const docPath = "/en/documentation/openshift_container_platform/%s/html/networking/ingress-operator"
+	page := `<html><body><h2 id="configuring-ingress">Ingress</h2></body></html>`
+
+	c := newFakeDocsChecker(t, map[string]string{
+		fmt.Sprintf(docPath, "4.9"):  page,
+		fmt.Sprintf(docPath, "4.10"): page,
+	})
+	c.SetVersions([]string{"4.9", "4.10"})
+
+	result, err := c.Check("https://docs.redhat.com" + fmt.Sprintf(docPath, "04.09.2") + "#configuring-ingress")
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if result.VersionIssue != parser.VersionNonCanonical {
+		t.Errorf("VersionIssue = %q, want %q", result.VersionIssue, parser.VersionNonCanonical)
+	}
+	if result.OriginalVersion != "4.9" {
+		t.Errorf("OriginalVersion = %q, want 4.9", result.OriginalVersion)
+	}
+	if want := "https://docs.redhat.com" + fmt.Sprintf(docPath, "4.9") + "#configuring-ingress"; result.SuggestedURL != want {
+		t.Errorf("SuggestedURL = %q, want %q", result.SuggestedURL, want)
+	}
+	if !result.IsOutdated || result.LatestVersion != "4.10" {
+		t.Errorf("IsOutdated = %v, LatestVersion = %q, want outdated to 4.10", result.IsOutdated, result.LatestVersion)
+	}
+}
+
+func TestCheckURL_EgressPolicy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "http://evil.example.com/phish", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("host not on the allowlist is refused", func(t *testing.T) {
+		c := NewChecker()
+		result := c.checkURL(context.Background(), server.URL+"/page")
+		if !errors.Is(result.Error, ErrHostNotAllowed) {
+			t.Errorf("checkURL() error = %v, want ErrHostNotAllowed", result.Error)
+		}
+		if result.Exists {
+			t.Error("checkURL() reported a refused page as existing")
+		}
+	})
+
+	t.Run("allowed host is checked", func(t *testing.T) {
+		c := NewChecker()
+		c.AllowHost(serverURL.Hostname())
+		result := c.checkURL(context.Background(), server.URL+"/page")
+		if result.Error != nil {
+			t.Fatalf("checkURL() error = %v", result.Error)
+		}
+		if !result.Exists {
+			t.Error("checkURL() = false, want true")
+		}
+	})
+
+	t.Run("redirect to a foreign host is blocked", func(t *testing.T) {
+		c := NewChecker()
+		c.AllowHost(serverURL.Hostname())
+		result := c.checkURL(context.Background(), server.URL+"/redirect")
+		if !errors.Is(result.Error, ErrHostNotAllowed) {
+			t.Fatalf("checkURL() error = %v, want ErrHostNotAllowed", result.Error)
+		}
+		if !strings.Contains(result.Error.Error(), "evil.example.com") {
+			t.Errorf("error %q does not name the refused host", result.Error)
+		}
+		if result.Exists {
+			t.Error("checkURL() reported a blocked redirect as existing")
+		}
+	})
+}
+
+// rewriteTransport sends every request to a test server, keeping the path
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	r.URL.Scheme = t.target.Scheme
+	r.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// fakeLastModified is the Last-Modified time of every fake docs page
+var fakeLastModified = time.Date(2025, time.March, 4, 12, 0, 0, 0, time.UTC)
+
+// newFakeDocsChecker returns a Checker whose docs.redhat.com requests are
+// served from pages, keyed by URL path. Unknown paths return 404, and a page
+// of "redirect:" followed by a path redirects there.
+func newFakeDocsChecker(t *testing.T, pages map[string]string) *Checker {
+	t.Helper()
+	return newHandlerChecker(t, fakeDocsHandler(pages))
+}
+
+// fakeDocsHandler serves pages by path, 404 for the others; a page whose
+// body is "redirect:" and a path redirects there
+func fakeDocsHandler(pages map[string]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if target, ok := strings.CutPrefix(body, "redirect:"); ok {
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Last-Modified", fakeLastModified.Format(http.TimeFormat))
+		_, _ = w.Write([]byte(body))
+	})
+}
+
+// newHandlerChecker returns a checker whose requests to docs.redhat.com are
+// answered by handler
+func newHandlerChecker(t *testing.T, handler http.Handler) *Checker {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	target, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewChecker()
+	c.SetTransport(rewriteTransport{target: target})
+	return c
+}
+
+// stubTransport answers every request from canned pages keyed by URL
+// without a fragment, 404 for the others, without any network
+type stubTransport struct {
+	pages map[string]string
+	mu    sync.Mutex
+	seen  []string
+}
+
+func (t *stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	t.seen = append(t.seen, req.Method+" "+req.URL.String())
+	t.mu.Unlock()
+	resp := &http.Response{
+		StatusCode: http.StatusNotFound,
+		Header:     make(http.Header),
+		Body:       io.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}
+	if body, ok := t.pages[req.URL.String()]; ok {
+		resp.StatusCode = http.StatusOK
+		resp.Body = io.NopCloser(strings.NewReader(body))
+	}
+	return resp, nil
+}
+
+func TestSetTransport(t *testing.T) {
+	const docURL = "https://docs.redhat.com/en/documentation/openshift_container_platform/%s/html/networking/index"
+	page := `<html><body><h2 id="ingress">Ingress</h2></body></html>`
+	stub := &stubTransport{pages: map[string]string{
+		fmt.Sprintf(docURL, "4.16"): page,
+		fmt.Sprintf(docURL, "4.17"): page,
+	}}
+
+	c := NewChecker()
+	c.SetVersions([]string{"4.16", "4.17", "4.18"})
+	c.SetTransport(stub)
+
+	result, err := c.Check(fmt.Sprintf(docURL, "4.16") + "#ingress")
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if !result.IsOutdated || result.LatestVersion != "4.17" {
+		t.Errorf("Check() = outdated %v, latest %s; want outdated, latest 4.17", result.IsOutdated, result.LatestVersion)
+	}
+	// Versions are checked concurrently, the original one included
+	sort.Strings(stub.seen)
+	want := []string{"GET " + fmt.Sprintf(docURL, "4.16"), "GET " + fmt.Sprintf(docURL, "4.17"), "GET " + fmt.Sprintf(docURL, "4.18")}
+	if !reflect.DeepEqual(stub.seen, want) {
+		t.Errorf("requests = %v, want %v", stub.seen, want)
+	}
+	// The egress policy and the request count still apply
+	if got := c.Requests(); got != 3 {
+		t.Errorf("Requests() = %d, want 3", got)
+	}
+	if _, err := c.CheckURLOnce(context.Background(), "https://example.com/"); !errors.Is(err, ErrHostNotAllowed) {
+		t.Errorf("CheckURLOnce() of another host error = %v, want ErrHostNotAllowed", err)
+	}
+
+	c.SetTransport(nil)
+	if _, ok := c.client.Transport.(*egressTransport).base.(*http.Transport); !ok {
+		t.Error("SetTransport(nil) did not restore the default transport")
+	}
+}
+
+func TestCheck_SlugRenameFallback(t *testing.T) {
+	const docPath = "/en/documentation/openshift_container_platform/%s/html/networking/%s"
+	page := `<html><body><h2 id="configuring-ingress">Ingress</h2></body></html>`
+
+	c := newFakeDocsChecker(t, map[string]string{
+		fmt.Sprintf(docPath, "4.15", "ingress-operator"):                    page,
+		fmt.Sprintf(docPath, "4.16", "configuring-ingress-cluster-traffic"): page,
+		fmt.Sprintf(docPath, "4.17", "configuring-ingress-cluster-traffic"): page,
+	})
+	c.SetVersions([]string{"4.15", "4.16", "4.17"})
+	c.SetSlugMap(&SlugMap{Renames: []SlugRename{
+		{Document: "networking", From: "ingress-operator", To: "configuring-ingress-cluster-traffic", Since: "4.16"},
+	}})
+
+	result, err := c.Check("https://docs.redhat.com/en/documentation/openshift_container_platform/4.15/html/networking/ingress-operator#configuring-ingress")
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	if !result.IsOutdated || result.LatestVersion != "4.17" {
+		t.Fatalf("Check() outdated = %v latest = %s, want outdated at 4.17", result.IsOutdated, result.LatestVersion)
+	}
+
+	latest := result.NewerVersions[len(result.NewerVersions)-1]
+	wantURL := "https://docs.redhat.com/en/documentation/openshift_container_platform/4.17/html/networking/configuring-ingress-cluster-traffic#configuring-ingress"
+	if latest.URL != wantURL {
+		t.Errorf("latest URL = %s, want %s", latest.URL, wantURL)
+	}
+	if latest.RenamedFrom != "ingress-operator" {
+		t.Errorf("RenamedFrom = %q, want %q", latest.RenamedFrom, "ingress-operator")
+	}
+}
+
+func TestChecker_Requests(t *testing.T) {
+	const docPath = "/en/documentation/openshift_container_platform/%s/html/networking/index"
+
+	c := newFakeDocsChecker(t, map[string]string{
+		fmt.Sprintf(docPath, "4.17"): "<html></html>",
+	})
+	c.SetVersions([]string{"4.15", "4.16", "4.17"})
+
+	if _, err := c.Check("https://docs.redhat.com/en/documentation/openshift_container_platform/4.15/html/networking/index"); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	// One HEAD request for the original version and each newer one
+	if got := c.Requests(); got != 3 {
+		t.Errorf("Requests() = %d, want 3", got)
+	}
+
+	if _, err := c.client.Get("https://example.com/"); !errors.Is(err, ErrHostNotAllowed) {
+		t.Fatalf("Get() error = %v, want ErrHostNotAllowed", err)
+	}
+	if got := c.Requests(); got != 3 {
+		t.Errorf("Requests() after a refused request = %d, want 3", got)
+	}
+}
+
+func TestChecker_RateLimit(t *testing.T) {
+	const pageURL = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.17/html/networking/index"
+	c := newFakeDocsChecker(t, map[string]string{
+		"/en/documentation/openshift_container_platform/4.17/html/networking/index": "<html></html>",
+	})
+
+	// After a burst of two, the other requests of concurrent workers wait
+	// their turn at 20 per second
+	c.SetRateLimit(20, 2)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for range 6 {
+		wg.Go(func() {
+			if _, err := c.CheckURLOnce(context.Background(), pageURL); err != nil {
+				t.Errorf("CheckURLOnce() error = %v", err)
+			}
+		})
+	}
+	wg.Wait()
+	if elapsed := time.Since(start); elapsed < 180*time.Millisecond {
+		t.Errorf("6 requests took %s, want at least 200ms at 20 per second after a burst of 2", elapsed)
+	}
+
+	// A request waiting for its turn gives up with its context
+	c.SetRateLimit(0.01, 1)
+	if _, err := c.CheckURLOnce(context.Background(), pageURL); err != nil {
+		t.Fatalf("CheckURLOnce() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	requests := c.Requests()
+	if _, err := c.CheckURLOnce(ctx, pageURL); !errors.Is(err, context.Canceled) {
+		t.Errorf("CheckURLOnce() waiting for the limit error = %v, want context.Canceled", err)
+	}
+	if got := c.Requests(); got != requests {
+		t.Errorf("Requests() = %d, want %d: a canceled wait sends nothing", got, requests)
+	}
+}
+
+func TestCheck_ConcurrentVersions(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			max := maxInFlight.Load()
+			if n <= max || maxInFlight.CompareAndSwap(max, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		// Every other version has the page
+		if strings.Contains(r.URL.Path, "/4.12/") || strings.Contains(r.URL.Path, "/4.14/") || strings.Contains(r.URL.Path, "/4.16/") {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("<html></html>"))
+	}))
+	defer server.Close()
+	target, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	versions := []string{"4.10", "4.11", "4.12", "4.13", "4.14", "4.15", "4.16", "4.17", "4.18"}
+	for _, n := range []int{1, 3} {
+		t.Run(fmt.Sprintf("max %d", n), func(t *testing.T) {
+			maxInFlight.Store(0)
+			c := NewChecker()
+			c.SetTransport(rewriteTransport{target: target})
+			c.SetVersions(versions)
+			c.SetMaxConcurrent(n)
+
+			result, err := c.Check("https://docs.redhat.com/en/documentation/openshift_container_platform/4.10/html/networking/index")
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+
+			if got := int(maxInFlight.Load()); got > n || n > 1 && got < 2 {
+				t.Errorf("requests in flight peaked at %d, want up to %d in parallel", got, n)
+			}
+			var all, newer []string
+			for _, v := range result.AllResults {
+				all = append(all, v.Version)
+			}
+			for _, v := range result.NewerVersions {
+				newer = append(newer, v.Version)
+			}
+			if want := versions[1:]; !reflect.DeepEqual(all, want) {
+				t.Errorf("AllResults versions = %v, want %v", all, want)
+			}
+			if want := []string{"4.11", "4.13", "4.15", "4.17", "4.18"}; !reflect.DeepEqual(newer, want) {
+				t.Errorf("NewerVersions = %v, want %v", newer, want)
+			}
+		})
+	}
+}
+
+func TestChecker_Stats(t *testing.T) {
+	const docPath = "/en/documentation/openshift_container_platform/%s/html-single/networking/index"
+	anchors := []string{"a", "b", "c", "d", "e"}
+	page := "<html><body>"
+	for _, a := range anchors {
+		page += `<h2 id="` + a + `">` + a + `</h2>`
+	}
+	page += "</body></html>"
+
+	c := newFakeDocsChecker(t, map[string]string{
+		fmt.Sprintf(docPath, "4.16"): page,
+		fmt.Sprintf(docPath, "4.17"): page,
+	})
+	c.SetVersions([]string{"4.15", "4.16", "4.17"})
+
+	check := func(fragment string) {
+		t.Helper()
+		if _, err := c.Check("https://docs.redhat.com" + fmt.Sprintf(docPath, "4.15") + fragment); err != nil {
+			t.Fatalf("Check() error = %v", err)
+		}
+	}
+
+	// A HEAD request per version, the original one included, does not
+	// tell the anchors apart
+	check("")
+	if got, want := c.Stats(), (Stats{PageLookups: 3, Requests: 3}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Stats() after a URL without anchor = %+v, want %+v", got, want)
+	}
+
+	// Each page is downloaded once, however many of its anchors are
+	// linked; the missing original page is known from its HEAD request
+	for _, a := range anchors {
+		check("#" + a)
+	}
+	if got, want := c.Stats(), (Stats{PageLookups: 18, CacheHits: 13, Requests: 5}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Stats() after five anchors = %+v, want %+v", got, want)
+	}
+}
+
+func TestCheck_SlugMapLeavesUnknownDocumentsAlone(t *testing.T) {
+	const docPath = "/en/documentation/openshift_container_platform/%s/html/storage/%s"
+
+	c := newFakeDocsChecker(t, map[string]string{
+		fmt.Sprintf(docPath, "4.15", "ingress-operator"): "<html></html>",
+		fmt.Sprintf(docPath, "4.16", "renamed"):          "<html></html>",
+	})
+	c.SetVersions([]string{"4.15", "4.16"})
+	c.SetSlugMap(&SlugMap{Renames: []SlugRename{
+		{Document: "networking", From: "ingress-operator", To: "renamed", Since: "4.16"},
+	}})
+
+	result, err := c.Check("https://docs.redhat.com/en/documentation/openshift_container_platform/4.15/html/storage/ingress-operator")
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if result.IsOutdated {
+		t.Errorf("Check() used a rename from another document: %+v", result.NewerVersions)
+	}
+	if result.AllResults[0].RenamedFrom != "" {
+		t.Errorf("RenamedFrom = %q, want empty", result.AllResults[0].RenamedFrom)
+	}
+}
+
+func TestSetAllowedTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets []string
+		want    []string
+		wantErr bool
+	}{
+		{"eus", []string{"eus"}, []string{"4.14", "4.16", "4.18", "4.20"}, false},
+		{"eus is case-insensitive", []string{"EUS"}, []string{"4.14", "4.16", "4.18", "4.20"}, false},
+		{"list", []string{"4.16", "4.19"}, []string{"4.16", "4.19"}, false},
+		{"eus plus a list", []string{"eus", "4.19"}, []string{"4.14", "4.16", "4.18", "4.19", "4.20"}, false},
+		{"aliases", []string{"latest", "latest-2"}, []string{"4.18", "4.20"}, false},
+		{"eus-latest", []string{"eus-latest"}, []string{"4.20"}, false},
+		{"invalid", []string{"nightly"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker()
+			c.SetVersions([]string{"4.14", "4.15", "4.16", "4.17", "4.18", "4.19", "4.20"})
+
+			err := c.SetAllowedTargets(tt.targets)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetAllowedTargets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var got []string
+			for _, v := range c.knownVersions {
+				if c.allowedTargets[v] {
+					got = append(got, v)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("allowed targets = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck_AllowedTargets(t *testing.T) {
+	const docPath = "/en/documentation/openshift_container_platform/%s/html/networking/index"
+	pages := make(map[string]string)
+	for _, v := range []string{"4.14", "4.15", "4.16", "4.17"} {
+		pages[fmt.Sprintf(docPath, v)] = "<html></html>"
+	}
+	const rawURL = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.14/html/networking/index"
+
+	tests := []struct {
+		name     string
+		targets  []string
+		latest   string
+		newer    []string
+		excluded []string
+	}{
+		{"all versions", nil, "4.17", []string{"4.15", "4.16", "4.17"}, nil},
+		{"eus", []string{"eus"}, "4.16", []string{"4.16"}, []string{"4.15", "4.17"}},
+		{"nothing newer allowed", []string{"4.14"}, "4.14", nil, []string{"4.15", "4.16", "4.17"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeDocsChecker(t, pages)
+			c.SetVersions([]string{"4.14", "4.15", "4.16", "4.17"})
+			if err := c.SetAllowedTargets(tt.targets); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := c.Check(rawURL)
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+
+			if result.LatestVersion != tt.latest || result.IsOutdated != (len(tt.newer) > 0) {
+				t.Errorf("Check() latest = %s outdated = %v, want %s", result.LatestVersion, result.IsOutdated, tt.latest)
+			}
+			if got := versionsOf(result.NewerVersions); !reflect.DeepEqual(got, tt.newer) {
+				t.Errorf("NewerVersions = %v, want %v", got, tt.newer)
+			}
+			if got := versionsOf(result.ExcludedVersions); !reflect.DeepEqual(got, tt.excluded) {
+				t.Errorf("ExcludedVersions = %v, want %v", got, tt.excluded)
+			}
+			if newest, ok := result.NewestExcluded(); ok != (tt.name != "all versions") || ok && newest.Version != "4.17" {
+				t.Errorf("NewestExcluded() = %s, %v", newest.Version, ok)
+			}
+			if len(result.AllResults) != 3 {
+				t.Errorf("checked %d versions, want all 3", len(result.AllResults))
+			}
+		})
+	}
+}
+
+func versionsOf(results []VersionCheckResult) []string {
+	var versions []string
+	for _, v := range results {
+		versions = append(versions, v.Version)
+	}
+	return versions
+}
+
+func TestCheck_CrossDocumentRedirect(t *testing.T) {
+	const docPath = "/en/documentation/openshift_container_platform/%s/html/%s/%s"
+	page := func(guide, version string) string {
+		return `<html><head><title>Chapter 3. Ingress | ` + guide + ` | OpenShift Container Platform | ` + version + ` | Red Hat Documentation</title></head>` +
+			`<body><h2 id="configuring-ingress">Ingress</h2></body></html>`
+	}
+
+	c := newFakeDocsChecker(t, map[string]string{
+		fmt.Sprintf(docPath, "4.17", "networking", "ingress"): page("Networking", "4.17"),
+		// 4.18 moves the page within the guide, 4.19 into another guide
+		fmt.Sprintf(docPath, "4.18", "networking", "ingress"):                 "redirect:" + fmt.Sprintf(docPath, "4.18", "networking", "ingress-traffic"),
+		fmt.Sprintf(docPath, "4.18", "networking", "ingress-traffic"):         page("Networking", "4.18"),
+		fmt.Sprintf(docPath, "4.19", "networking", "ingress"):                 "redirect:" + fmt.Sprintf(docPath, "4.19", "ingress_and_load_balancing", "ingress"),
+		fmt.Sprintf(docPath, "4.19", "ingress_and_load_balancing", "ingress"): page("Ingress and load balancing", "4.19"),
+	})
+	c.SetVersions([]string{"4.17", "4.18", "4.19"})
+	c.SetAcceptRedirects(true)
+
+	result, err := c.Check("https://docs.redhat.com" + fmt.Sprintf(docPath, "4.17", "networking", "ingress") + "#configuring-ingress")
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if len(result.NewerVersions) != 2 {
+		t.Fatalf("Check() newer versions = %+v, want 4.18 and 4.19", result.NewerVersions)
+	}
+
+	if v := result.NewerVersions[0]; v.CrossDocument() || v.RedirectedTo != "https://docs.redhat.com"+fmt.Sprintf(docPath, "4.18", "networking", "ingress-traffic") {
+		t.Errorf("4.18 served from %q, redirected to %q; want the same guide, redirected to ingress-traffic", v.ServedDocument, v.RedirectedTo)
+	}
+	v := result.NewerVersions[1]
+	if !v.CrossDocument() || v.ServedDocument != "ingress_and_load_balancing" || v.ServedTitle != "Ingress and load balancing" {
+		t.Errorf("4.19 served from %q titled %q, want ingress_and_load_balancing titled Ingress and load balancing", v.ServedDocument, v.ServedTitle)
+	}
+	if v.RedirectedTo != "https://docs.redhat.com"+fmt.Sprintf(docPath, "4.19", "ingress_and_load_balancing", "ingress") {
+		t.Errorf("4.19 redirected to %q, want the page of ingress_and_load_balancing", v.RedirectedTo)
+	}
+	if result.DocumentTitle != "Networking" {
+		t.Errorf("DocumentTitle = %q, want Networking", result.DocumentTitle)
+	}
+}
+
+func TestCheck_Redirects(t *testing.T) {
+	const docPath = "/en/documentation/openshift_container_platform/%s/html/networking/%s"
+	const landingPath = "/en/documentation/openshift_container_platform/4.18"
+	page := `<html><body><h2 id="ingress">Ingress</h2></body></html>`
+	pages := map[string]string{
+		fmt.Sprintf(docPath, "4.17", "ingress"): page,
+		// 4.18 sends every page to the landing page of the release, 4.19
+		// only adds a trailing slash to the same page
+		fmt.Sprintf(docPath, "4.18", "ingress"):       "redirect:" + landingPath,
+		landingPath:                                   `<html><body><h1>OpenShift Container Platform 4.18</h1></body></html>`,
+		fmt.Sprintf(docPath, "4.19", "ingress"):       "redirect:" + fmt.Sprintf(docPath, "4.19", "ingress") + "/",
+		fmt.Sprintf(docPath, "4.19", "ingress") + "/": page,
+	}
+	linked := "https://docs.redhat.com" + fmt.Sprintf(docPath, "4.17", "ingress")
+
+	tests := []struct {
+		name   string
+		accept bool
+		// wantNewer are the working newer versions
+		wantNewer []string
+	}{
+		{name: "redirects rejected", wantNewer: []string{"4.19"}},
+		{name: "redirects accepted", accept: true, wantNewer: []string{"4.18", "4.19"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeDocsChecker(t, pages)
+			c.SetVersions([]string{"4.17", "4.18", "4.19"})
+			c.SetAcceptRedirects(tt.accept)
+
+			result, err := c.Check(linked)
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if got := versionsOf(result.NewerVersions); !reflect.DeepEqual(got, tt.wantNewer) {
+				t.Errorf("newer versions = %v, want %v", got, tt.wantNewer)
+			}
+			for _, v := range result.AllResults {
+				want := ""
+				if v.Version == "4.18" {
+					want = "https://docs.redhat.com" + landingPath
+				}
+				if v.RedirectedTo != want {
+					t.Errorf("%s redirected to %q, want %q", v.Version, v.RedirectedTo, want)
+				}
+			}
+			if !slices.Contains(result.AppliedHeuristics, HeuristicRedirect) {
+				t.Errorf("AppliedHeuristics = %v, want %s", result.AppliedHeuristics, HeuristicRedirect)
+			}
+		})
+	}
+}
+
+func TestCheck_SingleFormatFallback(t *testing.T) {
+	const (
+		pagePath   = "/en/documentation/openshift_container_platform/%s/html/networking/ingress"
+		singlePath = "/en/documentation/openshift_container_platform/%s/html-single/networking/index"
+	)
+	// The multi-page html renders its section ids with JavaScript, the
+	// html-single variant carries them in the served HTML
+	pages := make(map[string]string)
+	for _, v := range []string{"4.17", "4.18"} {
+		pages[fmt.Sprintf(pagePath, v)] = `<html><body><div id="app"></div></body></html>`
+		pages[fmt.Sprintf(singlePath, v)] = `<html><body><h2 id="configuring-ingress">Ingress</h2><h2 id="ingress-sharding">Sharding</h2></body></html>`
+	}
+	linked := "https://docs.redhat.com" + fmt.Sprintf(pagePath, "4.17")
+
+	tests := []struct {
+		name     string
+		fallback bool
+		wantVia  string
+	}{
+		{"fallback", true, AnchorViaSingle},
+		{"no fallback", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeDocsChecker(t, pages)
+			c.SetVersions([]string{"4.17", "4.18"})
+			c.SetFormatFallback(tt.fallback)
+
+			result, err := c.Check(linked + "#configuring-ingress")
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if !tt.fallback {
+				if len(result.NewerVersions) != 0 {
+					t.Errorf("Check() newer versions = %+v, want none without the anchor", result.NewerVersions)
+				}
+			} else if len(result.NewerVersions) != 1 {
+				t.Errorf("Check() newer versions = %+v, want 4.18", result.NewerVersions)
+			} else if v := result.NewerVersions[0]; v.AnchorVia != tt.wantVia || v.URL != "https://docs.redhat.com"+fmt.Sprintf(pagePath, "4.18")+"#configuring-ingress" {
+				t.Errorf("4.18 = %s via %q, want the linked html page via %q", v.URL, v.AnchorVia, tt.wantVia)
+			}
+
+			// Another anchor of the same page reuses both fetched pages
+			requests := c.Requests()
+			if _, err := c.Check(linked + "#ingress-sharding"); err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if got := c.Requests(); got != requests {
+				t.Errorf("Requests() = %d after a second anchor, want %d", got, requests)
+			}
+		})
+	}
+}
+
+func TestCheck_SuggestedAnchors(t *testing.T) {
+	const (
+		pagePath   = "/en/documentation/openshift_container_platform/%s/html/disconnected_environments/mirroring"
+		singlePath = "/en/documentation/openshift_container_platform/%s/html-single/disconnected_environments/index"
+	)
+	// 4.17 and 4.18 include the module from another assembly, which
+	// appends its context to the id
+	renamed := `<html><body><h2 id="installing-mirroring-disconnected">Mirroring</h2>` +
+		`<h3 id="mirroring-image-set-partial_installing-mirroring-disconnected">Partial</h3>` +
+		`<h3 id="mirroring-image-set-full_installing-mirroring-disconnected">Full</h3></body></html>`
+	pages := map[string]string{
+		fmt.Sprintf(pagePath, "4.16"): `<html><body><h2 id="mirroring-image-set-full">Full</h2></body></html>`,
+		fmt.Sprintf(pagePath, "4.17"): renamed,
+		fmt.Sprintf(pagePath, "4.18"): renamed,
+		// The html-single variant of 4.18 still has the old id
+		fmt.Sprintf(singlePath, "4.17"): renamed,
+		fmt.Sprintf(singlePath, "4.18"): `<html><body><h2 id="mirroring-image-set-full">Full</h2></body></html>`,
+	}
+	linked := "https://docs.redhat.com" + fmt.Sprintf(pagePath, "4.16") + "#mirroring-image-set-full"
+
+	tests := []struct {
+		name     string
+		fallback bool
+		want     map[string][]string
+	}{
+		{
+			name:     "fallback",
+			fallback: true,
+			want: map[string][]string{
+				"4.17": {"mirroring-image-set-full_installing-mirroring-disconnected", "mirroring-image-set-partial_installing-mirroring-disconnected"},
+			},
+		},
+		{
+			name:     "no fallback",
+			fallback: false,
+			want: map[string][]string{
+				"4.17": {"mirroring-image-set-full_installing-mirroring-disconnected", "mirroring-image-set-partial_installing-mirroring-disconnected"},
+				"4.18": {"mirroring-image-set-full_installing-mirroring-disconnected", "mirroring-image-set-partial_installing-mirroring-disconnected"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeDocsChecker(t, pages)
+			c.SetVersions([]string{"4.16", "4.17", "4.18"})
+			c.SetFormatFallback(tt.fallback)
+
+			result, err := c.Check(linked)
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			got := make(map[string][]string)
+			for _, v := range result.AllResults {
+				if v.SuggestedAnchors != nil {
+					got[v.Version] = v.SuggestedAnchors
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SuggestedAnchors by version = %q, want %q", got, tt.want)
+			}
+			// Suggestions are hints, never a verified version
+			for _, v := range result.NewerVersions {
+				if v.Version == "4.17" {
+					t.Errorf("NewerVersions holds 4.17, whose page lacks the anchor")
+				}
+			}
+		})
+	}
+}
+
+func TestCheck_AnchorText(t *testing.T) {
+	const (
+		pagePath   = "/en/documentation/openshift_container_platform/%s/html/disconnected_environments/mirroring"
+		singlePath = "/en/documentation/openshift_container_platform/%s/html-single/disconnected_environments/index"
+	)
+	section := func(title string) string {
+		return `<html><body><section id="mirroring-image-set-full"><div class="titlepage"><h3 class="title">` + title +
+			`</h3></div><p>Mirror the image set.</p></section><p><a name="no-heading"></a>Text first.</p></body></html>`
+	}
+	pages := map[string]string{
+		fmt.Sprintf(pagePath, "4.16"): section("Mirroring an image set in full"),
+		// The id was reused for another section
+		fmt.Sprintf(pagePath, "4.17"):   section("Mirroring an image set to a registry"),
+		fmt.Sprintf(pagePath, "4.18"):   `<html><body><h2 id="other">Other</h2></body></html>`,
+		fmt.Sprintf(singlePath, "4.18"): section("Mirroring an image set in full"),
+	}
+
+	tests := []struct {
+		anchor string
+		want   map[string]string
+	}{
+		{"mirroring-image-set-full", map[string]string{
+			"4.17": "Mirroring an image set to a registry",
+			"4.18": "Mirroring an image set in full",
+		}},
+		{"no-heading", map[string]string{"4.17": "", "4.18": ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.anchor, func(t *testing.T) {
+			c := newFakeDocsChecker(t, pages)
+			c.SetVersions([]string{"4.16", "4.17", "4.18"})
+
+			result, err := c.Check("https://docs.redhat.com" + fmt.Sprintf(pagePath, "4.16") + "#" + tt.anchor)
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			got := make(map[string]string)
+			for _, v := range result.AllResults {
+				if v.AnchorExists {
+					got[v.Version] = v.AnchorText
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AnchorText by version = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckContext_Done(t *testing.T) {
+	// The server answers only once the request is given up on
+	c := newHandlerChecker(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	c.SetVersions([]string{"4.16", "4.17", "4.18"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	result, err := c.CheckContext(ctx, "https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html/networking/ingress#a")
+	if !errors.Is(err, context.DeadlineExceeded) || result != nil {
+		t.Errorf("CheckContext() = %+v, %v; want context.DeadlineExceeded", result, err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("CheckContext() returned after %s, want when the context was done", elapsed)
+	}
+}
+
+func TestCheckVersion(t *testing.T) {
+	const docPath = "/en/documentation/openshift_container_platform/%s/html/networking/%s"
+	page := `<html><body><h2 id="configuring-ingress">Ingress</h2></body></html>`
+	renamed := `<html><body><h2 id="configuring-ingress_ingress-operator">Ingress</h2></body></html>`
+
+	tests := []struct {
+		name        string
+		url         string
+		version     string
+		wantURL     string
+		wantExists  bool
+		wantAnchor  bool
+		wantRenamed string
+		wantErr     bool
+	}{
+		{name: "page and anchor", url: "ingress#configuring-ingress", version: "4.20", wantURL: "4.20/html/networking/ingress#configuring-ingress", wantExists: true, wantAnchor: true},
+		{name: "page without fragment", url: "ingress", version: "4.20", wantURL: "4.20/html/networking/ingress", wantExists: true},
+		{name: "anchor missing", url: "ingress#configuring-ingress", version: "4.19", wantURL: "4.19/html/networking/ingress#configuring-ingress", wantExists: true},
+		{name: "page missing", url: "ingress#configuring-ingress", version: "4.18", wantURL: "4.18/html/networking/ingress#configuring-ingress"},
+		{name: "renamed page slug", url: "ingress-operator#configuring-ingress", version: "4.20", wantURL: "4.20/html/networking/ingress#configuring-ingress", wantExists: true, wantAnchor: true, wantRenamed: "ingress-operator"},
+		{name: "alias", url: "ingress#configuring-ingress", version: "latest", wantURL: "4.20/html/networking/ingress#configuring-ingress", wantExists: true, wantAnchor: true},
+		{name: "unknown version", url: "ingress#configuring-ingress", version: "next", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeDocsChecker(t, map[string]string{
+				fmt.Sprintf(docPath, "4.19", "ingress"): renamed,
+				fmt.Sprintf(docPath, "4.20", "ingress"): page,
+			})
+			c.SetVersions([]string{"4.18", "4.19", "4.20"})
+			c.SetSlugMap(&SlugMap{Renames: []SlugRename{
+				{Document: "networking", From: "ingress-operator", To: "ingress", Since: "4.20"},
+			}})
+			docURL, err := parser.ParseOCPDocURL("https://docs.redhat.com" + fmt.Sprintf(docPath, "4.16", tt.url))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			v, err := c.CheckVersion(docURL, tt.version)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckVersion() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if want := "https://docs.redhat.com/en/documentation/openshift_container_platform/" + tt.wantURL; v.URL != want {
+				t.Errorf("CheckVersion() URL = %s, want %s", v.URL, want)
+			}
+			if v.Exists != tt.wantExists || v.AnchorExists != tt.wantAnchor || v.RenamedFrom != tt.wantRenamed {
+				t.Errorf("CheckVersion() = exists %v, anchor %v, renamed from %q; want %v, %v, %q",
+					v.Exists, v.AnchorExists, v.RenamedFrom, tt.wantExists, tt.wantAnchor, tt.wantRenamed)
+			}
+			// Only the version asked for is requested, with its renamed
+			// slug or html-single variant
+			if got := c.Stats().PageLookups; v.Version != tt.wantURL[:4] || got > 2 {
+				t.Errorf("CheckVersion() version %s after %d page lookups, want %s", v.Version, got, tt.wantURL[:4])
+			}
+		})
+	}
+}
+
+func TestCheckVersion_Errors(t *testing.T) {
+	docURL, err := parser.ParseOCPDocURL("https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html/networking/ingress#a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A page that got no answer is an error, reported in the result too
+	c := newHandlerChecker(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	if err := c.SetRetryPolicy(RetryPolicy{MaxAttempts: 2}); err != nil {
+		t.Fatal(err)
+	}
+	v, err := c.CheckVersion(docURL, "4.17")
+	var reqErr *RequestError
+	if !errors.As(err, &reqErr) || reqErr.Version != "4.17" || reqErr.Attempts != 2 || v.Error != err {
+		t.Errorf("CheckVersion() = %+v, %v; want a RequestError for 4.17 after 2 attempts", v, err)
+	}
+
+	c = newHandlerChecker(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.CheckVersionContext(ctx, docURL, "4.17"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("CheckVersionContext() error = %v, want context.DeadlineExceeded", err)
+	}
+}
@@ -0,0 +1,98 @@
+package checker
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// DefaultDeprecationMarkers are the banner docs.redhat.com shows on the
+// documentation of releases past their end of life: its message, and the
+// class of the element that holds it
+var DefaultDeprecationMarkers = []string{
+	"You are viewing documentation for a release that is no longer maintained",
+	"This documentation is for a release that is no longer maintained",
+	".eol-banner",
+}
+
+// SetDeprecationMarkers sets how to recognize the banner of a release that
+// is no longer maintained, which CheckResult.OriginalDeprecated reports for
+// the version of a URL. A marker starting with "." is the class of an
+// element and one starting with "#" its id, so that a reworded banner is
+// still found; any other marker is text matched like soft-404 markers. They
+// are looked for in the first 64 KiB of the pages read for an anchor or for
+// soft-404 markers, so pages only requested with HEAD are never found
+// deprecated. nil disables the detection. It is DefaultDeprecationMarkers
+// by default.
+func (c *Checker) SetDeprecationMarkers(markers []string) {
+	c.deprecationMarkers = nil
+	for _, m := range markers {
+		if !strings.HasPrefix(m, ".") && !strings.HasPrefix(m, "#") {
+			m = normalizeText(m)
+		}
+		if len(strings.TrimLeft(m, ".#")) > 0 {
+			c.deprecationMarkers = append(c.deprecationMarkers, m)
+		}
+	}
+}
+
+// deprecationMarker returns the first marker of SetDeprecationMarkers found
+// in prefix, the start of a page, or "" when it shows none
+func (c *Checker) deprecationMarker(prefix []byte) string {
+	if len(c.deprecationMarkers) == 0 {
+		return ""
+	}
+	classes, ids := pageSelectors(prefix)
+	text := normalizeText(pageText(prefix))
+	for _, m := range c.deprecationMarkers {
+		switch {
+		case strings.HasPrefix(m, "."):
+			if classes[m[1:]] {
+				return m
+			}
+		case strings.HasPrefix(m, "#"):
+			if ids[m[1:]] {
+				return m
+			}
+		case strings.Contains(text, m):
+			return m
+		}
+	}
+	return ""
+}
+
+// pageSelectors returns the classes and ids of the elements of an HTML
+// page, which may be cut anywhere
+func pageSelectors(page []byte) (classes, ids map[string]bool) {
+	classes, ids = make(map[string]bool), make(map[string]bool)
+	z := html.NewTokenizer(bytes.NewReader(page))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return classes, ids
+		case html.StartTagToken, html.SelfClosingTagToken:
+			_, hasAttr := z.TagName()
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = z.TagAttr()
+				switch string(key) {
+				case "class":
+					for _, class := range strings.Fields(string(val)) {
+						classes[class] = true
+					}
+				case "id":
+					ids[string(val)] = true
+				}
+			}
+		}
+	}
+}
+
+// markDeprecated records on the facts of a page the deprecation marker
+// prefix, the start of the page, shows
+func (c *Checker) markDeprecated(facts *PageFacts, prefix []byte) {
+	if facts.Exists {
+		facts.DeprecationMarker = c.deprecationMarker(prefix)
+	}
+}
@@ -0,0 +1,81 @@
+package checker
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestCheck_OriginalDeprecated(t *testing.T) {
+	const docPath = "/en/documentation/openshift_container_platform/%s/html/networking/ingress"
+	banner := `<html><head><title>Networking | Red Hat Documentation</title></head><body>` +
+		`<div class="rh-alert eol-banner"><p>You are viewing documentation for a release that is
+		no longer maintained. See the latest version.</p></div><h2 id="ingress">Ingress</h2></body></html>`
+	reworded := `<html><body><div class="eol-banner">Unsupported release</div><h2 id="ingress">Ingress</h2></body></html>`
+	custom := `<html><body><aside id="lifecycle-notice">Past end of life</aside><h2 id="ingress">Ingress</h2></body></html>`
+	current := `<html><body><h2 id="ingress">Ingress</h2><p>The 4.14 release is no longer maintained.</p></body></html>`
+
+	tests := []struct {
+		name     string
+		original string
+		markers  []string // nil keeps the defaults
+		fragment string
+		want     bool
+	}{
+		{name: "banner text and class", original: banner, fragment: "#ingress", want: true},
+		{name: "page without anchor", original: banner, want: true},
+		{name: "reworded banner found by its class", original: reworded, fragment: "#ingress", want: true},
+		{name: "maintained release", original: current, fragment: "#ingress"},
+		{name: "no banner by default", original: custom, fragment: "#ingress"},
+		{name: "added id marker", original: custom, markers: append(slices.Clone(DefaultDeprecationMarkers), "#lifecycle-notice"), fragment: "#ingress", want: true},
+		{name: "detection disabled", original: banner, markers: []string{}, fragment: "#ingress"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeDocsChecker(t, map[string]string{
+				fmt.Sprintf(docPath, "4.16"): tt.original,
+				fmt.Sprintf(docPath, "4.17"): current,
+			})
+			c.SetVersions([]string{"4.16", "4.17"})
+			if tt.markers != nil {
+				c.SetDeprecationMarkers(tt.markers)
+			}
+
+			result, err := c.Check("https://docs.redhat.com" + fmt.Sprintf(docPath, "4.16") + tt.fragment)
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if result.OriginalDeprecated != tt.want {
+				t.Errorf("OriginalDeprecated = %v, want %v", result.OriginalDeprecated, tt.want)
+			}
+			// Only the version of the URL is reported
+			if !result.IsOutdated || result.AllResults[0].DeprecationMarker != "" {
+				t.Errorf("Check() = outdated %v, 4.17 marker %q; want outdated and no marker", result.IsOutdated, result.AllResults[0].DeprecationMarker)
+			}
+		})
+	}
+}
+
+func TestDeprecationMarker(t *testing.T) {
+	c := NewChecker()
+	c.SetDeprecationMarkers([]string{"  Release NO longer\nmaintained ", ".eol", "#eol-notice", ".", ""})
+
+	tests := []struct {
+		page string
+		want string
+	}{
+		{`<p>This release no longer maintained.</p>`, "release no longer maintained"},
+		{`<div class="banner eol"></div>`, ".eol"},
+		{`<div class="eol-banner"></div>`, ""},
+		{`<section id="eol-notice"/>`, "#eol-notice"},
+		// Scripts may quote the banner on every page
+		{`<script>const eol = "release no longer maintained";</script>`, ""},
+		{`<div class="eo`, ""},
+	}
+	for _, tt := range tests {
+		if got := c.deprecationMarker([]byte(tt.page)); got != tt.want {
+			t.Errorf("deprecationMarker(%q) = %q, want %q", tt.page, got, tt.want)
+		}
+	}
+}
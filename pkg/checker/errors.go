@@ -0,0 +1,64 @@
+package checker
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/parser"
+)
+
+// ErrNotOCPDocURL is returned by Check for a URL that is not an OCP
+// documentation URL
+var ErrNotOCPDocURL = parser.ErrNotOCPDocURL
+
+// ErrAllVersionsFailed is returned by Check when the request for every
+// newer version failed, e.g. with the network down, so whether the URL is
+// outdated is unknown. The error also wraps the RequestError of the first
+// version.
+var ErrAllVersionsFailed = errors.New("every newer version failed to be requested")
+
+// RequestError is a request for a page that got no usable answer, such as
+// a timeout, a refused host or an unexpected status. It is the Error of a
+// VersionCheckResult; errors.Is and errors.As see through it to Err.
+type RequestError struct {
+	// Version is the version checked, empty outside of Check
+	Version string
+	URL     string
+	// StatusCode is the status answered, 0 when there was no answer
+	StatusCode int
+	// Attempts and Duration are the requests made before giving up and
+	// the time they took, retries and their backoff included
+	Attempts int
+	Duration time.Duration
+	Err      error
+}
+
+func (e *RequestError) Error() string {
+	where := e.URL
+	if e.Version != "" {
+		where = fmt.Sprintf("version %s (%s)", e.Version, e.URL)
+	}
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%s: status %d: %v", where, e.StatusCode, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", where, e.Err)
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// allFailed returns ErrAllVersionsFailed when there are results and every
+// one of them failed, nil otherwise
+func allFailed(results []VersionCheckResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	for _, v := range results {
+		if v.Error == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %w", ErrAllVersionsFailed, results[0].Error)
+}
@@ -0,0 +1,132 @@
+package checker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// roundTripFunc answers requests without a server
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestCheck_Errors(t *testing.T) {
+	const pagePath = "/en/documentation/openshift_container_platform/%s/html/networking/ingress"
+	docURL := "https://docs.redhat.com" + fmt.Sprintf(pagePath, "4.16") + "#ingress"
+	// Requests are made for pages, without the fragment
+	pageURL := "https://docs.redhat.com" + fmt.Sprintf(pagePath, "4.17")
+
+	tests := []struct {
+		name      string
+		url       string
+		handler   http.HandlerFunc
+		transport http.RoundTripper
+		wantIs    []error
+		// wantRequest is the RequestError expected in the error, if any
+		wantRequest *RequestError
+	}{
+		{
+			name:   "not a documentation URL",
+			url:    "https://example.com/docs",
+			wantIs: []error{ErrNotOCPDocURL},
+		},
+		{
+			name: "no answer",
+			url:  docURL,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				panic(http.ErrAbortHandler)
+			},
+			wantIs:      []error{ErrAllVersionsFailed},
+			wantRequest: &RequestError{Version: "4.17", URL: pageURL, Attempts: 1},
+		},
+		{
+			name: "refused by egress policy",
+			url:  docURL,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "https://elsewhere.example.com/", http.StatusFound)
+			},
+			wantIs:      []error{ErrAllVersionsFailed, ErrHostNotAllowed},
+			wantRequest: &RequestError{Version: "4.17", URL: pageURL, Attempts: 1},
+		},
+		{
+			name: "unexpected status",
+			url:  docURL,
+			transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: 199, Header: make(http.Header), Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+			}),
+			wantIs:      []error{ErrAllVersionsFailed},
+			wantRequest: &RequestError{Version: "4.17", URL: pageURL, StatusCode: 199, Attempts: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newHandlerChecker(t, tt.handler)
+			if tt.transport != nil {
+				c.SetTransport(tt.transport)
+			}
+			c.SetVersions([]string{"4.16", "4.17", "4.18"})
+			c.SetFormatFallback(false)
+			if err := c.SetRetryPolicy(RetryPolicy{MaxAttempts: 1}); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := c.Check(tt.url)
+			if err == nil {
+				t.Fatalf("Check() = %+v, want an error", result)
+			}
+			for _, want := range tt.wantIs {
+				if !errors.Is(err, want) {
+					t.Errorf("Check() error = %v, want errors.Is %v", err, want)
+				}
+			}
+			var reqErr *RequestError
+			if tt.wantRequest == nil {
+				if errors.As(err, &reqErr) {
+					t.Errorf("Check() error = %v, want no RequestError", err)
+				}
+				return
+			}
+			if !errors.As(err, &reqErr) {
+				t.Fatalf("Check() error = %v, want a RequestError", err)
+			}
+			if reqErr.Version != tt.wantRequest.Version || reqErr.URL != tt.wantRequest.URL || reqErr.StatusCode != tt.wantRequest.StatusCode || reqErr.Attempts != tt.wantRequest.Attempts || reqErr.Err == nil {
+				t.Errorf("RequestError = %+v, want %+v", reqErr, tt.wantRequest)
+			}
+		})
+	}
+}
+
+func TestCheck_SomeVersionsFail(t *testing.T) {
+	// An answer from any version gives a verdict; the failed versions
+	// carry their RequestError
+	c := newHandlerChecker(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/4.18/") {
+			panic(http.ErrAbortHandler)
+		}
+		w.Write([]byte(`<html><body><h2 id="ingress">Ingress</h2></body></html>`))
+	}))
+	c.SetVersions([]string{"4.16", "4.17", "4.18"})
+	c.SetFormatFallback(false)
+	if err := c.SetRetryPolicy(RetryPolicy{MaxAttempts: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := c.Check("https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html/networking/ingress#ingress")
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if result.LatestVersion != "4.17" {
+		t.Errorf("LatestVersion = %s, want 4.17", result.LatestVersion)
+	}
+	var reqErr *RequestError
+	if failed := result.AllResults[1]; !errors.As(failed.Error, &reqErr) || reqErr.Version != "4.18" {
+		t.Errorf("4.18 error = %v, want a RequestError for 4.18", failed.Error)
+	}
+}
@@ -0,0 +1,97 @@
+package checker
+
+import (
+	"maps"
+	"slices"
+)
+
+// Heuristic identifies logic beyond requesting each newer version of a URL
+// as written that altered, or could have altered, a verdict. The values are
+// stable, for support tooling to match on.
+type Heuristic string
+
+const (
+	// HeuristicFragmentDedup checked a duplicated fragment, e.g.
+	// #a#a, as its single spelling
+	HeuristicFragmentDedup Heuristic = "fragment-dedup"
+	// HeuristicVersionCanonical checked a version not written as
+	// major.minor, e.g. 4.17.0, as its canonical spelling
+	HeuristicVersionCanonical Heuristic = "version-canonical"
+	// HeuristicAsOf capped the versions checked with SetAsOf
+	HeuristicAsOf Heuristic = "as-of"
+	// HeuristicSlugMap tried a renamed page slug from the slug map for a
+	// version missing the page
+	HeuristicSlugMap Heuristic = "slug-map"
+	// HeuristicFormatFallback looked for a missing anchor on the
+	// html-single variant of the guide
+	HeuristicFormatFallback Heuristic = "format-fallback"
+	// HeuristicSiblingPage looked for an anchor missing from its page on
+	// the other pages of the guide, set with SetSiblingSearch
+	HeuristicSiblingPage Heuristic = "sibling-page"
+	// HeuristicParseBudget left an anchor indeterminate when the parse
+	// budget of a page ran out before it was found
+	HeuristicParseBudget Heuristic = "parse-budget"
+	// HeuristicHeadFallback sent GET for a page after HEAD failed or was
+	// refused
+	HeuristicHeadFallback Heuristic = "head-fallback"
+	// HeuristicRedirect found a page redirecting to another page, guide or
+	// no documentation page, and counted it missing unless
+	// SetAcceptRedirects accepts redirects
+	HeuristicRedirect Heuristic = "redirect"
+	// HeuristicCrossDocument followed a redirect of a page to another guide
+	HeuristicCrossDocument Heuristic = "cross-document"
+	// HeuristicSoft404 counted a page answered with 2xx as missing because
+	// it shows a not-found message of SetSoft404Markers
+	HeuristicSoft404 Heuristic = "soft-404"
+	// HeuristicNetworkFallback connected over the other address family
+	// after a connection attempt timed out, with NetworkAuto
+	HeuristicNetworkFallback Heuristic = "network-fallback"
+	// HeuristicImportedFacts answered for a page from an imported matrix or
+	// the disk cache instead of a request
+	HeuristicImportedFacts Heuristic = "imported-facts"
+	// HeuristicVersionRange restricted the versions checked with
+	// SetVersionRange
+	HeuristicVersionRange Heuristic = "version-range"
+	// HeuristicExcludedVersions skipped the versions excluded with
+	// ExcludeVersions
+	HeuristicExcludedVersions Heuristic = "excluded-versions"
+	// HeuristicTargetPolicy excluded a working version that is not an
+	// allowed upgrade target
+	HeuristicTargetPolicy Heuristic = "target-policy"
+	// HeuristicPageOnlyAnchor counted a newer version whose page lacks the
+	// anchor, under AnchorPolicyPageOnly
+	HeuristicPageOnlyAnchor Heuristic = "page-only-anchor"
+	// HeuristicResultHook ran result hooks registered with
+	// RegisterResultHook
+	HeuristicResultHook Heuristic = "result-hook"
+)
+
+// applyHeuristic records that h was applied, once, keeping the order of
+// first use
+func applyHeuristic(applied *[]Heuristic, h Heuristic) {
+	if !slices.Contains(*applied, h) {
+		*applied = append(*applied, h)
+	}
+}
+
+// countHeuristics adds the heuristics applied to result to the run counts
+func (c *Checker) countHeuristics(result *CheckResult) {
+	if len(result.AppliedHeuristics) == 0 {
+		return
+	}
+	c.heuristicsMu.Lock()
+	defer c.heuristicsMu.Unlock()
+	if c.heuristicCounts == nil {
+		c.heuristicCounts = make(map[Heuristic]int64)
+	}
+	for _, h := range result.AppliedHeuristics {
+		c.heuristicCounts[h]++
+	}
+}
+
+// heuristicStats returns a copy of the run counts of heuristics
+func (c *Checker) heuristicStats() map[Heuristic]int64 {
+	c.heuristicsMu.Lock()
+	defer c.heuristicsMu.Unlock()
+	return maps.Clone(c.heuristicCounts)
+}
@@ -0,0 +1,159 @@
+package checker
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheck_AppliedHeuristics(t *testing.T) {
+	const docPath = "/en/documentation/openshift_container_platform/%s/html/%s/%s"
+	page := `<html><body><h2 id="ingress">Ingress</h2></body></html>`
+	pages := map[string]string{
+		fmt.Sprintf(docPath, "4.17", "networking", "ingress"):                              page,
+		fmt.Sprintf(docPath, "4.18", "networking", "ingress"):                              page,
+		"/en/documentation/openshift_container_platform/4.18/html-single/networking/index": `<html><body><h2 id="sharding">Sharding</h2></body></html>`,
+		fmt.Sprintf(docPath, "4.18", "networking", "refuses-head"):                         page,
+		fmt.Sprintf(docPath, "4.18", "networking", "moved"):                                "redirect:" + fmt.Sprintf(docPath, "4.18", "ingress_and_load_balancing", "ingress"),
+		fmt.Sprintf(docPath, "4.18", "ingress_and_load_balancing", "ingress"):              page,
+	}
+	docURL := func(page string) string {
+		return "https://docs.redhat.com" + fmt.Sprintf(docPath, "4.17", "networking", page)
+	}
+
+	tests := []struct {
+		name  string
+		url   string
+		setup func(*testing.T, *Checker)
+		want  []Heuristic
+	}{
+		{name: "plain URL", url: docURL("ingress") + "#ingress"},
+		{name: "duplicated fragment", url: docURL("ingress") + "#ingress#ingress", want: []Heuristic{HeuristicFragmentDedup}},
+		{name: "non-canonical version", url: strings.Replace(docURL("ingress"), "4.17", "4.17.0", 1), want: []Heuristic{HeuristicVersionCanonical}},
+		{
+			name: "as-of",
+			url:  docURL("ingress"),
+			setup: func(t *testing.T, c *Checker) {
+				if err := c.SetAsOf("4.18"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []Heuristic{HeuristicAsOf},
+		},
+		{
+			name: "slug map",
+			url:  docURL("old-ingress") + "#ingress",
+			setup: func(t *testing.T, c *Checker) {
+				c.SetSlugMap(&SlugMap{Renames: []SlugRename{{Document: "networking", From: "old-ingress", To: "ingress", Since: "4.18"}}})
+			},
+			want: []Heuristic{HeuristicSlugMap},
+		},
+		{name: "format fallback", url: docURL("ingress") + "#sharding", want: []Heuristic{HeuristicFormatFallback}},
+		{
+			name: "HEAD refused",
+			url:  docURL("refuses-head"),
+			setup: func(t *testing.T, c *Checker) {
+				// HEAD is only sent without soft-404 detection
+				c.SetSoft404Markers(nil)
+			},
+			want: []Heuristic{HeuristicHeadFallback},
+		},
+		{name: "redirect", url: docURL("moved") + "#ingress", want: []Heuristic{HeuristicRedirect}},
+		{
+			name: "cross-document redirect",
+			url:  docURL("moved") + "#ingress",
+			setup: func(t *testing.T, c *Checker) {
+				c.SetAcceptRedirects(true)
+			},
+			want: []Heuristic{HeuristicRedirect, HeuristicCrossDocument},
+		},
+		{
+			name: "imported facts",
+			url:  docURL("ingress"),
+			setup: func(t *testing.T, c *Checker) {
+				c.recordPage(&PageFacts{
+					URL:        "https://docs.redhat.com" + fmt.Sprintf(docPath, "4.18", "networking", "ingress"),
+					Method:     http.MethodHead,
+					StatusCode: http.StatusOK,
+					Exists:     true,
+					CheckedAt:  time.Now(),
+					Imported:   true,
+				})
+			},
+			want: []Heuristic{HeuristicImportedFacts},
+		},
+		{
+			name: "target policy",
+			url:  docURL("ingress"),
+			setup: func(t *testing.T, c *Checker) {
+				if err := c.SetAllowedTargets([]string{"4.17"}); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []Heuristic{HeuristicTargetPolicy},
+		},
+		{
+			name: "result hook",
+			url:  docURL("ingress"),
+			setup: func(t *testing.T, c *Checker) {
+				c.RegisterResultHook(func(*CheckResult) error { return nil })
+			},
+			want: []Heuristic{HeuristicResultHook},
+		},
+		{
+			name: "several, in order of use",
+			url:  docURL("old-ingress") + "#ingress#ingress",
+			setup: func(t *testing.T, c *Checker) {
+				c.SetSlugMap(&SlugMap{Renames: []SlugRename{{Document: "networking", From: "old-ingress", To: "ingress", Since: "4.18"}}})
+				c.RegisterResultHook(func(*CheckResult) error { return nil })
+			},
+			want: []Heuristic{HeuristicFragmentDedup, HeuristicSlugMap, HeuristicResultHook},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newHandlerChecker(t, refuseHead(fakeDocsHandler(pages), "refuses-head"))
+			c.SetVersions([]string{"4.17", "4.18"})
+			c.SetSlugMap(&SlugMap{})
+			if tt.setup != nil {
+				tt.setup(t, c)
+			}
+
+			result, err := c.Check(tt.url)
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if !reflect.DeepEqual(result.AppliedHeuristics, tt.want) {
+				t.Errorf("AppliedHeuristics = %v, want %v", result.AppliedHeuristics, tt.want)
+			}
+
+			// The run stats count each heuristic once per result
+			var wantCounts map[Heuristic]int64
+			if len(tt.want) > 0 {
+				wantCounts = make(map[Heuristic]int64)
+			}
+			for _, h := range tt.want {
+				wantCounts[h] = 1
+			}
+			if got := c.Stats().Heuristics; !reflect.DeepEqual(got, wantCounts) {
+				t.Errorf("Stats().Heuristics = %v, want %v", got, wantCounts)
+			}
+		})
+	}
+}
+
+// refuseHead answers HEAD requests for pages whose path ends with suffix
+// with 405 Method Not Allowed, and passes the others to next
+func refuseHead(next http.Handler, suffix string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead && strings.HasSuffix(r.URL.Path, suffix) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,54 @@
+package checker
+
+import "fmt"
+
+// ResultHook adjusts the verdict of a check, e.g. to apply rules of an
+// organization. It may change NewerVersions, LatestVersion and IsOutdated,
+// keeping them consistent with each other, and append to Notes. A returned
+// error does not fail the check.
+type ResultHook func(*CheckResult) error
+
+// RegisterResultHook adds a hook that Check runs on every result after its
+// verdict is assembled and before it is returned.
+//
+// Hooks run one after the other in registration order, each seeing the
+// changes of the hooks before it. When a hook returns an error or panics,
+// the result is marked LowConfidence, the error is added to its Notes and
+// the remaining hooks still run.
+//
+// Check may be called concurrently, so a hook can run concurrently for
+// different results, but never for the same result; hooks that share state
+// across results must synchronize it. Hooks may be registered while checks
+// are running: a check uses the hooks registered when its hooks start.
+func (c *Checker) RegisterResultHook(hook ResultHook) {
+	c.hooksMu.Lock()
+	defer c.hooksMu.Unlock()
+	c.hooks = append(c.hooks, hook)
+}
+
+// runHooks runs the registered hooks on result
+func (c *Checker) runHooks(result *CheckResult) {
+	c.hooksMu.Lock()
+	hooks := c.hooks
+	c.hooksMu.Unlock()
+
+	if len(hooks) > 0 {
+		applyHeuristic(&result.AppliedHeuristics, HeuristicResultHook)
+	}
+	for i, hook := range hooks {
+		if err := runHook(hook, result); err != nil {
+			result.LowConfidence = true
+			result.Notes = append(result.Notes, fmt.Sprintf("result hook %d failed: %v", i+1, err))
+		}
+	}
+}
+
+// runHook runs a single hook, turning a panic into an error
+func runHook(hook ResultHook, result *CheckResult) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return hook(result)
+}
@@ -0,0 +1,146 @@
+package checker
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/parser"
+)
+
+// capLatest returns a hook that drops suggestions newer than max
+func capLatest(max string) ResultHook {
+	limit := &parser.OCPDocURL{Version: max}
+	_ = parseVersionInPlace(limit)
+	return func(result *CheckResult) error {
+		var kept []VersionCheckResult
+		for _, v := range result.NewerVersions {
+			version := &parser.OCPDocURL{Version: v.Version}
+			if err := parseVersionInPlace(version); err == nil && version.GetVersionFloat() <= limit.GetVersionFloat() {
+				kept = append(kept, v)
+			}
+		}
+		if len(kept) < len(result.NewerVersions) {
+			result.Notes = append(result.Notes, "capped at "+max)
+		}
+		result.NewerVersions = kept
+		result.IsOutdated = len(kept) > 0
+		result.LatestVersion = result.OriginalVersion
+		if best, ok := result.BestSuggestion(); ok {
+			result.LatestVersion = best.Version
+		}
+		return nil
+	}
+}
+
+// newHookChecker returns a Checker for the networking guide, which exists
+// from 4.16 to 4.19
+func newHookChecker(t *testing.T) *Checker {
+	t.Helper()
+	pages := make(map[string]string)
+	for _, v := range []string{"4.16", "4.17", "4.18", "4.19"} {
+		pages["/en/documentation/openshift_container_platform/"+v+"/html/networking/index"] = "<html></html>"
+	}
+	c := newFakeDocsChecker(t, pages)
+	c.SetVersions([]string{"4.16", "4.17", "4.18", "4.19"})
+	return c
+}
+
+const hookURL = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html/networking/index"
+
+func TestRegisterResultHook_CapsLatest(t *testing.T) {
+	c := newHookChecker(t)
+	c.RegisterResultHook(capLatest("4.17"))
+
+	result, err := c.Check(hookURL)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if !result.IsOutdated || result.LatestVersion != "4.17" || len(result.NewerVersions) != 1 {
+		t.Errorf("Check() = outdated %v, latest %s, %d newer versions; want outdated at 4.17 with 1",
+			result.IsOutdated, result.LatestVersion, len(result.NewerVersions))
+	}
+	if !reflect.DeepEqual(result.Notes, []string{"capped at 4.17"}) || result.LowConfidence {
+		t.Errorf("Check() notes = %v, low confidence = %v", result.Notes, result.LowConfidence)
+	}
+
+	// Capping below the original version leaves nothing to suggest
+	c = newHookChecker(t)
+	c.RegisterResultHook(capLatest("4.16"))
+	result, err = c.Check(hookURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.IsOutdated || result.LatestVersion != "4.16" {
+		t.Errorf("Check() = outdated %v, latest %s; want up to date at 4.16", result.IsOutdated, result.LatestVersion)
+	}
+}
+
+func TestRegisterResultHook_OrderAndErrors(t *testing.T) {
+	c := newHookChecker(t)
+	c.RegisterResultHook(func(r *CheckResult) error {
+		r.Notes = append(r.Notes, "first saw "+r.LatestVersion)
+		r.LatestVersion = "4.18"
+		return nil
+	})
+	c.RegisterResultHook(func(r *CheckResult) error {
+		return errors.New("quota service unavailable")
+	})
+	c.RegisterResultHook(func(r *CheckResult) error {
+		panic("bad rule")
+	})
+	c.RegisterResultHook(func(r *CheckResult) error {
+		r.Notes = append(r.Notes, "last saw "+r.LatestVersion)
+		return nil
+	})
+
+	result, err := c.Check(hookURL)
+	if err != nil {
+		t.Fatalf("Check() error = %v, want hook errors not to fail the check", err)
+	}
+
+	want := []string{
+		"first saw 4.19",
+		"result hook 2 failed: quota service unavailable",
+		"result hook 3 failed: panic: bad rule",
+		"last saw 4.18",
+	}
+	if !reflect.DeepEqual(result.Notes, want) {
+		t.Errorf("Notes =\n%q\nwant\n%q", result.Notes, want)
+	}
+	if !result.LowConfidence {
+		t.Error("LowConfidence = false after a failing hook")
+	}
+}
+
+func TestRegisterResultHook_Concurrent(t *testing.T) {
+	c := newHookChecker(t)
+
+	var mu sync.Mutex
+	seen := 0
+	c.RegisterResultHook(func(r *CheckResult) error {
+		mu.Lock()
+		defer mu.Unlock()
+		seen++
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Registering while checks run must be safe too
+			c.RegisterResultHook(func(r *CheckResult) error { return nil })
+			if _, err := c.Check(hookURL); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if seen != 8 {
+		t.Errorf("hook ran %d times, want 8", seen)
+	}
+}
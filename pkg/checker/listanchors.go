@@ -0,0 +1,122 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/anchors"
+	"github.com/sebrandon1/ocp-doc-checker/pkg/parser"
+)
+
+// ErrPageNotFound is returned by ListAnchors for a page that does not
+// exist: answered with 4xx or 5xx, with a soft-404 marker, or redirected to
+// another page while redirects are not accepted
+var ErrPageNotFound = errors.New("page not found")
+
+// ErrPageTruncated is returned by ListAnchors for a page whose parse budget
+// ran out before its end, see SetMaxPageParseTime
+var ErrPageTruncated = errors.New("page parse budget exceeded before the end of the page")
+
+// Anchor is an anchor of a documentation page, as listed by ListAnchors
+type Anchor struct {
+	// ID is the value of the id attribute, or the name of an <a>
+	ID string
+	// Tag is the name of the element, e.g. "section", "h2" or "a"
+	Tag string
+	// Heading is the text of an h1 to h6 element with runs of whitespace
+	// collapsed, empty for other elements
+	Heading string
+}
+
+// ListAnchors returns the anchors of the page of an OCP documentation URL,
+// html or html-single, in document order and each id once; the fragment of
+// the URL is ignored. The page is requested like the pages of Check, with
+// its retries, timeouts and parse budget, but always anew: the facts Check
+// keeps of a page do not hold its headings.
+func (c *Checker) ListAnchors(rawURL string) ([]Anchor, error) {
+	return c.ListAnchorsContext(context.Background(), rawURL)
+}
+
+// ListAnchorsContext is ListAnchors, giving up once ctx is done
+func (c *Checker) ListAnchorsContext(ctx context.Context, rawURL string) ([]Anchor, error) {
+	if _, err := parser.ParseOCPDocURL(rawURL); err != nil {
+		return nil, err
+	}
+	pageURL, _, _ := strings.Cut(rawURL, "#")
+
+	var list []Anchor
+	_, err := c.withRetries(ctx, pageURL, func(ctx context.Context) error {
+		var err error
+		list, err = c.requestAnchors(ctx, pageURL)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// requestAnchors makes a single attempt at listing the anchors of a page
+func (c *Checker) requestAnchors(ctx context.Context, pageURL string) ([]Anchor, error) {
+	resp, err := c.do(ctx, http.MethodGet, pageURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// 4xx or 5xx - page doesn't exist, no point retrying, as for Check
+	if resp.StatusCode >= 400 {
+		return nil, &RequestError{URL: pageURL, StatusCode: resp.StatusCode, Err: permanentError{ErrPageNotFound}}
+	}
+	if resp.StatusCode < 200 {
+		return nil, &RequestError{URL: pageURL, StatusCode: resp.StatusCode, Err: errUnexpectedStatus}
+	}
+	if target, _ := redirectTarget(pageURL, resp.Request.URL.String()); target != "" && !c.acceptRedirects {
+		return nil, &RequestError{URL: pageURL, StatusCode: resp.StatusCode, Err: permanentError{fmt.Errorf("%w: redirects to %s", ErrPageNotFound, target)}}
+	}
+
+	var r io.Reader = resp.Body
+	if c.maxParseTime > 0 {
+		r = &budgetReader{r: r, now: c.now, deadline: c.now().Add(c.maxParseTime)}
+	}
+	prefix := &prefixWriter{max: soft404ScanBytes}
+	found, err := anchors.ExtractIDs(io.TeeReader(r, prefix))
+	if errors.Is(err, errParseBudget) {
+		c.logger.WarnContext(ctx, "page parse budget exceeded", "url", pageURL, "budget", c.maxParseTime)
+		return nil, &RequestError{URL: pageURL, StatusCode: resp.StatusCode, Err: permanentError{ErrPageTruncated}}
+	}
+	if err != nil {
+		c.logger.WarnContext(ctx, "failed to parse page", "url", pageURL, "error", err)
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	if marker := c.soft404Marker(prefix.b); marker != "" {
+		return nil, &RequestError{URL: pageURL, StatusCode: resp.StatusCode, Err: permanentError{fmt.Errorf("%w: shows %q", ErrPageNotFound, marker)}}
+	}
+
+	var list []Anchor
+	seen := make(map[string]bool)
+	for _, a := range found {
+		if seen[a.ID] {
+			continue
+		}
+		seen[a.ID] = true
+		anchor := Anchor{ID: a.ID, Tag: a.Element}
+		if anchors.IsHeading(a.Element) {
+			anchor.Heading = a.Text
+		}
+		list = append(list, anchor)
+	}
+	return list, nil
+}
+
+// permanentError is an answer that retrying would not change
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
@@ -0,0 +1,131 @@
+package checker
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestListAnchors(t *testing.T) {
+	const docPath = "/en/documentation/openshift_container_platform/4.17/%s/networking/%s"
+	pages := map[string]string{
+		fmt.Sprintf(docPath, "html", "ingress"): `<html><head><title>Ingress | Networking</title></head><body>
+<section id="configuring-ingress"><h2 id="configuring-ingress-heading">Configuring
+   <em>ingress</em></h2>
+<p><a name="legacy-anchor"></a>Text.</p>
+<div id="configuring-ingress">Duplicate id</div>
+<h7 id="not-a-heading">No</h7>
+</section></body></html>`,
+		fmt.Sprintf(docPath, "html-single", "index"): `<html><body>
+<h1 id="networking">Networking</h1>
+<section id="ingress"><h3 id="ingress-sharding">Ingress sharding</h3></section>
+</body></html>`,
+		fmt.Sprintf(docPath, "html", "retired"):               `<html><body><h1 id="oops">The page you requested could not be found</h1></body></html>`,
+		fmt.Sprintf(docPath, "html", "moved"):                 "redirect:/en/documentation/openshift_container_platform/4.17",
+		"/en/documentation/openshift_container_platform/4.17": `<html><body><h1 id="landing">OpenShift</h1></body></html>`,
+	}
+	urlOf := func(format, page string) string {
+		return "https://docs.redhat.com" + fmt.Sprintf(docPath, format, page)
+	}
+
+	tests := []struct {
+		name    string
+		url     string
+		want    []Anchor
+		wantErr error
+	}{
+		{
+			name: "html page",
+			// The fragment names no anchor to look for
+			url: urlOf("html", "ingress") + "#configuring-ingress",
+			want: []Anchor{
+				{ID: "configuring-ingress", Tag: "section"},
+				{ID: "configuring-ingress-heading", Tag: "h2", Heading: "Configuring ingress"},
+				{ID: "legacy-anchor", Tag: "a"},
+				{ID: "not-a-heading", Tag: "h7"},
+			},
+		},
+		{
+			name: "html-single guide",
+			url:  urlOf("html-single", "index"),
+			want: []Anchor{
+				{ID: "networking", Tag: "h1", Heading: "Networking"},
+				{ID: "ingress", Tag: "section"},
+				{ID: "ingress-sharding", Tag: "h3", Heading: "Ingress sharding"},
+			},
+		},
+		{name: "missing page", url: urlOf("html", "removed"), wantErr: ErrPageNotFound},
+		{name: "soft 404", url: urlOf("html", "retired"), wantErr: ErrPageNotFound},
+		{name: "redirect to the landing page", url: urlOf("html", "moved"), wantErr: ErrPageNotFound},
+		{name: "not a documentation URL", url: "https://docs.redhat.com/en/products", wantErr: ErrNotOCPDocURL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeDocsChecker(t, pages)
+			got, err := c.ListAnchors(tt.url)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("ListAnchors() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ListAnchors() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListAnchors() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListAnchors_Retries(t *testing.T) {
+	const pageURL = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.17/html/networking/index"
+
+	c, requests := newFlakyChecker(t, 2)
+	if err := c.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.ListAnchors(pageURL)
+	if err != nil || len(got) != 1 || got[0].ID != "ingress" {
+		t.Errorf("ListAnchors() = %+v, %v; want the anchor once retried", got, err)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("%d requests made, want 3", n)
+	}
+
+	// A missing page is an answer, not a failure to retry
+	c = newFakeDocsChecker(t, nil)
+	if err := c.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.ListAnchors(pageURL)
+	var reqErr *RequestError
+	if !errors.As(err, &reqErr) || reqErr.Attempts != 1 || reqErr.StatusCode != 404 {
+		t.Errorf("ListAnchors() error = %#v, want a 404 after a single attempt", err)
+	}
+}
+
+func TestListAnchors_ParseBudget(t *testing.T) {
+	const singlePath = "/en/documentation/openshift_container_platform/4.17/html-single/networking/index"
+	c := newFakeDocsChecker(t, map[string]string{singlePath: hugeGuide("ingress-overview", "ingress-sharding")})
+	c.now = tickingClock(time.Millisecond)
+	c.SetMaxPageParseTime(50 * time.Millisecond)
+
+	// A partial list would pass for the page's anchors
+	if _, err := c.ListAnchors("https://docs.redhat.com" + singlePath); !errors.Is(err, ErrPageTruncated) {
+		t.Errorf("ListAnchors() error = %v, want %v", err, ErrPageTruncated)
+	}
+
+	c.SetMaxPageParseTime(0)
+	got, err := c.ListAnchors("https://docs.redhat.com" + singlePath)
+	if err != nil {
+		t.Fatalf("ListAnchors() without a budget error = %v", err)
+	}
+	if last := got[len(got)-1]; last.ID != "ingress-sharding" {
+		t.Errorf("ListAnchors() ends with %+v, want the anchor at the end of the page", last)
+	}
+}
@@ -0,0 +1,53 @@
+package checker
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// SetLogger sets the logger the checker reports its work to: every HTTP
+// request at debug level, with its URL, method, status, duration, attempt
+// and address family, and retries, connections that timed out over one
+// family and pages that cannot be parsed at warn level. A
+// nil logger, the default, logs nothing. Call it before checking URLs.
+func (c *Checker) SetLogger(l *slog.Logger) {
+	if l == nil {
+		l = slog.New(slog.DiscardHandler)
+	}
+	c.logger = l
+}
+
+// attemptKey carries the attempt number of the requests for a page, from 1,
+// in their context
+type attemptKey struct{}
+
+// withAttempt returns ctx for the requests of attempt n at a page
+func withAttempt(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, n)
+}
+
+// logRequest logs a request sent by do at debug level
+func (c *Checker) logRequest(ctx context.Context, method, url string, network Network, resp *http.Response, err error, d time.Duration) {
+	if !c.logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("url", url),
+		slog.String("method", method),
+		slog.Duration("duration", d),
+	}
+	if attempt, ok := ctx.Value(attemptKey{}).(int); ok {
+		attrs = append(attrs, slog.Int("attempt", attempt))
+	}
+	if network != "" {
+		attrs = append(attrs, slog.String("network", string(network)))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	} else {
+		attrs = append(attrs, slog.Int("status", resp.StatusCode))
+	}
+	c.logger.LogAttrs(ctx, slog.LevelDebug, "request", attrs...)
+}
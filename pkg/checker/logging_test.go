@@ -0,0 +1,82 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetLogger(t *testing.T) {
+	const pageURL = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.17/html/networking/index"
+
+	tests := []struct {
+		name  string
+		level slog.Level
+		want  []string
+	}{
+		{
+			name:  "debug",
+			level: slog.LevelDebug,
+			want: []string{
+				"DEBUG request GET attempt=1 status=0",
+				"WARN retrying request attempt=2",
+				"DEBUG request GET attempt=2 status=200",
+			},
+		},
+		{
+			name:  "warn",
+			level: slog.LevelWarn,
+			want:  []string{"WARN retrying request attempt=2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newFlakyChecker(t, 1)
+			if err := c.SetRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}); err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			c.SetLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: tt.level})))
+
+			if _, err := c.CheckURLOnce(context.Background(), pageURL+"#ingress"); err != nil {
+				t.Fatalf("CheckURLOnce() error = %v", err)
+			}
+
+			var got []string
+			for line := range strings.Lines(buf.String()) {
+				var entry struct {
+					Level, Msg, URL, Method string
+					Attempt, Status         int
+					Duration                int64
+				}
+				if err := json.Unmarshal([]byte(line), &entry); err != nil {
+					t.Fatalf("log line %q: %v", line, err)
+				}
+				if entry.URL != pageURL {
+					t.Errorf("log line %q: url = %q, want %q", line, entry.URL, pageURL)
+				}
+				logged := fmt.Sprintf("%s %s attempt=%d", entry.Level, entry.Msg, entry.Attempt)
+				if entry.Msg == "request" {
+					logged = fmt.Sprintf("%s %s %s attempt=%d status=%d", entry.Level, entry.Msg, entry.Method, entry.Attempt, entry.Status)
+				}
+				got = append(got, logged)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("logged:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+
+	// A nil logger restores the default, which logs nothing
+	c, _ := newFlakyChecker(t, 0)
+	c.SetLogger(nil)
+	if _, err := c.CheckURLOnce(context.Background(), pageURL); err != nil {
+		t.Fatalf("CheckURLOnce() error = %v", err)
+	}
+}
@@ -0,0 +1,329 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/parser"
+)
+
+// MatrixSchema is the version of the matrix format written by ExportMatrix
+const MatrixSchema = 1
+
+// Matrix records which pages exist at which version and their anchors, as
+// discovered by a run. It does not depend on the repository that referenced
+// the pages, so one run can seed the page facts of another.
+type Matrix struct {
+	Schema      int          `json:"schema"`
+	GeneratedAt time.Time    `json:"generated_at"`
+	Pages       []MatrixPage `json:"pages"`
+}
+
+// MatrixPage holds the facts of one page. Document, Page and Version are
+// informational; URL identifies the page.
+type MatrixPage struct {
+	URL string `json:"url"` // without fragment
+	// FinalURL is the URL that answered after redirects, when it differs
+	FinalURL   string `json:"final_url,omitempty"`
+	Method     string `json:"method,omitempty"`
+	Document   string `json:"document,omitempty"`
+	Page       string `json:"page,omitempty"`
+	Version    string `json:"version,omitempty"`
+	StatusCode int    `json:"status_code"`
+	Exists     bool   `json:"exists"`
+	// NotFoundMarker is the soft-404 marker of a page answered with 2xx
+	// that is counted missing
+	NotFoundMarker string `json:"not_found_marker,omitempty"`
+	// DeprecationMarker is the marker of SetDeprecationMarkers an existing
+	// page shows
+	DeprecationMarker string   `json:"deprecation_marker,omitempty"`
+	Fetched           bool     `json:"fetched"` // AnchorIDs and Title are only known for fetched pages
+	Probed            bool     `json:"probed,omitempty"`
+	AnchorIDs         []string `json:"anchor_ids,omitempty"`
+	// AnchorTexts are the heading texts of the anchors that name one;
+	// absent from matrices written before they were recorded
+	AnchorTexts map[string]string `json:"anchor_texts,omitempty"`
+	Title       string            `json:"title,omitempty"`
+	CheckedAt   time.Time         `json:"checked_at"`
+}
+
+// newMatrixPage returns the matrix entry of the facts of a page
+func newMatrixPage(facts *PageFacts) MatrixPage {
+	page := MatrixPage{
+		URL:               facts.URL,
+		Method:            facts.Method,
+		StatusCode:        facts.StatusCode,
+		Exists:            facts.Exists,
+		NotFoundMarker:    facts.NotFoundMarker,
+		DeprecationMarker: facts.DeprecationMarker,
+		Fetched:           facts.Fetched,
+		Probed:            facts.Probed,
+		AnchorIDs:         facts.AnchorIDs,
+		AnchorTexts:       facts.AnchorTexts,
+		Title:             facts.Title,
+		CheckedAt:         facts.CheckedAt.UTC(),
+	}
+	if facts.FinalURL != facts.URL {
+		page.FinalURL = facts.FinalURL
+	}
+	if facts.Truncated {
+		// Anchors of part of a page would read as missing in a later run
+		page.Fetched, page.AnchorIDs, page.AnchorTexts = false, nil, nil
+	}
+	if docURL, err := parser.ParseOCPDocURL(facts.URL); err == nil {
+		page.Document, page.Page, page.Version = docURL.Document, docURL.Page, docURL.Version
+	}
+	return page
+}
+
+// facts returns the page facts of a matrix entry, marked as imported
+func (page MatrixPage) facts() *PageFacts {
+	finalURL := page.FinalURL
+	if finalURL == "" {
+		finalURL = page.URL
+	}
+	return &PageFacts{
+		URL:               page.URL,
+		Method:            page.Method,
+		FinalURL:          finalURL,
+		StatusCode:        page.StatusCode,
+		Exists:            page.Exists,
+		NotFoundMarker:    page.NotFoundMarker,
+		DeprecationMarker: page.DeprecationMarker,
+		Fetched:           page.Fetched,
+		Probed:            page.Probed,
+		AnchorIDs:         page.AnchorIDs,
+		AnchorTexts:       page.AnchorTexts,
+		Title:             page.Title,
+		Size:              -1,
+		CheckedAt:         page.CheckedAt,
+		Imported:          true,
+	}
+}
+
+// MatrixImport counts the pages read by ImportMatrix
+type MatrixImport struct {
+	// Imported pages seed the checker's page facts
+	Imported int
+	// Stale pages were checked longer than the maximum age ago
+	Stale int
+	// Superseded pages were already known from a fresher check
+	Superseded int
+}
+
+// pageFacts returns the facts of the page at rawURL, requesting it only when
+// no usable facts are known, in memory or in the disk cache: a page with a
+// fragment needs its anchors, so facts from a HEAD request only answer for
+// it when the page is missing.
+// Every anchor of a fetched page is kept, so other anchors on the same page
+// are answered without downloading it again. A lookup of a page already
+// being requested, e.g. by the check of a URL to the same page at another
+// version, waits for that request rather than sending its own.
+func (c *Checker) pageFacts(ctx context.Context, rawURL string) (*PageFacts, error) {
+	baseURL, fragment, _ := strings.Cut(rawURL, "#")
+
+	c.pageLookups.Add(1)
+	for {
+		if facts, ok := c.knownFacts(baseURL, fragment); ok {
+			return facts, nil
+		}
+
+		c.pagesMu.Lock()
+		wait, busy := c.inflight[baseURL]
+		if !busy {
+			c.inflight[baseURL] = make(chan struct{})
+		}
+		c.pagesMu.Unlock()
+		if !busy {
+			break
+		}
+		// The request in flight may not answer this lookup, e.g. a HEAD
+		// request for a lookup that needs the anchors, or may fail; the
+		// facts are looked up again once it is over
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	defer func() {
+		c.pagesMu.Lock()
+		close(c.inflight[baseURL])
+		delete(c.inflight, baseURL)
+		c.pagesMu.Unlock()
+	}()
+
+	facts, err := c.CheckURLOnce(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	c.recordPage(facts)
+	if c.cache != nil && facts.StatusCode < 500 && !facts.Truncated {
+		// The cache only saves requests; a run does not fail for it
+		_ = c.cache.store(facts)
+	}
+	return facts, nil
+}
+
+// knownFacts returns the facts of the page at baseURL when those known, in
+// memory or in the disk cache, answer a lookup of it with fragment
+func (c *Checker) knownFacts(baseURL, fragment string) (*PageFacts, bool) {
+	c.pagesMu.Lock()
+	facts, ok := c.pages[baseURL]
+	refresh := c.refreshImported
+	if ok && c.lru != nil {
+		c.lru.touch(baseURL)
+	}
+	c.pagesMu.Unlock()
+	if ok && answers(facts, fragment) && !(facts.Imported && refresh) {
+		c.pageHits.Add(1)
+		if facts.Imported {
+			c.importedHits.Add(1)
+		}
+		return facts, true
+	}
+
+	if c.cache != nil && !refresh {
+		if facts, ok := c.cache.load(baseURL, time.Now()); ok && answers(facts, fragment) {
+			c.recordPage(facts)
+			c.pageHits.Add(1)
+			c.importedHits.Add(1)
+			c.diskReads.Add(1)
+			return facts, true
+		}
+	}
+	return nil, false
+}
+
+// answers reports whether known facts of a page answer a lookup of it with
+// fragment, without requesting the page again
+func answers(facts *PageFacts, fragment string) bool {
+	return fragment == "" || !facts.Exists || facts.Fetched
+}
+
+// recordPage remembers the facts of a requested page. Server errors are not
+// remembered, since they say nothing about the page, and fetched facts are
+// kept over unfetched ones for a page that still exists.
+func (c *Checker) recordPage(facts *PageFacts) {
+	if facts.StatusCode >= 500 {
+		return
+	}
+
+	c.pagesMu.Lock()
+	if old, ok := c.pages[facts.URL]; ok && old.Fetched && !facts.Fetched && facts.Exists {
+		c.pagesMu.Unlock()
+		return
+	}
+	evicted := c.setPage(facts)
+	c.pagesMu.Unlock()
+	c.spill(evicted)
+}
+
+// RefreshImported makes the checker request every page again instead of
+// using facts loaded with ImportMatrix. Imported facts of pages this run
+// does not use are still exported.
+func (c *Checker) RefreshImported(refresh bool) {
+	c.pagesMu.Lock()
+	defer c.pagesMu.Unlock()
+	c.refreshImported = refresh
+}
+
+// ExportMatrix writes the facts of every page requested or imported so far
+// as a Matrix in JSON
+func (c *Checker) ExportMatrix(w io.Writer) error {
+	c.pagesMu.Lock()
+	m := Matrix{Schema: MatrixSchema, GeneratedAt: time.Now().UTC(), Pages: []MatrixPage{}}
+	for _, facts := range c.pages {
+		m.Pages = append(m.Pages, newMatrixPage(facts))
+	}
+	c.pagesMu.Unlock()
+
+	sort.Slice(m.Pages, func(i, j int) bool {
+		return m.Pages[i].URL < m.Pages[j].URL
+	})
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
+// ExportMatrixFile writes the matrix to path, replacing it atomically
+func (c *Checker) ExportMatrixFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := c.ExportMatrix(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// ImportMatrix seeds the checker's page facts from a matrix written by
+// ExportMatrix, so the pages it lists are not requested again. Pages checked
+// more than maxAge ago are skipped; a maxAge of zero accepts any age.
+// Imported facts never replace facts checked more recently.
+func (c *Checker) ImportMatrix(r io.Reader, maxAge time.Duration) (MatrixImport, error) {
+	var m Matrix
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return MatrixImport{}, fmt.Errorf("failed to decode matrix: %w", err)
+	}
+	if m.Schema != MatrixSchema {
+		return MatrixImport{}, fmt.Errorf("unsupported matrix schema %d (expected %d)", m.Schema, MatrixSchema)
+	}
+
+	now := time.Now()
+	var counts MatrixImport
+
+	var evicted []*PageFacts
+	defer func() { c.spill(evicted) }()
+	c.pagesMu.Lock()
+	defer c.pagesMu.Unlock()
+	for i, page := range m.Pages {
+		if page.URL == "" || strings.Contains(page.URL, "#") {
+			return MatrixImport{}, fmt.Errorf("matrix page %d: url must be set and have no fragment", i)
+		}
+		if maxAge > 0 && now.Sub(page.CheckedAt) > maxAge {
+			counts.Stale++
+			continue
+		}
+		if old, ok := c.pages[page.URL]; ok && !old.CheckedAt.Before(page.CheckedAt) {
+			counts.Superseded++
+			continue
+		}
+
+		evicted = append(evicted, c.setPage(page.facts())...)
+		counts.Imported++
+	}
+
+	return counts, nil
+}
+
+// ImportMatrixFile imports the matrix at path with ImportMatrix
+func (c *Checker) ImportMatrixFile(path string, maxAge time.Duration) (MatrixImport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return MatrixImport{}, err
+	}
+	defer f.Close()
+
+	return c.ImportMatrix(f, maxAge)
+}
@@ -0,0 +1,219 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+const matrixDocPath = "/en/documentation/openshift_container_platform/%s/html/networking/%s"
+
+// newMatrixChecker returns a Checker for a guide with an ingress page from
+// 4.16 and a dns page that was dropped after 4.17
+func newMatrixChecker(t *testing.T) *Checker {
+	t.Helper()
+	page := `<html><head><title>Ingress | Networking | OpenShift Container Platform | 4.19 | Red Hat Documentation</title></head><body><h2 id="configuring-ingress">Ingress</h2><h2 id="ingress-sharding">Sharding</h2></body></html>`
+
+	pages := make(map[string]string)
+	for _, v := range []string{"4.16", "4.17", "4.18", "4.19"} {
+		pages[fmt.Sprintf(matrixDocPath, v, "ingress")] = page
+	}
+	for _, v := range []string{"4.16", "4.17"} {
+		pages[fmt.Sprintf(matrixDocPath, v, "dns")] = "<html></html>"
+	}
+
+	c := newFakeDocsChecker(t, pages)
+	c.SetVersions([]string{"4.16", "4.17", "4.18", "4.19"})
+	return c
+}
+
+var matrixURLs = []string{
+	"https://docs.redhat.com" + fmt.Sprintf(matrixDocPath, "4.16", "ingress") + "#configuring-ingress",
+	"https://docs.redhat.com" + fmt.Sprintf(matrixDocPath, "4.16", "ingress") + "#ingress-sharding",
+	"https://docs.redhat.com" + fmt.Sprintf(matrixDocPath, "4.16", "dns"),
+}
+
+// checkAll checks every URL and returns the latest version of each
+func checkAll(t *testing.T, c *Checker, urls []string) []string {
+	t.Helper()
+	var latest []string
+	for _, u := range urls {
+		result, err := c.Check(u)
+		if err != nil {
+			t.Fatalf("Check(%s) error = %v", u, err)
+		}
+		latest = append(latest, result.LatestVersion)
+	}
+	return latest
+}
+
+func TestMatrix_SecondRunSkipsRequests(t *testing.T) {
+	first := newMatrixChecker(t)
+	want := checkAll(t, first, matrixURLs)
+	// Both anchors share one GET per version of the ingress page, the
+	// original version included
+	if got := first.Requests(); got != 8 {
+		t.Errorf("first run Requests() = %d, want 8", got)
+	}
+
+	var matrix bytes.Buffer
+	if err := first.ExportMatrix(&matrix); err != nil {
+		t.Fatal(err)
+	}
+
+	second := newMatrixChecker(t)
+	counts, err := second.ImportMatrix(bytes.NewReader(matrix.Bytes()), time.Hour)
+	if err != nil {
+		t.Fatalf("ImportMatrix() error = %v", err)
+	}
+	if counts.Imported != 8 {
+		t.Errorf("ImportMatrix() imported %d pages, want 8", counts.Imported)
+	}
+
+	if got := checkAll(t, second, matrixURLs); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("second run latest versions = %v, want %v", got, want)
+	}
+	if got := second.Requests(); got != 0 {
+		t.Errorf("second run Requests() = %d, want 0", got)
+	}
+	if stats := second.Stats(); stats.ImportedHits != stats.PageLookups || stats.CacheHits != stats.PageLookups {
+		t.Errorf("second run Stats() = %+v, want every lookup answered from the imported matrix", stats)
+	}
+
+	result, err := second.Check(matrixURLs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.NewerVersions[0].Cached || result.DocumentTitle != "Networking" {
+		t.Errorf("imported result = cached %v, title %q; want cached with the imported title",
+			result.NewerVersions[0].Cached, result.DocumentTitle)
+	}
+
+	// Refreshing requests every page again
+	third := newMatrixChecker(t)
+	if _, err := third.ImportMatrix(bytes.NewReader(matrix.Bytes()), 0); err != nil {
+		t.Fatal(err)
+	}
+	third.RefreshImported(true)
+	checkAll(t, third, matrixURLs)
+	if got := third.Requests(); got != 8 {
+		t.Errorf("refreshed run Requests() = %d, want 8", got)
+	}
+}
+
+func TestMatrix_HeadOnlyFactsDoNotAnswerAnchors(t *testing.T) {
+	first := newMatrixChecker(t)
+	checkAll(t, first, matrixURLs[2:]) // no fragment, HEAD requests only
+	var matrix bytes.Buffer
+	if err := first.ExportMatrix(&matrix); err != nil {
+		t.Fatal(err)
+	}
+
+	second := newMatrixChecker(t)
+	second.SetFormatFallback(false)
+	if _, err := second.ImportMatrix(&matrix, 0); err != nil {
+		t.Fatal(err)
+	}
+	// The existing dns pages must be fetched for their anchors, the
+	// missing ones are known not to exist
+	checkAll(t, second, []string{"https://docs.redhat.com" + fmt.Sprintf(matrixDocPath, "4.16", "dns") + "#dns-operator"})
+	if got := second.Requests(); got != 2 {
+		t.Errorf("Requests() = %d, want 2", got)
+	}
+}
+
+func TestImportMatrix_Merge(t *testing.T) {
+	const pageURL = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.18/html/networking/ingress"
+	now := time.Now().UTC()
+
+	matrix := func(pages ...MatrixPage) string {
+		b, err := json.Marshal(Matrix{Schema: MatrixSchema, GeneratedAt: now, Pages: pages})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	c := NewChecker()
+	counts, err := c.ImportMatrix(strings.NewReader(matrix(
+		MatrixPage{URL: pageURL, StatusCode: 200, Exists: true, CheckedAt: now.Add(-time.Hour)},
+		MatrixPage{URL: pageURL + "-old", StatusCode: 200, Exists: true, CheckedAt: now.Add(-48 * time.Hour)},
+	)), 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts != (MatrixImport{Imported: 1, Stale: 1}) {
+		t.Errorf("ImportMatrix() = %+v, want 1 imported and 1 stale", counts)
+	}
+
+	// An older fact loses to the one already known, a newer one wins
+	counts, err = c.ImportMatrix(strings.NewReader(matrix(
+		MatrixPage{URL: pageURL, StatusCode: 404, CheckedAt: now.Add(-2 * time.Hour)},
+	)), 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts != (MatrixImport{Superseded: 1}) || !c.pages[pageURL].Exists {
+		t.Errorf("ImportMatrix() = %+v, exists = %v; want the fresher fact kept", counts, c.pages[pageURL].Exists)
+	}
+
+	counts, err = c.ImportMatrix(strings.NewReader(matrix(
+		MatrixPage{URL: pageURL, StatusCode: 404, CheckedAt: now},
+	)), 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts != (MatrixImport{Imported: 1}) || c.pages[pageURL].Exists {
+		t.Errorf("ImportMatrix() = %+v, exists = %v; want the newer fact imported", counts, c.pages[pageURL].Exists)
+	}
+}
+
+func TestImportMatrix_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		matrix string
+	}{
+		{"not JSON", "pages"},
+		{"other schema", `{"schema": 2, "pages": []}`},
+		{"page without url", `{"schema": 1, "pages": [{"exists": true}]}`},
+		{"url with fragment", `{"schema": 1, "pages": [{"url": "https://docs.redhat.com/a#b"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewChecker().ImportMatrix(strings.NewReader(tt.matrix), 0); err == nil {
+				t.Error("ImportMatrix() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestMatrix_KeepsCrossDocumentRedirects(t *testing.T) {
+	const movedPath = "/en/documentation/openshift_container_platform/4.19/html/ingress_and_load_balancing/ingress"
+	pageURL := "https://docs.redhat.com" + fmt.Sprintf(matrixDocPath, "4.19", "ingress")
+	first := newFakeDocsChecker(t, map[string]string{
+		fmt.Sprintf(matrixDocPath, "4.19", "ingress"): "redirect:" + movedPath,
+		movedPath: "<html></html>",
+	})
+	first.SetAcceptRedirects(true)
+	if result := first.checkURL(context.Background(), pageURL); result.ServedDocument != "ingress_and_load_balancing" {
+		t.Fatalf("checkURL() served from %q, want ingress_and_load_balancing", result.ServedDocument)
+	}
+
+	var matrix bytes.Buffer
+	if err := first.ExportMatrix(&matrix); err != nil {
+		t.Fatal(err)
+	}
+	second := NewChecker()
+	second.SetAcceptRedirects(true)
+	if _, err := second.ImportMatrix(&matrix, 0); err != nil {
+		t.Fatalf("ImportMatrix() error = %v", err)
+	}
+	if result := second.checkURL(context.Background(), pageURL); !result.Cached || result.ServedDocument != "ingress_and_load_balancing" {
+		t.Errorf("imported checkURL() cached = %v, served from %q; want the redirect kept", result.Cached, result.ServedDocument)
+	}
+}
@@ -0,0 +1,131 @@
+package checker
+
+import "container/list"
+
+// pageLRU bounds the memory held by the anchor ids and titles of fetched
+// pages, which for a run over thousands of large guides is most of the
+// checker's memory. The least recently used pages lose their anchors first;
+// whether a page exists is always kept, as it takes little room.
+type pageLRU struct {
+	max   int64
+	bytes int64
+	peak  int64
+	// order lists the URLs of pages holding anchors, least recently used
+	// first; entries maps each to its element and sizes to its size
+	order   *list.List
+	entries map[string]*list.Element
+	sizes   map[string]int64
+}
+
+// SetPageCacheLimit bounds the estimated bytes of anchor ids and titles
+// kept in memory to maxBytes. Once over, the least recently used pages
+// drop their anchors, keeping whether they exist; an anchor lookup on such
+// a page reads it from the disk cache when one is set, where dropped pages
+// are written, or requests it again. Zero or less removes the limit. Call
+// it before checking URLs.
+func (c *Checker) SetPageCacheLimit(maxBytes int64) {
+	c.pagesMu.Lock()
+	defer c.pagesMu.Unlock()
+	if maxBytes <= 0 {
+		c.lru = nil
+		return
+	}
+	c.lru = &pageLRU{max: maxBytes, order: list.New(), entries: make(map[string]*list.Element), sizes: make(map[string]int64)}
+	for _, facts := range c.pages {
+		c.lru.add(facts)
+	}
+	c.evictPages()
+}
+
+// pageSize estimates the bytes held by the anchor ids, heading texts and
+// title of facts, counting a string header for every id and text
+func pageSize(facts *PageFacts) int64 {
+	n := int64(len(facts.Title))
+	for _, id := range facts.AnchorIDs {
+		n += int64(len(id)) + 16
+	}
+	for id, text := range facts.AnchorTexts {
+		n += int64(len(id)+len(text)) + 32
+	}
+	return n
+}
+
+// add accounts for the facts of a page, replacing earlier ones of its URL,
+// and marks it most recently used
+func (l *pageLRU) add(facts *PageFacts) {
+	l.remove(facts.URL)
+	if !facts.Fetched {
+		return
+	}
+	size := pageSize(facts)
+	l.entries[facts.URL] = l.order.PushBack(facts.URL)
+	l.sizes[facts.URL] = size
+	l.bytes += size
+}
+
+// touch marks the page at url most recently used
+func (l *pageLRU) touch(url string) {
+	if e, ok := l.entries[url]; ok {
+		l.order.MoveToBack(e)
+	}
+}
+
+// remove stops accounting for the page at url
+func (l *pageLRU) remove(url string) {
+	if e, ok := l.entries[url]; ok {
+		l.order.Remove(e)
+		l.bytes -= l.sizes[url]
+		delete(l.entries, url)
+		delete(l.sizes, url)
+	}
+}
+
+// setPage stores the facts of a page and drops the anchors of the least
+// recently used pages when over the limit, returning the facts they had.
+// c.pagesMu must be held.
+func (c *Checker) setPage(facts *PageFacts) []*PageFacts {
+	c.pages[facts.URL] = facts
+	if c.lru == nil {
+		return nil
+	}
+	c.lru.add(facts)
+	return c.evictPages()
+}
+
+// evictPages drops the anchors of the least recently used pages until the
+// limit is met, keeping the most recent page whatever its size, and returns
+// the facts they had. c.pagesMu must be held.
+func (c *Checker) evictPages() []*PageFacts {
+	var evicted []*PageFacts
+	for c.lru.bytes > c.lru.max && c.lru.order.Len() > 1 {
+		url := c.lru.order.Front().Value.(string)
+		c.lru.remove(url)
+
+		facts := c.pages[url]
+		stripped := *facts
+		stripped.Fetched = false
+		stripped.AnchorIDs = nil
+		stripped.AnchorTexts = nil
+		stripped.Title = ""
+		c.pages[url] = &stripped
+		evicted = append(evicted, facts)
+	}
+	c.lru.peak = max(c.lru.peak, c.lru.bytes)
+	c.pageEvictions.Add(int64(len(evicted)))
+	return evicted
+}
+
+// spill writes the facts of evicted pages to the disk cache, if any, so
+// that their anchors are read back instead of requested again. Requested
+// pages were written when fetched; imported ones may not be on disk yet.
+func (c *Checker) spill(evicted []*PageFacts) {
+	if c.cache == nil {
+		return
+	}
+	for _, facts := range evicted {
+		if facts.Imported {
+			// The cache only saves requests; a run does not fail for it
+			_ = c.cache.store(facts)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package checker
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newLargeDocsChecker returns a checker serving count guides in versions
+// 4.17 and 4.18, each with anchors ids, and the URLs of two anchors of each
+// guide in 4.17
+func newLargeDocsChecker(t *testing.T, count, anchors int) (*Checker, []string) {
+	t.Helper()
+	pages := make(map[string]string)
+	var urls []string
+	for i := range count {
+		guide := fmt.Sprintf("guide-%d", i)
+		var body strings.Builder
+		body.WriteString("<html><head><title>" + guide + "</title></head><body>")
+		for j := range anchors {
+			fmt.Fprintf(&body, `<h2 id="%s-section-%d_%s">Section %d</h2>`, guide, j, guide, j)
+		}
+		body.WriteString("</body></html>")
+		for _, v := range []string{"4.17", "4.18"} {
+			pages[fmt.Sprintf(matrixDocPath, v, guide)] = body.String()
+		}
+		base := "https://docs.redhat.com" + fmt.Sprintf(matrixDocPath, "4.17", guide)
+		urls = append(urls,
+			fmt.Sprintf("%s#%s-section-0_%s", base, guide, guide),
+			fmt.Sprintf("%s#%s-section-%d_%s", base, guide, anchors-1, guide))
+	}
+
+	c := newFakeDocsChecker(t, pages)
+	c.SetVersions([]string{"4.17", "4.18"})
+	c.SetFormatFallback(false)
+	c.SetOriginalCheck(false)
+	return c, urls
+}
+
+// heldBytes returns the estimated bytes of anchors the checker holds
+func heldBytes(c *Checker) int64 {
+	c.pagesMu.Lock()
+	defer c.pagesMu.Unlock()
+	var n int64
+	for _, facts := range c.pages {
+		n += pageSize(facts)
+	}
+	return n
+}
+
+func TestPageCacheLimit_Stress(t *testing.T) {
+	if testing.Short() {
+		t.Skip("checks thousands of URLs")
+	}
+	const limit = 256 << 10
+	c, urls := newLargeDocsChecker(t, 2000, 40)
+	c.SetPageCacheLimit(limit)
+
+	results, err := c.CheckAll(urls)
+	if err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	for i, result := range results {
+		if result.LatestVersion != "4.18" || !result.IsOutdated {
+			t.Fatalf("result %d = %s at %s, want outdated at 4.18", i, result.OriginalURL, result.LatestVersion)
+		}
+	}
+
+	// Every page is far over the limit together, about 4 MB of anchors
+	if c.lru.peak > limit {
+		t.Errorf("peak anchor bytes = %d, want at most %d", c.lru.peak, limit)
+	}
+	if held := heldBytes(c); held > limit {
+		t.Errorf("anchor bytes held after the run = %d, want at most %d", held, limit)
+	}
+	if stats := c.Stats(); stats.PageEvictions == 0 {
+		t.Errorf("Stats() = %+v, want pages dropped from memory", stats)
+	}
+}
+
+func TestPageCacheLimit_KeepsRecentPages(t *testing.T) {
+	c, urls := newLargeDocsChecker(t, 2, 40)
+	// Far below one page: only the most recent page keeps its anchors
+	c.SetPageCacheLimit(1)
+
+	// Each anchor is checked on 4.18; the first guide's second anchor is
+	// answered from memory, the one checked after the other guide is not
+	checkAll(t, c, urls[:2])
+	if got := c.Requests(); got != 1 {
+		t.Errorf("Requests() = %d, want 1", got)
+	}
+	checkAll(t, c, []string{urls[2], urls[0]})
+	if got := c.Requests(); got != 3 {
+		t.Errorf("Requests() = %d, want 3", got)
+	}
+
+	c.SetPageCacheLimit(0)
+	if c.lru != nil {
+		t.Error("SetPageCacheLimit(0) kept a limit")
+	}
+}
+
+func TestPageCacheLimit_ReadsDroppedPagesFromDisk(t *testing.T) {
+	// An unbounded run exports every page with its anchors
+	first, urls := newLargeDocsChecker(t, 20, 10)
+	want := checkAll(t, first, urls)
+	var matrix bytes.Buffer
+	if err := first.ExportMatrix(&matrix); err != nil {
+		t.Fatalf("ExportMatrix() error = %v", err)
+	}
+
+	// Importing into a bounded checker drops most pages' anchors right away;
+	// they are written to the disk cache, not requested again
+	second, _ := newLargeDocsChecker(t, 20, 10)
+	if err := second.SetCache(t.TempDir(), time.Hour); err != nil {
+		t.Fatalf("SetCache() error = %v", err)
+	}
+	second.SetPageCacheLimit(1 << 10)
+	if _, err := second.ImportMatrix(&matrix, time.Hour); err != nil {
+		t.Fatalf("ImportMatrix() error = %v", err)
+	}
+	if got := checkAll(t, second, urls); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("bounded run latest versions = %v, want %v", got, want)
+	}
+	if got := second.Requests(); got != 0 {
+		t.Errorf("bounded run Requests() = %d, want 0", got)
+	}
+	if stats := second.Stats(); stats.PageEvictions == 0 || stats.DiskReads == 0 {
+		t.Errorf("bounded run Stats() = %+v, want pages dropped and read back from disk", stats)
+	}
+}
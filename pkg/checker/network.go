@@ -0,0 +1,167 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Network is the IP address family the checker connects over
+type Network string
+
+const (
+	// NetworkAuto connects over the families the host resolves to, in the
+	// order the system prefers them, and retries a connection attempt
+	// that timed out over the other family
+	NetworkAuto Network = "auto"
+	// NetworkIPv4 connects over IPv4 only
+	NetworkIPv4 Network = "ipv4"
+	// NetworkIPv6 connects over IPv6 only
+	NetworkIPv6 Network = "ipv6"
+)
+
+// defaultConnectTimeout bounds dialing a server and the TLS handshake with
+// it, well within the timeout of a request, so that a connection attempt
+// that timed out leaves time to try the other family
+const defaultConnectTimeout = 10 * time.Second
+
+// SetNetwork sets the address family the checker connects over, e.g.
+// NetworkIPv4 behind a broken IPv6 path where every connection over IPv6
+// times out. The default is NetworkAuto. With a proxy, the family is that
+// of the connection to the proxy. It replaces any transport set with
+// SetTransport.
+func (c *Checker) SetNetwork(n Network) error {
+	switch n {
+	case NetworkAuto, NetworkIPv4, NetworkIPv6:
+	default:
+		return fmt.Errorf("invalid network %q (expected auto, ipv4 or ipv6)", n)
+	}
+	c.network = n
+	c.SetTransport(nil)
+	return nil
+}
+
+// networkKey is the context key of the family a request is forced over
+type networkKey struct{}
+
+// dialContext dials addr over the family the request is forced over, if
+// any, or else over that of SetNetwork
+func (c *Checker) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	family, ok := ctx.Value(networkKey{}).(Network)
+	if !ok {
+		family = c.network
+	}
+	switch family {
+	case NetworkIPv4:
+		network = "tcp4"
+	case NetworkIPv6:
+		network = "tcp6"
+	}
+	return c.dialer.DialContext(ctx, network, addr)
+}
+
+// connTrace follows the connections of a request, to tell the family it
+// was answered over, or the family a connection attempt timed out on
+type connTrace struct {
+	mu sync.Mutex
+	// tried are the families dialed; connected is that of the last
+	// connection dialed, established is set once a connection is ready to
+	// send the request, TLS handshake included
+	tried       map[Network]bool
+	connected   Network
+	established bool
+}
+
+// clientTrace returns the hooks that fill t
+func (t *connTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		// Dials of both families may run at once
+		ConnectStart: func(_, addr string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if t.tried == nil {
+				t.tried = make(map[Network]bool)
+			}
+			t.tried[addrFamily(addr)] = true
+		},
+		ConnectDone: func(_, addr string, err error) {
+			if err == nil {
+				t.mu.Lock()
+				defer t.mu.Unlock()
+				t.connected = addrFamily(addr)
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.established = true
+			t.connected = addrFamily(info.Conn.RemoteAddr().String())
+		},
+	}
+}
+
+// family returns the family of the connection that answered, empty when
+// unknown
+func (t *connTrace) family() Network {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.established {
+		return ""
+	}
+	return t.connected
+}
+
+// failedFamily returns the family a connection attempt failed on before
+// it was established: the family of a connection whose TLS handshake
+// failed, or the only family dialed. It is empty once a connection was
+// established, or when both families were dialed and neither connected.
+func (t *connTrace) failedFamily() Network {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch {
+	case t.established:
+		return ""
+	case t.connected != "":
+		return t.connected
+	case len(t.tried) == 1:
+		for family := range t.tried {
+			return family
+		}
+	}
+	return ""
+}
+
+// addrFamily returns the family of a host:port address, empty when the
+// host is not an IP address
+func addrFamily(addr string) Network {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		return ""
+	case ip.To4() != nil:
+		return NetworkIPv4
+	}
+	return NetworkIPv6
+}
+
+// otherFamily returns the family that is not family
+func otherFamily(family Network) Network {
+	if family == NetworkIPv4 {
+		return NetworkIPv6
+	}
+	return NetworkIPv4
+}
+
+// timedOut reports whether err is a timeout
+func timedOut(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
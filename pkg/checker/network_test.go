@@ -0,0 +1,232 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dualStackHost is a host name that resolveDualStack resolves to both
+// loopback addresses
+const dualStackHost = "docs.test"
+
+// resolveDualStack answers every DNS query with 127.0.0.1 and ::1, as a
+// resolver of a host published over both families does
+func resolveDualStack(ctx context.Context, _, _ string) (net.Conn, error) {
+	client, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		for {
+			var size uint16
+			if err := binary.Read(server, binary.BigEndian, &size); err != nil {
+				return
+			}
+			query := make([]byte, size)
+			if _, err := io.ReadFull(server, query); err != nil {
+				return
+			}
+			answer, err := dualStackAnswer(query)
+			if err != nil {
+				return
+			}
+			if err := binary.Write(server, binary.BigEndian, uint16(len(answer))); err != nil {
+				return
+			}
+			if _, err := server.Write(answer); err != nil {
+				return
+			}
+		}
+	}()
+	return client, nil
+}
+
+// dualStackAnswer answers an A or AAAA query with the loopback address
+func dualStackAnswer(query []byte) ([]byte, error) {
+	var p dnsmessage.Parser
+	header, err := p.Start(query)
+	if err != nil {
+		return nil, err
+	}
+	q, err := p.Question()
+	if err != nil {
+		return nil, err
+	}
+
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: header.ID, Response: true, Authoritative: true, RecursionDesired: header.RecursionDesired, RecursionAvailable: true})
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := b.Question(q); err != nil {
+		return nil, err
+	}
+	if err := b.StartAnswers(); err != nil {
+		return nil, err
+	}
+	rh := dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: 60}
+	switch q.Type {
+	case dnsmessage.TypeA:
+		err = b.AResource(rh, dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}})
+	case dnsmessage.TypeAAAA:
+		err = b.AAAAResource(rh, dnsmessage.AAAAResource{AAAA: [16]byte{15: 1}})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b.Finish()
+}
+
+// newDualStackChecker serves a page over TLS at dualStackHost on one
+// loopback family, and on the other accepts connections but never answers
+// their TLS handshake, as a broken path does. It returns a checker that
+// reaches the host over both families and the URL of the page, and counts
+// the connections to the broken family in stalled.
+func newDualStackChecker(t *testing.T, broken Network) (c *Checker, pageURL string, stalled *atomic.Int64) {
+	t.Helper()
+
+	v4, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(v4.Addr().String())
+	v6, err := net.Listen("tcp6", net.JoinHostPort("::1", port))
+	if err != nil {
+		v4.Close()
+		t.Skipf("IPv6 loopback unavailable: %v", err)
+	}
+	working, stalling := v4, v6
+	if broken == NetworkIPv4 {
+		working, stalling = v6, v4
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body><h2 id="ingress">Ingress</h2></body></html>`))
+	}))
+	server.Listener = working
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	stalled = &atomic.Int64{}
+	var conns sync.WaitGroup
+	done := make(chan struct{})
+	go func() {
+		for {
+			conn, err := stalling.Accept()
+			if err != nil {
+				return
+			}
+			stalled.Add(1)
+			conns.Add(1)
+			go func() {
+				defer conns.Done()
+				<-done
+				conn.Close()
+			}()
+		}
+	}()
+	t.Cleanup(func() {
+		stalling.Close()
+		close(done)
+		conns.Wait()
+	})
+
+	c = NewChecker()
+	c.AllowHost(dualStackHost)
+	c.dialer.Resolver = &net.Resolver{PreferGo: true, Dial: resolveDualStack}
+	c.connectTimeout = 200 * time.Millisecond
+	c.SetTLSConfig(&tls.Config{InsecureSkipVerify: true})
+	if err := c.SetRetryPolicy(RetryPolicy{MaxAttempts: 1}); err != nil {
+		t.Fatal(err)
+	}
+	return c, "https://" + net.JoinHostPort(dualStackHost, port) + "/en/networking#ingress", stalled
+}
+
+func TestNetwork_BrokenFamily(t *testing.T) {
+	tests := []struct {
+		name         string
+		broken       Network
+		network      Network
+		wantNetwork  Network // empty for a timeout
+		wantFallback bool
+		wantStalled  bool
+	}{
+		{name: "auto falls back to IPv4", broken: NetworkIPv6, network: NetworkAuto, wantNetwork: NetworkIPv4, wantFallback: true, wantStalled: true},
+		{name: "IPv4 only avoids broken IPv6", broken: NetworkIPv6, network: NetworkIPv4, wantNetwork: NetworkIPv4},
+		{name: "IPv6 only over broken IPv6", broken: NetworkIPv6, network: NetworkIPv6, wantStalled: true},
+		{name: "IPv6 only avoids broken IPv4", broken: NetworkIPv4, network: NetworkIPv6, wantNetwork: NetworkIPv6},
+		{name: "IPv4 only over broken IPv4", broken: NetworkIPv4, network: NetworkIPv4, wantStalled: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, pageURL, stalled := newDualStackChecker(t, tt.broken)
+			if err := c.SetNetwork(tt.network); err != nil {
+				t.Fatal(err)
+			}
+
+			facts, err := c.CheckURLOnce(context.Background(), pageURL)
+			if tt.wantNetwork == "" {
+				var reqErr *RequestError
+				if !errors.As(err, &reqErr) || !timedOut(err) || reqErr.Attempts != 1 {
+					t.Errorf("CheckURLOnce() error = %v, want a timeout after 1 attempt", err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("CheckURLOnce() error = %v", err)
+				}
+				// The fallback is part of the attempt, not a retry
+				if facts.Network != tt.wantNetwork || facts.NetworkFallback != tt.wantFallback || facts.Attempts != 1 || !facts.HasAnchor("ingress") {
+					t.Errorf("CheckURLOnce() = network %q, fallback %v, %d attempt(s); want %q, %v, 1",
+						facts.Network, facts.NetworkFallback, facts.Attempts, tt.wantNetwork, tt.wantFallback)
+				}
+			}
+			if got := stalled.Load() > 0; got != tt.wantStalled {
+				t.Errorf("connected to the broken family: %v, want %v", got, tt.wantStalled)
+			}
+		})
+	}
+}
+
+func TestNetwork_FallbackProvenance(t *testing.T) {
+	c, pageURL, _ := newDualStackChecker(t, NetworkIPv6)
+
+	v := c.checkURL(context.Background(), pageURL)
+	if !v.Exists || !v.AnchorExists || v.Network != NetworkIPv4 {
+		t.Errorf("checkURL() = exists %v, anchor %v, network %q; want the page over IPv4", v.Exists, v.AnchorExists, v.Network)
+	}
+	if !slices.Contains(v.heuristics, HeuristicNetworkFallback) {
+		t.Errorf("heuristics = %v, want %s", v.heuristics, HeuristicNetworkFallback)
+	}
+}
+
+func TestSetNetwork_Invalid(t *testing.T) {
+	if err := NewChecker().SetNetwork("ipv5"); err == nil {
+		t.Error("SetNetwork(ipv5) succeeded, want error")
+	}
+}
+
+func TestAddrFamily(t *testing.T) {
+	tests := map[string]Network{
+		"127.0.0.1:443":        NetworkIPv4,
+		"[::1]:443":            NetworkIPv6,
+		"[::ffff:10.0.0.1]:80": NetworkIPv4,
+		"2001:db8::1":          NetworkIPv6,
+		"docs.redhat.com:443":  "",
+	}
+	for addr, want := range tests {
+		if got := addrFamily(addr); got != want {
+			t.Errorf("addrFamily(%q) = %q, want %q", addr, got, want)
+		}
+	}
+}
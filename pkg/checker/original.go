@@ -0,0 +1,75 @@
+package checker
+
+import (
+	"context"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/parser"
+)
+
+// SetOriginalCheck sets whether Check also requests the original URL at its
+// own version, to report a dead link as Broken. It costs a request per
+// linked page. It is on by default.
+func (c *Checker) SetOriginalCheck(enabled bool) {
+	c.noOriginalCheck = !enabled
+}
+
+// startOriginal checks the original URL at its own version alongside its
+// newer versions, holding a slot like they do, and returns where the check
+// is delivered, nil when SetOriginalCheck disabled it. A missing anchor is
+// looked up on the html-single variant, so a section rendered with
+// JavaScript never makes a link look dead.
+func (c *Checker) startOriginal(ctx context.Context, docURL *parser.OCPDocURL) <-chan VersionCheckResult {
+	if c.noOriginalCheck {
+		return nil
+	}
+	original := make(chan VersionCheckResult, 1)
+	go func() {
+		original <- c.checkOwnVersion(ctx, docURL)
+	}()
+	return original
+}
+
+// VerifyURL checks rawURL at its own version alone, e.g. to confirm that a
+// URL written by a fix resolves, with the same html-single fallback as
+// Check. Pages already cached are not requested again. The error is that
+// of parsing rawURL or of ctx; a failed request is reported in the result.
+func (c *Checker) VerifyURL(ctx context.Context, rawURL string) (VersionCheckResult, error) {
+	docURL, err := parser.ParseOCPDocURL(rawURL)
+	if err != nil {
+		return VersionCheckResult{}, err
+	}
+	v := c.checkOwnVersion(ctx, docURL)
+	return v, ctx.Err()
+}
+
+// checkOwnVersion checks docURL at its own version, holding a slot
+func (c *Checker) checkOwnVersion(ctx context.Context, docURL *parser.OCPDocURL) VersionCheckResult {
+	c.slots <- struct{}{}
+	defer func() { <-c.slots }()
+	v := c.checkURL(ctx, docURL.BuildURL(docURL.Version))
+	v.Version = docURL.Version
+	return c.formatFallback(ctx, docURL, v)
+}
+
+// awaitOriginal records the check of the original URL started by
+// startOriginal on result. A check that got no answer, a server error or
+// an anchor left indeterminate verifies nothing, so it never marks the URL
+// broken; a page read for an indeterminate anchor still tells whether it
+// is deprecated.
+func awaitOriginal(result *CheckResult, original <-chan VersionCheckResult) {
+	if original == nil {
+		return
+	}
+	v := <-original
+	for _, h := range v.heuristics {
+		applyHeuristic(&result.AppliedHeuristics, h)
+	}
+	result.OriginalDeprecated = v.DeprecationMarker != ""
+	if v.Error != nil || v.StatusCode >= 500 || v.AnchorIndeterminate {
+		return
+	}
+	result.OriginalExists = v.Exists
+	result.OriginalRedirectedTo = v.RedirectedTo
+	result.OriginalAnchorExists = v.Exists && v.HasAnchor && v.AnchorExists
+	result.Broken = !v.Exists || (v.HasAnchor && !v.AnchorExists)
+}
@@ -0,0 +1,140 @@
+package checker
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCheck_Original(t *testing.T) {
+	const pagePath = "/en/documentation/openshift_container_platform/%s/html/networking/%s"
+	const singlePath = "/en/documentation/openshift_container_platform/%s/html-single/networking/index"
+	page := `<html><body><h2 id="ingress">Ingress</h2></body></html>`
+	pages := map[string]string{
+		fmt.Sprintf(pagePath, "4.16", "ingress"): page,
+		fmt.Sprintf(pagePath, "4.17", "ingress"): page,
+		// The dns page renders its sections with JavaScript
+		fmt.Sprintf(pagePath, "4.16", "dns"): `<html><body></body></html>`,
+		fmt.Sprintf(singlePath, "4.16"):      `<html><body><h2 id="dns-operator">DNS</h2></body></html>`,
+	}
+	urlAt := func(version, page, fragment string) string {
+		return "https://docs.redhat.com" + fmt.Sprintf(pagePath, version, page) + fragment
+	}
+
+	tests := []struct {
+		name   string
+		url    string
+		failed bool // the original version answers 500
+		off    bool // SetOriginalCheck(false)
+
+		wantExists, wantAnchor, wantBroken bool
+		wantOutdated                       bool
+	}{
+		{name: "page and anchor", url: urlAt("4.16", "ingress", "#ingress"), wantExists: true, wantAnchor: true, wantOutdated: true},
+		{name: "page without anchor", url: urlAt("4.16", "ingress", ""), wantExists: true, wantOutdated: true},
+		{name: "anchor missing", url: urlAt("4.16", "ingress", "#ingress-removed"), wantExists: true, wantBroken: true},
+		{name: "page missing", url: urlAt("4.16", "ingres", "#ingress"), wantBroken: true},
+		{name: "page gone but a newer version works", url: urlAt("4.15", "ingress", "#ingress"), wantBroken: true, wantOutdated: true},
+		{name: "anchor on the html-single variant", url: urlAt("4.16", "dns", "#dns-operator"), wantExists: true, wantAnchor: true},
+		{name: "server error", url: urlAt("4.16", "ingress", "#ingress"), failed: true, wantOutdated: true},
+		{name: "check disabled", url: urlAt("4.16", "ingres", "#ingress"), off: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs := fakeDocsHandler(pages)
+			var originalRequests atomic.Int64
+			c := newHandlerChecker(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.Contains(r.URL.Path, "/4.16/") || strings.Contains(r.URL.Path, "/4.15/") {
+					originalRequests.Add(1)
+					if tt.failed {
+						http.Error(w, "unavailable", http.StatusInternalServerError)
+						return
+					}
+				}
+				docs.ServeHTTP(w, r)
+			}))
+			c.SetVersions([]string{"4.15", "4.16", "4.17"})
+			c.SetMaxConcurrent(1)
+			if err := c.SetRetryPolicy(RetryPolicy{MaxAttempts: 1}); err != nil {
+				t.Fatal(err)
+			}
+			c.SetOriginalCheck(!tt.off)
+
+			result, err := c.Check(tt.url)
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if result.OriginalExists != tt.wantExists || result.OriginalAnchorExists != tt.wantAnchor || result.Broken != tt.wantBroken {
+				t.Errorf("OriginalExists, OriginalAnchorExists, Broken = %v, %v, %v; want %v, %v, %v",
+					result.OriginalExists, result.OriginalAnchorExists, result.Broken, tt.wantExists, tt.wantAnchor, tt.wantBroken)
+			}
+			if result.IsOutdated != tt.wantOutdated {
+				t.Errorf("IsOutdated = %v, want %v", result.IsOutdated, tt.wantOutdated)
+			}
+			// The original is never one of the versions checked
+			for _, v := range result.AllResults {
+				if v.Version == result.OriginalVersion {
+					t.Errorf("AllResults holds the original version %s", v.Version)
+				}
+			}
+			if n := originalRequests.Load(); tt.off && n != 0 {
+				t.Errorf("%d requests for the original version, want none", n)
+			}
+		})
+	}
+}
+
+func TestVerifyURL(t *testing.T) {
+	const pagePath = "/en/documentation/openshift_container_platform/4.17/html/networking/%s"
+	pages := map[string]string{
+		fmt.Sprintf(pagePath, "ingress"): `<html><body><h2 id="ingress">Ingress</h2></body></html>`,
+		fmt.Sprintf(pagePath, "dns"):     `<html><body></body></html>`,
+		"/en/documentation/openshift_container_platform/4.17/html-single/networking/index": `<html><body><h2 id="dns-operator">DNS</h2></body></html>`,
+	}
+	urlOf := func(page, fragment string) string {
+		return "https://docs.redhat.com" + fmt.Sprintf(pagePath, page) + fragment
+	}
+
+	tests := []struct {
+		name                   string
+		url                    string
+		wantExists, wantAnchor bool
+		wantErr                bool
+	}{
+		{name: "page and anchor", url: urlOf("ingress", "#ingress"), wantExists: true, wantAnchor: true},
+		{name: "anchor missing", url: urlOf("ingress", "#ingress-removed"), wantExists: true},
+		{name: "anchor on the html-single variant", url: urlOf("dns", "#dns-operator"), wantExists: true, wantAnchor: true},
+		{name: "page missing", url: urlOf("ingres", "#ingress")},
+		{name: "not a docs URL", url: "https://example.com/ingress", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeDocsChecker(t, pages)
+			c.SetVersions([]string{"4.16", "4.17"})
+
+			v, err := c.VerifyURL(t.Context(), tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifyURL() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if v.Version != "4.17" || v.Exists != tt.wantExists || (v.HasAnchor && v.AnchorExists) != tt.wantAnchor {
+				t.Errorf("VerifyURL() = %s, Exists %v, AnchorExists %v; want 4.17, %v, %v", v.Version, v.Exists, v.AnchorExists, tt.wantExists, tt.wantAnchor)
+			}
+
+			// A page verified once is not requested again
+			requests := c.Stats().Requests
+			if _, err := c.VerifyURL(t.Context(), tt.url); err != nil {
+				t.Fatal(err)
+			}
+			if again := c.Stats().Requests; again != requests {
+				t.Errorf("Stats().Requests = %d after verifying again, want %d", again, requests)
+			}
+		})
+	}
+}
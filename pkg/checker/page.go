@@ -0,0 +1,369 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"time"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/anchors"
+)
+
+// PageFacts describes a single documentation page as fetched by CheckURLOnce
+type PageFacts struct {
+	// URL is the requested URL without its fragment
+	URL string
+	// Method is the method of the request that answered: GET for fetched
+	// and probed pages, otherwise HEAD, or GET when HEAD failed or was
+	// refused
+	Method string
+	// FinalURL is the URL that answered, after following redirects
+	FinalURL   string
+	StatusCode int
+	// Exists is true for a 2xx or 3xx answer, unless it is a soft 404
+	Exists bool
+	// NotFoundMarker is the soft-404 marker of SetSoft404Markers the page
+	// shows, when it was answered with 2xx but is a not-found page
+	NotFoundMarker string
+	// DeprecationMarker is the marker of SetDeprecationMarkers the page
+	// shows, when it exists but documents a release no longer maintained
+	DeprecationMarker string
+	// Fetched is true when the page body was downloaded and parsed, which
+	// happens only for URLs with a fragment. AnchorIDs and Title are only
+	// set for fetched pages.
+	Fetched bool
+	// Probed is true when a page without a fragment was requested with GET
+	// to read its start for soft-404 markers, instead of HEAD
+	Probed bool
+	// AnchorIDs are the element ids and <a name> values of the page, in
+	// document order
+	AnchorIDs []string
+	// AnchorTexts maps the anchors that name a heading, such as the id of a
+	// <section>, to the text of that heading, see anchors.Anchor.Heading
+	AnchorTexts map[string]string
+	// Title is the text of the page's <title> element
+	Title string
+	// LastModified is the Last-Modified header, zero when absent
+	LastModified time.Time
+	// Size is the body size in bytes: the bytes read for fetched pages,
+	// otherwise the Content-Length, or -1 when unknown
+	Size int64
+	// CheckedAt is when the page was requested
+	CheckedAt time.Time
+	// Attempts is the number of attempts the answer took, 1 when the first
+	// one succeeded; 0 for imported facts
+	Attempts int
+	// Duration is how long the answer took, retries and their backoff
+	// included; 0 for imported facts
+	Duration time.Duration
+	// Network is the address family of the connection that answered,
+	// empty when unknown, e.g. for imported facts or with SetTransport.
+	// NetworkFallback is set when it is the family NetworkAuto fell back
+	// to after a connection attempt over the other one timed out.
+	Network         Network
+	NetworkFallback bool
+	// Imported is set for facts loaded with ImportMatrix or from the disk
+	// cache rather than requested by this checker
+	Imported bool
+	// Truncated is set when the parse budget of SetMaxPageParseTime ran
+	// out before the end of the page: AnchorIDs are those of its first Size
+	// bytes
+	Truncated bool
+}
+
+// HasAnchor reports whether anchor is an id or <a name> of the page
+func (p *PageFacts) HasAnchor(anchor string) bool {
+	for _, id := range p.AnchorIDs {
+		if id == anchor {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckURLOnce requests a single URL, without looking at other versions,
+// through the checker's client and egress policy. A URL with a fragment is
+// fetched with GET and its body parsed for anchors and the title; other URLs
+// are probed with a GET that reads only the start of the page, for soft-404
+// markers, or with SetSoft404Markers(nil) only get a HEAD request, falling
+// back to GET if HEAD fails. Transient
+// failures are retried as set with SetRetryPolicy, giving up when ctx is
+// done; a 4xx or 5xx answer is a page that does not exist and is returned
+// without an error. Once requests were made, the error is a RequestError.
+func (c *Checker) CheckURLOnce(ctx context.Context, rawURL string) (*PageFacts, error) {
+	baseURL, fragment, _ := strings.Cut(rawURL, "#")
+	fetch := fragment != ""
+	start := time.Now()
+
+	var facts *PageFacts
+	attempts, err := c.withRetries(ctx, baseURL, func(ctx context.Context) error {
+		var err error
+		facts, err = c.requestPage(ctx, baseURL, fetch)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	facts.Attempts = attempts
+	facts.Duration = time.Since(start)
+	return facts, nil
+}
+
+// withRetries calls request until it succeeds, retrying transient failures
+// as set with SetRetryPolicy, and returns the attempts it took. It gives up
+// when ctx is done, returning the error of ctx if no attempt was made and a
+// RequestError for pageURL otherwise.
+func (c *Checker) withRetries(ctx context.Context, pageURL string, request func(ctx context.Context) error) (int, error) {
+	start := time.Now()
+	var lastErr error
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	attempts := 0
+	for attempt := range c.retry.MaxAttempts {
+		if attempt > 0 {
+			wait := c.retryWait(attempt)
+			c.logger.WarnContext(ctx, "retrying request", "url", pageURL, "attempt", attempt+1, "wait", wait, "error", lastErr)
+			select {
+			case <-ctx.Done():
+				return attempts, requestFailed(pageURL, ctx.Err(), attempts, start)
+			case <-time.After(wait):
+			}
+		}
+
+		attempts++
+		err := request(withAttempt(ctx, attempts))
+		if err == nil {
+			return attempts, nil
+		}
+		if permanent(err) || ctx.Err() != nil {
+			// Policy violations are permanent, retrying won't help
+			return attempts, requestFailed(pageURL, err, attempts, start)
+		}
+		lastErr = err
+	}
+
+	return attempts, requestFailed(pageURL, lastErr, attempts, start)
+}
+
+// requestFailed returns err as a RequestError for pageURL that gave up
+// after attempts made since start
+func requestFailed(pageURL string, err error, attempts int, start time.Time) error {
+	reqErr, ok := err.(*RequestError)
+	if !ok {
+		reqErr = &RequestError{URL: pageURL, Err: err}
+	}
+	reqErr.Attempts = attempts
+	reqErr.Duration = time.Since(start)
+	return reqErr
+}
+
+// permanent reports whether err is a policy error: a refused host, a
+// certificate pin mismatch or an untrusted certificate; or an answer that
+// retrying would not change
+func permanent(err error) bool {
+	var verifyErr *tls.CertificateVerificationError
+	var answer permanentError
+	return errors.Is(err, ErrHostNotAllowed) || errors.Is(err, ErrPinMismatch) || errors.As(err, &verifyErr) || errors.As(err, &answer)
+}
+
+// errHeadRefused stands for a HEAD request answered with a status that
+// refuses the method, e.g. 405, so that it is retried with GET
+var errHeadRefused = errors.New("HEAD refused")
+
+// headRefused reports whether status answers a HEAD request without saying
+// anything about the page: docs.redhat.com intermittently answers HEAD with
+// 405 Method Not Allowed or 403 Forbidden for pages that GET serves
+func headRefused(status int) bool {
+	return status == http.StatusMethodNotAllowed || status == http.StatusForbidden
+}
+
+// errUnexpectedStatus is returned for answers that are neither a page nor
+// a missing page, which are worth retrying
+var errUnexpectedStatus = errors.New("unexpected status")
+
+// requestPage makes a single attempt at a page
+func (c *Checker) requestPage(ctx context.Context, pageURL string, fetch bool) (*PageFacts, error) {
+	var resp *http.Response
+	var conn connInfo
+	var err error
+	method := http.MethodGet
+	probe := !fetch && len(c.soft404Markers) > 0
+	if fetch || probe {
+		resp, conn, err = c.doNetwork(ctx, method, pageURL)
+	} else {
+		// No anchor, use HEAD for efficiency
+		method = http.MethodHead
+		resp, conn, err = c.doNetwork(ctx, method, pageURL)
+		if err == nil && headRefused(resp.StatusCode) {
+			// Some answers refuse the method rather than the page
+			resp.Body.Close()
+			err = errHeadRefused
+		}
+		if err != nil && !permanent(err) && ctx.Err() == nil {
+			// If HEAD fails, try GET
+			method = http.MethodGet
+			resp, conn, err = c.doNetwork(ctx, method, pageURL)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	facts := &PageFacts{
+		URL:             pageURL,
+		Method:          method,
+		FinalURL:        resp.Request.URL.String(),
+		StatusCode:      resp.StatusCode,
+		Probed:          probe,
+		Size:            resp.ContentLength,
+		CheckedAt:       time.Now(),
+		Network:         conn.network,
+		NetworkFallback: conn.fallback,
+	}
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		facts.LastModified = t
+	}
+
+	// 4xx or 5xx - page doesn't exist, no point retrying
+	if resp.StatusCode >= 400 {
+		return facts, nil
+	}
+	if resp.StatusCode < 200 {
+		return nil, &RequestError{URL: pageURL, StatusCode: resp.StatusCode, Err: errUnexpectedStatus}
+	}
+
+	// Page exists (2xx or 3xx)
+	facts.Exists = true
+	if probe {
+		// The rest of the page is not needed; closing the body early costs
+		// the connection of a large page, still less than reading it
+		prefix, err := io.ReadAll(io.LimitReader(resp.Body, soft404ScanBytes))
+		if err != nil {
+			return nil, err
+		}
+		c.markSoft404(facts, prefix)
+		c.markDeprecated(facts, prefix)
+		return facts, nil
+	}
+	if !fetch {
+		return facts, nil
+	}
+
+	// Every anchor is kept, so the whole page is read, within the budget
+	var r io.Reader = resp.Body
+	if c.maxParseTime > 0 {
+		r = &budgetReader{r: r, now: c.now, deadline: c.now().Add(c.maxParseTime)}
+	}
+	prefix := &prefixWriter{max: soft404ScanBytes}
+	body := &countingReader{r: io.TeeReader(r, prefix)}
+	var ids []string
+	texts := make(map[string]string)
+	seen := make(map[string]bool)
+	title, err := anchors.WalkHeadings(body, func(a anchors.Anchor) bool {
+		ids = append(ids, a.ID)
+		// A fragment goes to the first element of its id
+		if !seen[a.ID] && a.Heading != "" {
+			texts[a.ID] = a.Heading
+		}
+		seen[a.ID] = true
+		return true
+	})
+	if errors.Is(err, errParseBudget) {
+		c.logger.WarnContext(ctx, "page parse budget exceeded", "url", pageURL, "budget", c.maxParseTime, "bytes", body.n)
+		facts.Truncated = true
+		err = nil
+	}
+	if err != nil {
+		c.logger.WarnContext(ctx, "failed to parse page", "url", pageURL, "error", err)
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	facts.Fetched = true
+	facts.Size = body.n
+	facts.AnchorIDs = ids
+	facts.AnchorTexts = texts
+	facts.Title = title
+	c.markSoft404(facts, prefix.b)
+	c.markDeprecated(facts, prefix.b)
+
+	return facts, nil
+}
+
+// markSoft404 marks the facts of a page missing when prefix, the start of
+// the page, shows a soft-404 marker
+func (c *Checker) markSoft404(facts *PageFacts, prefix []byte) {
+	if marker := c.soft404Marker(prefix); marker != "" {
+		facts.Exists = false
+		facts.NotFoundMarker = marker
+	}
+}
+
+// do sends a request through the checker's client
+func (c *Checker) do(ctx context.Context, method, url string) (*http.Response, error) {
+	resp, _, err := c.doNetwork(ctx, method, url)
+	return resp, err
+}
+
+// connInfo is how a request was answered: over network, empty when
+// unknown, which is another family than the system preferred when
+// fallback is set
+type connInfo struct {
+	network  Network
+	fallback bool
+}
+
+// doNetwork is do, also returning the family the request was answered
+// over. With NetworkAuto, a connection attempt that timed out on one family
+// is made again at once over the other, within the same attempt.
+func (c *Checker) doNetwork(ctx context.Context, method, url string) (*http.Response, connInfo, error) {
+	trace := &connTrace{}
+	resp, err := c.send(ctx, method, url, trace)
+	if err == nil || c.network != NetworkAuto || c.customTransport || ctx.Err() != nil || !timedOut(err) {
+		return resp, connInfo{network: trace.family()}, err
+	}
+	failed := trace.failedFamily()
+	if failed == "" {
+		return resp, connInfo{}, err
+	}
+
+	other := otherFamily(failed)
+	c.logger.WarnContext(ctx, "connection timed out, trying the other address family", "url", url, "network", failed, "fallback_network", other, "error", err)
+	trace = &connTrace{}
+	resp, err = c.send(context.WithValue(ctx, networkKey{}, other), method, url, trace)
+	return resp, connInfo{network: trace.family(), fallback: true}, err
+}
+
+// send makes a single request through the checker's client, following its
+// connections with trace
+func (c *Checker) send(ctx context.Context, method, url string, trace *connTrace) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace.clientTrace()), method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	// The client keeps the header on redirects
+	req.Header.Set("User-Agent", c.userAgent)
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	c.logRequest(ctx, method, url, trace.family(), resp, err, time.Since(start))
+	return resp, err
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,329 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+func TestCheckURLOnce(t *testing.T) {
+	const pagePath = "/en/documentation/openshift_container_platform/4.17/html/networking/index"
+	const pageURL = "https://docs.redhat.com" + pagePath
+	page := `<html><head><title>Networking | OpenShift Container Platform | 4.17 | Red Hat Documentation</title></head>` +
+		`<body><h2 id="ingress">Ingress</h2><a name="legacy-anchor"></a><p id="routes">Routes</p></body></html>`
+
+	c := newFakeDocsChecker(t, map[string]string{pagePath: page})
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+		wantExists bool
+		wantFacts  func(*testing.T, *PageFacts)
+	}{
+		{"fetched with a fragment", pageURL + "#ingress", 200, true, func(t *testing.T, f *PageFacts) {
+			if !f.Fetched {
+				t.Error("Fetched = false for a URL with a fragment")
+			}
+			if want := []string{"ingress", "legacy-anchor", "routes"}; !reflect.DeepEqual(f.AnchorIDs, want) {
+				t.Errorf("AnchorIDs = %v, want %v", f.AnchorIDs, want)
+			}
+			if !f.HasAnchor("legacy-anchor") || f.HasAnchor("missing") {
+				t.Error("HasAnchor() disagrees with AnchorIDs")
+			}
+			if !strings.HasPrefix(f.Title, "Networking |") {
+				t.Errorf("Title = %q", f.Title)
+			}
+			if f.Size != int64(len(page)) {
+				t.Errorf("Size = %d, want %d", f.Size, len(page))
+			}
+		}},
+		{"probed without a fragment", pageURL, 200, true, func(t *testing.T, f *PageFacts) {
+			if f.Fetched || f.AnchorIDs != nil || f.Title != "" {
+				t.Errorf("page without a fragment was fetched: %+v", f)
+			}
+			if !f.Probed || f.Method != http.MethodGet {
+				t.Errorf("Probed = %v, Method = %s; want the start of the page read with GET", f.Probed, f.Method)
+			}
+			if f.Size != int64(len(page)) {
+				t.Errorf("Size = %d, want the Content-Length %d", f.Size, len(page))
+			}
+		}},
+		{"missing page", pageURL + "-missing#ingress", 404, false, func(t *testing.T, f *PageFacts) {
+			if f.Fetched {
+				t.Error("Fetched = true for a missing page")
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			facts, err := c.CheckURLOnce(context.Background(), tt.url)
+			if err != nil {
+				t.Fatalf("CheckURLOnce() error = %v", err)
+			}
+			if facts.StatusCode != tt.wantStatus || facts.Exists != tt.wantExists {
+				t.Errorf("StatusCode = %d, Exists = %v; want %d, %v", facts.StatusCode, facts.Exists, tt.wantStatus, tt.wantExists)
+			}
+			if base, _, _ := strings.Cut(tt.url, "#"); facts.URL != base || !strings.HasSuffix(facts.FinalURL, strings.TrimPrefix(base, "https://docs.redhat.com")) {
+				t.Errorf("URL = %s, FinalURL = %s, want both for %s", facts.URL, facts.FinalURL, base)
+			}
+			if facts.Attempts != 1 || facts.Duration <= 0 {
+				t.Errorf("Attempts = %d, Duration = %s; want 1 attempt that took time", facts.Attempts, facts.Duration)
+			}
+			if tt.wantExists && !facts.LastModified.Equal(fakeLastModified) {
+				t.Errorf("LastModified = %v, want %v", facts.LastModified, fakeLastModified)
+			}
+			tt.wantFacts(t, facts)
+		})
+	}
+}
+
+func TestCheckURLOnce_HeadRefused(t *testing.T) {
+	const docPath = "/en/documentation/openshift_container_platform/%s/html/networking/index"
+
+	// headStatus is the answer to HEAD by version; GET serves every page
+	// but 4.19
+	headStatus := map[string]int{"4.16": 200, "4.17": 405, "4.18": 403, "4.19": 405}
+	c := newHandlerChecker(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for version, status := range headStatus {
+			if r.URL.Path != fmt.Sprintf(docPath, version) {
+				continue
+			}
+			switch {
+			case r.Method == http.MethodHead:
+				w.WriteHeader(status)
+			case version == "4.19":
+				http.NotFound(w, r)
+			}
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	// HEAD is only sent without soft-404 detection
+	c.SetSoft404Markers(nil)
+
+	tests := []struct {
+		version    string
+		wantExists bool
+		wantMethod string
+	}{
+		{"4.16", true, http.MethodHead},
+		{"4.17", true, http.MethodGet},
+		{"4.18", true, http.MethodGet},
+		{"4.19", false, http.MethodGet},
+		{"4.20", false, http.MethodHead},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			facts, err := c.CheckURLOnce(context.Background(), "https://docs.redhat.com"+fmt.Sprintf(docPath, tt.version))
+			if err != nil {
+				t.Fatalf("CheckURLOnce() error = %v", err)
+			}
+			if facts.Exists != tt.wantExists || facts.Method != tt.wantMethod {
+				t.Errorf("Exists = %v, Method = %s; want %v, %s", facts.Exists, facts.Method, tt.wantExists, tt.wantMethod)
+			}
+		})
+	}
+
+	// The method reaches the version results
+	c.SetVersions([]string{"4.16", "4.17"})
+	result, err := c.Check("https://docs.redhat.com" + fmt.Sprintf(docPath, "4.16"))
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if len(result.AllResults) != 1 || !result.AllResults[0].Exists || result.AllResults[0].Method != http.MethodGet || result.AllResults[0].StatusCode != http.StatusOK {
+		t.Errorf("Check() AllResults = %+v, want 4.17 found with GET", result.AllResults)
+	}
+}
+
+func TestCheck_VersionStatusAndTiming(t *testing.T) {
+	const docPath = "/en/documentation/openshift_container_platform/%s/html/networking/index"
+
+	// 4.17 is refused to every method, like a WAF would; 4.19 never answers
+	c := newHandlerChecker(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case fmt.Sprintf(docPath, "4.17"):
+			w.WriteHeader(http.StatusForbidden)
+		case fmt.Sprintf(docPath, "4.18"):
+			w.Write([]byte(`<html><body></body></html>`))
+		default:
+			panic(http.ErrAbortHandler)
+		}
+	}))
+	c.SetVersions([]string{"4.16", "4.17", "4.18", "4.19"})
+	c.SetFormatFallback(false)
+	if err := c.SetRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := c.Check("https://docs.redhat.com" + fmt.Sprintf(docPath, "4.16"))
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	tests := []struct {
+		version      string
+		wantExists   bool
+		wantStatus   int
+		wantAttempts int
+		wantErr      bool
+	}{
+		{"4.17", false, http.StatusForbidden, 1, false},
+		{"4.18", true, http.StatusOK, 1, false},
+		{"4.19", false, 0, 2, true},
+	}
+	if len(result.AllResults) != len(tests) {
+		t.Fatalf("Check() AllResults = %+v, want %d versions", result.AllResults, len(tests))
+	}
+	for i, tt := range tests {
+		v := result.AllResults[i]
+		if v.Version != tt.version || v.Exists != tt.wantExists || v.StatusCode != tt.wantStatus || v.Attempts != tt.wantAttempts || (v.Error != nil) != tt.wantErr {
+			t.Errorf("version %s = %+v; want Exists %v, StatusCode %d, %d attempts, error %v", tt.version, v, tt.wantExists, tt.wantStatus, tt.wantAttempts, tt.wantErr)
+		}
+		if v.Duration <= 0 {
+			t.Errorf("version %s Duration = %s, want the time the request took", tt.version, v.Duration)
+		}
+	}
+}
+
+func TestCheckURLOnce_UserAgent(t *testing.T) {
+	if got, want := UserAgent("1.2.3"), "ocp-doc-checker/1.2.3 (+https://github.com/sebrandon1/ocp-doc-checker)"; got != want {
+		t.Errorf("UserAgent() = %q, want %q", got, want)
+	}
+
+	const pagePath = "/en/documentation/openshift_container_platform/4.17/html/networking/index"
+	var mu sync.Mutex
+	agents := make(map[string][]string)
+	c := newHandlerChecker(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		agents[r.Method] = append(agents[r.Method], r.Header.Get("User-Agent"))
+		mu.Unlock()
+		if r.URL.Path != pagePath {
+			http.Redirect(w, r, pagePath, http.StatusMovedPermanently)
+		}
+	}))
+
+	c.SetSoft404Markers(nil)
+
+	for _, ua := range []string{"", "docs-bot/1.0"} {
+		c.SetUserAgent(ua)
+		clear(agents)
+		for _, u := range []string{pagePath, pagePath + "#ingress", "/old" + pagePath} {
+			if _, err := c.CheckURLOnce(context.Background(), "https://docs.redhat.com"+u); err != nil {
+				t.Fatalf("CheckURLOnce(%s) error = %v", u, err)
+			}
+		}
+
+		want := ua
+		if ua == "" {
+			want = UserAgent("dev")
+		}
+		// One HEAD per page without a fragment, one GET with it, and the
+		// redirect followed with HEAD
+		if len(agents[http.MethodHead]) != 3 || len(agents[http.MethodGet]) != 1 {
+			t.Fatalf("requests = %v, want 3 HEAD and 1 GET", agents)
+		}
+		for method, got := range agents {
+			for _, agent := range got {
+				if agent != want {
+					t.Errorf("%s User-Agent = %q, want %q", method, agent, want)
+				}
+			}
+		}
+	}
+}
+
+func TestCheckURLOnce_Errors(t *testing.T) {
+	c := newFakeDocsChecker(t, nil)
+
+	if _, err := c.CheckURLOnce(context.Background(), "https://example.com/page"); !errors.Is(err, ErrHostNotAllowed) {
+		t.Errorf("CheckURLOnce() outside the egress policy error = %v, want ErrHostNotAllowed", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.CheckURLOnce(ctx, "https://docs.redhat.com/en/documentation/openshift_container_platform/4.17/html/networking/index"); !errors.Is(err, context.Canceled) {
+		t.Errorf("CheckURLOnce() with a canceled context error = %v, want context.Canceled", err)
+	}
+	if got := c.Requests(); got != 0 {
+		t.Errorf("Requests() = %d, want 0", got)
+	}
+}
+
+// largePage returns an html-single guide of about 8 MB, with the anchor
+// checked by BenchmarkAnchorCheck in its last section
+func largePage() string {
+	var b strings.Builder
+	b.WriteString(`<html><head><title>Networking | OpenShift Container Platform | 4.19 | Red Hat Documentation</title></head><body>`)
+	for i := range 20000 {
+		fmt.Fprintf(&b, `<section class="section" id="section-%d"><h2 id="heading-%d">Section %d</h2>`, i, i, i)
+		b.WriteString(`<p>` + strings.Repeat("Some procedure text with <code>oc</code> commands. ", 6) + `</p>`)
+		b.WriteString(`<pre class="language-yaml">apiVersion: v1 kind: ConfigMap</pre></section>`)
+	}
+	b.WriteString(`</body></html>`)
+	return b.String()
+}
+
+// BenchmarkAnchorCheck compares the allocations of the token scan of a
+// page check with those of the node tree it replaced: go test -bench
+// AnchorCheck -benchmem
+func BenchmarkAnchorCheck(b *testing.B) {
+	page := largePage()
+	const anchor = "heading-19999"
+
+	b.Run("tree", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(page)))
+		for b.Loop() {
+			doc, err := html.Parse(strings.NewReader(page))
+			if err != nil {
+				b.Fatal(err)
+			}
+			if !treeHasAnchor(doc, anchor) {
+				b.Fatal("anchor not found")
+			}
+		}
+	})
+
+	b.Run("tokens", func(b *testing.B) {
+		const pageURL = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.19/html/networking/index"
+		c := NewChecker()
+		c.SetTransport(&stubTransport{pages: map[string]string{pageURL: page}})
+		b.ReportAllocs()
+		b.SetBytes(int64(len(page)))
+		for b.Loop() {
+			facts, err := c.CheckURLOnce(context.Background(), pageURL+"#"+anchor)
+			if err != nil || !facts.HasAnchor(anchor) {
+				b.Fatalf("CheckURLOnce() = %+v, %v", facts, err)
+			}
+		}
+	})
+}
+
+// treeHasAnchor is the anchor check on a parsed node tree, as done before
+// the token scan of anchors.Walk
+func treeHasAnchor(n *html.Node, anchor string) bool {
+	if n.Type == html.ElementNode {
+		for _, attr := range n.Attr {
+			if (attr.Key == "id" || (n.Data == "a" && attr.Key == "name")) && attr.Val == anchor {
+				return true
+			}
+		}
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if treeHasAnchor(child, anchor) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package checker
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrPinMismatch is returned when a pinned host presents no certificate
+// whose public key matches a pinned fingerprint
+var ErrPinMismatch = errors.New("certificate pin mismatch")
+
+// newTransport returns the transport the checker sends requests through,
+// with the TLS settings of SetTLSConfig and verifying certificate pins on
+// every TLS connection. Proxies are taken from HTTPS_PROXY, HTTP_PROXY and
+// NO_PROXY.
+func newTransport(c *Checker) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = http.ProxyFromEnvironment
+	t.DialContext = c.dialContext
+	t.TLSHandshakeTimeout = c.connectTimeout
+	t.TLSClientConfig = c.tlsClientConfig()
+	return t
+}
+
+// SetCertPins requires TLS connections to present at least one certificate
+// whose SubjectPublicKeyInfo has one of the given SHA-256 fingerprints.
+// Fingerprints are hex, optionally colon-separated, or standard base64. Pins
+// apply to DefaultAllowedHost only, or to every allowed host with allHosts.
+// An empty list disables pinning.
+func (c *Checker) SetCertPins(fingerprints []string, allHosts bool) error {
+	pins := make(map[[sha256.Size]byte]bool)
+	for _, fp := range fingerprints {
+		sum, err := parseFingerprint(fp)
+		if err != nil {
+			return err
+		}
+		pins[sum] = true
+	}
+
+	if len(pins) == 0 {
+		pins = nil
+	}
+	c.pins = pins
+	c.pinAllHosts = allHosts
+	return nil
+}
+
+// parseFingerprint decodes a SHA-256 fingerprint in hex or base64
+func parseFingerprint(fp string) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+
+	raw, err := hex.DecodeString(strings.ReplaceAll(fp, ":", ""))
+	if err != nil || len(raw) != sha256.Size {
+		raw, err = base64.StdEncoding.DecodeString(fp)
+	}
+	if err != nil || len(raw) != sha256.Size {
+		return sum, fmt.Errorf("invalid SPKI fingerprint %q: expected a SHA-256 hash in hex or base64", fp)
+	}
+
+	copy(sum[:], raw)
+	return sum, nil
+}
+
+// verifyPins checks the certificates of a TLS connection against the pins
+// that apply to its server
+func (c *Checker) verifyPins(cs tls.ConnectionState) error {
+	if c.pins == nil || !(c.pinAllHosts || strings.EqualFold(cs.ServerName, DefaultAllowedHost)) {
+		return nil
+	}
+
+	for _, cert := range cs.PeerCertificates {
+		if c.pins[sha256.Sum256(cert.RawSubjectPublicKeyInfo)] {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: no certificate presented by %s matches a pinned SPKI fingerprint", ErrPinMismatch, cs.ServerName)
+}
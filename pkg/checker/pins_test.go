@@ -0,0 +1,165 @@
+package checker
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// mirrorHost is an additional allowed host served by the pinning test server
+const mirrorHost = "mirror.example.com"
+
+// newPinnedServer starts a TLS server with a generated certificate for
+// docs.redhat.com and mirrorHost, and returns a Checker that trusts it and
+// connects to it for both hosts, plus the SPKI fingerprint of the certificate
+func newPinnedServer(t *testing.T) (func() *Checker, [sha256.Size]byte) {
+	t.Helper()
+	addr, cert := newTLSDocsServer(t)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	newChecker := func() *Checker {
+		c := NewChecker()
+		c.AllowHost(mirrorHost)
+		dialServer(c, addr).TLSClientConfig.RootCAs = roots
+		return c
+	}
+
+	return newChecker, sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+}
+
+// newTLSDocsServer starts a TLS server with a generated self-signed
+// certificate for docs.redhat.com and mirrorHost, and returns its address
+// and certificate
+func newTLSDocsServer(t *testing.T) (string, *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: DefaultAllowedHost},
+		DNSNames:     []string{DefaultAllowedHost, mirrorHost},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	// Rejected handshakes are expected, keep them out of the test log
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	return server.Listener.Addr().String(), cert
+}
+
+// dialServer makes the checker's current transport connect to addr for
+// every host, and returns the transport
+func dialServer(c *Checker, addr string) *http.Transport {
+	transport := c.client.Transport.(*egressTransport).base.(*http.Transport)
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, addr)
+	}
+	return transport
+}
+
+func TestSetCertPins(t *testing.T) {
+	newChecker, spki := newPinnedServer(t)
+	other := sha256.Sum256([]byte("another key"))
+
+	tests := []struct {
+		name     string
+		pins     []string
+		allHosts bool
+		host     string
+		wantErr  bool
+	}{
+		{"no pins", nil, false, DefaultAllowedHost, false},
+		{"hex pin matches", []string{hex.EncodeToString(spki[:])}, false, DefaultAllowedHost, false},
+		{"base64 pin among others matches", []string{hex.EncodeToString(other[:]), base64.StdEncoding.EncodeToString(spki[:])}, false, DefaultAllowedHost, false},
+		{"pin mismatch", []string{hex.EncodeToString(other[:])}, false, DefaultAllowedHost, true},
+		{"mirror is not pinned", []string{hex.EncodeToString(other[:])}, false, mirrorHost, false},
+		{"mirror pin mismatch with all hosts", []string{hex.EncodeToString(other[:])}, true, mirrorHost, true},
+		{"mirror pin matches with all hosts", []string{hex.EncodeToString(spki[:])}, true, mirrorHost, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newChecker()
+			if err := c.SetCertPins(tt.pins, tt.allHosts); err != nil {
+				t.Fatalf("SetCertPins() error = %v", err)
+			}
+
+			facts, err := c.CheckURLOnce(context.Background(), "https://"+tt.host+"/en/documentation/openshift_container_platform/4.17/html/networking/index")
+			if tt.wantErr {
+				if !errors.Is(err, ErrPinMismatch) {
+					t.Fatalf("CheckURLOnce() error = %v, want ErrPinMismatch", err)
+				}
+				// A mismatch is permanent: no retries and no GET fallback
+				if got := c.Requests(); got != 1 {
+					t.Errorf("Requests() = %d, want 1", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CheckURLOnce() error = %v", err)
+			}
+			if !facts.Exists {
+				t.Errorf("CheckURLOnce() = %+v, want an existing page", facts)
+			}
+		})
+	}
+}
+
+func TestSetCertPins_Invalid(t *testing.T) {
+	sum := sha256.Sum256([]byte("key"))
+	colons := ""
+	for i, b := range sum {
+		if i > 0 {
+			colons += ":"
+		}
+		colons += hex.EncodeToString([]byte{b})
+	}
+
+	for _, fp := range []string{colons, base64.StdEncoding.EncodeToString(sum[:])} {
+		if err := NewChecker().SetCertPins([]string{fp}, false); err != nil {
+			t.Errorf("SetCertPins(%q) error = %v", fp, err)
+		}
+	}
+
+	for _, fp := range []string{"", "abcd", hex.EncodeToString(sum[:16]), "not a fingerprint"} {
+		if err := NewChecker().SetCertPins([]string{fp}, false); err == nil {
+			t.Errorf("SetCertPins(%q) error = nil, want an error", fp)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package checker
+
+import (
+	"context"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/parser"
+)
+
+// CheckProductURL checks the same document, page and anchor as docURL in
+// the documentation of another product at version, built with
+// parser.OCPDocURL.BuildProductURL, e.g. to offer the ROSA page in place
+// of an OCP link in a ROSA guide. It holds a slot like the checks of
+// Check, and a page redirected elsewhere, such as the landing page of the
+// product, only exists with SetAcceptRedirects. The error is that of ctx;
+// a failed request is reported in the result.
+func (c *Checker) CheckProductURL(ctx context.Context, docURL *parser.OCPDocURL, product, version string) (VersionCheckResult, error) {
+	c.slots <- struct{}{}
+	defer func() { <-c.slots }()
+	v := c.checkURL(ctx, docURL.BuildProductURL(product, version))
+	v.Version = version
+	return v, ctx.Err()
+}
@@ -0,0 +1,66 @@
+package checker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/parser"
+)
+
+func TestCheckProductURL(t *testing.T) {
+	const (
+		rosa    = "/en/documentation/red_hat_openshift_service_on_aws/4/html/networking/ingress"
+		osd     = "/en/documentation/openshift_dedicated/4/html/networking/ingress"
+		landing = "/en/documentation/openshift_dedicated/4"
+	)
+	page := `<html><body><h2 id="nw-ingress-sharding_ingress">Ingress sharding</h2></body></html>`
+
+	tests := []struct {
+		name            string
+		product         string
+		fragment        string
+		acceptRedirects bool
+		wantURL         string
+		wantExists      bool
+		wantAnchor      bool
+		wantRedirect    string
+	}{
+		{name: "page and anchor", product: "red_hat_openshift_service_on_aws", fragment: "#nw-ingress-sharding_ingress", wantURL: rosa + "#nw-ingress-sharding_ingress", wantExists: true, wantAnchor: true},
+		{name: "page without the anchor", product: "red_hat_openshift_service_on_aws", fragment: "#nw-ingress-controller", wantURL: rosa + "#nw-ingress-controller", wantExists: true},
+		{name: "page without fragment", product: "red_hat_openshift_service_on_aws", wantURL: rosa, wantExists: true},
+		{name: "redirected to the landing page", product: "openshift_dedicated", wantURL: osd, wantRedirect: landing},
+		{name: "accepted redirect", product: "openshift_dedicated", acceptRedirects: true, wantURL: osd, wantExists: true, wantRedirect: landing},
+		{name: "not published", product: "microshift", wantURL: "/en/documentation/microshift/4/html/networking/ingress"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeDocsChecker(t, map[string]string{
+				rosa:    page,
+				osd:     "redirect:" + landing,
+				landing: `<html><body>OpenShift Dedicated</body></html>`,
+			})
+			c.SetAcceptRedirects(tt.acceptRedirects)
+			docURL, err := parser.ParseOCPDocURL("https://docs.redhat.com/en/documentation/openshift_container_platform/4.17/html/networking/ingress" + tt.fragment)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			v, err := c.CheckProductURL(context.Background(), docURL, tt.product, "4")
+			if err != nil {
+				t.Fatalf("CheckProductURL() error = %v", err)
+			}
+			if v.URL != "https://docs.redhat.com"+tt.wantURL || v.Version != "4" {
+				t.Errorf("CheckProductURL() = %s at %s, want %s at 4", v.URL, v.Version, tt.wantURL)
+			}
+			wantRedirect := ""
+			if tt.wantRedirect != "" {
+				wantRedirect = "https://docs.redhat.com" + tt.wantRedirect
+			}
+			if v.Exists != tt.wantExists || v.AnchorExists != tt.wantAnchor || v.RedirectedTo != wantRedirect {
+				t.Errorf("CheckProductURL() = exists %v, anchor %v, redirected to %q; want %v, %v, %q",
+					v.Exists, v.AnchorExists, v.RedirectedTo, tt.wantExists, tt.wantAnchor, wantRedirect)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package checker
+
+import "sync/atomic"
+
+// ProgressEvent reports a version check that completed
+type ProgressEvent struct {
+	// URL is the URL being checked, as given to Check or CheckAll
+	URL string
+	// Version is the newer version whose check completed
+	Version string
+	// Index is the position of URL in the URLs of a CheckAll call, from 0,
+	// and Total their number; 0 and 1 for Check
+	Index int
+	Total int
+	// Completed is the number of URLs of the CheckAll call checked so far
+	Completed int
+	// Result is the outcome of the version check
+	Result VersionCheckResult
+}
+
+// OnProgress sets a function called as each version check completes, e.g.
+// to render a progress indicator; nil removes it. Calls are made one at a
+// time, also when versions and URLs are checked concurrently, and a slow
+// function delays the checks waiting to report. Call it before checking
+// URLs.
+func (c *Checker) OnProgress(fn func(ProgressEvent)) {
+	c.onProgress = fn
+}
+
+// batchPosition places a check within the CheckAll call it is part of
+type batchPosition struct {
+	index, total int
+	// completed counts the URLs of the call checked so far; nil for Check
+	completed *atomic.Int64
+}
+
+// progressReporter returns the function reporting the version checks of
+// rawURL to the progress function, nil when there is none
+func (c *Checker) progressReporter(rawURL string, pos batchPosition) func(VersionCheckResult) {
+	fn := c.onProgress
+	if fn == nil {
+		return nil
+	}
+	return func(v VersionCheckResult) {
+		ev := ProgressEvent{URL: rawURL, Version: v.Version, Index: pos.index, Total: pos.total, Result: v}
+		if pos.completed != nil {
+			ev.Completed = int(pos.completed.Load())
+		}
+		c.progressMu.Lock()
+		defer c.progressMu.Unlock()
+		fn(ev)
+	}
+}
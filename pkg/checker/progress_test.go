@@ -0,0 +1,75 @@
+package checker
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOnProgress(t *testing.T) {
+	const docPath = "/en/documentation/openshift_container_platform/%s/html/networking/%s"
+	pages := make(map[string]string)
+	var urls []string
+	for _, page := range []string{"ingress", "routes", "dns"} {
+		for _, v := range []string{"4.16", "4.17", "4.18"} {
+			pages[fmt.Sprintf(docPath, v, page)] = `<html><body><h2 id="a">A</h2></body></html>`
+		}
+		urls = append(urls, "https://docs.redhat.com"+fmt.Sprintf(docPath, "4.16", page)+"#a")
+	}
+	// An unknown page still reports its versions as not found
+	urls = append(urls, "https://docs.redhat.com"+fmt.Sprintf(docPath, "4.16", "missing"))
+
+	c := newFakeDocsChecker(t, pages)
+	c.SetVersions([]string{"4.16", "4.17", "4.18"})
+	c.SetMaxConcurrent(4)
+
+	// The function is not synchronized: the checker calls it one at a time
+	var inside atomic.Int32
+	seen := make(map[string]ProgressEvent)
+	c.OnProgress(func(ev ProgressEvent) {
+		if inside.Add(1) > 1 {
+			t.Error("progress function called concurrently")
+		}
+		defer inside.Add(-1)
+		time.Sleep(time.Millisecond)
+		seen[ev.URL+" "+ev.Version] = ev
+	})
+
+	if _, err := c.CheckAll(urls); err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+
+	if len(seen) != 2*len(urls) {
+		t.Errorf("%d version checks reported, want %d", len(seen), 2*len(urls))
+	}
+	for i, u := range urls {
+		for _, v := range []string{"4.17", "4.18"} {
+			ev, ok := seen[u+" "+v]
+			if !ok {
+				t.Errorf("version %s of URL %d not reported", v, i)
+				continue
+			}
+			if ev.Index != i || ev.Total != len(urls) || ev.Completed >= len(urls) || ev.Result.Version != v {
+				t.Errorf("URL %d, version %s reported as %+v", i, v, ev)
+			}
+			if wantExists := i < 3; ev.Result.Exists != wantExists {
+				t.Errorf("URL %d, version %s Exists = %v, want %v", i, v, ev.Result.Exists, wantExists)
+			}
+		}
+	}
+
+	// A single check is its own batch
+	clear(seen)
+	if _, err := c.Check(urls[1]); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	for _, ev := range seen {
+		if ev.URL != urls[1] || ev.Index != 0 || ev.Total != 1 || ev.Completed != 0 {
+			t.Errorf("Check() reported %+v", ev)
+		}
+	}
+	if len(seen) != 2 {
+		t.Errorf("Check() reported %d version checks, want 2", len(seen))
+	}
+}
@@ -0,0 +1,59 @@
+package checker
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/parser"
+)
+
+// SetAcceptRedirects sets whether a page that redirects to another page,
+// another guide or no documentation page at all, e.g. the product landing
+// page, counts as existing. Such a redirect is reported in RedirectedTo
+// either way. It is off by default, so that a generic index never passes
+// for the page a link names.
+func (c *Checker) SetAcceptRedirects(accept bool) {
+	c.acceptRedirects = accept
+}
+
+// redirectTarget compares the URL that answered after redirects with the
+// URL requested. It returns finalURL when it names another page, another
+// document or no documentation page at all, and the document slug of
+// finalURL when it names another document. Only the paths are compared:
+// the redirect policy already vets the host. The URLs of other products,
+// see CheckProductURL, are compared by path alone.
+func redirectTarget(requestedURL, finalURL string) (target, document string) {
+	requested, err := parser.ParseOCPDocURL(requestedURL)
+	if err != nil {
+		return pathRedirectTarget(requestedURL, finalURL), ""
+	}
+	final, err := url.Parse(finalURL)
+	if err != nil || final.Path == "" {
+		return "", ""
+	}
+	target = requested.BaseURL + final.Path
+	served, err := parser.ParseOCPDocURL(target)
+	switch {
+	case err != nil:
+		return target, ""
+	case served.Document != requested.Document:
+		return target, served.Document
+	case served.Page != requested.Page:
+		return target, ""
+	}
+	return "", ""
+}
+
+// pathRedirectTarget returns finalURL, on the host of requestedURL, when
+// its path differs from that of requestedURL
+func pathRedirectTarget(requestedURL, finalURL string) string {
+	requested, err := url.Parse(requestedURL)
+	if err != nil {
+		return ""
+	}
+	final, err := url.Parse(finalURL)
+	if err != nil || final.Path == "" || strings.TrimSuffix(final.Path, "/") == strings.TrimSuffix(requested.Path, "/") {
+		return ""
+	}
+	return requested.Scheme + "://" + requested.Host + final.Path
+}
@@ -0,0 +1,85 @@
+package checker
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy decides how often a page is requested again after a transient
+// failure, and how long CheckURLOnce waits before each new attempt. The
+// waits grow exponentially from InitialBackoff, capped at MaxBackoff, and
+// are shortened by a random part of up to Jitter of their length so that
+// concurrent checks do not retry in lockstep.
+type RetryPolicy struct {
+	// MaxAttempts is the number of requests made for a page before giving
+	// up, the first one included
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry; every further
+	// retry waits twice as long as the one before
+	InitialBackoff time.Duration
+	// MaxBackoff caps every wait, 0 for no cap
+	MaxBackoff time.Duration
+	// Jitter is the fraction of a wait, from 0 to 1, that is randomly
+	// left out
+	Jitter float64
+}
+
+// DefaultRetryPolicy makes three attempts, waiting about 2s and 4s between
+// them
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 2 * time.Second,
+	MaxBackoff:     30 * time.Second,
+	Jitter:         0.2,
+}
+
+// Validate reports the first invalid setting of the policy
+func (p RetryPolicy) Validate() error {
+	switch {
+	case p.MaxAttempts < 1:
+		return fmt.Errorf("invalid retry policy: %d attempts (expected at least 1)", p.MaxAttempts)
+	case p.InitialBackoff < 0 || p.MaxBackoff < 0:
+		return fmt.Errorf("invalid retry policy: negative backoff")
+	case p.Jitter < 0 || p.Jitter > 1:
+		return fmt.Errorf("invalid retry policy: jitter %g (expected 0 to 1)", p.Jitter)
+	}
+	return nil
+}
+
+// Backoff returns the wait before retry n, counting from 1, without jitter
+func (p RetryPolicy) Backoff(n int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < n && d > 0; i++ {
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff || d > time.Duration(1<<62) {
+			break
+		}
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+// wait returns the wait before retry n with jitter applied, r being a
+// random number in [0, 1)
+func (p RetryPolicy) wait(n int, r float64) time.Duration {
+	d := p.Backoff(n)
+	return d - time.Duration(float64(d)*p.Jitter*r)
+}
+
+// SetRetryPolicy sets how transient failures are retried; the default is
+// DefaultRetryPolicy. Call it before checking URLs.
+func (c *Checker) SetRetryPolicy(p RetryPolicy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	c.retry = p
+	return nil
+}
+
+// retryWait returns the wait before retry n under the checker's policy
+func (c *Checker) retryWait(n int) time.Duration {
+	return c.retry.wait(n, rand.Float64())
+}
@@ -0,0 +1,124 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 6, InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if got := p.Backoff(i + 1); got != w {
+			t.Errorf("Backoff(%d) = %s, want %s", i+1, got, w)
+		}
+	}
+
+	p.MaxBackoff = 0
+	if got := p.Backoff(100); got <= 0 {
+		t.Errorf("Backoff(100) without a cap = %s, want a positive wait", got)
+	}
+}
+
+func TestRetryPolicy_Jitter(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 3, InitialBackoff: 10 * time.Second, Jitter: 0.5}
+	tests := []struct {
+		r    float64
+		want time.Duration
+	}{
+		{0, 10 * time.Second},
+		{0.5, 7500 * time.Millisecond},
+		{0.999, 5005 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := p.wait(1, tt.r); got != tt.want {
+			t.Errorf("wait(1, %g) = %s, want %s", tt.r, got, tt.want)
+		}
+	}
+}
+
+func TestSetRetryPolicy_Invalid(t *testing.T) {
+	tests := []RetryPolicy{
+		{MaxAttempts: 0},
+		{MaxAttempts: 1, InitialBackoff: -time.Second},
+		{MaxAttempts: 1, MaxBackoff: -time.Second},
+		{MaxAttempts: 1, Jitter: 1.5},
+	}
+	for _, p := range tests {
+		if err := NewChecker().SetRetryPolicy(p); err == nil {
+			t.Errorf("SetRetryPolicy(%+v) succeeded, want an error", p)
+		}
+	}
+}
+
+// newFlakyChecker returns a checker whose server drops the connection for
+// the first failures requests and then serves an empty page
+func newFlakyChecker(t *testing.T, failures int64) (*Checker, *atomic.Int64) {
+	t.Helper()
+	var requests atomic.Int64
+	c := newHandlerChecker(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= failures {
+			panic(http.ErrAbortHandler)
+		}
+		w.Write([]byte(`<html><body><h2 id="ingress">Ingress</h2></body></html>`))
+	}))
+	return c, &requests
+}
+
+func TestCheckURLOnce_Retries(t *testing.T) {
+	const pageURL = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.17/html/networking/index#ingress"
+
+	tests := []struct {
+		failures     int64
+		attempts     int
+		wantAttempts int
+		wantErr      bool
+	}{
+		{0, 3, 1, false},
+		{2, 3, 3, false},
+		{3, 3, 3, true},
+		{3, 4, 4, false},
+	}
+	for _, tt := range tests {
+		c, requests := newFlakyChecker(t, tt.failures)
+		if err := c.SetRetryPolicy(RetryPolicy{MaxAttempts: tt.attempts, InitialBackoff: time.Millisecond}); err != nil {
+			t.Fatal(err)
+		}
+
+		facts, err := c.CheckURLOnce(context.Background(), pageURL)
+		if tt.wantErr {
+			// The error tells how many attempts were made before giving up
+			var reqErr *RequestError
+			if !errors.As(err, &reqErr) || reqErr.Attempts != tt.wantAttempts || reqErr.Duration <= 0 {
+				t.Errorf("%d failures, %d attempts: CheckURLOnce() error = %#v, want a RequestError after %d attempts", tt.failures, tt.attempts, err, tt.wantAttempts)
+			}
+		} else if err != nil || facts.Attempts != tt.wantAttempts || facts.Duration <= 0 {
+			t.Errorf("%d failures, %d attempts: CheckURLOnce() = %+v, %v; want %d attempts", tt.failures, tt.attempts, facts, err, tt.wantAttempts)
+		}
+		if got := requests.Load(); got != min(tt.failures+1, int64(tt.attempts)) {
+			t.Errorf("%d failures, %d attempts: %d requests made", tt.failures, tt.attempts, got)
+		}
+	}
+}
+
+func TestCheckURLOnce_CanceledDuringBackoff(t *testing.T) {
+	c, _ := newFlakyChecker(t, 1)
+	if err := c.SetRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.CheckURLOnce(ctx, "https://docs.redhat.com/en/documentation/openshift_container_platform/4.17/html/networking/index#ingress")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("CheckURLOnce() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("CheckURLOnce() returned after %s, want when the context was done", elapsed)
+	}
+}
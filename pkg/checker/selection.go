@@ -0,0 +1,91 @@
+package checker
+
+import "github.com/sebrandon1/ocp-doc-checker/pkg/parser"
+
+// criterion is one rule of the selection policy. compare returns a positive
+// number when a is preferred over b, a negative one when b is, and zero when
+// the rule does not tell them apart.
+type criterion struct {
+	reason  string
+	compare func(a, b VersionCheckResult) int
+}
+
+// selectionPolicy ranks candidate versions, most important rule first. A
+// new kind of candidate must add its rule here, at the position that says
+// how it weighs against the others, rather than rely on slice order.
+var selectionPolicy = []criterion{
+	{"newer version", compareVersions},
+	{"original page slug over a renamed one", func(a, b VersionCheckResult) int {
+		return boolRank(a.RenamedFrom == "", b.RenamedFrom == "")
+	}},
+	{"anchor on the linked page over one moved to another page", func(a, b VersionCheckResult) int {
+		return boolRank(a.MovedFrom == "", b.MovedFrom == "")
+	}},
+	{"anchor on the linked page over one verified via html-single", func(a, b VersionCheckResult) int {
+		return boolRank(a.AnchorVia == "", b.AnchorVia == "")
+	}},
+	{"verified in this run over imported facts", func(a, b VersionCheckResult) int {
+		return boolRank(!a.Cached, !b.Cached)
+	}},
+}
+
+// CompareCandidates orders two candidate versions by the selection policy:
+// it returns a positive number when a is preferred, a negative one when b
+// is, and zero for candidates of equal standing, with the reason of the
+// rule that decided
+func CompareCandidates(a, b VersionCheckResult) (int, string) {
+	for _, c := range selectionPolicy {
+		if n := c.compare(a, b); n != 0 {
+			return n, c.reason
+		}
+	}
+	return 0, ""
+}
+
+// rankCandidates returns the best and second best of candidates. Of
+// candidates of equal standing, the one listed last wins.
+func rankCandidates(candidates []VersionCheckResult) (best, runnerUp int) {
+	best, runnerUp = -1, -1
+	for i := range candidates {
+		switch {
+		case best < 0 || better(candidates[i], candidates[best]):
+			best, runnerUp = i, best
+		case runnerUp < 0 || better(candidates[i], candidates[runnerUp]):
+			runnerUp = i
+		}
+	}
+	return best, runnerUp
+}
+
+// better reports whether a is preferred over b, or of equal standing
+func better(a, b VersionCheckResult) bool {
+	n, _ := CompareCandidates(a, b)
+	return n >= 0
+}
+
+// compareVersions prefers the higher major.minor version
+func compareVersions(a, b VersionCheckResult) int {
+	va := &parser.OCPDocURL{Version: a.Version}
+	vb := &parser.OCPDocURL{Version: b.Version}
+	if parseVersionInPlace(va) != nil || parseVersionInPlace(vb) != nil {
+		return 0
+	}
+	for i := range va.MajorMinor {
+		if va.MajorMinor[i] != vb.MajorMinor[i] {
+			return va.MajorMinor[i] - vb.MajorMinor[i]
+		}
+	}
+	return 0
+}
+
+// boolRank prefers the candidate for which the rule holds
+func boolRank(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	default:
+		return -1
+	}
+}
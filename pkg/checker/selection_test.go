@@ -0,0 +1,87 @@
+package checker
+
+import "testing"
+
+func TestCompareCandidates(t *testing.T) {
+	tests := []struct {
+		name       string
+		a, b       VersionCheckResult
+		want       int // sign only
+		wantReason string
+	}{
+		{"newer version wins", VersionCheckResult{Version: "4.20"}, VersionCheckResult{Version: "4.19"}, 1, "newer version"},
+		{"minor compared as a number", VersionCheckResult{Version: "4.9"}, VersionCheckResult{Version: "4.10"}, -1, "newer version"},
+		{"newer version beats original slug", VersionCheckResult{Version: "4.20", RenamedFrom: "old"}, VersionCheckResult{Version: "4.19"}, 1, "newer version"},
+		{"newer version beats fresh facts", VersionCheckResult{Version: "4.20", Cached: true}, VersionCheckResult{Version: "4.19"}, 1, "newer version"},
+		{"original slug over renamed", VersionCheckResult{Version: "4.20"}, VersionCheckResult{Version: "4.20", RenamedFrom: "old"}, 1, "original page slug over a renamed one"},
+		{"original slug beats fresh facts", VersionCheckResult{Version: "4.20", Cached: true}, VersionCheckResult{Version: "4.20", RenamedFrom: "old"}, 1, "original page slug over a renamed one"},
+		{"anchor on page over html-single", VersionCheckResult{Version: "4.20", Cached: true}, VersionCheckResult{Version: "4.20", AnchorVia: AnchorViaSingle}, 1, "anchor on the linked page over one verified via html-single"},
+		{"fresh over imported", VersionCheckResult{Version: "4.20", Cached: true}, VersionCheckResult{Version: "4.20"}, -1, "verified in this run over imported facts"},
+		{"equal standing", VersionCheckResult{Version: "4.20", URL: "a"}, VersionCheckResult{Version: "4.20", URL: "b"}, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := CompareCandidates(tt.a, tt.b)
+			if sign(got) != tt.want || reason != tt.wantReason {
+				t.Errorf("CompareCandidates() = %d, %q, want sign %d, %q", got, reason, tt.want, tt.wantReason)
+			}
+			// Swapping the candidates swaps the preference, for the same reason
+			back, backReason := CompareCandidates(tt.b, tt.a)
+			if sign(back) != -tt.want || backReason != tt.wantReason {
+				t.Errorf("CompareCandidates(b, a) = %d, %q, want sign %d, %q", back, backReason, -tt.want, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestBestSuggestion_TieBreaking(t *testing.T) {
+	fresh := VersionCheckResult{Version: "4.20", URL: "fresh"}
+	imported := VersionCheckResult{Version: "4.20", URL: "imported", Cached: true}
+	renamed := VersionCheckResult{Version: "4.20", URL: "renamed", RenamedFrom: "old"}
+	older := VersionCheckResult{Version: "4.19", URL: "older"}
+
+	tests := []struct {
+		name         string
+		candidates   []VersionCheckResult
+		wantBest     string
+		wantRunnerUp string
+		wantReason   string
+	}{
+		{"none", nil, "", "", ""},
+		{"single", []VersionCheckResult{older}, "older", "", ""},
+		{"newest", []VersionCheckResult{older, fresh}, "fresh", "older", "newer version"},
+		{"newest listed first", []VersionCheckResult{fresh, older}, "fresh", "older", "newer version"},
+		{"fresh over imported", []VersionCheckResult{fresh, imported}, "fresh", "imported", "verified in this run over imported facts"},
+		{"imported listed first", []VersionCheckResult{imported, fresh}, "fresh", "imported", "verified in this run over imported facts"},
+		{"original slug over renamed", []VersionCheckResult{renamed, imported}, "imported", "renamed", "original page slug over a renamed one"},
+		{"all candidates", []VersionCheckResult{older, renamed, fresh, imported}, "fresh", "imported", "verified in this run over imported facts"},
+		{"equal standing keeps the last", []VersionCheckResult{fresh, {Version: "4.20", URL: "same"}}, "same", "fresh", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &CheckResult{NewerVersions: tt.candidates}
+			best, ok := r.BestSuggestion()
+			if ok != (tt.wantBest != "") || best.URL != tt.wantBest {
+				t.Errorf("BestSuggestion() = %q, %v, want %q", best.URL, ok, tt.wantBest)
+			}
+			runnerUp, reason, ok := r.RunnerUp()
+			if ok != (tt.wantRunnerUp != "") || runnerUp.URL != tt.wantRunnerUp || reason != tt.wantReason {
+				t.Errorf("RunnerUp() = %q, %q, %v, want %q, %q", runnerUp.URL, reason, ok, tt.wantRunnerUp, tt.wantReason)
+			}
+		})
+	}
+}
+
+// sign returns -1, 0 or 1 according to the sign of n
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,265 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/anchors"
+	"github.com/sebrandon1/ocp-doc-checker/pkg/parser"
+)
+
+// SetSiblingSearch sets how many other pages of a multi-page guide are
+// probed for an anchor missing from its page at the newest version checked,
+// e.g. because the section moved to a sibling page. Candidates are picked
+// from the guide's table of contents by how much their titles resemble the
+// anchor, and an anchor matches exactly or with another assembly context
+// suffix. A match becomes a candidate with MovedFrom set. Each search sends
+// at most one request for the table of contents, retries aside, and
+// maxProbes for pages, fewer when they are already known. 0, the default, disables the search.
+// Call it before checking URLs.
+func (c *Checker) SetSiblingSearch(maxProbes int) {
+	c.siblingProbes = max(maxProbes, 0)
+}
+
+// siblingRequestKey marks the context of the requests of a sibling search,
+// for egressTransport to count them
+type siblingRequestKey struct{}
+
+// tocEntry is a page listed in the table of contents of a guide
+type tocEntry struct {
+	page  string
+	title string
+}
+
+// searchSiblings looks for the anchor of docURL, missing from the page of
+// v, on the other pages of the guide at v's version, and returns the first
+// page that has it
+func (c *Checker) searchSiblings(ctx context.Context, docURL *parser.OCPDocURL, v VersionCheckResult) (VersionCheckResult, bool) {
+	if c.siblingProbes == 0 || docURL.Format != "html" || !v.Exists || !v.HasAnchor || v.AnchorExists || v.AnchorIndeterminate || v.CrossDocument() {
+		return v, false
+	}
+	applyHeuristic(&v.heuristics, HeuristicSiblingPage)
+	c.siblingSearches.Add(1)
+	ctx = context.WithValue(ctx, siblingRequestKey{}, true)
+
+	// The page the anchor was missing from, under its renamed slug if any
+	skip := []string{docURL.Page}
+	if current, err := parser.ParseOCPDocURL(v.URL); err == nil {
+		skip = append(skip, current.Page)
+	}
+
+	for _, page := range siblingCandidates(c.tableOfContents(ctx, docURL, v.Version), docURL.Anchor, skip, c.siblingProbes) {
+		sibling := *docURL
+		sibling.Page = page
+		sibling.Anchor = ""
+		facts, err := c.pageFacts(ctx, sibling.BuildURL(v.Version)+"#"+docURL.Anchor)
+		if err != nil || !facts.Exists {
+			continue
+		}
+		anchor, ok := matchAnchor(facts.AnchorIDs, docURL.Anchor)
+		if !ok {
+			continue
+		}
+
+		sibling.Anchor = anchor
+		moved := c.checkURL(ctx, sibling.BuildURL(v.Version))
+		if !moved.AnchorExists {
+			continue
+		}
+		moved.Version = v.Version
+		moved.MovedFrom = docURL.Page
+		for _, h := range v.heuristics {
+			applyHeuristic(&moved.heuristics, h)
+		}
+		return moved, true
+	}
+	return v, false
+}
+
+// tableOfContents returns the pages of the multi-page guide of docURL at
+// version, as listed on its first page, which is fetched once per guide
+// and version, with retries. A lookup of a table of contents being fetched
+// waits for that request. A guide without a first page has an empty table
+// of contents; one whose request failed is empty for this search only.
+func (c *Checker) tableOfContents(ctx context.Context, docURL *parser.OCPDocURL, version string) []tocEntry {
+	key := [2]string{docURL.Document, version}
+	for {
+		c.tocsMu.Lock()
+		if entries, ok := c.tocs[key]; ok {
+			c.tocsMu.Unlock()
+			return entries
+		}
+		wait, busy := c.tocsInflight[key]
+		if !busy {
+			if c.tocsInflight == nil {
+				c.tocsInflight = make(map[[2]string]chan struct{})
+			}
+			c.tocsInflight[key] = make(chan struct{})
+		}
+		c.tocsMu.Unlock()
+		if !busy {
+			break
+		}
+		// The request in flight may fail; the table is looked up again once
+		// it is over
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	defer func() {
+		c.tocsMu.Lock()
+		close(c.tocsInflight[key])
+		delete(c.tocsInflight, key)
+		c.tocsMu.Unlock()
+	}()
+
+	first := *docURL
+	first.Page = "index"
+	first.Anchor = ""
+	indexURL := first.BuildURL(version)
+	var entries []tocEntry
+	_, err := c.withRetries(ctx, indexURL, func(ctx context.Context) error {
+		var err error
+		entries, err = c.fetchTableOfContents(ctx, indexURL)
+		return err
+	})
+	if err != nil {
+		// Another search may still get the answer
+		c.logger.WarnContext(ctx, "failed to read the table of contents", "url", indexURL, "error", err)
+		return nil
+	}
+
+	c.tocsMu.Lock()
+	if c.tocs == nil {
+		c.tocs = make(map[[2]string][]tocEntry)
+	}
+	c.tocs[key] = entries
+	c.tocsMu.Unlock()
+	return entries
+}
+
+// fetchTableOfContents requests the first page of a multi-page guide and
+// returns the pages of the same guide and version it links to, in order
+func (c *Checker) fetchTableOfContents(ctx context.Context, indexURL string) ([]tocEntry, error) {
+	index, err := parser.ParseOCPDocURL(indexURL)
+	if err != nil {
+		return nil, err
+	}
+	base, err := url.Parse(indexURL)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(ctx, http.MethodGet, indexURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	// A guide without a first page lists no pages, which retrying would
+	// not change; other answers are worth retrying
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &RequestError{URL: indexURL, StatusCode: resp.StatusCode, Err: errUnexpectedStatus}
+	}
+	links, err := anchors.ExtractLinks(resp.Body)
+	if err != nil {
+		c.logger.WarnContext(ctx, "failed to parse page", "url", indexURL, "error", err)
+		return nil, permanentError{fmt.Errorf("failed to parse HTML: %w", err)}
+	}
+
+	var entries []tocEntry
+	for _, link := range links {
+		ref, err := base.Parse(link.Href)
+		if err != nil {
+			continue
+		}
+		ref.Fragment = ""
+		page, err := parser.ParseOCPDocURL(ref.String())
+		if err != nil || page.Version != index.Version || page.Format != index.Format || page.Document != index.Document {
+			continue
+		}
+		if !slices.ContainsFunc(entries, func(e tocEntry) bool { return e.page == page.Page }) {
+			entries = append(entries, tocEntry{page: page.Page, title: link.Text})
+		}
+	}
+	return entries, nil
+}
+
+// siblingCandidates returns up to n pages of entries, other than those of
+// skip, whose title or slug shares a word with anchor, the most words
+// first and in table of contents order among equals
+func siblingCandidates(entries []tocEntry, anchor string, skip []string, n int) []string {
+	base, _ := anchors.SplitContext(anchor)
+	want := significantWords(base)
+
+	type candidate struct {
+		page  string
+		score int
+	}
+	var candidates []candidate
+	for _, e := range entries {
+		if slices.Contains(skip, e.page) {
+			continue
+		}
+		score := 0
+		words := append(significantWords(anchors.SlugForHeading(e.title)), significantWords(e.page)...)
+		for _, w := range want {
+			if slices.Contains(words, w) {
+				score++
+			}
+		}
+		if score > 0 {
+			candidates = append(candidates, candidate{e.page, score})
+		}
+	}
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		return b.score - a.score
+	})
+
+	var pages []string
+	for _, cand := range candidates[:min(n, len(candidates))] {
+		pages = append(pages, cand.page)
+	}
+	return pages
+}
+
+// stopWords are too common in section titles to tell them apart
+var stopWords = map[string]bool{"about": true, "and": true, "chapter": true, "for": true, "the": true, "using": true, "with": true, "your": true}
+
+// significantWords splits a slug into its words that say something about
+// the section: neither short words, numbers nor stop words
+func significantWords(slug string) []string {
+	var words []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(slug), func(r rune) bool {
+		return r == '-' || r == '_' || r == '.'
+	}) {
+		if len(w) > 2 && !stopWords[w] && strings.Trim(w, "0123456789") != "" {
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+// matchAnchor returns the id of ids that anchor names: the anchor itself,
+// or else the first id with the same base and another assembly context,
+// which a section gets when its module is included by another assembly
+func matchAnchor(ids []string, anchor string) (string, bool) {
+	if slices.Contains(ids, anchor) {
+		return anchor, true
+	}
+	base, _ := anchors.SplitContext(anchor)
+	for _, id := range ids {
+		if idBase, context := anchors.SplitContext(id); idBase == base && context != "" {
+			return id, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,275 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/parser"
+)
+
+// siblingDocs models a section of the networking guide that moved from the
+// ingress-operator page to its own ingress-sharding page in 4.18, taking the
+// assembly context of its new page in its id
+func siblingDocs() map[string]string {
+	const docPath = "/en/documentation/openshift_container_platform/%s/html/networking/%s"
+	pages := make(map[string]string)
+	for _, v := range []string{"4.16", "4.17"} {
+		pages[fmt.Sprintf(docPath, v, "ingress-operator")] = `<html><body>
+			<section id="nw-ingress-operator_ingress-operator"><h2>Ingress Operator</h2></section>
+			<section id="nw-ingress-sharding_ingress-operator"><h3>Ingress sharding</h3></section>
+		</body></html>`
+	}
+	pages[fmt.Sprintf(docPath, "4.18", "ingress-operator")] = `<html><body>
+		<section id="nw-ingress-operator_ingress-operator"><h2>Ingress Operator</h2></section>
+	</body></html>`
+	pages[fmt.Sprintf(docPath, "4.18", "ingress-node-firewall")] = `<html><body>
+		<section id="nw-ingress-node-firewall_ingress-node-firewall"><h2>Ingress Node Firewall</h2></section>
+	</body></html>`
+	pages[fmt.Sprintf(docPath, "4.18", "ingress-sharding")] = `<html><body>
+		<section id="nw-ingress-sharding_ingress-sharding"><h2>Ingress sharding</h2></section>
+		<section id="ingress-sharding-examples"><h3>Examples</h3></section>
+	</body></html>`
+	pages[fmt.Sprintf(docPath, "4.18", "index")] = `<html><body><nav><ol>
+		<li><a href="ingress-operator">Chapter 1. Ingress Operator</a></li>
+		<li><a href="/en/documentation/openshift_container_platform/4.18/html/networking/dns-operator">Chapter 2. DNS Operator</a></li>
+		<li><a href="ingress-node-firewall">Chapter 3. Ingress Node Firewall Operator</a></li>
+		<li><a href="ingress-sharding#nw-ingress-sharding_ingress-sharding">Chapter 4. Configuring ingress sharding</a></li>
+		<li><a href="/en/documentation/openshift_container_platform/4.18/html/security/ingress-sharding">Ingress sharding security</a></li>
+	</ol></nav></body></html>`
+	return pages
+}
+
+func TestCheck_SiblingSearch(t *testing.T) {
+	const docURL = "https://docs.redhat.com/en/documentation/openshift_container_platform/%s/html/networking/%s"
+
+	tests := []struct {
+		name   string
+		url    string
+		probes int
+		// want is the suggested URL, empty when the URL is up to date
+		want          string
+		wantMovedFrom string
+		// wantRequests are the requests of the sibling search
+		wantRequests int64
+	}{
+		{
+			name: "disabled",
+			url:  fmt.Sprintf(docURL, "4.16", "ingress-operator#nw-ingress-sharding_ingress-operator"),
+			want: fmt.Sprintf(docURL, "4.17", "ingress-operator#nw-ingress-sharding_ingress-operator"),
+		},
+		{
+			name:          "moved with a new assembly context",
+			url:           fmt.Sprintf(docURL, "4.16", "ingress-operator#nw-ingress-sharding_ingress-operator"),
+			probes:        3,
+			want:          fmt.Sprintf(docURL, "4.18", "ingress-sharding#nw-ingress-sharding_ingress-sharding"),
+			wantMovedFrom: "ingress-operator",
+			// The table of contents, then the best matching page
+			wantRequests: 2,
+		},
+		{
+			name:         "missing from every page, probes capped",
+			url:          fmt.Sprintf(docURL, "4.17", "ingress-operator#nw-ingress-removed_ingress-operator"),
+			probes:       1,
+			wantRequests: 2,
+		},
+		{
+			name:         "no page resembles the anchor",
+			url:          fmt.Sprintf(docURL, "4.17", "ingress-operator#nw-egress-ip_ingress-operator"),
+			probes:       3,
+			wantRequests: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeDocsChecker(t, siblingDocs())
+			c.SetVersions([]string{"4.16", "4.17", "4.18"})
+			c.SetFormatFallback(false)
+			c.SetSiblingSearch(tt.probes)
+
+			result, err := c.Check(tt.url)
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			best, ok := result.BestSuggestion()
+			if ok != (tt.want != "") || best.URL != tt.want {
+				t.Errorf("BestSuggestion() = %q, %v; want %q", best.URL, ok, tt.want)
+			}
+			if best.MovedFrom != tt.wantMovedFrom {
+				t.Errorf("MovedFrom = %q, want %q", best.MovedFrom, tt.wantMovedFrom)
+			}
+			searched := slices.Contains(result.AppliedHeuristics, HeuristicSiblingPage)
+			if searched != (tt.probes > 0) {
+				t.Errorf("AppliedHeuristics = %v, sibling search applied = %v", result.AppliedHeuristics, searched)
+			}
+
+			stats := c.Stats()
+			if stats.SiblingRequests != tt.wantRequests {
+				t.Errorf("Stats().SiblingRequests = %d, want %d", stats.SiblingRequests, tt.wantRequests)
+			}
+			if want := int64(min(tt.probes, 1)); stats.SiblingSearches != want {
+				t.Errorf("Stats().SiblingSearches = %d, want %d", stats.SiblingSearches, want)
+			}
+		})
+	}
+}
+
+func TestCheck_SiblingSearchSharesPages(t *testing.T) {
+	const docURL = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html/networking/ingress-operator#"
+
+	c := newFakeDocsChecker(t, siblingDocs())
+	c.SetVersions([]string{"4.16", "4.17", "4.18"})
+	c.SetFormatFallback(false)
+	c.SetSiblingSearch(3)
+
+	// The table of contents and pages probed are requested once for every
+	// anchor of the guide
+	results, err := c.CheckAll([]string{docURL + "nw-ingress-sharding_ingress-operator", docURL + "ingress-sharding-examples"})
+	if err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	for i, want := range []string{"nw-ingress-sharding_ingress-sharding", "ingress-sharding-examples"} {
+		best, ok := results[i].BestSuggestion()
+		if !ok || best.Version != "4.18" || best.URL != "https://docs.redhat.com/en/documentation/openshift_container_platform/4.18/html/networking/ingress-sharding#"+want {
+			t.Errorf("URL %d suggestion = %+v, want ingress-sharding#%s at 4.18", i, best, want)
+		}
+	}
+	if got := c.Stats().SiblingRequests; got != 2 {
+		t.Errorf("Stats().SiblingRequests = %d, want 2", got)
+	}
+}
+
+func TestTableOfContents_Concurrent(t *testing.T) {
+	const indexPath = "/en/documentation/openshift_container_platform/4.18/html/%s/index"
+	index := `<html><body><a href="ingress-operator">Chapter 1. Ingress Operator</a></body></html>`
+
+	// Each index answers only once both are being requested, so a lookup
+	// holding the others back fails
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	both := make(chan struct{})
+	c := newHandlerChecker(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		if len(requests) == 2 && requests[r.URL.Path] == 1 {
+			close(both)
+		}
+		mu.Unlock()
+		select {
+		case <-both:
+			_, _ = io.WriteString(w, strings.Replace(index, "ingress-operator", path.Base(path.Dir(r.URL.Path))+"-page", 1))
+		case <-time.After(2 * time.Second):
+			http.Error(w, "the other index was not requested", http.StatusServiceUnavailable)
+		}
+	}))
+	if err := c.SetRetryPolicy(RetryPolicy{MaxAttempts: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	guides := []string{"networking", "security", "networking"}
+	tocs := make([][]tocEntry, len(guides))
+	var wg sync.WaitGroup
+	for i, guide := range guides {
+		wg.Go(func() {
+			docURL, err := parser.ParseOCPDocURL("https://docs.redhat.com" + strings.Replace(fmt.Sprintf(indexPath, guide), "index", "ingress", 1))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			tocs[i] = c.tableOfContents(context.Background(), docURL, "4.18")
+		})
+	}
+	wg.Wait()
+
+	for i, guide := range guides {
+		if len(tocs[i]) != 1 || tocs[i][0].page != guide+"-page" {
+			t.Errorf("tableOfContents(%s) = %+v, want the %s-page entry", guide, tocs[i], guide)
+		}
+	}
+	for _, guide := range []string{"networking", "security"} {
+		if n := requests[fmt.Sprintf(indexPath, guide)]; n != 1 {
+			t.Errorf("%s index requested %d time(s), want once", guide, n)
+		}
+	}
+}
+
+func TestTableOfContents_Retries(t *testing.T) {
+	const indexPath = "/en/documentation/openshift_container_platform/4.18/html/networking/index"
+	docURL, err := parser.ParseOCPDocURL("https://docs.redhat.com" + strings.Replace(indexPath, "index", "ingress", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		// failures are the requests answered 503 before the index
+		failures int
+		attempts int
+		// want are the entries of each of two lookups
+		want         [2]int
+		wantRequests int
+	}{
+		{"retried", 1, 2, [2]int{1, 1}, 2},
+		// A failed request is not cached as an empty table of contents
+		{"failed", 1, 1, [2]int{0, 1}, 2},
+		{"missing index", -1, 2, [2]int{0, 0}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int
+			c := newHandlerChecker(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				switch {
+				case r.URL.Path != indexPath || tt.failures < 0:
+					http.NotFound(w, r)
+				case requests <= tt.failures:
+					http.Error(w, "try again", http.StatusServiceUnavailable)
+				default:
+					_, _ = io.WriteString(w, `<html><body><a href="ingress-operator">Chapter 1. Ingress Operator</a></body></html>`)
+				}
+			}))
+			if err := c.SetRetryPolicy(RetryPolicy{MaxAttempts: tt.attempts}); err != nil {
+				t.Fatal(err)
+			}
+
+			for i, want := range tt.want {
+				if got := c.tableOfContents(context.Background(), docURL, "4.18"); len(got) != want {
+					t.Errorf("lookup %d: tableOfContents() = %+v, want %d entries", i+1, got, want)
+				}
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestMatchAnchor(t *testing.T) {
+	ids := []string{"nw-ingress_networking", "nw-ingress_ingress-operator", "overview"}
+	tests := []struct {
+		anchor string
+		want   string
+		wantOK bool
+	}{
+		{"nw-ingress_ingress-operator", "nw-ingress_ingress-operator", true},
+		{"nw-ingress_dns", "nw-ingress_networking", true},
+		{"nw-ingress", "nw-ingress_networking", true},
+		{"overview_networking", "", false},
+		{"nw-egress_networking", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := matchAnchor(ids, tt.anchor)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("matchAnchor(%q) = %q, %v; want %q, %v", tt.anchor, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
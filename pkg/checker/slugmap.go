@@ -0,0 +1,131 @@
+package checker
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/parser"
+)
+
+//go:embed slugmap.json
+var embeddedSlugMap []byte
+
+// SlugRename records a multi-page slug that Red Hat renamed between releases
+type SlugRename struct {
+	Document string `json:"document"`        // e.g., "networking"
+	From     string `json:"from"`            // slug used before the rename
+	To       string `json:"to"`              // slug used from Since onwards
+	Since    string `json:"since"`           // first version using To
+	Until    string `json:"until,omitempty"` // last version using To, empty if still current
+}
+
+// SlugMap holds the known page slug renames
+type SlugMap struct {
+	Renames []SlugRename `json:"renames"`
+}
+
+// DefaultSlugMap returns the slug map embedded in the binary. It holds no
+// renames yet: entries are added once a rename is confirmed on
+// docs.redhat.com, so until then only a map of SetSlugMap renames pages.
+func DefaultSlugMap() *SlugMap {
+	m, err := LoadSlugMap(bytes.NewReader(embeddedSlugMap))
+	if err != nil {
+		// The embedded file is validated by tests, so this is a build problem
+		panic(fmt.Sprintf("invalid embedded slug map: %v", err))
+	}
+	return m
+}
+
+// LoadSlugMapFile loads a slug map from a JSON file
+func LoadSlugMapFile(path string) (*SlugMap, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return LoadSlugMap(f)
+}
+
+// LoadSlugMap decodes and validates a slug map
+func LoadSlugMap(r io.Reader) (*SlugMap, error) {
+	var m SlugMap
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to decode slug map: %w", err)
+	}
+
+	for i, rename := range m.Renames {
+		if rename.Document == "" || rename.From == "" || rename.To == "" {
+			return nil, fmt.Errorf("slug map entry %d: document, from and to are required", i)
+		}
+		if _, err := versionFloat(rename.Since); err != nil {
+			return nil, fmt.Errorf("slug map entry %d: invalid since version %q", i, rename.Since)
+		}
+		if rename.Until != "" {
+			if _, err := versionFloat(rename.Until); err != nil {
+				return nil, fmt.Errorf("slug map entry %d: invalid until version %q", i, rename.Until)
+			}
+		}
+	}
+
+	return &m, nil
+}
+
+// Lookup returns the slug a page of the given document uses at version, if a
+// rename applies. Chained renames (a → b → c) are followed.
+func (m *SlugMap) Lookup(document, page, version string) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+
+	target, err := versionFloat(version)
+	if err != nil {
+		return "", false
+	}
+
+	renamed := page
+	// Each rename can apply at most once, which also guards against cycles
+	for range m.Renames {
+		next, ok := m.next(document, renamed, target)
+		if !ok {
+			break
+		}
+		renamed = next
+	}
+
+	return renamed, renamed != page
+}
+
+// next returns the slug the first applicable rename maps page to
+func (m *SlugMap) next(document, page string, target float64) (string, bool) {
+	for _, rename := range m.Renames {
+		if rename.Document != document || rename.From != page {
+			continue
+		}
+		since, _ := versionFloat(rename.Since)
+		if target < since {
+			continue
+		}
+		if rename.Until != "" {
+			until, _ := versionFloat(rename.Until)
+			if target > until {
+				continue
+			}
+		}
+		return rename.To, true
+	}
+	return "", false
+}
+
+// versionFloat converts a "major.minor" version for comparison
+func versionFloat(version string) (float64, error) {
+	doc := &parser.OCPDocURL{Version: version}
+	if err := parseVersionInPlace(doc); err != nil {
+		return 0, err
+	}
+	return doc.GetVersionFloat(), nil
+}
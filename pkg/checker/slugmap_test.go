@@ -0,0 +1,111 @@
+package checker
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefaultSlugMap(t *testing.T) {
+	// Panics if the embedded file is invalid
+	if m := DefaultSlugMap(); m == nil {
+		t.Fatal("DefaultSlugMap() = nil")
+	}
+}
+
+func TestLoadSlugMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{
+			name:  "empty map",
+			input: `{"renames": []}`,
+			want:  0,
+		},
+		{
+			name:  "valid entries",
+			input: `{"renames": [{"document": "networking", "from": "a", "to": "b", "since": "4.16"}, {"document": "storage", "from": "c", "to": "d", "since": "4.12", "until": "4.14"}]}`,
+			want:  2,
+		},
+		{
+			name:    "invalid JSON",
+			input:   `{"renames": [`,
+			wantErr: true,
+		},
+		{
+			name:    "missing target slug",
+			input:   `{"renames": [{"document": "networking", "from": "a", "since": "4.16"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid since version",
+			input:   `{"renames": [{"document": "networking", "from": "a", "to": "b", "since": "latest"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid until version",
+			input:   `{"renames": [{"document": "networking", "from": "a", "to": "b", "since": "4.16", "until": "x"}]}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := LoadSlugMap(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadSlugMap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(m.Renames) != tt.want {
+				t.Errorf("LoadSlugMap() loaded %d renames, want %d", len(m.Renames), tt.want)
+			}
+		})
+	}
+}
+
+func TestSlugMapLookup(t *testing.T) {
+	m := &SlugMap{Renames: []SlugRename{
+		{Document: "networking", From: "ingress-operator", To: "configuring-ingress", Since: "4.14"},
+		{Document: "networking", From: "configuring-ingress", To: "ingress-and-load-balancing", Since: "4.18"},
+		{Document: "storage", From: "persistent-storage", To: "understanding-persistent-storage", Since: "4.12", Until: "4.15"},
+	}}
+
+	tests := []struct {
+		name     string
+		document string
+		page     string
+		version  string
+		want     string
+		wantOK   bool
+	}{
+		{"before the rename", "networking", "ingress-operator", "4.13", "", false},
+		{"at the rename", "networking", "ingress-operator", "4.14", "configuring-ingress", true},
+		{"chained rename", "networking", "ingress-operator", "4.19", "ingress-and-load-balancing", true},
+		{"within until range", "storage", "persistent-storage", "4.15", "understanding-persistent-storage", true},
+		{"after until range", "storage", "persistent-storage", "4.16", "", false},
+		{"unknown document", "security", "ingress-operator", "4.16", "", false},
+		{"unknown page", "networking", "index", "4.16", "", false},
+		{"invalid version", "networking", "ingress-operator", "next", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := m.Lookup(tt.document, tt.page, tt.version)
+			if ok != tt.wantOK {
+				t.Fatalf("Lookup() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && got != tt.want {
+				t.Errorf("Lookup() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	var nilMap *SlugMap
+	if _, ok := nilMap.Lookup("networking", "ingress-operator", "4.16"); ok {
+		t.Error("nil SlugMap should never apply a rename")
+	}
+}
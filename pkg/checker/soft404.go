@@ -0,0 +1,90 @@
+package checker
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// DefaultSoft404Markers are the messages of the not-found page that
+// docs.redhat.com serves with 200 OK for some retired pages
+var DefaultSoft404Markers = []string{
+	"The page you requested could not be found",
+}
+
+// soft404ScanBytes is how much of a page is searched for soft-404 markers,
+// and all that is read of a page without an anchor
+const soft404ScanBytes = 64 << 10
+
+// SetSoft404Markers sets the messages that make a page answered with 2xx
+// count as missing, a soft 404. They are matched without regard to case or
+// runs of whitespace against the text of the first 64 KiB of the page, its
+// title included. To find them, pages without an anchor are requested with
+// a GET that reads only that much, rather than HEAD. nil disables the
+// detection and brings back HEAD. It is DefaultSoft404Markers by default.
+func (c *Checker) SetSoft404Markers(markers []string) {
+	c.soft404Markers = nil
+	for _, m := range markers {
+		if m = normalizeText(m); m != "" {
+			c.soft404Markers = append(c.soft404Markers, m)
+		}
+	}
+}
+
+// soft404Marker returns the first marker found in the text of prefix, the
+// start of a page, or "" when it shows none
+func (c *Checker) soft404Marker(prefix []byte) string {
+	if len(c.soft404Markers) == 0 {
+		return ""
+	}
+	text := normalizeText(pageText(prefix))
+	for _, m := range c.soft404Markers {
+		if strings.Contains(text, m) {
+			return m
+		}
+	}
+	return ""
+}
+
+// pageText returns the text of an HTML page, which may be cut anywhere,
+// leaving out scripts and styles
+func pageText(page []byte) string {
+	var b strings.Builder
+	z := html.NewTokenizer(bytes.NewReader(page))
+	skip := false
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return b.String()
+		case html.StartTagToken:
+			name, _ := z.TagName()
+			skip = string(name) == "script" || string(name) == "style"
+		case html.EndTagToken:
+			skip = false
+		case html.TextToken:
+			if !skip {
+				b.Write(z.Text())
+				b.WriteByte(' ')
+			}
+		}
+	}
+}
+
+// normalizeText lowercases s and collapses its runs of whitespace
+func normalizeText(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// prefixWriter keeps the first max bytes written to it
+type prefixWriter struct {
+	b   []byte
+	max int
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	if room := w.max - len(w.b); room > 0 {
+		w.b = append(w.b, p[:min(room, len(p))]...)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,126 @@
+package checker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCheck_Soft404(t *testing.T) {
+	soft404, err := os.ReadFile(filepath.Join("testdata", "soft-404.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const docPath = "/en/documentation/openshift_container_platform/%s/html/networking/%s"
+	page := `<html><head><title>Networking | Red Hat Documentation</title></head><body><h2 id="ingress">Ingress</h2></body></html>`
+	pages := map[string]string{
+		fmt.Sprintf(docPath, "4.16", "ingress"): page,
+		fmt.Sprintf(docPath, "4.17", "ingress"): page,
+		// Retired at 4.18, but still answered with 200
+		fmt.Sprintf(docPath, "4.18", "ingress"): string(soft404),
+		// Another wording, as the site might adopt
+		fmt.Sprintf(docPath, "4.16", "routes"): page,
+		fmt.Sprintf(docPath, "4.17", "routes"): `<html><body><h1>This content is no longer available</h1></body></html>`,
+		fmt.Sprintf(docPath, "4.18", "routes"): `<html><body><h1>This content is no longer available</h1></body></html>`,
+		// A page that quotes the message past the part that is searched
+		fmt.Sprintf(docPath, "4.16", "troubleshooting"): page,
+		fmt.Sprintf(docPath, "4.17", "troubleshooting"): page,
+		fmt.Sprintf(docPath, "4.18", "troubleshooting"): `<html><body><h2 id="ingress">Ingress</h2>` + strings.Repeat("<p>Filler.</p>\n", 5000) +
+			`<pre>The page you requested could not be found</pre></body></html>`,
+	}
+	urlAt := func(page, fragment string) string {
+		return "https://docs.redhat.com" + fmt.Sprintf(docPath, "4.16", page) + fragment
+	}
+
+	tests := []struct {
+		name       string
+		url        string
+		markers    []string // nil keeps the defaults
+		disabled   bool
+		wantLatest string
+		wantMarker string // the marker of 4.18
+	}{
+		{name: "page with anchor", url: urlAt("ingress", "#ingress"), wantLatest: "4.17", wantMarker: "the page you requested could not be found"},
+		{name: "page without anchor", url: urlAt("ingress", ""), wantLatest: "4.17", wantMarker: "the page you requested could not be found"},
+		{name: "detection disabled", url: urlAt("ingress", ""), disabled: true, wantLatest: "4.18"},
+		{name: "default markers only", url: urlAt("routes", ""), wantLatest: "4.18"},
+		{
+			name:       "added marker",
+			url:        urlAt("routes", ""),
+			markers:    append(slices.Clone(DefaultSoft404Markers), "  This content is\nno longer AVAILABLE "),
+			wantLatest: "4.16",
+			wantMarker: "this content is no longer available",
+		},
+		{name: "marker past the searched part", url: urlAt("troubleshooting", "#ingress"), wantLatest: "4.18"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeDocsChecker(t, pages)
+			c.SetVersions([]string{"4.16", "4.17", "4.18"})
+			c.SetFormatFallback(false)
+			if tt.markers != nil {
+				c.SetSoft404Markers(tt.markers)
+			}
+			if tt.disabled {
+				c.SetSoft404Markers(nil)
+			}
+
+			result, err := c.Check(tt.url)
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if result.LatestVersion != tt.wantLatest {
+				t.Errorf("LatestVersion = %s, want %s", result.LatestVersion, tt.wantLatest)
+			}
+			var markers []string
+			for _, v := range result.AllResults {
+				if v.NotFoundMarker != "" {
+					markers = append(markers, v.Version+": "+v.NotFoundMarker)
+					if v.Exists {
+						t.Errorf("version %s shows %q but exists", v.Version, v.NotFoundMarker)
+					}
+				}
+			}
+			var want []string
+			if tt.wantMarker != "" {
+				want = []string{"4.18: " + tt.wantMarker}
+				if tt.wantLatest == "4.16" {
+					want = []string{"4.17: " + tt.wantMarker, want[0]}
+				}
+			}
+			if !reflect.DeepEqual(markers, want) {
+				t.Errorf("soft 404s = %q, want %q", markers, want)
+			}
+			if slices.Contains(result.AppliedHeuristics, HeuristicSoft404) != (want != nil) {
+				t.Errorf("AppliedHeuristics = %v, want %s applied = %v", result.AppliedHeuristics, HeuristicSoft404, want != nil)
+			}
+			// A soft 404 is never suggested, so -fix never writes it
+			if best, ok := result.BestSuggestion(); ok && best.NotFoundMarker != "" {
+				t.Errorf("BestSuggestion() = %s, a soft 404", best.Version)
+			}
+		})
+	}
+}
+
+func TestPageText(t *testing.T) {
+	soft404, err := os.ReadFile(filepath.Join("testdata", "soft-404.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := normalizeText(pageText(soft404))
+	if !strings.Contains(text, "page not found the page you requested could not be found.") {
+		t.Errorf("pageText() = %q, want the message with its whitespace collapsed", text)
+	}
+	if strings.Contains(text, "datalayer") || strings.Contains(text, "font-size") {
+		t.Errorf("pageText() = %q, want no script or style", text)
+	}
+	// A page cut in the middle of a tag still yields the text before it
+	if got := normalizeText(pageText([]byte("<p>Not found</p><a hr"))); got != "not found" {
+		t.Errorf("pageText() of a cut page = %q, want %q", got, "not found")
+	}
+}
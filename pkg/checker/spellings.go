@@ -0,0 +1,50 @@
+package checker
+
+import "github.com/sebrandon1/ocp-doc-checker/pkg/parser"
+
+// Spelling is a checked URL together with the format it is spelled in
+type Spelling struct {
+	Result *CheckResult
+	Format string // e.g., "html-single" or "html"
+}
+
+// GroupSpellings returns the groups of results that are different format
+// spellings (html and html-single) of the same logical section, in order of
+// first appearance. Results whose URLs only appear in one format are left
+// out. Each spelling is still checked on its own, since availability can
+// differ between formats.
+func GroupSpellings(results []*CheckResult) [][]Spelling {
+	var keys []string
+	groups := make(map[string][]Spelling)
+
+	for _, result := range results {
+		docURL, err := parser.ParseOCPDocURL(result.OriginalURL)
+		if err != nil {
+			continue
+		}
+
+		key := docURL.SectionKey()
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], Spelling{Result: result, Format: docURL.Format})
+	}
+
+	var spellings [][]Spelling
+	for _, key := range keys {
+		if group := groups[key]; hasMixedFormats(group) {
+			spellings = append(spellings, group)
+		}
+	}
+	return spellings
+}
+
+// hasMixedFormats reports whether a group uses more than one format
+func hasMixedFormats(group []Spelling) bool {
+	for _, s := range group[1:] {
+		if s.Format != group[0].Format {
+			return true
+		}
+	}
+	return false
+}
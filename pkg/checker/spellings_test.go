@@ -0,0 +1,45 @@
+package checker
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroupSpellings(t *testing.T) {
+	const base = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.17/"
+
+	results := []*CheckResult{
+		{OriginalURL: base + "html-single/networking/index#configuring-ingress"},
+		{OriginalURL: base + "html/storage/index"},
+		{OriginalURL: base + "html/networking/configuring-ingress-cluster-traffic#configuring-ingress"},
+		{OriginalURL: base + "html/networking/ingress#configuring-ingress"},
+		{OriginalURL: base + "html-single/storage/index#pv"},
+		{OriginalURL: base + "html/operators/index"},
+		{OriginalURL: base + "html-single/operators/index"},
+	}
+
+	var got [][]string
+	for _, group := range GroupSpellings(results) {
+		var urls []string
+		for _, s := range group {
+			urls = append(urls, s.Format+" "+s.Result.OriginalURL[len(base):])
+		}
+		got = append(got, urls)
+	}
+
+	want := [][]string{
+		{
+			"html-single html-single/networking/index#configuring-ingress",
+			"html html/networking/configuring-ingress-cluster-traffic#configuring-ingress",
+			"html html/networking/ingress#configuring-ingress",
+		},
+		{
+			"html html/operators/index",
+			"html-single html-single/operators/index",
+		},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GroupSpellings() =\n%v\nwant\n%v", got, want)
+	}
+}
@@ -0,0 +1,73 @@
+package checker
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/parser"
+)
+
+// titleSuffix ends the <title> of every documentation page
+const titleSuffix = " | Red Hat Documentation"
+
+// titleVersionRe matches the version component of a page title
+var titleVersionRe = regexp.MustCompile(`^\d+\.\d+$`)
+
+// GuideTitle extracts the guide name from a documentation page title such as
+// "Chapter 3. Ingress | Networking | OpenShift Container Platform | 4.17 |
+// Red Hat Documentation". A title in another shape is returned without the
+// common suffix, and an empty title stays empty.
+func GuideTitle(pageTitle string) string {
+	title := strings.TrimSuffix(strings.Join(strings.Fields(pageTitle), " "), titleSuffix)
+
+	// ... | <guide> | <product> | <version>
+	parts := strings.Split(title, " | ")
+	if n := len(parts); n >= 3 && titleVersionRe.MatchString(parts[n-1]) {
+		return parts[n-3]
+	}
+	return title
+}
+
+// recordTitle caches the guide title of a fetched page per (document,
+// version). Pages without a usable title are ignored.
+func (c *Checker) recordTitle(pageURL, pageTitle string) {
+	title := GuideTitle(pageTitle)
+	if title == "" {
+		return
+	}
+	docURL, err := parser.ParseOCPDocURL(pageURL)
+	if err != nil {
+		return
+	}
+
+	c.titlesMu.Lock()
+	defer c.titlesMu.Unlock()
+	c.titles[[2]string{docURL.Document, docURL.Version}] = title
+}
+
+// DocumentTitle returns the guide title of document at version, if a page
+// of it was fetched. Titles are only known for pages fetched to check an
+// anchor; existence checks without an anchor do not download the page.
+func (c *Checker) DocumentTitle(document, version string) (string, bool) {
+	c.titlesMu.Lock()
+	defer c.titlesMu.Unlock()
+	title, ok := c.titles[[2]string{document, version}]
+	return title, ok
+}
+
+// documentTitle returns the best known title for the document of a result:
+// the one of the suggested version, otherwise the newest fetched one
+func (c *Checker) documentTitle(docURL *parser.OCPDocURL, result *CheckResult) string {
+	if best, ok := result.BestSuggestion(); ok {
+		if title, ok := c.DocumentTitle(docURL.Document, best.Version); ok {
+			return title
+		}
+	}
+	for i := len(result.AllResults) - 1; i >= 0; i-- {
+		if title, ok := c.DocumentTitle(docURL.Document, result.AllResults[i].Version); ok {
+			return title
+		}
+	}
+	title, _ := c.DocumentTitle(docURL.Document, docURL.Version)
+	return title
+}
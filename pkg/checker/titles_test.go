@@ -0,0 +1,56 @@
+package checker
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGuideTitle(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		want  string
+	}{
+		{"chapter page", "Chapter 3. Configuring ingress | Networking | OpenShift Container Platform | 4.17 | Red Hat Documentation", "Networking"},
+		{"single page", "Scalability and performance | OpenShift Container Platform | 4.18 | Red Hat Documentation", "Scalability and performance"},
+		{"whitespace", "\n  Networking |  OpenShift Container Platform | 4.17\n | Red Hat Documentation ", "Networking"},
+		{"other shape", "Networking | Red Hat Documentation", "Networking"},
+		{"no suffix", "Release notes", "Release notes"},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GuideTitle(tt.title); got != tt.want {
+				t.Errorf("GuideTitle(%q) = %q, want %q", tt.title, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck_DocumentTitle(t *testing.T) {
+	const docPath = "/en/documentation/openshift_container_platform/%s/html/networking/configuring-ingress"
+	page := func(version string) string {
+		return fmt.Sprintf(`<html><head><title>Chapter 3. Ingress | Networking %s | OpenShift Container Platform | %s | Red Hat Documentation</title></head>`+
+			`<body><h2 id="ingress">Ingress</h2></body></html>`, version, version)
+	}
+
+	c := newFakeDocsChecker(t, map[string]string{
+		fmt.Sprintf(docPath, "4.16"): page("4.16"),
+		fmt.Sprintf(docPath, "4.17"): `<html><body><h2 id="other">Untitled</h2></body></html>`,
+	})
+	c.SetVersions([]string{"4.15", "4.16", "4.17"})
+
+	result, err := c.Check("https://docs.redhat.com/en/documentation/openshift_container_platform/4.15/html/networking/configuring-ingress#ingress")
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	// 4.17 has no title, so the title of the suggested 4.16 page is used
+	if result.DocumentTitle != "Networking 4.16" {
+		t.Errorf("DocumentTitle = %q, want %q", result.DocumentTitle, "Networking 4.16")
+	}
+	if _, ok := c.DocumentTitle("networking", "4.17"); ok {
+		t.Error("DocumentTitle() cached a title for a page without one")
+	}
+}
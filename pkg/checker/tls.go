@@ -0,0 +1,59 @@
+package checker
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// SetTLSConfig sets the TLS settings of the checker's transport, e.g. the
+// root CAs of a TLS-intercepting proxy or InsecureSkipVerify. It replaces
+// any transport set with SetTransport. Certificate pins are verified on top
+// of cfg's own checks, even when it skips verification. A nil cfg restores
+// the default settings.
+func (c *Checker) SetTLSConfig(cfg *tls.Config) {
+	c.tlsConfig = cfg
+	c.SetTransport(nil)
+}
+
+// LoadCACerts returns the system root CAs plus the PEM certificates in the
+// file at path, for the RootCAs of SetTLSConfig
+func LoadCACerts(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificates: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// tlsClientConfig returns the TLS settings of a new transport: those set
+// with SetTLSConfig, verifying certificate pins after its own checks
+func (c *Checker) tlsClientConfig() *tls.Config {
+	if c.tlsConfig == nil {
+		// VerifyConnection rather than VerifyPeerCertificate: it knows the
+		// server name, to scope pins to hosts, and also runs on resumed
+		// sessions
+		return &tls.Config{VerifyConnection: c.verifyPins}
+	}
+
+	cfg := c.tlsConfig.Clone()
+	verify := cfg.VerifyConnection
+	cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+		if verify != nil {
+			if err := verify(cs); err != nil {
+				return err
+			}
+		}
+		return c.verifyPins(cs)
+	}
+	return cfg
+}
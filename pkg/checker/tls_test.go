@@ -0,0 +1,89 @@
+package checker
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetTLSConfig(t *testing.T) {
+	addr, cert := newTLSDocsServer(t)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	roots, err := LoadCACerts(caFile)
+	if err != nil {
+		t.Fatalf("LoadCACerts() error = %v", err)
+	}
+	other := sha256.Sum256([]byte("another key"))
+
+	tests := []struct {
+		name    string
+		cfg     *tls.Config
+		pins    []string
+		wantErr error
+	}{
+		{"untrusted certificate", nil, nil, &tls.CertificateVerificationError{}},
+		{"CA bundle", &tls.Config{RootCAs: roots}, nil, nil},
+		{"skip verification", &tls.Config{InsecureSkipVerify: true}, nil, nil},
+		{"pins still verified", &tls.Config{InsecureSkipVerify: true}, []string{hex.EncodeToString(other[:])}, ErrPinMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker()
+			if err := c.SetCertPins(tt.pins, false); err != nil {
+				t.Fatalf("SetCertPins() error = %v", err)
+			}
+			c.SetTLSConfig(tt.cfg)
+			dialServer(c, addr)
+
+			_, err := c.CheckURLOnce(context.Background(), "https://docs.redhat.com/en/documentation/openshift_container_platform/4.17/html/networking/index")
+			switch want := tt.wantErr.(type) {
+			case nil:
+				if err != nil {
+					t.Fatalf("CheckURLOnce() error = %v", err)
+				}
+			case *tls.CertificateVerificationError:
+				if !errors.As(err, &want) {
+					t.Fatalf("CheckURLOnce() error = %v, want a certificate verification error", err)
+				}
+			default:
+				if !errors.Is(err, want) {
+					t.Fatalf("CheckURLOnce() error = %v, want %v", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadCACerts(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "ca.txt")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{notPEM, filepath.Join(dir, "missing.pem")} {
+		if _, err := LoadCACerts(path); err == nil {
+			t.Errorf("LoadCACerts(%s) error = nil, want an error", filepath.Base(path))
+		}
+	}
+
+	// A nil config restores the default transport
+	c := NewChecker()
+	c.SetTLSConfig(&tls.Config{RootCAs: x509.NewCertPool()})
+	c.SetTLSConfig(nil)
+	if got := c.client.Transport.(*egressTransport).base.(*http.Transport).TLSClientConfig; got.RootCAs != nil || got.InsecureSkipVerify {
+		t.Errorf("SetTLSConfig(nil) kept %+v", got)
+	}
+}
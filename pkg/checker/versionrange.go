@@ -0,0 +1,127 @@
+package checker
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/parser"
+)
+
+// SetVersionRange restricts the newer versions checked, and so the upgrade
+// targets, to those from minVersion to maxVersion inclusive, e.g. to keep
+// docs on the EUS releases a policy allows. Either bound may be empty for
+// no bound, or a version alias such as latest, resolved against the known
+// versions, so call it after SetVersions and SetVersionAliases. A URL whose
+// version is above maxVersion is not checked and is reported as up to
+// date, with a note. A bound that does not resolve to a major.minor
+// version, or a minVersion above maxVersion, is an error. Call it before
+// checking URLs.
+func (c *Checker) SetVersionRange(minVersion, maxVersion string) error {
+	for _, v := range []*string{&minVersion, &maxVersion} {
+		if *v == "" {
+			continue
+		}
+		resolved, err := c.ResolveVersion(*v)
+		if err != nil {
+			return fmt.Errorf("invalid version range bound %q: %w", *v, err)
+		}
+		*v = resolved
+	}
+	if minVersion != "" && maxVersion != "" && versionAfter(minVersion, maxVersion) {
+		return fmt.Errorf("minimum version %s is newer than the maximum version %s", minVersion, maxVersion)
+	}
+	c.minVersion, c.maxVersion = minVersion, maxVersion
+	return nil
+}
+
+// VersionRange returns the bounds set with SetVersionRange, aliases
+// resolved, empty when unbounded
+func (c *Checker) VersionRange() (minVersion, maxVersion string) {
+	return c.minVersion, c.maxVersion
+}
+
+// checksVersion reports whether a newer version is checked: within the
+// range set with SetVersionRange and not excluded with ExcludeVersions
+func (c *Checker) checksVersion(version string) bool {
+	if c.excludedVersions[version] {
+		return false
+	}
+	if c.minVersion != "" && versionAfter(c.minVersion, version) {
+		return false
+	}
+	return c.maxVersion == "" || !versionAfter(version, c.maxVersion)
+}
+
+// SetRecordSkippedVersions lists the newer versions outside the range of
+// SetVersionRange in AllResults, with NotChecked set and the bound that
+// left them out as SkippedReason, so that a report tells them from
+// missing pages. Versions excluded with ExcludeVersions stay out of
+// AllResults. It is off by default.
+func (c *Checker) SetRecordSkippedVersions(on bool) {
+	c.recordSkipped = on
+}
+
+// withSkippedVersions returns results, the checked versions of docURL in
+// order, with the newer versions outside the range when
+// SetRecordSkippedVersions lists them
+func (c *Checker) withSkippedVersions(docURL *parser.OCPDocURL, results []VersionCheckResult) []VersionCheckResult {
+	if !c.recordSkipped {
+		return results
+	}
+	added := false
+	for _, v := range c.knownVersions {
+		if c.excludedVersions[v] || !versionAfter(v, docURL.Version) {
+			continue
+		}
+		var reason string
+		switch {
+		case c.minVersion != "" && versionAfter(c.minVersion, v):
+			reason = "below the minimum version " + c.minVersion
+		case c.maxVersion != "" && versionAfter(v, c.maxVersion):
+			reason = "above the maximum version " + c.maxVersion
+		default:
+			continue
+		}
+		results = append(results, VersionCheckResult{
+			Version:       v,
+			URL:           docURL.BuildURL(v),
+			NotChecked:    true,
+			SkippedReason: reason,
+		})
+		added = true
+	}
+	if added {
+		slices.SortStableFunc(results, func(a, b VersionCheckResult) int {
+			switch {
+			case versionAfter(a.Version, b.Version):
+				return 1
+			case versionAfter(b.Version, a.Version):
+				return -1
+			}
+			return 0
+		})
+	}
+	return results
+}
+
+// ExcludeVersions removes versions from the newer versions checked, e.g.
+// releases a team skips, so they are never requested, never in AllResults
+// and never suggested; the newest version left is. Versions must be
+// major.minor. Each call replaces the excluded versions, and an empty list
+// excludes none. Call it before checking URLs.
+func (c *Checker) ExcludeVersions(versions []string) error {
+	excluded := make(map[string]bool)
+	for _, v := range versions {
+		v = strings.TrimSpace(v)
+		if !concreteVersionRe.MatchString(v) {
+			return fmt.Errorf("invalid excluded version %q: expected major.minor", v)
+		}
+		excluded[v] = true
+	}
+	if len(excluded) == 0 {
+		excluded = nil
+	}
+	c.excludedVersions = excluded
+	return nil
+}
@@ -0,0 +1,358 @@
+package checker
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCheck_VersionRange(t *testing.T) {
+	const docPath = "/en/documentation/openshift_container_platform/%s/html/networking/index"
+	page := `<html><body><h2 id="ingress">Ingress</h2></body></html>`
+	versions := []string{"4.12", "4.13", "4.14", "4.15", "4.16", "4.17", "4.18"}
+	pages := make(map[string]string)
+	for _, v := range versions {
+		pages[fmt.Sprintf(docPath, v)] = page
+	}
+	urlAt := func(v string) string {
+		return "https://docs.redhat.com" + fmt.Sprintf(docPath, v) + "#ingress"
+	}
+
+	tests := []struct {
+		name     string
+		url      string
+		min, max string
+		want     verdict
+		wantNote string
+	}{
+		{
+			name: "unbounded",
+			url:  urlAt("4.15"),
+			want: verdict{Latest: "4.18", Outdated: true, Checked: []string{"4.16", "4.17", "4.18"}},
+		},
+		{
+			name: "both bounds",
+			url:  urlAt("4.12"),
+			min:  "4.14",
+			max:  "4.16",
+			want: verdict{Latest: "4.16", Outdated: true, Checked: []string{"4.14", "4.15", "4.16"}},
+		},
+		{
+			name: "minimum only",
+			url:  urlAt("4.12"),
+			min:  "4.17",
+			want: verdict{Latest: "4.18", Outdated: true, Checked: []string{"4.17", "4.18"}},
+		},
+		{
+			name: "at the maximum",
+			url:  urlAt("4.16"),
+			max:  "4.16",
+			want: verdict{Latest: "4.16"},
+		},
+		{
+			name:     "above the maximum",
+			url:      urlAt("4.17"),
+			max:      "4.16",
+			want:     verdict{Latest: "4.17"},
+			wantNote: "version 4.17 is above the maximum version 4.16",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeDocsChecker(t, pages)
+			c.SetVersions(versions)
+			c.SetFormatFallback(false)
+			if err := c.SetVersionRange(tt.min, tt.max); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := c.Check(tt.url)
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			got := verdictOf(result)
+			got.Newer = nil
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("verdict = %+v, want %+v", got, tt.want)
+			}
+			// Only the versions checked are requested, besides the original
+			if requests := c.Stats().Requests; requests != int64(len(tt.want.Checked))+1 {
+				t.Errorf("Stats().Requests = %d, want %d", requests, len(tt.want.Checked)+1)
+			}
+
+			note := strings.Join(result.Notes, "\n")
+			if (tt.wantNote == "") != (note == "") || !strings.Contains(note, tt.wantNote) {
+				t.Errorf("Notes = %q, want %q", result.Notes, tt.wantNote)
+			}
+			bounded := tt.min != "" || tt.max != ""
+			if slices.Contains(result.AppliedHeuristics, HeuristicVersionRange) != bounded {
+				t.Errorf("AppliedHeuristics = %v, want %s applied = %v", result.AppliedHeuristics, HeuristicVersionRange, bounded)
+			}
+		})
+	}
+}
+
+func TestCheck_SkippedVersions(t *testing.T) {
+	const docPath = "/en/documentation/openshift_container_platform/%s/html/networking/index"
+	page := `<html><body><h2 id="ingress">Ingress</h2></body></html>`
+	versions := []string{"4.12", "4.13", "4.14", "4.15", "4.16", "4.17"}
+	pages := make(map[string]string)
+	for _, v := range versions {
+		pages[fmt.Sprintf(docPath, v)] = page
+	}
+	urlAt := func(v string) string {
+		return "https://docs.redhat.com" + fmt.Sprintf(docPath, v) + "#ingress"
+	}
+
+	tests := []struct {
+		name     string
+		url      string
+		min, max string
+		excluded []string
+		record   bool
+		// want is each version in AllResults with its skipped reason, empty
+		// for a checked version
+		want [][2]string
+	}{
+		{
+			name: "hidden by default",
+			url:  urlAt("4.12"),
+			min:  "4.14",
+			max:  "4.15",
+			want: [][2]string{{"4.14", ""}, {"4.15", ""}},
+		},
+		{
+			name:   "both bounds",
+			url:    urlAt("4.12"),
+			min:    "4.14",
+			max:    "4.15",
+			record: true,
+			want: [][2]string{
+				{"4.13", "below the minimum version 4.14"},
+				{"4.14", ""},
+				{"4.15", ""},
+				{"4.16", "above the maximum version 4.15"},
+				{"4.17", "above the maximum version 4.15"},
+			},
+		},
+		{
+			name:   "above the maximum",
+			url:    urlAt("4.16"),
+			max:    "4.15",
+			record: true,
+			want:   [][2]string{{"4.17", "above the maximum version 4.15"}},
+		},
+		{
+			name:     "excluded versions stay hidden",
+			url:      urlAt("4.14"),
+			max:      "4.15",
+			excluded: []string{"4.16"},
+			record:   true,
+			want:     [][2]string{{"4.15", ""}, {"4.17", "above the maximum version 4.15"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeDocsChecker(t, pages)
+			c.SetVersions(versions)
+			c.SetFormatFallback(false)
+			if err := c.SetVersionRange(tt.min, tt.max); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.ExcludeVersions(tt.excluded); err != nil {
+				t.Fatal(err)
+			}
+			c.SetRecordSkippedVersions(tt.record)
+
+			result, err := c.Check(tt.url)
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			var got [][2]string
+			for _, v := range result.AllResults {
+				got = append(got, [2]string{v.Version, v.SkippedReason})
+				if v.NotChecked != (v.SkippedReason != "") || v.NotChecked && (v.Exists || v.URL != urlAt(v.Version)) {
+					t.Errorf("AllResults has %+v", v)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AllResults = %v, want %v", got, tt.want)
+			}
+			// Skipped versions are never requested, the original always is
+			checked := 0
+			for _, w := range tt.want {
+				if w[1] == "" {
+					checked++
+				}
+			}
+			if requests := c.Stats().Requests; requests != int64(checked)+1 {
+				t.Errorf("Stats().Requests = %d, want %d", requests, checked+1)
+			}
+		})
+	}
+}
+
+func TestSetVersionRange(t *testing.T) {
+	tests := []struct {
+		min, max         string
+		wantMin, wantMax string
+		wantErr          string
+	}{
+		{min: "4.14", max: "4.16", wantMin: "4.14", wantMax: "4.16"},
+		{min: "4.16", max: "4.16", wantMin: "4.16", wantMax: "4.16"},
+		{min: "4.14", wantMin: "4.14"},
+		{max: "4.16", wantMax: "4.16"},
+		{},
+		{max: "latest", wantMax: "4.18"},
+		{min: "latest-1", max: "latest", wantMin: "4.17", wantMax: "4.18"},
+		{min: "stable", wantMin: "4.16"},
+		{min: "4.16", max: "4.14", wantErr: "minimum version 4.16 is newer than the maximum version 4.14"},
+		{min: "latest", max: "stable", wantErr: "minimum version 4.18 is newer than the maximum version 4.16"},
+		{min: "4.x", wantErr: `invalid version range bound "4.x"`},
+		{max: "latest-9", wantErr: `invalid version range bound "latest-9"`},
+		{max: "4.16.1", wantErr: `invalid version range bound "4.16.1"`},
+	}
+
+	for _, tt := range tests {
+		c := NewChecker()
+		c.SetVersions([]string{"4.14", "4.15", "4.16", "4.17", "4.18"})
+		if err := c.SetVersionAliases(map[string]string{"stable": "4.16"}); err != nil {
+			t.Fatal(err)
+		}
+		err := c.SetVersionRange(tt.min, tt.max)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("SetVersionRange(%q, %q) error = %v", tt.min, tt.max, err)
+			}
+			if gotMin, gotMax := c.VersionRange(); gotMin != tt.wantMin || gotMax != tt.wantMax {
+				t.Errorf("VersionRange() = %q, %q; want %q, %q", gotMin, gotMax, tt.wantMin, tt.wantMax)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("SetVersionRange(%q, %q) error = %v, want %q", tt.min, tt.max, err, tt.wantErr)
+		}
+		// A rejected range leaves the checker unbounded
+		if gotMin, gotMax := c.VersionRange(); gotMin != "" || gotMax != "" {
+			t.Errorf("VersionRange() after an error = %q, %q", gotMin, gotMax)
+		}
+	}
+
+	// The resolved bounds are reported like the other aliases
+	c := NewChecker()
+	c.SetVersions([]string{"4.14", "4.15", "4.16"})
+	if err := c.SetVersionRange("", "latest"); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.ResolvedAliases(); !reflect.DeepEqual(got, map[string]string{"latest": "4.16"}) {
+		t.Errorf("ResolvedAliases() = %v, want latest resolved to 4.16", got)
+	}
+}
+
+func TestCheck_ExcludeVersions(t *testing.T) {
+	const docPath = "/en/documentation/openshift_container_platform/%s/html/networking/index"
+	page := `<html><body><h2 id="ingress">Ingress</h2></body></html>`
+	versions := []string{"4.15", "4.16", "4.17", "4.18", "4.19"}
+	pages := make(map[string]string)
+	for _, v := range versions {
+		pages[fmt.Sprintf(docPath, v)] = page
+	}
+	urlAt := func(v string) string {
+		return "https://docs.redhat.com" + fmt.Sprintf(docPath, v) + "#ingress"
+	}
+
+	tests := []struct {
+		name     string
+		url      string
+		excluded []string
+		min, max string
+		want     verdict
+	}{
+		{
+			name:     "odd releases",
+			url:      urlAt("4.15"),
+			excluded: []string{"4.17", "4.19"},
+			want:     verdict{Latest: "4.18", Outdated: true, Checked: []string{"4.16", "4.18"}, Newer: []string{urlAt("4.16"), urlAt("4.18")}},
+		},
+		{
+			name:     "every newer version",
+			url:      urlAt("4.17"),
+			excluded: []string{"4.18", "4.19"},
+			want:     verdict{Latest: "4.17"},
+		},
+		{
+			name:     "within a range",
+			url:      urlAt("4.15"),
+			excluded: []string{"4.17"},
+			max:      "4.18",
+			want:     verdict{Latest: "4.18", Outdated: true, Checked: []string{"4.16", "4.18"}, Newer: []string{urlAt("4.16"), urlAt("4.18")}},
+		},
+		{
+			name:     "cleared",
+			url:      urlAt("4.18"),
+			excluded: []string{},
+			want:     verdict{Latest: "4.19", Outdated: true, Checked: []string{"4.19"}, Newer: []string{urlAt("4.19")}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeDocsChecker(t, pages)
+			c.SetVersions(versions)
+			c.SetFormatFallback(false)
+			if err := c.SetVersionRange(tt.min, tt.max); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.ExcludeVersions([]string{"4.19"}); err != nil {
+				t.Fatal(err)
+			}
+			// The last call replaces the excluded versions
+			if err := c.ExcludeVersions(tt.excluded); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := c.Check(tt.url)
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if got := verdictOf(result); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("verdict = %+v, want %+v", got, tt.want)
+			}
+			// Excluded versions are never requested, the original always is
+			if requests := c.Stats().Requests; requests != int64(len(tt.want.Checked))+1 {
+				t.Errorf("Stats().Requests = %d, want %d", requests, len(tt.want.Checked)+1)
+			}
+			excluding := len(tt.excluded) > 0
+			if slices.Contains(result.AppliedHeuristics, HeuristicExcludedVersions) != excluding {
+				t.Errorf("AppliedHeuristics = %v, want %s applied = %v", result.AppliedHeuristics, HeuristicExcludedVersions, excluding)
+			}
+		})
+	}
+}
+
+func TestExcludeVersions(t *testing.T) {
+	tests := []struct {
+		versions []string
+		wantErr  string
+	}{
+		{versions: []string{"4.17", " 4.19"}},
+		{versions: nil},
+		{versions: []string{"4.17", ""}, wantErr: `invalid excluded version ""`},
+		{versions: []string{"4.x"}, wantErr: `invalid excluded version "4.x"`},
+		{versions: []string{"4.17.2"}, wantErr: `invalid excluded version "4.17.2"`},
+		{versions: []string{"eus"}, wantErr: `invalid excluded version "eus"`},
+	}
+	for _, tt := range tests {
+		err := NewChecker().ExcludeVersions(tt.versions)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("ExcludeVersions(%q) error = %v", tt.versions, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("ExcludeVersions(%q) error = %v, want %q", tt.versions, err, tt.wantErr)
+		}
+	}
+}
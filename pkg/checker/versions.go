@@ -0,0 +1,103 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+
+	"golang.org/x/net/html"
+)
+
+// ProductURL is the landing page of the OpenShift Container Platform
+// documentation, whose version selector links every published version
+const ProductURL = "https://docs.redhat.com/en/documentation/openshift_container_platform/"
+
+// versionLinkRegex matches a link to the documentation of one version,
+// absolute or relative to the docs host
+var versionLinkRegex = regexp.MustCompile(`^(?:https://docs\.redhat\.com)?/en/documentation/openshift_container_platform/(\d+\.\d+)(?:[/?#]|$)`)
+
+// DiscoverVersions is DiscoverVersionsContext without a deadline
+func (c *Checker) DiscoverVersions() ([]string, error) {
+	return c.DiscoverVersionsContext(context.Background())
+}
+
+// DiscoverVersionsContext reads the published versions from the product
+// landing page and checks those instead of the built-in list. The first
+// successful discovery is kept for the life of the checker, so later calls
+// send no request. Versions newer than the as-of version are dropped. On
+// failure the versions are left unchanged and returned with the error. Call
+// it before checking URLs.
+func (c *Checker) DiscoverVersionsContext(ctx context.Context) ([]string, error) {
+	c.discoverMu.Lock()
+	defer c.discoverMu.Unlock()
+
+	if c.discovered {
+		return c.knownVersions, nil
+	}
+
+	versions, err := c.fetchVersions(ctx)
+	if err != nil {
+		return c.knownVersions, fmt.Errorf("discovering versions: %w", err)
+	}
+
+	c.knownVersions = c.capVersions(versions)
+	c.discovered = true
+	return c.knownVersions, nil
+}
+
+// fetchVersions requests the product landing page and returns the versions
+// it links to, oldest first
+func (c *Checker) fetchVersions(ctx context.Context) ([]string, error) {
+	resp, err := c.do(ctx, http.MethodGet, ProductURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned status %d", ProductURL, resp.StatusCode)
+	}
+
+	versions, err := versionLinks(resp.Body)
+	if err != nil {
+		c.logger.WarnContext(ctx, "failed to parse page", "url", ProductURL, "error", err)
+		return nil, fmt.Errorf("reading %s: %w", ProductURL, err)
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("no versions found on %s", ProductURL)
+	}
+	return versions, nil
+}
+
+// versionLinks returns the distinct versions linked from the href of a link
+// or the value of a version selector option, oldest first
+func versionLinks(r io.Reader) ([]string, error) {
+	seen := make(map[string]bool)
+	var versions []string
+
+	z := html.NewTokenizer(r)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if err := z.Err(); err != io.EOF {
+				return nil, err
+			}
+			return sortedVersions(versions), nil
+		case html.StartTagToken, html.SelfClosingTagToken:
+			_, hasAttr := z.TagName()
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = z.TagAttr()
+				if string(key) != "href" && string(key) != "value" {
+					continue
+				}
+				if m := versionLinkRegex.FindStringSubmatch(string(val)); m != nil && !seen[m[1]] {
+					seen[m[1]] = true
+					versions = append(versions, m[1])
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package checker
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const productPath = "/en/documentation/openshift_container_platform/"
+
+func TestDiscoverVersions(t *testing.T) {
+	landing, err := os.ReadFile(filepath.Join("testdata", "product-landing.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newFakeDocsChecker(t, map[string]string{productPath: string(landing)})
+
+	want := []string{"3.11", "4.12", "4.13", "4.14", "4.15", "4.16", "4.17", "4.18", "4.19", "4.20", "4.21"}
+	got, err := c.DiscoverVersions()
+	if err != nil {
+		t.Fatalf("DiscoverVersions() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiscoverVersions() = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(c.knownVersions, want) {
+		t.Errorf("knownVersions = %v, want %v", c.knownVersions, want)
+	}
+
+	// The discovered versions are kept for the rest of the run
+	requests := c.Requests()
+	if got, err := c.DiscoverVersions(); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("second DiscoverVersions() = %v, %v, want %v", got, err, want)
+	}
+	if got := c.Requests(); got != requests {
+		t.Errorf("second DiscoverVersions() sent %d requests, want none", got-requests)
+	}
+}
+
+func TestDiscoverVersions_Fallback(t *testing.T) {
+	tests := []struct {
+		name  string
+		pages map[string]string
+	}{
+		{"landing page missing", map[string]string{}},
+		{"no version links", map[string]string{productPath: `<html><body><a href="/en/products">Products</a></body></html>`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeDocsChecker(t, tt.pages)
+			builtIn := NewChecker().knownVersions
+
+			got, err := c.DiscoverVersions()
+			if err == nil {
+				t.Fatal("DiscoverVersions() error = nil, want an error")
+			}
+			if !reflect.DeepEqual(got, builtIn) || !reflect.DeepEqual(c.knownVersions, builtIn) {
+				t.Errorf("DiscoverVersions() = %v, knownVersions = %v, want the built-in %v", got, c.knownVersions, builtIn)
+			}
+		})
+	}
+}
@@ -0,0 +1,370 @@
+package fixer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// CampaignSchema is the version of the campaign state file format
+const CampaignSchema = 1
+
+// CampaignStatus is the state of a group of a campaign
+type CampaignStatus string
+
+const (
+	// CampaignPending is a group whose fixes are still to be applied
+	CampaignPending CampaignStatus = "pending"
+	// CampaignApplied is a group with no edits left to apply
+	CampaignApplied CampaignStatus = "applied"
+	// CampaignFailed is a group whose last step failed; it is retried
+	// first by the next step
+	CampaignFailed CampaignStatus = "failed"
+)
+
+// Campaign is an upgrade of a repository's links to a target version, split
+// into groups that are fixed a few at a time over several runs. It is kept
+// in a state file between runs. Every run plans the fixes of the whole
+// repository again and reconciles the campaign with them: applied groups
+// with edits again are pending again, pending groups without edits are
+// applied, and groups not seen before are added at the end.
+type Campaign struct {
+	Schema        int    `json:"schema"`
+	TargetVersion string `json:"target_version"`
+	// Steps is the number of steps run, each applying some groups
+	Steps int `json:"steps"`
+	// RunID identifies the last run of the campaign, set by the caller
+	// before Step; the groups a step applies or fails record it too
+	RunID  string           `json:"run_id,omitempty"`
+	Groups []*CampaignGroup `json:"groups"`
+}
+
+// CampaignGroup is the unit of a campaign step: the changeset groups of a
+// changeset when the group was added to the campaign
+type CampaignGroup struct {
+	Groups []ChangesetGroup `json:"groups"`
+	Status CampaignStatus   `json:"status"`
+	// Edits and Files are the edits left to apply and the files they are
+	// in, as of the last run
+	Edits int      `json:"edits"`
+	Files []string `json:"files,omitempty"`
+	// Review is the number of occurrences left for manual review, which no
+	// step fixes
+	Review int `json:"review,omitempty"`
+	// Step is the step the group was last applied or failed in, 0 when it
+	// needed no step
+	Step int `json:"step,omitempty"`
+	// RunID identifies the run of that step
+	RunID string `json:"run_id,omitempty"`
+	Error string `json:"error,omitempty"`
+	// Regressions counts the runs that found edits in the group again
+	// after it was applied
+	Regressions int `json:"regressions,omitempty"`
+}
+
+// Name returns the name of the group, as for a changeset
+func (g *CampaignGroup) Name() string {
+	names := make([]string, len(g.Groups))
+	for i, cg := range g.Groups {
+		names[i] = cg.String()
+	}
+	return strings.Join(names, "+")
+}
+
+// NewCampaign returns an empty campaign to upgrade links to targetVersion
+func NewCampaign(targetVersion string) *Campaign {
+	return &Campaign{Schema: CampaignSchema, TargetVersion: targetVersion}
+}
+
+// LoadCampaign reads a campaign state file
+func LoadCampaign(path string) (*Campaign, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Campaign
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if c.Schema != CampaignSchema {
+		return nil, fmt.Errorf("%s: unsupported campaign schema %d (expected %d)", path, c.Schema, CampaignSchema)
+	}
+	if c.TargetVersion == "" {
+		return nil, fmt.Errorf("%s: campaign has no target version", path)
+	}
+	return &c, nil
+}
+
+// Save writes the campaign state file, replacing it only once the new
+// state is complete so an interrupted run leaves the previous state
+func (c *Campaign) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Progress returns the number of applied groups and of all groups
+func (c *Campaign) Progress() (applied, total int) {
+	for _, g := range c.Groups {
+		if g.Status == CampaignApplied {
+			applied++
+		}
+	}
+	return applied, len(c.Groups)
+}
+
+// Percent returns the share of applied groups, 100 for a campaign without
+// groups
+func (c *Campaign) Percent() int {
+	applied, total := c.Progress()
+	if total == 0 {
+		return 100
+	}
+	return applied * 100 / total
+}
+
+// Remaining returns the groups not applied yet, in the order steps apply
+// them
+func (c *Campaign) Remaining() []*CampaignGroup {
+	var groups []*CampaignGroup
+	for _, g := range c.Groups {
+		if g.Status != CampaignApplied {
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
+
+// Complete reports whether every group is applied
+func (c *Campaign) Complete() bool {
+	return len(c.Remaining()) == 0
+}
+
+// CampaignStep is the outcome of a run of a campaign
+type CampaignStep struct {
+	// Plans are the plans written by the step, for the files of its groups
+	Plans []*FilePlan
+	// Applied and Failed are the groups the step applied or failed to
+	Applied []*CampaignGroup
+	Failed  []*CampaignGroup
+	// Regressed are the applied groups found with edits again, now pending
+	Regressed []*CampaignGroup
+}
+
+// Step runs the campaign on the targets of the files of a repository: it
+// plans every fix, reconciles the campaign with the plan and then applies
+// the fixes of the next n groups with edits, failed groups first, writing
+// their files. n may be 0 to only reconcile. A group with a file that
+// cannot be planned fails without changes; one with a file that cannot be
+// written fails too, but keeps its other files, and the next step resumes
+// it with what is left.
+func (c *Campaign) Step(files []string, targets map[string][]Target, opts Options, n int) *CampaignStep {
+	step := &CampaignStep{}
+
+	// Plan every file to see what is left to fix. Files that cannot be
+	// planned fail the groups of their targets.
+	var plans []*FilePlan
+	fileErrors := make(map[ChangesetGroup]error)
+	for _, path := range files {
+		plan, err := FixFile(path, targets[path], opts, false)
+		for _, t := range targets[path] {
+			g := groupOf(t.Replacement)
+			if fileErrors[g] != nil {
+				continue
+			}
+			if err != nil {
+				fileErrors[g] = fmt.Errorf("%s: %w", path, err)
+			} else if len(plan.Errors) > 0 {
+				// Only the targets that failed fail their group
+				if _, err := Plan(plan.text, t.Occurrence, t.Replacement, opts); err != nil {
+					fileErrors[g] = err
+				}
+			}
+		}
+		if err == nil {
+			plans = append(plans, plan)
+		}
+	}
+	step.Regressed = c.reconcile(plans, fileErrors)
+	if n <= 0 {
+		return step
+	}
+
+	next := c.next(n)
+	if len(next) == 0 {
+		return step
+	}
+	c.Steps++
+	picked := make(map[ChangesetGroup]*CampaignGroup)
+	for _, g := range next {
+		g.Error = ""
+		for _, cg := range g.Groups {
+			picked[cg] = g
+		}
+	}
+	failed := make(map[*CampaignGroup]error)
+	for _, g := range next {
+		for _, cg := range g.Groups {
+			if err := fileErrors[cg]; err != nil && failed[g] == nil {
+				failed[g] = err
+			}
+		}
+	}
+
+	for _, path := range files {
+		var selected []Target
+		groups := make(map[*CampaignGroup]bool)
+		for _, t := range targets[path] {
+			if g := picked[groupOf(t.Replacement)]; g != nil && failed[g] == nil {
+				selected = append(selected, t)
+				groups[g] = true
+			}
+		}
+		if len(selected) == 0 {
+			continue
+		}
+		plan, err := FixFile(path, selected, opts, true)
+		if err != nil {
+			for g := range groups {
+				failed[g] = fmt.Errorf("%s: %w", path, err)
+			}
+			continue
+		}
+		step.Plans = append(step.Plans, plan)
+	}
+
+	for _, g := range next {
+		g.Step = c.Steps
+		g.RunID = c.RunID
+		if err := failed[g]; err != nil {
+			g.Status = CampaignFailed
+			g.Error = err.Error()
+			step.Failed = append(step.Failed, g)
+			continue
+		}
+		g.Status = CampaignApplied
+		g.Edits = 0
+		g.Files = nil
+		step.Applied = append(step.Applied, g)
+	}
+	return step
+}
+
+// next returns up to n groups to apply: failed groups, then pending ones,
+// in campaign order
+func (c *Campaign) next(n int) []*CampaignGroup {
+	var groups []*CampaignGroup
+	for _, status := range []CampaignStatus{CampaignFailed, CampaignPending} {
+		for _, g := range c.Groups {
+			if g.Status == status && len(groups) < n {
+				groups = append(groups, g)
+			}
+		}
+	}
+	return groups
+}
+
+// reconcile updates the groups of the campaign from plans of every file,
+// adds the groups not seen before and returns the applied groups found with
+// edits again. fileErrors are the groups with files that could not be
+// planned, whose edits are unknown.
+func (c *Campaign) reconcile(plans []*FilePlan, fileErrors map[ChangesetGroup]error) []*CampaignGroup {
+	byGroup := make(map[ChangesetGroup]*CampaignGroup)
+	for _, g := range c.Groups {
+		for _, cg := range g.Groups {
+			byGroup[cg] = g
+		}
+	}
+
+	// Groups not seen before are added as the changesets would split them,
+	// and groups of files that cannot be patched on their own
+	var patchable []*FilePlan
+	for _, plan := range plans {
+		if plan.Patchable() == nil {
+			patchable = append(patchable, plan)
+		}
+	}
+	changesets, _ := Changesets(patchable)
+	for _, cs := range changesets {
+		var added *CampaignGroup
+		for _, cg := range cs.Groups {
+			if byGroup[cg] != nil {
+				continue
+			}
+			if added == nil {
+				added = &CampaignGroup{Status: CampaignPending}
+				c.Groups = append(c.Groups, added)
+			}
+			added.Groups = append(added.Groups, cg)
+			byGroup[cg] = added
+		}
+	}
+	addGroup := func(cg ChangesetGroup) {
+		if byGroup[cg] == nil {
+			byGroup[cg] = &CampaignGroup{Groups: []ChangesetGroup{cg}, Status: CampaignPending}
+			c.Groups = append(c.Groups, byGroup[cg])
+		}
+	}
+	for _, plan := range plans {
+		for _, change := range plan.Changes {
+			if !change.Outcome.leftAsWritten() {
+				addGroup(groupOf(change.Replacement))
+			}
+		}
+	}
+	for cg := range fileErrors {
+		addGroup(cg)
+	}
+
+	// Count what is left in every group
+	for _, g := range c.Groups {
+		g.Edits, g.Files, g.Review = 0, nil, 0
+	}
+	for _, plan := range plans {
+		for _, change := range plan.Changes {
+			g := byGroup[groupOf(change.Replacement)]
+			switch {
+			case len(change.Edits) > 0:
+				g.Edits += len(change.Edits)
+				if !slices.Contains(g.Files, plan.Path) {
+					g.Files = append(g.Files, plan.Path)
+				}
+			case !change.Outcome.leftAsWritten():
+				g.Review++
+			}
+		}
+	}
+
+	var regressed []*CampaignGroup
+	for _, g := range c.Groups {
+		unknown := slices.ContainsFunc(g.Groups, func(cg ChangesetGroup) bool { return fileErrors[cg] != nil })
+		switch {
+		case g.Status == CampaignApplied && (g.Edits > 0 || unknown):
+			g.Status = CampaignPending
+			g.Regressions++
+			regressed = append(regressed, g)
+		case g.Status != CampaignApplied && g.Edits == 0 && !unknown:
+			// Fixed by hand, or nothing left to fix automatically
+			g.Status = CampaignApplied
+			g.Error = ""
+		}
+	}
+	return regressed
+}
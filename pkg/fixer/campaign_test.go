@@ -0,0 +1,335 @@
+package fixer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// campaignFiles are the files of the campaign tests: four guides, of which
+// networking is linked from two files and security only from a link text
+// that mentions its version
+var campaignFiles = map[string]string{
+	"README.md": "# Project\n\n" +
+		"See " + docURL("4.14", "networking") + ".\n" +
+		strings.Repeat("filler\n", 10) +
+		"Storage: " + docURL("4.16", "storage") + "\n",
+	"docs/install.md": "Install with " + docURL("4.14", "installing") + "\n" +
+		strings.Repeat("filler\n", 10) +
+		"Then " + docURL("4.15", "networking") + "\n",
+	"docs/security.md": "[Security in 4.14](" + docURL("4.14", "security") + ")\n",
+}
+
+// campaignState summarizes the groups of a campaign as "name status edits"
+func campaignState(c *Campaign) []string {
+	var state []string
+	for _, g := range c.Groups {
+		state = append(state, fmt.Sprintf("%s %s %d", g.Name(), g.Status, g.Edits))
+	}
+	return state
+}
+
+// stepTree runs a step of c over the files below root as they are now
+func stepTree(t *testing.T, c *Campaign, root string, n int) *CampaignStep {
+	t.Helper()
+	files, targets := treeTargets(t, root)
+	return c.Step(files, targets, Options{}, n)
+}
+
+func groupNames(groups []*CampaignGroup) []string {
+	var names []string
+	for _, g := range groups {
+		names = append(names, g.Name())
+	}
+	return names
+}
+
+func TestCampaign_Steps(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, campaignFiles)
+
+	fixed := t.TempDir()
+	writeTree(t, fixed, campaignFiles)
+	planTree(t, fixed, Options{}, true)
+	want := readTree(t, fixed, campaignFiles)
+
+	c := NewCampaign("4.20")
+
+	// Step 0 plans the campaign without touching the files
+	step := stepTree(t, c, root, 0)
+	if len(step.Plans) > 0 || len(step.Applied) > 0 {
+		t.Errorf("Step(0) applied %v", groupNames(step.Applied))
+	}
+	if got := readTree(t, root, campaignFiles); !reflect.DeepEqual(got, campaignFiles) {
+		t.Fatal("Step(0) modified files")
+	}
+	wantState := []string{
+		"networking-4.20 pending 2",
+		"storage-4.20 pending 1",
+		"installing-4.20 pending 1",
+		// Its only link is left for review, so no step is needed
+		"security-4.20 applied 0",
+	}
+	if got := campaignState(c); !reflect.DeepEqual(got, wantState) {
+		t.Errorf("groups = %q, want %q", got, wantState)
+	}
+	if c.Percent() != 25 || c.Steps != 0 {
+		t.Errorf("Percent() = %d, Steps = %d; want 25, 0", c.Percent(), c.Steps)
+	}
+	if security := c.Groups[3]; security.Review != 1 || security.Step != 0 {
+		t.Errorf("security group = %+v, want 1 left for review and no step", security)
+	}
+
+	steps := []struct {
+		n       int
+		applied []string
+		percent int
+	}{
+		{n: 1, applied: []string{"networking-4.20"}, percent: 50},
+		{n: 5, applied: []string{"storage-4.20", "installing-4.20"}, percent: 100},
+		{n: 1, percent: 100},
+	}
+	for i, s := range steps {
+		c.RunID = fmt.Sprintf("run-%d", i+1)
+		step := stepTree(t, c, root, s.n)
+		if got := groupNames(step.Applied); !reflect.DeepEqual(got, s.applied) {
+			t.Errorf("step %d applied %v, want %v", i+1, got, s.applied)
+		}
+		if len(step.Failed) > 0 || len(step.Regressed) > 0 {
+			t.Errorf("step %d failed %v, regressed %v", i+1, groupNames(step.Failed), groupNames(step.Regressed))
+		}
+		if c.Percent() != s.percent {
+			t.Errorf("step %d: Percent() = %d, want %d", i+1, c.Percent(), s.percent)
+		}
+	}
+
+	// The last step had nothing left to apply
+	if c.Steps != 2 || !c.Complete() {
+		t.Errorf("Steps = %d, Complete() = %v; want 2, true", c.Steps, c.Complete())
+	}
+	// Each group records the run of the step that applied it
+	var runs []string
+	for _, g := range c.Groups {
+		runs = append(runs, g.RunID)
+	}
+	if want := []string{"run-1", "run-2", "run-2", ""}; !reflect.DeepEqual(runs, want) {
+		t.Errorf("group run IDs = %q, want %q", runs, want)
+	}
+	if got := readTree(t, root, campaignFiles); !reflect.DeepEqual(got, want) {
+		t.Errorf("files after the campaign =\n%q\nwant, as fixed at once,\n%q", got, want)
+	}
+	if networking := c.Groups[0]; networking.Step != 1 || networking.Edits != 0 || networking.Files != nil {
+		t.Errorf("networking group = %+v, want applied in step 1", networking)
+	}
+}
+
+func TestCampaign_StepWritesOnlyItsGroups(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, campaignFiles)
+
+	c := NewCampaign("4.20")
+	step := stepTree(t, c, root, 1)
+
+	var written []string
+	for _, plan := range step.Plans {
+		rel, _ := filepath.Rel(root, plan.Path)
+		written = append(written, filepath.ToSlash(rel))
+	}
+	if want := []string{"README.md", "docs/install.md"}; !reflect.DeepEqual(written, want) {
+		t.Errorf("written = %v, want %v", written, want)
+	}
+
+	got := readTree(t, root, campaignFiles)
+	if !strings.Contains(got["README.md"], docURL("4.20", "networking")) || !strings.Contains(got["README.md"], docURL("4.16", "storage")) {
+		t.Errorf("README.md = %q, want only networking fixed", got["README.md"])
+	}
+	if !strings.Contains(got["docs/install.md"], docURL("4.14", "installing")) {
+		t.Errorf("docs/install.md = %q, want installing left", got["docs/install.md"])
+	}
+}
+
+func TestCampaign_ResumesAfterFailure(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, campaignFiles)
+
+	c := NewCampaign("4.20")
+	stepTree(t, c, root, 0)
+
+	// The README changes after the scan, so the step cannot plan its links
+	// and their groups fail without changes, while the installing link
+	// next to a networking link in another file is still fixed
+	files, targets := treeTargets(t, root)
+	readme := filepath.Join(root, "README.md")
+	if err := os.WriteFile(readme, []byte("Moved\n\n"+campaignFiles["README.md"]), 0644); err != nil {
+		t.Fatal(err)
+	}
+	step := c.Step(files, targets, Options{}, 3)
+	if got := groupNames(step.Failed); !reflect.DeepEqual(got, []string{"networking-4.20", "storage-4.20"}) {
+		t.Errorf("failed = %v, want [networking-4.20 storage-4.20]", got)
+	}
+	if got := groupNames(step.Applied); !reflect.DeepEqual(got, []string{"installing-4.20"}) {
+		t.Errorf("applied = %v, want [installing-4.20]", got)
+	}
+	networking := c.Groups[0]
+	if networking.Status != CampaignFailed || networking.Step != 1 || !strings.Contains(networking.Error, "changed since it was scanned") {
+		t.Errorf("networking group = %+v, want failed in step 1", networking)
+	}
+	got := readTree(t, root, campaignFiles)
+	if !strings.Contains(got["docs/install.md"], docURL("4.15", "networking")) || strings.Contains(got["docs/install.md"], docURL("4.14", "installing")) {
+		t.Errorf("docs/install.md = %q, want only installing fixed", got["docs/install.md"])
+	}
+
+	// The state survives the failure
+	path := filepath.Join(t.TempDir(), "campaign.json")
+	if err := c.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	c, err := LoadCampaign(path)
+	if err != nil {
+		t.Fatalf("LoadCampaign() error = %v", err)
+	}
+
+	// The next step scans again and retries the failed groups first
+	step = stepTree(t, c, root, 1)
+	if got := groupNames(step.Applied); !reflect.DeepEqual(got, []string{"networking-4.20"}) {
+		t.Errorf("applied = %v, want [networking-4.20]", got)
+	}
+	if networking := c.Groups[0]; networking.Status != CampaignApplied || networking.Error != "" || networking.Step != 2 {
+		t.Errorf("networking group = %+v, want applied in step 2", networking)
+	}
+	wantState := []string{
+		"networking-4.20 applied 0",
+		"storage-4.20 failed 1",
+		"installing-4.20 applied 0",
+		"security-4.20 applied 0",
+	}
+	if got := campaignState(c); !reflect.DeepEqual(got, wantState) {
+		t.Errorf("groups = %q, want %q", got, wantState)
+	}
+
+	stepTree(t, c, root, 1)
+	if !c.Complete() || c.Steps != 3 {
+		t.Errorf("Complete() = %v, Steps = %d; want true, 3", c.Complete(), c.Steps)
+	}
+}
+
+func TestCampaign_Revalidation(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, campaignFiles)
+
+	c := NewCampaign("4.20")
+	stepTree(t, c, root, 1)
+
+	// An outdated networking link comes back, a storage link is fixed by
+	// hand and a new guide is linked
+	install := filepath.Join(root, "docs", "install.md")
+	content, err := os.ReadFile(install)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content = append(content, "Again "+docURL("4.16", "networking")+"\nNew "+docURL("4.16", "etcd")+"\n"...)
+	if err := os.WriteFile(install, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	readme := filepath.Join(root, "README.md")
+	content, err = os.ReadFile(readme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content = []byte(strings.ReplaceAll(string(content), docURL("4.16", "storage"), docURL("4.20", "storage")))
+	if err := os.WriteFile(readme, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	step := stepTree(t, c, root, 0)
+	if got := groupNames(step.Regressed); !reflect.DeepEqual(got, []string{"networking-4.20"}) {
+		t.Errorf("regressed = %v, want [networking-4.20]", got)
+	}
+	wantState := []string{
+		"networking-4.20 pending 1",
+		"storage-4.20 applied 0",
+		"installing-4.20 pending 1",
+		"security-4.20 applied 0",
+		"etcd-4.20 pending 1",
+	}
+	if got := campaignState(c); !reflect.DeepEqual(got, wantState) {
+		t.Errorf("groups = %q, want %q", got, wantState)
+	}
+	if networking := c.Groups[0]; networking.Regressions != 1 || !reflect.DeepEqual(networking.Files, []string{install}) {
+		t.Errorf("networking group = %+v, want 1 regression in %s", networking, install)
+	}
+	if storage := c.Groups[1]; storage.Step != 0 {
+		t.Errorf("storage group step = %d, want 0 for a group fixed by hand", storage.Step)
+	}
+	if applied, total := c.Progress(); applied != 2 || total != 5 || c.Percent() != 40 {
+		t.Errorf("Progress() = %d, %d, Percent() = %d; want 2, 5, 40", applied, total, c.Percent())
+	}
+	if got := groupNames(c.Remaining()); !reflect.DeepEqual(got, []string{"networking-4.20", "installing-4.20", "etcd-4.20"}) {
+		t.Errorf("Remaining() = %v", got)
+	}
+}
+
+func TestCampaign_MergesGroupsOnNearbyLines(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, changesetFiles)
+
+	c := NewCampaign("4.20")
+	stepTree(t, c, root, 0)
+	want := []string{"networking-4.20", "storage-4.20+etcd-4.20", "installing-4.20+security-4.20"}
+	if got := groupNames(c.Groups); !reflect.DeepEqual(got, want) {
+		t.Errorf("groups = %v, want %v", got, want)
+	}
+
+	step := stepTree(t, c, root, 2)
+	if got := groupNames(step.Applied); !reflect.DeepEqual(got, want[:2]) {
+		t.Errorf("applied = %v, want %v", got, want[:2])
+	}
+	guide := readTree(t, root, changesetFiles)["guide.md"]
+	if strings.Contains(guide, docURL("4.16", "etcd")) || !strings.Contains(guide, docURL("4.14", "security")) {
+		t.Errorf("guide.md = %q, want etcd fixed and security left", guide)
+	}
+}
+
+func TestLoadCampaign(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "campaign.json")
+
+	c := NewCampaign("4.20")
+	c.Groups = []*CampaignGroup{{Groups: []ChangesetGroup{{Document: "networking", TargetVersion: "4.20"}}, Status: CampaignPending, Edits: 2}}
+	if err := c.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	got, err := LoadCampaign(path)
+	if err != nil {
+		t.Fatalf("LoadCampaign() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, c) {
+		t.Errorf("LoadCampaign() = %+v, want %+v", got, c)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Save() left %d files, want 1", len(entries))
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "not JSON", content: "pending", want: "invalid character"},
+		{name: "other schema", content: `{"schema": 2, "target_version": "4.20"}`, want: "unsupported campaign schema 2"},
+		{name: "no target", content: `{"schema": 1}`, want: "no target version"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadCampaign(path); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadCampaign() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
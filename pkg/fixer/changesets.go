@@ -0,0 +1,391 @@
+package fixer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/parser"
+	"github.com/sebrandon1/ocp-doc-checker/pkg/scanner"
+)
+
+// diffContext is the number of unchanged lines around each hunk of a patch
+const diffContext = 3
+
+// ChangesetIndexFile is the name of the index written next to the patches
+const ChangesetIndexFile = "changesets.json"
+
+// ChangesetGroup identifies the fixes of one guide moving to one version
+type ChangesetGroup struct {
+	Document      string `json:"document"`
+	TargetVersion string `json:"target_version"`
+}
+
+// String returns the group as a name usable in file names, e.g.
+// "networking-4.20"
+func (g ChangesetGroup) String() string {
+	return g.Document + "-" + g.TargetVersion
+}
+
+// Changeset is a subset of the fixes of a run that can be reviewed and
+// applied on its own. Most changesets hold a single group; groups with edits
+// on the same or nearby lines of a file are merged so that every patch
+// applies regardless of which other patches were applied before it.
+type Changeset struct {
+	Groups []ChangesetGroup
+	// Changes are the changes with edits of the changeset's groups, by file
+	// in plan order
+	Changes []Change
+
+	plans []*FilePlan
+	edits map[string][]Edit
+}
+
+// Name returns the name of the changeset, e.g. "networking-4.20" or
+// "networking-4.20+storage-4.20" for merged groups
+func (cs *Changeset) Name() string {
+	names := make([]string, len(cs.Groups))
+	for i, g := range cs.Groups {
+		names[i] = g.String()
+	}
+	return strings.Join(names, "+")
+}
+
+// Title returns a one-line summary of the changeset, e.g. "Update networking
+// links to 4.20"
+func (cs *Changeset) Title() string {
+	parts := make([]string, len(cs.Groups))
+	for i, g := range cs.Groups {
+		parts[i] = fmt.Sprintf("%s links to %s", g.Document, g.TargetVersion)
+	}
+	return "Update " + strings.Join(parts, " and ")
+}
+
+// Files returns the paths of the files the changeset modifies
+func (cs *Changeset) Files() []string {
+	var files []string
+	for _, plan := range cs.plans {
+		if len(cs.edits[plan.Path]) > 0 {
+			files = append(files, plan.Path)
+		}
+	}
+	return files
+}
+
+// Edits returns the number of edits of the changeset
+func (cs *Changeset) Edits() int {
+	n := 0
+	for _, edits := range cs.edits {
+		n += len(edits)
+	}
+	return n
+}
+
+// Patchable reports why the plan's fixes cannot be written as a patch, or
+// nil if they can. Patches are made of the file's bytes, which only match
+// its decoded text for UTF-8 files.
+func (p *FilePlan) Patchable() error {
+	if p.encoding.Name != scanner.UTF8.Name {
+		return fmt.Errorf("%s is %s encoded and cannot be fixed with a patch", p.Path, p.encoding.Name)
+	}
+	return nil
+}
+
+// groupOf returns the changeset group of a replacement: the guide of the old
+// URL and the version it moves to
+func groupOf(r Replacement) ChangesetGroup {
+	document := "other"
+	if docURL, err := parser.ParseOCPDocURL(r.OldURL); err == nil {
+		document = docURL.Document
+	}
+	return ChangesetGroup{Document: document, TargetVersion: r.NewVersion}
+}
+
+// Changesets splits the fixes of plans into changesets by (document, target
+// version), in order of first appearance. Only changes with edits are
+// included; every plan must be Patchable.
+func Changesets(plans []*FilePlan) ([]*Changeset, error) {
+	var groups []ChangesetGroup
+	index := make(map[ChangesetGroup]int)
+	parent := []int{}
+	find := func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+	union := func(i, j int) {
+		i, j = find(i), find(j)
+		// Keep the group that appeared first as the root
+		if i > j {
+			i, j = j, i
+		}
+		parent[j] = i
+	}
+
+	type span struct {
+		first, last int // 1-based lines
+		group       int
+	}
+
+	for _, plan := range plans {
+		if err := plan.Patchable(); err != nil {
+			return nil, err
+		}
+
+		var spans []span
+		for _, change := range plan.Changes {
+			if len(change.Edits) == 0 {
+				continue
+			}
+			g := groupOf(change.Replacement)
+			i, ok := index[g]
+			if !ok {
+				i = len(groups)
+				index[g] = i
+				groups = append(groups, g)
+				parent = append(parent, i)
+			}
+			for _, e := range change.Edits {
+				spans = append(spans, span{
+					first: lineAt(plan.text, e.Start),
+					last:  lineAt(plan.text, e.End),
+					group: i,
+				})
+			}
+		}
+
+		// Hunks of different changesets must not touch each other's lines,
+		// not even as context, or one patch stops applying after the other
+		for a := range spans {
+			for b := a + 1; b < len(spans); b++ {
+				if spans[a].group == spans[b].group {
+					continue
+				}
+				if spans[b].first <= spans[a].last+diffContext && spans[a].first <= spans[b].last+diffContext {
+					union(spans[a].group, spans[b].group)
+				}
+			}
+		}
+	}
+
+	var changesets []*Changeset
+	byRoot := make(map[int]*Changeset)
+	for i, g := range groups {
+		root := find(i)
+		cs, ok := byRoot[root]
+		if !ok {
+			cs = &Changeset{edits: make(map[string][]Edit)}
+			byRoot[root] = cs
+			changesets = append(changesets, cs)
+		}
+		cs.Groups = append(cs.Groups, g)
+	}
+
+	for _, plan := range plans {
+		for _, change := range plan.Changes {
+			if len(change.Edits) == 0 {
+				continue
+			}
+			cs := byRoot[find(index[groupOf(change.Replacement)])]
+			if len(cs.edits[plan.Path]) == 0 {
+				cs.plans = append(cs.plans, plan)
+			}
+			cs.Changes = append(cs.Changes, change)
+			cs.edits[plan.Path] = append(cs.edits[plan.Path], change.Edits...)
+		}
+	}
+
+	return changesets, nil
+}
+
+// lineAt returns the 1-based line of a byte offset in text
+func lineAt(text string, offset int) int {
+	return strings.Count(text[:offset], "\n") + 1
+}
+
+// Patch returns the changeset as a unified diff in git format, with file
+// paths relative to base so the patch applies with "git apply" run in base
+func (cs *Changeset) Patch(base string) (string, error) {
+	var b strings.Builder
+	for _, plan := range cs.plans {
+		edits := cs.edits[plan.Path]
+		fixed, err := Apply(plan.text, edits)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", plan.Path, err)
+		}
+
+		bom := ""
+		if plan.encoding.BOM {
+			bom = "\ufeff"
+		}
+		oldLines := splitLines(bom + plan.text)
+		newLines := splitLines(bom + fixed)
+		if len(oldLines) != len(newLines) {
+			return "", fmt.Errorf("%s: fixes change the number of lines", plan.Path)
+		}
+
+		name := patchPath(base, plan.Path)
+		fmt.Fprintf(&b, "diff --git a/%s b/%s\n--- a/%s\n+++ b/%s\n", name, name, name, name)
+		writeHunks(&b, oldLines, newLines)
+	}
+	return b.String(), nil
+}
+
+// patchPath returns path relative to base, with forward slashes
+func patchPath(base, path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		if rel, err := filepath.Rel(base, abs); err == nil {
+			path = rel
+		}
+	}
+	return filepath.ToSlash(path)
+}
+
+// splitLines splits text after every newline, keeping the newlines
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// writeHunks writes the hunks of a diff between two texts with the same
+// number of lines
+func writeHunks(b *strings.Builder, oldLines, newLines []string) {
+	var changed []int
+	for i := range oldLines {
+		if oldLines[i] != newLines[i] {
+			changed = append(changed, i)
+		}
+	}
+
+	for len(changed) > 0 {
+		// Extend the hunk while the next change is close enough to share
+		// its context
+		n := 1
+		for n < len(changed) && changed[n]-changed[n-1] <= 2*diffContext {
+			n++
+		}
+		start := max(changed[0]-diffContext, 0)
+		end := min(changed[n-1]+diffContext+1, len(oldLines))
+		changed = changed[n:]
+
+		fmt.Fprintf(b, "@@ -%d,%d +%d,%d @@\n", start+1, end-start, start+1, end-start)
+		for i := start; i < end; {
+			if oldLines[i] == newLines[i] {
+				writeLine(b, " ", oldLines[i])
+				i++
+				continue
+			}
+			// Removed lines of a run come before its added lines
+			j := i
+			for j < end && oldLines[j] != newLines[j] {
+				writeLine(b, "-", oldLines[j])
+				j++
+			}
+			for k := i; k < j; k++ {
+				writeLine(b, "+", newLines[k])
+			}
+			i = j
+		}
+	}
+}
+
+// writeLine writes a diff line, marking a last line without a newline
+func writeLine(b *strings.Builder, prefix, line string) {
+	b.WriteString(prefix)
+	b.WriteString(line)
+	if !strings.HasSuffix(line, "\n") {
+		b.WriteString("\n\\ No newline at end of file\n")
+	}
+}
+
+// changesetIndex is the content of ChangesetIndexFile
+type changesetIndex struct {
+	Changesets []changesetEntry `json:"changesets"`
+}
+
+type changesetEntry struct {
+	Patch  string           `json:"patch"`
+	Title  string           `json:"title"`
+	Groups []ChangesetGroup `json:"groups"`
+	Files  []string         `json:"files"`
+	Edits  int              `json:"edits"`
+	URLs   []changesetURL   `json:"urls"`
+}
+
+type changesetURL struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// WriteChangesets writes one patch per changeset into dir, numbered in
+// order, and an index of them in ChangesetIndexFile. Patch paths are
+// relative to base. dir is created if needed and must be empty, so patches
+// of an earlier run are never mixed in.
+func WriteChangesets(dir, base string, changesets []*Changeset) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) > 0 {
+		return nil, fmt.Errorf("%s is not empty", dir)
+	}
+
+	var index changesetIndex
+	var patches []string
+	for i, cs := range changesets {
+		patch, err := cs.Patch(base)
+		if err != nil {
+			return nil, err
+		}
+		name := fmt.Sprintf("%02d-%s.patch", i+1, cs.Name())
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(patch), 0644); err != nil {
+			return nil, err
+		}
+		patches = append(patches, filepath.Join(dir, name))
+
+		entry := changesetEntry{
+			Patch:  name,
+			Title:  cs.Title(),
+			Groups: cs.Groups,
+			Edits:  cs.Edits(),
+			URLs:   []changesetURL{},
+		}
+		for _, file := range cs.Files() {
+			entry.Files = append(entry.Files, patchPath(base, file))
+		}
+		seen := make(map[string]bool)
+		for _, change := range cs.Changes {
+			r := change.Replacement
+			if !seen[r.OldURL] {
+				seen[r.OldURL] = true
+				entry.URLs = append(entry.URLs, changesetURL{Old: r.OldURL, New: r.NewURL})
+			}
+		}
+		sort.Slice(entry.URLs, func(a, b int) bool { return entry.URLs[a].Old < entry.URLs[b].Old })
+		index.Changesets = append(index.Changesets, entry)
+	}
+	if index.Changesets == nil {
+		index.Changesets = []changesetEntry{}
+	}
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, ChangesetIndexFile), append(data, '\n'), 0644); err != nil {
+		return nil, err
+	}
+
+	return patches, nil
+}
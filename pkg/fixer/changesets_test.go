@@ -0,0 +1,257 @@
+package fixer
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/parser"
+	"github.com/sebrandon1/ocp-doc-checker/pkg/scanner"
+)
+
+// docURL returns the URL of the index page of a guide at a version
+func docURL(version, document string) string {
+	return "https://docs.redhat.com/en/documentation/openshift_container_platform/" + version + "/html/" + document + "/index"
+}
+
+// changesetFiles are the files of the changeset tests: networking and storage
+// are far apart, installing and security share a line and etcd is two lines
+// below a storage link
+var changesetFiles = map[string]string{
+	"guide.md": "# Guide\n\n" +
+		"See " + docURL("4.14", "networking") + ".\n" +
+		strings.Repeat("filler\n", 10) +
+		"Storage: " + docURL("4.16", "storage") + "\n" +
+		"\n" +
+		"Backups: " + docURL("4.16", "etcd") + "\n" +
+		strings.Repeat("filler\n", 10) +
+		"Both " + docURL("4.14", "installing") + " and " + docURL("4.14", "security") + ".\n",
+	"sub dir/notes.md": "\ufeffNotes\r\n" +
+		"[Networking in 4.14](" + docURL("4.14", "networking") + ")\r\n" +
+		strings.Repeat("filler\r\n", 10) +
+		"Last: " + docURL("4.14", "networking"),
+}
+
+// writeTree writes files below root
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readTree reads the named files below root
+func readTree(t *testing.T, root string, files map[string]string) map[string]string {
+	t.Helper()
+	got := make(map[string]string)
+	for name := range files {
+		content, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[name] = string(content)
+	}
+	return got
+}
+
+// planTree plans the fixes of every URL below root to 4.20, or to write
+// them when write is set
+func planTree(t *testing.T, root string, opts Options, write bool) []*FilePlan {
+	t.Helper()
+
+	files, targets := treeTargets(t, root)
+	var plans []*FilePlan
+	for _, path := range files {
+		plan, err := FixFile(path, targets[path], opts, write)
+		if err != nil {
+			t.Fatal(err)
+		}
+		plans = append(plans, plan)
+	}
+	return plans
+}
+
+// treeTargets scans root and returns the files with URLs before 4.20 and
+// the targets moving them to 4.20
+func treeTargets(t *testing.T, root string) ([]string, map[string][]Target) {
+	t.Helper()
+
+	occurrences, err := scanner.New().ScanDirectory(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var files []string
+	targets := make(map[string][]Target)
+	for _, occ := range occurrences {
+		doc, err := parser.ParseOCPDocURL(occ.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if doc.Version == "4.20" {
+			continue
+		}
+		r := Replacement{
+			OldURL:     occ.URL,
+			NewURL:     docURL("4.20", doc.Document),
+			OldVersion: doc.Version,
+			NewVersion: "4.20",
+		}
+		if _, ok := targets[occ.Path]; !ok {
+			files = append(files, occ.Path)
+		}
+		targets[occ.Path] = append(targets[occ.Path], Target{Occurrence: occ, Replacement: r})
+	}
+	return files, targets
+}
+
+// Applying every patch, in any order, must give the same files as -fix
+func TestWriteChangesets_MatchesFix(t *testing.T) {
+	git, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git is needed to apply the patches")
+	}
+	opts := Options{FixLinkText: true}
+
+	source := t.TempDir()
+	writeTree(t, source, changesetFiles)
+	changesets, err := Changesets(planTree(t, source, opts, false))
+	if err != nil {
+		t.Fatalf("Changesets() error = %v", err)
+	}
+
+	var names []string
+	for _, cs := range changesets {
+		names = append(names, cs.Name())
+	}
+	want := []string{"networking-4.20", "storage-4.20+etcd-4.20", "installing-4.20+security-4.20"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("changesets = %v, want %v", names, want)
+	}
+
+	dir := filepath.Join(t.TempDir(), "changesets")
+	patches, err := WriteChangesets(dir, source, changesets)
+	if err != nil {
+		t.Fatalf("WriteChangesets() error = %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(source, "guide.md")); string(got) != changesetFiles["guide.md"] {
+		t.Fatal("WriteChangesets modified a scanned file")
+	}
+
+	planTree(t, source, opts, true)
+	fixed := readTree(t, source, changesetFiles)
+
+	reversed := make([]string, len(patches))
+	for i, patch := range patches {
+		reversed[len(patches)-1-i] = patch
+	}
+	for name, order := range map[string][]string{"in order": patches, "reversed": reversed} {
+		t.Run(name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTree(t, root, changesetFiles)
+			for _, patch := range order {
+				cmd := exec.Command(git, "apply", patch)
+				cmd.Dir = root
+				if out, err := cmd.CombinedOutput(); err != nil {
+					t.Fatalf("git apply %s: %v\n%s", filepath.Base(patch), err, out)
+				}
+			}
+			if got := readTree(t, root, changesetFiles); !reflect.DeepEqual(got, fixed) {
+				t.Errorf("patched files =\n%q\nwant\n%q", got, fixed)
+			}
+		})
+	}
+}
+
+func TestWriteChangesets_Index(t *testing.T) {
+	source := t.TempDir()
+	writeTree(t, source, changesetFiles)
+	changesets, err := Changesets(planTree(t, source, Options{}, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if _, err := WriteChangesets(dir, source, changesets); err != nil {
+		t.Fatalf("WriteChangesets() error = %v", err)
+	}
+	if _, err := WriteChangesets(dir, source, changesets); err == nil {
+		t.Error("WriteChangesets() into a non-empty directory succeeded")
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, ChangesetIndexFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index changesetIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		t.Fatal(err)
+	}
+
+	// The link text of the first networking link mentions 4.14, so without
+	// FixLinkText only its plain occurrence is fixed
+	want := []changesetEntry{
+		{
+			Patch:  "01-networking-4.20.patch",
+			Title:  "Update networking links to 4.20",
+			Groups: []ChangesetGroup{{Document: "networking", TargetVersion: "4.20"}},
+			Files:  []string{"guide.md", "sub dir/notes.md"},
+			Edits:  2,
+			URLs:   []changesetURL{{Old: docURL("4.14", "networking"), New: docURL("4.20", "networking")}},
+		},
+		{
+			Patch:  "02-storage-4.20+etcd-4.20.patch",
+			Title:  "Update storage links to 4.20 and etcd links to 4.20",
+			Groups: []ChangesetGroup{{Document: "storage", TargetVersion: "4.20"}, {Document: "etcd", TargetVersion: "4.20"}},
+			Files:  []string{"guide.md"},
+			Edits:  2,
+			URLs: []changesetURL{
+				{Old: docURL("4.16", "etcd"), New: docURL("4.20", "etcd")},
+				{Old: docURL("4.16", "storage"), New: docURL("4.20", "storage")},
+			},
+		},
+		{
+			Patch:  "03-installing-4.20+security-4.20.patch",
+			Title:  "Update installing links to 4.20 and security links to 4.20",
+			Groups: []ChangesetGroup{{Document: "installing", TargetVersion: "4.20"}, {Document: "security", TargetVersion: "4.20"}},
+			Files:  []string{"guide.md"},
+			Edits:  2,
+			URLs: []changesetURL{
+				{Old: docURL("4.14", "installing"), New: docURL("4.20", "installing")},
+				{Old: docURL("4.14", "security"), New: docURL("4.20", "security")},
+			},
+		},
+	}
+	if !reflect.DeepEqual(index.Changesets, want) {
+		t.Errorf("index =\n%+v\nwant\n%+v", index.Changesets, want)
+	}
+}
+
+func TestChangesets_NotPatchable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "guide.md")
+	content := "See " + oldURL + ".\n"
+	if err := scanner.WriteFile(path, []byte(content), scanner.Encoding{Name: "utf-16le", BOM: true}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := FixFile(path, targetsFor(t, path), Options{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Patchable() == nil {
+		t.Error("Patchable() = nil for a UTF-16 file")
+	}
+	if _, err := Changesets([]*FilePlan{plan}); err == nil {
+		t.Error("Changesets() error = nil for a UTF-16 file")
+	}
+}
@@ -0,0 +1,112 @@
+package fixer
+
+import (
+	"os"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/scanner"
+)
+
+// Target is an occurrence of an outdated URL and its replacement
+type Target struct {
+	Occurrence  scanner.Occurrence
+	Replacement Replacement
+}
+
+// FilePlan is the planned fix for a single file
+type FilePlan struct {
+	Path    string
+	Changes []Change
+	// Errors holds the occurrences that could not be planned, e.g. because
+	// the file changed since it was scanned
+	Errors []error
+
+	// text and encoding are the decoded content the plan was made for
+	text     string
+	encoding scanner.Encoding
+}
+
+// Edits returns the edits of every change in the plan
+func (p *FilePlan) Edits() []Edit {
+	var edits []Edit
+	for _, change := range p.Changes {
+		edits = append(edits, change.Edits...)
+	}
+	return edits
+}
+
+// Modifies reports whether applying the plan changes the file
+func (p *FilePlan) Modifies() bool {
+	return len(p.Edits()) > 0
+}
+
+// Unfixed returns the number of targets the plan leaves outdated for manual
+// fixing; historical references and occurrences in generated files are
+// meant to stay as written
+func (p *FilePlan) Unfixed() int {
+	unfixed := len(p.Errors)
+	for _, change := range p.Changes {
+		if len(change.Edits) == 0 && !change.Outcome.leftAsWritten() {
+			unfixed++
+		}
+	}
+	return unfixed
+}
+
+// CrossDocument returns the changes whose new URL is served from another
+// guide, whether or not they are applied
+func (p *FilePlan) CrossDocument() []Change {
+	var changes []Change
+	for _, change := range p.Changes {
+		if change.Replacement.CrossDocument() && !change.Outcome.leftAsWritten() && change.Outcome != OutcomeEncoded {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// FixFile plans the fixes for targets in the file at path and, when write
+// is set, applies them. The file is edited as UTF-8 and written back in its
+// original encoding. The plan is the same whether or not it is written, so
+// a dry run reports exactly the edits a real run makes. Before writing, the
+// fixed content is verified against the plan, and the file is left
+// unchanged with ErrUnexpectedModification if anything else changed.
+func FixFile(path string, targets []Target, opts Options, write bool) (*FilePlan, error) {
+	content, encoding, err := scanner.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	text := string(content)
+
+	plan := &FilePlan{Path: path, text: text, encoding: encoding}
+	for _, target := range targets {
+		change, err := Plan(text, target.Occurrence, target.Replacement, opts)
+		if err != nil {
+			plan.Errors = append(plan.Errors, err)
+			continue
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	if !write || !plan.Modifies() {
+		return plan, nil
+	}
+
+	// A file whose fixed content fails verification is left as it was
+	fixed, err := apply(text, plan.Edits())
+	if err != nil {
+		return nil, err
+	}
+	if err := plan.Verify(fixed); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := scanner.WriteFile(path, []byte(fixed), encoding, info.Mode().Perm()); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
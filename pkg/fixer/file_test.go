@@ -0,0 +1,292 @@
+package fixer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/scanner"
+)
+
+// targetsFor scans the file at path and targets every occurrence of oldURL
+func targetsFor(t *testing.T, path string) []Target {
+	t.Helper()
+
+	occurrences, err := scanner.New().ScanFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var targets []Target
+	for _, occ := range occurrences {
+		if occ.URL == oldURL {
+			targets = append(targets, Target{Occurrence: occ, Replacement: replacement})
+		}
+	}
+	return targets
+}
+
+// A dry run must report exactly the edits a real run makes
+func TestFixFile_DryRunMatchesFix(t *testing.T) {
+	content := "# Guide\n\n" +
+		"See [OpenShift 4.14 networking](" + oldURL + ") and [networking](" + oldURL + ").\n" +
+		"Plain: " + oldURL + ".\n"
+
+	tests := []struct {
+		name     string
+		encoding scanner.Encoding
+		opts     Options
+		edits    int
+		unfixed  int
+	}{
+		{"utf-8", scanner.UTF8, Options{}, 2, 1},
+		{"utf-8 with link text", scanner.UTF8, Options{FixLinkText: true}, 4, 0},
+		{"utf-16le", scanner.Encoding{Name: "utf-16le", BOM: true}, Options{}, 2, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "guide.md")
+			if err := scanner.WriteFile(path, []byte(content), tt.encoding, 0644); err != nil {
+				t.Fatal(err)
+			}
+			targets := targetsFor(t, path)
+
+			dryRun, err := FixFile(path, targets, tt.opts, false)
+			if err != nil {
+				t.Fatalf("FixFile(dry run) error = %v", err)
+			}
+			if got, _, _ := scanner.ReadFile(path); string(got) != content {
+				t.Fatal("dry run modified the file")
+			}
+			if len(dryRun.Edits()) != tt.edits || dryRun.Unfixed() != tt.unfixed {
+				t.Errorf("dry run = %d edits, %d unfixed; want %d edits, %d unfixed",
+					len(dryRun.Edits()), dryRun.Unfixed(), tt.edits, tt.unfixed)
+			}
+
+			fix, err := FixFile(path, targets, tt.opts, true)
+			if err != nil {
+				t.Fatalf("FixFile() error = %v", err)
+			}
+			if !reflect.DeepEqual(dryRun, fix) {
+				t.Errorf("dry run plan differs from the applied plan:\n%+v\n%+v", dryRun, fix)
+			}
+
+			want, err := Apply(content, dryRun.Edits())
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, encoding, err := scanner.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != want {
+				t.Errorf("fixed file =\n%s\nwant\n%s", got, want)
+			}
+			if encoding != tt.encoding {
+				t.Errorf("fixed file encoding = %+v, want %+v", encoding, tt.encoding)
+			}
+		})
+	}
+}
+
+func TestFixFile_NothingToFix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "guide.md")
+	content := "See [OpenShift 4.14](" + oldURL + ").\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := FixFile(path, targetsFor(t, path), Options{}, true)
+	if err != nil {
+		t.Fatalf("FixFile() error = %v", err)
+	}
+	if plan.Modifies() {
+		t.Error("Modifies() = true for a plan with only links left for review")
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != content || info.Mode().Perm() != 0600 {
+		t.Error("file was rewritten although nothing changed")
+	}
+}
+
+// A replacement bug must abort the fix and leave the file as it was
+func TestFixFile_UnexpectedModification(t *testing.T) {
+	// The second URL extends the outdated one, which the checker found
+	// up to date and did not target
+	content := "# Guide\n\n" +
+		"See [networking](" + oldURL + ").\n" +
+		"Also " + oldURL + "/ingress for ingress.\n"
+
+	tests := []struct {
+		name  string
+		apply func(text string, edits []Edit) (string, error)
+		line  string
+	}{
+		{
+			// Replacing by text instead of offset hits every URL sharing the prefix
+			name: "prefix collision",
+			apply: func(text string, edits []Edit) (string, error) {
+				fixed := text
+				for _, e := range edits {
+					fixed = strings.ReplaceAll(fixed, text[e.Start:e.End], e.Text)
+				}
+				return fixed, nil
+			},
+			line: "at line 4:",
+		},
+		{
+			name: "off by one",
+			apply: func(text string, edits []Edit) (string, error) {
+				shifted := make([]Edit, len(edits))
+				for i, e := range edits {
+					shifted[i] = Edit{Start: e.Start, End: e.End + 1, Text: e.Text}
+				}
+				return Apply(text, shifted)
+			},
+			line: "at line 3:",
+		},
+		{
+			name: "edits nothing",
+			apply: func(text string, edits []Edit) (string, error) {
+				return text, nil
+			},
+			line: "at line 3:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(orig func(string, []Edit) (string, error)) { apply = orig }(apply)
+			apply = tt.apply
+
+			path := filepath.Join(t.TempDir(), "guide.md")
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := FixFile(path, targetsFor(t, path), Options{}, true)
+			if !errors.Is(err, ErrUnexpectedModification) {
+				t.Fatalf("FixFile() error = %v, want %v", err, ErrUnexpectedModification)
+			}
+			if !strings.Contains(err.Error(), tt.line) {
+				t.Errorf("FixFile() error = %v, want it to report %q", err, tt.line)
+			}
+			if got, _ := os.ReadFile(path); string(got) != content {
+				t.Errorf("file was modified by an aborted fix:\n%s", got)
+			}
+		})
+	}
+}
+
+// A fix undone or mangled after it was written must fail verification,
+// while a file only reformatted or re-encoded since passes
+func TestFilePlan_VerifyWritten(t *testing.T) {
+	content := "# Guide\n\n" +
+		"See [networking](" + oldURL + ").\n" +
+		"Plain: " + oldURL + ".\n"
+
+	tests := []struct {
+		name string
+		// corrupt changes the fixed file between the fix and its verification
+		corrupt func(t *testing.T, path, fixed string)
+		want    []string
+	}{
+		{
+			name:    "untouched",
+			corrupt: func(t *testing.T, path, fixed string) {},
+		},
+		{
+			name: "reformatted",
+			corrupt: func(t *testing.T, path, fixed string) {
+				writeFile(t, path, strings.ReplaceAll(fixed, "\n", "\n\n"))
+			},
+		},
+		{
+			name: "re-encoded",
+			corrupt: func(t *testing.T, path, fixed string) {
+				if err := scanner.WriteFile(path, []byte(fixed), scanner.Encoding{Name: "utf-16le", BOM: true}, 0644); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "fix reverted",
+			corrupt: func(t *testing.T, path, fixed string) {
+				writeFile(t, path, content)
+			},
+			want: []string{"new URL missing: " + newURL, "old URL still present: " + oldURL},
+		},
+		{
+			name: "one fix reverted",
+			corrupt: func(t *testing.T, path, fixed string) {
+				writeFile(t, path, strings.Replace(fixed, newURL, oldURL, 1))
+			},
+			want: []string{"new URL missing: " + newURL, "old URL still present: " + oldURL},
+		},
+		{
+			name: "old URL added back",
+			corrupt: func(t *testing.T, path, fixed string) {
+				writeFile(t, path, fixed+"Again: "+oldURL+"\n")
+			},
+			want: []string{"old URL still present: " + oldURL},
+		},
+		{
+			name: "new URL truncated",
+			corrupt: func(t *testing.T, path, fixed string) {
+				writeFile(t, path, strings.ReplaceAll(fixed, "/4.20/html/networking/index", "/4.20/html/networking"))
+			},
+			want: []string{"new URL missing: " + newURL},
+		},
+		{
+			name: "deleted",
+			corrupt: func(t *testing.T, path, fixed string) {
+				if err := os.Remove(path); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []string{"no such file or directory"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "guide.md")
+			writeFile(t, path, content)
+			plan, err := FixFile(path, targetsFor(t, path), Options{}, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fixed, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tt.corrupt(t, path, string(fixed))
+			errs := plan.VerifyWritten(scanner.New())
+			if len(errs) != len(tt.want) {
+				t.Fatalf("VerifyWritten() = %v, want %d error(s)", errs, len(tt.want))
+			}
+			for i, err := range errs {
+				if !errors.Is(err, ErrFixNotWritten) || !strings.Contains(err.Error(), path) || !strings.Contains(err.Error(), tt.want[i]) {
+					t.Errorf("VerifyWritten()[%d] = %v, want %v for %s: %s", i, err, ErrFixNotWritten, path, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// writeFile writes content to the file at path
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
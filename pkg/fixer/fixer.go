@@ -0,0 +1,237 @@
+package fixer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/scanner"
+)
+
+// Edit replaces the text between two byte offsets
+type Edit struct {
+	Start int
+	End   int
+	Text  string
+}
+
+// Replacement describes an outdated URL and the URL that replaces it
+type Replacement struct {
+	OldURL     string
+	NewURL     string
+	OldVersion string // e.g., "4.14"
+	NewVersion string // e.g., "4.20"
+	// RenamedFrom is the old page slug when the new URL uses a renamed page
+	RenamedFrom string
+	// MovedFrom is the old page slug when the new URL is another page of
+	// the guide that the section moved to, found by a sibling search
+	MovedFrom string
+	// DroppedAnchor is the fragment left out of the new URL because the
+	// page at the new version lacks it, under the page-only anchor policy
+	DroppedAnchor string
+	// ServedDocument is set when the new URL redirects to another guide,
+	// the slug of that guide. OldTitle and NewTitle are the titles of both
+	// guides, when known.
+	ServedDocument string
+	OldTitle       string
+	NewTitle       string
+}
+
+// CrossDocument reports whether the replacement moves readers to another
+// guide
+func (r Replacement) CrossDocument() bool {
+	return r.ServedDocument != ""
+}
+
+// Outcome describes what fixing a single occurrence does
+type Outcome string
+
+const (
+	// OutcomeFixed means the URL is replaced
+	OutcomeFixed Outcome = "fixed"
+	// OutcomeLinkTextUpdated means the URL is replaced and the old version
+	// in the text of its Markdown link is updated too
+	OutcomeLinkTextUpdated Outcome = "link-text-updated"
+	// OutcomeLinkTextReview means the text of the URL's Markdown link
+	// mentions the old version, so the occurrence is left for manual review
+	OutcomeLinkTextReview Outcome = "link-text-review"
+	// OutcomeEncoded means the URL was found inside an encoded value by a
+	// deep scan and has to be fixed by hand
+	OutcomeEncoded Outcome = "encoded"
+	// OutcomeHistorical means the URL is a historical reference in a
+	// changelog or release notes and is left as written
+	OutcomeHistorical Outcome = "historical"
+	// OutcomeGenerated means the URL is in a generated or minified file,
+	// which its generator would overwrite, and is left as written
+	OutcomeGenerated Outcome = "generated"
+	// OutcomeCrossDocument means the new URL is served from another guide,
+	// so fixing it changes which guide readers land in. It is left for a
+	// human to decide unless Options.AllowCrossDocument is set.
+	OutcomeCrossDocument Outcome = "cross-document"
+	// OutcomeMovedAnchor means the section was found on another page of the
+	// guide by a sibling search, from the resemblance of its title and id,
+	// so the new URL is a guess. It is left for a human to confirm unless
+	// Options.AllowMovedAnchor is set.
+	OutcomeMovedAnchor Outcome = "moved-anchor"
+	// OutcomeDroppedAnchor means the page at the new version lacks the
+	// anchor, so the new URL leaves the fragment out and readers land at
+	// the top of the page. It is left for a human to decide unless
+	// Options.AllowDroppedAnchor is set.
+	OutcomeDroppedAnchor Outcome = "dropped-anchor"
+)
+
+// leftAsWritten reports whether the outcome keeps the occurrence as it is
+// on purpose, rather than leaving it for manual fixing
+func (o Outcome) leftAsWritten() bool {
+	return o == OutcomeHistorical || o == OutcomeGenerated
+}
+
+// Options controls how occurrences are fixed
+type Options struct {
+	// FixLinkText updates the old version in Markdown link text instead of
+	// skipping those occurrences for manual review
+	FixLinkText bool
+	// AllowCrossDocument fixes URLs whose new version is served from
+	// another guide instead of leaving them for review
+	AllowCrossDocument bool
+	// AllowMovedAnchor fixes URLs whose section was found on another page
+	// of the guide instead of leaving them for review
+	AllowMovedAnchor bool
+	// AllowDroppedAnchor fixes URLs whose new version lacks the anchor,
+	// without their fragment, instead of leaving them for review
+	AllowDroppedAnchor bool
+}
+
+// Change is the planned fix for a single occurrence
+type Change struct {
+	Occurrence  scanner.Occurrence
+	Replacement Replacement
+	Outcome     Outcome
+	Edits       []Edit // empty when the occurrence is left for manual fixing
+	NewLinkText string // set when Outcome is OutcomeLinkTextUpdated
+}
+
+// Plan decides how to fix one occurrence of r.OldURL in text, the decoded
+// content the occurrence was found in. It fails when the text at the
+// occurrence no longer holds the old URL.
+func Plan(text string, occ scanner.Occurrence, r Replacement, opts Options) (Change, error) {
+	if occ.Historical {
+		return Change{Occurrence: occ, Replacement: r, Outcome: OutcomeHistorical}, nil
+	}
+	if occ.Generated != "" {
+		return Change{Occurrence: occ, Replacement: r, Outcome: OutcomeGenerated}, nil
+	}
+	if occ.Encoding != "" {
+		return Change{Occurrence: occ, Replacement: r, Outcome: OutcomeEncoded}, nil
+	}
+
+	if occ.Start < 0 || occ.End > len(text) || text[occ.Start:occ.End] != r.OldURL {
+		return Change{}, fmt.Errorf("%s:%d: %s not found at its scanned position, the file changed since it was scanned", occ.Path, occ.Line, r.OldURL)
+	}
+
+	change := Change{
+		Occurrence:  occ,
+		Replacement: r,
+		Outcome:     OutcomeFixed,
+	}
+
+	if r.CrossDocument() && !opts.AllowCrossDocument {
+		change.Outcome = OutcomeCrossDocument
+		return change, nil
+	}
+	if r.MovedFrom != "" && !opts.AllowMovedAnchor {
+		change.Outcome = OutcomeMovedAnchor
+		return change, nil
+	}
+	if r.DroppedAnchor != "" && !opts.AllowDroppedAnchor {
+		change.Outcome = OutcomeDroppedAnchor
+		return change, nil
+	}
+
+	if occ.LinkText != "" && r.OldVersion != r.NewVersion && MentionsVersion(occ.LinkText, r.OldVersion) {
+		if !opts.FixLinkText {
+			change.Outcome = OutcomeLinkTextReview
+			return change, nil
+		}
+
+		change.Outcome = OutcomeLinkTextUpdated
+		change.NewLinkText = ReplaceVersion(occ.LinkText, r.OldVersion, r.NewVersion)
+		change.Edits = append(change.Edits, Edit{Start: occ.LinkTextStart, End: occ.LinkTextEnd, Text: change.NewLinkText})
+	}
+
+	change.Edits = append(change.Edits, Edit{Start: occ.Start, End: occ.End, Text: r.NewURL})
+	return change, nil
+}
+
+// Apply returns text with the edits applied. Edits may be given in any
+// order but must not overlap.
+func Apply(text string, edits []Edit) (string, error) {
+	sorted := make([]Edit, len(edits))
+	copy(sorted, edits)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start < sorted[j].Start
+	})
+
+	var b strings.Builder
+	last := 0
+	for _, e := range sorted {
+		if e.Start < last || e.End < e.Start || e.End > len(text) {
+			return "", fmt.Errorf("edit at bytes %d-%d overlaps another edit or is out of range", e.Start, e.End)
+		}
+		b.WriteString(text[last:e.Start])
+		b.WriteString(e.Text)
+		last = e.End
+	}
+	b.WriteString(text[last:])
+
+	return b.String(), nil
+}
+
+// MentionsVersion reports whether text contains version as a whole
+// major.minor token; "4.14" matches "OpenShift 4.14" and "4.14." at the end
+// of a sentence but not "4.14.3" or "14.14"
+func MentionsVersion(text, version string) bool {
+	return len(versionTokens(text, version)) > 0
+}
+
+// ReplaceVersion replaces every whole major.minor token oldVersion in text
+// with newVersion
+func ReplaceVersion(text, oldVersion, newVersion string) string {
+	var b strings.Builder
+	last := 0
+	for _, i := range versionTokens(text, oldVersion) {
+		b.WriteString(text[last:i])
+		b.WriteString(newVersion)
+		last = i + len(oldVersion)
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// versionTokens returns the offsets of whole-token matches of version in text
+func versionTokens(text, version string) []int {
+	if version == "" {
+		return nil
+	}
+
+	var offsets []int
+	for from := 0; ; {
+		i := strings.Index(text[from:], version)
+		if i < 0 {
+			return offsets
+		}
+		i += from
+		end := i + len(version)
+
+		before := i == 0 || !isDigit(text[i-1]) && !(text[i-1] == '.' && i > 1 && isDigit(text[i-2]))
+		after := end == len(text) || !isDigit(text[end]) && !(text[end] == '.' && end+1 < len(text) && isDigit(text[end+1]))
+		if before && after {
+			offsets = append(offsets, i)
+		}
+		from = i + 1
+	}
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
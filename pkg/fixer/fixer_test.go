@@ -0,0 +1,305 @@
+package fixer
+
+import (
+	"testing"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/scanner"
+)
+
+const (
+	oldURL = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.14/html/networking/index"
+	newURL = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.20/html/networking/index"
+)
+
+var replacement = Replacement{OldURL: oldURL, NewURL: newURL, OldVersion: "4.14", NewVersion: "4.20"}
+
+// fix scans content as a Markdown file and applies the planned change for
+// its only URL
+func fix(t *testing.T, content string, opts Options) (Change, string) {
+	t.Helper()
+
+	occurrences := scanner.New().ScanContent("doc.md", []byte(content))
+	if len(occurrences) != 1 {
+		t.Fatalf("found %d URLs, want 1", len(occurrences))
+	}
+
+	change, err := Plan(content, occurrences[0], replacement, opts)
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+
+	fixed, err := Apply(content, change.Edits)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	return change, fixed
+}
+
+func TestPlan_LinkText(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		opts    Options
+		outcome Outcome
+		want    string
+	}{
+		{
+			name:    "link text mentions version, flagged by default",
+			content: "See [OpenShift 4.14 networking guide](" + oldURL + ").",
+			outcome: OutcomeLinkTextReview,
+			want:    "See [OpenShift 4.14 networking guide](" + oldURL + ").",
+		},
+		{
+			name:    "link text mentions version, updated with FixLinkText",
+			content: "See [OpenShift 4.14 networking guide](" + oldURL + ").",
+			opts:    Options{FixLinkText: true},
+			outcome: OutcomeLinkTextUpdated,
+			want:    "See [OpenShift 4.20 networking guide](" + newURL + ").",
+		},
+		{
+			name:    "link text without version",
+			content: "See [the networking guide](" + oldURL + ").",
+			outcome: OutcomeFixed,
+			want:    "See [the networking guide](" + newURL + ").",
+		},
+		{
+			name:    "link text with a z-stream release only",
+			content: "See [fixed in 4.14.3](" + oldURL + ").",
+			outcome: OutcomeFixed,
+			want:    "See [fixed in 4.14.3](" + newURL + ").",
+		},
+		{
+			name:    "bare URL",
+			content: "OpenShift 4.14: " + oldURL,
+			outcome: OutcomeFixed,
+			want:    "OpenShift 4.14: " + newURL,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change, fixed := fix(t, tt.content, tt.opts)
+			if change.Outcome != tt.outcome {
+				t.Errorf("Outcome = %s, want %s", change.Outcome, tt.outcome)
+			}
+			if fixed != tt.want {
+				t.Errorf("fixed content = %q, want %q", fixed, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlan_StaleOccurrence(t *testing.T) {
+	content := "See " + oldURL
+	occ := scanner.New().ScanContent("doc.md", []byte(content))[0]
+
+	if _, err := Plan("Changed. "+content, occ, replacement, Options{}); err == nil {
+		t.Error("Plan() on changed content succeeded, want error")
+	}
+}
+
+func TestPlan_EncodedOccurrence(t *testing.T) {
+	occ := scanner.Occurrence{URL: oldURL, Path: "csv.yaml", Line: 6, KeyPath: "metadata.annotations.alm-examples", Encoding: scanner.EncodingBase64}
+
+	change, err := Plan("alm-examples: W3sKICAiYXBpVmVyc2lvbiI6...", occ, replacement, Options{FixLinkText: true})
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	if change.Outcome != OutcomeEncoded || len(change.Edits) != 0 {
+		t.Errorf("Plan() = %s with %d edits, want %s with none", change.Outcome, len(change.Edits), OutcomeEncoded)
+	}
+}
+
+func TestApply(t *testing.T) {
+	got, err := Apply("aaa bbb ccc", []Edit{
+		{Start: 8, End: 11, Text: "CCC"},
+		{Start: 0, End: 3, Text: "A"},
+	})
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if got != "A bbb CCC" {
+		t.Errorf("Apply() = %q, want %q", got, "A bbb CCC")
+	}
+
+	if _, err := Apply("aaa bbb", []Edit{{Start: 0, End: 5}, {Start: 4, End: 7}}); err == nil {
+		t.Error("Apply() with overlapping edits succeeded, want error")
+	}
+}
+
+func TestMentionsVersion(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"OpenShift 4.14 networking", true},
+		{"4.14", true},
+		{"see the 4.14.", true},
+		{"(4.14)", true},
+		{"4.14.3 release notes", false},
+		{"14.14 or 4.140", false},
+		{"v4.1", false},
+		{"networking guide", false},
+	}
+
+	for _, tt := range tests {
+		if got := MentionsVersion(tt.text, "4.14"); got != tt.want {
+			t.Errorf("MentionsVersion(%q, 4.14) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestReplaceVersion(t *testing.T) {
+	got := ReplaceVersion("4.14 vs 4.14.3 (4.14)", "4.14", "4.20")
+	if want := "4.20 vs 4.14.3 (4.20)"; got != want {
+		t.Errorf("ReplaceVersion() = %q, want %q", got, want)
+	}
+}
+
+func TestPlan_HistoricalOccurrence(t *testing.T) {
+	content := "- Linked the [networking guide](" + oldURL + ")\n"
+	occ := scanner.New().ScanContent("CHANGELOG.md", []byte(content))[0]
+	occ.Historical = true
+
+	change, err := Plan(content, occ, replacement, Options{})
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	if change.Outcome != OutcomeHistorical || len(change.Edits) != 0 {
+		t.Errorf("Plan() = %s with %d edits, want %s with none", change.Outcome, len(change.Edits), OutcomeHistorical)
+	}
+
+	// Historical references are left as written on purpose
+	plan := &FilePlan{Changes: []Change{change}}
+	if plan.Unfixed() != 0 {
+		t.Errorf("Unfixed() = %d, want 0", plan.Unfixed())
+	}
+}
+
+func TestPlan_GeneratedOccurrence(t *testing.T) {
+	content := "<!-- Code generated by crd-ref-docs. DO NOT EDIT. -->\nSee " + oldURL + ".\n"
+	occ := scanner.New().ScanContent("crds.md", []byte(content))[0]
+	occ.Generated = scanner.GeneratedHeader
+
+	change, err := Plan(content, occ, replacement, Options{})
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	if change.Outcome != OutcomeGenerated || len(change.Edits) != 0 {
+		t.Errorf("Plan() = %s with %d edits, want %s with none", change.Outcome, len(change.Edits), OutcomeGenerated)
+	}
+
+	// The generator owns the file, so it is not left for manual fixing
+	plan := &FilePlan{Changes: []Change{change}}
+	if plan.Unfixed() != 0 {
+		t.Errorf("Unfixed() = %d, want 0", plan.Unfixed())
+	}
+}
+
+func TestPlan_CrossDocument(t *testing.T) {
+	content := "See " + oldURL + ".\n"
+	occ := scanner.New().ScanContent("doc.md", []byte(content))[0]
+	moved := replacement
+	moved.ServedDocument = "ingress_and_load_balancing"
+
+	tests := []struct {
+		name        string
+		r           Replacement
+		opts        Options
+		wantOutcome Outcome
+		wantEdits   int
+		wantUnfixed int
+	}{
+		{"same guide", replacement, Options{}, OutcomeFixed, 1, 0},
+		{"another guide", moved, Options{}, OutcomeCrossDocument, 0, 1},
+		{"another guide, allowed", moved, Options{AllowCrossDocument: true}, OutcomeFixed, 1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change, err := Plan(content, occ, tt.r, tt.opts)
+			if err != nil {
+				t.Fatalf("Plan() error = %v", err)
+			}
+			if change.Outcome != tt.wantOutcome || len(change.Edits) != tt.wantEdits {
+				t.Errorf("Plan() = %s with %d edits, want %s with %d", change.Outcome, len(change.Edits), tt.wantOutcome, tt.wantEdits)
+			}
+
+			plan := &FilePlan{Changes: []Change{change}}
+			if plan.Unfixed() != tt.wantUnfixed {
+				t.Errorf("Unfixed() = %d, want %d", plan.Unfixed(), tt.wantUnfixed)
+			}
+			// Cross-document changes are reported whether or not they are fixed
+			if got, want := len(plan.CrossDocument()), map[bool]int{false: 0, true: 1}[tt.r.CrossDocument()]; got != want {
+				t.Errorf("CrossDocument() = %d changes, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestPlan_MovedAnchor(t *testing.T) {
+	content := "See " + oldURL + ".\n"
+	occ := scanner.New().ScanContent("doc.md", []byte(content))[0]
+	moved := replacement
+	moved.MovedFrom = "index"
+	moved.NewURL = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.20/html/networking/ingress-sharding#nw-ingress-sharding_ingress-sharding"
+
+	tests := []struct {
+		name        string
+		opts        Options
+		wantOutcome Outcome
+		wantEdits   int
+	}{
+		{"left for review", Options{}, OutcomeMovedAnchor, 0},
+		{"allowed", Options{AllowMovedAnchor: true}, OutcomeFixed, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change, err := Plan(content, occ, moved, tt.opts)
+			if err != nil {
+				t.Fatalf("Plan() error = %v", err)
+			}
+			if change.Outcome != tt.wantOutcome || len(change.Edits) != tt.wantEdits {
+				t.Errorf("Plan() = %s with %d edits, want %s with %d", change.Outcome, len(change.Edits), tt.wantOutcome, tt.wantEdits)
+			}
+			if plan := (&FilePlan{Changes: []Change{change}}); plan.Unfixed() != 1-tt.wantEdits {
+				t.Errorf("Unfixed() = %d, want %d", plan.Unfixed(), 1-tt.wantEdits)
+			}
+		})
+	}
+}
+
+func TestPlan_DroppedAnchor(t *testing.T) {
+	content := "See " + oldURL + "#nw-ingress-sharding.\n"
+	occ := scanner.New().ScanContent("doc.md", []byte(content))[0]
+	dropped := replacement
+	dropped.OldURL = oldURL + "#nw-ingress-sharding"
+	dropped.DroppedAnchor = "nw-ingress-sharding"
+
+	tests := []struct {
+		name        string
+		opts        Options
+		wantOutcome Outcome
+		wantContent string
+	}{
+		{"left for review", Options{}, OutcomeDroppedAnchor, content},
+		{"allowed", Options{AllowDroppedAnchor: true}, OutcomeFixed, "See " + newURL + ".\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change, err := Plan(content, occ, dropped, tt.opts)
+			if err != nil {
+				t.Fatalf("Plan() error = %v", err)
+			}
+			got, err := Apply(content, change.Edits)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if change.Outcome != tt.wantOutcome || got != tt.wantContent {
+				t.Errorf("Plan() = %s, content %q; want %s, %q", change.Outcome, got, tt.wantOutcome, tt.wantContent)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package fixer
+
+import (
+	"strings"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/checker"
+	"github.com/sebrandon1/ocp-doc-checker/pkg/scanner"
+)
+
+// ReplacementFor returns the replacement of an outdated URL by its best
+// suggestion, or false if the result is not outdated. The new URL keeps
+// the query of the outdated one. A suggestion whose page lacks the anchor,
+// under the page-only anchor policy, is replaced without its fragment.
+func ReplacementFor(result *checker.CheckResult) (Replacement, bool) {
+	latest, ok := result.BestSuggestion()
+	if !result.IsOutdated || !ok {
+		return Replacement{}, false
+	}
+
+	r := Replacement{
+		OldURL:      result.OriginalURL,
+		NewURL:      latest.URL,
+		OldVersion:  result.OriginalVersion,
+		NewVersion:  latest.Version,
+		RenamedFrom: latest.RenamedFrom,
+		MovedFrom:   latest.MovedFrom,
+	}
+	if latest.AnchorMissing() {
+		r.NewURL, r.DroppedAnchor, _ = strings.Cut(latest.URL, "#")
+	}
+	r.NewURL = withQuery(r.NewURL, scanner.Query(result.OriginalURL))
+	if latest.CrossDocument() {
+		r.ServedDocument = latest.ServedDocument
+		r.OldTitle = result.DocumentTitle
+		r.NewTitle = latest.ServedTitle
+	}
+	return r, true
+}
+
+// NormalizationFor returns the replacement of one spelling of an
+// up-to-date URL by its normalized spelling, or false if the spelling is
+// already normal. A URL with a non-canonical version or a duplicated
+// fragment moves to its suggested URL; a spelling without a locale or with
+// tracking parameters moves to the canonical URL, which has the default
+// locale and only the query parameters the scan grouped it under.
+func NormalizationFor(result *checker.CheckResult, spelling string) (Replacement, bool) {
+	newURL := result.SuggestedURL
+	if newURL == "" {
+		if !scanner.MissingLocale(spelling) && scanner.Query(spelling) == scanner.Query(result.OriginalURL) {
+			return Replacement{}, false
+		}
+		newURL = result.OriginalURL
+	}
+	return Replacement{
+		OldURL:     spelling,
+		NewURL:     newURL,
+		OldVersion: result.OriginalVersion,
+		NewVersion: result.OriginalVersion,
+	}, true
+}
+
+// UnifyFormat returns replacements moving every spelling of a section that
+// is not in the preferred format onto the preferred spelling, as -fix would
+// write it. Nothing is returned when no spelling uses the preferred format:
+// the html page holding a section cannot be derived from html-single.
+func UnifyFormat(group []checker.Spelling, prefer string) []Replacement {
+	var preferred *checker.CheckResult
+	for _, s := range group {
+		if s.Format == prefer {
+			preferred = s.Result
+			break
+		}
+	}
+	if preferred == nil {
+		return nil
+	}
+
+	target, ok := ReplacementFor(preferred)
+	if !ok {
+		target = Replacement{NewURL: preferred.OriginalURL, NewVersion: preferred.OriginalVersion}
+	}
+
+	var replacements []Replacement
+	for _, s := range group {
+		if s.Format == prefer {
+			continue
+		}
+		r := target
+		r.OldURL = s.Result.OriginalURL
+		r.OldVersion = s.Result.OriginalVersion
+		replacements = append(replacements, r)
+	}
+	return replacements
+}
+
+// Targets collects the occurrences to fix, by file in order of first
+// appearance: every outdated URL, with normalize set the spellings of
+// other URLs that are not normal, and with preferFormat set every spelling
+// of a section in another format than the preferred one
+func Targets(results []*checker.CheckResult, locations map[string]scanner.Location, preferFormat string, normalize bool) ([]string, map[string][]Target) {
+	var urls []string
+	replacements := make(map[string]Replacement)
+	// normalizing are the up-to-date URLs whose spellings are normalized
+	// one by one
+	normalizing := make(map[string]*checker.CheckResult)
+	add := func(r Replacement) {
+		if _, ok := replacements[r.OldURL]; !ok && normalizing[r.OldURL] == nil {
+			urls = append(urls, r.OldURL)
+		}
+		replacements[r.OldURL] = r
+	}
+
+	for _, result := range results {
+		if r, ok := ReplacementFor(result); ok {
+			add(r)
+		} else if normalize {
+			urls = append(urls, result.OriginalURL)
+			normalizing[result.OriginalURL] = result
+		}
+	}
+	if preferFormat != "" {
+		for _, group := range checker.GroupSpellings(results) {
+			for _, r := range UnifyFormat(group, preferFormat) {
+				add(r)
+			}
+		}
+	}
+
+	var files []string
+	targets := make(map[string][]Target)
+	for _, oldURL := range urls {
+		for _, occ := range locations[oldURL].Occurrences {
+			r, ok := replacements[oldURL]
+			if !ok {
+				if r, ok = NormalizationFor(normalizing[oldURL], occ.URL); !ok {
+					continue
+				}
+			}
+			if _, ok := targets[occ.Path]; !ok {
+				files = append(files, occ.Path)
+			}
+			// Occurrences of other spellings of the URL replace their own
+			// text and keep their own query, which normalizing reduces to
+			// the query the scan grouped them under
+			r.OldURL = occ.URL
+			query := scanner.Query(occ.URL)
+			if normalize {
+				query = scanner.Query(oldURL)
+			}
+			r.NewURL = withQuery(r.NewURL, query)
+			targets[occ.Path] = append(targets[occ.Path], Target{Occurrence: occ, Replacement: r})
+		}
+	}
+
+	return files, targets
+}
+
+// withQuery returns url with its query replaced by query, or without one
+// when query is empty, keeping the fragment
+func withQuery(url, query string) string {
+	rest, fragment, hasFragment := strings.Cut(url, "#")
+	base, _, _ := strings.Cut(rest, "?")
+	if query != "" {
+		base += "?" + query
+	}
+	if hasFragment {
+		base += "#" + fragment
+	}
+	return base
+}
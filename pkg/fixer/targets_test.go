@@ -0,0 +1,471 @@
+package fixer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/checker"
+	"github.com/sebrandon1/ocp-doc-checker/pkg/scanner"
+)
+
+const docsBase = "https://docs.redhat.com/en/documentation/openshift_container_platform/"
+
+// checked builds a result for url that is outdated when latest is set
+func checked(version, path, latest string) *checker.CheckResult {
+	result := &checker.CheckResult{
+		OriginalURL:     docsBase + version + path,
+		OriginalVersion: version,
+		LatestVersion:   version,
+	}
+	if latest != "" {
+		result.IsOutdated = true
+		result.LatestVersion = latest
+		// Like the checker, the newer URL is built without the query
+		result.NewerVersions = []checker.VersionCheckResult{{Version: latest, URL: withQuery(docsBase+latest+path, ""), Exists: true}}
+	}
+	return result
+}
+
+// mixedFormatResults are the check results for testdata/mixed-formats.md
+func mixedFormatResults() []*checker.CheckResult {
+	return []*checker.CheckResult{
+		checked("4.16", "/html-single/networking/index#configuring-ingress", "4.17"),
+		checked("4.16", "/html/networking/configuring-ingress-cluster-traffic#configuring-ingress", "4.17"),
+		checked("4.17", "/html/operators/index", ""),
+		checked("4.17", "/html-single/operators/index", ""),
+		checked("4.16", "/html/storage/index", "4.17"),
+	}
+}
+
+func TestTargets_PreferFormat(t *testing.T) {
+	tests := []struct {
+		prefer string
+		golden string
+	}{
+		{"html-single", "mixed-formats.html-single.golden"},
+		{"html", "mixed-formats.html.golden"},
+		{"", "mixed-formats.golden"},
+	}
+
+	for _, tt := range tests {
+		t.Run("prefer "+tt.prefer, func(t *testing.T) {
+			input, err := os.ReadFile(filepath.Join("testdata", "mixed-formats.md"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(t.TempDir(), "mixed-formats.md")
+			if err := os.WriteFile(path, input, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			locations, err := scanner.New().Scan(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			byURL := make(map[string]scanner.Location)
+			for _, loc := range locations {
+				byURL[loc.URL] = loc
+			}
+
+			files, targets := Targets(mixedFormatResults(), byURL, tt.prefer, false)
+			if len(files) != 1 || files[0] != path {
+				t.Fatalf("Targets() files = %v, want [%s]", files, path)
+			}
+			if _, err := FixFile(path, targets[path], Options{}, true); err != nil {
+				t.Fatalf("FixFile() error = %v", err)
+			}
+
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := os.ReadFile(filepath.Join("testdata", tt.golden))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("fixed content =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+// Every spelling of an outdated URL is fixed in place, in its own syntax
+func TestTargets_Spellings(t *testing.T) {
+	const page = "/html/networking/index"
+	upper := "https://DOCS.REDHAT.COM/en/documentation/openshift_container_platform/4.16" + page + "#ingress"
+	files := map[string][2]string{
+		"guide.md": {
+			"See [ingress](" + docsBase + "4.16" + page + "#ingress) and <" + upper + ">.\n",
+			"See [ingress](" + docsBase + "4.17" + page + "#ingress) and <" + docsBase + "4.17" + page + "#ingress>.\n",
+		},
+		"notes.adoc": {
+			"link:" + docsBase + "4.16" + page + "/#ingress[Ingress]\n" +
+				"Other section: " + docsBase + "4.16" + page + "#Ingress\n",
+			"link:" + docsBase + "4.17" + page + "#ingress[Ingress]\n" +
+				"Other section: " + docsBase + "4.16" + page + "#Ingress\n",
+		},
+	}
+
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content[0]), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	locations, err := scanner.New().Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byURL := make(map[string]scanner.Location)
+	for _, loc := range locations {
+		byURL[loc.URL] = loc
+	}
+
+	// Only the canonical spelling is checked; #Ingress is another section
+	results := []*checker.CheckResult{
+		checked("4.16", page+"#ingress", "4.17"),
+		checked("4.16", page+"#Ingress", ""),
+	}
+	paths, targets := Targets(results, byURL, "", false)
+	for _, path := range paths {
+		if _, err := FixFile(path, targets[path], Options{}, true); err != nil {
+			t.Fatalf("FixFile() error = %v", err)
+		}
+	}
+
+	for name, content := range files {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != content[1] {
+			t.Errorf("%s =\n%s\nwant\n%s", name, got, content[1])
+		}
+	}
+}
+
+func TestTargets_Normalize(t *testing.T) {
+	const page = "/html/networking/index#ingress"
+	content := "Current: " + docsBase + "4.17.1" + page + "\n" +
+		"Outdated: " + docsBase + "04.16" + page + "\n"
+
+	current := checked("4.17", page, "")
+	current.OriginalURL = docsBase + "4.17.1" + page
+	current.SuggestedURL = docsBase + "4.17" + page
+	outdated := checked("4.16", page, "4.17")
+	outdated.OriginalURL = docsBase + "04.16" + page
+	outdated.SuggestedURL = docsBase + "4.16" + page
+
+	tests := []struct {
+		normalize bool
+		want      string
+	}{
+		{false, "Current: " + docsBase + "4.17.1" + page + "\n" + "Outdated: " + docsBase + "4.17" + page + "\n"},
+		{true, "Current: " + docsBase + "4.17" + page + "\n" + "Outdated: " + docsBase + "4.17" + page + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("normalize %v", tt.normalize), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "guide.md")
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			locations, err := scanner.New().Scan(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			byURL := make(map[string]scanner.Location)
+			for _, loc := range locations {
+				byURL[loc.URL] = loc
+			}
+
+			paths, targets := Targets([]*checker.CheckResult{current, outdated}, byURL, "", tt.normalize)
+			for _, path := range paths {
+				if _, err := FixFile(path, targets[path], Options{}, true); err != nil {
+					t.Fatalf("FixFile() error = %v", err)
+				}
+			}
+
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("fixed file =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTargets_MixedLocale(t *testing.T) {
+	const page = "/html/networking/index#ingress"
+	legacy := strings.Replace(docsBase, "/en/", "/", 1)
+	files := map[string]string{
+		"guide.md": "See " + docsBase + "4.16" + page + ".\n",
+		"notes.md": "See " + legacy + "4.16" + page + ".\n",
+	}
+
+	tests := []struct {
+		name      string
+		latest    string
+		normalize bool
+		want      map[string]string
+	}{
+		{"outdated", "4.17", false, map[string]string{
+			"guide.md": "See " + docsBase + "4.17" + page + ".\n",
+			"notes.md": "See " + docsBase + "4.17" + page + ".\n",
+		}},
+		{"up to date", "", false, files},
+		{"up to date with normalize", "", true, map[string]string{
+			"guide.md": "See " + docsBase + "4.16" + page + ".\n",
+			"notes.md": "See " + docsBase + "4.16" + page + ".\n",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// Both spellings are one logical link, checked once
+			locations, err := scanner.New().Scan(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(locations) != 1 || locations[0].URL != docsBase+"4.16"+page || len(locations[0].Files) != 2 {
+				t.Fatalf("Scan() = %+v, want one location in both files", locations)
+			}
+			byURL := map[string]scanner.Location{locations[0].URL: locations[0]}
+
+			paths, targets := Targets([]*checker.CheckResult{checked("4.16", page, tt.latest)}, byURL, "", tt.normalize)
+			for _, path := range paths {
+				if _, err := FixFile(path, targets[path], Options{}, true); err != nil {
+					t.Fatalf("FixFile() error = %v", err)
+				}
+			}
+
+			for name, want := range tt.want {
+				got, err := os.ReadFile(filepath.Join(dir, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(got) != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTargets_TrackingParams(t *testing.T) {
+	const page = "/html/networking/index"
+	spelling := func(version string) map[string]string {
+		return map[string]string{
+			"guide.md":  "See " + docsBase + version + page + "?lang=ja#ingress.\n",
+			"blog.md":   "See " + docsBase + version + page + "?sc_cid=701f2000001OH7JAAW&lang=ja#ingress.\n",
+			"social.md": "See " + docsBase + version + page + "?lang=ja&utm_source=x&utm_medium=y#ingress.\n",
+		}
+	}
+	files := spelling("4.16")
+	upgraded := spelling("4.17")
+
+	tests := []struct {
+		name      string
+		latest    string
+		normalize bool
+		want      map[string]string
+	}{
+		{"up to date", "", false, files},
+		{"up to date with normalize", "", true, map[string]string{
+			"guide.md":  files["guide.md"],
+			"blog.md":   files["guide.md"],
+			"social.md": files["guide.md"],
+		}},
+		// Every spelling keeps its own query
+		{"outdated", "4.17", false, upgraded},
+		{"outdated with normalize", "4.17", true, map[string]string{
+			"guide.md":  upgraded["guide.md"],
+			"blog.md":   upgraded["guide.md"],
+			"social.md": upgraded["guide.md"],
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// The spellings are one link, grouped without the tracking
+			// parameters but with the meaningful one
+			locations, err := scanner.New().Scan(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(locations) != 1 || locations[0].URL != docsBase+"4.16"+page+"?lang=ja#ingress" || len(locations[0].Files) != 3 {
+				t.Fatalf("Scan() = %+v, want one location in every file", locations)
+			}
+			byURL := map[string]scanner.Location{locations[0].URL: locations[0]}
+
+			result := checked("4.16", page+"?lang=ja#ingress", tt.latest)
+			paths, targets := Targets([]*checker.CheckResult{result}, byURL, "", tt.normalize)
+			for _, path := range paths {
+				if _, err := FixFile(path, targets[path], Options{}, true); err != nil {
+					t.Fatalf("FixFile() error = %v", err)
+				}
+			}
+
+			for name, want := range tt.want {
+				got, err := os.ReadFile(filepath.Join(dir, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(got) != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReplacementFor_Query(t *testing.T) {
+	result := checked("4.12", "/html/networking/ingress?lang=ja#configuring-ingress", "4.15")
+	if result.NewerVersions[0].URL != docsBase+"4.15/html/networking/ingress#configuring-ingress" {
+		t.Fatalf("newer URL = %s, want it without the query", result.NewerVersions[0].URL)
+	}
+
+	r, ok := ReplacementFor(result)
+	if want := docsBase + "4.15/html/networking/ingress?lang=ja#configuring-ingress"; !ok || r.NewURL != want {
+		t.Errorf("ReplacementFor() = %+v, %v; want NewURL %s", r, ok, want)
+	}
+}
+
+func TestUnifyFormat_NoPreferredSpelling(t *testing.T) {
+	results := mixedFormatResults()[:2]
+	groups := checker.GroupSpellings(results)
+	if len(groups) != 1 {
+		t.Fatalf("GroupSpellings() returned %d groups, want 1", len(groups))
+	}
+
+	if got := UnifyFormat(groups[0], "pdf"); got != nil {
+		t.Errorf("UnifyFormat() without a preferred spelling = %+v, want nil", got)
+	}
+}
+
+func TestReplacementFor_CrossDocument(t *testing.T) {
+	result := checked("4.17", "/html/networking/ingress#configuring-ingress", "4.19")
+	result.DocumentTitle = "Networking"
+	result.NewerVersions[0].ServedDocument = "ingress_and_load_balancing"
+	result.NewerVersions[0].ServedTitle = "Ingress and load balancing"
+
+	r, ok := ReplacementFor(result)
+	if !ok {
+		t.Fatal("ReplacementFor() found no replacement")
+	}
+	want := Replacement{
+		OldURL:         result.OriginalURL,
+		NewURL:         result.NewerVersions[0].URL,
+		OldVersion:     "4.17",
+		NewVersion:     "4.19",
+		ServedDocument: "ingress_and_load_balancing",
+		OldTitle:       "Networking",
+		NewTitle:       "Ingress and load balancing",
+	}
+	if r != want {
+		t.Errorf("ReplacementFor() = %+v, want %+v", r, want)
+	}
+
+	// A guide that stays the same carries no titles
+	same := checked("4.17", "/html/networking/ingress", "4.19")
+	same.DocumentTitle = "Networking"
+	if r, _ := ReplacementFor(same); r.CrossDocument() || r.OldTitle != "" {
+		t.Errorf("ReplacementFor() = %+v, want a same-guide replacement", r)
+	}
+}
+
+func TestReplacementFor_MovedAnchor(t *testing.T) {
+	result := checked("4.17", "/html/networking/ingress-operator#nw-ingress-sharding_ingress-operator", "4.18")
+	result.NewerVersions[0].URL = docsBase + "4.18/html/networking/ingress-sharding#nw-ingress-sharding_ingress-sharding"
+	result.NewerVersions[0].MovedFrom = "ingress-operator"
+
+	r, ok := ReplacementFor(result)
+	if !ok || r.NewURL != result.NewerVersions[0].URL || r.MovedFrom != "ingress-operator" {
+		t.Errorf("ReplacementFor() = %+v, %v; want the ingress-sharding page, moved from ingress-operator", r, ok)
+	}
+}
+
+func TestReplacementFor_DroppedAnchor(t *testing.T) {
+	result := checked("4.17", "/html/networking/ingress#nw-ingress-sharding", "4.18")
+	result.NewerVersions[0].HasAnchor = true
+
+	// The page keeps the anchor under either policy
+	result.NewerVersions[0].AnchorExists = true
+	if r, ok := ReplacementFor(result); !ok || r.NewURL != result.NewerVersions[0].URL || r.DroppedAnchor != "" {
+		t.Errorf("ReplacementFor() = %+v, %v; want the 4.18 URL with its anchor", r, ok)
+	}
+
+	// Counted under the page-only policy, the page lacks it
+	result.NewerVersions[0].AnchorExists = false
+	r, ok := ReplacementFor(result)
+	if !ok || r.NewURL != docsBase+"4.18/html/networking/ingress" || r.DroppedAnchor != "nw-ingress-sharding" {
+		t.Errorf("ReplacementFor() = %+v, %v; want the 4.18 page without #nw-ingress-sharding", r, ok)
+	}
+}
+
+// Occurrences of a custom extractor are grouped, targeted and fixed like
+// those of the built-in search, through the byte span they record
+func TestTargets_CustomExtractor(t *testing.T) {
+	const page = "/html/networking/index#ingress"
+	export := "INSERT INTO links VALUES\n" +
+		"  (1, '" + docsBase + "4.16" + page + "'),\n" +
+		"  (2, '" + docsBase + "4.17/html/operators/index'),\n" +
+		"  (3, '" + docsBase + "4.16" + page + "');\n"
+	want := strings.ReplaceAll(export, "4.16", "4.17")
+
+	s := scanner.New()
+	s.SetExtractor(".sql", scanner.ExtractorFunc(func(content []byte, path string) []scanner.Occurrence {
+		var occurrences []scanner.Occurrence
+		for _, m := range regexp.MustCompile(`'(https://[^']*)'`).FindAllSubmatchIndex(content, -1) {
+			occurrences = append(occurrences, scanner.Occurrence{URL: string(content[m[2]:m[3]]), Start: m[2], End: m[3]})
+		}
+		return occurrences
+	}))
+
+	path := filepath.Join(t.TempDir(), "links.sql")
+	if err := os.WriteFile(path, []byte(export), 0644); err != nil {
+		t.Fatal(err)
+	}
+	locations, err := s.Scan(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byURL := make(map[string]scanner.Location)
+	for _, loc := range locations {
+		byURL[loc.URL] = loc
+	}
+
+	results := []*checker.CheckResult{checked("4.16", page, "4.17"), checked("4.17", "/html/operators/index", "")}
+	_, targets := Targets(results, byURL, "", false)
+	if len(targets[path]) != 2 {
+		t.Fatalf("Targets() = %+v, want both occurrences of the outdated URL", targets[path])
+	}
+	if _, err := FixFile(path, targets[path], Options{}, true); err != nil {
+		t.Fatalf("FixFile() error = %v", err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != want {
+		t.Errorf("fixed content =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,161 @@
+package fixer
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/scanner"
+)
+
+// ErrUnexpectedModification is returned when the fixed content of a file
+// differs from its original outside the planned edits
+var ErrUnexpectedModification = errors.New("fix aborted: unexpected modification")
+
+// ErrFixNotWritten is returned by VerifyWritten when a fixed file does not
+// hold the fixes of its plan
+var ErrFixNotWritten = errors.New("post-fix verification failed")
+
+// apply applies edits to the content of a file; tests replace it to
+// simulate a buggy replacement
+var apply = Apply
+
+// plannedEdit is an edit with the text it is expected to replace
+type plannedEdit struct {
+	Edit
+	Old string
+}
+
+// plannedEdits returns the edits of the plan with their expected old text,
+// ordered by offset
+func (p *FilePlan) plannedEdits() []plannedEdit {
+	var edits []plannedEdit
+	for _, change := range p.Changes {
+		for _, e := range change.Edits {
+			old := change.Replacement.OldURL
+			if e.Start != change.Occurrence.Start {
+				old = change.Occurrence.LinkText
+			}
+			edits = append(edits, plannedEdit{Edit: e, Old: old})
+		}
+	}
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].Start < edits[j].Start
+	})
+	return edits
+}
+
+// Verify checks that fixed is the content the plan was made for with only
+// the planned edits applied: every changed region must be a planned edit
+// replacing the expected old text with the expected new text, and every
+// other byte must be unchanged. It is independent of Apply, so it catches
+// replacement bugs before they reach the file.
+func (p *FilePlan) Verify(fixed string) error {
+	original := p.text
+	o, f := 0, 0
+	for _, e := range p.plannedEdits() {
+		if e.Start < o || e.End > len(original) {
+			return p.unexpected(fixed, e.Start, f)
+		}
+		if n := commonPrefix(original[o:e.Start], fixed[f:]); n < e.Start-o {
+			return p.unexpected(fixed, o+n, f+n)
+		}
+		f += e.Start - o
+		if original[e.Start:e.End] != e.Old || !strings.HasPrefix(fixed[f:], e.Text) {
+			return p.unexpected(fixed, e.Start, f)
+		}
+		o, f = e.End, f+len(e.Text)
+	}
+	if original[o:] != fixed[f:] {
+		n := commonPrefix(original[o:], fixed[f:])
+		return p.unexpected(fixed, o+n, f+n)
+	}
+	return nil
+}
+
+// unexpected reports a modification found at offset o of the original and
+// offset f of the fixed content
+func (p *FilePlan) unexpected(fixed string, o, f int) error {
+	return fmt.Errorf("%w at line %d: %q became %q", ErrUnexpectedModification,
+		lineAt(p.text, o), lineText(p.text, o), lineText(fixed, f))
+}
+
+// lineText returns the line of s holding offset i, without its newline
+func lineText(s string, i int) string {
+	if i > len(s) {
+		i = len(s)
+	}
+	start := strings.LastIndexByte(s[:i], '\n') + 1
+	end := strings.IndexByte(s[i:], '\n')
+	if end < 0 {
+		return s[start:]
+	}
+	return s[start : i+end]
+}
+
+// commonPrefix returns the length of the common prefix of a and b
+func commonPrefix(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+// VerifyWritten reads the file of an applied plan back and checks that its
+// fixes landed: every new URL is present and every old URL replaced is
+// gone, as found by s. It catches what Verify cannot see, such as an editor
+// hook or another process rewriting the file after the fix. A file only
+// reformatted since passes as long as its URLs are those the plan wrote.
+// Every discrepancy is returned, wrapping ErrFixNotWritten with the file
+// and URL.
+func (p *FilePlan) VerifyWritten(s *scanner.Scanner) []error {
+	if !p.Modifies() {
+		return nil
+	}
+	content, _, err := scanner.ReadFile(p.Path)
+	if err != nil {
+		return []error{fmt.Errorf("%w: %s: %w", ErrFixNotWritten, p.Path, err)}
+	}
+	want, err := Apply(p.text, p.Edits())
+	if err != nil {
+		return []error{fmt.Errorf("%w: %s: %w", ErrFixNotWritten, p.Path, err)}
+	}
+	if string(content) == want {
+		return nil
+	}
+
+	expected := countURLs(s.ScanContent(p.Path, []byte(want)))
+	got := countURLs(s.ScanContent(p.Path, content))
+	var errs []error
+	reported := make(map[string]bool)
+	report := func(problem, url string) {
+		if !reported[url] {
+			reported[url] = true
+			errs = append(errs, fmt.Errorf("%w: %s: %s: %s", ErrFixNotWritten, p.Path, problem, url))
+		}
+	}
+	for _, change := range p.Changes {
+		if len(change.Edits) == 0 {
+			continue
+		}
+		r := change.Replacement
+		if got[r.NewURL] < expected[r.NewURL] {
+			report("new URL missing", r.NewURL)
+		}
+		if got[r.OldURL] > expected[r.OldURL] {
+			report("old URL still present", r.OldURL)
+		}
+	}
+	return errs
+}
+
+// countURLs counts the occurrences of each URL
+func countURLs(occurrences []scanner.Occurrence) map[string]int {
+	counts := make(map[string]int)
+	for _, occ := range occurrences {
+		counts[occ.URL]++
+	}
+	return counts
+}
@@ -0,0 +1,288 @@
+package gitdiff
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/scanner"
+)
+
+// File is a file changed by a diff
+type File struct {
+	// Path is the path of the file after the change, relative to the root
+	// of the repository
+	Path string
+	// OldPath is the path of the file before the change, when it was
+	// renamed or copied
+	OldPath string
+	// Added are the numbers of the lines the change added, in order,
+	// counted in the file after the change
+	Added []int
+}
+
+// Diff is the set of files changed since a git revision
+type Diff struct {
+	// Root is the absolute path of the repository the paths are relative to
+	Root  string
+	Files []File
+
+	byPath map[string]*File
+}
+
+// New returns the diff of files relative to the repository at root
+func New(root string, files []File) *Diff {
+	d := &Diff{Root: root, Files: files, byPath: make(map[string]*File)}
+	for i := range d.Files {
+		d.byPath[filepath.Join(root, filepath.FromSlash(d.Files[i].Path))] = &d.Files[i]
+	}
+	return d
+}
+
+// Run returns the changes of the working tree of the repository holding
+// path since ref, which is passed to git diff as is, e.g. origin/main or
+// origin/main...HEAD for the changes of a branch alone. A ref starting with
+// '-' is an error, since git would take it for an option. Renames are
+// detected, so a moved file only counts the lines it changed.
+func Run(ctx context.Context, path, ref string) (*Diff, error) {
+	if strings.HasPrefix(ref, "-") {
+		return nil, fmt.Errorf("invalid git ref %q: must not start with '-'", ref)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	dir := path
+	if !info.IsDir() {
+		dir = filepath.Dir(path)
+	}
+
+	out, err := git(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	root := strings.TrimSpace(string(out))
+
+	out, err = git(ctx, root, "diff", "--no-color", "--no-ext-diff", "--unified=0", "--find-renames", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+	files, err := Parse(bytes.NewReader(out))
+	if err != nil {
+		return nil, err
+	}
+	return New(root, files), nil
+}
+
+// git runs git with args in dir and returns its output. Paths are only
+// quoted when they hold control characters.
+func git(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir, "-c", "core.quotePath=false"}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return out, nil
+}
+
+// Parse reads a unified diff, as written by git diff, and returns the files
+// it changes with the lines it adds to them. The added lines are counted
+// from the hunk headers, so any amount of context is handled. Deleted
+// files are left out; renamed files keep the lines their rename changed.
+func Parse(r io.Reader) ([]File, error) {
+	var files []File
+	var file *File
+	var deleted bool
+
+	// flush keeps the file parsed so far unless it was deleted
+	flush := func() {
+		if file != nil && !deleted && file.Path != "" {
+			files = append(files, *file)
+		}
+		file, deleted = nil, false
+	}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			flush()
+			file = &File{}
+			// The header only serves files without ---/+++ lines, such as
+			// mode changes; its paths are ambiguous when they hold spaces
+			if _, b, ok := strings.Cut(line[len("diff --git "):], " b/"); ok {
+				file.Path = b
+			}
+		case file == nil:
+			// Anything before the first file, e.g. a commit message
+		case strings.HasPrefix(line, "deleted file mode"):
+			deleted = true
+		case strings.HasPrefix(line, "rename from "), strings.HasPrefix(line, "copy from "):
+			_, old, _ := strings.Cut(line, " from ")
+			file.OldPath = unquote(old)
+		case strings.HasPrefix(line, "rename to "), strings.HasPrefix(line, "copy to "):
+			_, path, _ := strings.Cut(line, " to ")
+			file.Path = unquote(path)
+		case strings.HasPrefix(line, "+++ "):
+			if path := line[len("+++ "):]; path == "/dev/null" {
+				deleted = true
+			} else {
+				file.Path = strings.TrimPrefix(unquote(path), "b/")
+			}
+		case strings.HasPrefix(line, "@@ "):
+			oldCount, newStart, newCount, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			// Consume exactly the lines the header counts, so content that
+			// looks like a header is never taken for one
+			next := newStart
+			for oldCount > 0 || newCount > 0 {
+				if !sc.Scan() {
+					return nil, fmt.Errorf("line %d: hunk ends early", lineNo)
+				}
+				lineNo++
+				body := sc.Text()
+				if body == "" {
+					// Some tools strip the space of an empty context line
+					body = " "
+				}
+				switch body[0] {
+				case '+':
+					file.Added = append(file.Added, next)
+					next++
+					newCount--
+				case '-':
+					oldCount--
+				case ' ':
+					next++
+					oldCount--
+					newCount--
+				case '\\':
+					// \ No newline at end of file
+				default:
+					return nil, fmt.Errorf("line %d: unexpected line in hunk: %q", lineNo, body)
+				}
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+	return files, nil
+}
+
+// parseHunkHeader parses the line counts of a hunk header,
+// @@ -oldStart[,oldCount] +newStart[,newCount] @@
+func parseHunkHeader(line string) (oldCount, newStart, newCount int, err error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || fields[0] != "@@" || fields[3] != "@@" {
+		return 0, 0, 0, fmt.Errorf("malformed hunk header %q", line)
+	}
+	_, oldCount, err = parseRange(fields[1], "-")
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("malformed hunk header %q: %w", line, err)
+	}
+	newStart, newCount, err = parseRange(fields[2], "+")
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("malformed hunk header %q: %w", line, err)
+	}
+	return oldCount, newStart, newCount, nil
+}
+
+// parseRange parses start[,count] after prefix; count defaults to 1
+func parseRange(s, prefix string) (start, count int, err error) {
+	s, ok := strings.CutPrefix(s, prefix)
+	if !ok {
+		return 0, 0, errors.New("range without " + prefix)
+	}
+	startText, countText, hasCount := strings.Cut(s, ",")
+	if start, err = strconv.Atoi(startText); err != nil {
+		return 0, 0, err
+	}
+	count = 1
+	if hasCount {
+		if count, err = strconv.Atoi(countText); err != nil {
+			return 0, 0, err
+		}
+	}
+	return start, count, nil
+}
+
+// unquote returns a path as git writes it without its quotes, which it
+// adds around paths with unusual characters
+func unquote(path string) string {
+	if strings.HasPrefix(path, `"`) {
+		if unquoted, err := strconv.Unquote(path); err == nil {
+			return unquoted
+		}
+	}
+	return path
+}
+
+// file returns the changed file at path, nil if it was not changed
+func (d *Diff) file(path string) *File {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+	if f, ok := d.byPath[abs]; ok {
+		return f
+	}
+	// The root git reports has its symbolic links resolved
+	if real, err := filepath.EvalSymlinks(abs); err == nil {
+		return d.byPath[real]
+	}
+	return nil
+}
+
+// Changed reports whether the file at path was changed
+func (d *Diff) Changed(path string) bool {
+	return d.file(path) != nil
+}
+
+// Added reports whether line of the file at path was added by the change
+func (d *Diff) Added(path string, line int) bool {
+	f := d.file(path)
+	if f == nil {
+		return false
+	}
+	_, found := slices.BinarySearch(f.Added, line)
+	return found
+}
+
+// FilterChanged returns the locations with only their occurrences in
+// changed files, dropping locations left without any
+func (d *Diff) FilterChanged(locations []scanner.Location) []scanner.Location {
+	changed, _ := scanner.Partition(locations, func(occ scanner.Occurrence) bool {
+		return d.Changed(occ.Path)
+	})
+	return changed
+}
+
+// SplitAdded splits the occurrences of locations into those on lines the
+// change added and the pre-existing ones, each grouped into locations
+func (d *Diff) SplitAdded(locations []scanner.Location) (added, preexisting []scanner.Location) {
+	return scanner.Partition(locations, func(occ scanner.Occurrence) bool {
+		return d.Added(occ.Path, occ.Line)
+	})
+}
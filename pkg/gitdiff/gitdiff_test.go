@@ -0,0 +1,249 @@
+package gitdiff
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/scanner"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		diff    string
+		want    []File
+		wantErr string
+	}{
+		{
+			name: "added and changed lines",
+			diff: "diff --git a/docs/a.md b/docs/a.md\n" +
+				"index 1111111..2222222 100644\n" +
+				"--- a/docs/a.md\n" +
+				"+++ b/docs/a.md\n" +
+				"@@ -3 +3,2 @@ # Guide\n" +
+				"-old line\n" +
+				"+new line\n" +
+				"+another line\n" +
+				"@@ -10,0 +12 @@\n" +
+				"+appended\n",
+			want: []File{{Path: "docs/a.md", Added: []int{3, 4, 12}}},
+		},
+		{
+			name: "context lines",
+			diff: "diff --git a/a.md b/a.md\n" +
+				"--- a/a.md\n" +
+				"+++ b/a.md\n" +
+				"@@ -1,4 +1,5 @@\n" +
+				" one\n" +
+				"-two\n" +
+				"+2\n" +
+				"+2.5\n" +
+				" three\n" +
+				" four\n",
+			want: []File{{Path: "a.md", Added: []int{2, 3}}},
+		},
+		{
+			name: "content that looks like headers",
+			diff: "diff --git a/a.md b/a.md\n" +
+				"--- a/a.md\n" +
+				"+++ b/a.md\n" +
+				"@@ -1,2 +1,2 @@\n" +
+				"--- a/old\n" +
+				"-@@ -1 +1 @@\n" +
+				"+++ b/new\n" +
+				"+diff --git a/x b/x\n",
+			want: []File{{Path: "a.md", Added: []int{1, 2}}},
+		},
+		{
+			name: "new file without a trailing newline",
+			diff: "diff --git a/new.md b/new.md\n" +
+				"new file mode 100644\n" +
+				"--- /dev/null\n" +
+				"+++ b/new.md\n" +
+				"@@ -0,0 +1,2 @@\n" +
+				"+first\n" +
+				"+second\n" +
+				"\\ No newline at end of file\n",
+			want: []File{{Path: "new.md", Added: []int{1, 2}}},
+		},
+		{
+			name: "renamed and edited",
+			diff: "diff --git a/old.md b/docs/new.md\n" +
+				"similarity index 90%\n" +
+				"rename from old.md\n" +
+				"rename to docs/new.md\n" +
+				"--- a/old.md\n" +
+				"+++ b/docs/new.md\n" +
+				"@@ -7 +7 @@\n" +
+				"-before\n" +
+				"+after\n",
+			want: []File{{Path: "docs/new.md", OldPath: "old.md", Added: []int{7}}},
+		},
+		{
+			name: "pure rename",
+			diff: "diff --git a/old name.md b/new name.md\n" +
+				"similarity index 100%\n" +
+				"rename from old name.md\n" +
+				"rename to new name.md\n",
+			want: []File{{Path: "new name.md", OldPath: "old name.md"}},
+		},
+		{
+			name: "quoted path",
+			diff: "diff --git \"a/caf\\303\\251.md\" \"b/caf\\303\\251.md\"\n" +
+				"--- \"a/caf\\303\\251.md\"\n" +
+				"+++ \"b/caf\\303\\251.md\"\n" +
+				"@@ -1 +1 @@\n" +
+				"-a\n" +
+				"+b\n",
+			want: []File{{Path: "café.md", Added: []int{1}}},
+		},
+		{
+			name: "deleted file",
+			diff: "diff --git a/gone.md b/gone.md\n" +
+				"deleted file mode 100644\n" +
+				"--- a/gone.md\n" +
+				"+++ /dev/null\n" +
+				"@@ -1,2 +0,0 @@\n" +
+				"-one\n" +
+				"-two\n",
+		},
+		{
+			name: "truncated hunk",
+			diff: "diff --git a/a.md b/a.md\n" +
+				"--- a/a.md\n" +
+				"+++ b/a.md\n" +
+				"@@ -1 +1,2 @@\n" +
+				"+one\n",
+			wantErr: "hunk ends early",
+		},
+		{
+			name: "malformed hunk header",
+			diff: "diff --git a/a.md b/a.md\n" +
+				"--- a/a.md\n" +
+				"+++ b/a.md\n" +
+				"@@ -x +1 @@\n",
+			wantErr: "malformed hunk header",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(strings.NewReader(tt.diff))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// A commit adding URLs to a repository with older ones splits the URLs of
+// a scan into the new and the pre-existing, across a rename and lines that
+// moved down
+func TestRun(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is needed to build the repository")
+	}
+	const docs = "https://docs.redhat.com/en/documentation/openshift_container_platform/"
+	oldURL := docs + "4.14/html/networking/index"
+	newURL := docs + "4.17/html/networking/ingress"
+
+	repo := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(repo, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run("init", "-q")
+	write("docs/install.md", "# Install\n\nSee "+oldURL+".\n")
+	write("docs/untouched.md", "See "+oldURL+".\n")
+	write("guide.md", "# Guide\n\n"+strings.Repeat("Some text.\n", 10)+"Old: "+oldURL+"\n")
+	run("add", "-A")
+	run("commit", "-q", "-m", "base")
+	run("tag", "base")
+
+	// New lines above the old URL move it down; the guide is also renamed
+	write("docs/install.md", "# Install\n\nNew: "+newURL+"\nAlso "+oldURL+"#new\n\nSee "+oldURL+".\n")
+	run("mv", "guide.md", "docs/guide.md")
+	write("docs/guide.md", "# Guide\n\n"+strings.Repeat("Some text.\n", 10)+"Old: "+oldURL+"\nNew: "+newURL+"\n")
+	write("docs/added.md", "Added: "+newURL+"\n")
+	run("add", "-A")
+	run("commit", "-q", "-m", "change")
+
+	diff, err := Run(t.Context(), filepath.Join(repo, "docs"), "base")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	occurrences, err := scanner.New().Scan(filepath.Join(repo, "docs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changed := diff.FilterChanged(occurrences)
+	if got := positions(changed); !reflect.DeepEqual(got, []string{
+		"added.md:1", "guide.md:13", "guide.md:14", "install.md:3", "install.md:4", "install.md:6",
+	}) {
+		t.Errorf("FilterChanged() = %v, want every occurrence but those of untouched.md", got)
+	}
+
+	added, preexisting := diff.SplitAdded(occurrences)
+	if got := positions(added); !reflect.DeepEqual(got, []string{"added.md:1", "guide.md:14", "install.md:3", "install.md:4"}) {
+		t.Errorf("SplitAdded() added = %v", got)
+	}
+	if got := positions(preexisting); !reflect.DeepEqual(got, []string{"guide.md:13", "install.md:6", "untouched.md:1"}) {
+		t.Errorf("SplitAdded() pre-existing = %v", got)
+	}
+
+	if _, err := Run(t.Context(), repo, "no-such-ref"); err == nil || !strings.Contains(err.Error(), "git diff") {
+		t.Errorf("Run() with an unknown ref error = %v, want a git diff error", err)
+	}
+
+	// A ref that git would take for an option is refused before git runs
+	written := filepath.Join(t.TempDir(), "written")
+	if _, err := Run(t.Context(), repo, "--output="+written); err == nil || !strings.Contains(err.Error(), "must not start with '-'") {
+		t.Errorf("Run() with an option as ref error = %v, want an invalid ref", err)
+	}
+	if _, err := os.Stat(written); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("git wrote %s: %v", written, err)
+	}
+}
+
+// positions lists the occurrences of locations as file:line, sorted
+func positions(locations []scanner.Location) []string {
+	var got []string
+	for _, loc := range locations {
+		for _, occ := range loc.Occurrences {
+			got = append(got, fmt.Sprintf("%s:%d", filepath.Base(occ.Path), occ.Line))
+		}
+	}
+	sort.Strings(got)
+	return got
+}
@@ -0,0 +1,34 @@
+package output
+
+import (
+	"sort"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/checker"
+)
+
+// Anchor is an anchor of a page in the JSON output of -list-anchors
+type Anchor struct {
+	ID  string `json:"id"`
+	Tag string `json:"tag"`
+	// Heading is the text of an h1 to h6 element, absent for others
+	Heading string `json:"heading,omitempty"`
+}
+
+// AnchorList is the JSON output of -list-anchors
+type AnchorList struct {
+	RunID   string   `json:"run_id,omitempty"`
+	URL     string   `json:"url"`
+	Anchors []Anchor `json:"anchors"`
+}
+
+// NewAnchorList converts the anchors of the page of url, sorting them by id
+func NewAnchorList(url string, anchors []checker.Anchor) AnchorList {
+	list := AnchorList{URL: url, Anchors: []Anchor{}}
+	for _, a := range anchors {
+		list.Anchors = append(list.Anchors, Anchor{ID: a.ID, Tag: a.Tag, Heading: a.Heading})
+	}
+	sort.Slice(list.Anchors, func(i, j int) bool {
+		return list.Anchors[i].ID < list.Anchors[j].ID
+	})
+	return list
+}
@@ -0,0 +1,30 @@
+package output
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/checker"
+)
+
+func TestNewAnchorList(t *testing.T) {
+	const url = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.17/html/networking/ingress"
+	got := NewAnchorList(url, []checker.Anchor{
+		{ID: "ingress", Tag: "section"},
+		{ID: "configuring-ingress", Tag: "h2", Heading: "Configuring ingress"},
+		{ID: "_legacy", Tag: "a"},
+	})
+	want := AnchorList{URL: url, Anchors: []Anchor{
+		{ID: "_legacy", Tag: "a"},
+		{ID: "configuring-ingress", Tag: "h2", Heading: "Configuring ingress"},
+		{ID: "ingress", Tag: "section"},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NewAnchorList() = %+v, want %+v", got, want)
+	}
+
+	// A page without anchors is an empty list in JSON, not null
+	if empty := NewAnchorList(url, nil); empty.Anchors == nil {
+		t.Error("NewAnchorList() of no anchors has a nil list")
+	}
+}
@@ -0,0 +1,46 @@
+package output
+
+import "github.com/sebrandon1/ocp-doc-checker/pkg/checker"
+
+// VersionRun is a run of consecutive checked versions with the same status,
+// e.g. every version from 4.17 on where a dropped page is not found
+type VersionRun struct {
+	Status  string
+	Results []checker.VersionCheckResult
+}
+
+// First returns the oldest version of the run
+func (r VersionRun) First() string {
+	return r.Results[0].Version
+}
+
+// Last returns the newest version of the run
+func (r VersionRun) Last() string {
+	return r.Results[len(r.Results)-1].Version
+}
+
+// Versions names the versions of the run, e.g. "4.17" or "4.17–4.20"
+func (r VersionRun) Versions() string {
+	if len(r.Results) == 1 {
+		return r.First()
+	}
+	return r.First() + "–" + r.Last()
+}
+
+// ClusterVersions groups checked versions, in the order they were checked,
+// into runs of consecutive versions whose status is the same. Every boundary
+// between two runs is a change of status, such as the version after which a
+// page is no longer found. Only text output clusters versions; JSON keeps
+// the detail of every version.
+func ClusterVersions(results []checker.VersionCheckResult, status func(checker.VersionCheckResult) string) []VersionRun {
+	var runs []VersionRun
+	for _, v := range results {
+		s := status(v)
+		if n := len(runs); n > 0 && runs[n-1].Status == s {
+			runs[n-1].Results = append(runs[n-1].Results, v)
+			continue
+		}
+		runs = append(runs, VersionRun{Status: s, Results: []checker.VersionCheckResult{v}})
+	}
+	return runs
+}
@@ -0,0 +1,72 @@
+package output
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/checker"
+)
+
+// clusterStatus is a short status for cluster tests
+func clusterStatus(v checker.VersionCheckResult) string {
+	switch {
+	case !v.Exists:
+		return "not found"
+	case v.HasAnchor && !v.AnchorExists:
+		return "anchor missing"
+	default:
+		return "found"
+	}
+}
+
+// versionsFrom builds checked versions from 4.11 on with the given outcomes:
+// f for found, a for anchor missing and n for not found
+func versionsFrom(outcomes string) []checker.VersionCheckResult {
+	var results []checker.VersionCheckResult
+	for i, o := range outcomes {
+		results = append(results, checker.VersionCheckResult{
+			Version:      fmt.Sprintf("4.%d", 11+i),
+			Exists:       o != 'n',
+			HasAnchor:    true,
+			AnchorExists: o == 'f',
+		})
+	}
+	return results
+}
+
+func TestClusterVersions(t *testing.T) {
+	tests := []struct {
+		name     string
+		outcomes string
+		want     []string // "versions: status" per run
+	}{
+		{"none", "", nil},
+		{"single version", "n", []string{"4.11: not found"}},
+		{"uniform", "nnnnnnnnnn", []string{"4.11–4.20: not found"}},
+		{"single transition", "ffffffnnnn", []string{"4.11–4.16: found", "4.17–4.20: not found"}},
+		{"anchor dropped, then page", "ffaann", []string{"4.11–4.12: found", "4.13–4.14: anchor missing", "4.15–4.16: not found"}},
+		{"alternating", "fnfn", []string{"4.11: found", "4.12: not found", "4.13: found", "4.14: not found"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := versionsFrom(tt.outcomes)
+			runs := ClusterVersions(results, clusterStatus)
+
+			var got []string
+			var flattened []checker.VersionCheckResult
+			for _, run := range runs {
+				got = append(got, run.Versions()+": "+run.Status)
+				flattened = append(flattened, run.Results...)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ClusterVersions() = %q, want %q", got, tt.want)
+			}
+			// Every version is kept, in order
+			if !reflect.DeepEqual(flattened, results) {
+				t.Errorf("ClusterVersions() runs hold %v, want %v", flattened, results)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/checker"
+	"github.com/sebrandon1/ocp-doc-checker/pkg/parser"
+)
+
+// UpgradeEffort summarizes the work needed to move the URLs at one version
+// to the latest version that works for them
+type UpgradeEffort struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	URLs int    `json:"urls"`
+	// Fixable URLs have a newer version with the page and anchor verified
+	// and can be updated by -fix
+	Fixable int `json:"fixable"`
+	// NeedsAnchorWork URLs have a newer page, but their anchor is gone
+	NeedsAnchorWork int `json:"needs_anchor_work"`
+	// Blocked URLs have no newer page at all, e.g. the feature was removed
+	Blocked int `json:"blocked"`
+}
+
+// NewUpgradeEffort aggregates results by (current version → latest working
+// version). Up-to-date URLs with nothing newer to check and malformed URLs
+// are left out.
+func NewUpgradeEffort(results []*checker.CheckResult) []UpgradeEffort {
+	var efforts []UpgradeEffort
+	index := make(map[[2]string]int)
+
+	for _, result := range results {
+		// Versions left out of the range were never requested
+		checked := slices.DeleteFunc(slices.Clone(result.AllResults), func(v checker.VersionCheckResult) bool {
+			return v.NotChecked
+		})
+		if result.FragmentIssue == parser.FragmentMalformed || len(checked) == 0 {
+			continue
+		}
+
+		var to string
+		var count func(*UpgradeEffort)
+		best, hasBest := result.BestSuggestion()
+		switch {
+		case result.IsOutdated && hasBest:
+			to = best.Version
+			count = func(e *UpgradeEffort) { e.Fixable++ }
+		case latestExisting(checked) != "":
+			to = latestExisting(checked)
+			count = func(e *UpgradeEffort) { e.NeedsAnchorWork++ }
+		default:
+			to = checked[len(checked)-1].Version
+			count = func(e *UpgradeEffort) { e.Blocked++ }
+		}
+
+		key := [2]string{result.OriginalVersion, to}
+		i, ok := index[key]
+		if !ok {
+			i = len(efforts)
+			index[key] = i
+			efforts = append(efforts, UpgradeEffort{From: key[0], To: key[1]})
+		}
+		efforts[i].URLs++
+		count(&efforts[i])
+	}
+
+	sort.SliceStable(efforts, func(i, j int) bool {
+		if efforts[i].From != efforts[j].From {
+			return versionLess(efforts[i].From, efforts[j].From)
+		}
+		return versionLess(efforts[i].To, efforts[j].To)
+	})
+
+	return efforts
+}
+
+// latestExisting returns the latest checked version whose page exists
+func latestExisting(versions []checker.VersionCheckResult) string {
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i].Exists {
+			return versions[i].Version
+		}
+	}
+	return ""
+}
+
+// versionLess compares two major.minor versions numerically
+func versionLess(a, b string) bool {
+	var aMajor, aMinor, bMajor, bMinor int
+	fmt.Sscanf(a, "%d.%d", &aMajor, &aMinor)
+	fmt.Sscanf(b, "%d.%d", &bMajor, &bMinor)
+	if aMajor != bMajor {
+		return aMajor < bMajor
+	}
+	return aMinor < bMinor
+}
+
+// WriteUpgradeEffort writes upgrade effort as a text table
+func WriteUpgradeEffort(w io.Writer, efforts []UpgradeEffort) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FROM\tTO\tURLS\tAUTO-FIXABLE\tANCHOR WORK\tBLOCKED")
+
+	total := UpgradeEffort{}
+	for _, e := range efforts {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\n", e.From, e.To, e.URLs, e.Fixable, e.NeedsAnchorWork, e.Blocked)
+		total.URLs += e.URLs
+		total.Fixable += e.Fixable
+		total.NeedsAnchorWork += e.NeedsAnchorWork
+		total.Blocked += e.Blocked
+	}
+	fmt.Fprintf(tw, "total\t\t%d\t%d\t%d\t%d\n", total.URLs, total.Fixable, total.NeedsAnchorWork, total.Blocked)
+
+	return tw.Flush()
+}
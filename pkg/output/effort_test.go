@@ -0,0 +1,78 @@
+package output
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/checker"
+	"github.com/sebrandon1/ocp-doc-checker/pkg/parser"
+)
+
+// effortResult builds a result at version from whose newer versions are
+// described by status: 'f' verified page and anchor, 'a' page without the
+// anchor, 'm' missing page
+func effortResult(from string, newer map[string]byte) *checker.CheckResult {
+	result := &checker.CheckResult{OriginalVersion: from, LatestVersion: from}
+
+	for _, v := range []string{"4.14", "4.15", "4.16", "4.17", "4.18", "4.19", "4.20"} {
+		status, ok := newer[v]
+		if !ok {
+			continue
+		}
+		vr := checker.VersionCheckResult{Version: v, HasAnchor: true, Exists: status != 'm', AnchorExists: status == 'f'}
+		result.AllResults = append(result.AllResults, vr)
+		if status == 'f' {
+			result.NewerVersions = append(result.NewerVersions, vr)
+			result.IsOutdated = true
+			result.LatestVersion = v
+		}
+	}
+
+	return result
+}
+
+func effortResults() []*checker.CheckResult {
+	return []*checker.CheckResult{
+		effortResult("4.16", map[string]byte{"4.17": 'f', "4.18": 'f', "4.19": 'f', "4.20": 'f'}),
+		effortResult("4.14", map[string]byte{"4.19": 'f', "4.20": 'f'}),
+		effortResult("4.14", map[string]byte{"4.19": 'f', "4.20": 'f'}),
+		effortResult("4.14", map[string]byte{"4.19": 'a', "4.20": 'a'}),
+		effortResult("4.14", map[string]byte{"4.19": 'f', "4.20": 'a'}),
+		effortResult("4.14", map[string]byte{"4.19": 'm', "4.20": 'm'}),
+		effortResult("4.20", nil),
+		{OriginalVersion: "4.15", FragmentIssue: parser.FragmentMalformed},
+	}
+}
+
+func TestNewUpgradeEffort(t *testing.T) {
+	got := NewUpgradeEffort(effortResults())
+	want := []UpgradeEffort{
+		{From: "4.14", To: "4.19", URLs: 1, Fixable: 1},
+		{From: "4.14", To: "4.20", URLs: 4, Fixable: 2, NeedsAnchorWork: 1, Blocked: 1},
+		{From: "4.16", To: "4.20", URLs: 1, Fixable: 1},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NewUpgradeEffort() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestWriteUpgradeEffort(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteUpgradeEffort(&buf, NewUpgradeEffort(effortResults())); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "upgrade-effort.golden", buf.Bytes())
+}
+
+func TestWriteJSON_UpgradeEffort(t *testing.T) {
+	batch := NewBatch(nil, nil, sampleStats())
+	batch.UpgradeEffort = NewUpgradeEffort(effortResults())
+
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, batch); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "json-upgrade-effort.golden", buf.Bytes())
+}
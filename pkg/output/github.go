@@ -0,0 +1,64 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Finding is a single reportable problem tied to a location in a file
+type Finding struct {
+	Path    string
+	Line    int
+	Column  int
+	Status  string // e.g., "outdated" or "malformed-fragment"
+	Message string
+}
+
+// SortFindings orders findings by path, then line, then column
+func SortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
+
+// WriteGitHub writes findings for GitHub Actions logs: each file's findings
+// are wrapped in ::group:: / ::endgroup:: markers and every finding is one
+// `path:line:col: status: message` line that a problem matcher can parse.
+func WriteGitHub(w io.Writer, findings []Finding) error {
+	sorted := make([]Finding, len(findings))
+	copy(sorted, findings)
+	SortFindings(sorted)
+
+	for i, f := range sorted {
+		if i == 0 || sorted[i-1].Path != f.Path {
+			if i > 0 {
+				if _, err := fmt.Fprintln(w, "::endgroup::"); err != nil {
+					return err
+				}
+			}
+			if _, err := fmt.Fprintf(w, "::group::%s\n", f.Path); err != nil {
+				return err
+			}
+		}
+
+		if _, err := fmt.Fprintf(w, "%s:%d:%d: %s: %s\n", f.Path, f.Line, f.Column, f.Status, f.Message); err != nil {
+			return err
+		}
+	}
+
+	if len(sorted) > 0 {
+		if _, err := fmt.Fprintln(w, "::endgroup::"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
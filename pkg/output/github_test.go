@@ -0,0 +1,75 @@
+package output
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// checkGolden compares got with the named golden file in testdata
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("output does not match %s\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func TestWriteGitHub(t *testing.T) {
+	findings := []Finding{
+		{
+			Path:    "docs/install.md",
+			Line:    12,
+			Column:  5,
+			Status:  "outdated",
+			Message: "4.17 → 4.20: https://docs.redhat.com/en/documentation/openshift_container_platform/4.20/html/networking/index",
+		},
+		{
+			Path:    "README.md",
+			Line:    3,
+			Column:  1,
+			Status:  "malformed-fragment",
+			Message: "anchor contains whitespace or an extra '#': https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html-single/operators/index#a#b",
+		},
+		{
+			Path:    "docs/install.md",
+			Line:    4,
+			Column:  20,
+			Status:  "outdated",
+			Message: "4.16 → 4.20: https://docs.redhat.com/en/documentation/openshift_container_platform/4.20/html/storage/index",
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteGitHub(&buf, findings); err != nil {
+		t.Fatalf("WriteGitHub() error = %v", err)
+	}
+
+	checkGolden(t, "github.golden", buf.Bytes())
+}
+
+func TestWriteGitHub_NoFindings(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteGitHub(&buf, nil); err != nil {
+		t.Fatalf("WriteGitHub() error = %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("WriteGitHub() wrote %q for no findings", buf.String())
+	}
+}
@@ -0,0 +1,100 @@
+package output
+
+import (
+	"sort"
+	"strings"
+)
+
+// Finding severities, as reported by the GitHub Actions problem matcher
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	// SeverityInfo marks informational findings, which never fail a scan
+	SeverityInfo = "info"
+)
+
+// Severity returns the severity of a finding status: suspicious hosts are
+// errors, every other finding is a warning
+func Severity(status string) string {
+	if status == "suspicious-host" {
+		return SeverityError
+	}
+	return SeverityWarning
+}
+
+// Hotspot is a file with enough outdated URLs to be worth fixing on its own
+type Hotspot struct {
+	File     string `json:"file"`
+	Outdated int    `json:"outdated"`
+	// Findings counts every finding in the file by severity
+	Findings map[string]int `json:"findings"`
+	// Command fixes the outdated URLs of just this file
+	Command string `json:"command"`
+}
+
+// NewHotspots aggregates findings per file and returns the files with at
+// least threshold outdated URLs, most outdated first. Outdated URLs inside
+// encoded values are not counted, since -fix cannot update them. fixArgs are
+// extra flags appended to each file's fix command, e.g. the target policy of
+// the current run.
+func NewHotspots(findings []Finding, threshold int, fixArgs []string) []Hotspot {
+	byFile := make(map[string]*Hotspot)
+	var files []string
+	for _, f := range findings {
+		h, ok := byFile[f.Path]
+		if !ok {
+			h = &Hotspot{File: f.Path, Findings: make(map[string]int)}
+			byFile[f.Path] = h
+			files = append(files, f.Path)
+		}
+		h.Findings[Severity(f.Status)]++
+		if f.Status == "outdated" {
+			h.Outdated++
+		}
+	}
+
+	var hotspots []Hotspot
+	for _, file := range files {
+		h := byFile[file]
+		if h.Outdated < threshold {
+			continue
+		}
+		args := append([]string{"ocp-doc-checker", "-dir", h.File, "-fix"}, fixArgs...)
+		h.Command = ShellCommand(args...)
+		hotspots = append(hotspots, *h)
+	}
+
+	sort.SliceStable(hotspots, func(i, j int) bool {
+		if hotspots[i].Outdated != hotspots[j].Outdated {
+			return hotspots[i].Outdated > hotspots[j].Outdated
+		}
+		return hotspots[i].File < hotspots[j].File
+	})
+
+	return hotspots
+}
+
+// ShellCommand joins args into a command line for a POSIX shell, quoting
+// the arguments that need it
+func ShellCommand(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// shellQuote returns arg unchanged if the shell reads it literally, and in
+// single quotes otherwise
+func shellQuote(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+	safe := strings.IndexFunc(arg, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./=:,@+%", r))
+	}) == -1
+	if safe {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
@@ -0,0 +1,75 @@
+package output
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewHotspots(t *testing.T) {
+	var findings []Finding
+	add := func(path, status string, n int) {
+		for i := 0; i < n; i++ {
+			findings = append(findings, Finding{Path: path, Line: i + 1, Status: status})
+		}
+	}
+	add("docs/install.md", "outdated", 2)
+	add("docs/my guide.md", "outdated", 3)
+	add("docs/my guide.md", "suspicious-host", 1)
+	add("docs/install.md", "outdated", 1)
+	add("docs/install.md", "outdated-encoded", 4)
+	add("docs/upgrade.md", "outdated", 3)
+	add("README.md", "outdated", 2)
+
+	got := NewHotspots(findings, 3, []string{"-allowed-target-versions", "eus"})
+	want := []Hotspot{
+		{
+			File:     "docs/install.md",
+			Outdated: 3,
+			Findings: map[string]int{SeverityWarning: 7},
+			Command:  "ocp-doc-checker -dir docs/install.md -fix -allowed-target-versions eus",
+		},
+		{
+			File:     "docs/my guide.md",
+			Outdated: 3,
+			Findings: map[string]int{SeverityWarning: 3, SeverityError: 1},
+			Command:  "ocp-doc-checker -dir 'docs/my guide.md' -fix -allowed-target-versions eus",
+		},
+		{
+			File:     "docs/upgrade.md",
+			Outdated: 3,
+			Findings: map[string]int{SeverityWarning: 3},
+			Command:  "ocp-doc-checker -dir docs/upgrade.md -fix -allowed-target-versions eus",
+		},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NewHotspots() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	if got := NewHotspots(findings, 4, nil); got != nil {
+		t.Errorf("NewHotspots() above every file's count = %+v, want nil", got)
+	}
+}
+
+func TestShellCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"plain", []string{"ocp-doc-checker", "-dir", "docs/a_b-c.md"}, "ocp-doc-checker -dir docs/a_b-c.md"},
+		{"spaces", []string{"-dir", "my docs/guide one.md"}, "-dir 'my docs/guide one.md'"},
+		{"single quote", []string{"-dir", "it's.md"}, `-dir 'it'\''s.md'`},
+		{"shell metacharacters", []string{"-dir", "$(rm -rf).md", "a;b", "*.md"}, "-dir '$(rm -rf).md' 'a;b' '*.md'"},
+		{"empty", []string{"-slug-map", ""}, "-slug-map ''"},
+		{"non-ASCII", []string{"-dir", "guía.md"}, "-dir 'guía.md'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ShellCommand(tt.args...); got != tt.want {
+				t.Errorf("ShellCommand() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
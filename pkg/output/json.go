@@ -0,0 +1,574 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/checker"
+	"github.com/sebrandon1/ocp-doc-checker/pkg/scanner"
+)
+
+// Version is a newer version of a document in JSON output
+type Version struct {
+	Version     string `json:"version"`
+	URL         string `json:"url"`
+	RenamedFrom string `json:"renamed_from,omitempty"`
+	// Cached is set when the version was verified from an imported matrix
+	Cached bool `json:"cached,omitempty"`
+	// ServedDocument is the guide the version redirects to, when it is
+	// another guide than the URL names
+	ServedDocument string `json:"served_document,omitempty"`
+	// RedirectedTo is the page the version redirects to, when it is
+	// another page and redirects are accepted
+	RedirectedTo string `json:"redirected_to,omitempty"`
+	// AnchorVia is "html-single" when the anchor was verified on the
+	// html-single variant of the guide rather than on the page itself
+	AnchorVia string `json:"anchor_via,omitempty"`
+	// MovedFrom is the page the section was linked on, when it was found on
+	// another page of the guide by a sibling search
+	MovedFrom string `json:"moved_from,omitempty"`
+	// AnchorMissing is set when the page exists without the anchor, a
+	// version that only counts under the page-only anchor policy
+	AnchorMissing bool `json:"anchor_missing,omitempty"`
+}
+
+// CheckedVersion is the outcome of requesting a newer version, whether its
+// page was found or not
+type CheckedVersion struct {
+	Version string `json:"version"`
+	URL     string `json:"url"`
+	Exists  bool   `json:"exists"`
+	// AnchorExists is only present for URLs with a fragment whose page
+	// exists
+	AnchorExists *bool `json:"anchor_exists,omitempty"`
+	// AnchorText is the text of the heading the anchor names; only present
+	// when anchor_exists is true and the anchor names a heading
+	AnchorText string `json:"anchor_text,omitempty"`
+	// SuggestedAnchors are the ids of the page most like the missing
+	// anchor, best first; only present when anchor_exists is false
+	SuggestedAnchors []string `json:"suggested_anchors,omitempty"`
+	// AnchorIndeterminate is set when the page was too large to read for
+	// the anchor within the parse budget, with the bytes read in
+	// ScannedBytes; anchor_exists is then absent
+	AnchorIndeterminate bool  `json:"anchor_indeterminate,omitempty"`
+	ScannedBytes        int64 `json:"scanned_bytes,omitempty"`
+	// RedirectedTo is the page the version redirects to when it is another
+	// page, another guide or no documentation page; exists is false for
+	// it unless redirects are accepted
+	RedirectedTo string `json:"redirected_to,omitempty"`
+	// NotFoundMarker is the not-found message of a page answered with 2xx,
+	// a soft 404; exists is false for it
+	NotFoundMarker string `json:"not_found_marker,omitempty"`
+	// StatusCode is the HTTP status that answered, absent when none did,
+	// e.g. after a timeout
+	StatusCode int `json:"status_code,omitempty"`
+	// DurationMS is how long the requests for the page took, retries
+	// included. It and Attempts are absent for imported versions.
+	DurationMS int64 `json:"duration_ms,omitempty"`
+	Attempts   int   `json:"attempts,omitempty"`
+	// Network is the address family the page was answered over, "ipv4"
+	// or "ipv6"; absent when unknown, e.g. through a custom transport
+	Network string `json:"network,omitempty"`
+	Cached  bool   `json:"cached,omitempty"`
+	Error   string `json:"error,omitempty"`
+	// SkippedReason is set for a version that was not requested, e.g.
+	// "above the maximum version 4.16"; exists is false for it
+	SkippedReason string `json:"skipped_reason,omitempty"`
+}
+
+// Result is the JSON form of a single URL check
+type Result struct {
+	// RunID identifies the run that checked a single URL; it is left out of
+	// the results of a Batch, which carries it once
+	RunID           string `json:"run_id,omitempty"`
+	OriginalURL     string `json:"original_url"`
+	OriginalVersion string `json:"original_version"`
+	DocumentTitle   string `json:"document_title,omitempty"`
+	LatestVersion   string `json:"latest_version"`
+	IsOutdated      bool   `json:"is_outdated"`
+	// Broken is set when the page or anchor of the URL is missing at its
+	// own version. OriginalExists and OriginalAnchorExists are only present
+	// once the original URL was verified, the latter for URLs with an
+	// anchor whose page exists.
+	Broken               bool  `json:"broken,omitempty"`
+	OriginalExists       *bool `json:"original_exists,omitempty"`
+	OriginalAnchorExists *bool `json:"original_anchor_exists,omitempty"`
+	// OriginalRedirectedTo is the page the URL redirects to at its own
+	// version, when it is another page
+	OriginalRedirectedTo string `json:"original_redirected_to,omitempty"`
+	// OriginalDeprecated is set when the page at the URL's own version
+	// shows the banner of a release that is no longer maintained
+	OriginalDeprecated bool   `json:"original_deprecated,omitempty"`
+	FragmentIssue      string `json:"fragment_issue,omitempty"`
+	VersionIssue       string `json:"version_issue,omitempty"`
+	SuggestedURL       string `json:"suggested_url,omitempty"`
+	// BestSuggestion is the newer version -fix would move the URL to. It is
+	// always one of NewerVersions, which are listed in full.
+	BestSuggestion *Version  `json:"best_suggestion,omitempty"`
+	NewerVersions  []Version `json:"newer_versions"`
+	// ExcludedVersions are working newer versions that the target policy
+	// does not allow as upgrade targets
+	ExcludedVersions []Version `json:"excluded_versions,omitempty"`
+	// CheckedVersions are all the newer versions requested, in order, to
+	// tell missing pages from failed requests, and with -verbose those
+	// left out of the version range, with a skipped_reason
+	CheckedVersions []CheckedVersion `json:"checked_versions,omitempty"`
+	// Notes are remarks on the verdict, such as a version above
+	// -max-version; LowConfidence and other notes come from result hooks of
+	// library users
+	Notes         []string `json:"notes,omitempty"`
+	LowConfidence bool     `json:"low_confidence,omitempty"`
+	// AppliedHeuristics name the heuristics that altered or could have
+	// altered the verdict
+	AppliedHeuristics []string `json:"applied_heuristics,omitempty"`
+	// AsOf is the version -as-of capped the versions checked at
+	AsOf string `json:"as_of,omitempty"`
+	// AnchorPolicy is the -anchor-policy the verdict was reached under,
+	// "strict" or "page-only"
+	AnchorPolicy string `json:"anchor_policy,omitempty"`
+}
+
+// Placeholder is one occurrence of a URL with an unresolved version placeholder
+type Placeholder struct {
+	URL  string `json:"url"`
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+// EncodedOccurrence is a URL found inside an encoded value by a deep scan.
+// Such occurrences are never fixed automatically.
+type EncodedOccurrence struct {
+	URL        string `json:"url"`
+	File       string `json:"file"`
+	Line       int    `json:"line"`
+	KeyPath    string `json:"key_path"`
+	Encoding   string `json:"encoding"`
+	IsOutdated bool   `json:"is_outdated"`
+}
+
+// MixedLocaleSpelling is an occurrence of a checked URL spelled without the
+// locale segment, e.g. /documentation/... for /en/documentation/...
+type MixedLocaleSpelling struct {
+	URL      string `json:"url"`
+	Spelling string `json:"spelling"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// SuspiciousHost is a URL whose host resembles the documentation host
+// without being it. Such URLs are never checked.
+type SuspiciousHost struct {
+	URL       string `json:"url"`
+	Host      string `json:"host"`
+	ASCIIHost string `json:"ascii_host"`
+	Reason    string `json:"reason"`
+	Severity  string `json:"severity"`
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+}
+
+// HistoricalReference is an occurrence of a URL in a changelog or release
+// notes. It is informational: never fixed and never failing a scan.
+type HistoricalReference struct {
+	URL           string `json:"url"`
+	File          string `json:"file"`
+	Line          int    `json:"line"`
+	Version       string `json:"version"`
+	LatestVersion string `json:"latest_version"`
+	IsOutdated    bool   `json:"is_outdated"`
+	Severity      string `json:"severity"`
+}
+
+// ReasonSoftDeadline is why URLs are not checked when the run reached its
+// soft deadline
+const ReasonSoftDeadline = "soft deadline"
+
+// ReasonPreexisting is why URLs are not checked when they are on lines a
+// change did not add
+const ReasonPreexisting = "pre-existing"
+
+// ReasonGenerated is why URLs are not checked when they are only in
+// generated or minified files
+const ReasonGenerated = "generated file"
+
+// NotChecked is an occurrence of a URL the run did not check
+type NotChecked struct {
+	URL    string `json:"url"`
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+}
+
+// WrongProductLink is an occurrence of a URL to the documentation of
+// another product than the file declares, e.g. an OCP guide linked from a
+// ROSA guide. It is advisory: never fixed and never failing a scan.
+type WrongProductLink struct {
+	URL             string `json:"url"`
+	File            string `json:"file"`
+	Line            int    `json:"line"`
+	Column          int    `json:"column"`
+	Product         string `json:"product"`
+	DeclaredProduct string `json:"declared_product"`
+	// Equivalent is the same document, page and anchor under the declared
+	// product, when a probe found it
+	Equivalent string `json:"equivalent,omitempty"`
+	Severity   string `json:"severity"`
+}
+
+// SpellingGroup lists the html and html-single spellings of one section
+type SpellingGroup struct {
+	Spellings []Spelling `json:"spellings"`
+}
+
+// Spelling is one spelling of a section and whether it is outdated
+type Spelling struct {
+	URL        string `json:"url"`
+	Format     string `json:"format"`
+	IsOutdated bool   `json:"is_outdated"`
+}
+
+// ScanStats is the JSON form of scanner.Stats
+type ScanStats struct {
+	FilesVisited   int                `json:"files_visited"`
+	FilesScanned   int                `json:"files_scanned"`
+	FilesSkipped   map[string]int     `json:"files_skipped"`
+	FilesGenerated map[string]int     `json:"files_generated,omitempty"`
+	BytesScanned   int64              `json:"bytes_scanned"`
+	FilesPerExt    map[string]int     `json:"files_per_extension"`
+	SecondsPerExt  map[string]float64 `json:"seconds_per_extension"`
+}
+
+// Batch is the JSON form of a directory scan
+type Batch struct {
+	// RunID identifies the run that wrote the report
+	RunID                  string                `json:"run_id,omitempty"`
+	TotalCount             int                   `json:"total_count"`
+	UptodateCount          int                   `json:"uptodate_count"`
+	OutdatedCount          int                   `json:"outdated_count"`
+	BrokenCount            int                   `json:"broken_count,omitempty"`
+	ScannedFileCount       int                   `json:"scanned_file_count"`
+	UnresolvedPlaceholders []Placeholder         `json:"unresolved_placeholders,omitempty"`
+	ScanStats              *ScanStats            `json:"scan_stats,omitempty"`
+	UpgradeEffort          []UpgradeEffort       `json:"upgrade_effort,omitempty"`
+	EncodedOccurrences     []EncodedOccurrence   `json:"encoded_occurrences,omitempty"`
+	SuspiciousHosts        []SuspiciousHost      `json:"suspicious_hosts,omitempty"`
+	HistoricalReferences   []HistoricalReference `json:"historical_references,omitempty"`
+	WrongProductLinks      []WrongProductLink    `json:"wrong_product_links,omitempty"`
+	SameSection            []SpellingGroup       `json:"same_section,omitempty"`
+	MixedLocaleSpellings   []MixedLocaleSpelling `json:"mixed_locale_spellings,omitempty"`
+	Hotspots               []Hotspot             `json:"hotspots,omitempty"`
+	// ResolvedAliases maps each version alias of the run to its version
+	ResolvedAliases map[string]string `json:"resolved_aliases,omitempty"`
+	// AsOf is the version -as-of capped the versions checked at
+	AsOf string `json:"as_of,omitempty"`
+	// NotChecked are the URLs a run stopped before checking, left out as
+	// pre-existing with -git-new-only, or found only in generated files
+	NotChecked []NotChecked `json:"not_checked,omitempty"`
+	// Shard is set on the partial report of a -shard run
+	Shard *Shard `json:"shard,omitempty"`
+	// MergedShards is the number of partial reports merged into this one
+	MergedShards int `json:"merged_shards,omitempty"`
+	// MergedRunIDs are the run IDs of the partial reports, in shard order
+	MergedRunIDs []string `json:"merged_run_ids,omitempty"`
+	// Failed is set when the run exits 1, so that merged partial reports
+	// fail when any shard did
+	Failed  bool     `json:"failed,omitempty"`
+	Results []Result `json:"results"`
+}
+
+// Shard identifies the partial report of shard Index of Count
+type Shard struct {
+	Index int `json:"index"`
+	Count int `json:"count"`
+}
+
+// NewResult converts a check result to its JSON form
+func NewResult(result *checker.CheckResult) Result {
+	r := Result{
+		OriginalURL:          result.OriginalURL,
+		OriginalVersion:      result.OriginalVersion,
+		DocumentTitle:        result.DocumentTitle,
+		LatestVersion:        result.LatestVersion,
+		IsOutdated:           result.IsOutdated,
+		FragmentIssue:        string(result.FragmentIssue),
+		VersionIssue:         string(result.VersionIssue),
+		SuggestedURL:         result.SuggestedURL,
+		NewerVersions:        []Version{},
+		Notes:                result.Notes,
+		LowConfidence:        result.LowConfidence,
+		AsOf:                 result.AsOf,
+		AnchorPolicy:         string(result.AnchorPolicy),
+		Broken:               result.Broken,
+		OriginalRedirectedTo: result.OriginalRedirectedTo,
+		OriginalDeprecated:   result.OriginalDeprecated,
+	}
+	if result.OriginalExists || result.Broken {
+		r.OriginalExists = &result.OriginalExists
+		if result.OriginalExists && strings.Contains(result.OriginalURL, "#") {
+			r.OriginalAnchorExists = &result.OriginalAnchorExists
+		}
+	}
+
+	for _, v := range result.NewerVersions {
+		r.NewerVersions = append(r.NewerVersions, Version{
+			Version:        v.Version,
+			URL:            v.URL,
+			RenamedFrom:    v.RenamedFrom,
+			Cached:         v.Cached,
+			ServedDocument: v.ServedDocument,
+			RedirectedTo:   v.RedirectedTo,
+			AnchorVia:      v.AnchorVia,
+			MovedFrom:      v.MovedFrom,
+			AnchorMissing:  v.AnchorMissing(),
+		})
+	}
+
+	if best, ok := result.BestSuggestion(); ok {
+		r.BestSuggestion = &Version{
+			Version:        best.Version,
+			URL:            best.URL,
+			RenamedFrom:    best.RenamedFrom,
+			Cached:         best.Cached,
+			ServedDocument: best.ServedDocument,
+			RedirectedTo:   best.RedirectedTo,
+			AnchorVia:      best.AnchorVia,
+			MovedFrom:      best.MovedFrom,
+			AnchorMissing:  best.AnchorMissing(),
+		}
+	}
+
+	for _, v := range result.ExcludedVersions {
+		r.ExcludedVersions = append(r.ExcludedVersions, Version{
+			Version:        v.Version,
+			URL:            v.URL,
+			RenamedFrom:    v.RenamedFrom,
+			Cached:         v.Cached,
+			ServedDocument: v.ServedDocument,
+			RedirectedTo:   v.RedirectedTo,
+			AnchorVia:      v.AnchorVia,
+			MovedFrom:      v.MovedFrom,
+			AnchorMissing:  v.AnchorMissing(),
+		})
+	}
+
+	for _, v := range result.AllResults {
+		r.CheckedVersions = append(r.CheckedVersions, newCheckedVersion(v))
+	}
+	for _, h := range result.AppliedHeuristics {
+		r.AppliedHeuristics = append(r.AppliedHeuristics, string(h))
+	}
+
+	return r
+}
+
+// newCheckedVersion converts the outcome of a version check to its JSON form
+func newCheckedVersion(v checker.VersionCheckResult) CheckedVersion {
+	cv := CheckedVersion{
+		Version:        v.Version,
+		URL:            v.URL,
+		Exists:         v.Exists,
+		StatusCode:     v.StatusCode,
+		DurationMS:     v.Duration.Milliseconds(),
+		Attempts:       v.Attempts,
+		Network:        string(v.Network),
+		Cached:         v.Cached,
+		RedirectedTo:   v.RedirectedTo,
+		NotFoundMarker: v.NotFoundMarker,
+		SkippedReason:  v.SkippedReason,
+	}
+	if v.AnchorIndeterminate {
+		cv.AnchorIndeterminate = true
+		cv.ScannedBytes = v.ScannedBytes
+	} else if v.Exists && v.HasAnchor {
+		cv.AnchorExists = &v.AnchorExists
+		cv.AnchorText = v.AnchorText
+		cv.SuggestedAnchors = v.SuggestedAnchors
+	}
+	if v.Error != nil {
+		cv.Error = v.Error.Error()
+	}
+	return cv
+}
+
+// NewBatch converts the results of a directory scan to their JSON form.
+// Placeholder locations are listed once per occurrence.
+func NewBatch(results []*checker.CheckResult, placeholders []scanner.Location, stats scanner.Stats) Batch {
+	b := Batch{
+		TotalCount:       len(results),
+		ScannedFileCount: stats.FilesScanned,
+		ScanStats:        NewScanStats(stats),
+		Results:          []Result{},
+	}
+
+	for _, result := range results {
+		if result.Broken {
+			b.BrokenCount++
+		} else if result.IsOutdated {
+			b.OutdatedCount++
+		} else {
+			b.UptodateCount++
+		}
+		b.Results = append(b.Results, NewResult(result))
+	}
+
+	for _, loc := range placeholders {
+		for _, occ := range loc.Occurrences {
+			b.UnresolvedPlaceholders = append(b.UnresolvedPlaceholders, Placeholder{
+				URL:  occ.URL,
+				File: occ.Path,
+				Line: occ.Line,
+			})
+		}
+	}
+
+	return b
+}
+
+// NewEncodedOccurrences lists the encoded occurrences of the checked URLs
+func NewEncodedOccurrences(results []*checker.CheckResult, locations map[string]scanner.Location) []EncodedOccurrence {
+	var encoded []EncodedOccurrence
+	for _, result := range results {
+		for _, occ := range locations[result.OriginalURL].Occurrences {
+			if occ.Encoding == "" {
+				continue
+			}
+			encoded = append(encoded, EncodedOccurrence{
+				URL:        occ.URL,
+				File:       occ.Path,
+				Line:       occ.Line,
+				KeyPath:    occ.KeyPath,
+				Encoding:   occ.Encoding,
+				IsOutdated: result.IsOutdated,
+			})
+		}
+	}
+	return encoded
+}
+
+// NewMixedLocaleSpellings lists the occurrences of the checked URLs spelled
+// without a locale
+func NewMixedLocaleSpellings(results []*checker.CheckResult, locations map[string]scanner.Location) []MixedLocaleSpelling {
+	var spellings []MixedLocaleSpelling
+	for _, result := range results {
+		for _, occ := range locations[result.OriginalURL].Occurrences {
+			if !scanner.MissingLocale(occ.URL) {
+				continue
+			}
+			spellings = append(spellings, MixedLocaleSpelling{
+				URL:      result.OriginalURL,
+				Spelling: occ.URL,
+				File:     occ.Path,
+				Line:     occ.Line,
+			})
+		}
+	}
+	return spellings
+}
+
+// NewHistoricalReferences lists the historical occurrences of the checked
+// URLs, including those of URLs that also appear in other files
+func NewHistoricalReferences(results []*checker.CheckResult, locations map[string]scanner.Location) []HistoricalReference {
+	var refs []HistoricalReference
+	for _, result := range results {
+		for _, occ := range locations[result.OriginalURL].Occurrences {
+			if !occ.Historical {
+				continue
+			}
+			refs = append(refs, HistoricalReference{
+				URL:           occ.URL,
+				File:          occ.Path,
+				Line:          occ.Line,
+				Version:       result.OriginalVersion,
+				LatestVersion: result.LatestVersion,
+				IsOutdated:    result.IsOutdated,
+				Severity:      SeverityInfo,
+			})
+		}
+	}
+	return refs
+}
+
+// NewNotChecked lists every occurrence of URLs that were not checked
+func NewNotChecked(locations []scanner.Location, reason string) []NotChecked {
+	var notChecked []NotChecked
+	for _, loc := range locations {
+		for _, occ := range loc.Occurrences {
+			notChecked = append(notChecked, NotChecked{
+				URL:    occ.URL,
+				File:   occ.Path,
+				Line:   occ.Line,
+				Reason: reason,
+			})
+		}
+	}
+	return notChecked
+}
+
+// NewSuspiciousHosts lists every occurrence of URLs with a suspicious host.
+// They are security findings and always have error severity.
+func NewSuspiciousHosts(locations []scanner.Location) []SuspiciousHost {
+	var hosts []SuspiciousHost
+	for _, loc := range locations {
+		for _, occ := range loc.Occurrences {
+			host := scanner.URLHost(occ.URL)
+			hosts = append(hosts, SuspiciousHost{
+				URL:       occ.URL,
+				Host:      host,
+				ASCIIHost: scanner.ASCIIHost(host),
+				Reason:    occ.Suspicious,
+				Severity:  SeverityError,
+				File:      occ.Path,
+				Line:      occ.Line,
+				Column:    occ.Column,
+			})
+		}
+	}
+	return hosts
+}
+
+// NewSpellingGroups converts groups of spellings of the same section to
+// their JSON form
+func NewSpellingGroups(groups [][]checker.Spelling) []SpellingGroup {
+	var converted []SpellingGroup
+	for _, group := range groups {
+		var g SpellingGroup
+		for _, s := range group {
+			g.Spellings = append(g.Spellings, Spelling{
+				URL:        s.Result.OriginalURL,
+				Format:     s.Format,
+				IsOutdated: s.Result.IsOutdated,
+			})
+		}
+		converted = append(converted, g)
+	}
+	return converted
+}
+
+// NewScanStats converts scanner statistics to their JSON form
+func NewScanStats(stats scanner.Stats) *ScanStats {
+	secondsPerExt := make(map[string]float64)
+	for ext, d := range stats.TimePerExt {
+		secondsPerExt[ext] = d.Seconds()
+	}
+
+	return &ScanStats{
+		FilesVisited:   stats.FilesVisited,
+		FilesScanned:   stats.FilesScanned,
+		FilesSkipped:   stats.FilesSkipped,
+		FilesGenerated: stats.FilesGenerated,
+		BytesScanned:   stats.BytesScanned,
+		FilesPerExt:    stats.FilesPerExt,
+		SecondsPerExt:  secondsPerExt,
+	}
+}
+
+// WriteJSON writes v as JSON indented by two spaces. URLs are written
+// verbatim rather than with '&', '<' and '>' escaped.
+func WriteJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
@@ -0,0 +1,340 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/checker"
+	"github.com/sebrandon1/ocp-doc-checker/pkg/fixer"
+	"github.com/sebrandon1/ocp-doc-checker/pkg/parser"
+	"github.com/sebrandon1/ocp-doc-checker/pkg/scanner"
+)
+
+const docsBase = "https://docs.redhat.com/en/documentation/openshift_container_platform/"
+
+// sampleResults returns an outdated result carrying every field added since
+// the legacy format, and an up-to-date one without newer versions
+func sampleResults() []*checker.CheckResult {
+	return []*checker.CheckResult{
+		{
+			OriginalURL:       docsBase + "4.17/html-single/disconnected_environments/index#mirroring-image-set-full",
+			OriginalVersion:   "4.17",
+			DocumentTitle:     "Disconnected environments",
+			LatestVersion:     "4.19",
+			IsOutdated:        true,
+			FragmentIssue:     parser.FragmentDuplicated,
+			VersionIssue:      parser.VersionNonCanonical,
+			SuggestedURL:      docsBase + "4.17/html-single/disconnected_environments/index#mirroring-image-set-full",
+			AppliedHeuristics: []checker.Heuristic{checker.HeuristicFragmentDedup, checker.HeuristicVersionCanonical, checker.HeuristicSlugMap},
+			NewerVersions: []checker.VersionCheckResult{
+				{Version: "4.18", URL: docsBase + "4.18/html-single/disconnected_environments/index#mirroring-image-set-full"},
+				{Version: "4.19", URL: docsBase + "4.19/html-single/disconnected_environments/index#mirroring-image-set-full", RenamedFrom: "index"},
+			},
+			AllResults: []checker.VersionCheckResult{
+				{Version: "4.18", URL: docsBase + "4.18/html-single/disconnected_environments/index#mirroring-image-set-full", Exists: true, HasAnchor: true, AnchorExists: true, StatusCode: 200, Attempts: 1, Duration: 120 * time.Millisecond},
+				{Version: "4.19", URL: docsBase + "4.19/html-single/disconnected_environments/index#mirroring-image-set-full", Exists: true, HasAnchor: true, AnchorExists: true, RenamedFrom: "index", StatusCode: 200, Attempts: 2, Duration: 1500 * time.Millisecond},
+				{Version: "4.20", URL: docsBase + "4.20/html-single/disconnected_environments/index#mirroring-image-set-full", HasAnchor: true, StatusCode: 403, Attempts: 1, Duration: 80 * time.Millisecond},
+			},
+		},
+		{
+			OriginalURL:     docsBase + "4.20/html/networking/index",
+			OriginalVersion: "4.20",
+			LatestVersion:   "4.20",
+		},
+	}
+}
+
+func samplePlaceholders() []scanner.Location {
+	url := docsBase + "4.x/html/networking/index"
+	return []scanner.Location{{
+		URL:         url,
+		Placeholder: true,
+		Occurrences: []scanner.Occurrence{{URL: url, Path: "docs/install.md", Line: 7, Column: 3}},
+	}}
+}
+
+func sampleStats() scanner.Stats {
+	return scanner.Stats{
+		FilesVisited: 3,
+		FilesScanned: 2,
+		FilesSkipped: map[string]int{scanner.SkipUnsupportedExtension: 1},
+		BytesScanned: 512,
+		TimePerExt:   map[string]time.Duration{".md": 1500 * time.Microsecond},
+		FilesPerExt:  map[string]int{".md": 2},
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		golden string
+		value  any
+	}{
+		{"single", "json-single.golden", NewResult(sampleResults()[0])},
+		{"batch", "json-batch.golden", NewBatch(sampleResults(), samplePlaceholders(), sampleStats())},
+		{"batch without placeholders", "json-batch-empty.golden", NewBatch(nil, nil, scanner.New().Stats())},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteJSON(&buf, tt.value); err != nil {
+				t.Fatal(err)
+			}
+			if !json.Valid(buf.Bytes()) {
+				t.Fatalf("output is not valid JSON:\n%s", buf.Bytes())
+			}
+			checkGolden(t, tt.golden, buf.Bytes())
+		})
+	}
+}
+
+// The legacy goldens were captured from the hand-written formatter this
+// package replaced and must not be regenerated
+func TestWriteLegacyJSON(t *testing.T) {
+	results := sampleResults()
+
+	tests := []struct {
+		name   string
+		golden string
+		write  func(*bytes.Buffer) error
+	}{
+		{"outdated", "legacy-single.golden", func(b *bytes.Buffer) error {
+			return WriteLegacyJSON(b, NewResult(results[0]))
+		}},
+		{"current", "legacy-single-current.golden", func(b *bytes.Buffer) error {
+			return WriteLegacyJSON(b, NewResult(results[1]))
+		}},
+		{"batch", "legacy-batch.golden", func(b *bytes.Buffer) error {
+			return WriteLegacyBatchJSON(b, NewBatch(results, samplePlaceholders(), sampleStats()))
+		}},
+		{"empty batch", "legacy-batch-empty.golden", func(b *bytes.Buffer) error {
+			return WriteLegacyBatchJSON(b, NewBatch(nil, nil, scanner.New().Stats()))
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.write(&buf); err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, tt.golden, buf.Bytes())
+		})
+	}
+}
+
+func TestNewSuspiciousHosts(t *testing.T) {
+	url := "https://dоcs.redhat.com/en/documentation/openshift_container_platform/4.17/html/networking/index"
+	locations := []scanner.Location{{
+		URL:        url,
+		Suspicious: scanner.SuspiciousHomoglyph,
+		Occurrences: []scanner.Occurrence{
+			{URL: url, Path: "README.md", Line: 3, Column: 9, Suspicious: scanner.SuspiciousHomoglyph},
+			{URL: url, Path: "docs/a.md", Line: 1, Column: 1, Suspicious: scanner.SuspiciousHomoglyph},
+		},
+	}}
+
+	got := NewSuspiciousHosts(locations)
+	if len(got) != 2 {
+		t.Fatalf("NewSuspiciousHosts() returned %d entries, want 2", len(got))
+	}
+
+	want := SuspiciousHost{
+		URL:       url,
+		Host:      "dоcs.redhat.com",
+		ASCIIHost: "xn--dcs-sed.redhat.com",
+		Reason:    scanner.SuspiciousHomoglyph,
+		Severity:  "error",
+		File:      "README.md",
+		Line:      3,
+		Column:    9,
+	}
+	if got[0] != want {
+		t.Errorf("NewSuspiciousHosts()[0] = %+v, want %+v", got[0], want)
+	}
+}
+
+func TestNewNotChecked(t *testing.T) {
+	const url = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.17/html/networking/index"
+	locations := []scanner.Location{{
+		URL: url,
+		Occurrences: []scanner.Occurrence{
+			{URL: url, Path: "README.md", Line: 3},
+			{URL: url, Path: "docs/a.md", Line: 1},
+		},
+	}}
+
+	got := NewNotChecked(locations, ReasonSoftDeadline)
+	want := []NotChecked{
+		{URL: url, File: "README.md", Line: 3, Reason: "soft deadline"},
+		{URL: url, File: "docs/a.md", Line: 1, Reason: "soft deadline"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NewNotChecked() = %+v, want %+v", got, want)
+	}
+	if NewNotChecked(nil, ReasonSoftDeadline) != nil {
+		t.Error("NewNotChecked(nil) is not empty, want not_checked left out of JSON")
+	}
+}
+
+func TestNewMixedLocaleSpellings(t *testing.T) {
+	result := sampleResults()[1]
+	legacy := strings.Replace(result.OriginalURL, "/en/", "/", 1)
+	locations := map[string]scanner.Location{result.OriginalURL: {
+		URL: result.OriginalURL,
+		Occurrences: []scanner.Occurrence{
+			{URL: result.OriginalURL, Path: "README.md", Line: 3},
+			{URL: legacy, Path: "docs/a.md", Line: 1},
+		},
+	}}
+
+	got := NewMixedLocaleSpellings([]*checker.CheckResult{result}, locations)
+	want := []MixedLocaleSpelling{{URL: result.OriginalURL, Spelling: legacy, File: "docs/a.md", Line: 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NewMixedLocaleSpellings() = %+v, want %+v", got, want)
+	}
+}
+
+func TestNewResult_AsOf(t *testing.T) {
+	result := *sampleResults()[0]
+	result.AsOf = "4.19"
+
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, NewResult(&result)); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"as_of": "4.19"`) {
+		t.Errorf("WriteJSON() = %s, want the as-of version", buf.String())
+	}
+}
+
+func TestNewResult_Broken(t *testing.T) {
+	tests := []struct {
+		name                   string
+		result                 checker.CheckResult
+		wantExists, wantAnchor string
+	}{
+		{
+			name:       "page missing",
+			result:     checker.CheckResult{OriginalURL: docsBase + "4.16/html/networking/ingres#ingress", Broken: true},
+			wantExists: "false",
+		},
+		{
+			name:       "anchor missing",
+			result:     checker.CheckResult{OriginalURL: docsBase + "4.16/html/networking/ingress#ingress-removed", OriginalExists: true, Broken: true},
+			wantExists: "true",
+			wantAnchor: "false",
+		},
+		{
+			name:       "page without anchor",
+			result:     checker.CheckResult{OriginalURL: docsBase + "4.16/html/networking/ingress", OriginalExists: true},
+			wantExists: "true",
+		},
+		{
+			name:   "not verified",
+			result: checker.CheckResult{OriginalURL: docsBase + "4.16/html/networking/ingress#ingress"},
+		},
+	}
+
+	str := func(b *bool) string {
+		if b == nil {
+			return ""
+		}
+		return fmt.Sprint(*b)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewResult(&tt.result)
+			if r.Broken != tt.result.Broken || str(r.OriginalExists) != tt.wantExists || str(r.OriginalAnchorExists) != tt.wantAnchor {
+				t.Errorf("broken, original_exists, original_anchor_exists = %v, %q, %q; want %v, %q, %q",
+					r.Broken, str(r.OriginalExists), str(r.OriginalAnchorExists), tt.result.Broken, tt.wantExists, tt.wantAnchor)
+			}
+		})
+	}
+
+	b := NewBatch([]*checker.CheckResult{&tests[0].result, sampleResults()[0], sampleResults()[1]}, nil, scanner.Stats{})
+	if b.BrokenCount != 1 || b.OutdatedCount != 1 || b.UptodateCount != 1 {
+		t.Errorf("NewBatch() counts = %d broken, %d outdated, %d up to date; want 1 each", b.BrokenCount, b.OutdatedCount, b.UptodateCount)
+	}
+}
+
+func TestNewCheckedVersion_Indeterminate(t *testing.T) {
+	v := checker.VersionCheckResult{Version: "4.19", URL: docsBase + "4.19/html-single/networking/index#ingress-sharding", Exists: true, HasAnchor: true, AnchorIndeterminate: true, ScannedBytes: 2 << 20, StatusCode: 200, Attempts: 1}
+
+	got := newCheckedVersion(v)
+	if got.AnchorExists != nil || !got.AnchorIndeterminate || got.ScannedBytes != 2<<20 {
+		t.Errorf("newCheckedVersion() = %+v, want an indeterminate anchor with the bytes scanned", got)
+	}
+}
+
+func TestNewCheckedVersion_SuggestedAnchors(t *testing.T) {
+	suggested := []string{"configuring-ingress_networking"}
+	v := checker.VersionCheckResult{Version: "4.19", URL: docsBase + "4.19/html/networking/ingress#configuring-ingress", Exists: true, HasAnchor: true, SuggestedAnchors: suggested, StatusCode: 200, Attempts: 1}
+
+	got := newCheckedVersion(v)
+	if got.AnchorExists == nil || *got.AnchorExists || !reflect.DeepEqual(got.SuggestedAnchors, suggested) {
+		t.Errorf("newCheckedVersion() = %+v, want a missing anchor with its suggestions", got)
+	}
+}
+
+// JSON output, the upgrade effort and -fix must all pick the same target
+func TestNewResult_BestSuggestionMatchesFix(t *testing.T) {
+	results := append(sampleResults(), effortResults()...)
+
+	for _, result := range results {
+		r := NewResult(result)
+		if len(r.NewerVersions) != len(result.NewerVersions) {
+			t.Errorf("%s: JSON lists %d newer versions, want all %d", result.OriginalURL, len(r.NewerVersions), len(result.NewerVersions))
+		}
+
+		replacement, fixable := fixer.ReplacementFor(result)
+		if !fixable {
+			if r.BestSuggestion != nil {
+				t.Errorf("%s: best_suggestion %s for a URL -fix leaves alone", result.OriginalURL, r.BestSuggestion.Version)
+			}
+			continue
+		}
+
+		if r.BestSuggestion == nil || r.BestSuggestion.URL != replacement.NewURL || r.BestSuggestion.Version != replacement.NewVersion {
+			t.Errorf("%s: best_suggestion = %+v, -fix uses %s (%s)", result.OriginalURL, r.BestSuggestion, replacement.NewVersion, replacement.NewURL)
+		}
+		if efforts := NewUpgradeEffort([]*checker.CheckResult{result}); len(efforts) == 1 && efforts[0].To != replacement.NewVersion {
+			t.Errorf("%s: upgrade effort targets %s, -fix uses %s", result.OriginalURL, efforts[0].To, replacement.NewVersion)
+		}
+	}
+}
+
+func TestNewResult_Redirects(t *testing.T) {
+	landing := "https://docs.redhat.com/en/documentation/openshift_container_platform/4.18"
+	moved := docsBase + "4.19/html/networking/ingress-traffic"
+	result := &checker.CheckResult{
+		OriginalURL:          docsBase + "4.17/html/networking/ingress",
+		OriginalVersion:      "4.17",
+		LatestVersion:        "4.19",
+		IsOutdated:           true,
+		Broken:               true,
+		OriginalRedirectedTo: landing,
+		AllResults: []checker.VersionCheckResult{
+			{Version: "4.18", URL: docsBase + "4.18/html/networking/ingress", RedirectedTo: landing, StatusCode: 200},
+			{Version: "4.19", URL: docsBase + "4.19/html/networking/ingress", Exists: true, RedirectedTo: moved, StatusCode: 200},
+		},
+	}
+	result.NewerVersions = result.AllResults[1:]
+
+	r := NewResult(result)
+	if r.OriginalRedirectedTo != landing {
+		t.Errorf("original_redirected_to = %q, want %q", r.OriginalRedirectedTo, landing)
+	}
+	if got := r.CheckedVersions; len(got) != 2 || got[0].Exists || got[0].RedirectedTo != landing || !got[1].Exists || got[1].RedirectedTo != moved {
+		t.Errorf("checked_versions = %+v, want 4.18 missing, redirected to the landing page, and 4.19 redirected to %s", got, moved)
+	}
+	if r.BestSuggestion == nil || r.BestSuggestion.RedirectedTo != moved || r.NewerVersions[0].RedirectedTo != moved {
+		t.Errorf("best_suggestion = %+v, newer_versions = %+v; want both redirected to %s", r.BestSuggestion, r.NewerVersions, moved)
+	}
+}
@@ -0,0 +1,84 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteLegacyJSON writes a single result in the hand-written JSON format
+// used before the output moved to encoding/json. Only the original fields
+// are written and strings are not escaped, exactly as before.
+//
+// Deprecated: kept for one release for scripts that parse the old layout
+// textually; use WriteJSON.
+func WriteLegacyJSON(w io.Writer, result Result) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, `{
+  "original_url": "%s",
+  "original_version": "%s",
+  "latest_version": "%s",
+  "is_outdated": %t,
+  "newer_versions": [
+`, result.OriginalURL, result.OriginalVersion, result.LatestVersion, result.IsOutdated)
+
+	for i, v := range result.NewerVersions {
+		comma := ","
+		if i == len(result.NewerVersions)-1 {
+			comma = ""
+		}
+		fmt.Fprintf(&b, "    {\"version\": \"%s\", \"url\": \"%s\"}%s\n", v.Version, v.URL, comma)
+	}
+
+	b.WriteString("  ]\n}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteLegacyBatchJSON writes a directory scan in the hand-written JSON
+// format used before the output moved to encoding/json. Placeholders and
+// scan statistics are not part of that format and are left out.
+//
+// Deprecated: kept for one release for scripts that parse the old layout
+// textually; use WriteJSON.
+func WriteLegacyBatchJSON(w io.Writer, batch Batch) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, `{
+  "total_count": %d,
+  "uptodate_count": %d,
+  "outdated_count": %d,
+  "results": [
+`, batch.TotalCount, batch.UptodateCount, batch.OutdatedCount)
+
+	for i, result := range batch.Results {
+		comma := ","
+		if i == len(batch.Results)-1 {
+			comma = ""
+		}
+
+		fmt.Fprintf(&b, `    {
+      "original_url": "%s",
+      "original_version": "%s",
+      "latest_version": "%s",
+      "is_outdated": %t,
+      "newer_versions": [`, result.OriginalURL, result.OriginalVersion, result.LatestVersion, result.IsOutdated)
+
+		for j, v := range result.NewerVersions {
+			versionComma := ","
+			if j == len(result.NewerVersions)-1 {
+				versionComma = ""
+			}
+			fmt.Fprintf(&b, "\n        {\"version\": \"%s\", \"url\": \"%s\"}%s", v.Version, v.URL, versionComma)
+		}
+
+		fmt.Fprintf(&b, "\n      ]\n    }%s\n", comma)
+	}
+
+	b.WriteString("  ]\n}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
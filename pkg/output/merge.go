@@ -0,0 +1,166 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ReadBatchFile reads a JSON report written by a directory scan
+func ReadBatchFile(path string) (Batch, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Batch{}, err
+	}
+	var b Batch
+	if err := json.Unmarshal(data, &b); err != nil {
+		return Batch{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return b, nil
+}
+
+// MergeShards merges the partial reports of every shard of a run into the
+// report of the complete run. It fails unless there is exactly one report
+// per shard. The merged report fails when any shard failed.
+//
+// Every shard scans the same tree, so the scan statistics and aliases are
+// taken from the first report. Shards checked as of different versions do
+// not merge. Hotspots are summed per file, but a file
+// whose outdated URLs reach the threshold only across shards is not one.
+func MergeShards(batches []Batch) (Batch, error) {
+	if len(batches) == 0 {
+		return Batch{}, fmt.Errorf("no reports to merge")
+	}
+
+	count := 0
+	seen := make(map[int]bool)
+	for i, b := range batches {
+		if b.Shard == nil {
+			return Batch{}, fmt.Errorf("report %d is not the report of a -shard run", i+1)
+		}
+		if count == 0 {
+			count = b.Shard.Count
+		}
+		if b.Shard.Count != count {
+			return Batch{}, fmt.Errorf("shard %d/%d does not belong to a run of %d shards", b.Shard.Index, b.Shard.Count, count)
+		}
+		if b.AsOf != batches[0].AsOf {
+			return Batch{}, fmt.Errorf("shard %d/%d was checked as of %q, shard %d/%d as of %q", b.Shard.Index, b.Shard.Count, b.AsOf, batches[0].Shard.Index, batches[0].Shard.Count, batches[0].AsOf)
+		}
+		if seen[b.Shard.Index] {
+			return Batch{}, fmt.Errorf("shard %d/%d is given more than once", b.Shard.Index, count)
+		}
+		seen[b.Shard.Index] = true
+	}
+	var missing []string
+	for i := 1; i <= count; i++ {
+		if !seen[i] {
+			missing = append(missing, fmt.Sprintf("%d/%d", i, count))
+		}
+	}
+	if len(missing) > 0 {
+		return Batch{}, fmt.Errorf("missing shard(s) %s", strings.Join(missing, ", "))
+	}
+
+	batches = append([]Batch(nil), batches...)
+	sort.SliceStable(batches, func(i, j int) bool {
+		return batches[i].Shard.Index < batches[j].Shard.Index
+	})
+
+	merged := Batch{
+		ScannedFileCount: batches[0].ScannedFileCount,
+		ScanStats:        batches[0].ScanStats,
+		ResolvedAliases:  batches[0].ResolvedAliases,
+		AsOf:             batches[0].AsOf,
+		MergedShards:     count,
+		Results:          []Result{},
+	}
+	var efforts [][]UpgradeEffort
+	var hotspots [][]Hotspot
+	for _, b := range batches {
+		merged.TotalCount += b.TotalCount
+		merged.UptodateCount += b.UptodateCount
+		merged.OutdatedCount += b.OutdatedCount
+		merged.BrokenCount += b.BrokenCount
+		merged.Failed = merged.Failed || b.Failed
+		if b.RunID != "" {
+			merged.MergedRunIDs = append(merged.MergedRunIDs, b.RunID)
+		}
+		merged.UnresolvedPlaceholders = append(merged.UnresolvedPlaceholders, b.UnresolvedPlaceholders...)
+		merged.EncodedOccurrences = append(merged.EncodedOccurrences, b.EncodedOccurrences...)
+		merged.SuspiciousHosts = append(merged.SuspiciousHosts, b.SuspiciousHosts...)
+		merged.HistoricalReferences = append(merged.HistoricalReferences, b.HistoricalReferences...)
+		merged.WrongProductLinks = append(merged.WrongProductLinks, b.WrongProductLinks...)
+		merged.NotChecked = append(merged.NotChecked, b.NotChecked...)
+		merged.SameSection = append(merged.SameSection, b.SameSection...)
+		merged.MixedLocaleSpellings = append(merged.MixedLocaleSpellings, b.MixedLocaleSpellings...)
+		merged.Results = append(merged.Results, b.Results...)
+		efforts = append(efforts, b.UpgradeEffort)
+		hotspots = append(hotspots, b.Hotspots)
+	}
+	merged.UpgradeEffort = mergeUpgradeEffort(efforts)
+	merged.Hotspots = mergeHotspots(hotspots)
+
+	return merged, nil
+}
+
+// mergeUpgradeEffort sums the upgrade effort of each (from, to) pair
+func mergeUpgradeEffort(parts [][]UpgradeEffort) []UpgradeEffort {
+	var efforts []UpgradeEffort
+	index := make(map[[2]string]int)
+	for _, part := range parts {
+		for _, e := range part {
+			key := [2]string{e.From, e.To}
+			i, ok := index[key]
+			if !ok {
+				i = len(efforts)
+				index[key] = i
+				efforts = append(efforts, UpgradeEffort{From: e.From, To: e.To})
+			}
+			efforts[i].URLs += e.URLs
+			efforts[i].Fixable += e.Fixable
+			efforts[i].NeedsAnchorWork += e.NeedsAnchorWork
+			efforts[i].Blocked += e.Blocked
+		}
+	}
+
+	sort.SliceStable(efforts, func(i, j int) bool {
+		if efforts[i].From != efforts[j].From {
+			return versionLess(efforts[i].From, efforts[j].From)
+		}
+		return versionLess(efforts[i].To, efforts[j].To)
+	})
+
+	return efforts
+}
+
+// mergeHotspots sums the hotspots of each file, most outdated first
+func mergeHotspots(parts [][]Hotspot) []Hotspot {
+	var hotspots []Hotspot
+	index := make(map[string]int)
+	for _, part := range parts {
+		for _, h := range part {
+			i, ok := index[h.File]
+			if !ok {
+				i = len(hotspots)
+				index[h.File] = i
+				hotspots = append(hotspots, Hotspot{File: h.File, Findings: make(map[string]int), Command: h.Command})
+			}
+			hotspots[i].Outdated += h.Outdated
+			for severity, n := range h.Findings {
+				hotspots[i].Findings[severity] += n
+			}
+		}
+	}
+
+	sort.SliceStable(hotspots, func(i, j int) bool {
+		if hotspots[i].Outdated != hotspots[j].Outdated {
+			return hotspots[i].Outdated > hotspots[j].Outdated
+		}
+		return hotspots[i].File < hotspots[j].File
+	})
+
+	return hotspots
+}
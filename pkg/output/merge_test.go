@@ -0,0 +1,114 @@
+package output
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// shardBatch builds the partial report of shard index of count
+func shardBatch(index, count int, failed bool, urls ...string) Batch {
+	b := Batch{
+		ScannedFileCount: 3,
+		Shard:            &Shard{Index: index, Count: count},
+		Failed:           failed,
+		Results:          []Result{},
+	}
+	for _, url := range urls {
+		b.TotalCount++
+		b.OutdatedCount++
+		b.Results = append(b.Results, Result{OriginalURL: url, IsOutdated: true})
+	}
+	return b
+}
+
+func TestMergeShards(t *testing.T) {
+	batches := []Batch{
+		shardBatch(2, 3, true, "b"),
+		shardBatch(1, 3, false, "a", "c"),
+		shardBatch(3, 3, false),
+	}
+	batches[0].UpgradeEffort = []UpgradeEffort{{From: "4.16", To: "4.20", URLs: 1, Fixable: 1}}
+	batches[1].UpgradeEffort = []UpgradeEffort{{From: "4.16", To: "4.20", URLs: 2, Fixable: 1, Blocked: 1}, {From: "4.9", To: "4.20", URLs: 1, Fixable: 1}}
+	batches[0].Hotspots = []Hotspot{{File: "x.md", Outdated: 5, Findings: map[string]int{"warning": 5}}}
+	batches[1].Hotspots = []Hotspot{{File: "x.md", Outdated: 5, Findings: map[string]int{"warning": 6}}, {File: "y.md", Outdated: 7, Findings: map[string]int{"warning": 7}}}
+	batches[0].MixedLocaleSpellings = []MixedLocaleSpelling{{URL: "b", Spelling: "b-without-locale", File: "x.md", Line: 2}}
+	batches[0].RunID, batches[1].RunID = "run-b", "run-a"
+
+	merged, err := MergeShards(batches)
+	if err != nil {
+		t.Fatalf("MergeShards() error = %v", err)
+	}
+
+	if merged.TotalCount != 3 || merged.OutdatedCount != 3 || merged.ScannedFileCount != 3 || merged.MergedShards != 3 || merged.Shard != nil {
+		t.Errorf("MergeShards() counts = %+v", merged)
+	}
+	if !merged.Failed {
+		t.Error("MergeShards() did not fail although shard 2/3 failed")
+	}
+	// The merging run sets its own ID; a report without one is skipped
+	if want := []string{"run-a", "run-b"}; merged.RunID != "" || !reflect.DeepEqual(merged.MergedRunIDs, want) {
+		t.Errorf("MergeShards() run IDs = %q, %q; want none, %q", merged.RunID, merged.MergedRunIDs, want)
+	}
+	var urls []string
+	for _, r := range merged.Results {
+		urls = append(urls, r.OriginalURL)
+	}
+	if want := []string{"a", "c", "b"}; !reflect.DeepEqual(urls, want) {
+		t.Errorf("MergeShards() results = %v, want %v in shard order", urls, want)
+	}
+	wantEffort := []UpgradeEffort{
+		{From: "4.9", To: "4.20", URLs: 1, Fixable: 1},
+		{From: "4.16", To: "4.20", URLs: 3, Fixable: 2, Blocked: 1},
+	}
+	if !reflect.DeepEqual(merged.UpgradeEffort, wantEffort) {
+		t.Errorf("MergeShards() upgrade effort = %+v, want %+v", merged.UpgradeEffort, wantEffort)
+	}
+	wantHotspots := []Hotspot{
+		{File: "x.md", Outdated: 10, Findings: map[string]int{"warning": 11}},
+		{File: "y.md", Outdated: 7, Findings: map[string]int{"warning": 7}},
+	}
+	if !reflect.DeepEqual(merged.Hotspots, wantHotspots) {
+		t.Errorf("MergeShards() hotspots = %+v, want %+v", merged.Hotspots, wantHotspots)
+	}
+	if len(merged.MixedLocaleSpellings) != 1 || merged.MixedLocaleSpellings[0].URL != "b" {
+		t.Errorf("MergeShards() mixed locale spellings = %+v, want the one of shard 2/3", merged.MixedLocaleSpellings)
+	}
+	if batches[0].Shard.Index != 2 {
+		t.Error("MergeShards() reordered its argument")
+	}
+}
+
+func TestMergeShards_Passing(t *testing.T) {
+	merged, err := MergeShards([]Batch{shardBatch(1, 2, false), shardBatch(2, 2, false)})
+	if err != nil || merged.Failed {
+		t.Errorf("MergeShards() = failed %v, %v, want a passing run", merged.Failed, err)
+	}
+}
+
+func TestMergeShards_Invalid(t *testing.T) {
+	capped := shardBatch(2, 2, false)
+	capped.AsOf = "4.16"
+
+	tests := []struct {
+		name    string
+		batches []Batch
+		wantErr string
+	}{
+		{"no reports", nil, "no reports"},
+		{"missing shard", []Batch{shardBatch(1, 4, false), shardBatch(3, 4, false)}, "missing shard(s) 2/4, 4/4"},
+		{"duplicate shard", []Batch{shardBatch(1, 2, false), shardBatch(1, 2, false)}, "shard 1/2 is given more than once"},
+		{"different shard counts", []Batch{shardBatch(1, 2, false), shardBatch(2, 3, false)}, "shard 2/3 does not belong to a run of 2 shards"},
+		{"not a shard report", []Batch{shardBatch(1, 2, false), {}}, "report 2 is not the report of a -shard run"},
+		{"different as-of versions", []Batch{shardBatch(1, 2, false), capped}, `shard 2/2 was checked as of "4.16"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := MergeShards(tt.batches)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("MergeShards() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/checker"
+	"github.com/sebrandon1/ocp-doc-checker/pkg/parser"
+)
+
+// Metrics are the totals of a run, written for the node-exporter textfile
+// collector
+type Metrics struct {
+	// Root is the scanned path, added to every sample as a root label when
+	// set
+	Root string
+	// RunID identifies the run, written as the run_id label of the
+	// ocpdoc_run_info sample when set rather than on every sample, so that
+	// each run does not start new series of every gauge
+	RunID        string
+	URLs         int
+	Outdated     int
+	Broken       int
+	CheckErrors  int
+	Duration     time.Duration
+	HTTPRequests int64
+}
+
+// NewMetrics counts the checked URLs of a run. Broken URLs are the ones
+// with a malformed fragment, which can never resolve, or whose page or
+// anchor is missing at their own version; URLs that could not be checked
+// are counted by the caller in CheckErrors.
+func NewMetrics(root string, results []*checker.CheckResult) Metrics {
+	m := Metrics{Root: root, URLs: len(results)}
+	for _, result := range results {
+		if result.IsOutdated {
+			m.Outdated++
+		}
+		if result.FragmentIssue == parser.FragmentMalformed || result.Broken {
+			m.Broken++
+		}
+	}
+	return m
+}
+
+// metric is a single gauge of the exposition
+type metric struct {
+	name  string
+	help  string
+	value string
+}
+
+func (m Metrics) metrics() []metric {
+	count := func(n int) string { return strconv.Itoa(n) }
+	return []metric{
+		{"ocpdoc_urls_total", "Unique documentation URLs checked.", count(m.URLs)},
+		{"ocpdoc_urls_outdated", "Checked URLs with a newer version available.", count(m.Outdated)},
+		{"ocpdoc_urls_broken", "Checked URLs that do not resolve, e.g. because of a malformed fragment or a missing page.", count(m.Broken)},
+		{"ocpdoc_check_errors", "URLs that could not be checked.", count(m.CheckErrors)},
+		{"ocpdoc_run_duration_seconds", "Duration of the run in seconds.", strconv.FormatFloat(m.Duration.Seconds(), 'f', -1, 64)},
+		{"ocpdoc_http_requests_total", "HTTP requests sent to the documentation site, including retries.", strconv.FormatInt(m.HTTPRequests, 10)},
+	}
+}
+
+// labelEscaper escapes label values as the exposition format requires
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WriteMetrics writes m as gauges in the OpenMetrics text format, which the
+// Prometheus text parser of the textfile collector also accepts
+func WriteMetrics(w io.Writer, m Metrics) error {
+	labels := ""
+	if m.Root != "" {
+		labels = `{root="` + labelEscaper.Replace(m.Root) + `"}`
+	}
+
+	for _, metric := range m.metrics() {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s%s %s\n",
+			metric.name, metric.help, metric.name, metric.name, labels, metric.value); err != nil {
+			return err
+		}
+	}
+
+	if m.RunID != "" {
+		infoLabels := `{run_id="` + labelEscaper.Replace(m.RunID) + `"`
+		if m.Root != "" {
+			infoLabels += `,root="` + labelEscaper.Replace(m.Root) + `"`
+		}
+		if _, err := fmt.Fprintf(w, "# HELP ocpdoc_run_info Run that wrote the metrics, identified by its run_id label.\n# TYPE ocpdoc_run_info gauge\nocpdoc_run_info%s} 1\n", infoLabels); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintln(w, "# EOF")
+	return err
+}
+
+// WriteMetricsFile writes m to path atomically: the metrics are written to
+// a temporary file in the same directory and renamed over path, so a
+// collector reading concurrently never sees a partial file
+func WriteMetricsFile(path string, m Metrics) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := WriteMetrics(tmp, m); err != nil {
+		tmp.Close()
+		return err
+	}
+	// CreateTemp uses 0600, but the collector usually runs as another user
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,198 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	metricNameRe  = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNameRe   = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	metricTypes   = map[string]bool{"counter": true, "gauge": true, "histogram": true, "gaugehistogram": true, "stateset": true, "info": true, "summary": true, "unknown": true}
+	sampleLabelRe = regexp.MustCompile(`^([a-zA-Z_][a-zA-Z0-9_]*)="((?:[^"\\\n]|\\[\\"n])*)"`)
+)
+
+// parseExposition checks text against the OpenMetrics text format rules the
+// writer relies on and returns the sample values by series
+func parseExposition(text string) (map[string]float64, error) {
+	if !strings.HasSuffix(text, "# EOF\n") {
+		return nil, fmt.Errorf("exposition does not end with # EOF")
+	}
+
+	samples := make(map[string]float64)
+	typed := make(map[string]bool)
+	helped := make(map[string]bool)
+	family := ""
+
+	sc := bufio.NewScanner(strings.NewReader(strings.TrimSuffix(text, "# EOF\n")))
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+
+		if rest, ok := strings.CutPrefix(line, "# "); ok {
+			kind, rest, _ := strings.Cut(rest, " ")
+			name, value, _ := strings.Cut(rest, " ")
+			if !metricNameRe.MatchString(name) {
+				return nil, fmt.Errorf("line %d: invalid metric name %q", n, name)
+			}
+			if name != family {
+				if typed[name] || helped[name] {
+					return nil, fmt.Errorf("line %d: metric family %s is not contiguous", n, name)
+				}
+				family = name
+			}
+			switch kind {
+			case "HELP":
+				if helped[name] {
+					return nil, fmt.Errorf("line %d: second HELP for %s", n, name)
+				}
+				helped[name] = true
+			case "TYPE":
+				if typed[name] || !metricTypes[value] {
+					return nil, fmt.Errorf("line %d: invalid or second TYPE for %s", n, name)
+				}
+				typed[name] = true
+			default:
+				return nil, fmt.Errorf("line %d: unknown descriptor %q", n, kind)
+			}
+			continue
+		}
+
+		end := strings.IndexAny(line, "{ ")
+		if end < 0 {
+			return nil, fmt.Errorf("line %d: sample without value", n)
+		}
+		name := line[:end]
+		if name != family || !typed[name] {
+			return nil, fmt.Errorf("line %d: sample %s outside its metric family", n, name)
+		}
+
+		series, rest := name, line[end:]
+		if strings.HasPrefix(rest, "{") {
+			rest = rest[1:]
+			for !strings.HasPrefix(rest, "}") {
+				m := sampleLabelRe.FindStringSubmatch(rest)
+				if m == nil || !labelNameRe.MatchString(m[1]) {
+					return nil, fmt.Errorf("line %d: invalid label in %q", n, rest)
+				}
+				rest = strings.TrimPrefix(rest[len(m[0]):], ",")
+			}
+			series, rest = line[:len(line)-len(rest)+1], rest[1:]
+		}
+
+		value, ok := strings.CutPrefix(rest, " ")
+		if !ok {
+			return nil, fmt.Errorf("line %d: missing space before the value", n)
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid value %q", n, value)
+		}
+		if _, dup := samples[series]; dup {
+			return nil, fmt.Errorf("line %d: duplicate series %s", n, series)
+		}
+		samples[series] = v
+	}
+
+	return samples, sc.Err()
+}
+
+func TestWriteMetrics(t *testing.T) {
+	m := Metrics{
+		URLs:         12,
+		Outdated:     3,
+		Broken:       1,
+		CheckErrors:  2,
+		Duration:     1500 * time.Millisecond,
+		HTTPRequests: 40,
+	}
+
+	tests := []struct {
+		name        string
+		root, runID string
+		series      string
+		info        string // the ocpdoc_run_info series, when written
+	}{
+		{"without root", "", "", "ocpdoc_urls_outdated", ""},
+		{"with root", `docs/"odd"\dir`, "", `ocpdoc_urls_outdated{root="docs/\"odd\"\\dir"}`, ""},
+		{"with run ID", "docs", "20261016T093000Z-5f0c2a9e", `ocpdoc_urls_outdated{root="docs"}`, `ocpdoc_run_info{run_id="20261016T093000Z-5f0c2a9e",root="docs"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m.Root, m.RunID = tt.root, tt.runID
+
+			var buf bytes.Buffer
+			if err := WriteMetrics(&buf, m); err != nil {
+				t.Fatal(err)
+			}
+
+			samples, err := parseExposition(buf.String())
+			if err != nil {
+				t.Fatalf("invalid exposition: %v\n%s", err, buf.String())
+			}
+			want := 6
+			if tt.info != "" {
+				want++
+				if got, ok := samples[tt.info]; !ok || got != 1 {
+					t.Errorf("%s = %v, want 1", tt.info, got)
+				}
+			}
+			if len(samples) != want {
+				t.Errorf("exposition has %d samples, want %d", len(samples), want)
+			}
+			if got := samples[tt.series]; got != 3 {
+				t.Errorf("%s = %v, want 3", tt.series, got)
+			}
+		})
+	}
+}
+
+func TestWriteMetricsFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ocpdoc.prom")
+	if err := os.WriteFile(path, []byte("stale"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteMetricsFile(path, Metrics{Root: "docs", URLs: 1}); err != nil {
+		t.Fatalf("WriteMetricsFile() error = %v", err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseExposition(string(got)); err != nil {
+		t.Errorf("invalid exposition: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d files after the rename, want 1", len(entries))
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("metrics file mode = %v, want 0644", info.Mode().Perm())
+	}
+}
+
+func TestWriteMetricsFile_MissingDirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "ocpdoc.prom")
+	if err := WriteMetricsFile(path, Metrics{}); err == nil {
+		t.Error("WriteMetricsFile() into a missing directory succeeded")
+	}
+}
@@ -0,0 +1,157 @@
+package output
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// prCommentMarkerPrefix starts the hidden marker of a PR comment; the scope
+// hash and " -->" follow it
+const prCommentMarkerPrefix = "<!-- ocp-doc-checker:pr-comment scope="
+
+// PRCommentMaxFindings is how many findings a PR comment lists at most,
+// keeping it well below the size a comment may have
+const PRCommentMaxFindings = 100
+
+// PRComment is the Markdown comment a bot upserts on a pull request: it
+// finds its previous comment by the marker of Scope and replaces it
+type PRComment struct {
+	// Scope is the scope hash of the run, see ScopeHash
+	Scope string
+	// Checked is the number of unique URLs checked
+	Checked int
+	// Findings are the actionable findings, one per occurrence
+	Findings []Finding
+	// Failed is set when the run exits 1, which may also be for URLs left
+	// not checked by the soft deadline
+	Failed bool
+	// LinkTemplate turns a finding's location into a link, replacing
+	// {path} and {line}; without it locations are not linked
+	LinkTemplate string
+	// The counts of the footer, of URLs the findings leave out
+	Preexisting int
+	Generated   int
+	Historical  int
+	NotChecked  int
+	RunID       string
+}
+
+// ScopeHash returns a short hash of the parts that scope a run, such as
+// the scanned directory and the -git-diff revision. It must not change
+// between pushes to a pull request, so that each push's comment replaces
+// the previous one.
+func ScopeHash(parts ...string) string {
+	h := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(h[:8])
+}
+
+// PRCommentMarker returns the hidden HTML marker that starts the comment of
+// scope, which a bot searches its previous comment for
+func PRCommentMarker(scope string) string {
+	return prCommentMarkerPrefix + scope + " -->"
+}
+
+// ValidateLinkTemplate reports an error unless template has a {path}
+// placeholder
+func ValidateLinkTemplate(template string) error {
+	if !strings.Contains(template, "{path}") {
+		return fmt.Errorf("link template %q has no {path} placeholder", template)
+	}
+	return nil
+}
+
+// WritePRComment writes c as Markdown: the marker, a status header, the
+// findings in a collapsible section, and a footer with the URLs not listed
+func WritePRComment(w io.Writer, c PRComment) error {
+	var b strings.Builder
+	fmt.Fprintln(&b, PRCommentMarker(c.Scope))
+
+	sorted := make([]Finding, len(c.Findings))
+	copy(sorted, c.Findings)
+	SortFindings(sorted)
+	files := 0
+	for i, f := range sorted {
+		if i == 0 || sorted[i-1].Path != f.Path {
+			files++
+		}
+	}
+
+	switch {
+	case len(sorted) > 0:
+		fmt.Fprintf(&b, "### ❌ OCP documentation links: %d finding(s) in %d file(s)\n", len(sorted), files)
+	case c.Failed:
+		fmt.Fprintln(&b, "### ⚠️ OCP documentation links: check incomplete")
+	default:
+		fmt.Fprintln(&b, "### ✅ OCP documentation links: no findings")
+	}
+	fmt.Fprintf(&b, "\n%d unique URL(s) checked.\n", c.Checked)
+
+	if len(sorted) > 0 {
+		fmt.Fprint(&b, "\n<details>\n<summary>Findings</summary>\n\n")
+		for _, f := range sorted[:min(len(sorted), PRCommentMaxFindings)] {
+			fmt.Fprintf(&b, "- %s **%s**: %s\n", c.location(f), f.Status, markdownEscaper.Replace(f.Message))
+		}
+		if len(sorted) > PRCommentMaxFindings {
+			fmt.Fprintf(&b, "- … and %d more\n", len(sorted)-PRCommentMaxFindings)
+		}
+		fmt.Fprint(&b, "\n</details>\n")
+	}
+
+	var notes []string
+	if c.Preexisting > 0 {
+		notes = append(notes, fmt.Sprintf("%d pre-existing", c.Preexisting))
+	}
+	if c.Generated > 0 {
+		notes = append(notes, fmt.Sprintf("%d in generated files", c.Generated))
+	}
+	if c.NotChecked > 0 {
+		notes = append(notes, fmt.Sprintf("%d past the soft deadline", c.NotChecked))
+	}
+	var footer []string
+	if len(notes) > 0 {
+		footer = append(footer, "Not checked: "+strings.Join(notes, ", ")+".")
+	}
+	if c.Historical > 0 {
+		footer = append(footer, fmt.Sprintf("%d historical reference(s) not listed.", c.Historical))
+	}
+	if c.RunID != "" {
+		footer = append(footer, "Run "+c.RunID+".")
+	}
+	if len(footer) > 0 {
+		fmt.Fprintf(&b, "\n<sub>%s</sub>\n", strings.Join(footer, " "))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// location renders the path:line of f, linked when c has a link template
+func (c PRComment) location(f Finding) string {
+	label := fmt.Sprintf("`%s:%d`", f.Path, f.Line)
+	if c.LinkTemplate == "" {
+		return label
+	}
+	// Links are relative to the repository, which the paths of a scan of a
+	// relative -dir from its root are
+	segments := strings.Split(strings.TrimPrefix(path.Clean(filepath.ToSlash(f.Path)), "/"), "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	link := strings.NewReplacer(
+		"{path}", strings.Join(segments, "/"),
+		"{line}", strconv.Itoa(f.Line),
+	).Replace(c.LinkTemplate)
+	return fmt.Sprintf("[%s](%s)", label, link)
+}
+
+// markdownEscaper keeps finding messages from being read as HTML. Other
+// Markdown is left alone: escaping the underscores of URLs would keep them
+// from being linked.
+var markdownEscaper = strings.NewReplacer("<", "&lt;", ">", "&gt;")
@@ -0,0 +1,137 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPRCommentMarker(t *testing.T) {
+	// A bot matches on this exact format; changing it orphans the comments
+	// of earlier releases
+	want := "<!-- ocp-doc-checker:pr-comment scope=0123456789abcdef -->"
+	if got := PRCommentMarker("0123456789abcdef"); got != want {
+		t.Errorf("PRCommentMarker() = %q, want %q", got, want)
+	}
+}
+
+func TestScopeHash(t *testing.T) {
+	a := ScopeHash("./docs", "origin/main...HEAD", "")
+	if len(a) != 16 {
+		t.Errorf("ScopeHash() = %q, want 16 hex digits", a)
+	}
+	if b := ScopeHash("./docs", "origin/main...HEAD", ""); b != a {
+		t.Errorf("ScopeHash() = %q then %q for the same scope", a, b)
+	}
+	for _, parts := range [][]string{
+		{"./docs", "origin/main", ""},
+		{"./docs", "origin/main...HEAD", "1/2"},
+		// Parts are separated, not just concatenated
+		{"./docs", "origin/main...HEA", "D"},
+	} {
+		if b := ScopeHash(parts...); b == a {
+			t.Errorf("ScopeHash(%q) = %q, same as another scope", parts, b)
+		}
+	}
+}
+
+func TestValidateLinkTemplate(t *testing.T) {
+	if err := ValidateLinkTemplate("https://github.com/org/repo/blob/abc123/{path}#L{line}"); err != nil {
+		t.Errorf("ValidateLinkTemplate() error = %v", err)
+	}
+	if err := ValidateLinkTemplate("https://github.com/org/repo#L{line}"); err == nil {
+		t.Error("ValidateLinkTemplate() without {path} succeeded, want error")
+	}
+}
+
+func TestWritePRComment(t *testing.T) {
+	comment := PRComment{
+		Scope:   ScopeHash("./docs", "origin/main...HEAD", ""),
+		Checked: 7,
+		Findings: []Finding{
+			{
+				Path:    "docs/install.md",
+				Line:    12,
+				Column:  5,
+				Status:  "outdated",
+				Message: "4.17 → 4.20: https://docs.redhat.com/en/documentation/openshift_container_platform/4.20/html/networking/index",
+			},
+			{
+				Path:    "README.md",
+				Line:    3,
+				Column:  1,
+				Status:  "malformed-fragment",
+				Message: "anchor contains whitespace or an extra '#': https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html-single/operators/index#a#b",
+			},
+			{
+				Path:    "docs/getting started.md",
+				Line:    4,
+				Column:  20,
+				Status:  "broken",
+				Message: "page not found at 4.16: https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html/<version>/index",
+			},
+		},
+		Failed:       true,
+		LinkTemplate: "https://github.com/org/repo/blob/0a1b2c3/{path}#L{line}",
+		Preexisting:  4,
+		Generated:    1,
+		Historical:   2,
+		RunID:        "20260102T150405Z-abc123",
+	}
+
+	var buf bytes.Buffer
+	if err := WritePRComment(&buf, comment); err != nil {
+		t.Fatalf("WritePRComment() error = %v", err)
+	}
+	checkGolden(t, "pr-comment.golden", buf.Bytes())
+}
+
+func TestWritePRComment_Clean(t *testing.T) {
+	comment := PRComment{
+		Scope:   ScopeHash("./docs", "origin/main...HEAD", ""),
+		Checked: 3,
+		RunID:   "20260102T150405Z-abc123",
+	}
+
+	var buf bytes.Buffer
+	if err := WritePRComment(&buf, comment); err != nil {
+		t.Fatalf("WritePRComment() error = %v", err)
+	}
+	checkGolden(t, "pr-comment-clean.golden", buf.Bytes())
+}
+
+func TestWritePRComment_Incomplete(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WritePRComment(&buf, PRComment{Scope: "s", Checked: 3, Failed: true, NotChecked: 2}); err != nil {
+		t.Fatalf("WritePRComment() error = %v", err)
+	}
+	got := buf.String()
+	for _, want := range []string{"check incomplete", "Not checked: 2 past the soft deadline."} {
+		if !strings.Contains(got, want) {
+			t.Errorf("WritePRComment() = %q, want it to contain %q", got, want)
+		}
+	}
+}
+
+func TestWritePRComment_ManyFindings(t *testing.T) {
+	var findings []Finding
+	for i := 1; i <= PRCommentMaxFindings+5; i++ {
+		findings = append(findings, Finding{Path: "docs/a.md", Line: i, Status: "outdated", Message: fmt.Sprint(i)})
+	}
+
+	var buf bytes.Buffer
+	if err := WritePRComment(&buf, PRComment{Scope: "s", Findings: findings}); err != nil {
+		t.Fatalf("WritePRComment() error = %v", err)
+	}
+	got := buf.String()
+	if n := strings.Count(got, "\n- `docs/a.md:"); n != PRCommentMaxFindings {
+		t.Errorf("WritePRComment() listed %d findings, want %d", n, PRCommentMaxFindings)
+	}
+	if !strings.Contains(got, "- … and 5 more\n") {
+		t.Errorf("WritePRComment() = %q, want the findings left out counted", got)
+	}
+	if !strings.Contains(got, "105 finding(s) in 1 file(s)") {
+		t.Errorf("WritePRComment() = %q, want every finding counted in the header", got)
+	}
+}
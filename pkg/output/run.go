@@ -0,0 +1,53 @@
+package output
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+	"unicode"
+)
+
+// maxRunIDLength bounds a run ID given with -run-id, which ends up in
+// metric labels and every log record
+const maxRunIDLength = 128
+
+// RunContext identifies a run in every report, log record and file it
+// writes, so that they can be correlated when several runs write to the
+// same places
+type RunContext struct {
+	// ID is unique to the run, or the correlation ID of the pipeline that
+	// started it
+	ID string
+	// Started is when the run started
+	Started time.Time
+}
+
+// NewRunContext returns the context of a run started at started, with a new
+// ID: the start time in UTC and a random suffix, e.g.
+// 20261016T093000Z-5f0c2a9e, so that IDs sort by start time
+func NewRunContext(started time.Time) RunContext {
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return RunContext{
+		ID:      started.UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix),
+		Started: started,
+	}
+}
+
+// ValidateRunID checks that id can stand for a run: not empty, at most 128
+// characters, and without spaces or control characters
+func ValidateRunID(id string) error {
+	if id == "" {
+		return fmt.Errorf("run ID is empty")
+	}
+	if len(id) > maxRunIDLength {
+		return fmt.Errorf("run ID is longer than %d characters", maxRunIDLength)
+	}
+	for _, r := range id {
+		if unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return fmt.Errorf("run ID %q holds a space or control character", id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package output
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewRunContext(t *testing.T) {
+	started := time.Date(2026, 10, 16, 11, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	a, b := NewRunContext(started), NewRunContext(started)
+
+	if !regexp.MustCompile(`^20261016T093000Z-[0-9a-f]{8}$`).MatchString(a.ID) {
+		t.Errorf("ID = %q, want the UTC start time and a random suffix", a.ID)
+	}
+	if a.ID == b.ID {
+		t.Errorf("two runs started at once share the ID %q", a.ID)
+	}
+	if !a.Started.Equal(started) {
+		t.Errorf("Started = %v, want %v", a.Started, started)
+	}
+	if err := ValidateRunID(a.ID); err != nil {
+		t.Errorf("ValidateRunID(%q) error = %v", a.ID, err)
+	}
+}
+
+func TestValidateRunID(t *testing.T) {
+	tests := []struct {
+		id      string
+		wantErr string
+	}{
+		{id: "pipeline-1234"},
+		{id: "3f2c9a6e-7d41-4b8e-9c55-0a1d2e3f4a5b"},
+		{id: "", wantErr: "empty"},
+		{id: "nightly run", wantErr: "space"},
+		{id: "run\n2", wantErr: "control character"},
+		{id: strings.Repeat("x", 129), wantErr: "longer than 128"},
+	}
+	for _, tt := range tests {
+		err := ValidateRunID(tt.id)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("ValidateRunID(%q) error = %v", tt.id, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("ValidateRunID(%q) error = %v, want %q", tt.id, err, tt.wantErr)
+		}
+	}
+}
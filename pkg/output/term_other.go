@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package output
+
+import "os"
+
+// terminalWidth reports that the terminal width is unknown on platforms
+// without TIOCGWINSZ
+func terminalWidth(f *os.File) (int, bool) {
+	return 0, false
+}
@@ -0,0 +1,20 @@
+//go:build linux || darwin
+
+package output
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// terminalWidth returns the number of columns of the terminal f is
+// attached to
+func terminalWidth(f *os.File) (int, bool) {
+	var ws struct{ Row, Col, XPixel, YPixel uint16 }
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&ws)))
+	if errno != 0 || ws.Col == 0 {
+		return 0, false
+	}
+	return int(ws.Col), true
+}
@@ -0,0 +1,102 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultWidth is the text output width when the terminal width is
+	// unknown, e.g. when output goes to a file or a CI log
+	DefaultWidth = 80
+	// MinWidth is the narrowest width text output is rendered at
+	MinWidth = 40
+	// minURLWidth is the fewest columns a URL is shortened to; URLs that
+	// would need to be shorter are printed in full
+	minURLWidth = 24
+)
+
+// ellipsis replaces the middle of shortened URLs
+const ellipsis = "…"
+
+// TerminalWidth returns the width to render text output at: override when
+// it is positive, otherwise the width of the terminal f is attached to, or
+// DefaultWidth when f is not a terminal
+func TerminalWidth(f *os.File, override int) int {
+	if override > 0 {
+		return max(override, MinWidth)
+	}
+	if width, ok := terminalWidth(f); ok {
+		return max(width, MinWidth)
+	}
+	return DefaultWidth
+}
+
+// IsTerminal reports whether f is attached to a terminal, where a line can
+// be redrawn in place
+func IsTerminal(f *os.File) bool {
+	_, ok := terminalWidth(f)
+	return ok
+}
+
+// Text writes human-readable output sized to a width
+type Text struct {
+	W     io.Writer
+	Width int
+	// FullURLs disables shortening URLs, e.g. for verbose output
+	FullURLs bool
+}
+
+// Rule writes a separator line of char across the full width
+func (t *Text) Rule(char string) {
+	fmt.Fprintln(t.W, strings.Repeat(char, t.Width))
+}
+
+// Heading writes title between two "=" rules
+func (t *Text) Heading(title string) {
+	t.Rule("=")
+	fmt.Fprintln(t.W, title)
+	t.Rule("=")
+}
+
+// URLLine writes prefix, url and suffix on one line, shortening the middle
+// of url so that the line fits the width
+func (t *Text) URLLine(prefix, url, suffix string) {
+	fmt.Fprintf(t.W, "%s%s%s\n", prefix, t.FitURL(url, t.Width-columns(prefix)-columns(suffix)), suffix)
+}
+
+// FitURL shortens url to at most width columns by replacing its middle
+// with an ellipsis, keeping the host and the page at the end readable. URLs
+// that fit, or that cannot be shortened to a useful length, are returned
+// unchanged.
+func (t *Text) FitURL(url string, width int) string {
+	if t.FullURLs {
+		return url
+	}
+	return TruncateMiddle(url, width)
+}
+
+// TruncateMiddle shortens s to width columns by replacing its middle with
+// an ellipsis. s is returned unchanged when it fits or when width is below
+// the shortest useful length.
+func TruncateMiddle(s string, width int) string {
+	n := utf8.RuneCountInString(s)
+	if n <= width || width < minURLWidth {
+		return s
+	}
+
+	runes := []rune(s)
+	// Favor the end, which holds the page and anchor
+	head := (width - 1) / 2
+	tail := width - 1 - head
+	return string(runes[:head]) + ellipsis + string(runes[n-tail:])
+}
+
+// columns returns the number of terminal columns s takes, counting every
+// rune as one column
+func columns(s string) int {
+	return utf8.RuneCountInString(s)
+}
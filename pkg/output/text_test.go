@@ -0,0 +1,104 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+	"unicode/utf8"
+)
+
+// renderText writes a representative piece of batch text output
+func renderText(t *Text) {
+	const (
+		oldURL = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.17/html-single/disconnected_environments/index#mirroring-image-set-full"
+		newURL = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.19/html-single/disconnected_environments/index#mirroring-image-set-full"
+		short  = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.19"
+	)
+
+	t.Heading("📋 OCP Documentation URL Check Results")
+	fmt.Fprintln(t.W)
+	fmt.Fprintln(t.W, "[1] ⚠️  OUTDATED")
+	t.URLLine("    URL: ", oldURL, "")
+	t.URLLine("    Latest available: 4.19 (", newURL, ")")
+	t.URLLine("      - Version 4.19: ", short, "")
+	fmt.Fprintln(t.W)
+	t.Rule("=")
+	fmt.Fprintln(t.W, "Summary: 1 total, 0 up-to-date, 1 outdated")
+	t.Rule("=")
+}
+
+func TestText_Widths(t *testing.T) {
+	for _, width := range []int{60, 80, 160} {
+		t.Run(fmt.Sprint(width), func(t *testing.T) {
+			var buf bytes.Buffer
+			renderText(&Text{W: &buf, Width: width})
+			checkGolden(t, fmt.Sprintf("text-width-%d.golden", width), buf.Bytes())
+		})
+	}
+}
+
+func TestText_FullURLs(t *testing.T) {
+	var narrow, wide bytes.Buffer
+	renderText(&Text{W: &narrow, Width: 60, FullURLs: true})
+	renderText(&Text{W: &wide, Width: 1000})
+
+	// Only the rules differ: every URL is printed in full
+	if got, want := bytes.ReplaceAll(narrow.Bytes(), []byte("="), nil), bytes.ReplaceAll(wide.Bytes(), []byte("="), nil); !bytes.Equal(got, want) {
+		t.Errorf("full URLs at width 60 =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTruncateMiddle(t *testing.T) {
+	const url = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.19/html/networking/index"
+
+	tests := []struct {
+		name  string
+		s     string
+		width int
+		want  string
+	}{
+		{"fits", url, len(url), url},
+		{"shortened", url, 41, "https://docs.redhat.…tml/networking/index"},
+		{"too narrow to shorten", url, minURLWidth - 1, url},
+		{"non-ASCII", "https://docs.redhat.com/ja/documentation/ガイド/ネットワーク/索引", 30, "https://docs.r…n/ガイド/ネットワーク/索引"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TruncateMiddle(tt.s, tt.width)
+			if got != tt.want {
+				t.Errorf("TruncateMiddle() = %s, want %s", got, tt.want)
+			}
+			if n := utf8.RuneCountInString(got); n > tt.width && got != tt.s {
+				t.Errorf("TruncateMiddle() is %d runes, want at most %d", n, tt.width)
+			}
+		})
+	}
+}
+
+func TestTerminalWidth(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tests := []struct {
+		name     string
+		override int
+		want     int
+	}{
+		{"not a terminal", 0, DefaultWidth},
+		{"override", 120, 120},
+		{"override below the minimum", 10, MinWidth},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TerminalWidth(f, tt.override); got != tt.want {
+				t.Errorf("TerminalWidth() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package output
+
+import (
+	"io"
+	"strings"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/checker"
+	"github.com/sebrandon1/ocp-doc-checker/pkg/parser"
+	"github.com/sebrandon1/ocp-doc-checker/pkg/scanner"
+)
+
+// TSVRow is one line of -output tsv, describing one URL
+type TSVRow struct {
+	Status         string
+	CurrentVersion string
+	LatestVersion  string
+	URL            string
+	SuggestedURL   string
+	Files          []string
+	// RunID identifies the run that wrote the row
+	RunID string
+}
+
+// TSVColumn is a column of -output tsv
+type TSVColumn struct {
+	Name        string
+	Description string
+	value       func(TSVRow) string
+}
+
+// TSVColumns are the columns of -output tsv, in order. Scripts cut columns
+// by position, so new columns are only ever appended.
+var TSVColumns = []TSVColumn{
+	{"status", "up-to-date, outdated, broken, malformed-fragment, unresolved-placeholder, suspicious-host, not-checked, pre-existing or generated", func(r TSVRow) string { return r.Status }},
+	{"current_version", "version the URL points to", func(r TSVRow) string { return r.CurrentVersion }},
+	{"latest_version", "latest version the page and anchor exist in", func(r TSVRow) string { return r.LatestVersion }},
+	{"url", "URL as found", func(r TSVRow) string { return r.URL }},
+	{"suggested_url", "URL -fix would write, or the normalized spelling of an up-to-date URL", func(r TSVRow) string { return r.SuggestedURL }},
+	{"files", "comma-separated files the URL was found in", func(r TSVRow) string { return strings.Join(r.Files, ",") }},
+	{"run_id", "ID of the run that wrote the line", func(r TSVRow) string { return r.RunID }},
+}
+
+// TSVColumnHelp describes the columns for -help
+func TSVColumnHelp() string {
+	var parts []string
+	for _, col := range TSVColumns {
+		parts = append(parts, col.Name+" ("+col.Description+")")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// NewTSVRow describes a checked URL found at loc; loc is empty for a URL
+// checked with -url
+func NewTSVRow(result *checker.CheckResult, loc scanner.Location) TSVRow {
+	row := TSVRow{
+		Status:         "up-to-date",
+		CurrentVersion: result.OriginalVersion,
+		LatestVersion:  result.LatestVersion,
+		URL:            result.OriginalURL,
+		SuggestedURL:   result.SuggestedURL,
+		Files:          files(loc),
+	}
+	switch {
+	case result.FragmentIssue == parser.FragmentMalformed:
+		row.Status = "malformed-fragment"
+	case result.Broken:
+		row.Status = "broken"
+		if best, ok := result.BestSuggestion(); ok {
+			row.SuggestedURL = best.URL
+		}
+	case result.IsOutdated:
+		row.Status = "outdated"
+		if best, ok := result.BestSuggestion(); ok {
+			row.SuggestedURL = best.URL
+		}
+	}
+	return row
+}
+
+// NewTSVLocationRow describes a URL found at loc that was not checked,
+// e.g. with status "unresolved-placeholder"
+func NewTSVLocationRow(status string, loc scanner.Location) TSVRow {
+	return TSVRow{Status: status, URL: loc.URL, Files: files(loc)}
+}
+
+// files returns the distinct files of a location's occurrences in order
+func files(loc scanner.Location) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, occ := range loc.Occurrences {
+		if !seen[occ.Path] {
+			seen[occ.Path] = true
+			paths = append(paths, occ.Path)
+		}
+	}
+	return paths
+}
+
+// tsvEscaper keeps every field on its line and in its column
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// WriteTSV writes one tab-separated line per row, after a line of column
+// names when header is set. Backslashes, tabs and line breaks inside fields
+// are written as \\, \t, \n and \r.
+func WriteTSV(w io.Writer, rows []TSVRow, header bool) error {
+	var b strings.Builder
+	if header {
+		for i, col := range TSVColumns {
+			if i > 0 {
+				b.WriteByte('\t')
+			}
+			b.WriteString(col.Name)
+		}
+		b.WriteByte('\n')
+	}
+	for _, row := range rows {
+		for i, col := range TSVColumns {
+			if i > 0 {
+				b.WriteByte('\t')
+			}
+			b.WriteString(tsvEscaper.Replace(col.value(row)))
+		}
+		b.WriteByte('\n')
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
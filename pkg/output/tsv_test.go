@@ -0,0 +1,71 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/checker"
+	"github.com/sebrandon1/ocp-doc-checker/pkg/scanner"
+)
+
+// sampleTSVRows covers every status, a URL found in several files and a
+// path holding characters that must be escaped
+func sampleTSVRows() []TSVRow {
+	results := sampleResults()
+	located := func(url string, paths ...string) scanner.Location {
+		loc := scanner.Location{URL: url}
+		for _, path := range paths {
+			loc.Occurrences = append(loc.Occurrences, scanner.Occurrence{URL: url, Path: path})
+		}
+		return loc
+	}
+
+	rows := []TSVRow{
+		NewTSVRow(results[0], located(results[0].OriginalURL, "docs/install.md", "README.md", "docs/install.md")),
+		NewTSVRow(results[1], located(results[1].OriginalURL, "docs/odd\tname\nwith\\breaks.md")),
+		NewTSVRow(&checker.CheckResult{OriginalURL: docsBase + "4.16/html/networking/ingres", OriginalVersion: "4.16", LatestVersion: "4.16", Broken: true}, located(docsBase+"4.16/html/networking/ingres", "docs/networking.md")),
+		NewTSVLocationRow("unresolved-placeholder", samplePlaceholders()[0]),
+		NewTSVLocationRow("not-checked", located(docsBase+"4.18/html/storage/index", "docs/storage.md")),
+	}
+	for i := range rows {
+		rows[i].RunID = "20261016T093000Z-5f0c2a9e"
+	}
+	return rows
+}
+
+func TestWriteTSV(t *testing.T) {
+	tests := []struct {
+		name   string
+		golden string
+		header bool
+	}{
+		{"with header", "tsv.golden", true},
+		{"without header", "tsv-no-header.golden", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteTSV(&buf, sampleTSVRows(), tt.header); err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, tt.golden, buf.Bytes())
+
+			for i, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+				if got := strings.Count(line, "\t") + 1; got != len(TSVColumns) {
+					t.Errorf("line %d has %d fields, want %d: %q", i+1, got, len(TSVColumns), line)
+				}
+			}
+		})
+	}
+}
+
+func TestTSVColumnHelp(t *testing.T) {
+	help := TSVColumnHelp()
+	for _, col := range TSVColumns {
+		if !strings.Contains(help, col.Name) {
+			t.Errorf("TSVColumnHelp() = %q, missing column %s", help, col.Name)
+		}
+	}
+}
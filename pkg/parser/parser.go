@@ -1,16 +1,19 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode"
 )
 
 // OCPDocURL represents a parsed OCP documentation URL
 type OCPDocURL struct {
 	BaseURL     string
+	Product     string // e.g., "openshift_container_platform"
 	Version     string
 	MajorMinor  [2]int // e.g., [4, 17] for version 4.17
 	Format      string // e.g., "html-single" or "html"
@@ -18,27 +21,77 @@ type OCPDocURL struct {
 	Page        string // e.g., "index" or "telco-hub-ref-design-specs"
 	Anchor      string // e.g., "mirroring-image-set-full"
 	OriginalURL string
+
+	// FragmentIssue is set when the original fragment needed normalization
+	// or can never match an element id
+	FragmentIssue FragmentIssue
+	// VersionIssue is set when the version segment was not written as
+	// major.minor; Version always holds the canonical spelling
+	VersionIssue VersionIssue
 }
 
-// ParseOCPDocURL parses an OCP documentation URL and extracts its components
+// VersionIssue describes a problem found in the version segment of a URL
+type VersionIssue string
+
+const (
+	// VersionOK means the version is written as major.minor
+	VersionOK VersionIssue = ""
+	// VersionNonCanonical means the version has leading zeros (4.09), more
+	// than two components (4.16.1) or surrounding whitespace (%204.16).
+	// docs.redhat.com redirects these today, but the links are fragile.
+	VersionNonCanonical VersionIssue = "non-canonical"
+)
+
+// FragmentIssue describes a problem found in a URL fragment
+type FragmentIssue string
+
+const (
+	// FragmentOK means the fragment can be checked as-is
+	FragmentOK FragmentIssue = ""
+	// FragmentDuplicated means the fragment was repeated (e.g. #anchor#anchor)
+	// and has been collapsed to a single copy
+	FragmentDuplicated FragmentIssue = "duplicated"
+	// FragmentMalformed means the fragment contains characters that can never
+	// appear in an id (whitespace, additional '#')
+	FragmentMalformed FragmentIssue = "malformed"
+)
+
+// ProductOCP is the product slug of the OCP documentation, the product of
+// every URL ParseOCPDocURL accepts
+const ProductOCP = "openshift_container_platform"
+
+// docsHost is the host of every OCP documentation URL
+const docsHost = "docs.redhat.com"
+
+// ErrNotOCPDocURL is returned by ParseOCPDocURL for a URL that is not an
+// OCP documentation URL
+var ErrNotOCPDocURL = errors.New("not an OCP documentation URL")
+
+// docPathRegex matches the path of an OCP documentation page:
+// /en/documentation/openshift_container_platform/VERSION/FORMAT/DOCUMENT/PAGE.
+// Segments are limited to unreserved characters, so a decoded path always
+// rebuilds to the same URL. The version may be spelled loosely, see
+// VersionNonCanonical.
+var docPathRegex = regexp.MustCompile(`^/en/documentation/openshift_container_platform/(\s*\d+(?:\.\d+)+\s*)/([A-Za-z0-9._~-]+)/([A-Za-z0-9._~-]+)/([A-Za-z0-9._~-]+)/?$`)
+
+// ParseOCPDocURL parses an OCP documentation URL and extracts its components.
+// URLs on another host, with a path in another shape or with a version
+// that does not fit an int are rejected rather than guessed at.
 func ParseOCPDocURL(rawURL string) (*OCPDocURL, error) {
 	parsedURL, err := url.Parse(rawURL)
 	if err != nil {
-		return nil, fmt.Errorf("invalid URL: %w", err)
+		return nil, fmt.Errorf("%w: invalid URL: %w", ErrNotOCPDocURL, err)
 	}
 
 	// Validate this is an OCP documentation URL
-	if !strings.Contains(parsedURL.Host, "docs.redhat.com") {
-		return nil, fmt.Errorf("not a Red Hat documentation URL")
+	if (parsedURL.Scheme != "https" && parsedURL.Scheme != "http") || !strings.EqualFold(parsedURL.Hostname(), docsHost) {
+		return nil, fmt.Errorf("%w: expected an http(s) URL on %s", ErrNotOCPDocURL, docsHost)
 	}
 
 	// Extract components from path
-	// Expected format: /en/documentation/openshift_container_platform/VERSION/FORMAT/DOCUMENT/PAGE
-	pathRegex := regexp.MustCompile(`/documentation/openshift_container_platform/(\d+\.\d+)/([^/]+)/([^/]+)/([^/?\#]+)`)
-	matches := pathRegex.FindStringSubmatch(parsedURL.Path)
-
-	if len(matches) < 5 {
-		return nil, fmt.Errorf("URL does not match expected OCP documentation format")
+	matches := docPathRegex.FindStringSubmatch(parsedURL.Path)
+	if matches == nil {
+		return nil, fmt.Errorf("%w: path does not match /en/documentation/openshift_container_platform/VERSION/FORMAT/DOCUMENT/PAGE", ErrNotOCPDocURL)
 	}
 
 	version := matches[1]
@@ -46,35 +99,113 @@ func ParseOCPDocURL(rawURL string) (*OCPDocURL, error) {
 	document := matches[3]
 	page := matches[4]
 
-	// Parse major.minor version
-	versionParts := strings.Split(version, ".")
-	major, _ := strconv.Atoi(versionParts[0])
-	minor, _ := strconv.Atoi(versionParts[1])
+	// Parse major.minor version; anything after the minor is ignored
+	parts := strings.Split(strings.TrimSpace(version), ".")
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid version %q: %w", ErrNotOCPDocURL, version, err)
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid version %q: %w", ErrNotOCPDocURL, version, err)
+	}
+
+	versionIssue := VersionOK
+	if canonical := fmt.Sprintf("%d.%d", major, minor); canonical != version {
+		version, versionIssue = canonical, VersionNonCanonical
+	}
+
+	// The fragment is everything after the first '#' (RFC 3986)
+	anchor, issue := NormalizeFragment(parsedURL.Fragment)
 
 	return &OCPDocURL{
-		BaseURL:     fmt.Sprintf("%s://%s", parsedURL.Scheme, parsedURL.Host),
-		Version:     version,
-		MajorMinor:  [2]int{major, minor},
-		Format:      format,
-		Document:    document,
-		Page:        page,
-		Anchor:      strings.TrimPrefix(parsedURL.Fragment, ""),
-		OriginalURL: rawURL,
+		BaseURL:       fmt.Sprintf("%s://%s", parsedURL.Scheme, parsedURL.Host),
+		Product:       ProductOCP,
+		Version:       version,
+		MajorMinor:    [2]int{major, minor},
+		Format:        format,
+		Document:      document,
+		Page:          page,
+		Anchor:        anchor,
+		OriginalURL:   rawURL,
+		FragmentIssue: issue,
+		VersionIssue:  versionIssue,
 	}, nil
 }
 
+// NormalizeFragment returns the anchor to check for a URL fragment.
+// Slashes are valid id characters and are kept. A fragment repeated by a
+// templating accident (anchor#anchor) is collapsed to one copy; any other
+// fragment containing '#' or whitespace is reported as malformed and
+// returned unchanged.
+func NormalizeFragment(fragment string) (string, FragmentIssue) {
+	issue := FragmentOK
+
+	if first, _, found := strings.Cut(fragment, "#"); found {
+		for _, part := range strings.Split(fragment, "#") {
+			if part == "" || part != first {
+				return fragment, FragmentMalformed
+			}
+		}
+		fragment, issue = first, FragmentDuplicated
+	}
+
+	if strings.ContainsFunc(fragment, unicode.IsSpace) {
+		return fragment, FragmentMalformed
+	}
+
+	return fragment, issue
+}
+
 // BuildURL constructs a URL for a specific version
 func (o *OCPDocURL) BuildURL(version string) string {
-	url := fmt.Sprintf("%s/en/documentation/openshift_container_platform/%s/%s/%s/%s",
-		o.BaseURL, version, o.Format, o.Document, o.Page)
+	return o.BuildProductURL(ProductOCP, version)
+}
+
+// BuildProductURL constructs the URL of the same document, page and anchor
+// in the documentation of another product, e.g.
+// "red_hat_openshift_service_on_aws" at version "4". Whether the product
+// publishes that document is for a request to tell.
+func (o *OCPDocURL) BuildProductURL(product, version string) string {
+	url := fmt.Sprintf("%s/en/documentation/%s/%s/%s/%s/%s",
+		o.BaseURL, product, version, o.Format, o.Document, o.Page)
 
 	if o.Anchor != "" {
-		url += "#" + o.Anchor
+		url += "#" + escapeFragment(o.Anchor)
 	}
 
 	return url
 }
 
+// escapeFragment percent-encodes the characters of an anchor that cannot
+// be written literally: '%', which would start an escape, and control
+// characters. Everything else, including non-ASCII letters, stays readable.
+func escapeFragment(anchor string) string {
+	var b strings.Builder
+	for i := 0; i < len(anchor); i++ {
+		c := anchor[i]
+		if c == '%' || c < 0x20 || c == 0x7f {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// SectionKey identifies the logical section a URL points to, so the html
+// and html-single spellings of the same section share a key. Anchors are
+// unique within a document, so the page is left out of anchored URLs:
+// html-single always uses the index page while html splits the document
+// into per-chapter pages.
+func (o *OCPDocURL) SectionKey() string {
+	page := o.Page
+	if o.Anchor != "" {
+		page = ""
+	}
+	return fmt.Sprintf("openshift_container_platform/%s/%s/%s#%s", o.Version, o.Document, page, o.Anchor)
+}
+
 // GetVersionFloat returns the version as a float for comparison
 func (o *OCPDocURL) GetVersionFloat() float64 {
 	return float64(o.MajorMinor[0]) + float64(o.MajorMinor[1])/100.0
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -50,6 +51,24 @@ func TestParseOCPDocURL(t *testing.T) {
 			wantAnchor:  "",
 			wantErr:     false,
 		},
+		{
+			name:        "Anchor containing a slash",
+			url:         "https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html-single/operators/index#proc/installing-operator",
+			wantVersion: "4.16",
+			wantDoc:     "operators",
+			wantPage:    "index",
+			wantAnchor:  "proc/installing-operator",
+			wantErr:     false,
+		},
+		{
+			name:        "Duplicated anchor is collapsed",
+			url:         "https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html-single/operators/index#olm-installing#olm-installing",
+			wantVersion: "4.16",
+			wantDoc:     "operators",
+			wantPage:    "index",
+			wantAnchor:  "olm-installing",
+			wantErr:     false,
+		},
 		{
 			name:    "Invalid URL - not Red Hat",
 			url:     "https://example.com/docs",
@@ -60,6 +79,16 @@ func TestParseOCPDocURL(t *testing.T) {
 			url:     "https://docs.redhat.com/something/else",
 			wantErr: true,
 		},
+		{
+			name:    "Invalid URL - unparsable",
+			url:     "https://docs.redhat.com/%zz",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid URL - version out of range",
+			url:     "https://docs.redhat.com/en/documentation/openshift_container_platform/99999999999999999999.1/html/networking/index",
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -70,8 +99,14 @@ func TestParseOCPDocURL(t *testing.T) {
 				return
 			}
 			if tt.wantErr {
+				if !errors.Is(err, ErrNotOCPDocURL) {
+					t.Errorf("ParseOCPDocURL() error = %v, want ErrNotOCPDocURL", err)
+				}
 				return
 			}
+			if got.Product != ProductOCP {
+				t.Errorf("ParseOCPDocURL() Product = %v, want %v", got.Product, ProductOCP)
+			}
 			if got.Version != tt.wantVersion {
 				t.Errorf("ParseOCPDocURL() Version = %v, want %v", got.Version, tt.wantVersion)
 			}
@@ -131,6 +166,20 @@ func TestBuildURL(t *testing.T) {
 	}
 }
 
+func TestBuildProductURL(t *testing.T) {
+	docURL, err := ParseOCPDocURL("https://docs.redhat.com/en/documentation/openshift_container_platform/4.17/html/networking/ingress#nw-ingress-sharding_ingress")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "https://docs.redhat.com/en/documentation/red_hat_openshift_service_on_aws/4/html/networking/ingress#nw-ingress-sharding_ingress"
+	if got := docURL.BuildProductURL("red_hat_openshift_service_on_aws", "4"); got != want {
+		t.Errorf("BuildProductURL() = %v, want %v", got, want)
+	}
+	if got := docURL.BuildProductURL(ProductOCP, "4.17"); got != docURL.BuildURL("4.17") {
+		t.Errorf("BuildProductURL(%s) = %v, want %v", ProductOCP, got, docURL.BuildURL("4.17"))
+	}
+}
+
 func TestGetVersionFloat(t *testing.T) {
 	tests := []struct {
 		majorMinor [2]int
@@ -149,3 +198,255 @@ func TestGetVersionFloat(t *testing.T) {
 		}
 	}
 }
+
+func TestNormalizeFragment(t *testing.T) {
+	tests := []struct {
+		name      string
+		fragment  string
+		want      string
+		wantIssue FragmentIssue
+	}{
+		{"empty", "", "", FragmentOK},
+		{"plain anchor", "mirroring-image-set-full", "mirroring-image-set-full", FragmentOK},
+		{"slash is a valid id character", "proc/installing-operator", "proc/installing-operator", FragmentOK},
+		{"duplicated anchor", "anchor#anchor", "anchor", FragmentDuplicated},
+		{"triplicated anchor", "anchor#anchor#anchor", "anchor", FragmentDuplicated},
+		{"duplicated slash anchor", "proc/a#proc/a", "proc/a", FragmentDuplicated},
+		{"two different anchors", "first#second", "first#second", FragmentMalformed},
+		{"trailing hash", "anchor#", "anchor#", FragmentMalformed},
+		{"whitespace", "my anchor", "my anchor", FragmentMalformed},
+		{"duplicated with whitespace", "a b#a b", "a b", FragmentMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, issue := NormalizeFragment(tt.fragment)
+			if got != tt.want {
+				t.Errorf("NormalizeFragment(%q) = %q, want %q", tt.fragment, got, tt.want)
+			}
+			if issue != tt.wantIssue {
+				t.Errorf("NormalizeFragment(%q) issue = %q, want %q", tt.fragment, issue, tt.wantIssue)
+			}
+		})
+	}
+}
+
+func TestParseOCPDocURL_FragmentIssue(t *testing.T) {
+	tests := []struct {
+		name      string
+		url       string
+		wantIssue FragmentIssue
+	}{
+		{
+			name:      "slash anchor is not an issue",
+			url:       "https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html-single/operators/index#proc/installing-operator",
+			wantIssue: FragmentOK,
+		},
+		{
+			name:      "double hash from templating",
+			url:       "https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html-single/operators/index#olm#olm",
+			wantIssue: FragmentDuplicated,
+		},
+		{
+			name:      "encoded space",
+			url:       "https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html-single/operators/index#olm%20install",
+			wantIssue: FragmentMalformed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseOCPDocURL(tt.url)
+			if err != nil {
+				t.Fatalf("ParseOCPDocURL() error = %v", err)
+			}
+			if got.FragmentIssue != tt.wantIssue {
+				t.Errorf("ParseOCPDocURL() FragmentIssue = %q, want %q", got.FragmentIssue, tt.wantIssue)
+			}
+		})
+	}
+}
+
+func TestParseOCPDocURL_VersionIssue(t *testing.T) {
+	const base = "https://docs.redhat.com/en/documentation/openshift_container_platform/"
+
+	tests := []struct {
+		name        string
+		version     string
+		wantVersion string
+		wantIssue   VersionIssue
+	}{
+		{"canonical", "4.16", "4.16", VersionOK},
+		{"two-digit minor", "4.20", "4.20", VersionOK},
+		{"leading zero in minor", "4.09", "4.9", VersionNonCanonical},
+		{"leading zero in major", "04.16", "4.16", VersionNonCanonical},
+		{"patch component", "4.16.1", "4.16", VersionNonCanonical},
+		{"zero patch component", "4.16.0", "4.16", VersionNonCanonical},
+		{"encoded leading space", "%204.16", "4.16", VersionNonCanonical},
+		{"encoded trailing space", "4.16%20", "4.16", VersionNonCanonical},
+		{"several oddities", "%2004.016.2%20", "4.16", VersionNonCanonical},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseOCPDocURL(base + tt.version + "/html/networking/ingress-operator#nw-ne-openshift-ingress")
+			if err != nil {
+				t.Fatalf("ParseOCPDocURL() error = %v", err)
+			}
+			if got.Version != tt.wantVersion {
+				t.Errorf("ParseOCPDocURL() Version = %q, want %q", got.Version, tt.wantVersion)
+			}
+			if got.VersionIssue != tt.wantIssue {
+				t.Errorf("ParseOCPDocURL() VersionIssue = %q, want %q", got.VersionIssue, tt.wantIssue)
+			}
+			if want := base + tt.wantVersion + "/html/networking/ingress-operator#nw-ne-openshift-ingress"; got.BuildURL(got.Version) != want {
+				t.Errorf("BuildURL() = %q, want %q", got.BuildURL(got.Version), want)
+			}
+		})
+	}
+}
+
+func TestSectionKey(t *testing.T) {
+	const base = "https://docs.redhat.com/en/documentation/openshift_container_platform/"
+
+	tests := []struct {
+		name string
+		a, b string
+		same bool
+	}{
+		{"html and html-single of an anchored section", base + "4.17/html-single/networking/index#configuring-ingress", base + "4.17/html/networking/configuring-ingress-cluster-traffic#configuring-ingress", true},
+		{"html and html-single landing pages", base + "4.17/html-single/networking/index", base + "4.17/html/networking/index", true},
+		{"different versions", base + "4.16/html-single/networking/index#a", base + "4.17/html/networking/page#a", false},
+		{"different anchors", base + "4.17/html-single/networking/index#a", base + "4.17/html/networking/page#b", false},
+		{"html chapter and html-single document", base + "4.17/html-single/networking/index", base + "4.17/html/networking/ingress", false},
+		{"different documents", base + "4.17/html-single/networking/index#a", base + "4.17/html/storage/index#a", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := ParseOCPDocURL(tt.a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := ParseOCPDocURL(tt.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if same := a.SectionKey() == b.SectionKey(); same != tt.same {
+				t.Errorf("SectionKey() %s vs %s: same = %v, want %v", a.SectionKey(), b.SectionKey(), same, tt.same)
+			}
+		})
+	}
+}
+
+// Near misses must be rejected, never parsed into the wrong components
+func TestParseOCPDocURL_Rejects(t *testing.T) {
+	const path = "/en/documentation/openshift_container_platform/4.16/html/operators/index"
+
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{"host suffix", "https://docs.redhat.com.evil.example" + path},
+		{"host prefix", "https://notdocs.redhat.com" + path},
+		{"homoglyph host", "https://dоcs.redhat.com" + path},
+		{"host in path", "https://example.com/docs.redhat.com" + path},
+		{"other scheme", "ftp://docs.redhat.com" + path},
+		{"no scheme", "docs.redhat.com" + path},
+		{"path prefix", "https://docs.redhat.com/prefix" + path},
+		{"other language", "https://docs.redhat.com/fr/documentation/openshift_container_platform/4.16/html/operators/index"},
+		{"extra segment", "https://docs.redhat.com" + path + "/extra"},
+		{"encoded slash", "https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html/oper%2Fators/index"},
+		{"encoded question mark", "https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html/operators/in%3Fdex"},
+		{"version overflow", "https://docs.redhat.com/en/documentation/openshift_container_platform/4.99999999999999999999/html/operators/index"},
+		{"version without minor", "https://docs.redhat.com/en/documentation/openshift_container_platform/4./html/operators/index"},
+		{"template braces", "https://docs.redhat.com/en/documentation/openshift_container_platform/{version}/html/operators/index"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := ParseOCPDocURL(tt.url); err == nil {
+				t.Errorf("ParseOCPDocURL(%q) = %+v, want an error", tt.url, *got)
+			}
+		})
+	}
+}
+
+// roundTripSeeds are parseable URLs, and near misses that must be rejected,
+// covering the edge cases found so far
+var roundTripSeeds = []string{
+	"https://docs.redhat.com/en/documentation/openshift_container_platform/4.17/html-single/disconnected_environments/index#mirroring-image-set-full",
+	"https://docs.redhat.com/en/documentation/openshift_container_platform/4.18/html/scalability_and_performance/telco-hub-ref-design-specs",
+	"https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html-single/operators/index#proc/installing-operator",
+	"https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html-single/operators/index#olm-installing#olm-installing",
+	"https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html-single/operators/index#olm installing",
+	"https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html-single/operators/index#100%25",
+	"https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html-single/operators/index?lang=en#olm",
+	"https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html-single/operators/index/",
+	"https://docs.redhat.com:443/en/documentation/openshift_container_platform/4.16/html/operators/index",
+	"https://DOCS.redhat.com/en/documentation/openshift_container_platform/4.16/html/operators/index",
+	"https://docs.redhat.com/en/documentation/openshift_container_platform/{version}/html/operators/index",
+	"https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html/operators/index/extra",
+	"https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html/oper%2Fators/index",
+	"https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html/operators/in%3Fdex",
+	"https://docs.redhat.com/en/documentation/openshift_container_platform/4.99999999999999999999/html/operators/index",
+	"https://docs.redhat.com/en/documentation/openshift_container_platform/04.09/html/operators/index",
+	"https://docs.redhat.com/en/documentation/openshift_container_platform/4.16.1/html/operators/index",
+	"https://docs.redhat.com/en/documentation/openshift_container_platform/%204.16%20/html/operators/index",
+	"https://docs.redhat.com/en/documentation/openshift_container_platform/4./html/operators/index",
+	"https://docs.redhat.com.evil.example/en/documentation/openshift_container_platform/4.16/html/operators/index",
+	"https://notdocs.redhat.com/en/documentation/openshift_container_platform/4.16/html/operators/index",
+	"https://dоcs.redhat.com/en/documentation/openshift_container_platform/4.16/html/operators/index",
+	"https://example.com/docs.redhat.com/en/documentation/openshift_container_platform/4.16/html/operators/index",
+	"https://docs.redhat.com/prefix/en/documentation/openshift_container_platform/4.16/html/operators/index",
+	"ftp://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html/operators/index",
+	"docs.redhat.com/en/documentation/openshift_container_platform/4.16/html/operators/index",
+	"",
+}
+
+// checkRoundTrip verifies that a parsed URL rebuilt for its own version
+// parses back to the same components
+func checkRoundTrip(t *testing.T, raw string) {
+	t.Helper()
+
+	parsed, err := ParseOCPDocURL(raw)
+	if err != nil {
+		return
+	}
+
+	rebuilt := parsed.BuildURL(parsed.Version)
+	again, err := ParseOCPDocURL(rebuilt)
+	if err != nil {
+		t.Fatalf("ParseOCPDocURL(%q) accepted, but its rebuilt URL %q is rejected: %v", raw, rebuilt, err)
+	}
+
+	want := *parsed
+	want.OriginalURL = rebuilt
+	// Rebuilding writes the collapsed copy of a duplicated fragment
+	if want.FragmentIssue == FragmentDuplicated {
+		want.FragmentIssue = FragmentOK
+	}
+	// and the canonical version
+	want.VersionIssue = VersionOK
+	if *again != want {
+		t.Errorf("ParseOCPDocURL(%q) = %+v\nrebuilt as %q, which parses to %+v", raw, *parsed, rebuilt, *again)
+	}
+}
+
+func TestBuildURL_RoundTrip(t *testing.T) {
+	for _, raw := range roundTripSeeds {
+		t.Run(raw, func(t *testing.T) {
+			checkRoundTrip(t, raw)
+		})
+	}
+}
+
+func FuzzParseOCPDocURL(f *testing.F) {
+	for _, raw := range roundTripSeeds {
+		f.Add(raw)
+	}
+
+	f.Fuzz(func(t *testing.T, raw string) {
+		checkRoundTrip(t, raw)
+	})
+}
@@ -0,0 +1,170 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DeepScanExtensions lists the structured files whose string values are
+// decoded and searched for URLs when deep scanning
+var DeepScanExtensions = map[string]bool{
+	".yaml": true,
+	".yml":  true,
+	".json": true,
+}
+
+// Encodings of values holding an encoded occurrence
+const (
+	EncodingBase64 = "base64"
+	EncodingURL    = "url"
+)
+
+const (
+	// MinEncodedLength is the shortest string value tried as base64; shorter
+	// values are mostly words that happen to be valid base64
+	MinEncodedLength = 32
+	// MaxEncodedLength is the longest string value that is decoded
+	MaxEncodedLength = 1 << 20
+	// MaxDecodedBytes caps the decoded bytes searched per file
+	MaxDecodedBytes = 8 << 20
+)
+
+// base64Regex matches a base64 value, standard or URL-safe, with optional
+// padding. Whitespace is removed before matching.
+var base64Regex = regexp.MustCompile(`^[A-Za-z0-9+/_-]+={0,2}$`)
+
+// urlEncodedRegex matches a percent-encoded documentation URL prefix
+var urlEncodedRegex = regexp.MustCompile(`(?i)https?%3A%2F%2Fdocs\.redhat\.com`)
+
+// deepScanFile decodes the string values of a YAML or JSON document and
+// returns the documentation URLs found in the decoded content. Plain URLs
+// in the document are not reported.
+func deepScanFile(path string, content []byte) ([]Occurrence, error) {
+	d := &deepScanner{path: path, budget: MaxDecodedBytes}
+	if err := d.scan(content); err != nil {
+		return nil, err
+	}
+	return d.occurrences, nil
+}
+
+// deepScanner accumulates encoded occurrences while walking a document
+type deepScanner struct {
+	path        string
+	budget      int // decoded bytes left to search
+	occurrences []Occurrence
+}
+
+// scan walks every document in a YAML or JSON stream
+func (d *deepScanner) scan(content []byte) error {
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("parsing %s: %w", d.path, err)
+		}
+		d.walk(&doc, "")
+	}
+}
+
+// walk visits every scalar value in n, tracking the key path leading to it
+func (d *deepScanner) walk(n *yaml.Node, keyPath string) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, child := range n.Content {
+			d.walk(child, keyPath)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			d.walk(n.Content[i+1], joinKey(keyPath, n.Content[i].Value))
+		}
+	case yaml.SequenceNode:
+		for i, child := range n.Content {
+			d.walk(child, keyPath+"["+strconv.Itoa(i)+"]")
+		}
+	case yaml.ScalarNode:
+		if n.Tag == "!!str" || n.Tag == "!!binary" {
+			d.scanValue(n, keyPath)
+		}
+	}
+}
+
+// scanValue decodes a string value that looks encoded and records the URLs
+// in the decoded content
+func (d *deepScanner) scanValue(n *yaml.Node, keyPath string) {
+	value := n.Value
+	if len(value) > MaxEncodedLength {
+		return
+	}
+
+	var decoded, encoding string
+	switch {
+	case urlEncodedRegex.MatchString(value):
+		unescaped, err := url.QueryUnescape(value)
+		if err != nil {
+			return
+		}
+		decoded, encoding = unescaped, EncodingURL
+	default:
+		text, ok := decodeBase64(value)
+		if !ok {
+			return
+		}
+		decoded, encoding = text, EncodingBase64
+	}
+
+	if len(decoded) > d.budget {
+		return
+	}
+	d.budget -= len(decoded)
+
+	for _, match := range urlRegex.FindAllString(decoded, -1) {
+		d.occurrences = append(d.occurrences, Occurrence{
+			URL:      CleanURL(match),
+			Path:     d.path,
+			Line:     n.Line,
+			Column:   n.Column,
+			Encoding: encoding,
+			KeyPath:  keyPath,
+		})
+	}
+}
+
+// decodeBase64 decodes a value that looks like base64 text
+func decodeBase64(value string) (string, bool) {
+	compact := strings.Join(strings.Fields(value), "")
+	if len(compact) < MinEncodedLength || !base64Regex.MatchString(compact) {
+		return "", false
+	}
+
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		raw, err := enc.DecodeString(compact)
+		if err == nil && utf8.Valid(raw) {
+			return string(raw), true
+		}
+	}
+	return "", false
+}
+
+// joinKey appends a mapping key to a key path, quoting keys with dots
+func joinKey(keyPath, key string) string {
+	if strings.ContainsAny(key, ".[]") {
+		return keyPath + "[" + strconv.Quote(key) + "]"
+	}
+	if keyPath == "" {
+		return key
+	}
+	return keyPath + "." + key
+}
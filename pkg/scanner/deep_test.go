@@ -0,0 +1,108 @@
+package scanner
+
+import (
+	"encoding/base64"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScan_DeepScan(t *testing.T) {
+	root := filepath.Join("testdata", "deep")
+
+	var warned []string
+	s := New()
+	s.DeepScan = true
+	s.Warn = func(path string, err error) {
+		warned = append(warned, filepath.Base(path))
+	}
+
+	locations, err := s.Scan(root)
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	tests := []struct {
+		url      string
+		line     int
+		keyPath  string
+		encoding string
+	}{
+		{
+			url:      "https://docs.redhat.com/en/documentation/openshift_container_platform/4.14/html/networking/index#nw-about",
+			line:     6,
+			keyPath:  "metadata.annotations.alm-examples",
+			encoding: EncodingBase64,
+		},
+		{
+			url:      "https://docs.redhat.com/en/documentation/openshift_container_platform/4.15/html/storage/index",
+			line:     17,
+			keyPath:  "spec.links[0].url",
+			encoding: EncodingURL,
+		},
+	}
+
+	if len(locations) != len(tests) {
+		t.Fatalf("Scan() found %d URLs, want %d: %+v", len(locations), len(tests), locations)
+	}
+
+	for i, tt := range tests {
+		occ := locations[i].Occurrences[0]
+		if occ.URL != tt.url {
+			t.Errorf("occurrence %d URL = %s, want %s", i, occ.URL, tt.url)
+		}
+		if occ.Line != tt.line || occ.KeyPath != tt.keyPath || occ.Encoding != tt.encoding {
+			t.Errorf("occurrence %d = line %d, key %s, encoding %s; want line %d, key %s, encoding %s",
+				i, occ.Line, occ.KeyPath, occ.Encoding, tt.line, tt.keyPath, tt.encoding)
+		}
+		if occ.Start != 0 || occ.End != 0 {
+			t.Errorf("occurrence %d has a text span %d-%d, want none", i, occ.Start, occ.End)
+		}
+	}
+
+	if len(warned) != 1 || warned[0] != "broken.json" {
+		t.Errorf("warned about %v, want [broken.json]", warned)
+	}
+	if got := s.Stats().FilesSkipped[SkipParseError]; got != 1 {
+		t.Errorf("FilesSkipped[%s] = %d, want 1", SkipParseError, got)
+	}
+}
+
+func TestScan_DeepScanDisabled(t *testing.T) {
+	locations, err := New().Scan(filepath.Join("testdata", "deep"))
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if len(locations) != 0 {
+		t.Errorf("Scan() without DeepScan found %d URLs, want 0", len(locations))
+	}
+}
+
+func TestDeepScan_Limits(t *testing.T) {
+	link := "https://docs.redhat.com/en/documentation/openshift_container_platform/4.14/html/networking/index"
+	encoded := base64.StdEncoding.EncodeToString([]byte(link))
+
+	tests := []struct {
+		name    string
+		content string
+		budget  int
+		want    int
+	}{
+		{"encoded link", "a: " + encoded, MaxDecodedBytes, 1},
+		{"oversized value", "a: " + encoded + strings.Repeat("A", MaxEncodedLength), MaxDecodedBytes, 0},
+		{"short value", "a: " + base64.StdEncoding.EncodeToString([]byte("docs")), MaxDecodedBytes, 0},
+		{"decoded budget exhausted", "[" + encoded + ", " + encoded + "]", len(link) + 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &deepScanner{path: "doc.yaml", budget: tt.budget}
+			if err := d.scan([]byte(tt.content)); err != nil {
+				t.Fatalf("scan() error = %v", err)
+			}
+			if len(d.occurrences) != tt.want {
+				t.Errorf("scan() found %d URLs, want %d", len(d.occurrences), tt.want)
+			}
+		})
+	}
+}
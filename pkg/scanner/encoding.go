@@ -0,0 +1,152 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// byteOrder reads and appends 16-bit code units
+type byteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+// Encoding describes how a file's text was stored on disk
+type Encoding struct {
+	Name string // "utf-8", "utf-16le" or "utf-16be"
+	BOM  bool   // true if the file started with a byte order mark
+}
+
+// UTF8 is the encoding of plain UTF-8 files without a byte order mark
+var UTF8 = Encoding{Name: "utf-8"}
+
+// ReadFile reads a file and transcodes it to UTF-8 for scanning, returning
+// the original encoding so edits can be written back unchanged
+func ReadFile(path string) ([]byte, Encoding, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, UTF8, err
+	}
+
+	text, enc := Decode(raw)
+	return text, enc, nil
+}
+
+// WriteFile encodes UTF-8 text in enc and writes it to path
+func WriteFile(path string, text []byte, enc Encoding, perm os.FileMode) error {
+	return os.WriteFile(path, Encode(text, enc), perm)
+}
+
+// Decode detects byte order marks and UTF-16 content and returns the text
+// as UTF-8 without a BOM. Content that is not recognized is returned as-is.
+func Decode(raw []byte) ([]byte, Encoding) {
+	switch {
+	case bytes.HasPrefix(raw, bomUTF8):
+		return raw[len(bomUTF8):], Encoding{Name: "utf-8", BOM: true}
+	case bytes.HasPrefix(raw, bomUTF16LE):
+		if text, ok := decodeUTF16(raw[2:], binary.LittleEndian); ok {
+			return text, Encoding{Name: "utf-16le", BOM: true}
+		}
+	case bytes.HasPrefix(raw, bomUTF16BE):
+		if text, ok := decodeUTF16(raw[2:], binary.BigEndian); ok {
+			return text, Encoding{Name: "utf-16be", BOM: true}
+		}
+	}
+
+	if !utf8.Valid(raw) || bytes.IndexByte(raw, 0) != -1 {
+		if order, ok := guessUTF16(raw); ok {
+			if text, ok := decodeUTF16(raw, order); ok {
+				name := "utf-16le"
+				if order == byteOrder(binary.BigEndian) {
+					name = "utf-16be"
+				}
+				return text, Encoding{Name: name}
+			}
+		}
+	}
+
+	return raw, UTF8
+}
+
+// Encode converts UTF-8 text back to enc, re-adding the byte order mark if
+// the original file had one
+func Encode(text []byte, enc Encoding) []byte {
+	var order byteOrder
+	switch enc.Name {
+	case "utf-16le":
+		order = binary.LittleEndian
+	case "utf-16be":
+		order = binary.BigEndian
+	default:
+		if enc.BOM {
+			return append(append([]byte{}, bomUTF8...), text...)
+		}
+		return text
+	}
+
+	units := utf16.Encode([]rune(string(text)))
+	out := make([]byte, 0, 2+2*len(units))
+	if enc.BOM {
+		out = order.AppendUint16(out, 0xFEFF)
+	}
+	for _, u := range units {
+		out = order.AppendUint16(out, u)
+	}
+	return out
+}
+
+// decodeUTF16 decodes UTF-16 bytes in the given byte order into UTF-8
+func decodeUTF16(raw []byte, order byteOrder) ([]byte, bool) {
+	if len(raw)%2 != 0 {
+		return nil, false
+	}
+
+	units := make([]uint16, len(raw)/2)
+	for i := range units {
+		units[i] = order.Uint16(raw[2*i:])
+	}
+
+	return []byte(string(utf16.Decode(units))), true
+}
+
+// guessUTF16 recognizes BOM-less UTF-16 text, which for the mostly-ASCII
+// content of documentation has a zero byte in every other position
+func guessUTF16(raw []byte) (byteOrder, bool) {
+	sample := raw
+	if len(sample) > 1024 {
+		sample = sample[:1024]
+	}
+	if len(sample) < 4 {
+		return nil, false
+	}
+
+	var evenZeros, oddZeros int
+	for i, b := range sample {
+		if b != 0 {
+			continue
+		}
+		if i%2 == 0 {
+			evenZeros++
+		} else {
+			oddZeros++
+		}
+	}
+
+	pairs := len(sample) / 2
+	switch {
+	case oddZeros > pairs*3/4 && evenZeros < pairs/10:
+		return binary.LittleEndian, true
+	case evenZeros > pairs*3/4 && oddZeros < pairs/10:
+		return binary.BigEndian, true
+	}
+	return nil, false
+}
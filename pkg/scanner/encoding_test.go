@@ -0,0 +1,121 @@
+package scanner
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const encodedURL = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html/networking/index"
+
+func TestScanFile_Encodings(t *testing.T) {
+	tests := []struct {
+		file    string
+		wantEnc Encoding
+		wantURL string
+	}{
+		{"utf8-bom.md", Encoding{Name: "utf-8", BOM: true}, encodedURL},
+		{"utf16le-bom.md", Encoding{Name: "utf-16le", BOM: true}, encodedURL},
+		{"utf16be-bom.md", Encoding{Name: "utf-16be", BOM: true}, encodedURL},
+		{"utf16le.md", Encoding{Name: "utf-16le"}, encodedURL},
+		{"utf8-bom-url-first.txt", Encoding{Name: "utf-8", BOM: true}, "https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html/storage/index"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			path := filepath.Join("testdata", "encodings", tt.file)
+
+			_, enc, err := ReadFile(path)
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+			if enc != tt.wantEnc {
+				t.Errorf("ReadFile() encoding = %+v, want %+v", enc, tt.wantEnc)
+			}
+
+			occurrences, err := New().ScanFile(path)
+			if err != nil {
+				t.Fatalf("ScanFile() error = %v", err)
+			}
+			if len(occurrences) != 1 {
+				t.Fatalf("ScanFile() found %d URLs, want 1", len(occurrences))
+			}
+			if occurrences[0].URL != tt.wantURL {
+				t.Errorf("ScanFile() URL = %q, want %q", occurrences[0].URL, tt.wantURL)
+			}
+		})
+	}
+}
+
+func TestEncode_RoundTrip(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "encodings", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range files {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			text, enc := Decode(raw)
+			if got := Encode(text, enc); !bytes.Equal(got, raw) {
+				t.Errorf("Encode(Decode()) changed the file bytes")
+			}
+		})
+	}
+}
+
+func TestWriteFile_PreservesEncoding(t *testing.T) {
+	src := filepath.Join("testdata", "encodings", "utf16le-bom.md")
+	raw, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "doc.md")
+	if err := os.WriteFile(path, raw, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	text, enc, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newURL := "https://docs.redhat.com/en/documentation/openshift_container_platform/4.20/html/networking/index"
+	updated := bytes.ReplaceAll(text, []byte(encodedURL), []byte(newURL))
+	if err := WriteFile(path, updated, enc, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(written, []byte{0xFF, 0xFE}) {
+		t.Errorf("WriteFile() dropped the UTF-16LE byte order mark")
+	}
+
+	reread, enc2, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enc2 != enc {
+		t.Errorf("encoding changed from %+v to %+v", enc, enc2)
+	}
+	if !bytes.Contains(reread, []byte(newURL)) {
+		t.Errorf("rewritten file does not contain the new URL")
+	}
+}
+
+func TestDecode_PlainUTF8(t *testing.T) {
+	raw := []byte("plain ASCII and ünïcode text")
+	text, enc := Decode(raw)
+	if enc != UTF8 || !bytes.Equal(text, raw) {
+		t.Errorf("Decode() = %q, %+v; want the input unchanged as UTF-8", text, enc)
+	}
+}
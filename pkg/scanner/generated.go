@@ -0,0 +1,113 @@
+package scanner
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Detectors of generated files, the values of Occurrence.Generated and the
+// keys of Stats.FilesGenerated
+const (
+	GeneratedHeader   = "header"
+	GeneratedMinified = "minified"
+	GeneratedPattern  = "pattern"
+)
+
+// DefaultGeneratedPatterns match minified bundles and the usual outputs of
+// code and API reference generators
+var DefaultGeneratedPatterns = []string{
+	"*.min.*",
+	"zz_generated*",
+	"*.generated.*",
+	"**/generated/**",
+}
+
+// DefaultMinifiedLineLength is the average line length in bytes above which
+// a file is taken for minified. Prose with unwrapped paragraphs stays well
+// below it, since blank lines separate them.
+const DefaultMinifiedLineLength = 1000
+
+// generatedHeaderLines is how many lines at the top of a file are searched
+// for a generated-code header
+const generatedHeaderLines = 20
+
+// generatedHeaderRegex matches the generated-code header of the Go
+// convention, behind whatever comment syntax the file uses, e.g.
+// "// Code generated by controller-gen. DO NOT EDIT." or
+// "<!-- Code generated by crd-ref-docs; DO NOT EDIT -->"
+var generatedHeaderRegex = regexp.MustCompile(`^[^\pL\pN]*Code generated\b.*\bDO NOT EDIT\b`)
+
+// HasGeneratedHeader reports whether content starts with a "Code generated
+// ... DO NOT EDIT" header, within its first 20 lines
+func HasGeneratedHeader(content []byte) bool {
+	for i := 0; i < generatedHeaderLines && len(content) > 0; i++ {
+		line, rest, _ := bytes.Cut(content, []byte("\n"))
+		if generatedHeaderRegex.Match(line) {
+			return true
+		}
+		content = rest
+	}
+	return false
+}
+
+// Minified reports whether the lines of content are longer than
+// maxLineLength bytes on average, as those of minified bundles are
+func Minified(content []byte, maxLineLength int) bool {
+	if maxLineLength <= 0 || len(content) <= maxLineLength {
+		return false
+	}
+	lines := bytes.Count(bytes.TrimSuffix(content, []byte("\n")), []byte("\n")) + 1
+	return len(content)/lines > maxLineLength
+}
+
+// SetGeneratedPatterns replaces the glob patterns of generated files,
+// matched like those of SetHistoricalPatterns. An empty list disables
+// matching by path; headers and minified content are still detected.
+func (s *Scanner) SetGeneratedPatterns(patterns []string) error {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid generated pattern %q: %w", p, err)
+		}
+	}
+	s.generatedPatterns = patterns
+	return nil
+}
+
+// SetMinifiedLineLength sets the average line length above which a file is
+// taken for minified, DefaultMinifiedLineLength by default; 0 disables the
+// detection
+func (s *Scanner) SetMinifiedLineLength(n int) {
+	s.minifiedLineLength = n
+}
+
+// SetGeneratedDetection turns the detection of generated files on or off.
+// It is on by default; with it off, URLs in generated files are marked like
+// any other.
+func (s *Scanner) SetGeneratedDetection(enabled bool) {
+	s.detectGenerated = enabled
+}
+
+// Generated returns the detector that takes the file at rel, a
+// slash-separated path relative to the scanned directory, with content
+// content for generated, or "" for a file written by hand
+func (s *Scanner) Generated(rel string, content []byte) string {
+	if !s.detectGenerated {
+		return ""
+	}
+	rel = strings.ToLower(rel)
+	for _, p := range s.generatedPatterns {
+		if matchPattern(strings.ToLower(p), rel) {
+			return GeneratedPattern
+		}
+	}
+	if HasGeneratedHeader(content) {
+		return GeneratedHeader
+	}
+	if Minified(content, s.minifiedLineLength) {
+		return GeneratedMinified
+	}
+	return ""
+}
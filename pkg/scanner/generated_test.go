@@ -0,0 +1,155 @@
+package scanner
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHasGeneratedHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{"go comment", "// Code generated by controller-gen. DO NOT EDIT.\n\npackage v1\n", true},
+		{"html comment", "<!-- Code generated by crd-ref-docs; DO NOT EDIT -->\n# API\n", true},
+		{"hash comment after a front matter", "---\ntitle: API\n---\n# Code generated by gen-api-docs. DO NOT EDIT.\n", true},
+		{"asciidoc comment", "////\nCode generated from openapi.json. DO NOT EDIT.\n////\n", true},
+		{"prose mentioning the convention", "Files that start with a Code generated ... DO NOT EDIT header are skipped.\n", false},
+		{"header past the top of the file", strings.Repeat("text\n", 20) + "// Code generated by gen. DO NOT EDIT.\n", false},
+		{"no marker", "# Guide\n\nCode generated by hand.\n", false},
+		{"lower case", "// code generated by gen. do not edit.\n", false},
+		{"empty", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasGeneratedHeader([]byte(tt.content)); got != tt.want {
+				t.Errorf("HasGeneratedHeader() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMinified(t *testing.T) {
+	long := strings.Repeat("x", 1500)
+	tests := []struct {
+		name    string
+		content string
+		max     int
+		want    bool
+	}{
+		{"single long line", long, 1000, true},
+		{"single long line with a final newline", long + "\n", 1000, true},
+		{"long paragraphs between blank lines", long + "\n\n" + long + "\n\n" + long + "\n", 1000, false},
+		{"prose", strings.Repeat(strings.Repeat("word ", 100)+"\n\n", 10), 1000, false},
+		{"short file", "{\"a\":1}", 1000, false},
+		{"lower threshold", strings.Repeat("y", 200) + "\n", 100, true},
+		{"disabled", long, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Minified([]byte(tt.content), tt.max); got != tt.want {
+				t.Errorf("Minified() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerated(t *testing.T) {
+	header := []byte("// Code generated by gen. DO NOT EDIT.\n")
+	tests := []struct {
+		name     string
+		patterns []string // nil keeps the defaults
+		rel      string
+		content  []byte
+		want     string
+	}{
+		{"minified bundle", nil, "assets/app.min.json", nil, GeneratedPattern},
+		{"deepcopy file", nil, "api/v1/zz_generated.deepcopy.yaml", nil, GeneratedPattern},
+		{"generated directory", nil, "docs/api/generated/pods.md", nil, GeneratedPattern},
+		{"generated directory at the root", nil, "generated/pods.md", nil, GeneratedPattern},
+		{"file named after generation", nil, "docs/generating-docs.md", nil, ""},
+		{"header", nil, "docs/crds.md", header, GeneratedHeader},
+		{"pattern before header", nil, "api.generated.md", header, GeneratedPattern},
+		{"minified", nil, "assets/index.txt", []byte(strings.Repeat("z", 2000)), GeneratedMinified},
+		{"custom glob", []string{"docs/reference/**"}, "docs/reference/cli.md", nil, GeneratedPattern},
+		{"custom glob replaces the defaults", []string{"docs/reference/**"}, "app.min.json", nil, ""},
+		{"custom glob keeps the header", []string{"docs/reference/**"}, "crds.md", header, GeneratedHeader},
+		{"authored", nil, "README.md", []byte("# Operator\n"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			if tt.patterns != nil {
+				if err := s.SetGeneratedPatterns(tt.patterns); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := s.Generated(tt.rel, tt.content); got != tt.want {
+				t.Errorf("Generated(%q) = %q, want %q", tt.rel, got, tt.want)
+			}
+		})
+	}
+
+	s := New()
+	s.SetGeneratedDetection(false)
+	if got := s.Generated("app.min.json", header); got != "" {
+		t.Errorf("Generated() with detection off = %q, want none", got)
+	}
+	if err := New().SetGeneratedPatterns([]string{"gen["}); err == nil {
+		t.Error("SetGeneratedPatterns() with a malformed glob succeeded, want error")
+	}
+}
+
+func TestScan_Generated(t *testing.T) {
+	const prefix = "https://docs.redhat.com/en/documentation/openshift_container_platform/"
+	root := filepath.Join("testdata", "generated")
+
+	s := New()
+	locations, err := s.Scan(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only URLs found nowhere but in generated files are generated
+	want := map[string]bool{
+		prefix + "4.14/html/networking/index":              false, // also in README.md
+		prefix + "4.16/html/nodes/index":                   false,
+		prefix + "4.12/html/storage/index":                 true,
+		prefix + "4.12/html/operators/index":               true,
+		prefix + "4.12/html/security_and_compliance/index": true,
+	}
+	if len(locations) != len(want) {
+		t.Fatalf("Scan() found %d URLs, want %d", len(locations), len(want))
+	}
+	occurrences := make(map[string]string)
+	for _, loc := range locations {
+		if loc.Generated != want[loc.URL] {
+			t.Errorf("%s: Generated = %v, want %v", loc.URL, loc.Generated, want[loc.URL])
+		}
+		for _, occ := range loc.Occurrences {
+			rel, _ := filepath.Rel(root, occ.Path)
+			occurrences[filepath.ToSlash(rel)] = occ.Generated
+		}
+	}
+
+	wantOccurrences := map[string]string{
+		"README.md":                       "",
+		"docs/contributing.adoc":          "",
+		"docs/api/generated/reference.md": GeneratedPattern,
+		"docs/crds.md":                    GeneratedHeader,
+		"assets/search-index.txt":         GeneratedMinified,
+	}
+	if !reflect.DeepEqual(occurrences, wantOccurrences) {
+		t.Errorf("Generated by file = %v, want %v", occurrences, wantOccurrences)
+	}
+	wantStats := map[string]int{GeneratedHeader: 1, GeneratedMinified: 1, GeneratedPattern: 1}
+	if got := s.Stats().FilesGenerated; !reflect.DeepEqual(got, wantStats) {
+		t.Errorf("Stats().FilesGenerated = %v, want %v", got, wantStats)
+	}
+}
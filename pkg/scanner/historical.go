@@ -0,0 +1,98 @@
+package scanner
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultHistoricalPatterns match changelogs and release notes, whose links
+// record the versions that were current when each entry was written
+var DefaultHistoricalPatterns = []string{
+	"CHANGELOG*",
+	"CHANGES*",
+	"HISTORY*",
+	"RELEASE-NOTES*",
+	"RELEASE_NOTES*",
+	"release-notes/**",
+	"docs/release-notes/**",
+}
+
+// SetHistoricalPatterns replaces the glob patterns of files holding
+// historical references. A pattern without a slash matches the file name
+// in any directory; other patterns match the path relative to the scanned
+// directory, where ** matches any number of directories. Matching ignores
+// case. An empty list disables the classification.
+func (s *Scanner) SetHistoricalPatterns(patterns []string) error {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid historical pattern %q: %w", p, err)
+		}
+	}
+	s.historicalPatterns = patterns
+	return nil
+}
+
+// Historical reports whether the file at rel, a slash-separated path
+// relative to the scanned directory, holds historical references
+func (s *Scanner) Historical(rel string) bool {
+	rel = strings.ToLower(rel)
+	for _, p := range s.historicalPatterns {
+		if matchPattern(strings.ToLower(p), rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPattern matches a slash-separated path against a glob pattern: a
+// pattern without a slash matches the file name, others the whole path
+func matchPattern(pattern, rel string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(rel))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(rel, "/"))
+}
+
+// matchSegments matches path segments against pattern segments, where a
+// ** segment matches zero or more path segments
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], segments[1:])
+}
+
+// markHistorical flags the occurrences found in files holding historical
+// references. root is the scanned file or directory.
+func (s *Scanner) markHistorical(root string, rootIsDir bool, occurrences []Occurrence) {
+	if len(s.historicalPatterns) == 0 {
+		return
+	}
+	for i := range occurrences {
+		occ := &occurrences[i]
+		rel := filepath.Base(occ.Path)
+		if rootIsDir {
+			if r, err := filepath.Rel(root, occ.Path); err == nil {
+				rel = r
+			}
+		}
+		occ.Historical = s.Historical(filepath.ToSlash(rel))
+	}
+}
@@ -0,0 +1,91 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHistorical(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string // nil keeps the defaults
+		rel      string
+		want     bool
+	}{
+		{"changelog at the root", nil, "CHANGELOG.md", true},
+		{"changelog in a subdirectory", nil, "operator/CHANGELOG.md", true},
+		{"lower-case changelog", nil, "changelog.md", true},
+		{"release notes", nil, "docs/release-notes/4.16/networking.adoc", true},
+		{"release notes directory at the root", nil, "release-notes/v1.md", true},
+		{"release notes elsewhere", nil, "guides/docs/release-notes/v1.md", false},
+		{"guide mentioning changes", nil, "docs/changelog-policy/guide.md", false},
+		{"readme", nil, "README.md", false},
+		{"custom glob", []string{"docs/**/history.md"}, "docs/a/b/history.md", true},
+		{"custom glob with no directory between", []string{"docs/**/history.md"}, "docs/history.md", true},
+		{"custom glob replaces the defaults", []string{"docs/**/history.md"}, "CHANGELOG.md", false},
+		{"disabled", []string{}, "CHANGELOG.md", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			if tt.patterns != nil {
+				if err := s.SetHistoricalPatterns(tt.patterns); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := s.Historical(tt.rel); got != tt.want {
+				t.Errorf("Historical(%q) = %v, want %v", tt.rel, got, tt.want)
+			}
+		})
+	}
+
+	if err := New().SetHistoricalPatterns([]string{"CHANGELOG["}); err == nil {
+		t.Error("SetHistoricalPatterns() with a malformed glob succeeded, want error")
+	}
+}
+
+func TestScan_Historical(t *testing.T) {
+	const (
+		shared   = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.14/html/networking/index"
+		onlyOld  = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.12/html/storage/index"
+		onlyDocs = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html/nodes/index"
+	)
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"CHANGELOG.md":                 "- " + shared + "\n- " + onlyOld + "\n",
+		"docs/guide.md":                shared + "\n" + onlyDocs + "\n",
+		"docs/release-notes/v1.0.adoc": onlyOld + "\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	locations, err := New().Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only URLs found nowhere but in changelogs and release notes are historical
+	want := map[string]bool{shared: false, onlyOld: true, onlyDocs: false}
+	for _, loc := range locations {
+		if loc.Historical != want[loc.URL] {
+			t.Errorf("%s: Historical = %v, want %v", loc.URL, loc.Historical, want[loc.URL])
+		}
+		for _, occ := range loc.Occurrences {
+			rel, _ := filepath.Rel(dir, occ.Path)
+			wantOcc := filepath.ToSlash(rel) != "docs/guide.md"
+			if occ.Historical != wantOcc {
+				t.Errorf("%s in %s: Historical = %v, want %v", occ.URL, rel, occ.Historical, wantOcc)
+			}
+		}
+	}
+}
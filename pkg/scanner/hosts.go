@@ -0,0 +1,122 @@
+package scanner
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// DocsHost is the documentation host every discovered URL should use
+const DocsHost = "docs.redhat.com"
+
+// Reasons a host is reported as suspicious
+const (
+	// SuspiciousNearMiss is an ASCII host one or two edits away from DocsHost
+	SuspiciousNearMiss = "near-miss"
+	// SuspiciousHomoglyph is a host that spells DocsHost, or a near miss of
+	// it, with look-alike characters from other scripts
+	SuspiciousHomoglyph = "homoglyph"
+)
+
+// homoglyphs maps characters from other scripts to the Latin letters they
+// are commonly mistaken for
+var homoglyphs = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'с': 'c', 'ԁ': 'd', 'е': 'e', 'һ': 'h', 'і': 'i', 'ј': 'j',
+	'о': 'o', 'р': 'p', 'ԛ': 'q', 'ѕ': 's', 'ԝ': 'w', 'х': 'x', 'у': 'y',
+	// Greek
+	'α': 'a', 'ε': 'e', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p',
+	'τ': 't', 'υ': 'u', 'χ': 'x',
+	// Latin look-alikes outside ASCII
+	'ı': 'i', 'ȷ': 'j', 'ɑ': 'a', 'ɡ': 'g', 'ℯ': 'e', 'ⅾ': 'd', 'ⅽ': 'c', 'ⅿ': 'm',
+}
+
+// ASCIIHost returns the punycode form of a host, or the host unchanged if
+// it cannot be converted
+func ASCIIHost(host string) string {
+	ascii, err := idna.Punycode.ToASCII(host)
+	if err != nil {
+		return host
+	}
+	return ascii
+}
+
+// URLHost returns the host of a URL without userinfo or port, or an empty
+// string if the URL cannot be parsed
+func URLHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// SuspiciousHost reports whether host resembles DocsHost without being it,
+// and why. Hosts are compared case-insensitively, ignoring a trailing dot.
+func SuspiciousHost(host string) (string, bool) {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if ASCIIHost(host) == DocsHost {
+		return "", false
+	}
+
+	if isASCII(host) {
+		if d := editDistance(host, DocsHost); d >= 1 && d <= 2 {
+			return SuspiciousNearMiss, true
+		}
+		return "", false
+	}
+
+	// Fullwidth and other compatibility forms fold to ASCII under IDNA
+	// mapping; anything left is compared through its homoglyph skeleton
+	if mapped, err := idna.Lookup.ToUnicode(host); err == nil {
+		host = mapped
+	}
+	if editDistance(skeleton(host), DocsHost) <= 2 {
+		return SuspiciousHomoglyph, true
+	}
+	return "", false
+}
+
+// skeleton replaces known homoglyphs with the Latin letters they imitate
+func skeleton(host string) string {
+	return strings.Map(func(r rune) rune {
+		if latin, ok := homoglyphs[r]; ok {
+			return latin
+		}
+		return r
+	}, host)
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// editDistance returns the Levenshtein distance between a and b in runes
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
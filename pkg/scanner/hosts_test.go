@@ -0,0 +1,63 @@
+package scanner
+
+import "testing"
+
+func TestSuspiciousHost(t *testing.T) {
+	tests := []struct {
+		host   string
+		reason string
+	}{
+		{"docs.redhat.com", ""},
+		{"DOCS.redhat.com.", ""},
+		{"access.redhat.com", ""},
+		{"docs.openshift.com", ""},
+		{"example.com", ""},
+		{"docs.redhat.co", SuspiciousNearMiss},
+		{"docs.redhat.corn", SuspiciousNearMiss},
+		{"docs.rehdat.com", SuspiciousNearMiss},
+		{"dosc.redhat.com", SuspiciousNearMiss},
+		{"docs-redhat.com", SuspiciousNearMiss},
+		{"dоcs.redhat.com", SuspiciousHomoglyph},  // Cyrillic о
+		{"docs.rеdhаt.com", SuspiciousHomoglyph},  // Cyrillic е and а
+		{"ԁocs.redhat.corn", SuspiciousHomoglyph}, // homoglyph plus a near miss
+		{"ｄｏｃｓ.redhat.com", SuspiciousHomoglyph},  // fullwidth
+		{"доки.example.com", ""},
+	}
+
+	for _, tt := range tests {
+		reason, ok := SuspiciousHost(tt.host)
+		if reason != tt.reason || ok != (tt.reason != "") {
+			t.Errorf("SuspiciousHost(%q) = %q, %v; want %q", tt.host, reason, ok, tt.reason)
+		}
+	}
+}
+
+func TestASCIIHost(t *testing.T) {
+	if got := ASCIIHost("dоcs.redhat.com"); got != "xn--dcs-sed.redhat.com" {
+		t.Errorf("ASCIIHost() = %s, want xn--dcs-sed.redhat.com", got)
+	}
+	if got := ASCIIHost(DocsHost); got != DocsHost {
+		t.Errorf("ASCIIHost(%s) = %s", DocsHost, got)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "abc", 0},
+		{"abc", "ab", 1},
+		{"abc", "axc", 1},
+		{"abc", "bac", 2},
+		{"kitten", "sitting", 3},
+		{"dоcs", "docs", 1},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
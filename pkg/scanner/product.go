@@ -0,0 +1,172 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProductContext tells which product the files of a tree document, so that
+// links to the documentation of another product stand out. A file's front
+// matter takes precedence over its path.
+type ProductContext struct {
+	// FrontMatter are the keys of a YAML front matter that name the
+	// product of the file, e.g. "product"; the first one present is used
+	FrontMatter []string `json:"front_matter"`
+	// Paths map globs of files to the product they document; the first
+	// glob matching a file is used
+	Paths []ProductPath `json:"paths"`
+	// Versions are the versions to link the documentation of a product at
+	// when it is not versioned like OCP, e.g. "4" for ROSA; a product left
+	// out is linked at the version of the URL
+	Versions map[string]string `json:"versions,omitempty"`
+}
+
+// ProductPath maps a glob of files, matched like the patterns of
+// SetHistoricalPatterns, to a product slug such as
+// "red_hat_openshift_service_on_aws"
+type ProductPath struct {
+	Glob    string `json:"glob"`
+	Product string `json:"product"`
+}
+
+// productSlugRegex matches a product slug of a docs.redhat.com URL
+var productSlugRegex = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// DefaultProductContext returns the product context used without a
+// configuration file: a "product" front matter key, and the versions of the
+// managed OpenShift services, whose documentation is published as "4"
+func DefaultProductContext() *ProductContext {
+	return &ProductContext{
+		FrontMatter: []string{"product"},
+		Versions: map[string]string{
+			"red_hat_openshift_service_on_aws": "4",
+			"openshift_dedicated":              "4",
+		},
+	}
+}
+
+// LoadProductContextFile loads a product context from a JSON file
+func LoadProductContextFile(path string) (*ProductContext, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return LoadProductContext(f)
+}
+
+// LoadProductContext decodes and validates a product context
+func LoadProductContext(r io.Reader) (*ProductContext, error) {
+	var pc ProductContext
+	if err := json.NewDecoder(r).Decode(&pc); err != nil {
+		return nil, fmt.Errorf("failed to decode product context: %w", err)
+	}
+
+	for i, key := range pc.FrontMatter {
+		if strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("product context front_matter %d: empty key", i)
+		}
+	}
+	for i, p := range pc.Paths {
+		if p.Glob == "" || p.Product == "" {
+			return nil, fmt.Errorf("product context path %d: glob and product are required", i)
+		}
+		if _, err := path.Match(p.Glob, ""); err != nil {
+			return nil, fmt.Errorf("product context path %d: invalid glob %q: %w", i, p.Glob, err)
+		}
+		if !productSlugRegex.MatchString(p.Product) {
+			return nil, fmt.Errorf("product context path %d: invalid product %q (expected a slug such as openshift_container_platform)", i, p.Product)
+		}
+	}
+	for product, version := range pc.Versions {
+		if !productSlugRegex.MatchString(product) || version == "" || strings.ContainsAny(version, "/?# ") {
+			return nil, fmt.Errorf("product context versions: invalid entry %q: %q", product, version)
+		}
+	}
+
+	return &pc, nil
+}
+
+// Product returns the product the file at rel, a slash-separated path
+// relative to the scanned directory, declares in its front matter or by
+// its path, or "" when it declares none. A front matter value that is not
+// a product slug is ignored.
+func (pc *ProductContext) Product(rel string, content []byte) string {
+	if pc == nil {
+		return ""
+	}
+	if len(pc.FrontMatter) > 0 {
+		if fm := frontMatter(content); fm != nil {
+			for _, key := range pc.FrontMatter {
+				if product, ok := fm[key].(string); ok && productSlugRegex.MatchString(product) {
+					return product
+				}
+			}
+		}
+	}
+	rel = strings.ToLower(rel)
+	for _, p := range pc.Paths {
+		if matchPattern(strings.ToLower(p.Glob), rel) {
+			return p.Product
+		}
+	}
+	return ""
+}
+
+// Version returns the version to link the documentation of product at, or
+// version when the context sets none
+func (pc *ProductContext) Version(product, version string) string {
+	if pc != nil && pc.Versions[product] != "" {
+		return pc.Versions[product]
+	}
+	return version
+}
+
+// frontMatter decodes the YAML front matter at the start of content,
+// between two "---" lines, or returns nil when there is none or it is not
+// a mapping
+func frontMatter(content []byte) map[string]any {
+	rest, ok := cutLine(content, "---")
+	if !ok {
+		return nil
+	}
+	var block []byte
+	for len(rest) > 0 {
+		line, next, _ := bytes.Cut(rest, []byte("\n"))
+		if trimmed := strings.TrimRight(string(line), " \t\r"); trimmed == "---" || trimmed == "..." {
+			var fm map[string]any
+			if err := yaml.Unmarshal(block, &fm); err != nil {
+				return nil
+			}
+			return fm
+		}
+		block = append(append(block, line...), '\n')
+		rest = next
+	}
+	return nil
+}
+
+// cutLine returns what follows the first line of content when that line is
+// want, trailing whitespace aside
+func cutLine(content []byte, want string) ([]byte, bool) {
+	line, rest, found := bytes.Cut(content, []byte("\n"))
+	if !found || strings.TrimRight(string(line), " \t\r") != want {
+		return nil, false
+	}
+	return rest, true
+}
+
+// SetProductContext sets the product context that fills in
+// Occurrence.Product; nil, the default, leaves it empty
+func (s *Scanner) SetProductContext(pc *ProductContext) {
+	s.productContext = pc
+}
@@ -0,0 +1,123 @@
+package scanner
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProductContext_Product(t *testing.T) {
+	pc := &ProductContext{
+		FrontMatter: []string{"product", "platform"},
+		Paths: []ProductPath{
+			{Glob: "docs/rosa/**", Product: "red_hat_openshift_service_on_aws"},
+			{Glob: "OSD-*.md", Product: "openshift_dedicated"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		rel     string
+		content string
+		want    string
+	}{
+		{"path glob", "docs/rosa/install.md", "# Install\n", "red_hat_openshift_service_on_aws"},
+		{"path glob ignores case", "Docs/ROSA/install.md", "", "red_hat_openshift_service_on_aws"},
+		{"file name glob", "guides/osd-upgrade.md", "", "openshift_dedicated"},
+		{"front matter", "README.md", "---\nproduct: openshift_dedicated\n---\n# Readme\n", "openshift_dedicated"},
+		{"front matter before the path", "docs/rosa/ocp.md", "---\ntitle: OCP\nproduct: openshift_container_platform\n---\n", "openshift_container_platform"},
+		{"second front matter key", "README.md", "---\nplatform: openshift_dedicated\n---\n", "openshift_dedicated"},
+		{"front matter closed with dots", "README.md", "---\nproduct: openshift_dedicated\n...\n", "openshift_dedicated"},
+		{"front matter with CRLF", "README.md", "---\r\nproduct: openshift_dedicated\r\n---\r\n", "openshift_dedicated"},
+		{"front matter value not a slug", "docs/rosa/a.md", "---\nproduct: ROSA classic\n---\n", "red_hat_openshift_service_on_aws"},
+		{"front matter not closed", "README.md", "---\nproduct: openshift_dedicated\n", ""},
+		{"rule later in the file", "README.md", "# Readme\n---\nproduct: openshift_dedicated\n---\n", ""},
+		{"malformed front matter", "README.md", "---\nproduct: [\n---\n", ""},
+		{"no declaration", "docs/guide.md", "# Guide\n", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pc.Product(tt.rel, []byte(tt.content)); got != tt.want {
+				t.Errorf("Product(%q) = %q, want %q", tt.rel, got, tt.want)
+			}
+		})
+	}
+
+	var none *ProductContext
+	if got := none.Product("docs/rosa/install.md", nil); got != "" {
+		t.Errorf("Product() without a context = %q, want none", got)
+	}
+}
+
+func TestLoadProductContext(t *testing.T) {
+	pc, err := LoadProductContextFile(filepath.Join("testdata", "products.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pc.Paths) != 2 || pc.Version("openshift_dedicated", "4.17") != "4" || pc.Version("openshift_container_platform", "4.17") != "4.17" {
+		t.Errorf("LoadProductContextFile() = %+v", pc)
+	}
+
+	invalid := map[string]string{
+		"not json":          `{"paths": [`,
+		"missing product":   `{"paths": [{"glob": "docs/**"}]}`,
+		"malformed glob":    `{"paths": [{"glob": "docs/[", "product": "openshift_dedicated"}]}`,
+		"product not slug":  `{"paths": [{"glob": "docs/**", "product": "OpenShift Dedicated"}]}`,
+		"empty key":         `{"front_matter": [" "]}`,
+		"empty version":     `{"versions": {"openshift_dedicated": ""}}`,
+		"version with path": `{"versions": {"openshift_dedicated": "4/html"}}`,
+	}
+	for name, config := range invalid {
+		if _, err := LoadProductContext(strings.NewReader(config)); err == nil {
+			t.Errorf("%s: LoadProductContext() succeeded, want error", name)
+		}
+	}
+}
+
+func TestScan_ProductContext(t *testing.T) {
+	root := filepath.Join("testdata", "products")
+	pc, err := LoadProductContextFile(filepath.Join("testdata", "products.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := New()
+	s.SetProductContext(pc)
+	locations, err := s.Scan(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]string)
+	for _, loc := range locations {
+		for _, occ := range loc.Occurrences {
+			rel, _ := filepath.Rel(root, occ.Path)
+			got[filepath.ToSlash(rel)] = occ.Product
+		}
+	}
+	want := map[string]string{
+		"README.md":                "",
+		"docs/rosa/install.md":     "red_hat_openshift_service_on_aws",
+		"docs/rosa/differences.md": "openshift_container_platform",
+		"docs/osd/networking.adoc": "openshift_dedicated",
+		"docs/shared/storage.md":   "red_hat_openshift_service_on_aws",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Product by file = %v, want %v", got, want)
+	}
+
+	// Without a context no file declares a product
+	locations, err = New().Scan(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, loc := range locations {
+		for _, occ := range loc.Occurrences {
+			if occ.Product != "" {
+				t.Errorf("%s: Product = %q without a context, want none", occ.Path, occ.Product)
+			}
+		}
+	}
+}
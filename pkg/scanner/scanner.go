@@ -0,0 +1,711 @@
+package scanner
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// urlRegex matches OCP documentation URLs embedded in text. A URL ends at
+// the delimiters of the syntax around it: Markdown links, <autolinks>, HTML
+// attributes and AsciiDoc link:url[text] macros. Loosely spelled versions
+// (04.16, 4.16.1, %204.16) are matched so the checker can flag them.
+var urlRegex = regexp.MustCompile(`https://(?i:docs\.redhat\.com)/[^\s)\]"<>\[]*openshift_container_platform/(?:%20)*\d+(?:\.\d+)+(?:%20)*/[^\s)\]"<>\[]*`)
+
+// hostURLRegex matches any http(s) URL, capturing its authority, to find
+// links whose host only resembles the documentation host
+var hostURLRegex = regexp.MustCompile(`https?://([^\s/?#)\]"'<>]+)[^\s)\]"<>\[]*`)
+
+// DefaultPlaceholderPatterns match template placeholders left in the
+// version segment of a documentation URL, e.g. X.Y, {version},
+// ${OCP_VERSION} or <version>
+var DefaultPlaceholderPatterns = []string{
+	`[Xx]\.[Yy]`,
+	`\{\{?\s*\.?[A-Za-z_]*[Vv]ersion\s*\}?\}`,
+	`\$\{?[A-Za-z_]*VERSION\}?`,
+	`<[A-Za-z_-]*version>`,
+}
+
+// markdownExtensions lists the extensions whose links are parsed for link text
+var markdownExtensions = map[string]bool{
+	".md":       true,
+	".markdown": true,
+}
+
+// SupportedExtensions lists the file extensions scanned when walking a directory
+var SupportedExtensions = map[string]bool{
+	".md":       true,
+	".markdown": true,
+	".txt":      true,
+	".adoc":     true,
+}
+
+// Occurrence is a single appearance of a documentation URL in a file
+type Occurrence struct {
+	URL    string
+	Path   string
+	Line   int // 1-based line number
+	Column int // 1-based column, counted in characters
+	Start  int // byte offset of the URL in the file
+	End    int // byte offset just past the URL
+
+	// Placeholder is true when the version segment is an unresolved
+	// template placeholder; such URLs must never be checked
+	Placeholder bool
+
+	// LinkText is the visible text when the URL is the target of a Markdown
+	// inline link, [text](url); LinkTextStart and LinkTextEnd are its byte
+	// offsets. All three are zero for any other occurrence.
+	LinkText      string
+	LinkTextStart int
+	LinkTextEnd   int
+
+	// Encoding is set when the URL was found by deep scanning inside an
+	// encoded value (EncodingBase64 or EncodingURL). Start and End are then
+	// zero: the URL does not appear in the file text and cannot be fixed in
+	// place. KeyPath locates the value, e.g. metadata.annotations.alm-examples.
+	Encoding string
+	KeyPath  string
+
+	// Suspicious is set to SuspiciousNearMiss or SuspiciousHomoglyph when
+	// the URL's host resembles DocsHost without being it; such URLs must
+	// never be checked
+	Suspicious string
+
+	// Historical is set for occurrences in a changelog or release notes,
+	// which record past versions on purpose; they are reported but never
+	// fixed and never fail a scan
+	Historical bool
+
+	// Generated is set for occurrences in a generated or minified file, to
+	// the detector that found it: GeneratedHeader, GeneratedMinified or
+	// GeneratedPattern. They are reported but never checked or fixed.
+	Generated string
+
+	// Product is the product slug the file declares with the product
+	// context of SetProductContext, empty when it declares none
+	Product string
+}
+
+// Extractor finds documentation URLs in the content of a file, for formats
+// the built-in search does not understand. Every occurrence needs its URL,
+// and Start and End spanning the URL in content exactly as written there:
+// that span is what a fix replaces. Occurrences with an empty span are
+// checked and reported but never fixed. The scanner fills in Path, and
+// Line and Column when Line is zero.
+type Extractor interface {
+	Extract(content []byte, path string) []Occurrence
+}
+
+// ExtractorFunc adapts a function to the Extractor interface
+type ExtractorFunc func(content []byte, path string) []Occurrence
+
+// Extract calls f(content, path)
+func (f ExtractorFunc) Extract(content []byte, path string) []Occurrence {
+	return f(content, path)
+}
+
+// Location tracks where a URL appears in the codebase
+type Location struct {
+	URL         string
+	Files       []string // Files where this URL appears
+	Occurrences []Occurrence
+	Placeholder bool
+	Suspicious  string
+	// Historical is set when every occurrence is a historical reference
+	Historical bool
+	// Generated is set when every occurrence is in a generated file
+	Generated bool
+}
+
+// Reasons a file is skipped during a scan, used as keys of Stats.FilesSkipped
+const (
+	SkipUnsupportedExtension = "unsupported-extension"
+	SkipReadError            = "read-error"
+	SkipParseError           = "parse-error"
+)
+
+// Stats describes the work done by a scan
+type Stats struct {
+	FilesVisited   int                      // files seen while walking
+	FilesMatched   int                      // files with an extension the scan searches
+	FilesScanned   int                      // files read and searched for URLs
+	FilesSkipped   map[string]int           // skipped files by reason
+	FilesGenerated map[string]int           // scanned files taken for generated, by detector
+	BytesScanned   int64                    // decoded bytes searched for URLs
+	TimePerExt     map[string]time.Duration // time spent reading and scanning, by extension
+	FilesPerExt    map[string]int           // scanned files by extension
+}
+
+// Scanner finds OCP documentation URLs in files and directories
+type Scanner struct {
+	// Warn is called for files that cannot be read during a directory scan.
+	// The scan continues with the remaining files.
+	Warn func(path string, err error)
+
+	// DeepScan also searches YAML and JSON files for URLs hidden in base64
+	// or URL-encoded string values
+	DeepScan bool
+
+	placeholderRegex   *regexp.Regexp
+	historicalPatterns []string
+	// detectGenerated enables the detection of generated files, by
+	// generatedPatterns, headers and lines longer than minifiedLineLength
+	detectGenerated    bool
+	generatedPatterns  []string
+	minifiedLineLength int
+	productContext     *ProductContext
+	trackingParams     []string
+	// extractors replace the built-in search by extension, and fallback
+	// searches files no other extractor covers; nil disables it
+	extractors map[string]Extractor
+	fallback   Extractor
+	stats      Stats
+}
+
+// New creates a new Scanner using the default placeholder patterns
+func New() *Scanner {
+	s := &Scanner{
+		extractors:         make(map[string]Extractor),
+		detectGenerated:    true,
+		minifiedLineLength: DefaultMinifiedLineLength,
+		trackingParams:     DefaultTrackingParams,
+		stats: Stats{
+			FilesSkipped:   make(map[string]int),
+			FilesGenerated: make(map[string]int),
+			TimePerExt:     make(map[string]time.Duration),
+			FilesPerExt:    make(map[string]int),
+		},
+	}
+	if err := s.SetPlaceholderPatterns(DefaultPlaceholderPatterns); err != nil {
+		panic(err) // the defaults are covered by tests
+	}
+	if err := s.SetHistoricalPatterns(DefaultHistoricalPatterns); err != nil {
+		panic(err)
+	}
+	if err := s.SetGeneratedPatterns(DefaultGeneratedPatterns); err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// SetPlaceholderPatterns replaces the regular expressions used to recognize
+// unresolved version placeholders. An empty list disables the detection.
+func (s *Scanner) SetPlaceholderPatterns(patterns []string) error {
+	if len(patterns) == 0 {
+		s.placeholderRegex = nil
+		return nil
+	}
+
+	for _, p := range patterns {
+		if _, err := regexp.Compile(p); err != nil {
+			return fmt.Errorf("invalid placeholder pattern %q: %w", p, err)
+		}
+	}
+
+	re, err := regexp.Compile(`https://docs\.redhat\.com/[^\s)\]"]*openshift_container_platform/(?:` +
+		strings.Join(patterns, "|") + `)/[^\s)\]"]*`)
+	if err != nil {
+		return fmt.Errorf("invalid placeholder patterns: %w", err)
+	}
+	s.placeholderRegex = re
+	return nil
+}
+
+// SetExtractor searches files with extension ext, e.g. ".sql", with e
+// instead of the built-in search or deep scan, and makes directory scans
+// include them. A nil e restores the built-in behavior.
+func (s *Scanner) SetExtractor(ext string, e Extractor) {
+	if e == nil {
+		delete(s.extractors, ext)
+		return
+	}
+	s.extractors[ext] = e
+}
+
+// SetFallbackExtractor searches every file that neither the built-in
+// search, deep scan nor an extractor set with SetExtractor covers with e,
+// so directory scans read every file. A nil e disables the fallback.
+func (s *Scanner) SetFallbackExtractor(e Extractor) {
+	s.fallback = e
+}
+
+// Stats returns the statistics accumulated by the scans run so far
+func (s *Scanner) Stats() Stats {
+	return s.stats
+}
+
+// Scan scans a file or recursively scans a directory and groups the
+// occurrences by URL, in order of first appearance, leaving out the
+// parameters of SetTrackingParams. Occurrences in files matching the
+// historical patterns are marked Historical, and those in generated files
+// Generated.
+func (s *Scanner) Scan(path string) ([]Location, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var occurrences []Occurrence
+	if info.IsDir() {
+		occurrences, err = s.ScanDirectory(path)
+	} else {
+		// A file named explicitly is scanned whatever its extension
+		s.stats.FilesVisited++
+		s.stats.FilesMatched++
+		occurrences, err = s.scanFile(path, filepath.Base(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.markHistorical(path, info.IsDir(), occurrences)
+
+	return group(occurrences, s.trackingParams), nil
+}
+
+// ScanDirectory recursively scans files with a supported extension
+func (s *Scanner) ScanDirectory(dir string) ([]Occurrence, error) {
+	var occurrences []Occurrence
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Skip directories
+		if d.IsDir() {
+			return nil
+		}
+
+		s.stats.FilesVisited++
+
+		// Check if file has supported extension
+		if !SupportedExtensions[filepath.Ext(path)] && !s.deepScanned(path) && s.extractor(path) == nil {
+			s.skip(SkipUnsupportedExtension)
+			return nil
+		}
+		s.stats.FilesMatched++
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			rel = filepath.Base(path)
+		}
+		fileOccurrences, err := s.scanFile(path, filepath.ToSlash(rel))
+		if err != nil {
+			if s.Warn != nil {
+				s.Warn(path, err)
+			}
+			return nil // Continue with other files
+		}
+
+		occurrences = append(occurrences, fileOccurrences...)
+		return nil
+	})
+
+	return occurrences, err
+}
+
+// ScanFile scans a single file for OCP documentation URLs. UTF-16 and
+// BOM-prefixed files are transcoded to UTF-8 first, so occurrence offsets
+// refer to the decoded text returned by ReadFile.
+func (s *Scanner) ScanFile(path string) ([]Occurrence, error) {
+	content, _, err := ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.ScanContent(path, content), nil
+}
+
+// scanFile scans a file, at rel relative to the scanned directory, marks
+// its occurrences when it is generated and with the product it declares,
+// and records its outcome in the scan statistics
+func (s *Scanner) scanFile(path, rel string) ([]Occurrence, error) {
+	start := time.Now()
+
+	content, _, err := ReadFile(path)
+	if err != nil {
+		s.skip(SkipReadError)
+		return nil, err
+	}
+
+	var occurrences []Occurrence
+	if s.deepScanned(path) {
+		occurrences, err = deepScanFile(path, content)
+		if err != nil {
+			s.skip(SkipParseError)
+			return nil, err
+		}
+	} else {
+		occurrences = s.ScanContent(path, content)
+	}
+
+	if detector := s.Generated(rel, content); detector != "" {
+		s.stats.FilesGenerated[detector]++
+		for i := range occurrences {
+			occurrences[i].Generated = detector
+		}
+	}
+	if product := s.productContext.Product(rel, content); product != "" {
+		for i := range occurrences {
+			occurrences[i].Product = product
+		}
+	}
+
+	ext := filepath.Ext(path)
+	s.stats.FilesScanned++
+	s.stats.BytesScanned += int64(len(content))
+	s.stats.TimePerExt[ext] += time.Since(start)
+	s.stats.FilesPerExt[ext]++
+
+	return occurrences, nil
+}
+
+// Extensions returns the sorted file extensions a directory scan searches
+func (s *Scanner) Extensions() []string {
+	set := make(map[string]bool)
+	for ext := range SupportedExtensions {
+		set[ext] = true
+	}
+	if s.DeepScan {
+		for ext := range DeepScanExtensions {
+			set[ext] = true
+		}
+	}
+	for ext := range s.extractors {
+		set[ext] = true
+	}
+
+	exts := make([]string, 0, len(set))
+	for ext := range set {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// deepScanned reports whether path is deep scanned instead of searched as text
+func (s *Scanner) deepScanned(path string) bool {
+	ext := filepath.Ext(path)
+	return s.DeepScan && DeepScanExtensions[ext] && s.extractors[ext] == nil
+}
+
+// extractor returns the extractor set for path, or nil when the built-in
+// search applies: an extractor set for its extension, else the fallback
+// for extensions the built-in search and deep scan do not cover
+func (s *Scanner) extractor(path string) Extractor {
+	ext := filepath.Ext(path)
+	if e, ok := s.extractors[ext]; ok {
+		return e
+	}
+	if SupportedExtensions[ext] || s.deepScanned(path) {
+		return nil
+	}
+	return s.fallback
+}
+
+// skip records a skipped file
+func (s *Scanner) skip(reason string) {
+	s.stats.FilesSkipped[reason]++
+}
+
+// ScanContent finds OCP documentation URLs, and URLs with an unresolved
+// version placeholder, in content read from path. Content of a path with an
+// extractor is searched by it instead.
+func (s *Scanner) ScanContent(path string, content []byte) []Occurrence {
+	if e := s.extractor(path); e != nil {
+		return extract(e, path, content)
+	}
+	return s.Extract(content, path)
+}
+
+// extract runs a custom extractor and completes the positions of its
+// occurrences, in order of their offsets
+func extract(e Extractor, path string, content []byte) []Occurrence {
+	occurrences := e.Extract(content, path)
+	lines := newLineIndex(content)
+	for i := range occurrences {
+		occ := &occurrences[i]
+		occ.Path = path
+		if occ.Line == 0 && occ.Start >= 0 && occ.Start <= len(content) {
+			occ.Line, occ.Column = lines.position(occ.Start)
+		}
+	}
+	sort.SliceStable(occurrences, func(i, j int) bool {
+		return occurrences[i].Start < occurrences[j].Start
+	})
+	return occurrences
+}
+
+// Extract is the built-in search of ScanContent, which makes Scanner an
+// Extractor: custom extractors can hand it the content they do not handle
+// themselves, such as a preprocessed template
+func (s *Scanner) Extract(content []byte, path string) []Occurrence {
+	lines := newLineIndex(content)
+	occurrences := findOccurrences(urlRegex, path, content, lines, false)
+
+	if s.placeholderRegex != nil {
+		occurrences = append(occurrences, findOccurrences(s.placeholderRegex, path, content, lines, true)...)
+	}
+	occurrences = append(occurrences, findSuspiciousHosts(path, content, lines)...)
+	sort.SliceStable(occurrences, func(i, j int) bool {
+		return occurrences[i].Start < occurrences[j].Start
+	})
+
+	if markdownExtensions[filepath.Ext(path)] {
+		for i := range occurrences {
+			occ := &occurrences[i]
+			if start, end, ok := markdownLinkText(content, occ.Start); ok {
+				occ.LinkText = string(content[start:end])
+				occ.LinkTextStart = start
+				occ.LinkTextEnd = end
+			}
+		}
+	}
+
+	return occurrences
+}
+
+// findOccurrences returns every match of re in content
+func findOccurrences(re *regexp.Regexp, path string, content []byte, lines *lineIndex, placeholder bool) []Occurrence {
+	var occurrences []Occurrence
+
+	for _, match := range re.FindAllIndex(content, -1) {
+		// Clean up URLs (remove trailing punctuation)
+		url := CleanURL(string(content[match[0]:match[1]]))
+		line, column := lines.position(match[0])
+
+		occurrences = append(occurrences, Occurrence{
+			URL:         url,
+			Path:        path,
+			Line:        line,
+			Column:      column,
+			Start:       match[0],
+			End:         match[0] + len(url),
+			Placeholder: placeholder,
+		})
+	}
+
+	return occurrences
+}
+
+// findSuspiciousHosts returns every URL whose host resembles DocsHost
+// without being it
+func findSuspiciousHosts(path string, content []byte, lines *lineIndex) []Occurrence {
+	var occurrences []Occurrence
+
+	for _, match := range hostURLRegex.FindAllSubmatchIndex(content, -1) {
+		host := string(content[match[2]:match[3]])
+		// Drop userinfo and port
+		if i := strings.LastIndex(host, "@"); i != -1 {
+			host = host[i+1:]
+		}
+		if i := strings.LastIndex(host, ":"); i != -1 {
+			host = host[:i]
+		}
+
+		reason, ok := SuspiciousHost(host)
+		if !ok {
+			continue
+		}
+
+		url := CleanURL(string(content[match[0]:match[1]]))
+		line, column := lines.position(match[0])
+		occurrences = append(occurrences, Occurrence{
+			URL:        url,
+			Path:       path,
+			Line:       line,
+			Column:     column,
+			Start:      match[0],
+			End:        match[0] + len(url),
+			Suspicious: reason,
+		})
+	}
+
+	return occurrences
+}
+
+// markdownLinkText returns the span of the text of the Markdown inline link
+// whose target starts at urlStart. Images, and link text running across a
+// blank line, are not links.
+func markdownLinkText(content []byte, urlStart int) (int, int, bool) {
+	if urlStart < 2 || content[urlStart-2] != ']' || content[urlStart-1] != '(' {
+		return 0, 0, false
+	}
+
+	end := urlStart - 2
+	depth := 0
+	for i := end - 1; i >= 0; i-- {
+		switch content[i] {
+		case ']':
+			depth++
+		case '[':
+			if depth > 0 {
+				depth--
+				continue
+			}
+			if i > 0 && content[i-1] == '!' {
+				return 0, 0, false
+			}
+			return i + 1, end, true
+		case '\n':
+			if i > 0 && content[i-1] == '\n' {
+				return 0, 0, false
+			}
+		}
+	}
+
+	return 0, 0, false
+}
+
+// CleanURL removes trailing punctuation picked up from surrounding prose
+func CleanURL(url string) string {
+	return strings.TrimRight(url, ".,;:!?")
+}
+
+// DefaultLocale is the locale segment docs.redhat.com redirects URLs
+// without one to
+const DefaultLocale = "en"
+
+// CanonicalURL returns the spelling equivalent spellings of a documentation
+// URL are grouped under: the scheme and host in lower case, the default
+// locale for a path without one and no trailing slash after the page. The
+// fragment is kept as is: ids are case-sensitive, so #Anchor and #anchor
+// are different sections.
+func CanonicalURL(url string) string {
+	rest, fragment, hasFragment := strings.Cut(url, "#")
+	rest, query, hasQuery := strings.Cut(rest, "?")
+	scheme, after, ok := strings.Cut(rest, "://")
+	if !ok {
+		return url
+	}
+	host, path, _ := strings.Cut(after, "/")
+	if MissingLocale(url) {
+		path = DefaultLocale + "/" + path
+	}
+
+	canonical := strings.ToLower(scheme) + "://" + strings.ToLower(host) + "/" + strings.TrimSuffix(path, "/")
+	if hasQuery {
+		canonical += "?" + query
+	}
+	if hasFragment {
+		canonical += "#" + fragment
+	}
+	return canonical
+}
+
+// MissingLocale reports whether a documentation URL is spelled without a
+// locale, as the legacy /documentation/... paths are
+func MissingLocale(url string) bool {
+	_, after, ok := strings.Cut(url, "://")
+	if !ok {
+		return false
+	}
+	_, path, _ := strings.Cut(after, "/")
+	return strings.HasPrefix(path, "documentation/")
+}
+
+// Group collects occurrences by canonical URL without the
+// DefaultTrackingParams, keeping the order of first appearance. Every
+// occurrence keeps its own spelling in URL, so a fix can replace exactly
+// the text it spans. Suspicious URLs are grouped as spelled.
+func Group(occurrences []Occurrence) []Location {
+	return group(occurrences, DefaultTrackingParams)
+}
+
+// group is Group, leaving out the tracking parameters trackingParams
+func group(occurrences []Occurrence, trackingParams []string) []Location {
+	var locations []Location
+	index := make(map[string]int)
+
+	for _, occ := range occurrences {
+		key := occ.URL
+		if occ.Suspicious == "" {
+			key = CanonicalURL(StripTrackingParams(occ.URL, trackingParams))
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(locations)
+			index[key] = i
+			locations = append(locations, Location{URL: key, Placeholder: occ.Placeholder, Suspicious: occ.Suspicious, Historical: occ.Historical, Generated: occ.Generated != ""})
+		}
+		locations[i].add(occ)
+	}
+
+	return locations
+}
+
+// add appends occ to the occurrences of loc
+func (loc *Location) add(occ Occurrence) {
+	loc.Historical = loc.Historical && occ.Historical
+	loc.Generated = loc.Generated && occ.Generated != ""
+	loc.Files = appendUnique(loc.Files, occ.Path)
+	loc.Occurrences = append(loc.Occurrences, occ)
+}
+
+// Partition splits the occurrences of locations by whether keep holds for
+// them. Each part keeps the URL the scan grouped its occurrences under;
+// locations left without occurrences are dropped.
+func Partition(locations []Location, keep func(Occurrence) bool) (kept, rest []Location) {
+	for _, loc := range locations {
+		var in, out *Location
+		for _, occ := range loc.Occurrences {
+			part := &out
+			if keep(occ) {
+				part = &in
+			}
+			if *part == nil {
+				*part = &Location{URL: loc.URL, Placeholder: loc.Placeholder, Suspicious: loc.Suspicious, Historical: occ.Historical, Generated: occ.Generated != ""}
+			}
+			(*part).add(occ)
+		}
+		if in != nil {
+			kept = append(kept, *in)
+		}
+		if out != nil {
+			rest = append(rest, *out)
+		}
+	}
+	return kept, rest
+}
+
+// appendUnique appends s unless it is already present
+func appendUnique(list []string, s string) []string {
+	for _, existing := range list {
+		if existing == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+// lineIndex converts byte offsets into line and column numbers
+type lineIndex struct {
+	content []byte
+	starts  []int
+}
+
+func newLineIndex(content []byte) *lineIndex {
+	starts := []int{0}
+	for i, b := range content {
+		if b == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return &lineIndex{content: content, starts: starts}
+}
+
+// position returns the 1-based line and column of a byte offset
+func (l *lineIndex) position(offset int) (int, int) {
+	// Find the last line starting at or before offset
+	line := sort.Search(len(l.starts), func(i int) bool { return l.starts[i] > offset }) - 1
+
+	column := utf8.RuneCount(l.content[l.starts[line]:offset]) + 1
+	return line + 1, column
+}
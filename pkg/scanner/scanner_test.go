@@ -0,0 +1,550 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestScanContent(t *testing.T) {
+	content := []byte("Intro line\n" +
+		"See https://docs.redhat.com/en/documentation/openshift_container_platform/4.17/html/networking/index.\n" +
+		"Ünïcode [link](https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html-single/storage/index#pv)\n")
+
+	got := New().ScanContent("doc.md", content)
+	if len(got) != 2 {
+		t.Fatalf("ScanContent() found %d URLs, want 2", len(got))
+	}
+
+	tests := []struct {
+		url    string
+		line   int
+		column int
+	}{
+		{"https://docs.redhat.com/en/documentation/openshift_container_platform/4.17/html/networking/index", 2, 5},
+		{"https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html-single/storage/index#pv", 3, 16},
+	}
+
+	for i, tt := range tests {
+		occ := got[i]
+		if occ.URL != tt.url {
+			t.Errorf("occurrence %d URL = %s, want %s", i, occ.URL, tt.url)
+		}
+		if occ.Line != tt.line || occ.Column != tt.column {
+			t.Errorf("occurrence %d position = %d:%d, want %d:%d", i, occ.Line, occ.Column, tt.line, tt.column)
+		}
+		if string(content[occ.Start:occ.End]) != occ.URL {
+			t.Errorf("occurrence %d span = %q, want %q", i, content[occ.Start:occ.End], occ.URL)
+		}
+		if occ.Path != "doc.md" {
+			t.Errorf("occurrence %d Path = %s, want doc.md", i, occ.Path)
+		}
+	}
+}
+
+func TestScanContent_LooseVersions(t *testing.T) {
+	const base = "https://docs.redhat.com/en/documentation/openshift_container_platform/"
+
+	for _, version := range []string{"4.09", "04.16", "4.16.1", "%204.16", "4.16%20"} {
+		t.Run(version, func(t *testing.T) {
+			url := base + version + "/html/networking/index"
+			got := New().ScanContent("doc.md", []byte("See "+url+" for details.\n"))
+			if len(got) != 1 || got[0].URL != url {
+				t.Errorf("ScanContent() = %+v, want one occurrence of %s", got, url)
+			}
+		})
+	}
+}
+
+func TestScanContent_MarkdownLinkText(t *testing.T) {
+	const u = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.14/html/networking/index"
+
+	tests := []struct {
+		name    string
+		path    string
+		content string
+		want    string
+	}{
+		{"inline link", "doc.md", "See [OpenShift 4.14 networking guide](" + u + ").", "OpenShift 4.14 networking guide"},
+		{"nested brackets", "doc.markdown", "[the [4.14] guide](" + u + ")", "the [4.14] guide"},
+		{"text across a line break", "doc.md", "[OpenShift\n4.14 guide](" + u + ")", "OpenShift\n4.14 guide"},
+		{"image", "doc.md", "![4.14 diagram](" + u + ")", ""},
+		{"blank line", "doc.md", "[4.14\n\nguide](" + u + ")", ""},
+		{"bare URL", "doc.md", "See " + u, ""},
+		{"not markdown", "doc.txt", "[4.14 guide](" + u + ")", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := []byte(tt.content)
+			got := New().ScanContent(tt.path, content)
+			if len(got) != 1 {
+				t.Fatalf("ScanContent() found %d URLs, want 1", len(got))
+			}
+
+			occ := got[0]
+			if occ.LinkText != tt.want {
+				t.Errorf("LinkText = %q, want %q", occ.LinkText, tt.want)
+			}
+			if string(content[occ.LinkTextStart:occ.LinkTextEnd]) != tt.want {
+				t.Errorf("link text span = %q, want %q", content[occ.LinkTextStart:occ.LinkTextEnd], tt.want)
+			}
+		})
+	}
+}
+
+func TestScanContent_SuspiciousHosts(t *testing.T) {
+	content := []byte("Real: https://docs.redhat.com/en/documentation/openshift_container_platform/4.17/html/networking/index\n" +
+		"Spoof: [guide](https://dоcs.redhat.com/en/documentation/openshift_container_platform/4.17/html/networking/index)\n" +
+		"Typo: https://user@docs.redhat.co:443/en/documentation/openshift_container_platform/4.17/html/storage/index.\n" +
+		"Other: https://example.com/docs.redhat.com\n")
+
+	got := New().ScanContent("doc.md", content)
+	if len(got) != 3 {
+		t.Fatalf("ScanContent() found %d URLs, want 3: %+v", len(got), got)
+	}
+
+	tests := []struct {
+		line       int
+		column     int
+		suspicious string
+	}{
+		{1, 7, ""},
+		{2, 16, SuspiciousHomoglyph},
+		{3, 7, SuspiciousNearMiss},
+	}
+
+	for i, tt := range tests {
+		occ := got[i]
+		if occ.Line != tt.line || occ.Column != tt.column || occ.Suspicious != tt.suspicious {
+			t.Errorf("occurrence %d = %d:%d suspicious %q, want %d:%d suspicious %q",
+				i, occ.Line, occ.Column, occ.Suspicious, tt.line, tt.column, tt.suspicious)
+		}
+		if string(content[occ.Start:occ.End]) != occ.URL {
+			t.Errorf("occurrence %d span = %q, want %q", i, content[occ.Start:occ.End], occ.URL)
+		}
+	}
+
+	if locations := Group(got); locations[1].Suspicious != SuspiciousHomoglyph {
+		t.Errorf("Group() Suspicious = %q, want %q", locations[1].Suspicious, SuspiciousHomoglyph)
+	}
+}
+
+func TestCleanURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"https://docs.redhat.com/x/index", "https://docs.redhat.com/x/index"},
+		{"https://docs.redhat.com/x/index.", "https://docs.redhat.com/x/index"},
+		{"https://docs.redhat.com/x/index#a,", "https://docs.redhat.com/x/index#a"},
+		{"https://docs.redhat.com/x/index?!;:", "https://docs.redhat.com/x/index"},
+	}
+
+	for _, tt := range tests {
+		if got := CleanURL(tt.in); got != tt.want {
+			t.Errorf("CleanURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestScan_Directory(t *testing.T) {
+	root := filepath.Join("testdata", "tree")
+
+	locations, err := New().Scan(root)
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	if len(locations) != 2 {
+		t.Fatalf("Scan() found %d unique URLs, want 2: %+v", len(locations), locations)
+	}
+
+	// Locations keep the order of first appearance in the walk
+	disconnected := locations[0]
+	if disconnected.URL != "https://docs.redhat.com/en/documentation/openshift_container_platform/4.17/html-single/disconnected_environments/index#mirroring-image-set-full" {
+		t.Errorf("first URL = %s", disconnected.URL)
+	}
+	if len(disconnected.Files) != 2 || len(disconnected.Occurrences) != 2 {
+		t.Errorf("first URL found in %d files with %d occurrences, want 2 and 2", len(disconnected.Files), len(disconnected.Occurrences))
+	}
+
+	// The URL in script.js is ignored because .js is not a supported extension
+	networking := locations[1]
+	if networking.URL != "https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html/networking/index" {
+		t.Errorf("second URL = %s", networking.URL)
+	}
+}
+
+func TestScan_SingleFileIgnoresExtension(t *testing.T) {
+	locations, err := New().Scan(filepath.Join("testdata", "tree", "docs", "script.js"))
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if len(locations) != 1 {
+		t.Fatalf("Scan() found %d URLs, want 1", len(locations))
+	}
+}
+
+func TestGroup(t *testing.T) {
+	occurrences := []Occurrence{
+		{URL: "b", Path: "one.md", Line: 1},
+		{URL: "a", Path: "one.md", Line: 2},
+		{URL: "b", Path: "one.md", Line: 3},
+		{URL: "b", Path: "two.md", Line: 1},
+	}
+
+	got := Group(occurrences)
+	if len(got) != 2 || got[0].URL != "b" || got[1].URL != "a" {
+		t.Fatalf("Group() = %+v, want b then a", got)
+	}
+	if len(got[0].Files) != 2 || len(got[0].Occurrences) != 3 {
+		t.Errorf("Group() b has %d files and %d occurrences, want 2 and 3", len(got[0].Files), len(got[0].Occurrences))
+	}
+}
+
+func TestCanonicalURL(t *testing.T) {
+	const page = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html/networking/index"
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{page, page},
+		{page + "#ingress", page + "#ingress"},
+		{page + "/", page},
+		{page + "/#ingress", page + "#ingress"},
+		{page + "/?lang=en#ingress", page + "?lang=en#ingress"},
+		{"HTTPS://DOCS.RedHat.com/en/documentation/openshift_container_platform/4.16/html/networking/index#ingress", page + "#ingress"},
+		// Ids are case-sensitive and the path is not lowered
+		{page + "#Ingress", page + "#Ingress"},
+		{"https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html/Networking/index", "https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html/Networking/index"},
+		// A path without a locale is grouped with the default locale
+		{"https://docs.redhat.com/documentation/openshift_container_platform/4.16/html/networking/index/#ingress", page + "#ingress"},
+		{"not a url", "not a url"},
+	}
+
+	for _, tt := range tests {
+		if got := CanonicalURL(tt.in); got != tt.want {
+			t.Errorf("CanonicalURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestScan_GroupsSpellings(t *testing.T) {
+	const page = "docs.redhat.com/en/documentation/openshift_container_platform/4.16/html/networking/index"
+	content := "[a](https://" + page + "#ingress)\n" +
+		"<https://DOCS.REDHAT.COM/en/documentation/openshift_container_platform/4.16/html/networking/index#ingress>\n" +
+		"https://" + page + "/#ingress\n" +
+		"https://" + page + "#Ingress\n"
+
+	locations := Group(New().ScanContent("doc.md", []byte(content)))
+	if len(locations) != 2 {
+		t.Fatalf("Group() = %d locations, want 2: %+v", len(locations), locations)
+	}
+	if locations[0].URL != "https://"+page+"#ingress" || len(locations[0].Occurrences) != 3 {
+		t.Errorf("first location = %s with %d occurrences, want the canonical URL with 3", locations[0].URL, len(locations[0].Occurrences))
+	}
+	if locations[1].URL != "https://"+page+"#Ingress" {
+		t.Errorf("second location = %s, want the #Ingress section", locations[1].URL)
+	}
+
+	// Every occurrence keeps the exact text it spans
+	for _, occ := range locations[0].Occurrences {
+		if got := content[occ.Start:occ.End]; got != occ.URL {
+			t.Errorf("occurrence at line %d spans %q, want its URL %q", occ.Line, got, occ.URL)
+		}
+	}
+}
+
+func TestScanContent_Placeholders(t *testing.T) {
+	content, err := os.ReadFile(filepath.Join("testdata", "placeholders.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := New().ScanContent("placeholders.md", content)
+
+	want := []struct {
+		line        int
+		version     string
+		placeholder bool
+	}{
+		{3, "/4.18/", false},
+		{4, "/X.Y/", true},
+		{5, "/{version}/", true},
+		{6, "/{{ .Version }}/", true},
+		{7, "/${OCP_VERSION}/", true},
+		{8, "/<version>/", true},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("ScanContent() found %d occurrences, want %d: %+v", len(got), len(want), got)
+	}
+
+	for i, w := range want {
+		if got[i].Line != w.line {
+			t.Errorf("occurrence %d Line = %d, want %d", i, got[i].Line, w.line)
+		}
+		if !strings.Contains(got[i].URL, w.version) {
+			t.Errorf("occurrence %d URL = %s, want version segment %s", i, got[i].URL, w.version)
+		}
+		if got[i].Placeholder != w.placeholder {
+			t.Errorf("occurrence %d Placeholder = %v, want %v", i, got[i].Placeholder, w.placeholder)
+		}
+	}
+}
+
+func TestSetPlaceholderPatterns(t *testing.T) {
+	content := []byte("https://docs.redhat.com/en/documentation/openshift_container_platform/@@OCP@@/html/networking/index\n" +
+		"https://docs.redhat.com/en/documentation/openshift_container_platform/X.Y/html/networking/index\n")
+
+	s := New()
+	if err := s.SetPlaceholderPatterns([]string{`@@OCP@@`}); err != nil {
+		t.Fatalf("SetPlaceholderPatterns() error = %v", err)
+	}
+
+	got := s.ScanContent("custom.md", content)
+	if len(got) != 1 || got[0].Line != 1 || !got[0].Placeholder {
+		t.Errorf("custom patterns found %+v, want only the @@OCP@@ URL", got)
+	}
+
+	if err := s.SetPlaceholderPatterns(nil); err != nil {
+		t.Fatalf("SetPlaceholderPatterns(nil) error = %v", err)
+	}
+	if got := s.ScanContent("custom.md", content); len(got) != 0 {
+		t.Errorf("disabled detection still found %+v", got)
+	}
+
+	if err := s.SetPlaceholderPatterns([]string{`(`}); err == nil {
+		t.Error("SetPlaceholderPatterns() accepted an invalid regular expression")
+	}
+}
+
+func TestScan_Stats(t *testing.T) {
+	root := filepath.Join("testdata", "tree")
+
+	s := New()
+	if _, err := s.Scan(root); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	var wantBytes int64
+	for _, f := range []string{"README.md", filepath.Join("docs", "install.adoc")} {
+		info, err := os.Stat(filepath.Join(root, f))
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantBytes += info.Size()
+	}
+
+	stats := s.Stats()
+	if stats.FilesVisited != 3 {
+		t.Errorf("FilesVisited = %d, want 3", stats.FilesVisited)
+	}
+	if stats.FilesMatched != 2 {
+		t.Errorf("FilesMatched = %d, want 2", stats.FilesMatched)
+	}
+	if stats.FilesScanned != 2 {
+		t.Errorf("FilesScanned = %d, want 2", stats.FilesScanned)
+	}
+	if len(stats.FilesSkipped) != 1 || stats.FilesSkipped[SkipUnsupportedExtension] != 1 {
+		t.Errorf("FilesSkipped = %v, want 1 %s", stats.FilesSkipped, SkipUnsupportedExtension)
+	}
+	if stats.BytesScanned != wantBytes {
+		t.Errorf("BytesScanned = %d, want %d", stats.BytesScanned, wantBytes)
+	}
+	if stats.FilesPerExt[".md"] != 1 || stats.FilesPerExt[".adoc"] != 1 || len(stats.FilesPerExt) != 2 {
+		t.Errorf("FilesPerExt = %v, want one .md and one .adoc", stats.FilesPerExt)
+	}
+	if _, ok := stats.TimePerExt[".adoc"]; !ok || len(stats.TimePerExt) != 2 {
+		t.Errorf("TimePerExt = %v, want entries for .md and .adoc", stats.TimePerExt)
+	}
+}
+
+func TestScan_StatsReadError(t *testing.T) {
+	dir := t.TempDir()
+	// A dangling symlink has a supported extension but cannot be read
+	if err := os.Symlink(filepath.Join(dir, "missing"), filepath.Join(dir, "broken.md")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	var warned []string
+	s := New()
+	s.Warn = func(path string, err error) {
+		warned = append(warned, path)
+	}
+
+	if _, err := s.Scan(dir); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if got := s.Stats().FilesMatched; got != 1 {
+		t.Errorf("FilesMatched = %d, want 1 for an unreadable supported file", got)
+	}
+	if got := s.Stats().FilesSkipped[SkipReadError]; got != 1 {
+		t.Errorf("FilesSkipped[%s] = %d, want 1", SkipReadError, got)
+	}
+	if len(warned) != 1 {
+		t.Errorf("Warn called %d times, want 1", len(warned))
+	}
+}
+
+func TestScan_NoSupportedFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"main.js", "data.json"} {
+		content := "https://docs.redhat.com/en/documentation/openshift_container_platform/4.17/html/networking/index"
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		deepScan bool
+		matched  int
+	}{
+		{"plain", false, 0},
+		{"deep scan", true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			s.DeepScan = tt.deepScan
+			if _, err := s.Scan(dir); err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+
+			stats := s.Stats()
+			if stats.FilesVisited != 2 || stats.FilesMatched != tt.matched {
+				t.Errorf("FilesVisited = %d, FilesMatched = %d; want 2, %d", stats.FilesVisited, stats.FilesMatched, tt.matched)
+			}
+			if exts := s.Extensions(); tt.deepScan != slices.Contains(exts, ".json") {
+				t.Errorf("Extensions() = %v with DeepScan %v", exts, tt.deepScan)
+			}
+		})
+	}
+}
+
+// sqlValues extracts the URLs quoted as values in an SQL export, which the
+// built-in search does not read
+var sqlValues = ExtractorFunc(func(content []byte, path string) []Occurrence {
+	var occurrences []Occurrence
+	for _, m := range regexp.MustCompile(`'(https://[^']*)'`).FindAllSubmatchIndex(content, -1) {
+		occurrences = append(occurrences, Occurrence{URL: string(content[m[2]:m[3]]), Start: m[2], End: m[3]})
+	}
+	return occurrences
+})
+
+func TestScan_Extractors(t *testing.T) {
+	const url = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.17/html/networking/index"
+	dir := t.TempDir()
+	files := map[string]string{
+		"doc.md":    "See " + url + "\n",
+		"links.sql": "INSERT INTO links VALUES\n  (1, '" + url + "#ingress');\n",
+		"page.tmpl": `{{ link "` + url + `#routes" }}` + "\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		setup     func(*Scanner)
+		wantFiles []string
+	}{
+		{"built-in only", func(*Scanner) {}, []string{"doc.md"}},
+		{"by extension", func(s *Scanner) { s.SetExtractor(".sql", sqlValues) }, []string{"doc.md", "links.sql"}},
+		{"replacing the built-in search", func(s *Scanner) {
+			s.SetExtractor(".md", ExtractorFunc(func([]byte, string) []Occurrence { return nil }))
+		}, nil},
+		{"removed again", func(s *Scanner) {
+			s.SetExtractor(".sql", sqlValues)
+			s.SetExtractor(".sql", nil)
+		}, []string{"doc.md"}},
+		{"fallback", func(s *Scanner) { s.SetFallbackExtractor(New()) }, []string{"doc.md", "links.sql", "page.tmpl"}},
+		{"extension before fallback", func(s *Scanner) {
+			s.SetFallbackExtractor(New())
+			s.SetExtractor(".tmpl", ExtractorFunc(func([]byte, string) []Occurrence { return nil }))
+		}, []string{"doc.md", "links.sql"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			tt.setup(s)
+			occurrences, err := s.ScanDirectory(dir)
+			if err != nil {
+				t.Fatalf("ScanDirectory() error = %v", err)
+			}
+
+			var gotFiles []string
+			for _, occ := range occurrences {
+				gotFiles = append(gotFiles, filepath.Base(occ.Path))
+				content := files[filepath.Base(occ.Path)]
+				if content[occ.Start:occ.End] != occ.URL || occ.Line == 0 || occ.Column == 0 {
+					t.Errorf("occurrence %+v does not span its URL at a position", occ)
+				}
+			}
+			slices.Sort(gotFiles)
+			if !slices.Equal(gotFiles, tt.wantFiles) {
+				t.Errorf("ScanDirectory() found URLs in %v, want %v", gotFiles, tt.wantFiles)
+			}
+		})
+	}
+
+	s := New()
+	s.SetExtractor(".sql", sqlValues)
+	occurrences, err := s.ScanFile(filepath.Join(dir, "links.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(occurrences) != 1 || occurrences[0].Line != 2 || occurrences[0].Column != 8 {
+		t.Errorf("ScanFile() = %+v, want one URL at 2:8", occurrences)
+	}
+	if !slices.Contains(s.Extensions(), ".sql") {
+		t.Errorf("Extensions() = %v, want .sql included", s.Extensions())
+	}
+}
+
+func FuzzScanContent(f *testing.F) {
+	seeds := []string{
+		"See https://docs.redhat.com/en/documentation/openshift_container_platform/4.17/html/networking/index.\n",
+		"[4.16 storage](https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html-single/storage/index#pv)",
+		"https://docs.redhat.com/en/documentation/openshift_container_platform/{{ .Version }}/html/networking/index",
+		"https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html/operators/index#a#a),;",
+		"Ünïcode\r\nhttps://dоcs.redhat.com/en/documentation/openshift_container_platform/4.16/html/x/y?!;:",
+		"[](https://docs.redhat.com/en/documentation/openshift_container_platform/4.1/a/b/c.",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, text string) {
+		content := []byte(text)
+		for _, occ := range New().ScanContent("doc.md", content) {
+			if occ.Start < 0 || occ.End > len(content) || string(content[occ.Start:occ.End]) != occ.URL {
+				t.Fatalf("occurrence %q does not match content[%d:%d]", occ.URL, occ.Start, occ.End)
+			}
+			if CleanURL(occ.URL) != occ.URL {
+				t.Errorf("occurrence %q is not clean", occ.URL)
+			}
+			if line := strings.Count(text[:occ.Start], "\n") + 1; occ.Line != line || occ.Column < 1 {
+				t.Errorf("occurrence %q at line %d column %d, want line %d", occ.URL, occ.Line, occ.Column, line)
+			}
+			if occ.LinkText != "" && (occ.LinkTextEnd > occ.Start || string(content[occ.LinkTextStart:occ.LinkTextEnd]) != occ.LinkText) {
+				t.Errorf("link text %q of %q does not match content[%d:%d]", occ.LinkText, occ.URL, occ.LinkTextStart, occ.LinkTextEnd)
+			}
+		}
+
+		cleaned := CleanURL(text)
+		if !strings.HasPrefix(text, cleaned) || CleanURL(cleaned) != cleaned {
+			t.Errorf("CleanURL(%q) = %q, want an idempotent prefix", text, cleaned)
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package scanner
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	"github.com/sebrandon1/ocp-doc-checker/pkg/parser"
+)
+
+// Shard selects one of Count disjoint parts of the unique URLs of a scan,
+// so that Count parallel jobs together check every URL exactly once. Index
+// counts from 1.
+type Shard struct {
+	Index int
+	Count int
+}
+
+// ParseShard parses a shard written as N/M, e.g. 2/4
+func ParseShard(s string) (Shard, error) {
+	n, m, ok := strings.Cut(s, "/")
+	index, errN := strconv.Atoi(n)
+	count, errM := strconv.Atoi(m)
+	if !ok || errN != nil || errM != nil {
+		return Shard{}, fmt.Errorf("invalid shard %q (expected N/M, e.g. 2/4)", s)
+	}
+	if count < 1 || index < 1 || index > count {
+		return Shard{}, fmt.Errorf("invalid shard %q (expected 1 <= N <= M)", s)
+	}
+	return Shard{Index: index, Count: count}, nil
+}
+
+// String returns the shard as N/M
+func (s Shard) String() string {
+	return fmt.Sprintf("%d/%d", s.Index, s.Count)
+}
+
+// Owns reports whether url belongs to the shard. The assignment depends
+// only on the URL and the shard count: it does not change between runs,
+// machines or releases, nor with the other URLs of the scan. The html and
+// html-single spellings of a section always share a shard, so that every
+// shard reports them together.
+func (s Shard) Owns(url string) bool {
+	return int(shardHash(url)%uint32(s.Count)) == s.Index-1
+}
+
+// shardHash is the FNV-1a hash of the shard key of url: its section key
+// when it is a documentation URL, else the URL itself
+func shardHash(url string) uint32 {
+	key := url
+	if docURL, err := parser.ParseOCPDocURL(url); err == nil {
+		key = docURL.SectionKey()
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return h.Sum32()
+}
+
+// FilterShard returns the locations whose URL belongs to the shard
+func FilterShard(locations []Location, s Shard) []Location {
+	var owned []Location
+	for _, loc := range locations {
+		if s.Owns(loc.URL) {
+			owned = append(owned, loc)
+		}
+	}
+	return owned
+}
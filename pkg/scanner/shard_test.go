@@ -0,0 +1,101 @@
+package scanner
+
+import (
+	"fmt"
+	"testing"
+)
+
+const shardBase = "https://docs.redhat.com/en/documentation/openshift_container_platform/"
+
+func TestParseShard(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Shard
+		wantErr bool
+	}{
+		{"1/1", Shard{1, 1}, false},
+		{"2/4", Shard{2, 4}, false},
+		{"4/4", Shard{4, 4}, false},
+		{"0/4", Shard{}, true},
+		{"5/4", Shard{}, true},
+		{"1/0", Shard{}, true},
+		{"-1/4", Shard{}, true},
+		{"2", Shard{}, true},
+		{"a/b", Shard{}, true},
+		{"", Shard{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseShard(tt.in)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ParseShard(%q) = %v, %v, want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestShard_Stable pins the shard of a few URLs. Changing the assignment
+// would mix the reports of jobs running different releases, so these must
+// never change.
+func TestShard_Stable(t *testing.T) {
+	tests := []struct {
+		url   string
+		count int
+		want  int
+	}{
+		{shardBase + "4.17/html-single/networking/index#configuring-ingress", 4, 1},
+		{shardBase + "4.17/html/networking/configuring-ingress-cluster-traffic#configuring-ingress", 4, 1},
+		{shardBase + "4.16/html-single/storage/index", 4, 2},
+		{"https://docs.redhat.com/not-a-doc-url", 4, 2},
+	}
+
+	for _, tt := range tests {
+		got := 0
+		for i := 1; i <= tt.count; i++ {
+			if (Shard{Index: i, Count: tt.count}).Owns(tt.url) {
+				got = i
+			}
+		}
+		if got != tt.want {
+			t.Errorf("shard of %s = %d/%d, want %d/%d", tt.url, got, tt.count, tt.want, tt.count)
+		}
+	}
+}
+
+func TestFilterShard_Partition(t *testing.T) {
+	var locations []Location
+	for minor := 10; minor < 20; minor++ {
+		for _, doc := range []string{"networking", "storage", "installing", "security"} {
+			locations = append(locations, Location{URL: fmt.Sprintf("%s4.%d/html-single/%s/index", shardBase, minor, doc)})
+		}
+	}
+
+	for _, count := range []int{1, 2, 3, 7} {
+		owner := make(map[string]int)
+		for i := 1; i <= count; i++ {
+			for _, loc := range FilterShard(locations, Shard{Index: i, Count: count}) {
+				if prev, ok := owner[loc.URL]; ok {
+					t.Errorf("%d shards: %s in shard %d and %d", count, loc.URL, prev, i)
+				}
+				owner[loc.URL] = i
+			}
+		}
+		if len(owner) != len(locations) {
+			t.Errorf("%d shards: %d of %d URLs assigned", count, len(owner), len(locations))
+		}
+	}
+}
+
+func TestShard_SpellingsTogether(t *testing.T) {
+	single := shardBase + "4.17/html-single/networking/index#configuring-ingress"
+	multi := shardBase + "4.17/html/networking/configuring-ingress-cluster-traffic#configuring-ingress"
+	for count := 1; count <= 16; count++ {
+		for i := 1; i <= count; i++ {
+			s := Shard{Index: i, Count: count}
+			if s.Owns(single) != s.Owns(multi) {
+				t.Fatalf("shard %s splits the spellings of a section", s)
+			}
+		}
+	}
+}
@@ -0,0 +1 @@
+// https://docs.redhat.com/en/documentation/openshift_container_platform/4.12/html/networking/index
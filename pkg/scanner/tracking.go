@@ -0,0 +1,90 @@
+package scanner
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// DefaultTrackingParams are the query parameters that marketing and
+// analytics tools append to links, which never change the page served
+var DefaultTrackingParams = []string{
+	"sc_cid",
+	"utm_*",
+	"extIdCarryOver",
+	"intcmp",
+}
+
+// SetTrackingParams replaces the names of the query parameters left out
+// when grouping the spellings of a URL, so that links differing only by
+// campaign parameters are checked once. A name may be a glob such as
+// utm_*; matching ignores case. Every occurrence keeps its spelling. An
+// empty list disables the stripping.
+func (s *Scanner) SetTrackingParams(params []string) error {
+	for _, p := range params {
+		if p == "" {
+			return fmt.Errorf("empty tracking parameter")
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid tracking parameter %q: %w", p, err)
+		}
+	}
+	s.trackingParams = params
+	return nil
+}
+
+// StripTrackingParams returns url without the query parameters matching
+// params, keeping the other parameters in order and the fragment as
+// written. A URL without such parameters is returned unchanged.
+func StripTrackingParams(url string, params []string) string {
+	rest, fragment, hasFragment := strings.Cut(url, "#")
+	base, query, hasQuery := strings.Cut(rest, "?")
+	if !hasQuery || len(params) == 0 {
+		return url
+	}
+
+	var kept []string
+	stripped := false
+	for _, p := range strings.Split(query, "&") {
+		name, _, _ := strings.Cut(p, "=")
+		if trackingParam(name, params) {
+			stripped = true
+			continue
+		}
+		kept = append(kept, p)
+	}
+	if !stripped {
+		return url
+	}
+
+	if len(kept) > 0 {
+		base += "?" + strings.Join(kept, "&")
+	}
+	if hasFragment {
+		base += "#" + fragment
+	}
+	return base
+}
+
+// trackingParam reports whether the query parameter name, as written,
+// matches one of params
+func trackingParam(name string, params []string) bool {
+	if unescaped, err := url.QueryUnescape(name); err == nil {
+		name = unescaped
+	}
+	name = strings.ToLower(name)
+	for _, p := range params {
+		if ok, _ := path.Match(strings.ToLower(p), name); ok && name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Query returns the query of url, without the '?', empty when it has none
+func Query(url string) string {
+	rest, _, _ := strings.Cut(url, "#")
+	_, query, _ := strings.Cut(rest, "?")
+	return query
+}
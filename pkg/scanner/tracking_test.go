@@ -0,0 +1,151 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeDoc writes content to a file named name in a new directory and
+// returns its path
+func writeDoc(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestStripTrackingParams(t *testing.T) {
+	const page = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html/networking/index"
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"tracking parameter alone", page + "?sc_cid=701f2000001OH7JAAW", page},
+		{"with a fragment", page + "?sc_cid=701f2000001OH7JAAW#ingress", page + "#ingress"},
+		{"utm glob", page + "?utm_source=blog&utm_medium=social#ingress", page + "#ingress"},
+		{"meaningful parameter kept", page + "?lang=en&sc_cid=x#ingress", page + "?lang=en#ingress"},
+		{"meaningful parameters kept in order", page + "?b=2&intcmp=home&a=1&extIdCarryOver=true", page + "?b=2&a=1"},
+		{"case ignored", page + "?SC_CID=x&UTM_Campaign=y", page},
+		{"escaped name", page + "?utm%5Fsource=x", page},
+		{"fragment holding a query", page + "#ingress?sc_cid=x", page + "#ingress?sc_cid=x"},
+		{"value named like a parameter", page + "?ref=sc_cid", page + "?ref=sc_cid"},
+		{"prefix of a parameter", page + "?sc_cidx=1", page + "?sc_cidx=1"},
+		{"no tracking parameter", page + "?lang=en#ingress", page + "?lang=en#ingress"},
+		{"empty query unchanged", page + "?#ingress", page + "?#ingress"},
+		{"no query", page + "#ingress", page + "#ingress"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StripTrackingParams(tt.in, DefaultTrackingParams); got != tt.want {
+				t.Errorf("StripTrackingParams(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+
+	if got := StripTrackingParams(page+"?sc_cid=x", nil); got != page+"?sc_cid=x" {
+		t.Errorf("StripTrackingParams() without parameters = %q, want it unchanged", got)
+	}
+}
+
+func TestScan_TrackingParams(t *testing.T) {
+	const page = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html/networking/index"
+	spellings := []string{
+		page + "#ingress",
+		page + "?sc_cid=701f2000001OH7JAAW#ingress",
+		page + "?utm_source=blog&lang=en#ingress",
+		page + "?lang=en&ref=partner#ingress",
+	}
+	var content string
+	for _, url := range spellings {
+		content += "[link](" + url + ")\n"
+	}
+
+	tests := []struct {
+		name   string
+		params []string // nil keeps the defaults
+		want   map[string]int
+	}{
+		{"defaults", nil, map[string]int{
+			page + "#ingress":                     2,
+			page + "?lang=en#ingress":             1,
+			page + "?lang=en&ref=partner#ingress": 1,
+		}},
+		{"custom list", []string{"ref", "utm_*"}, map[string]int{
+			page + "#ingress": 1,
+			page + "?sc_cid=701f2000001OH7JAAW#ingress": 1,
+			page + "?lang=en#ingress":                   2,
+		}},
+		{"disabled", []string{}, map[string]int{
+			page + "#ingress": 1,
+			page + "?sc_cid=701f2000001OH7JAAW#ingress": 1,
+			page + "?utm_source=blog&lang=en#ingress":   1,
+			page + "?lang=en&ref=partner#ingress":       1,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeDoc(t, "doc.md", content)
+			s := New()
+			if tt.params != nil {
+				if err := s.SetTrackingParams(tt.params); err != nil {
+					t.Fatal(err)
+				}
+			}
+			locations, err := s.Scan(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]int)
+			for _, loc := range locations {
+				got[loc.URL] = len(loc.Occurrences)
+				// Every occurrence keeps its spelling
+				for _, occ := range loc.Occurrences {
+					if occ.URL != spellings[occ.Line-1] {
+						t.Errorf("occurrence at line %d = %s, want %s", occ.Line, occ.URL, spellings[occ.Line-1])
+					}
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Scan() occurrences by URL = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	for _, params := range [][]string{{""}, {"utm_["}} {
+		if err := New().SetTrackingParams(params); err == nil {
+			t.Errorf("SetTrackingParams(%q) succeeded, want error", params)
+		}
+	}
+}
+
+func TestPartition(t *testing.T) {
+	const page = "https://docs.redhat.com/en/documentation/openshift_container_platform/4.16/html/networking/index"
+	s := New()
+	if err := s.SetTrackingParams([]string{"ref"}); err != nil {
+		t.Fatal(err)
+	}
+	locations, err := s.Scan(writeDoc(t, "CHANGELOG.md", page+"?ref=a\n"+page+"?ref=b\n"+page+"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The parts keep the URL of the scan rather than grouping again
+	kept, rest := Partition(locations, func(occ Occurrence) bool { return occ.Line != 2 })
+	if len(kept) != 1 || kept[0].URL != page || len(kept[0].Occurrences) != 2 || !kept[0].Historical {
+		t.Errorf("Partition() kept = %+v, want lines 1 and 3 under %s", kept, page)
+	}
+	if len(rest) != 1 || rest[0].URL != page || len(rest[0].Occurrences) != 1 || len(rest[0].Files) != 1 {
+		t.Errorf("Partition() rest = %+v, want line 2 under %s", rest, page)
+	}
+	if kept, _ := Partition(locations, func(Occurrence) bool { return false }); kept != nil {
+		t.Errorf("Partition() kept = %+v, want none", kept)
+	}
+}